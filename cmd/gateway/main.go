@@ -10,74 +10,195 @@ import (
 
 	"github.com/ahmadhassan44/container-orchestrator/internal/gateway"
 	"github.com/ahmadhassan44/container-orchestrator/pkg/config"
+	"github.com/ahmadhassan44/container-orchestrator/pkg/logging"
+	"github.com/ahmadhassan44/container-orchestrator/pkg/version"
 )
 
 func main() {
+	// Load configuration
+	cfg := config.LoadConfig()
+	if err := logging.Setup(cfg.Log); err != nil {
+		log.Fatalf("[FATAL] Logging setup failed: %v", err)
+	}
+
 	log.Println("Starting Container Orchestrator Gateway")
 	log.Println("========================================")
+	info := version.Get()
+	log.Printf("[Version] %s (commit %s, built %s, %s)", info.Version, info.Commit, info.BuildDate, info.GoVersion)
 
 	ctx := context.Background()
 
-	// Load configuration
-	cfg := config.LoadConfig()
 	log.Printf("[Config] Max CPU Threshold: %.0f%%", cfg.MaxCPUThreshold)
 	log.Printf("[Config] Pre-spawn Threshold: %.0f%%", cfg.PreSpawnThreshold)
 	log.Printf("[Config] Gateway Port: %d", cfg.GatewayPort)
-	log.Printf("[Config] Initial Workers: %d", cfg.InitialWorkers)
+	log.Printf("[Config] Initial Workers: %d (placement: %s)", cfg.InitialWorkers, cfg.InitialPlacement)
+	log.Printf("[Config] Runtime: %s", cfg.Runtime)
 
 	// Initialize orchestrator
-	orch, err := gateway.NewOrchestrator(ctx, cfg.WorkerBasePort)
-	if err != nil {
-		log.Fatalf("[FATAL] Orchestrator initialization failed: %v", err)
+	var orch *gateway.Orchestrator
+	switch cfg.Runtime {
+	case "fake":
+		orch = gateway.NewOrchestratorWithRuntime(ctx, gateway.NewFakeRuntime(), cfg)
+	case "local":
+		orch = gateway.NewOrchestratorWithRuntime(ctx, gateway.NewLocalRuntime(cfg), cfg)
+	default:
+		var err error
+		orch, err = gateway.NewOrchestrator(ctx, cfg)
+		if err == nil && cfg.LocalExecFallback {
+			pingCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+			err = orch.PingRuntime(pingCtx)
+			cancel()
+		}
+		switch {
+		case err != nil && cfg.LocalExecFallback:
+			log.Printf("[WARNING] Docker unreachable (%v); falling back to local-exec workers", err)
+			cfg.Runtime = "local"
+			orch = gateway.NewOrchestratorWithRuntime(ctx, gateway.NewLocalRuntime(cfg), cfg)
+		case err != nil:
+			log.Fatalf("[FATAL] Orchestrator initialization failed: %v", err)
+		}
 	}
 
+	// Keep the control plane on its reserved CPUs, ahead of the workers
+	orch.PinGateway()
+
 	// Verify Docker connectivity
 	orch.CheckConnectivity()
+	if err := orch.EnsureWorkerImage(ctx); err != nil {
+		log.Printf("[WARNING] %v", err)
+	}
+	orch.StartImageGC(cfg.ImageGCInterval)
 
 	// Setup cleanup on shutdown
 	defer func() {
-		if err := orch.Shutdown(); err != nil {
+		if err := orch.Shutdown(ctx); err != nil {
 			log.Printf("[ERROR] Shutdown errors: %v", err)
 		}
 	}()
 
-	// Handle shutdown signals (Ctrl+C, etc.)
+	// Resolve the internal listener before spawning so workers learn where to report
+	internalAddr, err := orch.ConfigureInternal(cfg)
+	if err != nil {
+		log.Printf("[WARNING] Internal listener disabled: %v", err)
+	}
+
+	if err := orch.ConfigureWorkerEnvironment(cfg); err != nil {
+		log.Fatalf("[FATAL] Worker environment: %v", err)
+	}
+
+	// Initialize scheduler
+	sched := gateway.NewScheduler(orch, cfg)
+	server := gateway.NewServer(sched, cfg)
+
+	// Admit jobs only within their tenant's business hours (a sample admission hook)
+	if cfg.BusinessHours != "" {
+		hook, err := gateway.NewBusinessHoursHook(cfg.BusinessHours)
+		if err != nil {
+			log.Fatalf("[FATAL] BUSINESS_HOURS: %v", err)
+		}
+		server.RegisterHook(hook)
+	}
+
+	// Handle shutdown signals (Ctrl+C, etc.): stop taking jobs, drain those in
+	// flight for up to SHUTDOWN_TIMEOUT seconds (a second signal cuts it short),
+	// then remove the worker containers before exiting
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
 	go func() {
 		<-sigChan
-		log.Println("\n[Gateway] Shutdown signal received")
-		if err := orch.Shutdown(); err != nil {
+		log.Printf("\n[Gateway] Shutdown signal received, draining jobs for up to %ds (signal again to stop now)", cfg.ShutdownTimeout)
+		shutdownCtx, cancel := context.WithTimeout(ctx, time.Duration(cfg.ShutdownTimeout)*time.Second)
+		go func() {
+			<-sigChan
+			log.Println("[Gateway] Second shutdown signal received, cancelling jobs still in flight")
+			cancel()
+		}()
+		if err := orch.Shutdown(shutdownCtx); err != nil {
 			log.Printf("[ERROR] Shutdown errors: %v", err)
 		}
+		cancel()
+
+		// Give the requests of drained jobs a moment to deliver their results
+		flushCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+		if err := server.Shutdown(flushCtx); err != nil {
+			log.Printf("[WARNING] HTTP server shutdown: %v", err)
+		}
 		os.Exit(0)
 	}()
 
-	// Initialize scheduler
-	sched := gateway.NewScheduler(orch, cfg) // Spawn initial workers
-	log.Printf("[Startup] Spawning %d initial worker(s)", cfg.InitialWorkers)
-	for i := 0; i < cfg.InitialWorkers; i++ {
-		coreID := i + 1
-		if coreID > 3 {
-			log.Printf("[Startup] Cannot spawn more than 3 workers (hardware limit)")
-			break
-		}
+	// Internal listener comes up before workers so their first heartbeat lands
+	if internalAddr != "" {
+		go func() {
+			if err := server.StartInternal(internalAddr); err != nil {
+				log.Printf("[ERROR] Internal listener failed: %v", err)
+			}
+		}()
+	}
 
-		_, err := orch.StartWorker(coreID)
-		if err != nil {
-			log.Printf("[WARNING] Failed to start initial worker on core %d: %v", coreID, err)
-			continue
+	// The public status page may get a listener of its own, with no other routes
+	if cfg.PublicStatusPort > 0 {
+		go func() {
+			if err := server.StartPublicStatus(cfg.PublicStatusPort); err != nil {
+				log.Printf("[ERROR] Public status listener failed: %v", err)
+			}
+		}()
+	}
+
+	// SIGHUP rebinds the public listener, rereading its TLS certificate (for rotation)
+	hupChan := make(chan os.Signal, 1)
+	signal.Notify(hupChan, syscall.SIGHUP)
+	go func() {
+		for range hupChan {
+			log.Println("[Gateway] SIGHUP received, reloading listener")
+			if err := server.ReloadListener(gateway.ListenerSpec{}, false); err != nil {
+				log.Printf("[ERROR] Listener reload failed: %v", err)
+			}
 		}
+	}()
 
-		// Brief pause to avoid overwhelming Docker daemon
-		time.Sleep(500 * time.Millisecond)
+	// Spawn initial workers
+	if _, err := orch.StartInitialWorkers(cfg); err != nil {
+		log.Fatalf("[FATAL] Initial worker placement: %v", err)
 	}
 
+	// Follow the primary in REPLICATE_FROM as a hot standby
+	server.StartReplication()
+
+	// Top up for a predicted peak (e.g. after a restart during rush hour)
+	server.StartWarmUp()
+
+	// Resize workers to what their job history suggests, if enabled
+	server.StartRecommendations()
+
+	// Roll utilization, queue depth and throughput up per minute for charts
+	server.StartMetricsHistory()
+
+	// Stop workers that stay idle, within the autoscaling cooldowns and rate limits
+	sched.StartScaleDown()
+
+	// Keep the worker count within MIN_WORKERS and MAX_WORKERS, or at an admin's hold
+	sched.StartWorkerBounds()
+
+	// Keep jobs off cores with upcoming maintenance windows and drain them
+	sched.StartMaintenance()
+
+	// Drop workers whose containers died or vanished outside the gateway's view
+	sched.StartJanitor(cfg.JanitorInterval)
+
+	// Notice the Docker daemon going away, and reconnect once it is back
+	sched.StartDaemonWatch()
+
+	// Measure workers' CPU from streamed Docker stats rather than trusting estimates
+	orch.StartStatsCollector()
+
+	// Log what sharing a physical core costs each operation
+	sched.StartInterferenceReports(cfg.InterferenceInterval)
+
 	log.Printf("[Startup] %d worker(s) ready", orch.GetWorkerCount())
 	log.Println("========================================")
 
 	// Start HTTP server
-	server := gateway.NewServer(sched, cfg.GatewayPort)
 	log.Printf("[Gateway] Ready to accept client connections")
 
 	if err := server.Start(); err != nil {
@@ -0,0 +1,242 @@
+// Command orchctl is an operator CLI for the gateway's admin API.
+//
+//	orchctl report [-days 7] [-json]
+//	orchctl recommendations [-days 7] [-apply] [-core N] [-json]
+//	orchctl replay -file telemetry.csv [-speed 1] [-out results.csv] [-json]
+//
+// The gateway URL and admin token come from -gateway and -token, or ORCH_GATEWAY
+// and ADMIN_TOKEN.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ahmadhassan44/container-orchestrator/internal/gateway"
+)
+
+const usage = `Usage: orchctl [-gateway URL] [-token TOKEN] <command> [flags]
+
+Commands:
+  report    Capacity planning report over recent job history
+  recommendations
+            Per-worker cpuset and CPU quota suggestions from job history
+  replay    Resubmit a recorded workload with its original arrival pattern
+`
+
+func main() {
+	gatewayURL := flag.String("gateway", envOr("ORCH_GATEWAY", "http://localhost:3000"), "Gateway base URL")
+	token := flag.String("token", os.Getenv("ADMIN_TOKEN"), "Admin token")
+	flag.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	c := &client{base: strings.TrimRight(*gatewayURL, "/"), token: *token, http: &http.Client{Timeout: time.Minute}}
+	var err error
+	switch flag.Arg(0) {
+	case "report":
+		err = runReport(c, flag.Args()[1:])
+	case "recommendations":
+		err = runRecommendations(c, flag.Args()[1:])
+	case "replay":
+		err = runReplay(c, flag.Args()[1:])
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "orchctl: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// client calls the gateway's admin API
+type client struct {
+	base  string
+	token string
+	http  *http.Client
+}
+
+// get fetches path and returns the body, turning non-2xx responses into errors
+func (c *client) get(path string) ([]byte, error) {
+	return c.do(http.MethodGet, path)
+}
+
+// do sends a bodiless request to path and returns the response body, turning
+// non-2xx responses into errors
+func (c *client) do(method, path string) ([]byte, error) {
+	req, err := http.NewRequest(method, c.base+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		return nil, fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	return body, nil
+}
+
+// runReport fetches a capacity report and prints it
+func runReport(c *client, args []string) error {
+	fs := flag.NewFlagSet("report", flag.ExitOnError)
+	days := fs.Int("days", 7, "Days of history to analyze")
+	raw := fs.Bool("json", false, "Print the report as JSON")
+	fs.Parse(args)
+
+	body, err := c.get("/admin/report?days=" + url.QueryEscape(fmt.Sprint(*days)))
+	if err != nil {
+		return err
+	}
+	if *raw {
+		_, err := os.Stdout.Write(body)
+		return err
+	}
+
+	var report gateway.CapacityReport
+	if err := json.Unmarshal(body, &report); err != nil {
+		return fmt.Errorf("invalid report: %w", err)
+	}
+	printReport(os.Stdout, report)
+	return nil
+}
+
+func printReport(out io.Writer, r gateway.CapacityReport) {
+	fmt.Fprintf(out, "Capacity report: %s to %s (%d day(s))\n\n",
+		r.From.Local().Format(time.DateTime), r.To.Local().Format(time.DateTime), r.Days)
+
+	tw := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "Jobs\t%d total, %d completed, %d failed, %d cancelled\n",
+		r.Jobs["total"], r.Jobs["completed"], r.Jobs["failed"], r.Jobs["cancelled"])
+	fmt.Fprintf(tw, "Peak concurrency\t%d job(s)", r.Concurrency.PeakJobs)
+	if !r.Concurrency.PeakJobsAt.IsZero() {
+		fmt.Fprintf(tw, " at %s", r.Concurrency.PeakJobsAt.Local().Format(time.DateTime))
+	}
+	fmt.Fprintln(tw)
+	fmt.Fprintf(tw, "CPU demand\tpeak %.0f%%, p95 %.0f%% (busy time), mean %.1f%%, busy %.1f%% of the period\n",
+		r.Concurrency.PeakCPU, r.Concurrency.P95CPU, r.Concurrency.MeanCPU, 100*r.Concurrency.BusyFraction)
+	fmt.Fprintf(tw, "Queue wait\tp50 %.2fs, p90 %.2fs, p99 %.2fs, max %.2fs (%d jobs)\n",
+		r.QueueWait.P50, r.QueueWait.P90, r.QueueWait.P99, r.QueueWait.Max, r.QueueWait.Samples)
+	fmt.Fprintf(tw, "Spawns\t%d total, %.1f/day", r.Spawns.Total, r.Spawns.PerDay)
+	if r.Spawns.BusiestDay != "" {
+		fmt.Fprintf(tw, ", busiest %s (%d)", r.Spawns.BusiestDay, r.Spawns.BusiestMax)
+	}
+	fmt.Fprintln(tw)
+	if r.Estimator.Samples > 0 {
+		fmt.Fprintf(tw, "Estimator error\tmean abs %.2fs, bias %+.2fs, actual/estimate p50 %.2f p90 %.2f (%d jobs)\n",
+			r.Estimator.MeanAbsError, r.Estimator.Bias, r.Estimator.RatioP50, r.Estimator.RatioP90, r.Estimator.Samples)
+	} else {
+		fmt.Fprintf(tw, "Estimator error\tno completed jobs with an estimate\n")
+	}
+	tw.Flush()
+
+	rec := r.Recommendation
+	fmt.Fprintf(out, "\nRecommendation: %d worker(s) (peak %d, INITIAL_WORKERS=%d)\n", rec.Workers, rec.PeakWorkers, rec.InitialWorkers)
+	for _, note := range rec.Notes {
+		fmt.Fprintf(out, "  - %s\n", note)
+	}
+}
+
+// runRecommendations fetches worker size recommendations, or applies them, and prints them
+func runRecommendations(c *client, args []string) error {
+	fs := flag.NewFlagSet("recommendations", flag.ExitOnError)
+	days := fs.Int("days", 0, "Days of history to analyze (default: the gateway's RECOMMENDATION_DAYS)")
+	apply := fs.Bool("apply", false, "Resize the workers to their recommendations")
+	core := fs.Int("core", 0, "With -apply, only resize this core's worker")
+	raw := fs.Bool("json", false, "Print the recommendations as JSON")
+	fs.Parse(args)
+
+	query := url.Values{}
+	if *days > 0 {
+		query.Set("days", fmt.Sprint(*days))
+	}
+	method, path := http.MethodGet, "/recommendations"
+	if *apply {
+		method, path = http.MethodPost, "/recommendations/apply"
+		if *core > 0 {
+			query.Set("core", fmt.Sprint(*core))
+		}
+	}
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+
+	body, err := c.do(method, path)
+	if err != nil {
+		return err
+	}
+	if *raw {
+		_, err := os.Stdout.Write(body)
+		return err
+	}
+
+	var recs gateway.WorkerRecommendations
+	if err := json.Unmarshal(body, &recs); err != nil {
+		return fmt.Errorf("invalid recommendations: %w", err)
+	}
+	printRecommendations(os.Stdout, recs)
+	return nil
+}
+
+func printRecommendations(out io.Writer, r gateway.WorkerRecommendations) {
+	fmt.Fprintf(out, "Worker sizing: %s to %s (%d day(s))\n\n",
+		r.From.Local().Format(time.DateTime), r.To.Local().Format(time.DateTime), r.Days)
+
+	resources := func(res gateway.WorkerResources) string {
+		if res.CPUs > 0 {
+			return fmt.Sprintf("cpuset %s, %.2f CPUs", res.Cpuset, res.CPUs)
+		}
+		return "cpuset " + res.Cpuset
+	}
+	for _, rec := range r.Workers {
+		status := "keep"
+		switch {
+		case rec.Applied:
+			status = "applied"
+		case rec.Change:
+			status = "change"
+		}
+		fmt.Fprintf(out, "Core %d (%d jobs, p95 demand %.0f%%): %s -> %s [%s]\n",
+			rec.CoreID, rec.Jobs, rec.Demand.P95CPU, resources(rec.Current), resources(rec.Recommended), status)
+		for _, reason := range rec.Reasons {
+			fmt.Fprintf(out, "  - %s\n", reason)
+		}
+	}
+	if r.AutoApply {
+		fmt.Fprintln(out, "\nRecommendations are applied automatically (RECOMMENDATION_AUTO_APPLY)")
+	}
+}
+
+func envOr(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
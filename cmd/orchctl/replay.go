@@ -0,0 +1,181 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ahmadhassan44/container-orchestrator/internal/gateway"
+	"github.com/ahmadhassan44/container-orchestrator/pkg/protocol"
+)
+
+// post sends a JSON body and returns the status code and response body
+func (c *client) post(path string, body interface{}) (int, []byte, error) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return 0, nil, err
+	}
+	req, err := http.NewRequest(http.MethodPost, c.base+path, bytes.NewReader(data))
+	if err != nil {
+		return 0, nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	return resp.StatusCode, respBody, err
+}
+
+// runReplay submits a recorded workload with its original arrival pattern
+func runReplay(c *client, args []string) error {
+	fs := flag.NewFlagSet("replay", flag.ExitOnError)
+	file := fs.String("file", "", "Telemetry CSV or job history log to replay (required)")
+	speed := fs.Float64("speed", 1, "Divide arrival gaps and job durations by this factor")
+	limit := fs.Int("limit", 0, "Replay only the first N jobs (0 = all)")
+	out := fs.String("out", "", "Write per-job results to this CSV")
+	raw := fs.Bool("json", false, "Print the summary as JSON")
+	fs.Parse(args)
+
+	if *file == "" {
+		return fmt.Errorf("replay: -file is required")
+	}
+	if *speed <= 0 {
+		return fmt.Errorf("replay: -speed must be positive")
+	}
+	jobs, err := gateway.LoadWorkload(*file)
+	if err != nil {
+		return err
+	}
+	if *limit > 0 && *limit < len(jobs) {
+		jobs = jobs[:*limit]
+	}
+	fmt.Fprintf(os.Stderr, "Replaying %d job(s) over %s at %gx\n",
+		len(jobs), time.Duration(float64(jobs[len(jobs)-1].Offset) / *speed).Round(time.Millisecond), *speed)
+
+	// Jobs run for their full duration, so responses can take much longer than the default timeout
+	c.http.Timeout = 0
+
+	results := make([]gateway.ReplayResult, len(jobs))
+	var wg sync.WaitGroup
+	start := time.Now()
+	for i, job := range jobs {
+		time.Sleep(time.Until(start.Add(time.Duration(float64(job.Offset) / *speed))))
+
+		wg.Add(1)
+		go func(i int, job gateway.ReplayJob) {
+			defer wg.Done()
+			results[i] = submitReplayJob(c, job, *speed)
+		}(i, job)
+	}
+	wg.Wait()
+	summary := gateway.SummarizeReplay(results, start, time.Now())
+
+	if *out != "" {
+		if err := writeReplayResults(*out, results); err != nil {
+			return err
+		}
+	}
+	if *raw {
+		return json.NewEncoder(os.Stdout).Encode(summary)
+	}
+	printReplaySummary(os.Stdout, summary)
+	return nil
+}
+
+func submitReplayJob(c *client, job gateway.ReplayJob, speed float64) gateway.ReplayResult {
+	result := gateway.ReplayResult{Job: job}
+	sent := time.Now()
+	status, body, err := c.post("/submit", job.Request(speed))
+	result.Latency = time.Since(sent)
+	result.Status = status
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	if status/100 != 2 {
+		result.Error = strings.TrimSpace(string(body))
+		return result
+	}
+
+	var resp protocol.JobResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		result.Error = fmt.Sprintf("invalid response: %v", err)
+		return result
+	}
+	result.JobID = resp.JobID
+	result.Annotations = resp.Annotations
+	return result
+}
+
+func writeReplayResults(path string, results []gateway.ReplayResult) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := csv.NewWriter(f)
+	w.Write([]string{"offset", "recorded_job_id", "job_id", "queue", "cpu_load", "load_time",
+		"status", "placement", "core_id", "queue_wait", "run_time", "latency", "error"})
+	format := func(v float64) string { return strconv.FormatFloat(v, 'f', -1, 64) }
+	for _, r := range results {
+		row := []string{
+			format(r.Job.Offset.Seconds()), r.Job.JobID, r.JobID, r.Job.Queue,
+			format(r.Job.CPULoad), format(r.Job.LoadTime), strconv.Itoa(r.Status),
+			"", "", "", "", format(r.Latency.Seconds()), r.Error,
+		}
+		if a := r.Annotations; a != nil {
+			row[7], row[8], row[9], row[10] = a.Placement, strconv.Itoa(a.CoreID), format(a.QueueWait), format(a.RunTime)
+		}
+		w.Write(row)
+	}
+	w.Flush()
+	return w.Error()
+}
+
+func printReplaySummary(out io.Writer, s gateway.ReplaySummary) {
+	tw := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "Jobs\t%d (%d failed)\n", s.Jobs, s.Failed)
+	fmt.Fprintf(tw, "Makespan\t%.2fs\n", s.Makespan)
+	fmt.Fprintf(tw, "Latency\tp50 %.2fs, p90 %.2fs, p99 %.2fs, max %.2fs\n", s.Latency.P50, s.Latency.P90, s.Latency.P99, s.Latency.Max)
+	fmt.Fprintf(tw, "Queue wait\tp50 %.2fs, p90 %.2fs, p99 %.2fs, max %.2fs\n", s.QueueWait.P50, s.QueueWait.P90, s.QueueWait.P99, s.QueueWait.Max)
+	fmt.Fprintf(tw, "Placements\t%s\n", formatCounts(s.Placements))
+	fmt.Fprintf(tw, "Strategies\t%s\n", formatCounts(s.Strategies))
+	tw.Flush()
+}
+
+// formatCounts renders counts as "a=1, b=2", sorted by key
+func formatCounts(counts map[string]int) string {
+	keys := make([]string, 0, len(counts))
+	for key := range counts {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	parts := make([]string, len(keys))
+	for i, key := range keys {
+		parts[i] = fmt.Sprintf("%s=%d", key, counts[key])
+	}
+	if len(parts) == 0 {
+		return "-"
+	}
+	return strings.Join(parts, ", ")
+}
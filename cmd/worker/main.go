@@ -1,12 +1,24 @@
 package main
 
 import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
 	"runtime"
+	"strconv"
+	"syscall"
+	"time"
 
 	"github.com/ahmadhassan44/container-orchestrator/internal/worker"
+	"github.com/ahmadhassan44/container-orchestrator/pkg/config"
+	"github.com/ahmadhassan44/container-orchestrator/pkg/logging"
+	"github.com/ahmadhassan44/container-orchestrator/pkg/signing"
+	"github.com/ahmadhassan44/container-orchestrator/pkg/version"
 )
 
 func main() {
@@ -15,29 +27,78 @@ func main() {
 	// we tell Go's runtime to only create 2 OS threads for execution.
 	runtime.GOMAXPROCS(2)
 
+	// Docker captures stderr, which is also what the gateway tails when a worker dies,
+	// so keep it among the outputs when adding files or syslog
+	if err := logging.Setup(config.LoadLogConfig()); err != nil {
+		log.Fatalf("Logging setup failed: %v", err)
+	}
+
 	// 2. Identity Setup
 	workerID := os.Getenv("WORKER_ID")
 	if workerID == "" {
 		workerID = "Worker-Unknown"
 	}
 
-	log.Printf("Starting %s | Cores: %d | GOMAXPROCS: %d",
-		workerID, runtime.NumCPU(), runtime.GOMAXPROCS(0))
+	log.Printf("Starting %s | Version: %s (%s) | Cores: %d | GOMAXPROCS: %d",
+		workerID, version.Version, version.Commit, runtime.NumCPU(), runtime.GOMAXPROCS(0))
 
 	// 3. Handler Setup
-	h := &worker.WorkerHandler{WorkerID: workerID}
-	http.HandleFunc("/submit", h.StartJob)
+	h := &worker.WorkerHandler{WorkerID: workerID, WorkerUUID: os.Getenv("WORKER_UUID")}
+	if limit, err := strconv.Atoi(os.Getenv("JOB_LOG_LIMIT")); err == nil {
+		h.LogLimit = limit
+	}
+	if key := os.Getenv("RESULT_SIGNING_KEY"); key != "" {
+		seed, err := base64.StdEncoding.DecodeString(key)
+		if err != nil || len(seed) != ed25519.SeedSize {
+			log.Fatalf("RESULT_SIGNING_KEY must be a base64 ed25519 seed")
+		}
+		h.SigningKey = ed25519.NewKeyFromSeed(seed)
+		log.Printf("%s signing results with key %s", workerID, signing.KeyID(h.SigningKey.Public().(ed25519.PublicKey)))
+	}
 
-	// Health check for the Gateway to ping
-	http.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
-		w.WriteHeader(http.StatusOK)
-		w.Write([]byte("OK"))
-	})
+	// Grace period for in-flight jobs on SIGTERM; the orchestrator sets this to
+	// match the container's StopTimeout so docker stop doesn't kill a job mid-flight
+	drainTimeout := 30 * time.Second
+	if secs, err := strconv.Atoi(os.Getenv("DRAIN_TIMEOUT")); err == nil && secs >= 0 {
+		drainTimeout = time.Duration(secs) * time.Second
+	}
 
 	// 4. Start Server
-	// We listen on 8080. The Docker mapping will expose this to unique ports on the host.
-	log.Printf("%s listening on port 8080...", workerID)
-	if err := http.ListenAndServe(":8080", nil); err != nil {
-		log.Fatal(err)
+	// We listen on 8080. The Docker mapping will expose this to unique ports on the host;
+	// run as a local process (RUNTIME=local), the gateway picks the port instead.
+	port := os.Getenv("WORKER_PORT")
+	if port == "" {
+		port = "8080"
+	}
+	srv := &http.Server{Addr: ":" + port, Handler: h.Routes()}
+	go func() {
+		log.Printf("%s listening on port %s...", workerID, port)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal(err)
+		}
+	}()
+
+	// Report to the gateway's internal listener (reachable over the Docker bridge only)
+	heartbeatCtx, stopHeartbeat := context.WithCancel(context.Background())
+	defer stopHeartbeat()
+	if gatewayURL := os.Getenv("GATEWAY_INTERNAL_URL"); gatewayURL != "" {
+		go h.RunHeartbeat(heartbeatCtx, gatewayURL, os.Getenv("INTERNAL_TOKEN"))
+	}
+
+	// 5. Drain on shutdown: refuse new jobs, let the current one finish, then exit
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+	<-sigChan
+
+	log.Printf("%s draining (grace period %s)...", workerID, drainTimeout)
+	ctx, cancel := context.WithTimeout(context.Background(), drainTimeout)
+	defer cancel()
+
+	if err := h.Drain(ctx); err != nil {
+		log.Printf("%s drain incomplete: %v", workerID, err)
+	}
+	if err := srv.Shutdown(ctx); err != nil {
+		srv.Close()
 	}
+	log.Printf("%s stopped", workerID)
 }
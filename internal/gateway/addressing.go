@@ -0,0 +1,111 @@
+package gateway
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/ahmadhassan44/container-orchestrator/pkg/config"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+)
+
+// workerContainerPort is the port the worker API listens on inside its container
+const workerContainerPort = 8080
+
+// Worker address modes (WORKER_ADDRESS_MODE)
+const (
+	addressHost          = "host"           // Published port on WORKER_HOST
+	addressContainerIP   = "container_ip"   // Container's IP on its network
+	addressContainerName = "container_name" // Container's DNS name on a user-defined network
+)
+
+// workerAddressing decides where the gateway sends requests for a worker, so
+// dispatch works whether the gateway runs on the host, in a container next to the
+// workers, or (later) against workers on another node
+type workerAddressing struct {
+	mode    string
+	host    string // In host mode
+	network string // Network workers join ("" = default bridge)
+	publish bool   // Publish worker ports on the host
+}
+
+func newWorkerAddressing(cfg *config.Config) workerAddressing {
+	a := workerAddressing{mode: cfg.WorkerAddressMode, host: cfg.WorkerHost, network: cfg.WorkerNetwork, publish: cfg.WorkerPublishPorts}
+	switch a.mode {
+	case "":
+		a.mode = addressHost
+	case addressHost, addressContainerIP:
+	case addressContainerName:
+		if a.network == "" || a.network == "bridge" {
+			log.Printf("[WARNING] WORKER_ADDRESS_MODE=container_name needs a user-defined WORKER_NETWORK; the default bridge has no DNS")
+		}
+	default:
+		log.Printf("[WARNING] Unknown WORKER_ADDRESS_MODE %q (valid: host, container_ip, container_name), using host", a.mode)
+		a.mode = addressHost
+	}
+	if a.host == "" {
+		a.host = "localhost"
+	}
+	if !a.publish && a.mode == addressHost {
+		if cfg.WorkerAddressMode != "" {
+			log.Printf("[WARNING] WORKER_PUBLISH_PORTS=false needs WORKER_ADDRESS_MODE=container_ip or container_name; publishing ports")
+		}
+		a.publish = true
+	}
+	return a
+}
+
+// networkMode is the network worker containers are attached to
+func (a workerAddressing) networkMode() container.NetworkMode {
+	return container.NetworkMode(a.network)
+}
+
+// baseURL builds a started worker's URL from its inspect data
+func (a workerAddressing) baseURL(inspect types.ContainerJSON, hostPort int) (string, error) {
+	switch a.mode {
+	case addressContainerIP:
+		ip := a.containerIP(inspect)
+		if ip == "" {
+			return "", fmt.Errorf("no IP address on network %q", a.networkName())
+		}
+		return workerURL(ip, workerContainerPort), nil
+	case addressContainerName:
+		if inspect.ContainerJSONBase == nil || inspect.Name == "" {
+			return "", fmt.Errorf("no container name")
+		}
+		return workerURL(strings.TrimPrefix(inspect.Name, "/"), workerContainerPort), nil
+	default:
+		return workerURL(a.host, hostPort), nil
+	}
+}
+
+// networkName is the network the worker's address is taken from
+func (a workerAddressing) networkName() string {
+	if a.network == "" || a.network == "default" {
+		return "bridge"
+	}
+	return a.network
+}
+
+// containerIP returns the container's IPv4 address on the worker network, or its
+// global IPv6 address on an IPv6-only network
+func (a workerAddressing) containerIP(inspect types.ContainerJSON) string {
+	if endpoint, exists := inspect.NetworkSettings.Networks[a.networkName()]; exists && endpoint != nil {
+		if endpoint.IPAddress != "" {
+			return endpoint.IPAddress
+		}
+		return endpoint.GlobalIPv6Address
+	}
+	if inspect.NetworkSettings.IPAddress != "" {
+		return inspect.NetworkSettings.IPAddress
+	}
+	return inspect.NetworkSettings.GlobalIPv6Address
+}
+
+// workerURL joins a host and port into an http URL, bracketing IPv6 literals
+func workerURL(host string, port int) string {
+	return "http://" + net.JoinHostPort(host, strconv.Itoa(port))
+}
@@ -0,0 +1,44 @@
+package gateway
+
+import (
+	"github.com/ahmadhassan44/container-orchestrator/pkg/protocol"
+)
+
+// placementStrategy is the default worker selection strategy (see placementStrategies)
+const placementStrategy = "least_loaded"
+
+// annotate updates a job's scheduler annotations, creating them on first use
+func (s *Scheduler) annotate(jobID string, fn func(a *protocol.JobAnnotations)) {
+	if jobID == "" {
+		return
+	}
+
+	s.runningMu.Lock()
+	defer s.runningMu.Unlock()
+
+	a, exists := s.annotations[jobID]
+	if !exists {
+		a = &protocol.JobAnnotations{Strategy: placementStrategy}
+		s.annotations[jobID] = a
+	}
+	fn(a)
+}
+
+// annotateWorker records the worker a job was dispatched to
+func (s *Scheduler) annotateWorker(jobID string, w *WorkerInfo) {
+	s.annotate(jobID, func(a *protocol.JobAnnotations) {
+		a.CoreID = w.CoreID
+		a.WorkerUUID = w.UUID
+		a.WorkerImage = w.ImageID
+	})
+}
+
+// TakeAnnotations returns a finished job's annotations and forgets them (nil if none)
+func (s *Scheduler) TakeAnnotations(jobID string) *protocol.JobAnnotations {
+	s.runningMu.Lock()
+	defer s.runningMu.Unlock()
+
+	a := s.annotations[jobID]
+	delete(s.annotations, jobID)
+	return a
+}
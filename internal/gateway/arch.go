@@ -0,0 +1,78 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+
+	"github.com/docker/docker/api/types/image"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// normalizeArch maps the architecture names Docker and the kernel report
+// ("x86_64", "aarch64") to the Go/OCI names used in image manifests
+func normalizeArch(arch string) string {
+	switch arch {
+	case "x86_64", "x86-64":
+		return "amd64"
+	case "aarch64", "armv8", "armv8l":
+		return "arm64"
+	case "armv7l", "armhf":
+		return "arm"
+	}
+	return arch
+}
+
+// Arch returns the container host's CPU architecture ("" until CheckConnectivity ran)
+func (o *Orchestrator) Arch() string {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	return o.arch
+}
+
+// platform is the OCI platform worker images are resolved for, so that a
+// multi-arch manifest list yields the host's native variant
+func (o *Orchestrator) platform() *ocispec.Platform {
+	if o.arch == "" {
+		return nil
+	}
+	return &ocispec.Platform{OS: "linux", Architecture: o.arch}
+}
+
+// EnsureWorkerImage checks the worker image is present and, if allowed, pulls it
+// for the host's platform when it isn't
+func (o *Orchestrator) EnsureWorkerImage(ctx context.Context) error {
+	err := o.CheckWorkerImage(ctx)
+	if err == nil || !o.workerImagePull {
+		return err
+	}
+
+	platform := ""
+	if p := o.platform(); p != nil {
+		platform = p.OS + "/" + p.Architecture
+	}
+	log.Printf("[Orchestrator] Pulling worker image %s (platform %s)", o.workerImage, platform)
+
+	reader, err := o.cli.ImagePull(ctx, o.workerImage, image.PullOptions{Platform: platform})
+	if err != nil {
+		return fmt.Errorf("failed to pull worker image %s: %w", o.workerImage, err)
+	}
+	defer reader.Close()
+
+	// The pull runs for as long as its progress stream is being read
+	if _, err := io.Copy(io.Discard, reader); err != nil {
+		return fmt.Errorf("failed to pull worker image %s: %w", o.workerImage, err)
+	}
+	return o.CheckWorkerImage(ctx)
+}
+
+// imageArch reads the architecture an image was built for, falling back to the host's
+func (o *Orchestrator) imageArch(imageID string) string {
+	if imageID != "" {
+		if inspect, _, err := o.cli.ImageInspectWithRaw(o.ctx, imageID); err == nil && inspect.Architecture != "" {
+			return normalizeArch(inspect.Architecture)
+		}
+	}
+	return o.arch
+}
@@ -0,0 +1,513 @@
+package gateway
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ahmadhassan44/container-orchestrator/pkg/config"
+)
+
+const (
+	// scaleRateWindow is the window MAX_SPAWNS_PER_MINUTE and MAX_REAPS_PER_MINUTE count over
+	scaleRateWindow = time.Minute
+
+	// scaleDownInterval is how often idle workers are looked for
+	scaleDownInterval = 5 * time.Second
+
+	// idleCPUThreshold is the reserved CPU below which a worker counts as idle
+	idleCPUThreshold = 1.0
+)
+
+// Autoscaling actions, as used in metrics and /status
+const (
+	scaleActionSpawn = "spawn"
+	scaleActionReap  = "reap"
+)
+
+// WorkerHold pins the worker count until it expires, overriding MIN_WORKERS,
+// MAX_WORKERS and the autoscaler
+type WorkerHold struct {
+	Workers   int       `json:"workers"`
+	ExpiresAt time.Time `json:"expires_at"`
+	Reason    string    `json:"reason,omitempty"`
+}
+
+// scaleGuard rate-limits the scheduler's scaling decisions so flapping load
+// doesn't churn containers: a spawn holds off scale-down for the scale-down
+// cooldown, a reap holds off scale-up for the scale-up cooldown, and each
+// direction is capped per minute.
+type scaleGuard struct {
+	idle         time.Duration // How long a worker must be idle to be reaped (0 = never)
+	upCooldown   time.Duration
+	downCooldown time.Duration
+	maxSpawns    int // Per scaleRateWindow (0 = unlimited)
+	maxReaps     int
+	minWorkers   int
+	maxWorkers   int
+	metrics      *Metrics
+
+	mu         sync.Mutex
+	lastSpawn  time.Time
+	lastReap   time.Time
+	spawns     []time.Time // Spawns within the last scaleRateWindow
+	reaps      []time.Time
+	totals     map[string]int    // By action
+	suppressed map[string]int    // By "action/reason"
+	lastActive map[int]time.Time // Core ID -> when it last started or finished a job
+	hold       *WorkerHold
+}
+
+func newScaleGuard(cfg *config.Config, metrics *Metrics) *scaleGuard {
+	metrics.Register("orchestrator_autoscale_events_total", metricCounter, "Workers spawned and reaped by the autoscaler, by action")
+	metrics.Register("orchestrator_autoscale_suppressed_total", metricCounter, "Scaling decisions held back by a cooldown or rate limit, by action and reason")
+
+	maxWorkers := cfg.MaxWorkers
+	if maxWorkers <= 0 || maxWorkers > len(coreMaps) {
+		maxWorkers = len(coreMaps)
+	}
+	minWorkers := max(cfg.MinWorkers, 0)
+	if minWorkers > maxWorkers {
+		log.Printf("[WARNING] MIN_WORKERS=%d exceeds the maximum of %d workers; using %d", minWorkers, maxWorkers, maxWorkers)
+		minWorkers = maxWorkers
+	}
+	if cfg.InitialWorkers > maxWorkers {
+		log.Printf("[WARNING] INITIAL_WORKERS=%d exceeds MAX_WORKERS=%d; the extra workers are stopped once idle", cfg.InitialWorkers, maxWorkers)
+	}
+
+	return &scaleGuard{
+		idle:         time.Duration(max(cfg.ScaleDownIdle, 0)) * time.Second,
+		upCooldown:   time.Duration(max(cfg.ScaleUpCooldown, 0)) * time.Second,
+		downCooldown: time.Duration(max(cfg.ScaleDownCooldown, 0)) * time.Second,
+		maxSpawns:    max(cfg.MaxSpawnsPerMinute, 0),
+		maxReaps:     max(cfg.MaxReapsPerMinute, 0),
+		minWorkers:   minWorkers,
+		maxWorkers:   maxWorkers,
+		metrics:      metrics,
+		totals:       make(map[string]int),
+		suppressed:   make(map[string]int),
+		lastActive:   make(map[int]time.Time),
+	}
+}
+
+// limitsLocked returns the worker count bounds in force: the hold's count while
+// one is active, else MIN_WORKERS and MAX_WORKERS
+func (g *scaleGuard) limitsLocked(now time.Time) (int, int) {
+	if g.hold != nil {
+		if now.Before(g.hold.ExpiresAt) {
+			return g.hold.Workers, g.hold.Workers
+		}
+		log.Printf("[Autoscale] Hold at %d worker(s) expired", g.hold.Workers)
+		g.hold = nil
+	}
+	return g.minWorkers, g.maxWorkers
+}
+
+// limits returns the worker count bounds in force
+func (g *scaleGuard) limits() (int, int) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.limitsLocked(time.Now())
+}
+
+// setHold pins the worker count, or clears the hold (nil)
+func (g *scaleGuard) setHold(hold *WorkerHold) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.hold = hold
+}
+
+// takeSpawn claims permission for the scheduler to spawn a worker, counting it
+// against the rate limit. Spawns past the maximum worker count are refused; with
+// no workers running a spawn is otherwise always allowed, since nothing else
+// could run the job.
+func (g *scaleGuard) takeSpawn(workers int) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := time.Now()
+	if reason, err := g.spawnBlockedLocked(workers, now); err != nil {
+		return g.suppressLocked(scaleActionSpawn, reason, err)
+	}
+	g.recordLocked(scaleActionSpawn, now)
+	return nil
+}
+
+// spawnBlocked reports why a spawn would be refused right now, without claiming one
+func (g *scaleGuard) spawnBlocked(workers int) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	_, err := g.spawnBlockedLocked(workers, time.Now())
+	return err
+}
+
+// spawnBlockedLocked checks a spawn against the worker ceiling, cooldown and rate
+// limit, returning the suppression reason with the error (caller holds g.mu)
+func (g *scaleGuard) spawnBlockedLocked(workers int, now time.Time) (string, error) {
+	g.spawns = pruneWindow(g.spawns, now)
+	if _, ceiling := g.limitsLocked(now); workers >= ceiling {
+		return "max_workers", fmt.Errorf("at the maximum of %d worker(s)", ceiling)
+	}
+	if workers > 0 {
+		if wait := g.upCooldown - now.Sub(g.lastReap); !g.lastReap.IsZero() && wait > 0 {
+			return "cooldown", fmt.Errorf("scale-up cooldown: %s left after the last scale-down", wait.Round(time.Second))
+		}
+		if g.maxSpawns > 0 && len(g.spawns) >= g.maxSpawns {
+			return "rate", fmt.Errorf("spawn rate limit of %d per minute reached", g.maxSpawns)
+		}
+	}
+	return "", nil
+}
+
+// takeReap claims permission to stop an idle worker, counting it against the rate limit
+func (g *scaleGuard) takeReap() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := time.Now()
+	g.reaps = pruneWindow(g.reaps, now)
+	if wait := g.downCooldown - now.Sub(g.lastSpawn); !g.lastSpawn.IsZero() && wait > 0 {
+		return g.suppressLocked(scaleActionReap, "cooldown", fmt.Errorf("scale-down cooldown: %s left after the last scale-up", wait.Round(time.Second)))
+	}
+	if g.maxReaps > 0 && len(g.reaps) >= g.maxReaps {
+		return g.suppressLocked(scaleActionReap, "rate", fmt.Errorf("reap rate limit of %d per minute reached", g.maxReaps))
+	}
+	g.recordLocked(scaleActionReap, now)
+	return nil
+}
+
+// recordSpawn notes a spawn that isn't subject to the guard (e.g. warm-up), so it
+// still holds off scale-down and shows up in the churn figures
+func (g *scaleGuard) recordSpawn() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	now := time.Now()
+	g.spawns = pruneWindow(g.spawns, now)
+	g.recordLocked(scaleActionSpawn, now)
+}
+
+// recordReap notes a reap that isn't subject to the guard (e.g. to honour a hold)
+func (g *scaleGuard) recordReap() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	now := time.Now()
+	g.reaps = pruneWindow(g.reaps, now)
+	g.recordLocked(scaleActionReap, now)
+}
+
+func (g *scaleGuard) recordLocked(action string, now time.Time) {
+	if action == scaleActionSpawn {
+		g.lastSpawn = now
+		g.spawns = append(g.spawns, now)
+	} else {
+		g.lastReap = now
+		g.reaps = append(g.reaps, now)
+	}
+	g.totals[action]++
+	g.metrics.Inc("orchestrator_autoscale_events_total", "action", action)
+}
+
+func (g *scaleGuard) suppressLocked(action, reason string, err error) error {
+	g.suppressed[action+"/"+reason]++
+	g.metrics.Inc("orchestrator_autoscale_suppressed_total", "action", action, "reason", reason)
+	return err
+}
+
+// pruneWindow drops timestamps older than scaleRateWindow
+func pruneWindow(times []time.Time, now time.Time) []time.Time {
+	i := 0
+	for i < len(times) && now.Sub(times[i]) >= scaleRateWindow {
+		i++
+	}
+	return times[i:]
+}
+
+// touch marks a core as active now
+func (g *scaleGuard) touch(coreID int) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.lastActive[coreID] = time.Now()
+}
+
+// idleFor is how long a core has gone without starting or finishing a job. A core
+// never seen active starts its idle clock now.
+func (g *scaleGuard) idleFor(coreID int, now time.Time) time.Duration {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	last, seen := g.lastActive[coreID]
+	if !seen {
+		g.lastActive[coreID] = now
+		return 0
+	}
+	return now.Sub(last)
+}
+
+// Status reports the guard settings, cooldown state and churn
+func (g *scaleGuard) Status() map[string]interface{} {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := time.Now()
+	g.spawns = pruneWindow(g.spawns, now)
+	g.reaps = pruneWindow(g.reaps, now)
+
+	remaining := func(last time.Time, cooldown time.Duration) float64 {
+		if last.IsZero() {
+			return 0
+		}
+		return max(cooldown-now.Sub(last), 0).Seconds()
+	}
+	timestamp := func(t time.Time) interface{} {
+		if t.IsZero() {
+			return nil
+		}
+		return t
+	}
+	suppressed := make(map[string]int, len(g.suppressed))
+	for key, count := range g.suppressed {
+		suppressed[key] = count
+	}
+	g.limitsLocked(now) // Drops an expired hold
+	var hold *WorkerHold
+	if g.hold != nil {
+		copied := *g.hold
+		hold = &copied
+	}
+
+	return map[string]interface{}{
+		"scale_down_enabled":     g.idle > 0,
+		"scale_down_idle":        g.idle.Seconds(),
+		"scale_up_cooldown":      g.upCooldown.Seconds(),
+		"scale_down_cooldown":    g.downCooldown.Seconds(),
+		"max_spawns_per_minute":  g.maxSpawns,
+		"max_reaps_per_minute":   g.maxReaps,
+		"last_spawn":             timestamp(g.lastSpawn),
+		"last_reap":              timestamp(g.lastReap),
+		"scale_up_blocked_for":   remaining(g.lastReap, g.upCooldown),
+		"scale_down_blocked_for": remaining(g.lastSpawn, g.downCooldown),
+		"spawns_last_minute":     len(g.spawns),
+		"reaps_last_minute":      len(g.reaps),
+		"spawns_total":           g.totals[scaleActionSpawn],
+		"reaps_total":            g.totals[scaleActionReap],
+		"suppressed":             suppressed,
+		"min_workers":            g.minWorkers,
+		"max_workers":            g.maxWorkers,
+		"hold":                   hold,
+	}
+}
+
+// SetScaleDownFloor registers how many workers scale-down must leave running
+// (e.g. the warm-up target). INITIAL_WORKERS is always a floor as well.
+func (s *Scheduler) SetScaleDownFloor(fn func() int) {
+	s.runningMu.Lock()
+	defer s.runningMu.Unlock()
+	s.scaleDownFloor = fn
+}
+
+// StartScaleDown begins stopping workers that have been idle for SCALE_DOWN_IDLE.
+// It does nothing when scale-down is disabled.
+func (s *Scheduler) StartScaleDown() {
+	if s.scaling.idle <= 0 {
+		return
+	}
+	log.Printf("[Autoscale] Scale-down enabled: idle %s, cooldown %s, max %d reap(s)/min",
+		s.scaling.idle, s.scaling.downCooldown, s.scaling.maxReaps)
+
+	go func() {
+		ticker := time.NewTicker(scaleDownInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-s.orchestrator.ctx.Done():
+				return
+			case <-ticker.C:
+				s.scaleDown()
+			}
+		}
+	}()
+}
+
+// scaleDown stops idle workers, longest idle first, down to the floor. Nothing
+// is reaped while jobs are waiting in a queue, nor while Docker is unreachable
+// (the worker would be lost, not stopped).
+func (s *Scheduler) scaleDown() {
+	if s.QueueLength() > 0 || !s.orchestrator.DaemonConnected() {
+		return
+	}
+
+	busy := make(map[int]bool)
+	for _, job := range s.RunningJobs() {
+		busy[job.CoreID] = true
+	}
+	for coreID := range s.receivingCores() {
+		busy[coreID] = true
+	}
+
+	s.runningMu.Lock()
+	floorFn := s.scaleDownFloor
+	s.runningMu.Unlock()
+	minimum, maximum := s.scaling.limits()
+	floor := max(s.config.InitialWorkers, minimum)
+	if floorFn != nil {
+		floor = max(floor, floorFn())
+	}
+	floor = min(floor, maximum)
+
+	// Workers are taken out of the pool under the scheduling lock, so nothing can
+	// be placed on one between the idle check and its removal
+	s.scheduleMux.Lock()
+	now := time.Now()
+	workers := s.orchestrator.GetAllWorkers()
+	idle := make(map[int]time.Duration, len(workers))
+	for _, worker := range workers {
+		idle[worker.CoreID] = s.scaling.idleFor(worker.CoreID, now)
+	}
+	sort.Slice(workers, func(i, j int) bool { return idle[workers[i].CoreID] > idle[workers[j].CoreID] })
+
+	var reaped []*WorkerInfo
+	for _, worker := range workers {
+		if len(workers)-len(reaped) <= floor {
+			break
+		}
+		if busy[worker.CoreID] || worker.CurrentCPU >= idleCPUThreshold || idle[worker.CoreID] < s.scaling.idle ||
+			s.benchmarks.Reserved(worker.CoreID) {
+			continue
+		}
+		if err := s.scaling.takeReap(); err != nil {
+			break
+		}
+		if detached, ok := s.orchestrator.DetachWorker(worker.CoreID); ok {
+			reaped = append(reaped, detached)
+		}
+	}
+	s.scheduleMux.Unlock()
+
+	for _, worker := range reaped {
+		log.Printf("[Autoscale] Worker on Core %d idle for %s, scaling down", worker.CoreID, idle[worker.CoreID].Round(time.Second))
+		s.orchestrator.StopDetached(worker)
+	}
+}
+
+// StartWorkerBounds keeps the worker count within MIN_WORKERS and MAX_WORKERS, or
+// at a hold's count while one is active
+func (s *Scheduler) StartWorkerBounds() {
+	go func() {
+		ticker := time.NewTicker(scaleDownInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-s.orchestrator.ctx.Done():
+				return
+			case <-ticker.C:
+				s.enforceWorkerBounds()
+			}
+		}
+	}()
+}
+
+// enforceWorkerBounds spawns workers up to the minimum and stops idle ones above
+// the maximum. Neither waits for the autoscaling cooldowns, though both count
+// as scaling events. Busy workers above the maximum are stopped once idle.
+func (s *Scheduler) enforceWorkerBounds() {
+	if !s.orchestrator.DaemonConnected() || s.orchestrator.ShuttingDown() {
+		return
+	}
+	minimum, maximum := s.scaling.limits()
+
+	for s.orchestrator.GetWorkerCount() < minimum {
+		coreID, err := s.orchestrator.GetNextAvailableCoreWhere(func(coreID int) bool { return s.maintenance.Clear(coreID, 0) })
+		if err != nil {
+			break
+		}
+		log.Printf("[Autoscale] Spawning a worker on Core %d to keep the minimum of %d", coreID, minimum)
+		if _, err := s.orchestrator.StartWorker(coreID); err != nil {
+			log.Printf("[Autoscale] Spawn on Core %d failed: %v", coreID, err)
+			break
+		}
+		s.scaling.recordSpawn()
+		s.wakeQueue()
+	}
+
+	if s.orchestrator.GetWorkerCount() <= maximum {
+		return
+	}
+	busy := make(map[int]bool)
+	for _, job := range s.RunningJobs() {
+		busy[job.CoreID] = true
+	}
+	for coreID := range s.receivingCores() {
+		busy[coreID] = true
+	}
+
+	s.scheduleMux.Lock()
+	workers := s.orchestrator.GetAllWorkers()
+	sort.Slice(workers, func(i, j int) bool { return workers[i].CoreID > workers[j].CoreID })
+	var reaped []*WorkerInfo
+	for _, worker := range workers {
+		if len(workers)-len(reaped) <= maximum {
+			break
+		}
+		if busy[worker.CoreID] || worker.CurrentCPU >= idleCPUThreshold || s.benchmarks.Reserved(worker.CoreID) {
+			continue
+		}
+		if detached, ok := s.orchestrator.DetachWorker(worker.CoreID); ok {
+			reaped = append(reaped, detached)
+		}
+	}
+	s.scheduleMux.Unlock()
+
+	for _, worker := range reaped {
+		log.Printf("[Autoscale] Stopping the worker on Core %d to keep the maximum of %d", worker.CoreID, maximum)
+		s.scaling.recordReap()
+		s.orchestrator.StopDetached(worker)
+	}
+}
+
+// handleSetWorkerHold pins the worker count for a while:
+// {"workers": 3, "duration_seconds": 3600, "reason": "load test"}
+func (s *Server) handleSetWorkerHold(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Workers         int    `json:"workers"`
+		DurationSeconds int    `json:"duration_seconds"`
+		Reason          string `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "Body must be JSON with \"workers\" and \"duration_seconds\"", http.StatusBadRequest)
+		return
+	}
+	if body.Workers < 1 || body.Workers > len(coreMaps) {
+		http.Error(w, fmt.Sprintf("workers must be between 1 and %d", len(coreMaps)), http.StatusBadRequest)
+		return
+	}
+	if body.DurationSeconds <= 0 {
+		http.Error(w, "duration_seconds must be positive", http.StatusBadRequest)
+		return
+	}
+
+	hold := &WorkerHold{
+		Workers:   body.Workers,
+		ExpiresAt: time.Now().Add(time.Duration(body.DurationSeconds) * time.Second),
+		Reason:    body.Reason,
+	}
+	s.scheduler.scaling.setHold(hold)
+	log.Printf("[Audit] Worker count held at %d until %s", hold.Workers, hold.ExpiresAt.Format(time.RFC3339))
+	go s.scheduler.enforceWorkerBounds()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(hold)
+}
+
+// handleClearWorkerHold returns the worker count to MIN_WORKERS, MAX_WORKERS and the autoscaler
+func (s *Server) handleClearWorkerHold(w http.ResponseWriter, r *http.Request) {
+	s.scheduler.scaling.setHold(nil)
+	log.Printf("[Audit] Worker count hold cleared")
+	s.scheduler.wakeQueue()
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,509 @@
+package gateway
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ahmadhassan44/container-orchestrator/pkg/protocol"
+)
+
+const (
+	// maxBatchSize bounds the jobs in one batch submission
+	maxBatchSize = 100
+
+	// compensationAttempts is how many times a compensation callback is tried
+	compensationAttempts = 3
+)
+
+// Batch states
+const (
+	BatchRunning            = "running"
+	BatchCompleted          = "completed"           // Every job completed
+	BatchPartial            = "partial"             // Some jobs failed; the rest ran (continue policy)
+	BatchAborted            = "aborted"             // A job failed; siblings that hadn't started were cancelled
+	BatchRolledBack         = "rolled_back"         // Aborted, and every completed job was compensated
+	BatchRollbackIncomplete = "rollback_incomplete" // Aborted, but some compensation callbacks failed
+	BatchCancelled          = "cancelled"           // Cancelled as a whole, or jobs cancelled with none failing
+)
+
+// Compensation states of a job in a rolled-back batch
+const (
+	CompensationDone   = "compensated"
+	CompensationFailed = "failed"
+)
+
+// BatchJob is one job's place in a batch
+type BatchJob struct {
+	JobID             string          `json:"job_id"`
+	Status            protocol.Status `json:"status"`
+	Compensation      string          `json:"compensation,omitempty"`
+	CompensationError string          `json:"compensation_error,omitempty"`
+}
+
+// BatchRecord is the gateway's view of a batch submission
+type BatchRecord struct {
+	ID              string     `json:"batch_id"`
+	OnFailure       string     `json:"on_failure"`
+	CompensationURL string     `json:"compensation_url,omitempty"`
+	NotifyEmail     string     `json:"notify_email,omitempty"`
+	State           string     `json:"state"`
+	FailedJob       string     `json:"failed_job,omitempty"` // The job whose failure triggered the policy
+	FailureError    string     `json:"failure_error,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+	FinishedAt      time.Time  `json:"finished_at,omitzero"`
+	Jobs            []BatchJob `json:"jobs"`
+}
+
+// batch tracks a running batch; its record is guarded by mu
+type batch struct {
+	mu        sync.Mutex
+	record    BatchRecord
+	tripped   bool     // The failure policy has fired
+	cancelled bool     // Cancelled by its submitter or an admin
+	completed []string // Completed job IDs, in completion order
+}
+
+// BatchStore keeps a bounded, in-memory history of batches
+type BatchStore struct {
+	mu         sync.RWMutex
+	batches    map[string]*batch
+	order      []string
+	maxHistory int
+}
+
+func NewBatchStore(maxHistory int) *BatchStore {
+	return &BatchStore{batches: make(map[string]*batch), maxHistory: maxHistory}
+}
+
+func (bs *BatchStore) add(b *batch) {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+
+	bs.batches[b.record.ID] = b
+	bs.order = append(bs.order, b.record.ID)
+	for bs.maxHistory > 0 && len(bs.order) > bs.maxHistory {
+		delete(bs.batches, bs.order[0])
+		bs.order = bs.order[1:]
+	}
+}
+
+func (bs *BatchStore) get(id string) (*batch, bool) {
+	bs.mu.RLock()
+	defer bs.mu.RUnlock()
+
+	b, exists := bs.batches[id]
+	return b, exists
+}
+
+// validateBatch checks the batch-level fields of a submission
+func validateBatch(req *protocol.BatchRequest) error {
+	if len(req.Jobs) == 0 {
+		return fmt.Errorf("batch has no jobs")
+	}
+	if len(req.Jobs) > maxBatchSize {
+		return fmt.Errorf("batch has %d jobs (max %d)", len(req.Jobs), maxBatchSize)
+	}
+
+	switch req.OnFailure {
+	case "":
+		req.OnFailure = protocol.BatchOnFailureContinue
+	case protocol.BatchOnFailureContinue, protocol.BatchOnFailureAbort, protocol.BatchOnFailureRollback:
+	default:
+		return fmt.Errorf("unknown on_failure %q (want %s, %s or %s)", req.OnFailure,
+			protocol.BatchOnFailureContinue, protocol.BatchOnFailureAbort, protocol.BatchOnFailureRollback)
+	}
+
+	if req.OnFailure == protocol.BatchOnFailureRollback {
+		u, err := url.Parse(req.CompensationURL)
+		if req.CompensationURL == "" || err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("on_failure %q needs an http(s) compensation_url", req.OnFailure)
+		}
+	} else if req.CompensationURL != "" {
+		return fmt.Errorf("compensation_url only applies to on_failure %q", protocol.BatchOnFailureRollback)
+	}
+
+	for i, job := range req.Jobs {
+		if job.Stream {
+			return fmt.Errorf("jobs[%d]: batch jobs can't be streamed", i)
+		}
+	}
+	return nil
+}
+
+// handleSubmitBatch accepts several jobs at once and runs them in the background
+// under a failure policy. Responds 202 with the batch and job IDs.
+func (s *Server) handleSubmitBatch(w http.ResponseWriter, r *http.Request) {
+	if s.refuseOnStandby(w) {
+		return
+	}
+	var req protocol.BatchRequest
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+	if err := validateBatch(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := s.checkNotifyEmail(req.NotifyEmail); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	for i := range req.Jobs {
+		if err := s.validateRequest(&req.Jobs[i]); err != nil {
+			http.Error(w, fmt.Sprintf("jobs[%d]: %v", i, err), http.StatusBadRequest)
+			return
+		}
+		if req.Jobs[i].JobID != "" {
+			http.Error(w, fmt.Sprintf("jobs[%d]: job_id can only be chosen on /submit; batch jobs get theirs from the gateway", i), http.StatusBadRequest)
+			return
+		}
+		if infeasible := s.checkFeasible(&req.Jobs[i]); infeasible != nil {
+			index := i
+			infeasible.Job = &index
+			s.metrics.Inc("orchestrator_jobs_total", "status", "infeasible")
+			writeInfeasible(w, infeasible)
+			return
+		}
+	}
+
+	source := s.sources.Identify(r)
+	if s.sources.IsDenied(source) {
+		s.sources.RecordRejected(source)
+		s.metrics.Inc("orchestrator_jobs_total", "status", "rejected")
+		log.Printf("[Gateway] Rejected batch from denylisted source %s", source.ID())
+		http.Error(w, "Source is blocked", http.StatusForbidden)
+		return
+	}
+	for i := range req.Jobs {
+		if err := s.admit(&req.Jobs[i], source); err != nil {
+			s.writeAdmissionError(w, source, fmt.Errorf("jobs[%d]: %w", i, err))
+			return
+		}
+	}
+
+	// The whole batch is admitted against the quota or none of it is
+	charges := make([]*quotaCharge, 0, len(req.Jobs))
+	release := func() {
+		for _, charge := range charges {
+			s.quotas.Release(charge)
+		}
+	}
+	for _, job := range req.Jobs {
+		charge, admitted := s.quotas.Admit(source.ID(), s.scheduler.EstimateCPUSeconds(&job))
+		if !admitted {
+			release()
+			quota := s.quotas.Status(source.ID())
+			s.metrics.Inc("orchestrator_jobs_total", "status", "quota_exceeded")
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(time.Until(quota.ResetsAt).Seconds()))))
+			http.Error(w, fmt.Sprintf("CPU-seconds quota exceeded: batch doesn't fit in the %.1f of %.1f remaining until %s",
+				quota.Remaining, quota.Budget, quota.ResetsAt.Format(time.RFC3339)), http.StatusTooManyRequests)
+			return
+		}
+		charges = append(charges, charge)
+	}
+
+	id, err := newBatchID()
+	if err != nil {
+		release()
+		http.Error(w, fmt.Sprintf("Batch failed: %v", err), http.StatusInternalServerError)
+		return
+	}
+	b := &batch{record: BatchRecord{
+		ID:              id,
+		OnFailure:       req.OnFailure,
+		CompensationURL: req.CompensationURL,
+		NotifyEmail:     req.NotifyEmail,
+		State:           BatchRunning,
+		CreatedAt:       time.Now(),
+	}}
+
+	jobs := make([]JobRecord, 0, len(req.Jobs))
+	for i := range req.Jobs {
+		job, err := s.jobs.Create(&req.Jobs[i], source)
+		if err != nil {
+			// Jobs already created never run; record them as failed and refund everything
+			for _, created := range jobs {
+				s.jobs.Fail(created.ID, err)
+				s.quotas.Settle(created.ID)
+			}
+			charges = charges[i:]
+			release()
+			http.Error(w, fmt.Sprintf("Batch failed: %v", err), http.StatusInternalServerError)
+			return
+		}
+		s.sources.RecordSubmitted(source)
+		s.quotas.Track(job.ID, charges[i])
+		jobs = append(jobs, job)
+		b.record.Jobs = append(b.record.Jobs, BatchJob{JobID: job.ID})
+	}
+	s.batches.add(b)
+
+	log.Printf("[Gateway] Batch %s accepted: %d job(s), on_failure=%s", id, len(jobs), req.OnFailure)
+	go s.runBatch(b, jobs, source)
+
+	jobIDs := make([]string, 0, len(jobs))
+	for _, job := range jobs {
+		jobIDs = append(jobIDs, job.ID)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"batch_id":   id,
+		"on_failure": req.OnFailure,
+		"job_ids":    jobIDs,
+	})
+}
+
+// runBatch executes a batch's jobs concurrently, applies the failure policy when
+// a job fails permanently, and runs the rollback once every job has settled
+func (s *Server) runBatch(b *batch, jobs []JobRecord, source JobSource) {
+	var wg sync.WaitGroup
+	for _, job := range jobs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer s.quotas.Settle(job.ID)
+
+			if b.isStopped() {
+				s.jobs.Cancel(job.ID)
+				s.metrics.Inc("orchestrator_jobs_total", "status", "cancelled")
+				return
+			}
+			_, _, err := s.executeJob(job, source)
+			s.batchJobDone(b, job.ID, err)
+		}()
+	}
+	wg.Wait()
+
+	b.mu.Lock()
+	rollback := b.tripped && b.record.OnFailure == protocol.BatchOnFailureRollback
+	completed := slices.Clone(b.completed)
+	b.mu.Unlock()
+
+	if rollback {
+		s.compensate(b, jobs, completed)
+	}
+
+	b.mu.Lock()
+	b.record.State = b.finalStateLocked()
+	b.record.FinishedAt = time.Now()
+	log.Printf("[Gateway] Batch %s finished: %s", b.record.ID, b.record.State)
+	b.mu.Unlock()
+
+	if s.email != nil {
+		s.email.NotifyBatch(s.snapshotBatch(b))
+	}
+}
+
+// batchJobDone records a job's outcome and fires the failure policy on its first
+// permanent failure
+func (s *Server) batchJobDone(b *batch, jobID string, err error) {
+	b.mu.Lock()
+	if err == nil {
+		b.completed = append(b.completed, jobID)
+		b.mu.Unlock()
+		return
+	}
+	if errors.Is(err, ErrJobCancelled) {
+		b.mu.Unlock()
+		return
+	}
+
+	if b.record.FailedJob == "" {
+		b.record.FailedJob = jobID
+		b.record.FailureError = err.Error()
+	}
+	trip := !b.tripped && b.record.OnFailure != protocol.BatchOnFailureContinue
+	b.tripped = b.tripped || trip
+	siblings := make([]string, 0, len(b.record.Jobs))
+	for _, job := range b.record.Jobs {
+		if job.JobID != jobID {
+			siblings = append(siblings, job.JobID)
+		}
+	}
+	b.mu.Unlock()
+
+	if !trip {
+		return
+	}
+
+	// Siblings already on a worker run to completion; only waiting ones are stopped
+	log.Printf("[Gateway] Batch %s: job %s failed, cancelling siblings that haven't started (%s)",
+		b.record.ID, jobID, b.record.OnFailure)
+	for _, sibling := range siblings {
+		if job, exists := s.jobs.Get(sibling); exists &&
+			(job.Status == protocol.StatusAccepted || job.Status == protocol.StatusQueued) {
+			s.scheduler.CancelUnstarted(sibling)
+		}
+	}
+}
+
+// compensate invokes the compensation callback for each completed job, most
+// recently completed first
+func (s *Server) compensate(b *batch, jobs []JobRecord, completed []string) {
+	client := &http.Client{Timeout: 10 * time.Second}
+	reason := fmt.Sprintf("job %s failed: %s", b.record.FailedJob, b.record.FailureError)
+
+	for i := len(completed) - 1; i >= 0; i-- {
+		jobID := completed[i]
+		record, _ := s.jobs.Get(jobID)
+		body, _ := json.Marshal(map[string]interface{}{
+			"batch_id": b.record.ID,
+			"job_id":   jobID,
+			"request":  record.Request,
+			"response": record.Response,
+			"reason":   reason,
+		})
+
+		err := postWithRetries(client, b.record.CompensationURL, body, s.callbackSecret, compensationAttempts)
+		state := CompensationDone
+		if err != nil {
+			state = CompensationFailed
+			log.Printf("[Gateway] Batch %s: compensation for job %s failed: %v", b.record.ID, jobID, err)
+		}
+
+		b.mu.Lock()
+		for j := range b.record.Jobs {
+			if b.record.Jobs[j].JobID == jobID {
+				b.record.Jobs[j].Compensation = state
+				if err != nil {
+					b.record.Jobs[j].CompensationError = err.Error()
+				}
+			}
+		}
+		b.mu.Unlock()
+	}
+}
+
+// postWithRetries POSTs a JSON body until it gets a 2xx, doubling the wait from one
+// second. Each attempt is signed afresh when secret is set.
+func postWithRetries(client *http.Client, target string, body []byte, secret string, attempts int) error {
+	backoff := time.Second
+	for attempt := 1; ; attempt++ {
+		resp, err := postSigned(client, target, body, secret)
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode >= 200 && resp.StatusCode <= 299 {
+				return nil
+			}
+			err = fmt.Errorf("endpoint returned status %d", resp.StatusCode)
+		}
+		if attempt >= attempts {
+			return err
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// isStopped reports whether the batch's jobs that haven't started should be skipped
+func (b *batch) isStopped() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.tripped || b.cancelled
+}
+
+// finalStateLocked derives the batch state once every job has settled (caller holds b.mu)
+func (b *batch) finalStateLocked() string {
+	switch {
+	case b.cancelled, b.record.FailedJob == "" && len(b.completed) < len(b.record.Jobs):
+		return BatchCancelled
+	case b.record.FailedJob == "":
+		return BatchCompleted
+	case !b.tripped:
+		return BatchPartial
+	case b.record.OnFailure != protocol.BatchOnFailureRollback:
+		return BatchAborted
+	}
+	for _, job := range b.record.Jobs {
+		if job.Compensation == CompensationFailed {
+			return BatchRollbackIncomplete
+		}
+	}
+	return BatchRolledBack
+}
+
+// snapshot copies the batch record with each job's current status
+func (s *Server) snapshotBatch(b *batch) BatchRecord {
+	b.mu.Lock()
+	record := b.record
+	record.Jobs = slices.Clone(b.record.Jobs)
+	b.mu.Unlock()
+
+	for i := range record.Jobs {
+		if job, exists := s.jobs.Get(record.Jobs[i].JobID); exists {
+			record.Jobs[i].Status = job.Status
+		}
+	}
+	return record
+}
+
+// handleGetBatch reports a batch's state and each job's status
+func (s *Server) handleGetBatch(w http.ResponseWriter, r *http.Request) {
+	b, exists := s.batches.get(r.PathValue("id"))
+	if !exists {
+		http.Error(w, "Batch not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.snapshotBatch(b))
+}
+
+// handleCancelBatch cancels a batch's queued and running jobs together and reports
+// each job's outcome. Only the submitter or an admin may cancel.
+func (s *Server) handleCancelBatch(w http.ResponseWriter, r *http.Request) {
+	b, exists := s.batches.get(r.PathValue("id"))
+	if !exists {
+		http.Error(w, "Batch not found", http.StatusNotFound)
+		return
+	}
+	record := s.snapshotBatch(b)
+	jobs := make([]JobRecord, 0, len(record.Jobs))
+	for _, batchJob := range record.Jobs {
+		if job, exists := s.jobs.Get(batchJob.JobID); exists {
+			jobs = append(jobs, job)
+		}
+	}
+	if len(jobs) > 0 && !s.isAdmin(r) && s.sources.Identify(r).ID() != jobs[0].Source.ID() {
+		http.Error(w, "Only the submitter or an admin may cancel this batch", http.StatusForbidden)
+		return
+	}
+
+	b.mu.Lock()
+	alreadyFinished := !b.record.FinishedAt.IsZero()
+	if !alreadyFinished {
+		b.cancelled = true
+	}
+	b.mu.Unlock()
+	if alreadyFinished {
+		http.Error(w, fmt.Sprintf("Batch already finished: %s", record.State), http.StatusConflict)
+		return
+	}
+
+	outcomes := s.cancelJobs(jobs)
+	log.Printf("[Gateway] Batch %s cancelled", record.ID)
+	writeCancellations(w, map[string]interface{}{"batch_id": record.ID}, outcomes)
+}
+
+func newBatchID() (string, error) {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate batch ID: %w", err)
+	}
+	return "BATCH-" + hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,302 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ahmadhassan44/container-orchestrator/pkg/config"
+)
+
+// Benchmark session states
+const (
+	benchmarkDraining = "draining" // Waiting for the worker's scheduled jobs to finish
+	benchmarkReady    = "ready"    // Exclusive: only benchmark submissions run on it
+)
+
+// BenchmarkSession reserves one worker for direct benchmark submissions
+type BenchmarkSession struct {
+	CoreID    int       `json:"core_id"`
+	Reason    string    `json:"reason,omitempty"`
+	StartedAt time.Time `json:"started_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+	Jobs      int       `json:"jobs"`      // Submissions passed through so far
+	InFlight  int       `json:"in_flight"` // Submissions running now
+
+	ctx    context.Context // Ends with the session, cancelling in-flight submissions
+	cancel context.CancelFunc
+	timer  *time.Timer
+}
+
+// Benchmarks holds the workers reserved for benchmarking. A reserved worker
+// takes no scheduled jobs; once those it was running finish, submissions are
+// passed straight to it, bypassing the estimator, queues and CPU accounting,
+// until the session ends or expires and the worker returns to the pool.
+type Benchmarks struct {
+	maxDuration time.Duration
+
+	mu       sync.Mutex
+	sessions map[int]*BenchmarkSession
+	onEnd    func(coreID int) // Called without the lock when a session ends
+}
+
+func NewBenchmarks(cfg *config.Config) *Benchmarks {
+	return &Benchmarks{
+		maxDuration: time.Duration(max(cfg.BenchmarkMaxDuration, 1)) * time.Second,
+		sessions:    make(map[int]*BenchmarkSession),
+	}
+}
+
+// Start reserves coreID's worker for duration
+func (b *Benchmarks) Start(coreID int, duration time.Duration, reason string) (BenchmarkSession, error) {
+	if duration <= 0 || duration > b.maxDuration {
+		return BenchmarkSession{}, fmt.Errorf("duration must be between 1s and %s", b.maxDuration)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, exists := b.sessions[coreID]; exists {
+		return BenchmarkSession{}, fmt.Errorf("core %d is already reserved for benchmarking", coreID)
+	}
+	now := time.Now()
+	session := &BenchmarkSession{CoreID: coreID, Reason: reason, StartedAt: now, ExpiresAt: now.Add(duration)}
+	session.ctx, session.cancel = context.WithDeadline(context.Background(), session.ExpiresAt)
+	session.timer = time.AfterFunc(duration, func() {
+		log.Printf("[Benchmark] Session on Core %d expired", coreID)
+		b.End(coreID)
+	})
+	b.sessions[coreID] = session
+
+	log.Printf("[Benchmark] Core %d reserved until %s", coreID, session.ExpiresAt.Format(time.RFC3339))
+	return *session, nil
+}
+
+// End releases coreID's worker back to the pool, cancelling running submissions
+func (b *Benchmarks) End(coreID int) (BenchmarkSession, bool) {
+	b.mu.Lock()
+	session, exists := b.sessions[coreID]
+	if exists {
+		delete(b.sessions, coreID)
+		session.timer.Stop()
+		session.cancel()
+	}
+	onEnd := b.onEnd
+	b.mu.Unlock()
+
+	if !exists {
+		return BenchmarkSession{}, false
+	}
+	log.Printf("[Benchmark] Core %d returned to the pool after %d submission(s)", coreID, session.Jobs)
+	if onEnd != nil {
+		onEnd(coreID)
+	}
+	return *session, true
+}
+
+// Reserved reports whether coreID's worker is taken out of scheduling
+func (b *Benchmarks) Reserved(coreID int) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	_, exists := b.sessions[coreID]
+	return exists
+}
+
+// acquire registers a submission for coreID's session, returning its context and
+// a func to call when it finishes
+func (b *Benchmarks) acquire(coreID int) (context.Context, func(), error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	session, exists := b.sessions[coreID]
+	if !exists {
+		return nil, nil, fmt.Errorf("core %d is not reserved for benchmarking", coreID)
+	}
+	session.Jobs++
+	session.InFlight++
+	return session.ctx, func() {
+		b.mu.Lock()
+		session.InFlight--
+		b.mu.Unlock()
+	}, nil
+}
+
+// Sessions lists the active sessions, each with its state
+func (b *Benchmarks) Sessions(s *Scheduler) []map[string]interface{} {
+	b.mu.Lock()
+	sessions := make([]BenchmarkSession, 0, len(b.sessions))
+	for _, session := range b.sessions {
+		sessions = append(sessions, *session)
+	}
+	b.mu.Unlock()
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].CoreID < sessions[j].CoreID })
+
+	list := make([]map[string]interface{}, 0, len(sessions))
+	for _, session := range sessions {
+		list = append(list, benchmarkStatus(session, s.scheduledJobsOn(session.CoreID)))
+	}
+	return list
+}
+
+func benchmarkStatus(session BenchmarkSession, scheduled int) map[string]interface{} {
+	state := benchmarkReady
+	if scheduled > 0 {
+		state = benchmarkDraining
+	}
+	return map[string]interface{}{
+		"core_id":        session.CoreID,
+		"state":          state,
+		"reason":         session.Reason,
+		"started_at":     session.StartedAt,
+		"expires_at":     session.ExpiresAt,
+		"remaining":      max(time.Until(session.ExpiresAt).Seconds(), 0),
+		"scheduled_jobs": scheduled,
+		"jobs":           session.Jobs,
+		"in_flight":      session.InFlight,
+	}
+}
+
+// ReserveForBenchmark starts a benchmark session on coreID under the scheduling
+// lock, so no job is placed on its worker once the call returns
+func (s *Scheduler) ReserveForBenchmark(coreID int, duration time.Duration, reason string) (BenchmarkSession, error) {
+	s.scheduleMux.Lock()
+	defer s.scheduleMux.Unlock()
+	return s.benchmarks.Start(coreID, duration, reason)
+}
+
+// scheduledJobsOn counts the scheduler's jobs still running on coreID, paired
+// jobs it receives included
+func (s *Scheduler) scheduledJobsOn(coreID int) int {
+	count := s.receivingCores()[coreID]
+	for _, job := range s.RunningJobs() {
+		if job.CoreID == coreID {
+			count++
+		}
+	}
+	return count
+}
+
+// handleListBenchmarks lists the active benchmark sessions
+func (s *Server) handleListBenchmarks(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.scheduler.benchmarks.Sessions(s.scheduler))
+}
+
+// runningWorker parses the {core} path value of a core with a running worker
+func (s *Server) runningWorker(w http.ResponseWriter, r *http.Request) (*WorkerInfo, bool) {
+	coreID, err := strconv.Atoi(r.PathValue("core"))
+	if _, exists := coreMaps[coreID]; err != nil || !exists {
+		http.Error(w, "Unknown core", http.StatusNotFound)
+		return nil, false
+	}
+	worker, exists := s.scheduler.orchestrator.GetWorkerByCore(coreID)
+	if !exists {
+		http.Error(w, fmt.Sprintf("No worker running on core %d", coreID), http.StatusConflict)
+		return nil, false
+	}
+	return worker, true
+}
+
+// handleStartBenchmark drains a worker and reserves it: {"duration": 600, "reason": "calibration"}
+func (s *Server) handleStartBenchmark(w http.ResponseWriter, r *http.Request) {
+	worker, ok := s.runningWorker(w, r)
+	if !ok {
+		return
+	}
+	var body struct {
+		Duration float64 `json:"duration"` // Seconds
+		Reason   string  `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.Duration <= 0 {
+		http.Error(w, "Body must be JSON with a positive \"duration\" in seconds", http.StatusBadRequest)
+		return
+	}
+
+	// Reserved under the scheduling lock, so nothing is placed on the worker after
+	session, err := s.scheduler.ReserveForBenchmark(worker.CoreID, time.Duration(body.Duration*float64(time.Second)), body.Reason)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	log.Printf("[Audit] Core %d reserved for benchmarking for %.0fs", worker.CoreID, body.Duration)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(benchmarkStatus(session, s.scheduler.scheduledJobsOn(worker.CoreID)))
+}
+
+// handleEndBenchmark returns a reserved worker to the pool early
+func (s *Server) handleEndBenchmark(w http.ResponseWriter, r *http.Request) {
+	coreID, _ := strconv.Atoi(r.PathValue("core"))
+	session, ended := s.scheduler.benchmarks.End(coreID)
+	if !ended {
+		http.Error(w, "No benchmark session on this core", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(benchmarkStatus(session, 0))
+}
+
+// handleBenchmarkSubmit passes a submission straight to a reserved worker and
+// relays its response as-is, streamed responses included. It runs at most until
+// the session ends.
+func (s *Server) handleBenchmarkSubmit(w http.ResponseWriter, r *http.Request) {
+	worker, ok := s.runningWorker(w, r)
+	if !ok {
+		return
+	}
+	ctx, done, err := s.scheduler.benchmarks.acquire(worker.CoreID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	defer done()
+	if scheduled := s.scheduler.scheduledJobsOn(worker.CoreID); scheduled > 0 {
+		http.Error(w, fmt.Sprintf("Worker still draining %d scheduled job(s)", scheduled), http.StatusConflict)
+		return
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	context.AfterFunc(r.Context(), cancel)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, worker.BaseURL+"/submit", r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := s.scheduler.httpClient.Do(req)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Worker communication failed: %v", err), http.StatusBadGateway)
+		return
+	}
+	defer resp.Body.Close()
+
+	w.Header().Set("Content-Type", resp.Header.Get("Content-Type"))
+	w.WriteHeader(resp.StatusCode)
+	flusher, _ := w.(http.Flusher)
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := resp.Body.Read(buf)
+		if n > 0 {
+			w.Write(buf[:n])
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+		if err != nil {
+			if err != io.EOF {
+				log.Printf("[Benchmark] Relaying from Core %d ended: %v", worker.CoreID, err)
+			}
+			return
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package gateway
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+	_ "time/tzdata" // Worker images and minimal hosts may have no zoneinfo
+
+	"github.com/ahmadhassan44/container-orchestrator/pkg/protocol"
+)
+
+// businessHoursTenantDefault is the BUSINESS_HOURS entry for tenants without one of their own
+const businessHoursTenantDefault = "*"
+
+// businessHours is when one tenant may submit jobs
+type businessHours struct {
+	spec       string // As configured, for rejections
+	days       [7]bool
+	start, end int // Minutes since midnight, by the wall clock
+	location   *time.Location
+}
+
+// BusinessHoursHook is a sample AdmissionHook: it rejects jobs submitted outside
+// their tenant's business hours. Tenants are job sources, by their ID ("key:..."
+// for API key fingerprints, "ip:..." otherwise). It is enabled by BUSINESS_HOURS.
+type BusinessHoursHook struct {
+	tenants map[string]businessHours
+}
+
+// NewBusinessHoursHook parses "tenant=days HH:MM-HH:MM [zone][;...]", e.g.
+// "*=Mon-Fri 09:00-17:00 Europe/Berlin;key:3fa9c2d1=Mon-Sat 07:00-22:00". Days
+// are ranges or comma-separated lists of weekdays, the zone defaults to UTC, and
+// tenant "*" covers tenants without an entry. Other tenants are unrestricted.
+func NewBusinessHoursHook(spec string) (*BusinessHoursHook, error) {
+	h := &BusinessHoursHook{tenants: make(map[string]businessHours)}
+	for _, entry := range strings.Split(spec, ";") {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+		tenant, hours, found := strings.Cut(entry, "=")
+		if !found {
+			return nil, fmt.Errorf("business hours %q: expected tenant=days HH:MM-HH:MM [zone]", entry)
+		}
+		parsed, err := parseBusinessHours(strings.TrimSpace(hours))
+		if err != nil {
+			return nil, fmt.Errorf("business hours for %s: %w", tenant, err)
+		}
+		h.tenants[strings.TrimSpace(tenant)] = parsed
+	}
+	if len(h.tenants) == 0 {
+		return nil, fmt.Errorf("no business hours given")
+	}
+	return h, nil
+}
+
+// parseBusinessHours parses "days HH:MM-HH:MM [zone]"
+func parseBusinessHours(spec string) (businessHours, error) {
+	fields := strings.Fields(spec)
+	if len(fields) < 2 || len(fields) > 3 {
+		return businessHours{}, fmt.Errorf("expected days HH:MM-HH:MM [zone], got %q", spec)
+	}
+	hours := businessHours{spec: spec, location: time.UTC}
+
+	for _, item := range strings.Split(fields[0], ",") {
+		from, to, isRange := strings.Cut(item, "-")
+		first, err := parseWeekday(from)
+		if err != nil {
+			return businessHours{}, err
+		}
+		last := first
+		if isRange {
+			if last, err = parseWeekday(to); err != nil {
+				return businessHours{}, err
+			}
+		}
+		for day := first; ; day = (day + 1) % 7 {
+			hours.days[day] = true
+			if day == last {
+				break
+			}
+		}
+	}
+
+	from, to, found := strings.Cut(fields[1], "-")
+	if !found {
+		return businessHours{}, fmt.Errorf("expected HH:MM-HH:MM, got %q", fields[1])
+	}
+	var err error
+	if hours.start, err = parseClock(from); err != nil {
+		return businessHours{}, err
+	}
+	if hours.end, err = parseClock(to); err != nil {
+		return businessHours{}, err
+	}
+	if hours.end <= hours.start {
+		return businessHours{}, fmt.Errorf("hours %q must end after they start, on the same day", fields[1])
+	}
+
+	if len(fields) == 3 {
+		if hours.location, err = time.LoadLocation(fields[2]); err != nil {
+			return businessHours{}, fmt.Errorf("unknown time zone %q", fields[2])
+		}
+	}
+	return hours, nil
+}
+
+// parseWeekday parses a weekday's English name or its first three letters
+func parseWeekday(name string) (time.Weekday, error) {
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		full := day.String()
+		if strings.EqualFold(name, full) || strings.EqualFold(name, full[:3]) {
+			return day, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown weekday %q", name)
+}
+
+// parseClock parses "HH:MM" as minutes since midnight
+func parseClock(clock string) (int, error) {
+	t, err := time.Parse("15:04", clock)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q, expected HH:MM", clock)
+	}
+	return 60*t.Hour() + t.Minute(), nil
+}
+
+func (h *BusinessHoursHook) Name() string {
+	return "business_hours"
+}
+
+// BeforeAdmit rejects a job outside its tenant's hours, saying when they next open
+func (h *BusinessHoursHook) BeforeAdmit(req *protocol.ComputeRequest, source JobSource) error {
+	hours, exists := h.tenants[source.ID()]
+	if !exists {
+		if hours, exists = h.tenants[businessHoursTenantDefault]; !exists {
+			return nil
+		}
+	}
+
+	now := time.Now().In(hours.location)
+	opens, open := hours.next(now)
+	if open {
+		return nil
+	}
+	return &AdmissionError{
+		Status:     http.StatusForbidden,
+		RetryAfter: opens.Sub(now),
+		Reason:     fmt.Sprintf("outside business hours (%s); jobs are accepted again from %s", hours.spec, opens.Format(time.RFC3339)),
+	}
+}
+
+// AfterCompletion does nothing: business hours only govern admission
+func (h *BusinessHoursHook) AfterCompletion(job JobRecord) {}
+
+// next reports whether the hours are open at now (in their time zone), and if
+// not, when they next open. Wall-clock times keep them right across DST changes.
+func (b businessHours) next(now time.Time) (time.Time, bool) {
+	if clock := 60*now.Hour() + now.Minute(); b.days[now.Weekday()] && clock >= b.start && clock < b.end {
+		return now, true
+	}
+	for d := 0; d <= 7; d++ {
+		opens := time.Date(now.Year(), now.Month(), now.Day()+d, b.start/60, b.start%60, 0, 0, b.location)
+		if b.days[opens.Weekday()] && opens.After(now) {
+			return opens, false
+		}
+	}
+	return time.Time{}, false
+}
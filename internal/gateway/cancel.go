@@ -0,0 +1,139 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"maps"
+	"net/http"
+
+	"github.com/ahmadhassan44/container-orchestrator/pkg/protocol"
+)
+
+// Outcomes of a job in a bulk cancellation
+const (
+	CancelOutcomeCancelled  = "cancelled"  // Removed before it reached a worker
+	CancelOutcomeCancelling = "cancelling" // Running, or being dispatched; stopped on its worker
+	CancelOutcomeFinished   = "finished"   // Already done, left as it was
+)
+
+// JobCancellation is one job's outcome in a bulk cancellation
+type JobCancellation struct {
+	JobID   string          `json:"job_id"`
+	Status  protocol.Status `json:"status"` // Status when the cancellation ran
+	Outcome string          `json:"outcome"`
+}
+
+// CancelJobs cancels several jobs as one step: it holds the scheduling lock, so
+// none of them can be dispatched from a queue between being matched and cancelled.
+// Jobs not yet running are also marked to be stopped the moment they start, which
+// covers those mid-placement. Returns each job's outcome.
+func (s *Scheduler) CancelJobs(jobIDs []string) map[string]string {
+	s.scheduleMux.Lock()
+	defer s.scheduleMux.Unlock()
+
+	outcomes := make(map[string]string, len(jobIDs))
+	for _, jobID := range jobIDs {
+		s.runningMu.Lock()
+		_, running := s.running[jobID]
+		if !running {
+			s.cancelOnStart[jobID] = true
+		}
+		s.runningMu.Unlock()
+
+		outcomes[jobID] = CancelOutcomeCancelling
+		if s.Cancel(jobID) && !running {
+			// Out of its queue or backoff for good; it won't start
+			s.runningMu.Lock()
+			delete(s.cancelOnStart, jobID)
+			s.runningMu.Unlock()
+			outcomes[jobID] = CancelOutcomeCancelled
+		}
+	}
+	return outcomes
+}
+
+// cancelOnClientGone cancels jobID, wherever it is, if ctx ends because the
+// client disconnected. Returns a func that stops watching, to call once the
+// job has finished.
+func (s *Server) cancelOnClientGone(ctx context.Context, jobID string) func() bool {
+	return context.AfterFunc(ctx, func() {
+		if s.scheduler.CancelJobs([]string{jobID})[jobID] == CancelOutcomeCancelled {
+			log.Printf("[Gateway] Client of job %s disconnected; cancelled before it ran", jobID)
+		} else {
+			log.Printf("[Gateway] Client of job %s disconnected; cancelling it", jobID)
+		}
+		s.metrics.Inc("orchestrator_abandoned_jobs_total")
+	})
+}
+
+// cancelJobs cancels every unfinished job among jobs and reports each job's outcome
+func (s *Server) cancelJobs(jobs []JobRecord) []JobCancellation {
+	ids := make([]string, 0, len(jobs))
+	held := make(map[string]string)
+	for _, job := range jobs {
+		switch {
+		case !job.CompletedAt.IsZero():
+		case s.intake.Remove(job.ID):
+			s.jobs.Cancel(job.ID)
+			held[job.ID] = CancelOutcomeCancelled
+		default:
+			ids = append(ids, job.ID)
+		}
+	}
+	cancelled := s.scheduler.CancelJobs(ids)
+	maps.Copy(cancelled, held)
+
+	outcomes := make([]JobCancellation, 0, len(jobs))
+	for _, job := range jobs {
+		outcome, matched := cancelled[job.ID]
+		if !matched {
+			outcome = CancelOutcomeFinished
+		}
+		outcomes = append(outcomes, JobCancellation{JobID: job.ID, Status: job.Status, Outcome: outcome})
+	}
+	return outcomes
+}
+
+// writeCancellations responds with the per-job outcomes of a bulk cancellation
+func writeCancellations(w http.ResponseWriter, extra map[string]interface{}, outcomes []JobCancellation) {
+	counts := make(map[string]int)
+	for _, o := range outcomes {
+		counts[o.Outcome]++
+	}
+	body := map[string]interface{}{
+		"matched":  len(outcomes),
+		"outcomes": counts,
+		"jobs":     outcomes,
+	}
+	for k, v := range extra {
+		body[k] = v
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(body)
+}
+
+// handleCancelJobs cancels every queued and running job carrying the selected
+// labels: DELETE /jobs?label=experiment=42. Non-admins only match their own jobs.
+func (s *Server) handleCancelJobs(w http.ResponseWriter, r *http.Request) {
+	selector, err := parseLabelSelector(r.URL.Query()["label"])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(selector) == 0 {
+		http.Error(w, "At least one ?label=key=value selector is required", http.StatusBadRequest)
+		return
+	}
+
+	sourceID := ""
+	if !s.isAdmin(r) {
+		sourceID = s.sources.Identify(r).ID()
+	}
+	jobs := s.jobs.List(sourceID, "", selector, 0)
+	outcomes := s.cancelJobs(jobs)
+	log.Printf("[Gateway] Bulk cancel by labels %v: %d job(s) matched", selector, len(jobs))
+
+	writeCancellations(w, map[string]interface{}{"selector": selector}, outcomes)
+}
@@ -0,0 +1,158 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+)
+
+// Docker connection alert events, sent through the webhook endpoint
+const (
+	daemonEventDisconnected = "docker.disconnected"
+	daemonEventReconnected  = "docker.reconnected"
+)
+
+// daemonReconnectBackoff is the wait before the first reconnection attempt; it
+// doubles on each further one, up to DOCKER_MAX_BACKOFF
+const daemonReconnectBackoff = time.Second
+
+// DaemonStatus is the state of the gateway's connection to the Docker daemon
+type DaemonStatus struct {
+	Connected   bool       `json:"connected"`
+	Since       time.Time  `json:"since"`                 // When it connected or was lost
+	Error       string     `json:"error,omitempty"`       // Why it was lost
+	Attempts    int        `json:"attempts,omitempty"`    // Failed reconnection attempts so far
+	NextRetry   *time.Time `json:"next_retry,omitempty"`  // When the next attempt is due
+	Disconnects int        `json:"disconnects,omitempty"` // Times it has been lost since start
+}
+
+// DaemonListener is told when the Docker daemon is lost or reconnects
+type DaemonListener func(event string, status DaemonStatus)
+
+// daemonState tracks the Docker connection (guarded by the orchestrator lock)
+type daemonState struct {
+	status   DaemonStatus
+	listener DaemonListener
+}
+
+// SetDaemonListener registers a callback for the Docker daemon being lost or
+// reconnecting
+func (o *Orchestrator) SetDaemonListener(fn DaemonListener) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.daemon.listener = fn
+}
+
+// Daemon reports the state of the Docker connection
+func (o *Orchestrator) Daemon() DaemonStatus {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	return o.daemon.status
+}
+
+// DaemonConnected reports whether the Docker daemon answered its last ping
+func (o *Orchestrator) DaemonConnected() bool {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	return o.daemon.status.Connected
+}
+
+// daemonDownLocked returns why workers can't be spawned while the Docker daemon
+// is unreachable, or nil (caller holds o.mu)
+func (o *Orchestrator) daemonDownLocked() error {
+	if o.daemon.status.Connected {
+		return nil
+	}
+	return fmt.Errorf("Docker daemon unreachable since %s; not spawning workers until it reconnects",
+		o.daemon.status.Since.Format(time.RFC3339))
+}
+
+// setDaemonConnected records a ping's outcome, alerting on a change of state
+func (o *Orchestrator) setDaemonConnected(err error, nextRetry time.Duration) {
+	o.mu.Lock()
+	status := &o.daemon.status
+	event := ""
+	switch {
+	case err == nil && !status.Connected:
+		event = daemonEventReconnected
+		status.Connected, status.Since, status.Error, status.Attempts, status.NextRetry = true, time.Now(), "", 0, nil
+	case err != nil && status.Connected:
+		event = daemonEventDisconnected
+		status.Connected, status.Since, status.Error = false, time.Now(), err.Error()
+		status.Disconnects++
+	}
+	if err != nil {
+		next := time.Now().Add(nextRetry)
+		status.NextRetry = &next
+		if event == "" {
+			status.Attempts++
+		}
+	}
+	snapshot, listener := *status, o.daemon.listener
+	o.mu.Unlock()
+
+	if event == "" {
+		return
+	}
+	o.metrics.Inc("orchestrator_docker_connection_events_total", "event", event)
+	connected := 0.0
+	if snapshot.Connected {
+		connected = 1
+	}
+	o.metrics.Set("orchestrator_docker_connected", connected)
+	if listener != nil {
+		listener(event, snapshot)
+	}
+}
+
+// StartDaemonWatch pings the Docker daemon every DOCKER_PING_INTERVAL seconds.
+// Once a ping fails, the gateway stops spawning and reaping workers, but keeps
+// serving jobs on the workers it already has, and pings again with doubling
+// backoff until the daemon answers. Then it reconciles its workers against the
+// containers that survived, and lets queued jobs spawn workers again.
+func (s *Scheduler) StartDaemonWatch() {
+	o := s.orchestrator
+	interval := time.Duration(s.config.DockerPingInterval) * time.Second
+	if interval <= 0 {
+		return
+	}
+	maxBackoff := max(time.Duration(s.config.DockerMaxBackoff)*time.Second, daemonReconnectBackoff)
+
+	go func() {
+		wait := interval
+		backoff := daemonReconnectBackoff
+		for {
+			select {
+			case <-o.ctx.Done():
+				return
+			case <-time.After(wait):
+			}
+
+			ctx, cancel := context.WithTimeout(o.ctx, healthProbeTimeout)
+			err := o.PingRuntime(ctx)
+			cancel()
+
+			wasConnected := o.DaemonConnected()
+			switch {
+			case err == nil:
+				o.setDaemonConnected(nil, 0)
+				if !wasConnected {
+					log.Printf("[Orchestrator] Docker daemon reconnected, resuming worker spawns")
+					s.reconcile()
+					s.wakeQueue()
+				}
+				wait, backoff = interval, daemonReconnectBackoff
+			case wasConnected:
+				o.setDaemonConnected(err, backoff)
+				log.Printf("[WARNING] Docker daemon unreachable, serving jobs on existing workers only: %v", err)
+				wait = backoff
+			default:
+				backoff = min(backoff*2, maxBackoff)
+				o.setDaemonConnected(err, backoff)
+				log.Printf("[Orchestrator] Docker daemon still unreachable, retrying in %s: %v", backoff, err)
+				wait = backoff
+			}
+		}
+	}()
+}
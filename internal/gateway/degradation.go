@@ -0,0 +1,360 @@
+package gateway
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ahmadhassan44/container-orchestrator/internal/worker"
+	"github.com/ahmadhassan44/container-orchestrator/pkg/config"
+	"github.com/ahmadhassan44/container-orchestrator/pkg/protocol"
+)
+
+// degradationMinRunTime is the shortest job whose throughput is measured; shorter
+// ones are dominated by startup noise
+const degradationMinRunTime = time.Second
+
+// Degradation alert events, sent through the webhook endpoint
+const (
+	degradationEventDegraded  = "core.degraded"
+	degradationEventRecovered = "core.recovered"
+)
+
+// opThroughput tracks one operation's throughput on one core
+type opThroughput struct {
+	calibration []float64 // First samples, until the baseline is set
+	baseline    float64   // Median of the calibration samples (0 = calibrating)
+	recent      []float64 // Last samples after calibration, oldest first
+	degraded    bool
+}
+
+// OperationThroughput is the evidence for one operation on a core. Throughput is
+// in units per second of a fully used worker: iterations for iterative
+// operations, operations per CPU-second for cpu_load.
+type OperationThroughput struct {
+	Operation string  `json:"operation"`
+	Baseline  float64 `json:"baseline"` // 0 while calibrating
+	Recent    float64 `json:"recent"`   // Median of the recent window
+	Ratio     float64 `json:"ratio"`    // Recent / baseline (1 while calibrating)
+	Samples   int     `json:"samples"`  // In the recent window (or calibration so far)
+	Degraded  bool    `json:"degraded"`
+	Reference float64 `json:"reference,omitempty"` // Best baseline among all cores, for comparison
+}
+
+// CoreDegradation is a core's measured performance against its calibration baseline
+type CoreDegradation struct {
+	CoreID     int                   `json:"core_id"`
+	Degraded   bool                  `json:"degraded"`
+	Since      *time.Time            `json:"since,omitempty"` // When it was flagged
+	Operations []OperationThroughput `json:"operations"`
+}
+
+// DegradationListener is told when a core is flagged or recovers. It runs on the
+// goroutine that finished the job, so it must not block.
+type DegradationListener func(event string, status CoreDegradation)
+
+// DegradationDetector compares each core's throughput on completed jobs with a
+// baseline calibrated from its first jobs, flagging cores that consistently
+// underperform (thermal throttling, a noisy neighbor). Degraded cores are only
+// used when no healthy worker fits a job.
+type DegradationDetector struct {
+	threshold float64 // Fraction below baseline that counts as degraded (0 = disabled)
+	calibrate int
+	window    int
+	metrics   *Metrics
+
+	mu     sync.Mutex
+	cores  map[int]map[string]*opThroughput
+	since  map[int]time.Time // Degraded cores, with when they were flagged
+	listen DegradationListener
+}
+
+func NewDegradationDetector(cfg *config.Config, metrics *Metrics) *DegradationDetector {
+	d := &DegradationDetector{
+		threshold: cfg.DegradationThreshold / 100,
+		calibrate: max(cfg.DegradationBaseline, 1),
+		window:    max(cfg.DegradationWindow, 1),
+		metrics:   metrics,
+		cores:     make(map[int]map[string]*opThroughput),
+		since:     make(map[int]time.Time),
+	}
+	if d.threshold <= 0 || d.threshold >= 1 {
+		d.threshold = 0
+		return d
+	}
+
+	metrics.Register("orchestrator_core_throughput_ratio", metricGauge, "Recent throughput of each core relative to its calibration baseline, by operation")
+	metrics.Register("orchestrator_core_degraded", metricGauge, "Whether each core is flagged as degraded (1) or not (0)")
+	metrics.Register("orchestrator_core_degradation_alerts_total", metricCounter, "Core degradation alerts, by core and event")
+	metrics.AddCollector(func(m *Metrics) {
+		for _, status := range d.Status() {
+			core := strconv.Itoa(status.CoreID)
+			degraded := 0.0
+			if status.Degraded {
+				degraded = 1
+			}
+			m.Set("orchestrator_core_degraded", degraded, "core", core)
+			for _, op := range status.Operations {
+				m.Set("orchestrator_core_throughput_ratio", op.Ratio, "core", core, "operation", op.Operation)
+			}
+		}
+	})
+
+	log.Printf("[Degradation] Flagging cores %.0f%% below baseline (baseline: %d job(s), window: %d job(s))",
+		100*d.threshold, d.calibrate, d.window)
+	return d
+}
+
+// SetAlertListener registers a callback for cores being flagged or recovering
+func (d *DegradationDetector) SetAlertListener(fn DegradationListener) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.listen = fn
+}
+
+// throughput measures a completed job's rate, normalized to a fully used worker;
+// false when the job says nothing comparable about its core
+func throughput(req *protocol.ComputeRequest, resp *protocol.JobResponse) (float64, bool) {
+	if req.Checkpoint != nil || resp.Checkpoint != nil {
+		return 0, false // A slice of a longer job
+	}
+	taken, err := time.ParseDuration(resp.TimeTaken)
+	if err != nil || taken < degradationMinRunTime {
+		return 0, false
+	}
+
+	operation := operationName(req)
+	switch {
+	case worker.IsIterative(operation):
+		if resp.Iterations <= 0 {
+			return 0, false
+		}
+		return float64(resp.Iterations) / taken.Seconds(), true
+	case operation == protocol.DefaultOperation:
+		if req.CPULoad <= 0 || resp.Result <= 0 {
+			return 0, false
+		}
+		return resp.Result / taken.Seconds() / (min(req.CPULoad, 100) / 100), true
+	default:
+		return 0, false
+	}
+}
+
+// Record measures a completed job on coreID against the core's baseline
+func (d *DegradationDetector) Record(coreID int, req *protocol.ComputeRequest, resp *protocol.JobResponse) {
+	if d.threshold == 0 {
+		return
+	}
+	rate, ok := throughput(req, resp)
+	if !ok {
+		return
+	}
+	operation := operationName(req)
+
+	d.mu.Lock()
+	ops, exists := d.cores[coreID]
+	if !exists {
+		ops = make(map[string]*opThroughput)
+		d.cores[coreID] = ops
+	}
+	op, exists := ops[operation]
+	if !exists {
+		op = &opThroughput{}
+		ops[operation] = op
+	}
+
+	if op.baseline == 0 {
+		op.calibration = append(op.calibration, rate)
+		if len(op.calibration) >= d.calibrate {
+			op.baseline = median(op.calibration)
+			log.Printf("[Degradation] Core %d calibrated for %s: %.4g/s", coreID, operation, op.baseline)
+		}
+		d.mu.Unlock()
+		return
+	}
+
+	op.recent = append(op.recent, rate)
+	if len(op.recent) > d.window {
+		op.recent = op.recent[len(op.recent)-d.window:]
+	}
+	// Flag only on a full window, and recover only halfway back to the baseline,
+	// so a core doesn't flap around the threshold
+	ratio := median(op.recent) / op.baseline
+	switch {
+	case !op.degraded && len(op.recent) >= d.window && ratio < 1-d.threshold:
+		op.degraded = true
+	case op.degraded && ratio >= 1-d.threshold/2:
+		op.degraded = false
+	}
+
+	event := d.updateCoreLocked(coreID, time.Now())
+	var status CoreDegradation
+	if event != "" {
+		status = d.statusLocked(coreID)
+	}
+	fn := d.listen
+	d.mu.Unlock()
+
+	if event == "" {
+		return
+	}
+	if event == degradationEventDegraded {
+		log.Printf("[WARNING] Core %d is degraded: %s", coreID, evidence(status))
+	} else {
+		log.Printf("[Degradation] Core %d recovered: %s", coreID, evidence(status))
+	}
+	d.metrics.Inc("orchestrator_core_degradation_alerts_total", "core", strconv.Itoa(coreID), "event", event)
+	if fn != nil {
+		fn(event, status)
+	}
+}
+
+// updateCoreLocked flags or clears a core from its operations, returning the
+// event if that changed (caller holds d.mu)
+func (d *DegradationDetector) updateCoreLocked(coreID int, now time.Time) string {
+	degraded := false
+	for _, op := range d.cores[coreID] {
+		degraded = degraded || op.degraded
+	}
+	_, flagged := d.since[coreID]
+	switch {
+	case degraded && !flagged:
+		d.since[coreID] = now
+		return degradationEventDegraded
+	case !degraded && flagged:
+		delete(d.since, coreID)
+		return degradationEventRecovered
+	}
+	return ""
+}
+
+// evidence summarizes the operations behind a core's state, for logs
+func evidence(status CoreDegradation) string {
+	summary := ""
+	for _, op := range status.Operations {
+		if op.Baseline == 0 {
+			continue
+		}
+		if summary != "" {
+			summary += "; "
+		}
+		summary += fmt.Sprintf("%s at %.0f%% of baseline (%.4g/s vs %.4g/s over %d jobs)",
+			op.Operation, 100*op.Ratio, op.Recent, op.Baseline, op.Samples)
+	}
+	return summary
+}
+
+// Degraded reports whether coreID is flagged
+func (d *DegradationDetector) Degraded(coreID int) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	_, flagged := d.since[coreID]
+	return flagged
+}
+
+// Recalibrate forgets a core's measurements, e.g. once its cooling is fixed. Its
+// next jobs set a new baseline.
+func (d *DegradationDetector) Recalibrate(coreID int) {
+	d.mu.Lock()
+	delete(d.cores, coreID)
+	event := d.updateCoreLocked(coreID, time.Now())
+	fn := d.listen
+	d.mu.Unlock()
+
+	log.Printf("[Degradation] Core %d recalibrating", coreID)
+	if event != "" && fn != nil {
+		fn(event, CoreDegradation{CoreID: coreID, Operations: []OperationThroughput{}})
+	}
+}
+
+// statusLocked reports one core (caller holds d.mu)
+func (d *DegradationDetector) statusLocked(coreID int) CoreDegradation {
+	status := CoreDegradation{CoreID: coreID, Operations: []OperationThroughput{}}
+	if since, flagged := d.since[coreID]; flagged {
+		status.Degraded, status.Since = true, &since
+	}
+	for name, op := range d.cores[coreID] {
+		t := OperationThroughput{Operation: name, Baseline: op.baseline, Ratio: 1, Degraded: op.degraded, Samples: len(op.calibration)}
+		if op.baseline > 0 && len(op.recent) > 0 {
+			t.Recent = median(op.recent)
+			t.Ratio = t.Recent / op.baseline
+			t.Samples = len(op.recent)
+		}
+		for _, other := range d.cores {
+			if o, exists := other[name]; exists {
+				t.Reference = max(t.Reference, o.baseline)
+			}
+		}
+		status.Operations = append(status.Operations, t)
+	}
+	sort.Slice(status.Operations, func(i, j int) bool { return status.Operations[i].Operation < status.Operations[j].Operation })
+	return status
+}
+
+// Status reports every measured core
+func (d *DegradationDetector) Status() []CoreDegradation {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	statuses := make([]CoreDegradation, 0, len(d.cores))
+	for coreID := range d.cores {
+		statuses = append(statuses, d.statusLocked(coreID))
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].CoreID < statuses[j].CoreID })
+	return statuses
+}
+
+// partition splits workers into healthy and degraded ones
+func (d *DegradationDetector) partition(workers []*WorkerInfo) (healthy, degraded []*WorkerInfo) {
+	for _, worker := range workers {
+		if d.Degraded(worker.CoreID) {
+			degraded = append(degraded, worker)
+		} else {
+			healthy = append(healthy, worker)
+		}
+	}
+	return healthy, degraded
+}
+
+func median(samples []float64) float64 {
+	if len(samples) == 0 {
+		return 0
+	}
+	sorted := append([]float64(nil), samples...)
+	sort.Float64s(sorted)
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return sorted[mid]
+}
+
+// handleGetDegradation reports each core's throughput against its baseline
+func (s *Server) handleGetDegradation(w http.ResponseWriter, r *http.Request) {
+	d := s.scheduler.degradation
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"enabled":          d.threshold > 0,
+		"threshold":        100 * d.threshold,
+		"baseline_samples": d.calibrate,
+		"window":           d.window,
+		"cores":            d.Status(),
+	})
+}
+
+// handleRecalibrate discards a core's baseline and clears its flag
+func (s *Server) handleRecalibrate(w http.ResponseWriter, r *http.Request) {
+	coreID, err := strconv.Atoi(r.PathValue("core"))
+	if _, exists := coreMaps[coreID]; err != nil || !exists {
+		http.Error(w, "Unknown core", http.StatusNotFound)
+		return
+	}
+	s.scheduler.degradation.Recalibrate(coreID)
+	log.Printf("[Audit] Core %d degradation baseline reset", coreID)
+	w.WriteHeader(http.StatusNoContent)
+}
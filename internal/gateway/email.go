@@ -0,0 +1,222 @@
+package gateway
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ahmadhassan44/container-orchestrator/pkg/config"
+	"github.com/ahmadhassan44/container-orchestrator/pkg/protocol"
+)
+
+const (
+	// emailBacklog is how many unsent emails are held before new ones are dropped
+	emailBacklog = 100
+
+	// emailAttempts is how many times sending an email is tried
+	emailAttempts = 3
+)
+
+// emailMessage is a summary email waiting to be sent
+type emailMessage struct {
+	kind    string // "job" or "batch"
+	about   string // The job or batch ID, for logs
+	to      string
+	subject string
+	body    string
+}
+
+// EmailNotifier emails submitters a summary of the jobs and batches they asked
+// to hear about with notify_email, once they finish, if they took at least
+// EMAIL_MIN_DURATION. Like webhooks, emails are sent in order by a single
+// goroutine, and new ones are dropped when the backlog is full.
+type EmailNotifier struct {
+	addr        string    // SMTP relay, host:port
+	auth        smtp.Auth // nil = no authentication
+	from        string    // From header, e.g. "Orchestrator <orchestrator@example.com>"
+	sender      string    // Envelope sender: from's bare address
+	minDuration time.Duration
+	baseURL     string // Links to job and batch records start with this
+	pending     chan emailMessage
+	metrics     *Metrics
+}
+
+// NewEmailNotifier returns nil when no SMTP_HOST is configured
+func NewEmailNotifier(cfg *config.Config, metrics *Metrics) *EmailNotifier {
+	if cfg.SMTPHost == "" {
+		return nil
+	}
+	metrics.Register("orchestrator_emails_total", metricCounter, "Notification emails, by kind and result")
+
+	n := &EmailNotifier{
+		addr:        net.JoinHostPort(cfg.SMTPHost, strconv.Itoa(cfg.SMTPPort)),
+		from:        cfg.SMTPFrom,
+		minDuration: time.Duration(cfg.EmailMinDuration * float64(time.Second)),
+		baseURL:     strings.TrimSuffix(cfg.GatewayURL, "/"),
+		pending:     make(chan emailMessage, emailBacklog),
+		metrics:     metrics,
+	}
+	if n.from == "" {
+		n.from = "orchestrator@" + cfg.NodeName
+	}
+	n.sender = n.from
+	if parsed, err := mail.ParseAddress(n.from); err != nil {
+		log.Printf("[WARNING] SMTP_FROM %q is not a valid address: %v", n.from, err)
+	} else {
+		n.sender = parsed.Address
+	}
+	if n.baseURL == "" {
+		n.baseURL = fmt.Sprintf("http://%s:%d", cfg.NodeName, cfg.GatewayPort)
+	}
+	if cfg.SMTPUsername != "" {
+		// Refuses to send the password unless the connection is TLS or to localhost
+		n.auth = smtp.PlainAuth("", cfg.SMTPUsername, cfg.SMTPPassword, cfg.SMTPHost)
+	}
+	go n.run()
+
+	log.Printf("[Email] Emailing summaries of jobs and batches taking over %s via %s", n.minDuration, n.addr)
+	return n
+}
+
+// checkNotifyEmail rejects a notify_email that isn't a bare address, or that
+// this gateway can't send to
+func (s *Server) checkNotifyEmail(address string) error {
+	if address == "" {
+		return nil
+	}
+	if s.email == nil {
+		return fmt.Errorf("notify_email needs email notifications, which this gateway doesn't send (no SMTP_HOST)")
+	}
+	if parsed, err := mail.ParseAddress(address); err != nil || parsed.Address != address {
+		return fmt.Errorf("notify_email %q is not an email address", address)
+	}
+	return nil
+}
+
+// Notify emails the summary of a finished job that asked for one and took long
+// enough. It is a TransitionListener.
+func (n *EmailNotifier) Notify(job JobRecord) {
+	if job.Request.NotifyEmail == "" || job.CompletedAt.IsZero() || job.CompletedAt.Sub(job.SubmittedAt) < n.minDuration {
+		return
+	}
+
+	var body bytes.Buffer
+	fmt.Fprintf(&body, "Job %s (%s) %s.\n\n", job.ID, operationName(&job.Request), job.Status)
+	switch {
+	case job.Response != nil && job.Response.Output != nil && job.Response.Output.Type != protocol.ResultTypeFloat:
+		fmt.Fprintf(&body, "Result:    a %s result, in the job record\n", job.Response.Output.Type)
+	case job.Response != nil:
+		fmt.Fprintf(&body, "Result:    %s %s\n", strconv.FormatFloat(job.Response.Result, 'f', -1, 64), job.Response.Units["result"])
+		if job.Response.Partial {
+			fmt.Fprintf(&body, "           (partial: the job was stopped before it finished)\n")
+		}
+	case job.Error != "":
+		fmt.Fprintf(&body, "Error:     %s\n", job.Error)
+	}
+	fmt.Fprintf(&body, "Submitted: %s\n", job.SubmittedAt.Format(time.RFC1123))
+	fmt.Fprintf(&body, "Finished:  %s\n", job.CompletedAt.Format(time.RFC1123))
+	fmt.Fprintf(&body, "Took:      %s", job.CompletedAt.Sub(job.SubmittedAt).Round(time.Second))
+	if a := job.Annotations; a != nil {
+		fmt.Fprintf(&body, " (%s queued, %s running)", secondsDuration(a.QueueWait), secondsDuration(a.RunTime))
+	}
+	fmt.Fprintf(&body, "\n\nJob record: %s/jobs/%s\n", n.baseURL, job.ID)
+
+	n.enqueue(emailMessage{
+		kind:    "job",
+		about:   job.ID,
+		to:      job.Request.NotifyEmail,
+		subject: fmt.Sprintf("Job %s %s", job.ID, job.Status),
+		body:    body.String(),
+	})
+}
+
+// NotifyBatch emails the summary of a finished batch that asked for one and
+// took long enough
+func (n *EmailNotifier) NotifyBatch(batch BatchRecord) {
+	if batch.NotifyEmail == "" || batch.FinishedAt.Sub(batch.CreatedAt) < n.minDuration {
+		return
+	}
+
+	counts := make(map[protocol.Status]int)
+	for _, job := range batch.Jobs {
+		counts[job.Status]++
+	}
+	var body bytes.Buffer
+	fmt.Fprintf(&body, "Batch %s finished: %s.\n\n", batch.ID, batch.State)
+	fmt.Fprintf(&body, "Jobs:      %d (%d completed, %d failed, %d cancelled)\n", len(batch.Jobs),
+		counts[protocol.StatusCompleted], counts[protocol.StatusFailed], counts[protocol.StatusCancelled])
+	if batch.FailedJob != "" {
+		fmt.Fprintf(&body, "Failure:   job %s: %s\n", batch.FailedJob, batch.FailureError)
+	}
+	fmt.Fprintf(&body, "Submitted: %s\n", batch.CreatedAt.Format(time.RFC1123))
+	fmt.Fprintf(&body, "Finished:  %s\n", batch.FinishedAt.Format(time.RFC1123))
+	fmt.Fprintf(&body, "Took:      %s\n\n", batch.FinishedAt.Sub(batch.CreatedAt).Round(time.Second))
+	for _, job := range batch.Jobs {
+		fmt.Fprintf(&body, "  %s  %-9s  %s/jobs/%s\n", job.JobID, job.Status, n.baseURL, job.JobID)
+	}
+	fmt.Fprintf(&body, "\nBatch record: %s/batches/%s\n", n.baseURL, batch.ID)
+
+	n.enqueue(emailMessage{
+		kind:    "batch",
+		about:   batch.ID,
+		to:      batch.NotifyEmail,
+		subject: fmt.Sprintf("Batch %s %s", batch.ID, batch.State),
+		body:    body.String(),
+	})
+}
+
+// secondsDuration formats seconds as a duration rounded to the second
+func secondsDuration(seconds float64) time.Duration {
+	return time.Duration(seconds * float64(time.Second)).Round(time.Second)
+}
+
+// enqueue hands an email to the sending goroutine without blocking
+func (n *EmailNotifier) enqueue(msg emailMessage) {
+	select {
+	case n.pending <- msg:
+	default:
+		n.metrics.Inc("orchestrator_emails_total", "kind", msg.kind, "result", "dropped")
+		log.Printf("[Email] Backlog full, dropped the summary of %s", msg.about)
+	}
+}
+
+func (n *EmailNotifier) run() {
+	for msg := range n.pending {
+		n.send(msg)
+	}
+}
+
+// send delivers an email through the relay, retrying with doubling backoff
+func (n *EmailNotifier) send(msg emailMessage) {
+	var data bytes.Buffer
+	fmt.Fprintf(&data, "From: %s\r\n", n.from)
+	fmt.Fprintf(&data, "To: %s\r\n", msg.to)
+	fmt.Fprintf(&data, "Subject: %s\r\n", msg.subject)
+	fmt.Fprintf(&data, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&data, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&data, "Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	data.WriteString(strings.ReplaceAll(msg.body, "\n", "\r\n"))
+
+	backoff := time.Second
+	for attempt := 1; ; attempt++ {
+		err := smtp.SendMail(n.addr, n.auth, n.sender, []string{msg.to}, data.Bytes())
+		if err == nil {
+			n.metrics.Inc("orchestrator_emails_total", "kind", msg.kind, "result", "sent")
+			log.Printf("[Email] Sent the summary of %s to %s", msg.about, msg.to)
+			return
+		}
+		if attempt >= emailAttempts {
+			n.metrics.Inc("orchestrator_emails_total", "kind", msg.kind, "result", "failed")
+			log.Printf("[Email] Giving up on the summary of %s after %d attempt(s): %v", msg.about, attempt, err)
+			return
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
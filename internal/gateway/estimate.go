@@ -0,0 +1,183 @@
+package gateway
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+
+	"github.com/ahmadhassan44/container-orchestrator/internal/worker"
+	"github.com/ahmadhassan44/container-orchestrator/pkg/protocol"
+)
+
+// workerSpawnSeconds is how long the scheduler waits for a freshly spawned worker
+const workerSpawnSeconds = 2.0
+
+// JobEstimate is the predicted cost of a request, computed without running it
+type JobEstimate struct {
+	Queue             string  `json:"queue"`
+	EstimatedCPU      float64 `json:"estimated_cpu"`
+	DurationSeconds   float64 `json:"duration_seconds"`
+	CPUSeconds        float64 `json:"cpu_seconds"`
+	QueueWaitSeconds  float64 `json:"queue_wait_seconds"`
+	StartsImmediately bool    `json:"starts_immediately"`
+	SpawnsWorker      bool    `json:"spawns_worker,omitempty"`
+	TimeSlices        int     `json:"time_slices,omitempty"` // Set when the job would run in slices
+
+	// Percentile ranges around the point estimates above, from how far actual times
+	// of past jobs strayed from theirs
+	Duration   ETARange `json:"duration"`
+	QueueWait  ETARange `json:"queue_wait"`
+	Completion ETARange `json:"completion"` // Queue wait plus duration
+
+	// Worker request timeout for the job (per slice when sliced), and whether
+	// DISPATCH_TIMEOUT_MAX cut it below the estimated duration
+	DispatchTimeoutSeconds float64 `json:"dispatch_timeout_seconds"`
+	TimeoutCapped          bool    `json:"timeout_capped,omitempty"`
+}
+
+// handleEstimate predicts duration, CPU-seconds and queue wait for a request without executing it
+func (s *Server) handleEstimate(w http.ResponseWriter, r *http.Request) {
+	req, err := decodeComputeRequest(w, r)
+	if err != nil {
+		writeDecodeError(w, err)
+		return
+	}
+	if err := s.validateRequest(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if infeasible := s.checkFeasible(&req); infeasible != nil {
+		writeInfeasible(w, infeasible)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.scheduler.Estimate(&req))
+}
+
+// Estimate predicts how a request would be scheduled given the current workers and queues
+func (s *Scheduler) Estimate(req *protocol.ComputeRequest) (estimate JobEstimate) {
+	estimatedCPU := s.estimator.EstimateCPUUsage(req)
+	duration := s.estimator.EstimateJobDuration(req)
+	queue, _ := s.queues.resolve(req.Queue)
+
+	estimate = JobEstimate{
+		Queue:           queue,
+		EstimatedCPU:    estimatedCPU,
+		DurationSeconds: duration,
+		CPUSeconds:      s.EstimateCPUSeconds(req),
+		Duration:        s.eta.runRange(operationName(req), duration),
+	}
+	defer func() { estimate.Completion = addRanges(estimate.QueueWait, estimate.Duration) }()
+	sliced := *req
+	if s.config.TimeSlice > 0 && duration > s.config.TimeSlice && worker.SupportsCheckpoints(req.Operation) {
+		estimate.TimeSlices = int(math.Ceil(duration / s.config.TimeSlice))
+		sliced.SliceTime = s.config.TimeSlice
+	}
+	timeout, capped := s.dispatchTimeout(&sliced)
+	estimate.DispatchTimeoutSeconds = timeout.Seconds()
+	estimate.TimeoutCapped = capped
+
+	// Same placement decision scheduleJobWithQueue would make right now
+	if !s.paused.Load() && s.queues.canRun(queue, estimatedCPU) {
+		if leastLoaded(s.placeableWorkers(duration, queue, operationName(req), req.Tolerations), estimatedCPU, s.config.MaxCPUThreshold) != nil {
+			estimate.StartsImmediately = true
+			estimate.QueueWait = ETARange{Source: "estimate"}
+			return estimate
+		}
+		if _, err := s.orchestrator.GetNextAvailableCoreWhere(s.spawnableFor(queue, operationName(req), req.Tolerations, duration)); err == nil {
+			estimate.StartsImmediately = true
+			estimate.SpawnsWorker = true
+			estimate.QueueWaitSeconds = workerSpawnSeconds
+			estimate.QueueWait = ETARange{P50: workerSpawnSeconds, P90: workerSpawnSeconds, Source: "estimate"}
+			return estimate
+		}
+	}
+
+	estimate.QueueWaitSeconds = s.estimateQueueWait(queue)
+	estimate.QueueWait = s.eta.waitRange(queue, estimate.QueueWaitSeconds)
+	return estimate
+}
+
+// JobETA predicts when a queued or running job will finish (nil if it is neither)
+func (s *Scheduler) JobETA(jobID string) *JobETA {
+	for _, job := range s.ActiveJobs() {
+		if job.JobID == jobID {
+			return &JobETA{QueueWait: ETARange{Source: "estimate"}, Completion: job.RemainingRange}
+		}
+	}
+	for _, job := range s.WaitingJobs() {
+		if job.JobID == jobID {
+			wait := s.eta.waitRange(job.Queue, s.estimateQueueWaitBefore(job.Queue, jobID))
+			return &JobETA{
+				QueueWait:  wait,
+				Completion: addRanges(wait, s.eta.runRange(job.Operation, job.WorkSeconds)),
+			}
+		}
+	}
+	return nil
+}
+
+// observeTimes feeds a successful job's actual run and queue-wait times to the ETA model
+func (s *Scheduler) observeTimes(req *protocol.ComputeRequest) {
+	s.runningMu.Lock()
+	a, exists := s.annotations[req.JobID]
+	var observed protocol.JobAnnotations
+	if exists {
+		observed = *a
+	}
+	s.runningMu.Unlock()
+
+	// Retries and backoff make a job's times say little about the estimates
+	if exists && observed.Attempts <= 1 {
+		s.eta.observe(operationName(req), &observed)
+	}
+}
+
+// operationName is the request's operation, defaulted
+func operationName(req *protocol.ComputeRequest) string {
+	if req.Operation == "" {
+		return protocol.DefaultOperation
+	}
+	return req.Operation
+}
+
+// EstimateCPUSeconds is the CPU time a request is expected to consume, in core-seconds
+// (estimated CPU share times estimated duration)
+func (s *Scheduler) EstimateCPUSeconds(req *protocol.ComputeRequest) float64 {
+	return s.estimator.EstimateCPUUsage(req) / 100 * s.estimator.EstimateJobDuration(req)
+}
+
+// estimateQueueWait is a rough wait for a job joining the back of queue: until the
+// first running job frees capacity, plus the work already queued ahead of it run
+// through the queue's share of worker capacity
+func (s *Scheduler) estimateQueueWait(queue string) float64 {
+	return s.estimateQueueWaitBefore(queue, "")
+}
+
+// estimateQueueWaitBefore is estimateQueueWait counting only the work queued ahead
+// of jobID (all of it when jobID is empty or not waiting in queue)
+func (s *Scheduler) estimateQueueWaitBefore(queue, jobID string) float64 {
+	firstFree := 0.0
+	for i, job := range s.RunningJobs() {
+		if i == 0 || job.RemainingSeconds < firstFree {
+			firstFree = job.RemainingSeconds
+		}
+	}
+
+	aheadCPUSeconds := 0.0
+	for _, job := range s.WaitingJobs() {
+		if job.JobID == jobID && jobID != "" {
+			break
+		}
+		if job.Queue == queue {
+			aheadCPUSeconds += job.EstimatedCPU / 100 * job.WorkSeconds
+		}
+	}
+
+	share := s.queues.queues[queue].config.WorkerShare * s.queues.totalCapacity() / 100
+	if share <= 0 {
+		return firstFree
+	}
+	return firstFree + aheadCPUSeconds/share
+}
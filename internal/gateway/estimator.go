@@ -1,9 +1,29 @@
 package gateway
 
 import (
+	"github.com/ahmadhassan44/container-orchestrator/internal/worker"
 	"github.com/ahmadhassan44/container-orchestrator/pkg/protocol"
 )
 
+// iterationRates are rough per-worker throughputs (iterations/second) used to
+// estimate how long fixed-iteration jobs take; kept on the conservative side
+var iterationRates = map[string]float64{
+	"monte_carlo_pi": 50e6,
+	"prime_search":   2e6,
+}
+
+// defaultIterationRate is assumed for iterative operations without a measured rate
+const defaultIterationRate = 1e6
+
+// Rough per-CPU throughputs of the resource load operations, used to estimate
+// their CPU when they come without a cpu_load hint; kept on the conservative side
+const (
+	memoryBandwidthPerCPU = 2000.0  // MiB/s one CPU rewrites a working set at
+	diskIOPSPerCPU        = 10000.0 // Small-block operations per second one CPU issues
+	networkMbpsPerCPU     = 2000.0  // Megabits per second one CPU streams over a container network
+	minResourceLoadCPU    = 5.0     // Pacing, allocation and syscalls aren't free
+)
+
 // CPUEstimator calculates expected CPU usage for different operations
 type CPUEstimator struct {
 	// No calibration needed - we use client-specified CPU load directly
@@ -16,6 +36,21 @@ func NewCPUEstimator() *CPUEstimator {
 // EstimateCPUUsage returns expected CPU percentage (0-100) for a given request
 // Now directly uses the client-specified cpu_load value
 func (e *CPUEstimator) EstimateCPUUsage(req *protocol.ComputeRequest) float64 {
+	if req.CPULoad == 0 {
+		switch {
+		case req.Memory != nil:
+			return resourceLoadCPU(req.Memory.BandwidthMBps, memoryBandwidthPerCPU)
+		case req.DiskIO != nil:
+			return resourceLoadCPU(req.DiskIO.IOPS, diskIOPSPerCPU)
+		case req.Network != nil:
+			return resourceLoadCPU(req.Network.RateMbps, networkMbpsPerCPU)
+		case worker.IsIterative(req.Operation), req.Matrix != nil:
+			// Iterative operations and matrix elimination keep every thread busy
+			// unless told otherwise
+			return 100.0
+		}
+	}
+
 	// Validate CPU load is within bounds
 	if req.CPULoad < 0 {
 		return 0.0
@@ -28,8 +63,27 @@ func (e *CPUEstimator) EstimateCPUUsage(req *protocol.ComputeRequest) float64 {
 
 // EstimateJobDuration returns expected execution time in seconds
 func (e *CPUEstimator) EstimateJobDuration(req *protocol.ComputeRequest) float64 {
+	if req.TimeBudget > 0 {
+		return req.TimeBudget
+	}
+	if req.Iterations > 0 {
+		rate, known := iterationRates[req.Operation]
+		if !known {
+			rate = defaultIterationRate
+		}
+		return float64(req.Iterations) / rate
+	}
 	if req.LoadTime < 0 {
 		return 0.0
 	}
 	return req.LoadTime
 }
+
+// resourceLoadCPU models a paced resource load's CPU as its rate's share of what
+// one CPU sustains; an unpaced one runs flat out
+func resourceLoadCPU(rate, perCPU float64) float64 {
+	if rate <= 0 {
+		return 100.0
+	}
+	return min(max(rate/perCPU*100, minResourceLoadCPU), 100)
+}
@@ -0,0 +1,146 @@
+package gateway
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/pkg/stdcopy"
+)
+
+// maxExitedWorkers bounds how many exited workers are remembered for /workers
+const maxExitedWorkers = 20
+
+// ExitDiagnostics explains why a worker container stopped, gathered from the runtime
+type ExitDiagnostics struct {
+	ExitCode   int       `json:"exit_code"`
+	OOMKilled  bool      `json:"oom_killed"`
+	Error      string    `json:"error,omitempty"` // Runtime-reported error, if any
+	FinishedAt time.Time `json:"finished_at,omitzero"`
+	Logs       []string  `json:"logs"` // Last lines of container output
+}
+
+func (d *ExitDiagnostics) String() string {
+	msg := fmt.Sprintf("exit code %d", d.ExitCode)
+	if d.OOMKilled {
+		msg += ", OOM killed"
+	}
+	if d.Error != "" {
+		msg += ", " + d.Error
+	}
+	return msg
+}
+
+// ExitedWorker is the record of a worker container that exited unexpectedly
+type ExitedWorker struct {
+	CoreID      int              `json:"core_id"`
+	ContainerID string           `json:"container_id"`
+	WorkerUUID  string           `json:"worker_uuid,omitempty"`
+	HostPort    int              `json:"host_port"`
+	Version     string           `json:"version,omitempty"`
+	DetectedAt  time.Time        `json:"detected_at"`
+	Exit        *ExitDiagnostics `json:"exit"`
+}
+
+// CheckWorkerExit inspects a worker that failed a request or health probe. If its
+// container is no longer running, the exit code, OOM flag and log tail are recorded,
+// the dead container is removed and its core freed. Returns the diagnostics, or nil
+// if the container is still running (or couldn't be inspected).
+func (o *Orchestrator) CheckWorkerExit(coreID int, containerID string) *ExitDiagnostics {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	// Another failed request may have already handled this exit
+	for _, exited := range o.exitedWorkers {
+		if exited.ContainerID == containerID[:12] {
+			return exited.Exit
+		}
+	}
+
+	worker, exists := o.workers[coreID]
+	if !exists || worker.ContainerID != containerID {
+		return nil
+	}
+
+	inspect, err := o.cli.ContainerInspect(o.ctx, containerID)
+	if err != nil || inspect.ContainerJSONBase == nil || inspect.State == nil {
+		return nil
+	}
+	if inspect.State.Running {
+		return nil
+	}
+
+	diag := &ExitDiagnostics{
+		ExitCode:  inspect.State.ExitCode,
+		OOMKilled: inspect.State.OOMKilled,
+		Error:     inspect.State.Error,
+		Logs:      o.containerLogTail(containerID),
+	}
+	if finishedAt, err := time.Parse(time.RFC3339Nano, inspect.State.FinishedAt); err == nil && !finishedAt.IsZero() {
+		diag.FinishedAt = finishedAt
+	}
+
+	o.exitedWorkers = append(o.exitedWorkers, ExitedWorker{
+		CoreID:      coreID,
+		ContainerID: containerID[:12],
+		WorkerUUID:  worker.UUID,
+		HostPort:    worker.HostPort,
+		Version:     worker.Version,
+		DetectedAt:  time.Now(),
+		Exit:        diag,
+	})
+	if len(o.exitedWorkers) > maxExitedWorkers {
+		o.exitedWorkers = o.exitedWorkers[len(o.exitedWorkers)-maxExitedWorkers:]
+	}
+	delete(o.workers, coreID)
+
+	log.Printf("[Audit] Worker on Core %d (container %s) exited unexpectedly: %s", coreID, containerID[:12], diag)
+	for _, line := range diag.Logs {
+		log.Printf("[Audit]   %s", line)
+	}
+
+	if err := o.cli.ContainerRemove(o.ctx, containerID, container.RemoveOptions{Force: true}); err != nil {
+		log.Printf("[WARNING] Failed to remove exited container %s: %v", containerID[:12], err)
+	}
+	return diag
+}
+
+// containerLogTail returns the last exitLogLines lines of a container's stdout and stderr
+func (o *Orchestrator) containerLogTail(containerID string) []string {
+	if o.exitLogLines <= 0 {
+		return []string{}
+	}
+
+	reader, err := o.cli.ContainerLogs(o.ctx, containerID, container.LogsOptions{
+		ShowStdout: true,
+		ShowStderr: true,
+		Tail:       strconv.Itoa(o.exitLogLines),
+	})
+	if err != nil {
+		return []string{fmt.Sprintf("(logs unavailable: %v)", err)}
+	}
+	defer reader.Close()
+
+	// Worker containers run without a TTY, so stdout and stderr arrive multiplexed
+	var output bytes.Buffer
+	if _, err := stdcopy.StdCopy(&output, &output, reader); err != nil {
+		return []string{fmt.Sprintf("(logs unreadable: %v)", err)}
+	}
+
+	text := strings.TrimRight(output.String(), "\n")
+	if text == "" {
+		return []string{}
+	}
+	return strings.Split(text, "\n")
+}
+
+// ExitedWorkers returns recently exited workers, oldest first
+func (o *Orchestrator) ExitedWorkers() []ExitedWorker {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	return append([]ExitedWorker{}, o.exitedWorkers...)
+}
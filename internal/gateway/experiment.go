@@ -0,0 +1,327 @@
+package gateway
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"math"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ahmadhassan44/container-orchestrator/pkg/config"
+	"github.com/ahmadhassan44/container-orchestrator/pkg/protocol"
+)
+
+// strategyFunc picks a worker with room for estimatedCPU under threshold (nil if none)
+type strategyFunc func(workers []*WorkerInfo, estimatedCPU, threshold float64) *WorkerInfo
+
+// placementStrategies are the worker selection strategies jobs can be routed through
+var placementStrategies = map[string]strategyFunc{
+	"least_loaded": leastLoaded, // Spread: the worker with the lowest CPU
+	"most_loaded":  mostLoaded,  // Pack: the busiest worker the job still fits on
+	"first_fit":    firstFit,    // The lowest core the job fits on
+}
+
+// Strategies compare load relative to each worker's capacity, so a resized
+// worker takes proportionally more
+
+func leastLoaded(workers []*WorkerInfo, estimatedCPU, threshold float64) *WorkerInfo {
+	var bestWorker *WorkerInfo
+	var lowestLoad float64 = math.Inf(1)
+
+	for _, worker := range workers {
+		// Check if this worker can handle the load without exceeding threshold
+		if worker.fits(estimatedCPU, threshold) && worker.load() < lowestLoad {
+			lowestLoad = worker.load()
+			bestWorker = worker
+		}
+	}
+	return bestWorker
+}
+
+func mostLoaded(workers []*WorkerInfo, estimatedCPU, threshold float64) *WorkerInfo {
+	var bestWorker *WorkerInfo
+	for _, worker := range workers {
+		if !worker.fits(estimatedCPU, threshold) {
+			continue
+		}
+		if bestWorker == nil || worker.load() > bestWorker.load() ||
+			(worker.load() == bestWorker.load() && worker.CoreID < bestWorker.CoreID) {
+			bestWorker = worker
+		}
+	}
+	return bestWorker
+}
+
+func firstFit(workers []*WorkerInfo, estimatedCPU, threshold float64) *WorkerInfo {
+	var bestWorker *WorkerInfo
+	for _, worker := range workers {
+		if worker.fits(estimatedCPU, threshold) && (bestWorker == nil || worker.CoreID < bestWorker.CoreID) {
+			bestWorker = worker
+		}
+	}
+	return bestWorker
+}
+
+// strategyNames lists the placement strategies, sorted
+func strategyNames() []string {
+	names := make([]string, 0, len(placementStrategies))
+	for name := range placementStrategies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// experimentArm accumulates outcomes of the jobs routed through one strategy
+type experimentArm struct {
+	jobs       map[string]int // By final status
+	waits      waitWindow     // Queue waits of jobs that reached a worker
+	runTime    float64        // Sum over jobs that reached a worker
+	ran        int
+	placed     map[string]int // Placements on arrival, by kind
+	dispatched int            // Dispatches to a worker, on arrival or from a queue
+	projected  float64        // Sum of the chosen worker's CPU after dispatch, over the threshold
+}
+
+func newExperimentArm() *experimentArm {
+	return &experimentArm{jobs: make(map[string]int), placed: make(map[string]int)}
+}
+
+// Experiment routes a share of jobs through an alternative placement strategy
+// and compares their outcomes with those placed by the default one. Jobs are
+// assigned by a hash of their ID, so a job keeps its strategy across retries.
+type Experiment struct {
+	control string // The default strategy
+	metrics *Metrics
+
+	mu        sync.Mutex
+	treatment string  // Strategy under test ("" = no experiment)
+	percent   float64 // Share of jobs routed through it (0-100)
+	started   time.Time
+	arms      map[string]*experimentArm
+}
+
+func NewExperiment(cfg *config.Config, metrics *Metrics) *Experiment {
+	metrics.Register("orchestrator_strategy_jobs_total", metricCounter, "Finished jobs, by placement strategy and final status")
+
+	e := &Experiment{control: placementStrategy, metrics: metrics}
+	e.reset("", 0)
+	if cfg.ExperimentStrategy != "" {
+		if err := e.Set(cfg.ExperimentStrategy, cfg.ExperimentPercent); err != nil {
+			log.Printf("[WARNING] Ignoring EXPERIMENT_STRATEGY: %v", err)
+		}
+	}
+	return e
+}
+
+// Set starts an experiment routing percent of jobs through strategy. Results of
+// any previous experiment are discarded.
+func (e *Experiment) Set(strategy string, percent float64) error {
+	if _, known := placementStrategies[strategy]; !known {
+		return fmt.Errorf("unknown strategy %q (valid: %v)", strategy, strategyNames())
+	}
+	if strategy == e.control {
+		return fmt.Errorf("%q is already the default strategy", strategy)
+	}
+	if percent < 0 || percent > 100 {
+		return fmt.Errorf("percent must be between 0 and 100")
+	}
+
+	e.mu.Lock()
+	e.reset(strategy, percent)
+	e.mu.Unlock()
+
+	log.Printf("[Experiment] Routing %.1f%% of jobs through %s (control: %s)", percent, strategy, e.control)
+	return nil
+}
+
+// Stop ends the experiment; every new job uses the default strategy again
+func (e *Experiment) Stop() {
+	e.mu.Lock()
+	e.reset("", 0)
+	e.mu.Unlock()
+	log.Printf("[Experiment] Stopped; all jobs use %s", e.control)
+}
+
+func (e *Experiment) reset(treatment string, percent float64) {
+	e.treatment, e.percent, e.started = treatment, percent, time.Now()
+	e.arms = map[string]*experimentArm{e.control: newExperimentArm()}
+	if treatment != "" {
+		e.arms[treatment] = newExperimentArm()
+	}
+}
+
+// Assign picks the strategy for a job
+func (e *Experiment) Assign(jobID string) string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.treatment == "" || jobID == "" {
+		return e.control
+	}
+	h := fnv.New32a()
+	h.Write([]byte(jobID))
+	if float64(h.Sum32()%10000) < e.percent*100 {
+		return e.treatment
+	}
+	return e.control
+}
+
+// recordDecision counts a scheduling decision under the job's strategy
+func (e *Experiment) recordDecision(d SchedulingDecision, worker *WorkerInfo, threshold float64) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	arm, exists := e.arms[d.Strategy]
+	if !exists {
+		return // Assigned before the experiment changed
+	}
+	if d.Event != telemetryDequeue {
+		arm.placed[d.Placement]++
+	}
+	if worker != nil && threshold > 0 {
+		arm.dispatched++
+		arm.projected += (worker.CurrentCPU + d.EstimatedCPU) / (threshold * worker.capacity())
+	}
+}
+
+// RecordOutcome counts a finished job under its strategy. It is a TransitionListener.
+func (e *Experiment) RecordOutcome(job JobRecord) {
+	switch job.Status {
+	case protocol.StatusCompleted, protocol.StatusFailed, protocol.StatusCancelled:
+	default:
+		return
+	}
+	a := job.Annotations
+	if a == nil {
+		return // Rejected before scheduling
+	}
+	e.metrics.Inc("orchestrator_strategy_jobs_total", "strategy", a.Strategy, "status", job.Status.String())
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	arm, exists := e.arms[a.Strategy]
+	if !exists || job.SubmittedAt.Before(e.started) {
+		return
+	}
+	arm.jobs[job.Status.String()]++
+	if a.RunTime > 0 {
+		arm.waits.add(a.QueueWait)
+		arm.runTime += a.RunTime
+		arm.ran++
+	}
+}
+
+// Status reports the experiment's configuration and each arm's comparative metrics
+func (e *Experiment) Status() map[string]interface{} {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	arms := make(map[string]interface{}, len(e.arms))
+	for name, arm := range e.arms {
+		finished := 0
+		for _, count := range arm.jobs {
+			finished += count
+		}
+		placements := 0
+		for _, count := range arm.placed {
+			placements += count
+		}
+
+		stats := map[string]interface{}{
+			"jobs":       finished,
+			"by_status":  copyCounts(arm.jobs),
+			"placements": copyCounts(arm.placed),
+			"wait_p50":   arm.waits.quantile(0.5),
+			"wait_p90":   arm.waits.quantile(0.9),
+			"wait_p99":   arm.waits.quantile(0.99),
+		}
+		stats["failure_rate"] = ratio(arm.jobs[protocol.StatusFailed.String()], finished)
+		stats["spawn_rate"] = ratio(arm.placed[protocol.PlacementSpawned], placements)
+		stats["mean_run_time"] = 0.0
+		if arm.ran > 0 {
+			stats["mean_run_time"] = arm.runTime / float64(arm.ran)
+		}
+		// How full the chosen worker was after placement, relative to MAX_CPU_THRESHOLD
+		stats["mean_worker_utilization"] = 0.0
+		if arm.dispatched > 0 {
+			stats["mean_worker_utilization"] = arm.projected / float64(arm.dispatched)
+		}
+		arms[name] = stats
+	}
+
+	return map[string]interface{}{
+		"active":     e.treatment != "",
+		"control":    e.control,
+		"treatment":  e.treatment,
+		"percent":    e.percent,
+		"started_at": e.started,
+		"strategies": strategyNames(),
+		"arms":       arms,
+	}
+}
+
+func copyCounts(counts map[string]int) map[string]int {
+	cp := make(map[string]int, len(counts))
+	for key, count := range counts {
+		cp[key] = count
+	}
+	return cp
+}
+
+func ratio(n, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(n) / float64(total)
+}
+
+// jobStrategy is the placement strategy assigned to a job in ScheduleJob
+func (s *Scheduler) jobStrategy(jobID string) string {
+	s.runningMu.Lock()
+	defer s.runningMu.Unlock()
+
+	if a, exists := s.annotations[jobID]; exists && a.Strategy != "" {
+		return a.Strategy
+	}
+	return placementStrategy
+}
+
+// handleGetExperiment reports the strategy experiment and per-strategy results
+func (s *Server) handleGetExperiment(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.scheduler.experiment.Status())
+}
+
+// handleSetExperiment starts an experiment: {"strategy": "most_loaded", "percent": 10}
+func (s *Server) handleSetExperiment(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Strategy string   `json:"strategy"`
+		Percent  *float64 `json:"percent"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.Strategy == "" || body.Percent == nil {
+		http.Error(w, "Body must be JSON with \"strategy\" and \"percent\"", http.StatusBadRequest)
+		return
+	}
+	if err := s.scheduler.experiment.Set(body.Strategy, *body.Percent); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.scheduler.experiment.Status())
+}
+
+// handleStopExperiment ends the experiment, returning its final results
+func (s *Server) handleStopExperiment(w http.ResponseWriter, r *http.Request) {
+	status := s.scheduler.experiment.Status()
+	s.scheduler.experiment.Stop()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(status)
+}
@@ -0,0 +1,133 @@
+package gateway
+
+import (
+	"math"
+	"slices"
+	"time"
+)
+
+const (
+	// waitWindowSize is how many recent queue waits each queue's distribution covers
+	waitWindowSize = 500
+
+	// starvationMinSamples is how many waits a queue needs before its median is
+	// trusted to judge starvation
+	starvationMinSamples = 10
+
+	// starvationMinWait keeps jobs from being flagged while the median is tiny
+	starvationMinWait = time.Second
+)
+
+// waitWindow keeps the most recent queue waits (seconds) of one queue
+type waitWindow struct {
+	samples []float64
+	next    int       // Ring position of the next sample once full
+	sorted  []float64 // Sorted copy of samples, rebuilt lazily (nil = stale)
+}
+
+func (w *waitWindow) add(seconds float64) {
+	if len(w.samples) < waitWindowSize {
+		w.samples = append(w.samples, seconds)
+	} else {
+		w.samples[w.next] = seconds
+		w.next = (w.next + 1) % waitWindowSize
+	}
+	w.sorted = nil
+}
+
+// quantile returns the q-quantile of the recent waits (0 with no samples)
+func (w *waitWindow) quantile(q float64) float64 {
+	if len(w.samples) == 0 {
+		return 0
+	}
+	if w.sorted == nil {
+		w.sorted = slices.Clone(w.samples)
+		slices.Sort(w.sorted)
+	}
+	i := int(math.Ceil(q*float64(len(w.sorted)))) - 1
+	return w.sorted[min(max(i, 0), len(w.sorted)-1)]
+}
+
+// recordWait adds a dispatched job's time in its queue to the queue's distribution
+func (qs *queueSet) recordWait(name string, wait time.Duration) {
+	qs.mu.Lock()
+	defer qs.mu.Unlock()
+	qs.queues[name].waits.add(wait.Seconds())
+}
+
+// starvationThresholdLocked is how long a job may wait in q before it counts as
+// starving: STARVATION_FACTOR times the queue's median wait. Returns 0 while
+// detection is off or the queue has too few samples (caller holds qs.mu).
+func (qs *queueSet) starvationThresholdLocked(q *namedQueue) time.Duration {
+	if qs.starvationFactor <= 0 || len(q.waits.samples) < starvationMinSamples {
+		return 0
+	}
+	threshold := time.Duration(qs.starvationFactor * q.waits.quantile(0.5) * float64(time.Second))
+	return max(threshold, starvationMinWait)
+}
+
+// markStarving flags waiting jobs that have crossed their queue's starvation
+// threshold and returns those flagged for the first time
+func (qs *queueSet) markStarving() []*QueuedJob {
+	qs.mu.Lock()
+	defer qs.mu.Unlock()
+
+	var marked []*QueuedJob
+	for _, q := range qs.queues {
+		threshold := qs.starvationThresholdLocked(q)
+		if threshold == 0 {
+			continue
+		}
+		for _, job := range q.items {
+			if !job.starving && time.Since(job.enqueuedAt) > threshold {
+				job.starving = true
+				marked = append(marked, job)
+			}
+		}
+	}
+	return marked
+}
+
+// effectiveWeightLocked is a queue's round-robin weight for this pick: its
+// configured weight, multiplied by STARVATION_BOOST while its head job is starving
+func (qs *queueSet) effectiveWeightLocked(q *namedQueue) int {
+	if qs.starvationBoost > 1 && q.items[0].starving {
+		return int(math.Ceil(float64(q.config.Weight) * qs.starvationBoost))
+	}
+	return q.config.Weight
+}
+
+// fairness reports each queue's recent wait distribution and starving job count
+func (qs *queueSet) fairness() map[string]QueueFairness {
+	qs.mu.Lock()
+	defer qs.mu.Unlock()
+
+	report := make(map[string]QueueFairness, len(qs.queues))
+	for name, q := range qs.queues {
+		starving := 0
+		for _, job := range q.items {
+			if job.starving {
+				starving++
+			}
+		}
+		report[name] = QueueFairness{
+			Samples:           len(q.waits.samples),
+			WaitP50:           q.waits.quantile(0.5),
+			WaitP90:           q.waits.quantile(0.9),
+			WaitP99:           q.waits.quantile(0.99),
+			StarvingThreshold: qs.starvationThresholdLocked(q).Seconds(),
+			Starving:          starving,
+		}
+	}
+	return report
+}
+
+// QueueFairness is one queue's recent wait distribution and starvation state
+type QueueFairness struct {
+	Samples           int     `json:"samples"` // Dispatched jobs the percentiles cover
+	WaitP50           float64 `json:"wait_p50"`
+	WaitP90           float64 `json:"wait_p90"`
+	WaitP99           float64 `json:"wait_p99"`
+	StarvingThreshold float64 `json:"starving_threshold"` // Seconds (0 = not enough samples yet)
+	Starving          int     `json:"starving"`           // Waiting jobs past the threshold
+}
@@ -0,0 +1,108 @@
+package gateway
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+
+	"github.com/ahmadhassan44/container-orchestrator/pkg/protocol"
+)
+
+// Admission limits an infeasible job can exceed
+const (
+	limitDuration   = "max_job_duration"
+	limitIterations = "max_iterations"
+)
+
+// InfeasibleJobError rejects a job that can't reasonably finish as submitted, with
+// how to split it into jobs that can
+type InfeasibleJobError struct {
+	Limit             string           `json:"limit"` // limitDuration or limitIterations
+	Message           string           `json:"message"`
+	EstimatedDuration float64          `json:"estimated_duration"` // Seconds
+	MaxDuration       float64          `json:"max_duration,omitempty"`
+	Iterations        int64            `json:"iterations,omitempty"`
+	MaxIterations     int64            `json:"max_iterations,omitempty"`
+	Suggestion        *ShardSuggestion `json:"suggestion,omitempty"`
+	Job               *int             `json:"job,omitempty"` // Index within a batch
+}
+
+func (e *InfeasibleJobError) Error() string {
+	return e.Message
+}
+
+// ShardSuggestion splits an infeasible job into Shards jobs that each fit the limits
+type ShardSuggestion struct {
+	Shards     int     `json:"shards"`
+	Iterations int64   `json:"iterations,omitempty"`  // Per shard
+	TimeBudget float64 `json:"time_budget,omitempty"` // Per shard
+	LoadTime   float64 `json:"load_time,omitempty"`   // Per shard
+	Hint       string  `json:"hint"`
+}
+
+// checkFeasible rejects requests whose iterations or estimated duration exceed
+// the admission limits (nil when the job fits)
+func (s *Server) checkFeasible(req *protocol.ComputeRequest) *InfeasibleJobError {
+	maxDuration, maxIterations := s.scheduler.config.MaxJobDuration, s.scheduler.config.MaxIterations
+	duration := s.scheduler.estimator.EstimateJobDuration(req)
+
+	e := &InfeasibleJobError{EstimatedDuration: duration}
+	switch {
+	case math.IsInf(duration, 0) || math.IsNaN(duration):
+		e.Limit = limitDuration
+		e.Message = "estimated duration overflows"
+		return e
+	case maxIterations > 0 && req.Iterations > maxIterations:
+		e.Limit = limitIterations
+		e.Message = fmt.Sprintf("%d iterations exceed the limit of %d", req.Iterations, maxIterations)
+	case maxDuration > 0 && duration > maxDuration:
+		e.Limit = limitDuration
+		e.Message = fmt.Sprintf("estimated duration %.0fs exceeds the limit of %.0fs", duration, maxDuration)
+	default:
+		return nil
+	}
+	if maxDuration > 0 {
+		e.MaxDuration = maxDuration
+	}
+	e.Iterations, e.MaxIterations = req.Iterations, maxIterations
+
+	// Enough shards to bring each under both limits
+	shards := 1.0
+	if maxDuration > 0 {
+		shards = math.Ceil(duration / maxDuration)
+	}
+	if maxIterations > 0 && req.Iterations > 0 {
+		shards = max(shards, math.Ceil(float64(req.Iterations)/float64(maxIterations)))
+	}
+	suggestion := &ShardSuggestion{Shards: int(shards)}
+	switch {
+	case req.Iterations > 0:
+		suggestion.Iterations = int64(math.Ceil(float64(req.Iterations) / shards))
+		suggestion.Hint = fmt.Sprintf("submit %d jobs of %d iterations (e.g. as one POST /batches) and combine their results",
+			suggestion.Shards, suggestion.Iterations)
+	case req.TimeBudget > 0:
+		suggestion.TimeBudget = math.Floor(req.TimeBudget/shards*1000) / 1000
+		suggestion.Hint = fmt.Sprintf("submit %d jobs with a time_budget of %gs (e.g. as one POST /batches) and combine their results",
+			suggestion.Shards, suggestion.TimeBudget)
+	default:
+		suggestion.LoadTime = math.Floor(req.LoadTime/shards*1000) / 1000
+		suggestion.Hint = fmt.Sprintf("submit %d jobs with a load_time of %gs (e.g. as one POST /batches)",
+			suggestion.Shards, suggestion.LoadTime)
+	}
+	if req.Seed != 0 {
+		suggestion.Hint += "; give each shard its own seed"
+	}
+	e.Suggestion = suggestion
+	return e
+}
+
+// writeInfeasible responds 422 with the structured rejection
+func writeInfeasible(w http.ResponseWriter, e *InfeasibleJobError) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusUnprocessableEntity)
+	json.NewEncoder(w).Encode(struct {
+		Error string `json:"error"`
+		*InfeasibleJobError
+	}{"infeasible_job", e})
+}
@@ -0,0 +1,285 @@
+package gateway
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ahmadhassan44/container-orchestrator/pkg/config"
+)
+
+// peerFetchTimeout bounds each peer request so one slow gateway can't stall the cluster view
+const peerFetchTimeout = 3 * time.Second
+
+// Federation builds cluster-wide views by combining this gateway's /status and
+// /metrics with those of configured peer gateways
+type Federation struct {
+	nodeName   string
+	peers      []string
+	httpClient *http.Client
+
+	forward   bool         // Forward jobs to peers when this gateway is at capacity
+	secret    string       // Signs jobs forwarded to and from peers (empty = none accepted)
+	jobClient *http.Client // Forwarded jobs run as long as they take
+}
+
+func NewFederation(cfg *config.Config) *Federation {
+	trimmed := make([]string, 0, len(cfg.PeerGateways))
+	for _, peer := range cfg.PeerGateways {
+		trimmed = append(trimmed, strings.TrimRight(peer, "/"))
+	}
+
+	forward := cfg.ForwardToPeers && len(trimmed) > 0
+	if forward && cfg.PeerSecret == "" {
+		log.Printf("[WARNING] FORWARD_TO_PEERS needs PEER_SECRET; jobs won't be forwarded")
+		forward = false
+	}
+
+	return &Federation{
+		nodeName:   cfg.NodeName,
+		peers:      trimmed,
+		httpClient: &http.Client{Timeout: peerFetchTimeout},
+		forward:    forward,
+		secret:     cfg.PeerSecret,
+		jobClient:  &http.Client{},
+	}
+}
+
+// peerResult is the outcome of fetching one endpoint from one peer
+type peerResult struct {
+	peer string
+	body []byte
+	err  error
+}
+
+// fetchAll GETs path from every peer concurrently
+func (f *Federation) fetchAll(path string) []peerResult {
+	results := make([]peerResult, len(f.peers))
+
+	var wg sync.WaitGroup
+	for i, peer := range f.peers {
+		wg.Add(1)
+		go func(i int, peer string) {
+			defer wg.Done()
+			results[i] = peerResult{peer: peer}
+
+			resp, err := f.httpClient.Get(peer + path)
+			if err != nil {
+				results[i].err = err
+				return
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != http.StatusOK {
+				results[i].err = fmt.Errorf("peer returned status %d", resp.StatusCode)
+				return
+			}
+			results[i].body, results[i].err = io.ReadAll(resp.Body)
+		}(i, peer)
+	}
+	wg.Wait()
+
+	return results
+}
+
+// ClusterStatus merges the local status with every peer's /status, plus cluster totals
+func (f *Federation) ClusterStatus(local map[string]interface{}) map[string]interface{} {
+	nodes := map[string]interface{}{f.nodeName: local}
+	totalWorkers, totalQueued := statusTotals(local)
+	reachable := 1
+
+	for _, result := range f.fetchAll("/status") {
+		if result.err != nil {
+			nodes[result.peer] = map[string]interface{}{"error": result.err.Error()}
+			continue
+		}
+
+		var status map[string]interface{}
+		if err := json.Unmarshal(result.body, &status); err != nil {
+			nodes[result.peer] = map[string]interface{}{"error": fmt.Sprintf("invalid status: %v", err)}
+			continue
+		}
+
+		nodes[result.peer] = status
+		workers, queued := statusTotals(status)
+		totalWorkers += workers
+		totalQueued += queued
+		reachable++
+	}
+
+	return map[string]interface{}{
+		"nodes": nodes,
+		"totals": map[string]interface{}{
+			"nodes":           len(f.peers) + 1,
+			"reachable_nodes": reachable,
+			"worker_count":    totalWorkers,
+			"queue_size":      totalQueued,
+		},
+	}
+}
+
+// statusTotals extracts worker count and queue depth from a /status document
+// (works for both local maps and decoded JSON, where numbers are float64)
+func statusTotals(status map[string]interface{}) (workers, queued int) {
+	workers = toInt(status["worker_count"])
+	if queue, ok := status["queue"].(map[string]interface{}); ok {
+		queued = toInt(queue["queue_size"])
+	}
+	return workers, queued
+}
+
+func toInt(v interface{}) int {
+	switch n := v.(type) {
+	case int:
+		return n
+	case float64:
+		return int(n)
+	default:
+		return 0
+	}
+}
+
+// WriteClusterMetrics renders local and peer metrics with a node label on every
+// sample, keeping each metric family's samples contiguous as Prometheus requires
+func (f *Federation) WriteClusterMetrics(w io.Writer, local *Metrics) error {
+	var localBuf bytes.Buffer
+	if _, err := local.WriteTo(&localBuf); err != nil {
+		return err
+	}
+
+	merged := newMergedExposition()
+	merged.add(localBuf.Bytes(), f.nodeName)
+
+	merged.meta("orchestrator_federation_peer_up",
+		"# HELP orchestrator_federation_peer_up Whether the peer gateway's /metrics was reachable",
+		"# TYPE orchestrator_federation_peer_up gauge")
+	for _, result := range f.fetchAll("/metrics") {
+		up := 1
+		if result.err != nil {
+			up = 0
+		} else {
+			merged.add(result.body, result.peer)
+		}
+		merged.sample("orchestrator_federation_peer_up",
+			fmt.Sprintf("orchestrator_federation_peer_up{node=%q} %d", result.peer, up))
+	}
+
+	_, err := io.WriteString(w, merged.String())
+	return err
+}
+
+// mergedExposition groups Prometheus text lines from several sources by metric family
+type mergedExposition struct {
+	order    []string
+	families map[string]*mergedFamily
+}
+
+type mergedFamily struct {
+	meta    []string
+	samples []string
+}
+
+func newMergedExposition() *mergedExposition {
+	return &mergedExposition{families: make(map[string]*mergedFamily)}
+}
+
+func (m *mergedExposition) family(name string) *mergedFamily {
+	family, exists := m.families[name]
+	if !exists {
+		family = &mergedFamily{}
+		m.families[name] = family
+		m.order = append(m.order, name)
+	}
+	return family
+}
+
+// meta records HELP/TYPE lines once per family
+func (m *mergedExposition) meta(name string, lines ...string) {
+	family := m.family(name)
+	if len(family.meta) == 0 {
+		family.meta = lines
+	}
+}
+
+func (m *mergedExposition) sample(name, line string) {
+	family := m.family(name)
+	family.samples = append(family.samples, line)
+}
+
+// add parses an exposition, injecting node="..." into each sample
+func (m *mergedExposition) add(exposition []byte, node string) {
+	var pendingMeta []string
+	scanner := bufio.NewScanner(bytes.NewReader(exposition))
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			continue
+		case strings.HasPrefix(line, "# HELP "), strings.HasPrefix(line, "# TYPE "):
+			pendingMeta = append(pendingMeta, line)
+			if fields := strings.Fields(line); len(fields) >= 3 && strings.HasPrefix(line, "# TYPE ") {
+				m.meta(fields[2], pendingMeta...)
+				pendingMeta = nil
+			}
+		case strings.HasPrefix(line, "#"):
+			continue
+		default:
+			name := line
+			if i := strings.IndexAny(line, "{ "); i >= 0 {
+				name = line[:i]
+			}
+			m.sample(name, injectNodeLabel(line, node))
+		}
+	}
+}
+
+func (m *mergedExposition) String() string {
+	var b strings.Builder
+	for _, name := range m.order {
+		family := m.families[name]
+		for _, line := range family.meta {
+			b.WriteString(line + "\n")
+		}
+		for _, line := range family.samples {
+			b.WriteString(line + "\n")
+		}
+	}
+	return b.String()
+}
+
+func injectNodeLabel(sample, node string) string {
+	nodeLabel := fmt.Sprintf("node=%q", node)
+	if i := strings.IndexAny(sample, "{ "); i >= 0 {
+		if sample[i] == '{' {
+			return sample[:i+1] + nodeLabel + "," + sample[i+1:]
+		}
+		return sample[:i] + "{" + nodeLabel + "}" + sample[i:]
+	}
+	return sample
+}
+
+// PeersWithArch returns the peers whose /status reports one of arches, for pointing
+// clients at a gateway that can run an architecture-restricted operation
+func (f *Federation) PeersWithArch(arches []string) []string {
+	var compatible []string
+	for _, result := range f.fetchAll("/status") {
+		if result.err != nil {
+			continue
+		}
+		var status struct {
+			Arch string `json:"arch"`
+		}
+		if json.Unmarshal(result.body, &status) == nil && slices.Contains(arches, status.Arch) {
+			compatible = append(compatible, result.peer)
+		}
+	}
+	return compatible
+}
@@ -0,0 +1,151 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/ahmadhassan44/container-orchestrator/pkg/protocol"
+)
+
+// forceSettleTimeout bounds how long a force request waits for the job's own
+// submission path to record the forced outcome
+const forceSettleTimeout = 10 * time.Second
+
+// forcedOutcome is an admin-imposed result for a job, returned by ScheduleJob in
+// place of whatever the scheduler got
+type forcedOutcome struct {
+	response *protocol.JobResponse
+	err      error
+}
+
+// Force makes a job end with the given outcome: it is stopped wherever it is
+// (queue, retry backoff, placement or a worker, releasing its CPU reservation)
+// and ScheduleJob returns the forced outcome to the submitter. Returns false if
+// the job isn't being scheduled, in which case nothing is waiting on it.
+func (s *Scheduler) Force(jobID string, response *protocol.JobResponse, err error) bool {
+	s.runningMu.Lock()
+	if !s.scheduling[jobID] {
+		s.runningMu.Unlock()
+		return false
+	}
+	s.forced[jobID] = forcedOutcome{response: response, err: err}
+	if _, running := s.running[jobID]; !running {
+		s.cancelOnStart[jobID] = true
+	}
+	s.runningMu.Unlock()
+
+	log.Printf("[Scheduler] Forcing outcome of job %s", jobID)
+	s.Cancel(jobID)
+	return true
+}
+
+// finishScheduling marks a job as out of ScheduleJob and returns its forced outcome, if any
+func (s *Scheduler) finishScheduling(jobID string) (forcedOutcome, bool) {
+	s.runningMu.Lock()
+	defer s.runningMu.Unlock()
+
+	delete(s.scheduling, jobID)
+	forced, exists := s.forced[jobID]
+	delete(s.forced, jobID)
+	return forced, exists
+}
+
+// handleForceFail fails a stuck job: POST /admin/jobs/{id}/force-fail {"reason": "..."}
+func (s *Server) handleForceFail(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Reason string `json:"reason"`
+	}
+	if err := decodeOptionalBody(r, &body); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if body.Reason == "" {
+		body.Reason = "force-failed by admin"
+	}
+
+	s.forceOutcome(w, r, body.Reason, nil, errors.New(body.Reason))
+}
+
+// handleForceComplete completes a stuck job with a supplied result:
+// POST /admin/jobs/{id}/force-complete {"result": 3.14} or {"output": {...}}
+func (s *Server) handleForceComplete(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Result *float64                 `json:"result"`
+		Output *protocol.ResultEnvelope `json:"output"`
+		Reason string                   `json:"reason"`
+	}
+	if err := decodeOptionalBody(r, &body); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if (body.Result == nil) == (body.Output == nil) {
+		http.Error(w, "Body must set exactly one of \"result\" or \"output\"", http.StatusBadRequest)
+		return
+	}
+	if body.Reason == "" {
+		body.Reason = "force-completed by admin"
+	}
+
+	response := &protocol.JobResponse{JobID: r.PathValue("id"), Output: body.Output, TimeTaken: "0s"}
+	if body.Result != nil {
+		response.Result = *body.Result
+		response.Output = protocol.FloatResult(*body.Result)
+	} else if v, ok := body.Output.Float(); ok {
+		response.Result = v
+	}
+
+	s.forceOutcome(w, r, body.Reason, response, nil)
+}
+
+// forceOutcome reconciles a stuck job with a forced outcome. A job the scheduler
+// still holds is stopped and finishes through its normal submission path, so the
+// client, quota, batch and webhooks all see the forced outcome; an orphaned
+// record is finished in the job store directly.
+func (s *Server) forceOutcome(w http.ResponseWriter, r *http.Request, reason string, response *protocol.JobResponse, jobErr error) {
+	id := r.PathValue("id")
+	job, exists := s.jobs.Get(id)
+	if !exists {
+		http.Error(w, "Job not found", http.StatusNotFound)
+		return
+	}
+	if !job.CompletedAt.IsZero() {
+		http.Error(w, fmt.Sprintf("Job already %s", job.Status), http.StatusConflict)
+		return
+	}
+
+	s.jobs.MarkForced(id, reason)
+	if s.scheduler.Force(id, response, jobErr) {
+		ctx, cancel := context.WithTimeout(r.Context(), forceSettleTimeout)
+		defer cancel()
+		job, _ = s.jobs.Wait(ctx, id)
+	} else {
+		if jobErr != nil {
+			s.jobs.Fail(id, jobErr)
+			s.metrics.Inc("orchestrator_jobs_total", "status", "failed")
+		} else {
+			s.jobs.Complete(id, response)
+			s.metrics.Inc("orchestrator_jobs_total", "status", "completed")
+		}
+		s.sources.RecordResult(job.Source, jobErr == nil)
+		s.quotas.Settle(id)
+		job, _ = s.jobs.Get(id)
+	}
+	log.Printf("[Audit] Job %s forced to %s: %s", id, job.Status, reason)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(job)
+}
+
+// decodeOptionalBody decodes a JSON body into v, allowing an empty body
+func decodeOptionalBody(r *http.Request, v interface{}) error {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("invalid JSON body: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,227 @@
+package gateway
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/ahmadhassan44/container-orchestrator/pkg/protocol"
+	"github.com/ahmadhassan44/container-orchestrator/pkg/signing"
+)
+
+// Headers on jobs forwarded between peer gateways (besides the signature's)
+const (
+	headerForwardedFrom = "X-Orchestrator-Forwarded-From" // Node name of the forwarding gateway
+	headerClientIP      = "X-Orchestrator-Client-IP"      // Submitter's IP as the forwarding gateway saw it
+)
+
+// maxForwardedBody bounds a forwarded job's body, read in full to check its signature
+const maxForwardedBody = 1 << 20
+
+// forwardedHeaders are the submitter's headers passed on with a forwarded job, so
+// the peer identifies and authorizes the submitter as this gateway did
+var forwardedHeaders = []string{"Authorization", "X-API-Key", "User-Agent"}
+
+// Forwarding reports whether jobs are forwarded to peers at capacity
+func (f *Federation) Forwarding() bool {
+	return f.forward
+}
+
+// peerWithCapacity asks every peer's /estimate about req and returns the peer that
+// would start it soonest, or "" if none would start it right away
+func (f *Federation) peerWithCapacity(req *protocol.ComputeRequest) string {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return ""
+	}
+
+	estimates := make([]*JobEstimate, len(f.peers))
+	var wg sync.WaitGroup
+	for i, peer := range f.peers {
+		wg.Add(1)
+		go func(i int, peer string) {
+			defer wg.Done()
+			resp, err := f.httpClient.Post(peer+"/estimate", "application/json", bytes.NewReader(body))
+			if err != nil {
+				return
+			}
+			defer resp.Body.Close()
+
+			var estimate JobEstimate
+			if resp.StatusCode == http.StatusOK && json.NewDecoder(resp.Body).Decode(&estimate) == nil {
+				estimates[i] = &estimate
+			}
+		}(i, peer)
+	}
+	wg.Wait()
+
+	best := -1
+	for i, estimate := range estimates {
+		if estimate == nil || !estimate.StartsImmediately {
+			continue
+		}
+		if best < 0 || estimate.QueueWaitSeconds < estimates[best].QueueWaitSeconds {
+			best = i
+		}
+	}
+	if best < 0 {
+		return ""
+	}
+	return f.peers[best]
+}
+
+// forwardTarget picks a peer for a job this gateway can't start right away ("" to
+// run it here). Jobs forwarded by a peer are never forwarded again.
+func (s *Server) forwardTarget(req *protocol.ComputeRequest, forwardedFrom string) string {
+	if !s.federation.Forwarding() || forwardedFrom != "" || s.scheduler.Estimate(req).StartsImmediately {
+		return ""
+	}
+	return s.federation.peerWithCapacity(req)
+}
+
+// forwardJob submits a job to peer under its job ID (a new one unless the client
+// chose it) and relays the peer's reply
+// to the client as-is, recording the outcome on a local job record of the same
+// ID. Returns false, having written nothing, if the peer couldn't be reached.
+func (s *Server) forwardJob(w http.ResponseWriter, r *http.Request, peer string, req protocol.ComputeRequest, source JobSource) bool {
+	id := req.JobID
+	if id == "" {
+		var err error
+		if id, err = newJobID(); err != nil {
+			return false
+		}
+		req.JobID = id
+	}
+	body, err := json.Marshal(req)
+	if err != nil {
+		return false
+	}
+
+	peerReq, err := http.NewRequestWithContext(r.Context(), http.MethodPost, peer+"/submit", bytes.NewReader(body))
+	if err != nil {
+		return false
+	}
+	for _, name := range forwardedHeaders {
+		if value := r.Header.Get(name); value != "" {
+			peerReq.Header.Set(name, value)
+		}
+	}
+	peerReq.Header.Set("Content-Type", "application/json")
+	peerReq.Header.Set(headerForwardedFrom, s.federation.nodeName)
+	peerReq.Header.Set(headerClientIP, source.IP)
+	if err := signing.Sign(peerReq, s.federation.secret, body); err != nil {
+		return false
+	}
+
+	resp, err := s.federation.jobClient.Do(peerReq)
+	if err != nil {
+		log.Printf("[Gateway] Failed to forward job to %s, running it here: %v", peer, err)
+		return false
+	}
+	defer resp.Body.Close()
+
+	job, err := s.jobs.CreateWithID(id, &req, source)
+	if err != nil {
+		writeCreateError(w, err)
+		return true
+	}
+	s.sources.RecordSubmitted(source)
+	s.jobs.Annotate(job.ID, &protocol.JobAnnotations{Placement: protocol.PlacementForwarded, Peer: peer})
+	s.jobs.SetStatus(job.ID, protocol.StatusInProgress)
+	s.metrics.Inc("orchestrator_jobs_total", "status", "forwarded")
+	log.Printf("[Gateway] Forwarded job %s to %s", job.ID, peer)
+
+	response, err := relayPeerResponse(w, resp)
+	if err != nil {
+		s.jobs.Fail(job.ID, fmt.Errorf("on peer %s: %w", peer, err))
+		s.sources.RecordResult(source, false)
+		return true
+	}
+	s.jobs.Complete(job.ID, response)
+	s.sources.RecordResult(source, true)
+	return true
+}
+
+// relayPeerResponse copies a peer's /submit reply to w, streamed replies line by
+// line, and returns the job's response or error from it
+func relayPeerResponse(w http.ResponseWriter, resp *http.Response) (*protocol.JobResponse, error) {
+	for _, name := range []string{"Content-Type", "Retry-After"} {
+		if value := resp.Header.Get(name); value != "" {
+			w.Header().Set(name, value)
+		}
+	}
+	w.WriteHeader(resp.StatusCode)
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		w.Write(body)
+		return nil, fmt.Errorf("status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	if !strings.HasPrefix(resp.Header.Get("Content-Type"), "application/x-ndjson") {
+		body, err := io.ReadAll(resp.Body)
+		w.Write(body)
+		if err != nil {
+			return nil, fmt.Errorf("reply cut short: %w", err)
+		}
+		var response protocol.JobResponse
+		if err := json.Unmarshal(body, &response); err != nil {
+			return nil, fmt.Errorf("invalid reply: %w", err)
+		}
+		return &response, nil
+	}
+
+	flusher, _ := w.(http.Flusher)
+	reader := bufio.NewReader(resp.Body)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(line) > 0 {
+			w.Write(line)
+			if flusher != nil {
+				flusher.Flush()
+			}
+			var event protocol.StreamEvent
+			if json.Unmarshal(line, &event) == nil {
+				switch event.Type {
+				case protocol.StreamEventResult:
+					if event.Response != nil {
+						return event.Response, nil
+					}
+				case protocol.StreamEventError:
+					return nil, errors.New(event.Error)
+				}
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("stream ended without a result: %w", err)
+		}
+	}
+}
+
+// verifyForwarded checks a job forwarded by a peer gateway, returning the peer's
+// node name ("" for a job submitted directly). The body is restored for decoding.
+func (s *Server) verifyForwarded(w http.ResponseWriter, r *http.Request) (string, error) {
+	from := r.Header.Get(headerForwardedFrom)
+	if from == "" {
+		return "", nil
+	}
+	if s.federation.secret == "" {
+		return "", errors.New("this gateway accepts no forwarded jobs (PEER_SECRET unset)")
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxForwardedBody))
+	if err != nil {
+		return "", fmt.Errorf("failed to read forwarded job: %w", err)
+	}
+	if err := s.forwardVerifier.Verify(s.federation.secret, r.Header, body); err != nil {
+		return "", fmt.Errorf("forwarded job from %s: %w", from, err)
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	return from, nil
+}
@@ -0,0 +1,34 @@
+//go:build linux
+
+package gateway
+
+import (
+	"fmt"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// ioprioWhoProcess has ioprio_set target a single thread (IOPRIO_WHO_PROCESS)
+const ioprioWhoProcess = 1
+
+// setNice sets the niceness of every thread of the gateway
+func setNice(nice int) error {
+	return eachThread(os.Getpid(), func(tid int) error {
+		if err := unix.Setpriority(unix.PRIO_PROCESS, tid, nice); err != nil {
+			return fmt.Errorf("can't set niceness %d on thread %d: %w", nice, tid, err)
+		}
+		return nil
+	})
+}
+
+// setIOPriority sets the I/O scheduling class and level of every thread of the gateway
+func setIOPriority(class, level int) error {
+	prio := class<<13 | level
+	return eachThread(os.Getpid(), func(tid int) error {
+		if _, _, errno := unix.Syscall(unix.SYS_IOPRIO_SET, ioprioWhoProcess, uintptr(tid), uintptr(prio)); errno != 0 {
+			return fmt.Errorf("can't set I/O priority on thread %d: %w", tid, errno)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,15 @@
+//go:build !linux
+
+package gateway
+
+import "fmt"
+
+// setNice can't change the gateway's niceness off Linux
+func setNice(nice int) error {
+	return fmt.Errorf("setting niceness is not supported on this platform")
+}
+
+// setIOPriority can't change the gateway's I/O priority off Linux
+func setIOPriority(class, level int) error {
+	return fmt.Errorf("I/O priorities are not supported on this platform")
+}
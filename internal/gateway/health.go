@@ -0,0 +1,130 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// healthCacheTTL bounds how often /health probes Docker and the workers
+	healthCacheTTL = 5 * time.Second
+
+	// healthProbeTimeout bounds each individual dependency check
+	healthProbeTimeout = 2 * time.Second
+)
+
+// ComponentHealth is the outcome of one dependency check
+type ComponentHealth struct {
+	Healthy bool   `json:"healthy"`
+	Detail  string `json:"detail,omitempty"`
+}
+
+// HealthReport is the gateway's overall health with per-component details
+type HealthReport struct {
+	Healthy    bool                       `json:"healthy"`
+	CheckedAt  time.Time                  `json:"checked_at"`
+	Components map[string]ComponentHealth `json:"components"`
+}
+
+// HealthChecker runs dependency checks for /health and caches the result so
+// frequent probes don't hammer the Docker daemon
+type HealthChecker struct {
+	orchestrator *Orchestrator
+	httpClient   *http.Client
+
+	mu     sync.Mutex
+	cached *HealthReport
+}
+
+func NewHealthChecker(orch *Orchestrator) *HealthChecker {
+	return &HealthChecker{
+		orchestrator: orch,
+		httpClient:   &http.Client{Timeout: healthProbeTimeout},
+	}
+}
+
+// Check returns the cached report, refreshing it once it is older than healthCacheTTL
+func (h *HealthChecker) Check() HealthReport {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.cached != nil && time.Since(h.cached.CheckedAt) < healthCacheTTL {
+		return *h.cached
+	}
+
+	report := h.run()
+	h.cached = &report
+	return report
+}
+
+func (h *HealthChecker) run() HealthReport {
+	ctx, cancel := context.WithTimeout(context.Background(), healthProbeTimeout)
+	defer cancel()
+
+	components := map[string]ComponentHealth{
+		"docker":       componentFromErr(h.orchestrator.PingRuntime(ctx)),
+		"worker_image": componentFromErr(h.orchestrator.CheckWorkerImage(ctx)),
+		"workers":      h.checkWorkers(),
+	}
+
+	healthy := true
+	for _, component := range components {
+		healthy = healthy && component.Healthy
+	}
+
+	return HealthReport{Healthy: healthy, CheckedAt: time.Now(), Components: components}
+}
+
+// checkWorkers probes every worker's /health and requires at least one to answer OK
+func (h *HealthChecker) checkWorkers() ComponentHealth {
+	workers := h.orchestrator.GetAllWorkers()
+	if len(workers) == 0 {
+		return ComponentHealth{Healthy: false, Detail: "no workers running"}
+	}
+
+	results := make([]bool, len(workers))
+	var wg sync.WaitGroup
+	for i, worker := range workers {
+		wg.Add(1)
+		go func(i int, worker *WorkerInfo) {
+			defer wg.Done()
+			results[i] = h.probeWorker(worker)
+			h.orchestrator.MarkWorkerHealth(worker.CoreID, results[i])
+			if !results[i] {
+				h.orchestrator.CheckWorkerExit(worker.CoreID, worker.ContainerID)
+			}
+		}(i, worker)
+	}
+	wg.Wait()
+
+	healthyCount := 0
+	for _, ok := range results {
+		if ok {
+			healthyCount++
+		}
+	}
+
+	return ComponentHealth{
+		Healthy: healthyCount > 0,
+		Detail:  fmt.Sprintf("%d/%d workers healthy", healthyCount, len(workers)),
+	}
+}
+
+func (h *HealthChecker) probeWorker(worker *WorkerInfo) bool {
+	resp, err := h.httpClient.Get(worker.BaseURL + "/health")
+	if err != nil {
+		return false
+	}
+	resp.Body.Close()
+	return resp.StatusCode == http.StatusOK
+}
+
+func componentFromErr(err error) ComponentHealth {
+	if err != nil {
+		return ComponentHealth{Healthy: false, Detail: err.Error()}
+	}
+	return ComponentHealth{Healthy: true}
+}
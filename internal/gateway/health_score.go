@@ -0,0 +1,188 @@
+package gateway
+
+import (
+	"math"
+	"time"
+
+	"github.com/ahmadhassan44/container-orchestrator/internal/worker"
+	"github.com/ahmadhassan44/container-orchestrator/pkg/config"
+	"github.com/ahmadhassan44/container-orchestrator/pkg/protocol"
+)
+
+const (
+	// healthHeartbeatFresh is how old a worker's last sign of life may be before
+	// its heartbeat factor starts falling
+	healthHeartbeatFresh = 2 * worker.HeartbeatInterval
+
+	// healthMinDispatches is the fewest dispatches an error rate is taken over, so
+	// one failed job doesn't take a worker out on its own
+	healthMinDispatches = 3
+
+	// healthStatsFloor is the stats factor of a worker Docker hasn't returned
+	// stats for in HEALTH_STATS_STALE seconds
+	healthStatsFloor = 0.5
+)
+
+// WorkerHealth is a worker's composite health score and the factors behind it.
+// Each factor is between 0 and 1, and the score is their product. The scheduler
+// scales the worker's capacity by the score, so a worker in doubt gets fewer
+// jobs instead of none.
+type WorkerHealth struct {
+	Score     float64 `json:"score"`
+	Heartbeat float64 `json:"heartbeat"`      // Recency of its last heartbeat or successful probe
+	Errors    float64 `json:"errors"`         // 1 - the rate of dispatches it broke off or died during
+	Stats     float64 `json:"stats"`          // Staleness of its Docker stats, once sampling them fails
+	Drift     float64 `json:"drift"`          // Recent throughput against its calibration baseline
+	Down      string  `json:"down,omitempty"` // Why it takes no jobs at all: "failed health probe" or "draining"
+}
+
+// dispatchOutcome is one job dispatched to a worker that it either answered or broke off
+type dispatchOutcome struct {
+	at     time.Time
+	failed bool
+}
+
+// healthState holds what worker health is scored from, beyond the workers
+// themselves (guarded by the orchestrator lock)
+type healthState struct {
+	heartbeatTimeout time.Duration // Age at which the heartbeat factor reaches 0
+	errorWindow      time.Duration // Dispatches older than this are forgotten (0 = errors not scored)
+	statsStale       time.Duration // Age of the last good stats sample at which the stats factor bottoms out
+
+	outcomes    map[int][]dispatchOutcome // By core, oldest first
+	statsOK     map[int]time.Time         // Last good stats sample, by core
+	statsFailed map[int]bool              // Whether the latest stats request failed, by core
+	drift       func(coreID int) float64  // Throughput against baseline (nil = not scored)
+}
+
+func newHealthState(cfg *config.Config) healthState {
+	return healthState{
+		heartbeatTimeout: time.Duration(cfg.HealthHeartbeatTimeout) * time.Second,
+		errorWindow:      time.Duration(cfg.HealthErrorWindow) * time.Second,
+		statsStale:       time.Duration(cfg.HealthStatsStale) * time.Second,
+		outcomes:         make(map[int][]dispatchOutcome),
+		statsOK:          make(map[int]time.Time),
+		statsFailed:      make(map[int]bool),
+	}
+}
+
+// SetDriftSource registers how far each core's throughput has drifted from its
+// baseline. It is called with the orchestrator lock held, so it must not call
+// back into the orchestrator.
+func (o *Orchestrator) SetDriftSource(fn func(coreID int) float64) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.health.drift = fn
+}
+
+// RecordDispatch notes whether a job dispatched to a core's worker was answered.
+// Failures the worker can't be blamed for (timeouts, job errors) aren't recorded.
+func (o *Orchestrator) RecordDispatch(coreID int, err error) {
+	kind := failureKind(err)
+	if err != nil && kind != protocol.FailureConnection && kind != protocol.FailureWorkerExit {
+		return
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.health.errorWindow <= 0 {
+		return
+	}
+	now := time.Now()
+	outcomes := append(o.health.outcomes[coreID], dispatchOutcome{at: now, failed: err != nil})
+	o.health.outcomes[coreID] = pruneOutcomes(outcomes, now.Add(-o.health.errorWindow))
+}
+
+// recordStatsLocked notes the outcome of a Docker stats request for a core's
+// worker (caller holds o.mu)
+func (o *Orchestrator) recordStatsLocked(coreID int, ok bool) {
+	o.health.statsFailed[coreID] = !ok
+	if ok {
+		o.health.statsOK[coreID] = time.Now()
+	}
+}
+
+// forgetHealthLocked clears a core's record when a new container starts on it
+// (caller holds o.mu)
+func (o *Orchestrator) forgetHealthLocked(coreID int) {
+	delete(o.health.outcomes, coreID)
+	delete(o.health.statsOK, coreID)
+	delete(o.health.statsFailed, coreID)
+}
+
+// pruneOutcomes drops outcomes from before cutoff
+func pruneOutcomes(outcomes []dispatchOutcome, cutoff time.Time) []dispatchOutcome {
+	for len(outcomes) > 0 && outcomes[0].at.Before(cutoff) {
+		outcomes = outcomes[1:]
+	}
+	return outcomes
+}
+
+// scoreLocked scores a worker's health as of now (caller holds o.mu)
+func (o *Orchestrator) scoreLocked(w *WorkerInfo, now time.Time) WorkerHealth {
+	h := WorkerHealth{Heartbeat: 1, Errors: 1, Stats: 1, Drift: 1, Down: w.Down}
+
+	// Only workers that can reach the internal listener heartbeat
+	if age := now.Sub(w.LastHeartbeat); o.internalURL != "" && age > healthHeartbeatFresh {
+		h.Heartbeat = linearFall(age, healthHeartbeatFresh, o.health.heartbeatTimeout)
+	}
+
+	if o.health.errorWindow > 0 {
+		failed := 0
+		outcomes := pruneOutcomes(o.health.outcomes[w.CoreID], now.Add(-o.health.errorWindow))
+		for _, outcome := range outcomes {
+			if outcome.failed {
+				failed++
+			}
+		}
+		h.Errors = 1 - float64(failed)/float64(max(len(outcomes), healthMinDispatches))
+	}
+
+	if o.health.statsFailed[w.CoreID] {
+		h.Stats = healthStatsFloor
+		if good, sampled := o.health.statsOK[w.CoreID]; sampled {
+			h.Stats = 1 - (1-healthStatsFloor)*(1-linearFall(now.Sub(good), 0, o.health.statsStale))
+		}
+	}
+
+	if o.health.drift != nil {
+		h.Drift = min(max(o.health.drift(w.CoreID), 0), 1)
+	}
+
+	if h.Down == "" {
+		h.Score = round2(h.Heartbeat * h.Errors * h.Stats * h.Drift)
+	}
+	h.Heartbeat, h.Errors, h.Stats, h.Drift = round2(h.Heartbeat), round2(h.Errors), round2(h.Stats), round2(h.Drift)
+	return h
+}
+
+// round2 rounds a factor to two decimals for reporting
+func round2(v float64) float64 {
+	return math.Round(100*v) / 100
+}
+
+// linearFall is 1 up to age from, falling linearly to 0 at age to (at once if to <= from)
+func linearFall(age, from, to time.Duration) float64 {
+	if age <= from {
+		return 1
+	}
+	if to <= from || age >= to {
+		return 0
+	}
+	return 1 - float64(age-from)/float64(to-from)
+}
+
+// Drift is a core's lowest recent throughput against its baseline over the
+// operations calibrated on it (1 while none are)
+func (d *DegradationDetector) Drift(coreID int) float64 {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	drift := 1.0
+	for _, op := range d.cores[coreID] {
+		if op.baseline > 0 && len(op.recent) > 0 {
+			drift = min(drift, median(op.recent)/op.baseline)
+		}
+	}
+	return drift
+}
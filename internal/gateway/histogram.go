@@ -0,0 +1,176 @@
+package gateway
+
+import (
+	"sync"
+
+	"github.com/ahmadhassan44/container-orchestrator/pkg/protocol"
+)
+
+const (
+	// etaMinSamples is how many observations a histogram needs before its
+	// percentiles replace the point estimate
+	etaMinSamples = 20
+
+	// etaMaxSamples bounds a histogram's memory: past it, all counts are halved
+	// so recent behaviour outweighs old
+	etaMaxSamples = 1000
+
+	// etaMinEstimate is the smallest point estimate (seconds) worth comparing an
+	// actual against; below it the ratio is mostly noise
+	etaMinEstimate = 0.1
+)
+
+// ratioBuckets are the upper bounds of the actual/estimated ratios a histogram
+// counts; the last bucket holds everything above 10x
+var ratioBuckets = []float64{0.25, 0.5, 0.75, 0.9, 1.0, 1.1, 1.25, 1.5, 2, 3, 5, 10}
+
+// ratioHistogram is the empirical distribution of actual/estimated time for one
+// operation (run time) or queue (wait time)
+type ratioHistogram struct {
+	counts []float64 // One per ratioBuckets entry, plus the overflow bucket
+	total  float64
+}
+
+func (h *ratioHistogram) add(ratio float64) {
+	if h.counts == nil {
+		h.counts = make([]float64, len(ratioBuckets)+1)
+	}
+	i := 0
+	for i < len(ratioBuckets) && ratio > ratioBuckets[i] {
+		i++
+	}
+	h.counts[i]++
+	h.total++
+
+	if h.total > etaMaxSamples {
+		for j := range h.counts {
+			h.counts[j] /= 2
+		}
+		h.total /= 2
+	}
+}
+
+// quantile interpolates the ratio below which a fraction q of samples fell
+func (h *ratioHistogram) quantile(q float64) float64 {
+	target := q * h.total
+	cumulative, lower := 0.0, 0.0
+	for i, count := range h.counts {
+		upper := ratioBuckets[len(ratioBuckets)-1]
+		if i < len(ratioBuckets) {
+			upper = ratioBuckets[i]
+		}
+		if count > 0 && cumulative+count >= target {
+			return lower + (upper-lower)*(target-cumulative)/count
+		}
+		cumulative += count
+		lower = upper
+	}
+	return lower
+}
+
+// ETARange is a percentile range for a time estimate, in seconds
+type ETARange struct {
+	P50    float64 `json:"p50"`
+	P90    float64 `json:"p90"`
+	Source string  `json:"source"` // "empirical", or "estimate" while too few jobs have been observed
+}
+
+// JobETA is the predicted wait and completion time of a queued or running job
+type JobETA struct {
+	QueueWait  ETARange `json:"queue_wait"`
+	Completion ETARange `json:"completion"` // From now until the result is ready
+}
+
+// etaModel learns how far actual run and queue-wait times stray from the point
+// estimates, per operation and per queue, and turns estimates into p50/p90 ranges
+type etaModel struct {
+	mu   sync.Mutex
+	run  map[string]*ratioHistogram // By operation
+	wait map[string]*ratioHistogram // By queue
+}
+
+func newETAModel() *etaModel {
+	return &etaModel{
+		run:  make(map[string]*ratioHistogram),
+		wait: make(map[string]*ratioHistogram),
+	}
+}
+
+// observe records a successful job's actual times against what was predicted
+func (m *etaModel) observe(operation string, a *protocol.JobAnnotations) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if a.EstimatedDuration >= etaMinEstimate && a.RunTime > 0 {
+		observeRatio(m.run, operation, a.RunTime/a.EstimatedDuration)
+	}
+	if a.Placement == protocol.PlacementQueued && a.EstimatedQueueWait >= etaMinEstimate {
+		observeRatio(m.wait, a.Queue, a.QueueWait/a.EstimatedQueueWait)
+	}
+}
+
+func observeRatio(histograms map[string]*ratioHistogram, key string, ratio float64) {
+	h, exists := histograms[key]
+	if !exists {
+		h = &ratioHistogram{}
+		histograms[key] = h
+	}
+	h.add(ratio)
+}
+
+// runRange scales a run-time point estimate by the operation's observed spread
+func (m *etaModel) runRange(operation string, estimate float64) ETARange {
+	return m.scale(m.run, operation, estimate)
+}
+
+// waitRange scales a queue-wait point estimate by the queue's observed spread
+func (m *etaModel) waitRange(queue string, estimate float64) ETARange {
+	return m.scale(m.wait, queue, estimate)
+}
+
+func (m *etaModel) scale(histograms map[string]*ratioHistogram, key string, estimate float64) ETARange {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	h := histograms[key]
+	if h == nil || h.total < etaMinSamples {
+		return ETARange{P50: estimate, P90: estimate, Source: "estimate"}
+	}
+	return ETARange{P50: estimate * h.quantile(0.5), P90: estimate * h.quantile(0.9), Source: "empirical"}
+}
+
+// Status reports sample counts and the ratio percentiles per operation and queue
+func (m *etaModel) Status() map[string]interface{} {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	summarize := func(histograms map[string]*ratioHistogram) map[string]interface{} {
+		out := make(map[string]interface{}, len(histograms))
+		for key, h := range histograms {
+			out[key] = map[string]interface{}{
+				"samples":   int(h.total),
+				"ratio_p50": h.quantile(0.5),
+				"ratio_p90": h.quantile(0.9),
+			}
+		}
+		return out
+	}
+	return map[string]interface{}{
+		"min_samples": etaMinSamples,
+		"run":         summarize(m.run),
+		"queue_wait":  summarize(m.wait),
+	}
+}
+
+// addRanges sums two ranges percentile-wise, which overstates the combined p90
+// slightly (the tails rarely coincide) rather than understating it. A zero range
+// (e.g. no queue wait) doesn't affect the source.
+func addRanges(a, b ETARange) ETARange {
+	sum := ETARange{P50: a.P50 + b.P50, P90: a.P90 + b.P90, Source: "empirical"}
+	for _, r := range []ETARange{a, b} {
+		if r.P90 > 0 && r.Source != "empirical" {
+			sum.Source = "estimate"
+		}
+	}
+	return sum
+}
@@ -0,0 +1,214 @@
+package gateway
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/ahmadhassan44/container-orchestrator/pkg/config"
+	"github.com/ahmadhassan44/container-orchestrator/pkg/protocol"
+)
+
+// maxMemoryHistory bounds the entries kept when JOB_HISTORY_FILE is empty
+const maxMemoryHistory = 100_000
+
+// Kinds of job history entries
+const (
+	historyJob   = "job"   // A job finished
+	historySpawn = "spawn" // A worker container started
+)
+
+// HistoryEntry is one line of the job history log
+type HistoryEntry struct {
+	Kind   string    `json:"kind"`
+	At     time.Time `json:"at"` // When the job finished or the worker started
+	CoreID int       `json:"core_id,omitempty"`
+
+	JobID             string    `json:"job_id,omitempty"`
+	Status            string    `json:"status,omitempty"`
+	Operation         string    `json:"operation,omitempty"`
+	Queue             string    `json:"queue,omitempty"`
+	SubmittedAt       time.Time `json:"submitted_at,omitzero"`
+	EstimatedCPU      float64   `json:"estimated_cpu,omitempty"`
+	EstimatedDuration float64   `json:"estimated_duration,omitempty"`
+	QueueWait         float64   `json:"queue_wait,omitempty"`
+	RunTime           float64   `json:"run_time,omitempty"`
+}
+
+// JobHistory is an append-only log of finished jobs and worker spawns that
+// outlives the in-memory job store, for capacity reports over days or weeks.
+// Entries older than the retention are dropped when the gateway starts.
+type JobHistory struct {
+	path      string
+	retention time.Duration
+
+	mu      sync.Mutex
+	file    *os.File       // Open for appending (nil = memory only)
+	entries []HistoryEntry // Used when there is no file
+}
+
+func NewJobHistory(cfg *config.Config) *JobHistory {
+	h := &JobHistory{
+		path:      cfg.JobHistoryFile,
+		retention: time.Duration(max(cfg.JobHistoryRetentionDays, 1)) * 24 * time.Hour,
+	}
+	if err := h.open(); err != nil {
+		log.Printf("[History] Keeping job history in memory only: %v", err)
+		h.path = ""
+	}
+	return h
+}
+
+// open compacts the log to the retention window and opens it for appending
+func (h *JobHistory) open() error {
+	if h.path == "" {
+		return nil
+	}
+
+	kept := 0
+	cutoff := time.Now().Add(-h.retention)
+	var buf bytes.Buffer
+	err := h.scan(func(e HistoryEntry) {
+		if e.At.After(cutoff) {
+			line, _ := json.Marshal(e)
+			buf.Write(append(line, '\n'))
+			kept++
+		}
+	})
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	if dir := filepath.Dir(h.path); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return err
+		}
+	}
+	tmp := h.path + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), 0o644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, h.path); err != nil {
+		return err
+	}
+
+	h.file, err = os.OpenFile(h.path, os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	log.Printf("[History] Job history at %s (%d entries within %s)", h.path, kept, h.retention)
+	return nil
+}
+
+// scan calls fn for each readable entry in the log file, skipping malformed lines
+func (h *JobHistory) scan(fn func(HistoryEntry)) error {
+	f, err := os.Open(h.path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1<<20)
+	for scanner.Scan() {
+		var e HistoryEntry
+		if json.Unmarshal(scanner.Bytes(), &e) == nil {
+			fn(e)
+		}
+	}
+	return scanner.Err()
+}
+
+// append writes one entry to the log
+func (h *JobHistory) append(e HistoryEntry) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.file == nil {
+		h.entries = append(h.entries, e)
+		if len(h.entries) > maxMemoryHistory {
+			h.entries = h.entries[len(h.entries)-maxMemoryHistory:]
+		}
+		return
+	}
+
+	line, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+	if _, err := h.file.Write(append(line, '\n')); err != nil {
+		log.Printf("[History] Failed to append to %s: %v", h.path, err)
+	}
+}
+
+// RecordJob logs a job once it reaches a final status. It is a TransitionListener.
+func (h *JobHistory) RecordJob(job JobRecord) {
+	switch job.Status {
+	case protocol.StatusCompleted, protocol.StatusFailed, protocol.StatusCancelled:
+	default:
+		return
+	}
+
+	e := HistoryEntry{
+		Kind:        historyJob,
+		At:          job.CompletedAt,
+		JobID:       job.ID,
+		Status:      job.Status.String(),
+		Operation:   operationName(&job.Request),
+		Queue:       job.Request.Queue,
+		SubmittedAt: job.SubmittedAt,
+	}
+	if a := job.Annotations; a != nil {
+		e.CoreID = a.CoreID
+		e.EstimatedCPU = a.EstimatedCPU
+		e.EstimatedDuration = a.EstimatedDuration
+		e.QueueWait = a.QueueWait
+		e.RunTime = a.RunTime
+		if a.Queue != "" {
+			e.Queue = a.Queue
+		}
+	}
+	h.append(e)
+}
+
+// RecordSpawn logs a worker container start
+func (h *JobHistory) RecordSpawn(coreID int) {
+	h.append(HistoryEntry{Kind: historySpawn, At: time.Now(), CoreID: coreID})
+}
+
+// Since returns the entries recorded at or after t, oldest first
+func (h *JobHistory) Since(t time.Time) ([]HistoryEntry, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	var entries []HistoryEntry
+	if h.file == nil {
+		for _, e := range h.entries {
+			if !e.At.Before(t) {
+				entries = append(entries, e)
+			}
+		}
+		return entries, nil
+	}
+
+	err := h.scan(func(e HistoryEntry) {
+		if !e.At.Before(t) {
+			entries = append(entries, e)
+		}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("reading job history: %w", err)
+	}
+	return entries, nil
+}
+
+// RetentionDays is how many days of history are kept
+func (h *JobHistory) RetentionDays() int {
+	return int(h.retention / (24 * time.Hour))
+}
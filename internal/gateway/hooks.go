@@ -0,0 +1,114 @@
+package gateway
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ahmadhassan44/container-orchestrator/pkg/protocol"
+)
+
+// AdmissionHook lets code embedding the gateway act on jobs without forking it:
+// check them against its own policies, adjust them, or account for them. Hooks
+// are registered with Server.RegisterHook and run in registration order.
+type AdmissionHook interface {
+	// Name identifies the hook in logs and rejections
+	Name() string
+
+	// BeforeAdmit runs once a submitted job has been validated and its source
+	// checked against the denylist, before it is held, forwarded, charged to the
+	// source's quota or scheduled. It may change req, which is validated again
+	// afterwards. An error rejects the job; return an *AdmissionError to choose
+	// the status it is answered with.
+	BeforeAdmit(req *protocol.ComputeRequest, source JobSource) error
+
+	// AfterCompletion runs when a job reaches a final status (completed, failed
+	// or cancelled). It runs on the job's status transition, so slow work belongs
+	// in a goroutine.
+	AfterCompletion(job JobRecord)
+}
+
+// AdmissionError is a hook's rejection of a job, with how to answer it
+type AdmissionError struct {
+	Status     int           // HTTP status (0 = 403 Forbidden)
+	RetryAfter time.Duration // Sent as Retry-After when positive
+	Reason     string
+}
+
+func (e *AdmissionError) Error() string {
+	return e.Reason
+}
+
+// admissionHooks holds the registered hooks
+type admissionHooks struct {
+	mu    sync.RWMutex
+	hooks []AdmissionHook
+}
+
+func (h *admissionHooks) list() []AdmissionHook {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.hooks
+}
+
+// RegisterHook adds a hook that sees every job submitted from now on. Register
+// hooks before the gateway starts serving.
+func (s *Server) RegisterHook(hook AdmissionHook) {
+	s.hooks.mu.Lock()
+	defer s.hooks.mu.Unlock()
+	s.hooks.hooks = append(s.hooks.hooks, hook)
+	log.Printf("[Hooks] Registered admission hook %s", hook.Name())
+}
+
+// admit runs the admission hooks on a job, then validates what they left of it
+func (s *Server) admit(req *protocol.ComputeRequest, source JobSource) error {
+	hooks := s.hooks.list()
+	if len(hooks) == 0 {
+		return nil
+	}
+	for _, hook := range hooks {
+		if err := hook.BeforeAdmit(req, source); err != nil {
+			return fmt.Errorf("%s: %w", hook.Name(), err)
+		}
+	}
+	if err := s.validateRequest(req); err != nil {
+		return &AdmissionError{Status: http.StatusBadRequest, Reason: fmt.Sprintf("invalid after admission hooks: %v", err)}
+	}
+	return nil
+}
+
+// writeAdmissionError answers a job rejected by an admission hook
+func (s *Server) writeAdmissionError(w http.ResponseWriter, source JobSource, err error) {
+	s.sources.RecordRejected(source)
+	s.metrics.Inc("orchestrator_jobs_total", "status", "rejected")
+	log.Printf("[Hooks] Rejected job from %s: %v", source.ID(), err)
+
+	status := http.StatusForbidden
+	var rejection *AdmissionError
+	if errors.As(err, &rejection) {
+		if rejection.Status != 0 {
+			status = rejection.Status
+		}
+		if rejection.RetryAfter > 0 {
+			w.Header().Set("Retry-After", strconv.Itoa(int(rejection.RetryAfter.Round(time.Second).Seconds())))
+		}
+	}
+	http.Error(w, err.Error(), status)
+}
+
+// completed passes a job that reached a final status to the hooks. It is a
+// TransitionListener.
+func (h *admissionHooks) completed(job JobRecord) {
+	switch job.Status {
+	case protocol.StatusCompleted, protocol.StatusFailed, protocol.StatusCancelled:
+	default:
+		return
+	}
+	for _, hook := range h.list() {
+		hook.AfterCompletion(job)
+	}
+}
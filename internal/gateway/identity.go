@@ -0,0 +1,95 @@
+package gateway
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// loadIdentities reads the persisted core -> worker UUID assignments
+func (o *Orchestrator) loadIdentities() error {
+	if o.identityFile == "" {
+		return nil
+	}
+	data, err := os.ReadFile(o.identityFile)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var stored map[string]string // JSON object keys are strings
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return fmt.Errorf("invalid worker identity file %s: %w", o.identityFile, err)
+	}
+	for key, uuid := range stored {
+		if coreID, err := strconv.Atoi(key); err == nil && uuid != "" {
+			o.identities[coreID] = uuid
+		}
+	}
+	return nil
+}
+
+// workerUUIDLocked returns the stable identity of the worker slot on a core,
+// minting and persisting one the first time the core is used. Every container
+// that replaces a worker on the core inherits it. (Caller holds o.mu.)
+func (o *Orchestrator) workerUUIDLocked(coreID int) string {
+	if uuid, exists := o.identities[coreID]; exists {
+		return uuid
+	}
+
+	uuid, err := newUUID()
+	if err != nil {
+		log.Printf("[WARNING] Worker on Core %d gets no stable identity: %v", coreID, err)
+		return ""
+	}
+	o.identities[coreID] = uuid
+	log.Printf("[Orchestrator] Core %d assigned worker identity %s", coreID, uuid)
+
+	if err := o.saveIdentitiesLocked(); err != nil {
+		log.Printf("[WARNING] Failed to persist worker identities: %v", err)
+	}
+	return uuid
+}
+
+// saveIdentitiesLocked writes the assignments atomically (caller holds o.mu)
+func (o *Orchestrator) saveIdentitiesLocked() error {
+	if o.identityFile == "" {
+		return nil
+	}
+
+	stored := make(map[string]string, len(o.identities))
+	for coreID, uuid := range o.identities {
+		stored[strconv.Itoa(coreID)] = uuid
+	}
+	data, err := json.MarshalIndent(stored, "", "  ")
+	if err != nil {
+		return err
+	}
+	if dir := filepath.Dir(o.identityFile); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return err
+		}
+	}
+	tmp := o.identityFile + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, o.identityFile)
+}
+
+// newUUID returns a random (version 4) UUID
+func newUUID() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate UUID: %w", err)
+	}
+	buf[6] = buf[6]&0x0f | 0x40
+	buf[8] = buf[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", buf[0:4], buf[4:6], buf[6:8], buf[8:10], buf[10:16]), nil
+}
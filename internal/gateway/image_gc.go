@@ -0,0 +1,126 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/image"
+)
+
+// Label set on worker images by Dockerfile.worker; only images carrying it are
+// ever garbage collected
+const (
+	imageRoleLabel  = "com.container-orchestrator.role"
+	imageRoleWorker = "worker"
+)
+
+// ImageGCEntry is one worker image considered by garbage collection
+type ImageGCEntry struct {
+	ID        string    `json:"id"`
+	Tags      []string  `json:"tags"`
+	CreatedAt time.Time `json:"created_at"`
+	SizeBytes int64     `json:"size_bytes"`
+	Error     string    `json:"error,omitempty"` // Set if removal failed
+}
+
+// ImageGCReport is the outcome of one garbage collection pass
+type ImageGCReport struct {
+	DryRun           bool           `json:"dry_run"`
+	RetentionSeconds int            `json:"retention_seconds"`
+	Removed          []ImageGCEntry `json:"removed"` // Would be removed, for dry runs
+	Kept             int            `json:"kept"`    // In use, current, or within retention
+	ReclaimedBytes   int64          `json:"reclaimed_bytes"`
+}
+
+// CollectImages removes orchestrator worker images that no container uses, that
+// aren't the image new workers are started from, and that are older than the
+// retention period. With dryRun it only reports what would be removed.
+func (o *Orchestrator) CollectImages(ctx context.Context, dryRun bool) (ImageGCReport, error) {
+	report := ImageGCReport{DryRun: dryRun, RetentionSeconds: o.imageGCRetention, Removed: []ImageGCEntry{}}
+
+	images, err := o.cli.ImageList(ctx, image.ListOptions{
+		Filters: filters.NewArgs(filters.Arg("label", imageRoleLabel+"="+imageRoleWorker)),
+	})
+	if err != nil {
+		return report, fmt.Errorf("failed to list worker images: %w", err)
+	}
+
+	// Stopped containers pin their image too, so include them
+	containers, err := o.cli.ContainerList(ctx, container.ListOptions{All: true})
+	if err != nil {
+		return report, fmt.Errorf("failed to list containers: %w", err)
+	}
+	inUse := make(map[string]bool, len(containers))
+	for _, c := range containers {
+		inUse[c.ImageID] = true
+	}
+
+	cutoff := time.Now().Add(-time.Duration(o.imageGCRetention) * time.Second)
+	for _, img := range images {
+		createdAt := time.Unix(img.Created, 0)
+		if inUse[img.ID] || slices.Contains(img.RepoTags, o.workerImage) || createdAt.After(cutoff) {
+			report.Kept++
+			continue
+		}
+
+		entry := ImageGCEntry{ID: img.ID, Tags: img.RepoTags, CreatedAt: createdAt, SizeBytes: img.Size}
+		if !dryRun {
+			if _, err := o.cli.ImageRemove(ctx, img.ID, image.RemoveOptions{Force: true, PruneChildren: true}); err != nil {
+				entry.Error = err.Error()
+				log.Printf("[ImageGC] Failed to remove %s: %v", img.ID, err)
+				report.Removed = append(report.Removed, entry)
+				continue
+			}
+			log.Printf("[ImageGC] Removed %s %v", img.ID, img.RepoTags)
+		}
+		report.Removed = append(report.Removed, entry)
+		report.ReclaimedBytes += img.Size
+	}
+
+	return report, nil
+}
+
+// StartImageGC runs CollectImages every interval seconds in the background (0 = never)
+func (o *Orchestrator) StartImageGC(interval int) {
+	if interval <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(time.Duration(interval) * time.Second)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-o.ctx.Done():
+				return
+			case <-ticker.C:
+				if _, err := o.CollectImages(o.ctx, false); err != nil {
+					log.Printf("[ImageGC] %v", err)
+				}
+			}
+		}
+	}()
+}
+
+// handleImageGC runs worker image garbage collection on demand (?dry_run=true to only list)
+func (s *Server) handleImageGC(w http.ResponseWriter, r *http.Request) {
+	dryRun, _ := strconv.ParseBool(r.URL.Query().Get("dry_run"))
+
+	report, err := s.scheduler.orchestrator.CollectImages(r.Context(), dryRun)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
@@ -0,0 +1,220 @@
+package gateway
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Reasons a queued job can't start right now (WaitReason.Code)
+const (
+	WaitSchedulerPaused = "scheduler_paused"
+	WaitIntakeHeld      = "intake_held"       // Held for maintenance, not yet in a queue
+	WaitQueuedBehind    = "queued_behind"     // Earlier jobs in its queue go first
+	WaitQueueShare      = "queue_share_full"  // The queue's running jobs hold its whole worker share
+	WaitNoCapacity      = "no_capacity"       // No worker it may run on has room for its estimated CPU
+	WaitWorkersExcluded = "workers_excluded"  // Maintenance, benchmarks, taints or jobs running alone rule workers out
+	WaitCannotSpawn     = "cannot_spawn"      // No further worker can be started
+	WaitStarvingJob     = "held_for_starving" // Freed capacity goes to a starving job in another queue
+	WaitDispatching     = "dispatching"       // Nothing blocks it; the next scheduling pass starts it
+)
+
+// WaitReason is one reason a queued job can't start right now
+type WaitReason struct {
+	Code   string    `json:"code"`
+	Detail string    `json:"detail"`
+	Until  time.Time `json:"until,omitzero"` // When it's expected to clear, if known
+}
+
+// QueueInsight is a queued job with its place in line and why it's waiting
+type QueueInsight struct {
+	WaitingJob
+	Position int          `json:"position"` // 1 = head of its queue
+	Reasons  []WaitReason `json:"reasons"`
+}
+
+// QueueInsights explains, for every queued job, why it can't start right now
+func (s *Scheduler) QueueInsights() []QueueInsight {
+	waiting := s.WaitingJobs()
+	insights := make([]QueueInsight, 0, len(waiting))
+	if len(waiting) == 0 {
+		return insights
+	}
+
+	finishing := make(map[int]float64) // Core ID -> seconds until its first running job ends
+	queueFinishing := make(map[string]float64)
+	for _, job := range s.RunningJobs() {
+		if remaining, seen := finishing[job.CoreID]; !seen || job.RemainingSeconds < remaining {
+			finishing[job.CoreID] = job.RemainingSeconds
+		}
+		if remaining, seen := queueFinishing[job.Queue]; !seen || job.RemainingSeconds < remaining {
+			queueFinishing[job.Queue] = job.RemainingSeconds
+		}
+	}
+	workers := s.orchestrator.GetAllWorkers()
+	spawnErr := s.scaling.spawnBlocked(len(workers))
+
+	position := make(map[string]int)
+	for _, job := range waiting {
+		position[job.Queue]++
+		insights = append(insights, QueueInsight{WaitingJob: job, Position: position[job.Queue]})
+	}
+	for i := range insights {
+		insights[i].Reasons = s.waitReasons(insights[i], insights, workers, finishing, queueFinishing, spawnErr)
+	}
+	return insights
+}
+
+// WaitReasons explains why a queued job can't start, or returns nil if it isn't queued
+func (s *Scheduler) WaitReasons(jobID string) []WaitReason {
+	for _, insight := range s.QueueInsights() {
+		if insight.JobID == jobID {
+			return insight.Reasons
+		}
+	}
+	return nil
+}
+
+// waitReasons works out what holds one queued job back, given every queued job,
+// the workers, and when running jobs are expected to finish
+func (s *Scheduler) waitReasons(job QueueInsight, queued []QueueInsight, workers []*WorkerInfo,
+	finishing map[int]float64, queueFinishing map[string]float64, spawnErr error) []WaitReason {
+	var reasons []WaitReason
+	now := time.Now()
+	at := func(seconds float64) time.Time {
+		return now.Add(time.Duration(seconds * float64(time.Second)))
+	}
+
+	if s.paused.Load() {
+		reasons = append(reasons, WaitReason{Code: WaitSchedulerPaused, Detail: "an admin paused scheduling; queued jobs start once it resumes"})
+	}
+	if job.Position > 1 {
+		reasons = append(reasons, WaitReason{Code: WaitQueuedBehind,
+			Detail: fmt.Sprintf("%d job(s) ahead of it in queue %q", job.Position-1, job.Queue)})
+	}
+
+	if !s.queues.canRun(job.Queue, job.EstimatedCPU) {
+		reason := WaitReason{Code: WaitQueueShare,
+			Detail: fmt.Sprintf("queue %q's running jobs hold its whole worker share; this job needs %.0f%% CPU more", job.Queue, job.EstimatedCPU)}
+		if remaining, running := queueFinishing[job.Queue]; running {
+			reason.Until = at(remaining)
+		}
+		reasons = append(reasons, reason)
+	}
+
+	// Which workers the job may run on, and whether any has room for it
+	causes := []string{"a maintenance window", "a benchmark", "taints it doesn't tolerate", "the sandbox pool",
+		"hyperthread sharing (a job running alone, or this job needing to)", "QUEUE_CORES (bound to another queue, or not to this one)",
+		"a health score of 0 (down, or failing its dispatches)"}
+	excluded := make([][]string, len(causes)) // Core IDs, by cause
+	fits, placeable := false, 0
+	freeAt := -1.0
+	smtAllowed := s.smtAllowed(s.interference.Isolated(job.Operation, job.noSMTSharing))
+	for _, worker := range workers {
+		cause := -1
+		switch {
+		case !s.maintenance.Clear(worker.CoreID, job.duration):
+			cause = 0
+		case s.benchmarks.Reserved(worker.CoreID):
+			cause = 1
+		case !s.taints.Tolerated(worker.CoreID, job.tolerations):
+			cause = 2
+		case !s.sandbox.Allows(worker.CoreID, job.Operation, job.tolerations):
+			cause = 3
+		case !s.queues.allows(job.Queue, worker.CoreID):
+			cause = 5
+		case smtAllowed != nil && !smtAllowed(worker):
+			cause = 4
+		case worker.Health.Score <= 0:
+			cause = 6
+		}
+		if cause >= 0 {
+			excluded[cause] = append(excluded[cause], fmt.Sprint(worker.CoreID))
+			continue
+		}
+		placeable++
+		if worker.fits(job.EstimatedCPU, s.config.MaxCPUThreshold) {
+			fits = true
+		} else if remaining, running := finishing[worker.CoreID]; running && (freeAt < 0 || remaining < freeAt) {
+			freeAt = remaining
+		}
+	}
+	var ruledOut []string
+	for i, cores := range excluded {
+		if len(cores) > 0 {
+			ruledOut = append(ruledOut, fmt.Sprintf("core(s) %s by %s", strings.Join(cores, ", "), causes[i]))
+		}
+	}
+	if len(ruledOut) > 0 {
+		reasons = append(reasons, WaitReason{Code: WaitWorkersExcluded, Detail: "ruled out " + strings.Join(ruledOut, "; ")})
+	}
+	if !fits {
+		reason := WaitReason{Code: WaitNoCapacity,
+			Detail: fmt.Sprintf("none of the %d worker(s) it may run on has %.0f%% CPU free under the %.0f%% threshold",
+				placeable, job.EstimatedCPU, s.config.MaxCPUThreshold)}
+		if freeAt >= 0 {
+			reason.Until = at(freeAt)
+		}
+		reasons = append(reasons, reason)
+
+		if _, err := s.orchestrator.GetNextAvailableCoreWhere(s.spawnableFor(job.Queue, job.Operation, job.tolerations, job.duration)); err != nil {
+			reasons = append(reasons, WaitReason{Code: WaitCannotSpawn, Detail: err.Error()})
+		} else if spawnErr != nil {
+			reasons = append(reasons, WaitReason{Code: WaitCannotSpawn, Detail: spawnErr.Error()})
+		}
+	}
+
+	// The queue processor holds freed capacity for a starving head job it can't place
+	if s.queues.starvationBoost > 1 {
+		for _, other := range queued {
+			if other.JobID == job.JobID || other.Position != 1 || !other.Starving || other.EstimatedCPU > s.config.MaxCPUThreshold {
+				continue
+			}
+			reasons = append(reasons, WaitReason{Code: WaitStarvingJob,
+				Detail: fmt.Sprintf("freed capacity goes first to job %s, starving in queue %q", other.JobID, other.Queue)})
+			break
+		}
+	}
+
+	if len(reasons) == 0 {
+		reasons = append(reasons, WaitReason{Code: WaitDispatching, Detail: "a worker has room; the next scheduling pass starts it"})
+	}
+	return reasons
+}
+
+// handleQueueInsights lists queued jobs and held submissions with the reasons
+// each can't start right now
+func (s *Server) handleQueueInsights(w http.ResponseWriter, r *http.Request) {
+	insights := s.scheduler.QueueInsights()
+	for i, held := range s.intake.Jobs() {
+		insights = append(insights, QueueInsight{
+			WaitingJob: WaitingJob{
+				JobID:          held.JobID,
+				Operation:      operationName(&held.Request),
+				Queue:          held.Request.Queue,
+				WaitingSeconds: time.Since(held.HeldAt).Seconds(),
+			},
+			Position: i + 1,
+			Reasons:  []WaitReason{intakeWaitReason()},
+		})
+	}
+
+	counts := make(map[string]int)
+	for _, insight := range insights {
+		for _, reason := range insight.Reasons {
+			counts[reason.Code]++
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"jobs":    insights,
+		"reasons": counts,
+	})
+}
+
+// intakeWaitReason explains a submission held in the intake
+func intakeWaitReason() WaitReason {
+	return WaitReason{Code: WaitIntakeHeld, Detail: "submissions are held for maintenance; held jobs are queued once an admin releases them"}
+}
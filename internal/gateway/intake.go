@@ -0,0 +1,417 @@
+package gateway
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/ahmadhassan44/container-orchestrator/pkg/config"
+	"github.com/ahmadhassan44/container-orchestrator/pkg/protocol"
+)
+
+// defaultReleaseRate is how many held jobs are released per second unless the
+// release or INTAKE_RELEASE_RATE says otherwise
+const defaultReleaseRate = 1.0
+
+// HeldJob is a submission accepted while intake was held
+type HeldJob struct {
+	JobID   string                  `json:"job_id"`
+	Request protocol.ComputeRequest `json:"request"`
+	Source  JobSource               `json:"source"`
+	HeldAt  time.Time               `json:"held_at"`
+}
+
+// intakeState is what the intake file holds
+type intakeState struct {
+	Holding bool      `json:"holding"`
+	Reason  string    `json:"reason,omitempty"`
+	Since   time.Time `json:"since,omitzero"`
+	Jobs    []HeldJob `json:"jobs"`
+}
+
+// Intake holds submissions without running them, e.g. right before a Docker
+// daemon upgrade. Held jobs are written to disk as they arrive, so a restart
+// keeps both the hold and the backlog. Releasing ends the hold and drains the
+// backlog, oldest first, at a controlled rate.
+type Intake struct {
+	path        string
+	defaultRate float64
+
+	mu        sync.Mutex
+	state     intakeState
+	releasing bool
+	rate      float64 // Jobs per second while releasing
+	released  int     // Jobs released by the current or last release
+}
+
+func NewIntake(cfg *config.Config) *Intake {
+	in := &Intake{path: cfg.IntakeFile, defaultRate: cfg.IntakeReleaseRate}
+	if in.defaultRate <= 0 {
+		in.defaultRate = defaultReleaseRate
+	}
+	if err := in.load(); err != nil {
+		log.Printf("[WARNING] Starting without held submissions: %v", err)
+		in.state = intakeState{}
+	}
+	return in
+}
+
+// Holding reports whether new submissions are held
+func (in *Intake) Holding() bool {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	return in.state.Holding
+}
+
+// Hold starts holding new submissions. Fails while a release is draining the backlog.
+func (in *Intake) Hold(reason string) error {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+
+	if in.releasing {
+		return errors.New("a release is still draining the backlog")
+	}
+	if in.state.Holding {
+		return errors.New("intake is already held")
+	}
+	previous := in.state
+	in.state.Holding = true
+	in.state.Reason = reason
+	in.state.Since = time.Now()
+	if err := in.saveLocked(); err != nil {
+		in.state = previous
+		return fmt.Errorf("failed to persist hold: %w", err)
+	}
+	return nil
+}
+
+// Add holds a job, returning its position in the backlog (1 = next). The job is
+// only accepted once it is on disk.
+func (in *Intake) Add(job HeldJob) (int, error) {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+
+	if !in.state.Holding {
+		return 0, errors.New("intake is not held")
+	}
+	in.state.Jobs = append(in.state.Jobs, job)
+	if err := in.saveLocked(); err != nil {
+		in.state.Jobs = in.state.Jobs[:len(in.state.Jobs)-1]
+		return 0, fmt.Errorf("failed to persist held job: %w", err)
+	}
+	return len(in.state.Jobs), nil
+}
+
+// Remove drops a held job (e.g. cancelled), reporting whether it was held
+func (in *Intake) Remove(jobID string) bool {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+
+	for i, job := range in.state.Jobs {
+		if job.JobID == jobID {
+			in.state.Jobs = append(in.state.Jobs[:i], in.state.Jobs[i+1:]...)
+			if err := in.saveLocked(); err != nil {
+				log.Printf("[WARNING] Failed to persist held jobs: %v", err)
+			}
+			return true
+		}
+	}
+	return false
+}
+
+// Update applies fn to a held job's request and persists it, reporting whether
+// the job is held. An error from fn leaves the job unchanged.
+func (in *Intake) Update(jobID string, fn func(req *protocol.ComputeRequest) error) (bool, error) {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+
+	for i := range in.state.Jobs {
+		job := &in.state.Jobs[i]
+		if job.JobID != jobID {
+			continue
+		}
+		updated := job.Request
+		if err := fn(&updated); err != nil {
+			return true, err
+		}
+		previous := job.Request
+		job.Request = updated
+		if err := in.saveLocked(); err != nil {
+			job.Request = previous
+			return true, fmt.Errorf("failed to persist held job: %w", err)
+		}
+		return true, nil
+	}
+	return false, nil
+}
+
+// Jobs returns the held jobs, oldest first
+func (in *Intake) Jobs() []HeldJob {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	return append([]HeldJob{}, in.state.Jobs...)
+}
+
+// Release ends the hold and hands the backlog to run, oldest first, rate jobs a
+// second (0 = the default rate). New submissions run right away from now on.
+func (in *Intake) Release(rate float64, run func(HeldJob)) (int, error) {
+	if rate <= 0 {
+		rate = in.defaultRate
+	}
+
+	in.mu.Lock()
+	if !in.state.Holding {
+		in.mu.Unlock()
+		return 0, errors.New("intake is not held")
+	}
+	in.state.Holding = false
+	in.state.Reason = ""
+	in.state.Since = time.Time{}
+	if err := in.saveLocked(); err != nil {
+		log.Printf("[WARNING] Failed to persist intake release: %v", err)
+	}
+	backlog := len(in.state.Jobs)
+	in.releasing = backlog > 0
+	in.rate = rate
+	in.released = 0
+	in.mu.Unlock()
+
+	if backlog > 0 {
+		go in.drain(rate, run)
+	}
+	return backlog, nil
+}
+
+// resume continues a release a restart interrupted, at the default rate
+func (in *Intake) resume(run func(HeldJob)) {
+	in.mu.Lock()
+	if in.state.Holding || in.releasing || len(in.state.Jobs) == 0 {
+		in.mu.Unlock()
+		return
+	}
+	in.releasing = true
+	in.rate = in.defaultRate
+	backlog := len(in.state.Jobs)
+	in.mu.Unlock()
+
+	log.Printf("[Intake] Resuming an interrupted release: %d job(s) left", backlog)
+	go in.drain(in.defaultRate, run)
+}
+
+// drain releases the backlog one job every 1/rate seconds until it is empty
+func (in *Intake) drain(rate float64, run func(HeldJob)) {
+	ticker := time.NewTicker(time.Duration(float64(time.Second) / rate))
+	defer ticker.Stop()
+
+	for {
+		in.mu.Lock()
+		if len(in.state.Jobs) == 0 {
+			in.releasing = false
+			released := in.released
+			in.mu.Unlock()
+			log.Printf("[Intake] Backlog drained: %d job(s) released", released)
+			return
+		}
+		job := in.state.Jobs[0]
+		in.state.Jobs = in.state.Jobs[1:]
+		in.released++
+		if err := in.saveLocked(); err != nil {
+			log.Printf("[WARNING] Failed to persist held jobs: %v", err)
+		}
+		in.mu.Unlock()
+
+		run(job)
+		<-ticker.C
+	}
+}
+
+// Status reports the hold, the backlog and any release in progress
+func (in *Intake) Status() map[string]interface{} {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+
+	status := map[string]interface{}{
+		"holding":   in.state.Holding,
+		"held_jobs": len(in.state.Jobs),
+		"releasing": in.releasing,
+		"released":  in.released,
+	}
+	if in.state.Holding {
+		status["reason"] = in.state.Reason
+		status["since"] = in.state.Since
+	}
+	if in.releasing {
+		status["release_rate"] = in.rate
+	}
+	if len(in.state.Jobs) > 0 {
+		status["oldest_held_at"] = in.state.Jobs[0].HeldAt
+	}
+	return status
+}
+
+func (in *Intake) load() error {
+	if in.path == "" {
+		return nil
+	}
+	data, err := os.ReadFile(in.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, &in.state); err != nil {
+		return fmt.Errorf("invalid intake file %s: %w", in.path, err)
+	}
+	if in.state.Holding || len(in.state.Jobs) > 0 {
+		log.Printf("[Intake] Loaded %d held job(s) from %s (holding: %t)", len(in.state.Jobs), in.path, in.state.Holding)
+	}
+	return nil
+}
+
+// saveLocked writes the state atomically so a crash can't leave it truncated
+func (in *Intake) saveLocked() error {
+	if in.path == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(in.state, "", "  ")
+	if err != nil {
+		return err
+	}
+	if dir := filepath.Dir(in.path); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return err
+		}
+	}
+	tmp := in.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, in.path)
+}
+
+// restoreHeldJobs recreates the records of jobs held before a restart, so their
+// IDs keep working for /jobs/{id}, /wait and cancellation
+func (s *Server) restoreHeldJobs() {
+	for _, held := range s.intake.Jobs() {
+		if _, err := s.jobs.CreateWithID(held.JobID, &held.Request, held.Source); err != nil {
+			log.Printf("[WARNING] Held job %s not restored: %v", held.JobID, err)
+			s.intake.Remove(held.JobID)
+			continue
+		}
+		s.jobs.SetStatus(held.JobID, protocol.StatusHeld)
+	}
+	s.intake.resume(s.runReleased)
+}
+
+// holdJob records and holds a submission instead of running it, answering
+// 202 with its ID and place in the backlog
+func (s *Server) holdJob(w http.ResponseWriter, req protocol.ComputeRequest, source JobSource) {
+	job, err := s.createJob(&req, source)
+	if err != nil {
+		writeCreateError(w, err)
+		return
+	}
+	position, err := s.intake.Add(HeldJob{JobID: job.ID, Request: job.Request, Source: source, HeldAt: time.Now()})
+	if err != nil {
+		// Not durable, so not accepted
+		s.jobs.Fail(job.ID, err)
+		log.Printf("[Intake] %v", err)
+		http.Error(w, "Submissions are held but this one could not be stored, retry shortly", http.StatusServiceUnavailable)
+		return
+	}
+	s.jobs.SetStatus(job.ID, protocol.StatusHeld)
+	s.sources.RecordSubmitted(source)
+	s.metrics.Inc("orchestrator_jobs_total", "status", "held")
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]interface{}{"job_id": job.ID, "status": protocol.StatusHeld, "position": position})
+}
+
+// runReleased runs a job released from the backlog like any other submission,
+// charging its quota now. Jobs finished while held (e.g. force-failed) are skipped.
+func (s *Server) runReleased(held HeldJob) {
+	job, exists := s.jobs.Get(held.JobID)
+	if !exists {
+		// Evicted from the job history while held
+		var err error
+		if job, err = s.jobs.CreateWithID(held.JobID, &held.Request, held.Source); err != nil {
+			log.Printf("[WARNING] Released job %s not run: %v", held.JobID, err)
+			return
+		}
+	}
+	if !job.CompletedAt.IsZero() {
+		return
+	}
+
+	charge, admitted := s.quotas.Admit(job.Source.ID(), s.scheduler.EstimateCPUSeconds(&job.Request))
+	if !admitted {
+		s.jobs.Fail(job.ID, errors.New("CPU-seconds quota exceeded when released"))
+		s.sources.RecordResult(job.Source, false)
+		s.metrics.Inc("orchestrator_jobs_total", "status", "quota_exceeded")
+		return
+	}
+	s.quotas.Track(job.ID, charge)
+	s.jobs.SetStatus(job.ID, protocol.StatusAccepted)
+
+	go func() {
+		defer s.quotas.Settle(job.ID)
+		s.executeJob(job, job.Source)
+	}()
+}
+
+// handleIntakeStatus reports whether submissions are held and the backlog
+func (s *Server) handleIntakeStatus(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.intake.Status())
+}
+
+// handleHoldIntake starts holding submissions: {"reason": "docker upgrade"} (body optional)
+func (s *Server) handleHoldIntake(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Reason string `json:"reason"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, "Body must be JSON", http.StatusBadRequest)
+			return
+		}
+	}
+	if err := s.intake.Hold(body.Reason); err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	log.Printf("[Audit] Intake held: submissions are stored, not run (reason: %q)", body.Reason)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.intake.Status())
+}
+
+// handleReleaseIntake ends the hold and drains the backlog: {"rate": 2} jobs per second (body optional)
+func (s *Server) handleReleaseIntake(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Rate float64 `json:"rate"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.Rate < 0 {
+			http.Error(w, "Body must be JSON with a non-negative \"rate\" in jobs per second", http.StatusBadRequest)
+			return
+		}
+	}
+	backlog, err := s.intake.Release(body.Rate, s.runReleased)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	log.Printf("[Audit] Intake released: draining %d held job(s)", backlog)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.intake.Status())
+}
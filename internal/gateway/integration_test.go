@@ -0,0 +1,448 @@
+//go:build integration
+
+// Integration tests drive the full gateway stack (server -> scheduler ->
+// orchestrator -> workers) over HTTP. By default workers run in-process via
+// FakeRuntime; set INTEGRATION_RUNTIME=docker to use a real Docker daemon
+// (requires the container-orchestrator-worker:latest image).
+//
+//	go test -tags=integration ./internal/gateway/
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/client"
+
+	"github.com/ahmadhassan44/container-orchestrator/pkg/config"
+	"github.com/ahmadhassan44/container-orchestrator/pkg/protocol"
+)
+
+const integrationBasePort = 18000
+
+type testGateway struct {
+	server *httptest.Server
+	orch   *Orchestrator
+	sched  *Scheduler
+	rt     ContainerRuntime
+}
+
+func integrationRuntime(t *testing.T) ContainerRuntime {
+	t.Helper()
+
+	if os.Getenv("INTEGRATION_RUNTIME") != "docker" {
+		return NewFakeRuntime()
+	}
+
+	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+	if err != nil {
+		t.Skipf("docker client unavailable: %v", err)
+	}
+	if _, err := cli.Info(context.Background()); err != nil {
+		t.Skipf("docker daemon unreachable: %v", err)
+	}
+	return cli
+}
+
+func newTestGateway(t *testing.T, cfg *config.Config) *testGateway {
+	t.Helper()
+
+	rt := integrationRuntime(t)
+	orch := NewOrchestratorWithRuntime(context.Background(), rt, cfg)
+	t.Cleanup(func() {
+		if err := orch.Shutdown(context.Background()); err != nil {
+			t.Logf("shutdown: %v", err)
+		}
+	})
+
+	sched := NewScheduler(orch, cfg)
+	t.Cleanup(sched.StopQueueProcessor)
+
+	srv := httptest.NewServer(NewServer(sched, cfg).Handler())
+	t.Cleanup(srv.Close)
+
+	return &testGateway{server: srv, orch: orch, sched: sched, rt: rt}
+}
+
+func testConfig() *config.Config {
+	return &config.Config{
+		MaxCPUThreshold:    100,
+		PreSpawnThreshold:  200, // disable proactive spawning so worker counts are deterministic
+		WorkerBasePort:     integrationBasePort,
+		WorkerDrainTimeout: 10,
+	}
+}
+
+// startWorkers spawns workers on the given cores and waits until they answer /health
+func (g *testGateway) startWorkers(t *testing.T, cores ...int) {
+	t.Helper()
+
+	for _, coreID := range cores {
+		if _, err := g.orch.StartWorker(coreID); err != nil {
+			t.Fatalf("start worker on core %d: %v", coreID, err)
+		}
+	}
+
+	deadline := time.Now().Add(15 * time.Second)
+	for _, coreID := range cores {
+		url := fmt.Sprintf("http://localhost:%d/health", integrationBasePort+coreID)
+		for {
+			resp, err := http.Get(url)
+			if err == nil {
+				resp.Body.Close()
+				if resp.StatusCode == http.StatusOK {
+					break
+				}
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("worker on core %d never became healthy", coreID)
+			}
+			time.Sleep(100 * time.Millisecond)
+		}
+	}
+}
+
+type submitResult struct {
+	status   int
+	response protocol.JobResponse
+	elapsed  time.Duration
+}
+
+func (g *testGateway) submit(t *testing.T, req protocol.ComputeRequest) submitResult {
+	t.Helper()
+
+	payload, _ := json.Marshal(req)
+	start := time.Now()
+	resp, err := http.Post(g.server.URL+"/submit", "application/json", bytes.NewReader(payload))
+	if err != nil {
+		t.Errorf("submit: %v", err)
+		return submitResult{}
+	}
+	defer resp.Body.Close()
+
+	result := submitResult{status: resp.StatusCode, elapsed: time.Since(start)}
+	if resp.StatusCode == http.StatusOK {
+		if err := json.NewDecoder(resp.Body).Decode(&result.response); err != nil {
+			t.Errorf("decode response: %v", err)
+		}
+	}
+	return result
+}
+
+// submitConcurrently fires all requests at once and returns results in request order
+func (g *testGateway) submitConcurrently(t *testing.T, reqs ...protocol.ComputeRequest) []submitResult {
+	t.Helper()
+
+	results := make([]submitResult, len(reqs))
+	var wg sync.WaitGroup
+	for i, req := range reqs {
+		wg.Add(1)
+		go func(i int, req protocol.ComputeRequest) {
+			defer wg.Done()
+			results[i] = g.submit(t, req)
+		}(i, req)
+	}
+	wg.Wait()
+	return results
+}
+
+func (g *testGateway) queueSize(t *testing.T) int {
+	t.Helper()
+
+	resp, err := http.Get(g.server.URL + "/queue")
+	if err != nil {
+		t.Fatalf("queue status: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var status struct {
+		QueueSize int `json:"queue_size"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		t.Fatalf("decode queue status: %v", err)
+	}
+	return status.QueueSize
+}
+
+func TestIntegrationScaleOutOnDemand(t *testing.T) {
+	g := newTestGateway(t, testConfig())
+
+	// Each spawn holds the scheduling lock for ~2s, so jobs must outlast two
+	// spawns to keep every earlier worker occupied when the next job arrives
+	job := protocol.ComputeRequest{CPULoad: 80, LoadTime: 5}
+	results := g.submitConcurrently(t, job, job, job)
+
+	workerIDs := make(map[string]bool)
+	for i, r := range results {
+		if r.status != http.StatusOK {
+			t.Fatalf("job %d: status %d", i, r.status)
+		}
+		workerIDs[r.response.WorkerID] = true
+	}
+
+	if got := g.orch.GetWorkerCount(); got != 3 {
+		t.Errorf("worker count = %d, want 3", got)
+	}
+	if len(workerIDs) != 3 {
+		t.Errorf("jobs landed on %d distinct workers, want 3: %v", len(workerIDs), workerIDs)
+	}
+}
+
+func TestIntegrationQueuesWhenSaturated(t *testing.T) {
+	g := newTestGateway(t, testConfig())
+	g.startWorkers(t, 1, 2, 3)
+
+	const loadTime = 3
+	saturating := protocol.ComputeRequest{CPULoad: 100, LoadTime: loadTime}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i, r := range g.submitConcurrently(t, saturating, saturating, saturating) {
+			if r.status != http.StatusOK {
+				t.Errorf("saturating job %d: status %d", i, r.status)
+			}
+		}
+	}()
+
+	// Let the first three jobs reserve every core before submitting the overflow job
+	time.Sleep(500 * time.Millisecond)
+
+	overflow := make(chan submitResult, 1)
+	go func() {
+		overflow <- g.submit(t, protocol.ComputeRequest{CPULoad: 50, LoadTime: 1})
+	}()
+
+	sawQueued := false
+	for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); {
+		if g.queueSize(t) > 0 {
+			sawQueued = true
+			break
+		}
+		time.Sleep(25 * time.Millisecond)
+	}
+	if !sawQueued {
+		t.Error("overflow job never appeared in the queue")
+	}
+
+	r := <-overflow
+	wg.Wait()
+
+	if r.status != http.StatusOK {
+		t.Fatalf("overflow job: status %d", r.status)
+	}
+	if r.elapsed < (loadTime-1)*time.Second {
+		t.Errorf("overflow job finished after %s; expected it to wait for a saturated worker", r.elapsed)
+	}
+	if got := g.orch.GetWorkerCount(); got != 3 {
+		t.Errorf("worker count = %d, want 3 (queueing must not exceed the core limit)", got)
+	}
+}
+
+func TestIntegrationWorkerKilledMidJob(t *testing.T) {
+	g := newTestGateway(t, testConfig())
+	g.startWorkers(t, 1)
+
+	done := make(chan submitResult, 1)
+	go func() {
+		done <- g.submit(t, protocol.ComputeRequest{CPULoad: 50, LoadTime: 5})
+	}()
+
+	time.Sleep(1 * time.Second)
+	worker, _ := g.orch.GetWorkerByCore(1)
+	if err := g.rt.ContainerKill(context.Background(), worker.ContainerID, "SIGKILL"); err != nil {
+		t.Fatalf("kill worker: %v", err)
+	}
+
+	select {
+	case r := <-done:
+		if r.status == http.StatusOK {
+			t.Fatalf("job on killed worker reported success: %+v", r.response)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("job on killed worker never returned")
+	}
+
+	// The failed request should have recorded why the worker died and freed its core
+	resp, err := http.Get(g.server.URL + "/workers")
+	if err != nil {
+		t.Fatalf("workers: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var workers struct {
+		Workers []map[string]interface{} `json:"workers"`
+		Exited  []ExitedWorker           `json:"exited"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&workers); err != nil {
+		t.Fatalf("decode workers: %v", err)
+	}
+	if len(workers.Workers) != 0 {
+		t.Errorf("killed worker still listed as active: %+v", workers.Workers)
+	}
+	if len(workers.Exited) != 1 || workers.Exited[0].CoreID != 1 || workers.Exited[0].Exit.ExitCode != 137 {
+		t.Errorf("expected core 1 exited with code 137, got %+v", workers.Exited)
+	}
+}
+
+func TestIntegrationRetriesAfterWorkerExit(t *testing.T) {
+	cfg := testConfig()
+	cfg.RetryMaxAttempts = 3
+	cfg.RetryMaxBackoff = 1
+	g := newTestGateway(t, cfg)
+	g.startWorkers(t, 1)
+
+	done := make(chan submitResult, 1)
+	go func() {
+		done <- g.submit(t, protocol.ComputeRequest{
+			CPULoad:  50,
+			LoadTime: 2,
+			Retry:    &protocol.RetryPolicy{MaxAttempts: 2, Backoff: 0.1},
+		})
+	}()
+
+	time.Sleep(1 * time.Second)
+	worker, _ := g.orch.GetWorkerByCore(1)
+	if err := g.rt.ContainerKill(context.Background(), worker.ContainerID, "SIGKILL"); err != nil {
+		t.Fatalf("kill worker: %v", err)
+	}
+
+	// The retry lands on a fresh worker spawned on the freed core
+	select {
+	case r := <-done:
+		if r.status != http.StatusOK {
+			t.Fatalf("retried job failed with status %d", r.status)
+		}
+		if r.response.Attempts != 2 {
+			t.Errorf("expected 2 attempts, got %d", r.response.Attempts)
+		}
+	case <-time.After(15 * time.Second):
+		t.Fatal("retried job never returned")
+	}
+}
+
+func TestIntegrationShutdownDrainsInFlightJob(t *testing.T) {
+	g := newTestGateway(t, testConfig())
+	g.startWorkers(t, 1)
+
+	done := make(chan submitResult, 1)
+	go func() {
+		done <- g.submit(t, protocol.ComputeRequest{CPULoad: 50, LoadTime: 2})
+	}()
+
+	time.Sleep(500 * time.Millisecond)
+	if err := g.orch.Shutdown(context.Background()); err != nil {
+		t.Fatalf("shutdown: %v", err)
+	}
+
+	r := <-done
+	if r.status != http.StatusOK {
+		t.Fatalf("in-flight job was not allowed to finish: status %d", r.status)
+	}
+}
+
+func TestIntegrationProtobufDispatch(t *testing.T) {
+	cfg := testConfig()
+	cfg.WorkerWire = protocol.WireProtobuf
+	g := newTestGateway(t, cfg)
+	g.startWorkers(t, 1)
+
+	// The encoding is agreed once the worker answers /version
+	deadline := time.Now().Add(15 * time.Second)
+	for {
+		if worker, _ := g.orch.GetWorkerByCore(1); worker != nil && worker.Wire == protocol.WireProtobuf {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("worker never agreed to protobuf dispatch")
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	r := g.submit(t, protocol.ComputeRequest{Operation: "monte_carlo_pi", Iterations: 100_000, Seed: 7, Labels: map[string]string{"team": "a"}})
+	if r.status != http.StatusOK {
+		t.Fatalf("status %d", r.status)
+	}
+	if r.response.Iterations != 100_000 || r.response.Precision == nil || r.response.Units["result"] == "" {
+		t.Errorf("response lost fields over protobuf: %+v", r.response)
+	}
+	if estimate, _ := r.response.Output.Float(); estimate != r.response.Result || estimate < 3 || estimate > 3.3 {
+		t.Errorf("output = %v, result = %v", estimate, r.response.Result)
+	}
+
+	// Typed parameters travel as embedded JSON
+	r = g.submit(t, protocol.ComputeRequest{Operation: "matrix_determinant", Matrix: &protocol.MatrixParams{Values: [][]float64{{2, 1}, {1, 3}}}})
+	if r.status != http.StatusOK {
+		t.Fatalf("matrix values: status %d", r.status)
+	}
+	if det, _ := r.response.Output.Float(); math.Abs(det-5) > 1e-9 {
+		t.Errorf("determinant of the given matrix = %v, want 5", det)
+	}
+	r = g.submit(t, protocol.ComputeRequest{Operation: "matrix_determinant", Matrix: &protocol.MatrixParams{Size: 50}, Seed: 7})
+	if r.status != http.StatusOK {
+		t.Fatalf("matrix size: status %d", r.status)
+	}
+	if size, _ := r.response.Metadata["size"].(float64); size != 50 {
+		t.Errorf("random matrix size = %v, want 50", r.response.Metadata["size"])
+	}
+}
+
+func TestIntegrationSubmitRejectsLargeBody(t *testing.T) {
+	g := newTestGateway(t, testConfig())
+
+	values := make([][]float64, 400)
+	for i := range values {
+		values[i] = make([]float64, len(values))
+		for j := range values[i] {
+			values[i][j] = 1.0 / float64(i+j+1)
+		}
+	}
+	r := g.submit(t, protocol.ComputeRequest{Operation: "matrix_determinant", Matrix: &protocol.MatrixParams{Values: values}})
+	if r.status != http.StatusRequestEntityTooLarge {
+		t.Errorf("submit of a %dx%d matrix: status %d, want 413", len(values), len(values), r.status)
+	}
+}
+
+// BenchmarkDispatchEncoding compares encoding and decoding a small job and its
+// result in JSON and protobuf, as the gateway and worker do per dispatch:
+//
+//	go test -tags=integration -run '^$' -bench DispatchEncoding -benchmem ./internal/gateway/
+func BenchmarkDispatchEncoding(b *testing.B) {
+	req := protocol.ComputeRequest{JobID: "JOB-6ee5968685105ea4", CPULoad: 50, LoadTime: 0.01, Queue: "interactive"}
+	met := true
+	resp := protocol.JobResponse{
+		JobID: req.JobID, WorkerID: "Worker-Core-1", WorkerUUID: "4a4fe1a4-dbd2-4518-9d2d-97840ee0f76c",
+		Result: 3.141592, Output: protocol.FloatResult(3.141592), TimeTaken: "12.65ms", Iterations: 1_000_000,
+		Precision: &protocol.Precision{StdError: 0.0016, CI95: [2]float64{3.1383, 3.1448}, Target: 0.002, TargetMet: &met},
+		Units:     map[string]string{"result": "dimensionless"},
+	}
+
+	b.Run("json", func(b *testing.B) {
+		for b.Loop() {
+			payload, _ := json.Marshal(&req)
+			var decodedReq protocol.ComputeRequest
+			json.Unmarshal(payload, &decodedReq)
+			body, _ := json.Marshal(&resp)
+			var decodedResp protocol.JobResponse
+			json.Unmarshal(body, &decodedResp)
+		}
+	})
+	b.Run("protobuf", func(b *testing.B) {
+		for b.Loop() {
+			payload, _ := protocol.MarshalRequestProto(&req)
+			protocol.UnmarshalRequestProto(payload)
+			body, _ := protocol.MarshalResponseProto(&resp)
+			protocol.UnmarshalResponseProto(body)
+		}
+	})
+}
@@ -0,0 +1,426 @@
+package gateway
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ahmadhassan44/container-orchestrator/pkg/config"
+	"github.com/ahmadhassan44/container-orchestrator/pkg/protocol"
+)
+
+// interferenceMinSamples is how many jobs, alone and sharing, an operation (or a
+// pairing) needs before its cost is reported
+const interferenceMinSamples = 3
+
+// A job counts as sharing its physical cores if it had neighbors for at least
+// this fraction of its run; jobs in between are left out of the comparison
+const interferenceSharedFraction = 0.5
+
+// coRun is a job executing on a worker, with the time it spent sharing a
+// physical core with other jobs
+type coRun struct {
+	coreID    int
+	operation string
+	physical  []string // Physical cores of its worker's cpuset
+	startedAt time.Time
+	neighbors int             // Jobs sharing a physical core with it right now
+	since     time.Time       // When its current stretch of sharing began
+	shared    time.Duration   // Time spent sharing, before the current stretch
+	with      map[string]bool // Operations it shared with
+}
+
+// opInterference holds one operation's recent throughputs, alone and sharing
+type opInterference struct {
+	solo   []float64
+	shared []float64
+	with   map[string][]float64 // Shared throughputs, by the operation shared with
+}
+
+// OperationInterference is how much sharing a physical core costs an operation.
+// Throughput is measured as for core degradation.
+type OperationInterference struct {
+	Operation     string             `json:"operation"`
+	Solo          float64            `json:"solo"`   // Median throughput with its physical cores to itself
+	Shared        float64            `json:"shared"` // Median throughput sharing a physical core
+	SoloSamples   int                `json:"solo_samples"`
+	SharedSamples int                `json:"shared_samples"`
+	Cost          float64            `json:"cost"`                // Fraction of throughput lost to sharing (0 until measured)
+	Measured      bool               `json:"measured"`            // Both sides have enough samples
+	Neighbors     map[string]float64 `json:"neighbors,omitempty"` // Cost by the operation shared with
+	Isolated      bool               `json:"isolated"`            // Its jobs run alone (cost at or above INTERFERENCE_ISOLATE)
+}
+
+// Interference correlates each job's throughput with the jobs that shared its
+// physical cores (hyperthread siblings, or the same worker) while it ran, to
+// measure what sharing costs each operation. Jobs that asked for it, or whose
+// operation loses too much, are placed alone on their physical cores.
+type Interference struct {
+	window   int
+	isolate  float64        // Cost at which an operation's jobs run alone (0 = only on request)
+	topology map[int]string // Logical CPU -> physical core ("package:core")
+
+	mu     sync.Mutex
+	runs   map[string]*coRun // By job ID
+	ops    map[string]*opInterference
+	claims map[string]int // Jobs running alone, by job ID -> core ID
+}
+
+func NewInterference(cfg *config.Config, metrics *Metrics) *Interference {
+	in := &Interference{
+		window:   max(cfg.InterferenceWindow, 1),
+		isolate:  max(cfg.InterferenceIsolate, 0) / 100,
+		topology: readTopology(),
+		runs:     make(map[string]*coRun),
+		ops:      make(map[string]*opInterference),
+		claims:   make(map[string]int),
+	}
+
+	metrics.Register("orchestrator_smt_interference_cost", metricGauge, "Fraction of throughput each operation loses sharing a physical core")
+	metrics.AddCollector(func(m *Metrics) {
+		for _, op := range in.Report() {
+			if op.Measured {
+				m.Set("orchestrator_smt_interference_cost", op.Cost, "operation", op.Operation)
+			}
+		}
+	})
+	return in
+}
+
+// readTopology maps each logical CPU to its physical core, from sysfs. CPUs it
+// can't place are treated as cores of their own.
+func readTopology() map[int]string {
+	topology := make(map[int]string)
+	paths, _ := filepath.Glob("/sys/devices/system/cpu/cpu[0-9]*/topology/core_id")
+	for _, path := range paths {
+		dir := filepath.Dir(path)
+		cpu, err := strconv.Atoi(strings.TrimPrefix(filepath.Base(filepath.Dir(dir)), "cpu"))
+		if err != nil {
+			continue
+		}
+		core, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		pkg, err := os.ReadFile(filepath.Join(dir, "physical_package_id"))
+		if err != nil {
+			continue
+		}
+		topology[cpu] = strings.TrimSpace(string(pkg)) + ":" + strings.TrimSpace(string(core))
+	}
+	return topology
+}
+
+// physical returns the physical cores a cpuset's logical CPUs belong to
+func (in *Interference) physical(cpuset string) []string {
+	cpus, _ := parseCpuset(cpuset)
+	var cores []string
+	for _, cpu := range cpus {
+		core, known := in.topology[cpu]
+		if !known {
+			core = "cpu" + strconv.Itoa(cpu)
+		}
+		if !slices.Contains(cores, core) {
+			cores = append(cores, core)
+		}
+	}
+	return cores
+}
+
+// near reports whether two workers share a physical core: they are the same
+// worker, or their cpusets hold sibling hyperthreads
+func near(coreA int, physicalA []string, coreB int, physicalB []string) bool {
+	if coreA == coreB {
+		return true
+	}
+	for _, core := range physicalA {
+		if slices.Contains(physicalB, core) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *coRun) join(operation string, now time.Time) {
+	if r.neighbors == 0 {
+		r.since = now
+	}
+	r.neighbors++
+	r.with[operation] = true
+}
+
+func (r *coRun) leave(now time.Time) {
+	r.neighbors--
+	if r.neighbors == 0 {
+		r.shared += now.Sub(r.since)
+	}
+}
+
+// sharedFraction is the part of the run so far spent sharing a physical core
+func (r *coRun) sharedFraction(now time.Time) float64 {
+	shared := r.shared
+	if r.neighbors > 0 {
+		shared += now.Sub(r.since)
+	}
+	elapsed := now.Sub(r.startedAt)
+	if elapsed <= 0 {
+		return 0
+	}
+	return shared.Seconds() / elapsed.Seconds()
+}
+
+// Start records a job beginning a run on coreID, whose worker has cpuset
+func (in *Interference) Start(jobID string, coreID int, operation, cpuset string) {
+	if jobID == "" {
+		return
+	}
+	now := time.Now()
+	run := &coRun{coreID: coreID, operation: operation, physical: in.physical(cpuset), startedAt: now, with: make(map[string]bool)}
+
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	for _, other := range in.runs {
+		if near(run.coreID, run.physical, other.coreID, other.physical) {
+			run.join(other.operation, now)
+			other.join(run.operation, now)
+		}
+	}
+	in.runs[jobID] = run
+}
+
+// Finish records a job's run ending, and gives up its claim to run alone
+func (in *Interference) Finish(jobID string) {
+	now := time.Now()
+	in.mu.Lock()
+	defer in.mu.Unlock()
+
+	delete(in.claims, jobID)
+	run, exists := in.runs[jobID]
+	if !exists {
+		return
+	}
+	delete(in.runs, jobID)
+	for _, other := range in.runs {
+		if near(run.coreID, run.physical, other.coreID, other.physical) {
+			other.leave(now)
+		}
+	}
+}
+
+// Record measures a completed run's throughput as alone or shared, by how long
+// it shared a physical core
+func (in *Interference) Record(req *protocol.ComputeRequest, resp *protocol.JobResponse) {
+	rate, ok := throughput(req, resp)
+	if !ok {
+		return
+	}
+	operation := operationName(req)
+
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	run, exists := in.runs[req.JobID]
+	if !exists {
+		return
+	}
+	op, exists := in.ops[operation]
+	if !exists {
+		op = &opInterference{with: make(map[string][]float64)}
+		in.ops[operation] = op
+	}
+
+	keep := func(samples []float64) []float64 {
+		samples = append(samples, rate)
+		if len(samples) > in.window {
+			samples = samples[len(samples)-in.window:]
+		}
+		return samples
+	}
+	switch fraction := run.sharedFraction(time.Now()); {
+	case fraction == 0:
+		op.solo = keep(op.solo)
+	case fraction >= interferenceSharedFraction:
+		op.shared = keep(op.shared)
+		for neighbor := range run.with {
+			op.with[neighbor] = keep(op.with[neighbor])
+		}
+	}
+}
+
+// costLocked is how much sharing costs an operation, if measured (caller holds in.mu)
+func (in *Interference) costLocked(operation string) (float64, bool) {
+	op, exists := in.ops[operation]
+	if !exists || len(op.solo) < interferenceMinSamples || len(op.shared) < interferenceMinSamples {
+		return 0, false
+	}
+	return 1 - median(op.shared)/median(op.solo), true
+}
+
+// Isolated reports whether a job must run alone on its physical cores: it asked
+// to, or its operation loses INTERFERENCE_ISOLATE or more to sharing
+func (in *Interference) Isolated(operation string, requested bool) bool {
+	if requested {
+		return true
+	}
+	if in.isolate == 0 {
+		return false
+	}
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	cost, measured := in.costLocked(operation)
+	return measured && cost >= in.isolate
+}
+
+// Claim reserves coreID's physical cores for a job running alone
+func (in *Interference) Claim(jobID string, coreID int) {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	in.claims[jobID] = coreID
+}
+
+// Claimed returns the cores of jobs running alone
+func (in *Interference) Claimed() map[int]bool {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	claimed := make(map[int]bool, len(in.claims))
+	for _, coreID := range in.claims {
+		claimed[coreID] = true
+	}
+	return claimed
+}
+
+// Report summarizes every measured operation
+func (in *Interference) Report() []OperationInterference {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+
+	report := make([]OperationInterference, 0, len(in.ops))
+	for name, op := range in.ops {
+		entry := OperationInterference{
+			Operation:     name,
+			Solo:          median(op.solo),
+			Shared:        median(op.shared),
+			SoloSamples:   len(op.solo),
+			SharedSamples: len(op.shared),
+		}
+		entry.Cost, entry.Measured = in.costLocked(name)
+		entry.Isolated = entry.Measured && in.isolate > 0 && entry.Cost >= in.isolate
+		if len(op.solo) >= interferenceMinSamples {
+			for neighbor, samples := range op.with {
+				if len(samples) < interferenceMinSamples {
+					continue
+				}
+				if entry.Neighbors == nil {
+					entry.Neighbors = make(map[string]float64)
+				}
+				entry.Neighbors[neighbor] = 1 - median(samples)/entry.Solo
+			}
+		}
+		report = append(report, entry)
+	}
+	sort.Slice(report, func(i, j int) bool { return report[i].Operation < report[j].Operation })
+	return report
+}
+
+// smtAllowed returns which workers a job may be placed on given jobs running
+// alone: none sharing a physical core with one, and for a job that must run
+// alone itself, only idle workers whose siblings are idle too. Returns nil when
+// nothing is ruled out.
+func (s *Scheduler) smtAllowed(isolated bool) func(worker *WorkerInfo) bool {
+	claimed := s.interference.Claimed()
+	if !isolated && len(claimed) == 0 {
+		return nil
+	}
+
+	workers := s.orchestrator.GetAllWorkers()
+	physical := make(map[int][]string, len(workers))
+	for coreID, resources := range s.orchestrator.CoreResources() {
+		physical[coreID] = s.interference.physical(resources.Cpuset)
+	}
+	return func(worker *WorkerInfo) bool {
+		for _, other := range workers {
+			if !near(worker.CoreID, physical[worker.CoreID], other.CoreID, physical[other.CoreID]) {
+				continue
+			}
+			if claimed[other.CoreID] || (isolated && other.ReservedCPU > 0) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// StartInterferenceReports logs the interference report every interval seconds (0 = never)
+func (s *Scheduler) StartInterferenceReports(interval int) {
+	if interval <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(time.Duration(interval) * time.Second)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-s.orchestrator.ctx.Done():
+				return
+			case <-ticker.C:
+				for _, op := range s.interference.Report() {
+					if op.Measured {
+						log.Printf("[Interference] %s", describeInterference(op))
+					}
+				}
+			}
+		}
+	}()
+}
+
+// describeInterference summarizes an operation's interference, for logs
+func describeInterference(op OperationInterference) string {
+	summary := fmt.Sprintf("%s loses %.0f%% sharing a physical core (%.4g/s alone over %d job(s), %.4g/s shared over %d)",
+		op.Operation, 100*op.Cost, op.Solo, op.SoloSamples, op.Shared, op.SharedSamples)
+	worst, worstCost := "", 0.0
+	for neighbor, cost := range op.Neighbors {
+		if worst == "" || cost > worstCost || (cost == worstCost && neighbor < worst) {
+			worst, worstCost = neighbor, cost
+		}
+	}
+	if worst != "" {
+		summary += fmt.Sprintf("; worst alongside %s (%.0f%%)", worst, 100*worstCost)
+	}
+	if op.Isolated {
+		summary += "; its jobs run alone"
+	}
+	return summary
+}
+
+// handleGetInterference reports what sharing a physical core costs each operation
+func (s *Server) handleGetInterference(w http.ResponseWriter, r *http.Request) {
+	in := s.scheduler.interference
+	topology := make(map[string]string, len(in.topology))
+	for cpu, core := range in.topology {
+		topology[strconv.Itoa(cpu)] = core
+	}
+	isolatedCores := make([]int, 0)
+	for coreID := range in.Claimed() {
+		isolatedCores = append(isolatedCores, coreID)
+	}
+	sort.Ints(isolatedCores)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"window":         in.window,
+		"isolate":        100 * in.isolate,
+		"topology":       topology,
+		"isolated_cores": isolatedCores,
+		"operations":     in.Report(),
+	})
+}
@@ -0,0 +1,87 @@
+package gateway
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/ahmadhassan44/container-orchestrator/pkg/protocol"
+	"github.com/ahmadhassan44/container-orchestrator/pkg/signing"
+)
+
+// maxInternalBody bounds the body of a request on the internal listener
+const maxInternalBody = 1 << 20
+
+// StartInternal serves the worker-facing API on addr (normally the Docker bridge
+// address). These routes are never registered on the public listener.
+func (s *Server) StartInternal(addr string) error {
+	log.Printf("[Gateway] Internal listener on %s", addr)
+	return http.ListenAndServe(addr, s.InternalHandler())
+}
+
+// InternalHandler builds the handler for the internal listener. Requests are not
+// access-logged: workers heartbeat every few seconds.
+func (s *Server) InternalHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /internal/heartbeat", s.internalOnly(s.handleHeartbeat))
+	return mux
+}
+
+// internalOnly requires requests signed with the internal token handed to workers
+// at spawn, each fresh and seen only once, so another process on the host or
+// bridge network can neither forge nor replay them
+func (s *Server) internalOnly(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token := s.scheduler.orchestrator.InternalToken()
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxInternalBody))
+		if err != nil {
+			http.Error(w, "Request body too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		if token == "" {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if err := s.internalVerifier.Verify(token, r.Header, body); err != nil {
+			s.metrics.Inc("orchestrator_internal_rejected_total", "reason", rejectionReason(err))
+			log.Printf("[Gateway] Rejected internal %s %s from %s: %v", r.Method, r.URL.Path, r.RemoteAddr, err)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		next(w, r)
+	}
+}
+
+// rejectionReason labels a signature verification failure for metrics
+func rejectionReason(err error) string {
+	switch {
+	case errors.Is(err, signing.ErrUnsigned):
+		return "unsigned"
+	case errors.Is(err, signing.ErrStale):
+		return "stale"
+	case errors.Is(err, signing.ErrReplayed):
+		return "replayed"
+	default:
+		return "bad_signature"
+	}
+}
+
+// handleHeartbeat records a worker's liveness, version and drain state
+func (s *Server) handleHeartbeat(w http.ResponseWriter, r *http.Request) {
+	var hb protocol.WorkerHeartbeat
+	if err := json.NewDecoder(r.Body).Decode(&hb); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid JSON: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if !s.scheduler.orchestrator.RecordHeartbeat(hb) {
+		http.Error(w, "Unknown worker", http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,241 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+)
+
+// Labels set on worker containers, alongside imageRoleLabel, so the janitor can
+// tell them apart from other containers on the host
+const (
+	containerCoreLabel = "com.container-orchestrator.core"
+	containerNodeLabel = "com.container-orchestrator.node"
+)
+
+// Reconciliation actions, as counted in orchestrator_janitor_actions_total
+const (
+	janitorVanished  = "removed_vanished"  // Tracked worker whose container no longer exists
+	janitorExited    = "removed_exited"    // Tracked worker whose container stopped
+	janitorUntracked = "flagged_untracked" // Worker container the gateway doesn't track
+)
+
+// JanitorAction is one reconciliation step
+type JanitorAction struct {
+	Action      string `json:"action"`
+	CoreID      int    `json:"core_id,omitempty"`
+	ContainerID string `json:"container_id"`
+	State       string `json:"state,omitempty"` // The container's runtime state, if it exists
+}
+
+// JanitorReport is the outcome of one reconciliation pass
+type JanitorReport struct {
+	RanAt     time.Time       `json:"ran_at"`
+	Tracked   int             `json:"tracked"`   // Workers in the map after the pass
+	Actions   []JanitorAction `json:"actions"`   // Taken on this pass
+	Untracked []JanitorAction `json:"untracked"` // Every flagged container still present
+	Error     string          `json:"error,omitempty"`
+}
+
+// janitorState carries untracked containers between passes. One is flagged when it
+// is seen on a second pass in a row, so workers mid-spawn or mid-stop aren't.
+type janitorState struct {
+	mu        sync.Mutex
+	sightings map[string]int // Container ID -> consecutive passes seen untracked
+	last      *JanitorReport
+}
+
+// Reconcile compares the workers map against the runtime's containers. Workers whose
+// containers vanished are dropped; those whose containers stopped are handled like
+// any other exit. Worker containers that aren't tracked are flagged, not removed:
+// they may belong to a previous gateway run or still hold a job.
+func (o *Orchestrator) Reconcile(ctx context.Context) JanitorReport {
+	report := JanitorReport{RanAt: time.Now(), Actions: []JanitorAction{}}
+
+	// The map is read on both sides of the list call instead of being locked across
+	// it, which would hold up scheduling for the length of a Docker call. Only
+	// workers tracked before and after the call are judged.
+	before := o.trackedContainers()
+	containers, err := o.cli.ContainerList(ctx, container.ListOptions{All: true})
+	if err != nil {
+		report.Error = fmt.Sprintf("failed to list containers: %v", err)
+		log.Printf("[Janitor] %s", report.Error)
+		return report
+	}
+	after := o.trackedContainers()
+
+	states := make(map[string]string, len(containers))
+	for _, c := range containers {
+		states[c.ID] = c.State
+	}
+
+	for coreID, containerID := range before {
+		if after[coreID] != containerID {
+			continue // Replaced or stopped meanwhile
+		}
+		state, exists := states[containerID]
+		switch {
+		case !exists:
+			if o.dropVanished(coreID, containerID) {
+				report.Actions = append(report.Actions, JanitorAction{Action: janitorVanished, CoreID: coreID, ContainerID: containerID[:12]})
+			}
+		case state != "running":
+			if diag := o.CheckWorkerExit(coreID, containerID); diag != nil {
+				log.Printf("[Janitor] Worker on Core %d was %s: %s", coreID, state, diag)
+				report.Actions = append(report.Actions, JanitorAction{Action: janitorExited, CoreID: coreID, ContainerID: containerID[:12], State: state})
+			}
+		}
+	}
+
+	tracked := make(map[string]bool, len(after))
+	for _, containerID := range after {
+		tracked[containerID] = true
+	}
+	sightings := make(map[string]int)
+	report.Untracked = []JanitorAction{}
+	o.janitor.mu.Lock()
+	for _, c := range containers {
+		if c.Labels[imageRoleLabel] != imageRoleWorker || tracked[c.ID] || !o.ownsContainer(c.Labels) {
+			continue
+		}
+		sightings[c.ID] = o.janitor.sightings[c.ID] + 1
+		if sightings[c.ID] < 2 {
+			continue
+		}
+		coreID, _ := strconv.Atoi(c.Labels[containerCoreLabel])
+		action := JanitorAction{Action: janitorUntracked, CoreID: coreID, ContainerID: c.ID[:12], State: c.State}
+		report.Untracked = append(report.Untracked, action)
+		if sightings[c.ID] == 2 {
+			report.Actions = append(report.Actions, action)
+			log.Printf("[WARNING] Worker container %s (%s, core %d) is not tracked by this gateway; left in place",
+				action.ContainerID, action.State, coreID)
+		}
+	}
+	o.janitor.sightings = sightings
+	o.janitor.mu.Unlock()
+
+	report.Tracked = o.GetWorkerCount()
+	sort.Slice(report.Actions, func(i, j int) bool { return report.Actions[i].CoreID < report.Actions[j].CoreID })
+	sort.Slice(report.Untracked, func(i, j int) bool { return report.Untracked[i].CoreID < report.Untracked[j].CoreID })
+
+	o.metrics.Set("orchestrator_untracked_containers", float64(len(report.Untracked)))
+	for _, action := range report.Actions {
+		o.metrics.Inc("orchestrator_janitor_actions_total", "action", action.Action)
+	}
+
+	o.janitor.mu.Lock()
+	o.janitor.last = &report
+	o.janitor.mu.Unlock()
+	return report
+}
+
+// trackedContainers snapshots the workers map as core -> container ID
+func (o *Orchestrator) trackedContainers() map[int]string {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+
+	tracked := make(map[int]string, len(o.workers))
+	for coreID, worker := range o.workers {
+		tracked[coreID] = worker.ContainerID
+	}
+	return tracked
+}
+
+// dropVanished removes a worker whose container no longer exists, recording it
+// as an exit. Returns false if the worker changed meanwhile.
+func (o *Orchestrator) dropVanished(coreID int, containerID string) bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	worker, exists := o.workers[coreID]
+	if !exists || worker.ContainerID != containerID {
+		return false
+	}
+	delete(o.workers, coreID)
+	o.exitedWorkers = append(o.exitedWorkers, ExitedWorker{
+		CoreID:      coreID,
+		ContainerID: containerID[:12],
+		WorkerUUID:  worker.UUID,
+		HostPort:    worker.HostPort,
+		Version:     worker.Version,
+		DetectedAt:  time.Now(),
+		Exit:        &ExitDiagnostics{ExitCode: -1, Error: "container no longer exists", Logs: []string{}},
+	})
+	if len(o.exitedWorkers) > maxExitedWorkers {
+		o.exitedWorkers = o.exitedWorkers[len(o.exitedWorkers)-maxExitedWorkers:]
+	}
+
+	log.Printf("[Audit] Worker on Core %d (container %s) vanished from the runtime; core freed", coreID, containerID[:12])
+	return true
+}
+
+// ownsContainer reports whether a worker container was started by this gateway's
+// node. Containers from before node labels are assumed to be ours.
+func (o *Orchestrator) ownsContainer(labels map[string]string) bool {
+	node, labelled := labels[containerNodeLabel]
+	return !labelled || node == o.nodeName
+}
+
+// LastReconcile returns the most recent janitor report (nil before the first pass)
+func (o *Orchestrator) LastReconcile() *JanitorReport {
+	o.janitor.mu.Lock()
+	defer o.janitor.mu.Unlock()
+	return o.janitor.last
+}
+
+// StartJanitor reconciles the workers map every interval seconds (0 = never)
+func (s *Scheduler) StartJanitor(interval int) {
+	if interval <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(time.Duration(interval) * time.Second)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-s.orchestrator.ctx.Done():
+				return
+			case <-ticker.C:
+				s.reconcile()
+			}
+		}
+	}()
+}
+
+// reconcile runs a janitor pass and lets queued jobs use any cores it freed
+func (s *Scheduler) reconcile() JanitorReport {
+	report := s.orchestrator.Reconcile(s.orchestrator.ctx)
+	for _, action := range report.Actions {
+		if action.Action != janitorUntracked {
+			s.wakeQueue()
+			break
+		}
+	}
+	return report
+}
+
+// handleJanitor reports the last reconciliation pass
+func (s *Server) handleJanitor(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.scheduler.orchestrator.LastReconcile())
+}
+
+// handleRunJanitor reconciles now and reports the result
+func (s *Server) handleRunJanitor(w http.ResponseWriter, r *http.Request) {
+	report := s.scheduler.reconcile()
+	w.Header().Set("Content-Type", "application/json")
+	if report.Error != "" {
+		w.WriteHeader(http.StatusBadGateway)
+	}
+	json.NewEncoder(w).Encode(report)
+}
@@ -0,0 +1,369 @@
+package gateway
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ahmadhassan44/container-orchestrator/pkg/protocol"
+)
+
+// errJobExists is returned when a job is created under an ID already in use
+var errJobExists = errors.New("job ID already in use")
+
+// clientJobIDPattern is the form of a job ID chosen by the submitter: a UUID
+var clientJobIDPattern = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
+
+// JobRecord is the gateway's view of a submitted job
+type JobRecord struct {
+	ID           string                   `json:"job_id"`
+	Status       protocol.Status          `json:"status"`
+	Request      protocol.ComputeRequest  `json:"request"`
+	Source       JobSource                `json:"source"`
+	SubmittedAt  time.Time                `json:"submitted_at"`
+	CompletedAt  time.Time                `json:"completed_at,omitzero"`
+	Response     *protocol.JobResponse    `json:"response,omitempty"`
+	Error        string                   `json:"error,omitempty"`
+	Annotations  *protocol.JobAnnotations `json:"annotations,omitempty"`  // How the scheduler ran the job
+	ETA          *JobETA                  `json:"eta,omitempty"`          // Predicted wait and completion while queued or running
+	WaitReasons  []WaitReason             `json:"wait_reasons,omitempty"` // Why it can't start yet, while queued or held
+	Forced       string                   `json:"forced,omitempty"`       // Admin's reason when the outcome was forced
+	Logs         string                   `json:"-"`                      // Served separately by GET /jobs/{id}/logs
+	Trace        []TraceEvent             `json:"-"`                      // Debug jobs' timeline, served by GET /jobs/{id}/trace
+	TraceDropped int                      `json:"-"`                      // Events past maxTraceEvents
+
+	rev uint64 // Store revision of the record's last change, for replication
+}
+
+// JobStore keeps a bounded, in-memory history of job records
+type JobStore struct {
+	mu         sync.RWMutex
+	jobs       map[string]*JobRecord
+	order      []string                 // Job IDs in submission order (oldest first)
+	done       map[string]chan struct{} // Closed when the job finishes; unfinished jobs only
+	maxHistory int
+	listener   TransitionListener
+	rev        uint64 // Bumped on every change to a record
+}
+
+// TransitionListener is told about every job status change, with a copy of the record
+// as of the change. It runs synchronously on the transitioning goroutine.
+type TransitionListener func(job JobRecord)
+
+// SetTransitionListener registers a callback for job status changes
+func (js *JobStore) SetTransitionListener(fn TransitionListener) {
+	js.mu.Lock()
+	defer js.mu.Unlock()
+	js.listener = fn
+}
+
+// touchLocked stamps a changed record with the next store revision (caller holds js.mu)
+func (js *JobStore) touchLocked(job *JobRecord) {
+	js.rev++
+	job.rev = js.rev
+}
+
+// transitionLocked updates a job's status and returns a notification to run once
+// js.mu is released (nil if the status didn't change or nobody is listening)
+func (js *JobStore) transitionLocked(job *JobRecord, status protocol.Status) func() {
+	changed := job.Status != status
+	job.Status = status
+	if !changed || js.listener == nil {
+		return nil
+	}
+	fn, snapshot := js.listener, *job
+	return func() { fn(snapshot) }
+}
+
+func NewJobStore(maxHistory int) *JobStore {
+	return &JobStore{
+		jobs:       make(map[string]*JobRecord),
+		done:       make(map[string]chan struct{}),
+		maxHistory: maxHistory,
+	}
+}
+
+// Create registers a new job in the Accepted state and returns a copy of its record
+func (js *JobStore) Create(req *protocol.ComputeRequest, source JobSource) (JobRecord, error) {
+	id, err := newJobID()
+	if err != nil {
+		return JobRecord{}, err
+	}
+	return js.CreateWithID(id, req, source)
+}
+
+// CreateWithID is Create with an ID chosen elsewhere, e.g. by the gateway that
+// forwarded the job
+func (js *JobStore) CreateWithID(id string, req *protocol.ComputeRequest, source JobSource) (JobRecord, error) {
+	if id == "" {
+		return JobRecord{}, fmt.Errorf("job ID is required")
+	}
+	js.mu.Lock()
+	defer js.mu.Unlock()
+
+	if _, exists := js.jobs[id]; exists {
+		return JobRecord{}, fmt.Errorf("%w: %s", errJobExists, id)
+	}
+	job := &JobRecord{
+		ID:          id,
+		Status:      protocol.StatusAccepted, // Not announced: the submitter gets the ID in the response
+		Request:     *req,
+		Source:      source,
+		SubmittedAt: time.Now(),
+	}
+	job.Request.JobID = id
+	js.touchLocked(job)
+
+	js.jobs[id] = job
+	js.done[id] = make(chan struct{})
+	js.order = append(js.order, id)
+	js.evictLocked()
+
+	return *job, nil
+}
+
+// UpdateRequest replaces the request of a job that hasn't started, e.g. after PATCH /jobs/{id}
+func (js *JobStore) UpdateRequest(id string, req protocol.ComputeRequest) {
+	js.mu.Lock()
+	defer js.mu.Unlock()
+
+	if job, exists := js.jobs[id]; exists && job.CompletedAt.IsZero() {
+		job.Request = req
+		js.touchLocked(job)
+	}
+}
+
+// SetStatus records a queued/in-progress transition (finished jobs are left as they are)
+func (js *JobStore) SetStatus(id string, status protocol.Status) {
+	js.mu.Lock()
+	var notify func()
+	if job, exists := js.jobs[id]; exists && job.CompletedAt.IsZero() {
+		notify = js.transitionLocked(job, status)
+		js.touchLocked(job)
+	}
+	js.mu.Unlock()
+
+	if notify != nil {
+		notify()
+	}
+}
+
+// Complete marks a job as successfully finished, moving any captured logs onto the record
+func (js *JobStore) Complete(id string, resp *protocol.JobResponse) {
+	js.mu.Lock()
+	var notify func()
+	if job, exists := js.jobs[id]; exists {
+		stored := *resp
+		job.Logs = stored.Logs
+		stored.Logs = ""
+		stored.Annotations = nil // Kept on the record itself
+
+		job.Response = &stored
+		job.CompletedAt = time.Now()
+		js.touchLocked(job)
+		notify = js.transitionLocked(job, protocol.StatusCompleted)
+		js.finishLocked(id)
+	}
+	js.mu.Unlock()
+
+	if notify != nil {
+		notify()
+	}
+}
+
+// MarkForced records that an admin is forcing the job's outcome, and why
+func (js *JobStore) MarkForced(id, reason string) {
+	js.mu.Lock()
+	defer js.mu.Unlock()
+
+	if job, exists := js.jobs[id]; exists {
+		job.Forced = reason
+		js.touchLocked(job)
+	}
+}
+
+// Annotate attaches the scheduler's annotations to a job's record
+func (js *JobStore) Annotate(id string, annotations *protocol.JobAnnotations) {
+	js.mu.Lock()
+	defer js.mu.Unlock()
+
+	if job, exists := js.jobs[id]; exists && annotations != nil {
+		job.Annotations = annotations
+		js.touchLocked(job)
+	}
+}
+
+// Fail marks a job as failed with the given error
+func (js *JobStore) Fail(id string, err error) {
+	js.mu.Lock()
+	var notify func()
+	if job, exists := js.jobs[id]; exists {
+		job.Error = err.Error()
+		job.CompletedAt = time.Now()
+		js.touchLocked(job)
+		notify = js.transitionLocked(job, protocol.StatusFailed)
+		js.finishLocked(id)
+	}
+	js.mu.Unlock()
+
+	if notify != nil {
+		notify()
+	}
+}
+
+// Cancel marks a job as cancelled
+func (js *JobStore) Cancel(id string) {
+	js.mu.Lock()
+	var notify func()
+	if job, exists := js.jobs[id]; exists {
+		job.Error = ErrJobCancelled.Error()
+		job.CompletedAt = time.Now()
+		js.touchLocked(job)
+		notify = js.transitionLocked(job, protocol.StatusCancelled)
+		js.finishLocked(id)
+	}
+	js.mu.Unlock()
+
+	if notify != nil {
+		notify()
+	}
+}
+
+// finishLocked wakes everyone waiting on the job (caller holds js.mu)
+func (js *JobStore) finishLocked(id string) {
+	if done, exists := js.done[id]; exists {
+		close(done)
+		delete(js.done, id)
+	}
+}
+
+// Wait blocks until the job finishes or ctx is done, then returns its current record
+func (js *JobStore) Wait(ctx context.Context, id string) (JobRecord, bool) {
+	js.mu.RLock()
+	done := js.done[id]
+	js.mu.RUnlock()
+
+	if done != nil {
+		select {
+		case <-done:
+		case <-ctx.Done():
+		}
+	}
+	return js.Get(id)
+}
+
+// Get returns a copy of a job record
+func (js *JobStore) Get(id string) (JobRecord, bool) {
+	js.mu.RLock()
+	defer js.mu.RUnlock()
+
+	job, exists := js.jobs[id]
+	if !exists {
+		return JobRecord{}, false
+	}
+	return *job, true
+}
+
+// List returns copies of the most recent jobs (newest first), optionally filtered by
+// source ID, by the stable UUID of the worker that ran them and by labels
+func (js *JobStore) List(sourceID, workerUUID string, labels map[string]string, limit int) []JobRecord {
+	js.mu.RLock()
+	defer js.mu.RUnlock()
+
+	jobs := make([]JobRecord, 0)
+	for i := len(js.order) - 1; i >= 0 && (limit <= 0 || len(jobs) < limit); i-- {
+		job := js.jobs[js.order[i]]
+		if sourceID != "" && job.Source.ID() != sourceID {
+			continue
+		}
+		if workerUUID != "" && job.workerUUID() != workerUUID {
+			continue
+		}
+		if !matchLabels(job.Request.Labels, labels) {
+			continue
+		}
+		jobs = append(jobs, *job)
+	}
+	return jobs
+}
+
+// ChangedSince returns copies of the records changed after revision rev, oldest
+// first, and the store's current revision
+func (js *JobStore) ChangedSince(rev uint64) ([]JobRecord, uint64) {
+	js.mu.RLock()
+	defer js.mu.RUnlock()
+
+	jobs := make([]JobRecord, 0)
+	for _, id := range js.order {
+		if job := js.jobs[id]; job.rev > rev {
+			jobs = append(jobs, *job)
+		}
+	}
+	return jobs, js.rev
+}
+
+// Replicate stores a copy of another gateway's record as-is, without telling the
+// transition listener: the other gateway already did
+func (js *JobStore) Replicate(record JobRecord) {
+	js.mu.Lock()
+	defer js.mu.Unlock()
+
+	if job, exists := js.jobs[record.ID]; exists {
+		record.Logs, record.Trace, record.TraceDropped = job.Logs, job.Trace, job.TraceDropped
+		*job = record
+	} else {
+		job := record
+		js.jobs[record.ID] = &job
+		js.done[record.ID] = make(chan struct{})
+		js.order = append(js.order, record.ID)
+	}
+	js.touchLocked(js.jobs[record.ID])
+	if !record.CompletedAt.IsZero() {
+		js.finishLocked(record.ID)
+	}
+	js.evictLocked()
+}
+
+// workerUUID is the stable identity of the worker the job ran (or last ran) on
+func (job *JobRecord) workerUUID() string {
+	if job.Response != nil && job.Response.WorkerUUID != "" {
+		return job.Response.WorkerUUID
+	}
+	if job.Annotations != nil {
+		return job.Annotations.WorkerUUID
+	}
+	return ""
+}
+
+// evictLocked drops the oldest records beyond maxHistory (caller holds js.mu)
+func (js *JobStore) evictLocked() {
+	for js.maxHistory > 0 && len(js.order) > js.maxHistory {
+		js.finishLocked(js.order[0])
+		delete(js.jobs, js.order[0])
+		js.order = js.order[1:]
+	}
+}
+
+// normalizeClientJobID lowercases a job ID chosen by the submitter, checking it
+// is a UUID
+func normalizeClientJobID(id string) (string, error) {
+	normalized := strings.ToLower(id)
+	if !clientJobIDPattern.MatchString(normalized) {
+		return "", fmt.Errorf("job_id %q must be a UUID, e.g. 3f2b8c1e-9a4d-4e7f-b6c5-0d1e2f3a4b5c", id)
+	}
+	return normalized, nil
+}
+
+func newJobID() (string, error) {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate job ID: %w", err)
+	}
+	return "JOB-" + hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,60 @@
+package gateway
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Limits on the labels a job may carry
+const (
+	maxLabels          = 16
+	maxLabelKeyLength  = 63
+	maxLabelValueBytes = 256
+)
+
+// validateLabels checks a job's labels: short, non-empty keys without '=' or ','
+// so every label can be written as a key=value selector
+func validateLabels(labels map[string]string) error {
+	if len(labels) > maxLabels {
+		return fmt.Errorf("too many labels: %d (max %d)", len(labels), maxLabels)
+	}
+	for key, value := range labels {
+		if key == "" || len(key) > maxLabelKeyLength || strings.ContainsAny(key, "=,") {
+			return fmt.Errorf("invalid label key %q: must be 1-%d characters without '=' or ','", key, maxLabelKeyLength)
+		}
+		if len(value) > maxLabelValueBytes {
+			return fmt.Errorf("label %q: value longer than %d bytes", key, maxLabelValueBytes)
+		}
+	}
+	return nil
+}
+
+// parseLabelSelector turns ?label=key=value parameters into labels a job must
+// all carry (nil when there are none)
+func parseLabelSelector(params []string) (map[string]string, error) {
+	if len(params) == 0 {
+		return nil, nil
+	}
+	selector := make(map[string]string, len(params))
+	for _, param := range params {
+		key, value, ok := strings.Cut(param, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("label selector %q must be key=value", param)
+		}
+		if existing, dup := selector[key]; dup && existing != value {
+			return nil, fmt.Errorf("label selector gives %q twice with different values", key)
+		}
+		selector[key] = value
+	}
+	return selector, nil
+}
+
+// matchLabels reports whether labels carry every key=value in selector
+func matchLabels(labels, selector map[string]string) bool {
+	for key, want := range selector {
+		if got, exists := labels[key]; !exists || got != want {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,121 @@
+package gateway
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// limiterTolerance is how far a request's overhead may exceed the long-run
+	// average before it counts as a sign of congestion
+	limiterTolerance = 2.0
+
+	// limiterBackoff is the multiplicative decrease applied on congestion
+	limiterBackoff = 0.9
+
+	// limiterSmoothing weights each new sample in the long-run overhead average
+	limiterSmoothing = 0.05
+
+	// limiterNoiseFloor is overhead too small to signal anything, whatever the average
+	limiterNoiseFloor = 50 * time.Millisecond
+)
+
+// ConcurrencyLimiter caps in-flight /submit requests with an AIMD limit driven by
+// scheduling overhead: the time a request spends neither running on a worker nor
+// waiting in a queue, i.e. in the scheduler mutex, spawning workers and talking to
+// Docker. The limit grows by one while requests keep the gateway busy and overhead
+// stays near its average, and shrinks by 10% when overhead spikes, so load is shed
+// before those shared paths saturate.
+type ConcurrencyLimiter struct {
+	mu       sync.Mutex
+	enabled  bool
+	limit    float64
+	min, max float64
+	inflight int
+	average  time.Duration // Long-run average overhead (0 until the first sample)
+	metrics  *Metrics
+}
+
+func NewConcurrencyLimiter(enabled bool, initial, minLimit, maxLimit int, metrics *Metrics) *ConcurrencyLimiter {
+	metrics.Register("orchestrator_concurrency_limit", metricGauge, "Current adaptive limit on in-flight /submit requests")
+	metrics.Register("orchestrator_concurrency_inflight", metricGauge, "In-flight /submit requests")
+	metrics.Register("orchestrator_concurrency_shed_total", metricCounter, "Submissions rejected by the adaptive concurrency limit")
+
+	minLimit = max(minLimit, 1)
+	maxLimit = max(maxLimit, minLimit)
+	l := &ConcurrencyLimiter{
+		enabled: enabled,
+		limit:   float64(min(max(initial, minLimit), maxLimit)),
+		min:     float64(minLimit),
+		max:     float64(maxLimit),
+		metrics: metrics,
+	}
+	l.publishLocked()
+	return l
+}
+
+// Acquire admits a request if fewer than limit are in flight. The caller must
+// call release exactly once, with the request's scheduling overhead (0 if it
+// never reached the scheduler).
+func (l *ConcurrencyLimiter) Acquire() (release func(overhead time.Duration), ok bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.enabled && float64(l.inflight) >= l.limit {
+		l.metrics.Inc("orchestrator_concurrency_shed_total")
+		return nil, false
+	}
+	l.inflight++
+	l.publishLocked()
+
+	var once sync.Once
+	return func(overhead time.Duration) {
+		once.Do(func() { l.release(overhead) })
+	}, true
+}
+
+func (l *ConcurrencyLimiter) release(overhead time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// Only requests that saw a busy gateway say anything about raising the limit
+	busy := float64(l.inflight) >= l.limit/2
+	l.inflight--
+
+	if overhead > 0 {
+		congested := overhead > limiterNoiseFloor && l.average > 0 &&
+			float64(overhead) > limiterTolerance*float64(l.average)
+
+		switch {
+		case congested:
+			l.limit = max(l.limit*limiterBackoff, l.min)
+		case busy:
+			l.limit = min(l.limit+1, l.max)
+		}
+
+		if l.average == 0 {
+			l.average = overhead
+		} else {
+			l.average = time.Duration(limiterSmoothing*float64(overhead) + (1-limiterSmoothing)*float64(l.average))
+		}
+	}
+	l.publishLocked()
+}
+
+// Status reports the current limit, in-flight count and average overhead
+func (l *ConcurrencyLimiter) Status() map[string]interface{} {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return map[string]interface{}{
+		"enabled":          l.enabled,
+		"limit":            int(l.limit),
+		"inflight":         l.inflight,
+		"overhead_average": l.average.Seconds(),
+	}
+}
+
+func (l *ConcurrencyLimiter) publishLocked() {
+	l.metrics.Set("orchestrator_concurrency_limit", float64(int(l.limit)))
+	l.metrics.Set("orchestrator_concurrency_inflight", float64(l.inflight))
+}
@@ -0,0 +1,244 @@
+package gateway
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/ahmadhassan44/container-orchestrator/pkg/config"
+)
+
+// ListenerSpec is what the public listener binds: a port, and a certificate and
+// key for TLS (both empty = plain HTTP)
+type ListenerSpec struct {
+	Port     int    `json:"port"`
+	CertFile string `json:"tls_cert,omitempty"`
+	KeyFile  string `json:"tls_key,omitempty"`
+}
+
+func (spec ListenerSpec) tls() bool {
+	return spec.CertFile != ""
+}
+
+func (spec ListenerSpec) String() string {
+	if spec.tls() {
+		return fmt.Sprintf(":%d (TLS, cert %s)", spec.Port, spec.CertFile)
+	}
+	return fmt.Sprintf(":%d", spec.Port)
+}
+
+// ListenerManager serves the public API and swaps its listener without dropping
+// requests: the new listener is bound and serving before the old one stops
+// accepting, and the old server's in-flight requests get up to drainTimeout to
+// finish. Listeners are bound with SO_REUSEPORT where available, so the same port
+// can be rebound (e.g. to rotate a certificate).
+type ListenerManager struct {
+	drainTimeout time.Duration
+
+	mu       sync.Mutex
+	handler  http.Handler
+	spec     ListenerSpec
+	current  *http.Server
+	since    time.Time
+	draining int
+	reloads  int
+	failed   chan error // Fatal serve errors of the current server
+}
+
+func NewListenerManager(cfg *config.Config) *ListenerManager {
+	return &ListenerManager{
+		drainTimeout: time.Duration(max(cfg.ListenerDrainTimeout, 0)) * time.Second,
+		spec:         ListenerSpec{Port: cfg.GatewayPort, CertFile: cfg.TLSCertFile, KeyFile: cfg.TLSKeyFile},
+		failed:       make(chan error, 1),
+	}
+}
+
+// Serve binds the configured listener and serves handler on it until a listener
+// fails
+func (lm *ListenerManager) Serve(handler http.Handler) error {
+	lm.mu.Lock()
+	lm.handler = handler
+	err := lm.swapLocked(lm.spec)
+	lm.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	return <-lm.failed
+}
+
+// Reload rebinds the listener with spec, keeping the current port or certificate
+// for any field left empty. On error the current listener keeps serving.
+func (lm *ListenerManager) Reload(spec ListenerSpec, disableTLS bool) error {
+	lm.mu.Lock()
+	defer lm.mu.Unlock()
+
+	if lm.current == nil {
+		return fmt.Errorf("listener is not serving yet")
+	}
+	if spec.Port == 0 {
+		spec.Port = lm.spec.Port
+	}
+	switch {
+	case disableTLS:
+		spec.CertFile, spec.KeyFile = "", ""
+	case spec.CertFile == "" && spec.KeyFile == "":
+		spec.CertFile, spec.KeyFile = lm.spec.CertFile, lm.spec.KeyFile
+	case spec.CertFile == "" || spec.KeyFile == "":
+		return fmt.Errorf("tls_cert and tls_key must be given together")
+	}
+
+	if err := lm.swapLocked(spec); err != nil {
+		return err
+	}
+	lm.reloads++
+	return nil
+}
+
+// swapLocked binds and starts serving spec, then drains the previous server (caller holds lm.mu)
+func (lm *ListenerManager) swapLocked(spec ListenerSpec) error {
+	srv := &http.Server{Handler: lm.handler}
+	if spec.tls() {
+		// Certificates are read on every reload, so rotated files are picked up
+		cert, err := tls.LoadX509KeyPair(spec.CertFile, spec.KeyFile)
+		if err != nil {
+			return fmt.Errorf("failed to load TLS certificate: %w", err)
+		}
+		srv.TLSConfig = &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}
+	}
+
+	lc := net.ListenConfig{Control: reusePort}
+	ln, err := lc.Listen(context.Background(), "tcp", fmt.Sprintf(":%d", spec.Port))
+	if err != nil {
+		return fmt.Errorf("failed to bind %s: %w", spec, err)
+	}
+
+	go func() {
+		var err error
+		if spec.tls() {
+			err = srv.ServeTLS(ln, "", "")
+		} else {
+			err = srv.Serve(ln)
+		}
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			select {
+			case lm.failed <- err:
+			default:
+			}
+		}
+	}()
+
+	old, oldSpec := lm.current, lm.spec
+	lm.current, lm.spec, lm.since = srv, spec, time.Now()
+	log.Printf("[Gateway] HTTP server listening on %s", spec)
+
+	if old != nil {
+		lm.draining++
+		go lm.drain(old, oldSpec)
+	}
+	return nil
+}
+
+// drain stops a replaced server from accepting and waits for its in-flight
+// requests, closing whatever is left after the drain timeout
+func (lm *ListenerManager) drain(srv *http.Server, spec ListenerSpec) {
+	log.Printf("[Gateway] Draining previous listener %s (up to %s)", spec, lm.drainTimeout)
+
+	ctx, cancel := context.WithTimeout(context.Background(), lm.drainTimeout)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		log.Printf("[WARNING] Previous listener %s still had requests after %s, closing them: %v", spec, lm.drainTimeout, err)
+		srv.Close()
+	} else {
+		log.Printf("[Gateway] Previous listener %s drained", spec)
+	}
+
+	lm.mu.Lock()
+	lm.draining--
+	lm.mu.Unlock()
+}
+
+// Spec returns what the current listener binds
+func (lm *ListenerManager) Spec() ListenerSpec {
+	lm.mu.Lock()
+	defer lm.mu.Unlock()
+	return lm.spec
+}
+
+// Status reports the current listener
+func (lm *ListenerManager) Status() map[string]interface{} {
+	lm.mu.Lock()
+	defer lm.mu.Unlock()
+
+	return map[string]interface{}{
+		"port":          lm.spec.Port,
+		"tls":           lm.spec.tls(),
+		"tls_cert":      lm.spec.CertFile,
+		"since":         lm.since,
+		"reloads":       lm.reloads,
+		"draining":      lm.draining,
+		"drain_timeout": lm.drainTimeout.Seconds(),
+	}
+}
+
+// Shutdown stops the current server accepting and waits until ctx ends for its
+// in-flight requests, closing whatever is left
+func (lm *ListenerManager) Shutdown(ctx context.Context) error {
+	lm.mu.Lock()
+	srv := lm.current
+	lm.mu.Unlock()
+	if srv == nil {
+		return nil
+	}
+	if err := srv.Shutdown(ctx); err != nil {
+		srv.Close()
+		return fmt.Errorf("closing requests still in flight: %w", err)
+	}
+	return nil
+}
+
+// Shutdown stops the public listener once its in-flight requests have finished
+// or ctx ends
+func (s *Server) Shutdown(ctx context.Context) error {
+	return s.listener.Shutdown(ctx)
+}
+
+// ReloadListener rebinds the public listener, keeping anything spec leaves empty
+func (s *Server) ReloadListener(spec ListenerSpec, disableTLS bool) error {
+	return s.listener.Reload(spec, disableTLS)
+}
+
+// handleGetListener reports the public listener's configuration
+func (s *Server) handleGetListener(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.listener.Status())
+}
+
+// handleReloadListener rebinds the public listener. An empty body rebinds the same
+// port and rereads the certificate files.
+func (s *Server) handleReloadListener(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		ListenerSpec
+		DisableTLS bool `json:"disable_tls"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, fmt.Sprintf("Invalid JSON: %v", err), http.StatusBadRequest)
+			return
+		}
+	}
+	if err := s.ReloadListener(body.ListenerSpec, body.DisableTLS); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	log.Printf("[Audit] Listener reloaded: %s", s.listener.Spec())
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.listener.Status())
+}
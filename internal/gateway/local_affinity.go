@@ -0,0 +1,105 @@
+//go:build linux
+
+package gateway
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+
+	"golang.org/x/sys/unix"
+)
+
+// hostCPUs is the gateway's affinity at startup, before GATEWAY_CPUSET pins it
+var hostCPUs = func() (set unix.CPUSet) {
+	unix.SchedGetaffinity(0, &set)
+	return set
+}()
+
+// startPinned starts cmd on cpus. The child inherits the affinity and priorities
+// of the thread that forks it, so that thread is pinned to cpus, and relieved of
+// any priority the gateway raised for itself, for the duration of the start.
+// Pinning the process afterwards would miss threads its runtime had already started.
+func startPinned(cmd *exec.Cmd, cpus []int) error {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	var previous unix.CPUSet
+	if err := unix.SchedGetaffinity(0, &previous); err != nil {
+		return cmd.Start()
+	}
+	// Keep to the CPUs the host lets the gateway use; a smaller host than the
+	// core map assumes has fewer
+	var usable []int
+	for _, cpu := range cpus {
+		if hostCPUs.IsSet(cpu) {
+			usable = append(usable, cpu)
+		}
+	}
+	target := &hostCPUs
+	switch {
+	case len(usable) > 0:
+		target = cpuSetOf(usable)
+	case len(cpus) > 0:
+		log.Printf("[LocalRuntime] None of CPUs %v are available, starting unpinned", cpus)
+	}
+	if err := unix.SchedSetaffinity(0, target); err != nil {
+		log.Printf("[LocalRuntime] Can't pin to CPUs %v, starting unpinned: %v", cpus, err)
+	} else {
+		defer unix.SchedSetaffinity(0, &previous)
+	}
+
+	// Getpriority returns 20 - niceness
+	if raw, err := unix.Getpriority(unix.PRIO_PROCESS, 0); err == nil && raw != 20 {
+		if unix.Setpriority(unix.PRIO_PROCESS, 0, 0) == nil {
+			defer unix.Setpriority(unix.PRIO_PROCESS, 0, 20-raw)
+		}
+	}
+	if prio, _, errno := unix.Syscall(unix.SYS_IOPRIO_GET, ioprioWhoProcess, 0, 0); errno == 0 && prio != 0 {
+		if _, _, errno := unix.Syscall(unix.SYS_IOPRIO_SET, ioprioWhoProcess, 0, 0); errno == 0 {
+			defer unix.Syscall(unix.SYS_IOPRIO_SET, ioprioWhoProcess, 0, prio)
+		}
+	}
+	return cmd.Start()
+}
+
+// repin moves every thread of a running process onto cpus
+func repin(pid int, cpus []int) error {
+	set := cpuSetOf(cpus)
+	return eachThread(pid, func(tid int) error {
+		if err := unix.SchedSetaffinity(tid, set); err != nil {
+			return fmt.Errorf("can't pin thread %d to CPUs %v: %w", tid, cpus, err)
+		}
+		return nil
+	})
+}
+
+// eachThread calls fn with the ID of every thread of a running process, for
+// the settings Linux keeps per thread
+func eachThread(pid int, fn func(tid int) error) error {
+	tasks, err := os.ReadDir(fmt.Sprintf("/proc/%d/task", pid))
+	if err != nil {
+		return fmt.Errorf("can't list threads of %d: %w", pid, err)
+	}
+	for _, task := range tasks {
+		tid, err := strconv.Atoi(task.Name())
+		if err != nil {
+			continue
+		}
+		if err := fn(tid); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func cpuSetOf(cpus []int) *unix.CPUSet {
+	var set unix.CPUSet
+	for _, cpu := range cpus {
+		set.Set(cpu)
+	}
+	return &set
+}
@@ -0,0 +1,18 @@
+//go:build !linux
+
+package gateway
+
+import (
+	"fmt"
+	"os/exec"
+)
+
+// startPinned starts cmd unpinned: CPU affinity is only supported on Linux
+func startPinned(cmd *exec.Cmd, cpus []int) error {
+	return cmd.Start()
+}
+
+// repin can't move a process between CPUs off Linux
+func repin(pid int, cpus []int) error {
+	return fmt.Errorf("CPU affinity is not supported on this platform")
+}
@@ -0,0 +1,436 @@
+package gateway
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ahmadhassan44/container-orchestrator/pkg/config"
+)
+
+// maintenanceInterval is how often windows are checked for state changes
+const maintenanceInterval = 5 * time.Second
+
+// Maintenance window states
+const (
+	maintenanceScheduled = "scheduled" // Not yet within the drain lead
+	maintenanceDraining  = "draining"  // Within the drain lead: no new workers, only jobs that finish in time
+	maintenanceActive    = "active"    // Affected cores run no jobs and idle workers are stopped
+	maintenanceEnded     = "ended"
+)
+
+// MaintenanceWindow reserves cores for maintenance between Start and End
+type MaintenanceWindow struct {
+	ID     string    `json:"id"`
+	Start  time.Time `json:"start"`
+	End    time.Time `json:"end"`
+	Cores  []int     `json:"cores,omitempty"` // Empty = every core
+	Reason string    `json:"reason,omitempty"`
+	Source string    `json:"source"` // "config" or "api"
+}
+
+func (w *MaintenanceWindow) affects(coreID int) bool {
+	if len(w.Cores) == 0 {
+		return true
+	}
+	for _, core := range w.Cores {
+		if core == coreID {
+			return true
+		}
+	}
+	return false
+}
+
+// overlaps reports whether the window affects coreID at any time in [from, to]
+func (w *MaintenanceWindow) overlaps(coreID int, from, to time.Time) bool {
+	return w.affects(coreID) && from.Before(w.End) && to.After(w.Start)
+}
+
+// maintenanceTransition is a window entering a new state
+type maintenanceTransition struct {
+	window MaintenanceWindow
+	state  string
+}
+
+// Maintenance holds the declared maintenance windows. Jobs are not started on a
+// core if their estimated completion would overlap one of its windows, no
+// workers are spawned on a core from drainLead before its window, and idle
+// workers on a core are stopped while its window is active.
+type Maintenance struct {
+	drainLead time.Duration
+
+	mu      sync.Mutex
+	windows []*MaintenanceWindow // By start
+	states  map[string]string    // Window ID -> state at the last advance
+	nextID  int
+}
+
+func NewMaintenance(cfg *config.Config) *Maintenance {
+	m := &Maintenance{
+		drainLead: time.Duration(max(cfg.MaintenanceDrainLead, 0)) * time.Second,
+		states:    make(map[string]string),
+	}
+	for _, spec := range strings.Split(cfg.MaintenanceWindows, ";") {
+		if spec = strings.TrimSpace(spec); spec == "" {
+			continue
+		}
+		start, end, cores, err := parseMaintenanceWindow(spec)
+		if err == nil {
+			_, err = m.Add(start, end, cores, "", "config")
+		}
+		if err != nil {
+			log.Printf("[WARNING] Ignoring maintenance window %q: %v", spec, err)
+		}
+	}
+	return m
+}
+
+// parseMaintenanceWindow parses "start/end[@core,core]" with RFC3339 times
+func parseMaintenanceWindow(spec string) (start, end time.Time, cores []int, err error) {
+	span, coreList, hasCores := strings.Cut(spec, "@")
+	from, to, found := strings.Cut(span, "/")
+	if !found {
+		return start, end, nil, fmt.Errorf("expected start/end")
+	}
+	if start, err = time.Parse(time.RFC3339, strings.TrimSpace(from)); err != nil {
+		return start, end, nil, fmt.Errorf("invalid start: %w", err)
+	}
+	if end, err = time.Parse(time.RFC3339, strings.TrimSpace(to)); err != nil {
+		return start, end, nil, fmt.Errorf("invalid end: %w", err)
+	}
+	if hasCores {
+		for _, item := range strings.Split(coreList, ",") {
+			core, err := strconv.Atoi(strings.TrimSpace(item))
+			if err != nil {
+				return start, end, nil, fmt.Errorf("invalid core %q", item)
+			}
+			cores = append(cores, core)
+		}
+	}
+	return start, end, cores, nil
+}
+
+// Add declares a window, returning it with its assigned ID
+func (m *Maintenance) Add(start, end time.Time, cores []int, reason, source string) (MaintenanceWindow, error) {
+	if !end.After(start) {
+		return MaintenanceWindow{}, fmt.Errorf("end must be after start")
+	}
+	if !end.After(time.Now()) {
+		return MaintenanceWindow{}, fmt.Errorf("window has already ended")
+	}
+	for _, core := range cores {
+		if _, exists := coreMaps[core]; !exists {
+			return MaintenanceWindow{}, fmt.Errorf("unknown core %d", core)
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.nextID++
+	w := &MaintenanceWindow{
+		ID:     fmt.Sprintf("MW-%d", m.nextID),
+		Start:  start,
+		End:    end,
+		Cores:  append([]int(nil), cores...),
+		Reason: reason,
+		Source: source,
+	}
+	sort.Ints(w.Cores)
+	m.windows = append(m.windows, w)
+	sort.SliceStable(m.windows, func(i, j int) bool { return m.windows[i].Start.Before(m.windows[j].Start) })
+	m.states[w.ID] = maintenanceScheduled
+
+	log.Printf("[Maintenance] Window %s declared: %s to %s on %s", w.ID,
+		w.Start.Format(time.RFC3339), w.End.Format(time.RFC3339), coresLabel(w.Cores))
+	return *w, nil
+}
+
+// Remove cancels a window; false if there is no such window
+func (m *Maintenance) Remove(id string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i, w := range m.windows {
+		if w.ID == id {
+			m.windows = append(m.windows[:i], m.windows[i+1:]...)
+			delete(m.states, id)
+			log.Printf("[Maintenance] Window %s cancelled", id)
+			return true
+		}
+	}
+	return false
+}
+
+// stateAt is a window's state at now
+func (m *Maintenance) stateAt(w *MaintenanceWindow, now time.Time) string {
+	switch {
+	case !now.Before(w.End):
+		return maintenanceEnded
+	case !now.Before(w.Start):
+		return maintenanceActive
+	case !now.Before(w.Start.Add(-m.drainLead)):
+		return maintenanceDraining
+	default:
+		return maintenanceScheduled
+	}
+}
+
+// Clear reports whether a job of duration seconds started on coreID now would
+// finish before the core's next window
+func (m *Maintenance) Clear(coreID int, duration float64) bool {
+	now := time.Now()
+	end := now.Add(time.Duration(duration * float64(time.Second)))
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, w := range m.windows {
+		if w.overlaps(coreID, now, end) {
+			return false
+		}
+	}
+	return true
+}
+
+// Spawnable reports whether a new worker may be started on coreID: none of its
+// windows is draining or active
+func (m *Maintenance) Spawnable(coreID int) bool {
+	now := time.Now()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, w := range m.windows {
+		if w.overlaps(coreID, now, now.Add(m.drainLead)) {
+			return false
+		}
+	}
+	return true
+}
+
+// Active reports whether coreID is inside an active window
+func (m *Maintenance) Active(coreID int, now time.Time) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, w := range m.windows {
+		if w.affects(coreID) && m.stateAt(w, now) == maintenanceActive {
+			return true
+		}
+	}
+	return false
+}
+
+// advance moves windows to their state at now, dropping ended ones, and returns
+// the windows whose state changed
+func (m *Maintenance) advance(now time.Time) []maintenanceTransition {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var transitions []maintenanceTransition
+	kept := m.windows[:0]
+	for _, w := range m.windows {
+		state := m.stateAt(w, now)
+		if state != m.states[w.ID] {
+			transitions = append(transitions, maintenanceTransition{window: *w, state: state})
+		}
+		if state == maintenanceEnded {
+			delete(m.states, w.ID)
+			continue
+		}
+		m.states[w.ID] = state
+		kept = append(kept, w)
+	}
+	m.windows = kept
+	return transitions
+}
+
+// Windows returns the scheduled windows
+func (m *Maintenance) Windows() []MaintenanceWindow {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	windows := make([]MaintenanceWindow, 0, len(m.windows))
+	for _, w := range m.windows {
+		windows = append(windows, *w)
+	}
+	return windows
+}
+
+// Status lists the windows with their current state
+func (m *Maintenance) Status() []map[string]interface{} {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	windows := make([]map[string]interface{}, 0, len(m.windows))
+	for _, w := range m.windows {
+		windows = append(windows, map[string]interface{}{
+			"id":          w.ID,
+			"start":       w.Start,
+			"end":         w.End,
+			"cores":       w.Cores,
+			"reason":      w.Reason,
+			"source":      w.Source,
+			"state":       m.stateAt(w, now),
+			"drain_start": w.Start.Add(-m.drainLead),
+		})
+	}
+	return windows
+}
+
+func coresLabel(cores []int) string {
+	if len(cores) == 0 {
+		return "all cores"
+	}
+	return fmt.Sprintf("cores %v", cores)
+}
+
+// StartMaintenance begins acting on maintenance windows: logging drain plans as
+// windows approach, and stopping idle workers on cores under maintenance
+func (s *Scheduler) StartMaintenance() {
+	if windows := s.maintenance.Status(); len(windows) > 0 {
+		log.Printf("[Maintenance] %d window(s) declared, drain lead %s", len(windows), s.maintenance.drainLead)
+	}
+
+	go func() {
+		ticker := time.NewTicker(maintenanceInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-s.orchestrator.ctx.Done():
+				return
+			case <-ticker.C:
+				s.maintain()
+			}
+		}
+	}()
+}
+
+// maintain reports window transitions and stops idle workers on cores under
+// maintenance. Busy workers are left to finish their jobs; nothing is killed.
+func (s *Scheduler) maintain() {
+	now := time.Now()
+	running := s.RunningJobs()
+
+	for _, t := range s.maintenance.advance(now) {
+		w := t.window
+		switch t.state {
+		case maintenanceDraining:
+			log.Printf("[Maintenance] Window %s starts at %s on %s: draining, only jobs that finish in time are placed there",
+				w.ID, w.Start.Format(time.RFC3339), coresLabel(w.Cores))
+			for _, job := range running {
+				if !w.affects(job.CoreID) {
+					continue
+				}
+				finish := now.Add(time.Duration(job.RemainingSeconds * float64(time.Second)))
+				if finish.After(w.Start) {
+					log.Printf("[WARNING] Job %s on Core %d is expected to run %s into maintenance window %s",
+						job.JobID, job.CoreID, finish.Sub(w.Start).Round(time.Second), w.ID)
+				} else {
+					log.Printf("[Maintenance] Job %s on Core %d should finish %s before window %s",
+						job.JobID, job.CoreID, w.Start.Sub(finish).Round(time.Second), w.ID)
+				}
+			}
+		case maintenanceActive:
+			log.Printf("[Maintenance] Window %s active until %s on %s",
+				w.ID, w.End.Format(time.RFC3339), coresLabel(w.Cores))
+		case maintenanceEnded:
+			log.Printf("[Maintenance] Window %s ended, %s available again", w.ID, coresLabel(w.Cores))
+			s.wakeQueue()
+		}
+	}
+
+	busy := make(map[int]bool)
+	for _, job := range running {
+		busy[job.CoreID] = true
+	}
+
+	// Detach under the scheduling lock so nothing is placed on a worker between
+	// the idle check and its removal
+	s.scheduleMux.Lock()
+	var stopped []*WorkerInfo
+	for _, worker := range s.orchestrator.GetAllWorkers() {
+		if !s.maintenance.Active(worker.CoreID, now) || busy[worker.CoreID] || worker.CurrentCPU >= idleCPUThreshold ||
+			s.benchmarks.Reserved(worker.CoreID) {
+			continue
+		}
+		if detached, ok := s.orchestrator.DetachWorker(worker.CoreID); ok {
+			stopped = append(stopped, detached)
+		}
+	}
+	s.scheduleMux.Unlock()
+
+	for _, worker := range stopped {
+		log.Printf("[Maintenance] Stopping idle worker on Core %d for maintenance", worker.CoreID)
+		s.orchestrator.StopDetached(worker)
+	}
+}
+
+// placeableWorkers are the workers a job of duration seconds may start on
+// without running into a maintenance window, excluding those reserved for
+// benchmarking and those its taints, sandbox requirement or queue rule out
+func (s *Scheduler) placeableWorkers(duration float64, queue, operation string, tolerations []string) []*WorkerInfo {
+	workers := s.orchestrator.GetAllWorkers()
+	placeable := workers[:0]
+	for _, worker := range workers {
+		if s.maintenance.Clear(worker.CoreID, duration) && !s.benchmarks.Reserved(worker.CoreID) &&
+			s.taints.Tolerated(worker.CoreID, tolerations) && s.sandbox.Allows(worker.CoreID, operation, tolerations) &&
+			s.queues.allows(queue, worker.CoreID) {
+			placeable = append(placeable, worker)
+		}
+	}
+	return placeable
+}
+
+// handleGetMaintenance lists maintenance windows
+func (s *Server) handleGetMaintenance(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"drain_lead": s.scheduler.maintenance.drainLead.Seconds(),
+		"windows":    s.scheduler.maintenance.Status(),
+	})
+}
+
+// handleAddMaintenance declares a window:
+// {"start": "2025-01-01T02:00:00Z", "end": "2025-01-01T03:00:00Z", "cores": [2], "reason": "kernel update"}
+func (s *Server) handleAddMaintenance(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Start  time.Time `json:"start"`
+		End    time.Time `json:"end"`
+		Cores  []int     `json:"cores"`
+		Reason string    `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.Start.IsZero() || body.End.IsZero() {
+		http.Error(w, "Body must be JSON with RFC3339 \"start\" and \"end\"", http.StatusBadRequest)
+		return
+	}
+	window, err := s.scheduler.maintenance.Add(body.Start, body.End, body.Cores, body.Reason, "api")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	log.Printf("[Audit] Maintenance window %s declared on %s", window.ID, coresLabel(window.Cores))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(window)
+}
+
+// handleRemoveMaintenance cancels a window
+func (s *Server) handleRemoveMaintenance(w http.ResponseWriter, r *http.Request) {
+	if !s.scheduler.maintenance.Remove(r.PathValue("id")) {
+		http.Error(w, "Maintenance window not found", http.StatusNotFound)
+		return
+	}
+	s.scheduler.wakeQueue()
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,551 @@
+package gateway
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"mime"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/ahmadhassan44/container-orchestrator/pkg/protocol"
+)
+
+// maxManifestBytes bounds an uploaded manifest document
+const maxManifestBytes = 1 << 20
+
+// Manifest run states
+const (
+	ManifestRunning   = "running"
+	ManifestCompleted = "completed" // Every job completed
+	ManifestFailed    = "failed"    // A job failed, or was skipped because a job it needs failed
+	ManifestCancelled = "cancelled"
+)
+
+// ManifestRunJob is one job's place in a manifest run
+type ManifestRunJob struct {
+	Name     string          `json:"name"`
+	JobID    string          `json:"job_id"`
+	Needs    []string        `json:"needs,omitempty"`
+	Priority int             `json:"priority,omitempty"`
+	Status   protocol.Status `json:"status"`
+	Skipped  string          `json:"skipped,omitempty"` // Why the job never ran
+}
+
+// ManifestRunRecord is the gateway's view of a submitted manifest
+type ManifestRunRecord struct {
+	ID         string           `json:"run_id"`
+	Name       string           `json:"name,omitempty"`
+	State      string           `json:"state"`
+	CreatedAt  time.Time        `json:"created_at"`
+	FinishedAt time.Time        `json:"finished_at,omitzero"`
+	Jobs       []ManifestRunJob `json:"jobs"`
+}
+
+// manifestRun tracks a running manifest; its record is guarded by mu
+type manifestRun struct {
+	mu        sync.Mutex
+	record    ManifestRunRecord
+	order     []int // Job indexes, each after every job it needs
+	started   []bool
+	failed    bool // A job failed or was skipped
+	cancelled bool
+}
+
+// ManifestStore keeps a bounded, in-memory history of manifest runs
+type ManifestStore struct {
+	mu         sync.RWMutex
+	runs       map[string]*manifestRun
+	order      []string
+	maxHistory int
+}
+
+func NewManifestStore(maxHistory int) *ManifestStore {
+	return &ManifestStore{runs: make(map[string]*manifestRun), maxHistory: maxHistory}
+}
+
+func (ms *ManifestStore) add(run *manifestRun) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	ms.runs[run.record.ID] = run
+	ms.order = append(ms.order, run.record.ID)
+	for ms.maxHistory > 0 && len(ms.order) > ms.maxHistory {
+		delete(ms.runs, ms.order[0])
+		ms.order = ms.order[1:]
+	}
+}
+
+func (ms *ManifestStore) get(id string) (*manifestRun, bool) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	run, exists := ms.runs[id]
+	return run, exists
+}
+
+// readManifest reads a manifest from the request body, or from the "manifest"
+// field of a multipart file upload
+func readManifest(w http.ResponseWriter, r *http.Request) ([]byte, error) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxManifestBytes)
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType == "multipart/form-data" {
+		file, _, err := r.FormFile("manifest")
+		if err != nil {
+			return nil, fmt.Errorf("no manifest file in upload: %w", err)
+		}
+		defer file.Close()
+		return io.ReadAll(file)
+	}
+	return io.ReadAll(r.Body)
+}
+
+// parseManifest decodes a YAML or JSON manifest and expands it into one request
+// per job, in manifest order
+func parseManifest(document []byte) (protocol.Manifest, []protocol.ComputeRequest, error) {
+	var manifest protocol.Manifest
+
+	// YAML is a superset of JSON; going through JSON applies the API's field names
+	// and its rejection of unknown fields
+	var generic interface{}
+	if err := yaml.Unmarshal(document, &generic); err != nil {
+		return manifest, nil, fmt.Errorf("invalid manifest: %w", err)
+	}
+	asJSON, err := json.Marshal(generic)
+	if err != nil {
+		return manifest, nil, fmt.Errorf("invalid manifest: %w", err)
+	}
+	if err := decodeStrict(asJSON, &manifest); err != nil {
+		return manifest, nil, fmt.Errorf("invalid manifest: %w", err)
+	}
+
+	if len(manifest.Jobs) == 0 {
+		return manifest, nil, fmt.Errorf("manifest has no jobs")
+	}
+	if len(manifest.Jobs) > maxBatchSize {
+		return manifest, nil, fmt.Errorf("manifest has %d jobs (max %d)", len(manifest.Jobs), maxBatchSize)
+	}
+
+	names := make(map[string]bool, len(manifest.Jobs))
+	for i, job := range manifest.Jobs {
+		if job.Name == "" {
+			return manifest, nil, fmt.Errorf("jobs[%d]: name is required", i)
+		}
+		if names[job.Name] {
+			return manifest, nil, fmt.Errorf("jobs[%d]: duplicate name %q", i, job.Name)
+		}
+		names[job.Name] = true
+	}
+
+	reqs := make([]protocol.ComputeRequest, len(manifest.Jobs))
+	for i, job := range manifest.Jobs {
+		fields := make(map[string]interface{})
+		if job.Template != "" {
+			template, exists := manifest.Templates[job.Template]
+			if !exists {
+				return manifest, nil, fmt.Errorf("job %q: unknown template %q", job.Name, job.Template)
+			}
+			for k, v := range template {
+				fields[k] = v
+			}
+		}
+		for k, v := range job.Request {
+			fields[k] = v
+		}
+		for _, need := range job.Needs {
+			if need == job.Name || !names[need] {
+				return manifest, nil, fmt.Errorf("job %q: needs unknown job %q", job.Name, need)
+			}
+		}
+
+		asJSON, _ := json.Marshal(fields)
+		if err := decodeStrict(asJSON, &reqs[i]); err != nil {
+			return manifest, nil, fmt.Errorf("job %q: %w", job.Name, err)
+		}
+		if reqs[i].Stream {
+			return manifest, nil, fmt.Errorf("job %q: manifest jobs can't be streamed", job.Name)
+		}
+	}
+	return manifest, reqs, nil
+}
+
+// manifestOrder sorts a manifest's jobs so each comes after every job it needs,
+// failing if the dependencies form a cycle
+func manifestOrder(jobs []protocol.ManifestJob) ([]int, error) {
+	index := make(map[string]int, len(jobs))
+	for i, job := range jobs {
+		index[job.Name] = i
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make([]int, len(jobs))
+	order := make([]int, 0, len(jobs))
+	var visit func(i int, path []string) error
+	visit = func(i int, path []string) error {
+		switch state[i] {
+		case visited:
+			return nil
+		case visiting:
+			return fmt.Errorf("dependency cycle: %v", append(path, jobs[i].Name))
+		}
+		state[i] = visiting
+		for _, need := range jobs[i].Needs {
+			if err := visit(index[need], append(path, jobs[i].Name)); err != nil {
+				return err
+			}
+		}
+		state[i] = visited
+		order = append(order, i)
+		return nil
+	}
+	for i := range jobs {
+		if err := visit(i, nil); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
+
+func decodeStrict(data []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(v)
+}
+
+// handleSubmitManifest accepts a manifest document, validates every job in it, and
+// runs the jobs in the background as their dependencies complete. Responds 202
+// with the run ID and each job's ID.
+func (s *Server) handleSubmitManifest(w http.ResponseWriter, r *http.Request) {
+	if s.refuseOnStandby(w) {
+		return
+	}
+	document, err := readManifest(w, r)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+	manifest, reqs, err := parseManifest(document)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	order, err := manifestOrder(manifest.Jobs)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	for i := range reqs {
+		if err := s.validateRequest(&reqs[i]); err != nil {
+			http.Error(w, fmt.Sprintf("job %q: %v", manifest.Jobs[i].Name, err), http.StatusBadRequest)
+			return
+		}
+		if reqs[i].JobID != "" {
+			http.Error(w, fmt.Sprintf("job %q: job_id can only be chosen on /submit; manifest jobs get theirs from the gateway", manifest.Jobs[i].Name), http.StatusBadRequest)
+			return
+		}
+		if infeasible := s.checkFeasible(&reqs[i]); infeasible != nil {
+			index := i
+			infeasible.Job = &index
+			s.metrics.Inc("orchestrator_jobs_total", "status", "infeasible")
+			writeInfeasible(w, infeasible)
+			return
+		}
+	}
+
+	source := s.sources.Identify(r)
+	if s.sources.IsDenied(source) {
+		s.sources.RecordRejected(source)
+		s.metrics.Inc("orchestrator_jobs_total", "status", "rejected")
+		log.Printf("[Gateway] Rejected manifest from denylisted source %s", source.ID())
+		http.Error(w, "Source is blocked", http.StatusForbidden)
+		return
+	}
+	for i := range reqs {
+		if err := s.admit(&reqs[i], source); err != nil {
+			s.writeAdmissionError(w, source, fmt.Errorf("job %q: %w", manifest.Jobs[i].Name, err))
+			return
+		}
+	}
+
+	// Like a batch, the whole manifest is admitted against the quota or none of it is
+	charges := make([]*quotaCharge, 0, len(reqs))
+	release := func() {
+		for _, charge := range charges {
+			s.quotas.Release(charge)
+		}
+	}
+	for _, req := range reqs {
+		charge, admitted := s.quotas.Admit(source.ID(), s.scheduler.EstimateCPUSeconds(&req))
+		if !admitted {
+			release()
+			quota := s.quotas.Status(source.ID())
+			s.metrics.Inc("orchestrator_jobs_total", "status", "quota_exceeded")
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(time.Until(quota.ResetsAt).Seconds()))))
+			http.Error(w, fmt.Sprintf("CPU-seconds quota exceeded: manifest doesn't fit in the %.1f of %.1f remaining until %s",
+				quota.Remaining, quota.Budget, quota.ResetsAt.Format(time.RFC3339)), http.StatusTooManyRequests)
+			return
+		}
+		charges = append(charges, charge)
+	}
+
+	id, err := newManifestRunID()
+	if err != nil {
+		release()
+		http.Error(w, fmt.Sprintf("Manifest failed: %v", err), http.StatusInternalServerError)
+		return
+	}
+	run := &manifestRun{
+		record: ManifestRunRecord{
+			ID:        id,
+			Name:      manifest.Name,
+			State:     ManifestRunning,
+			CreatedAt: time.Now(),
+		},
+		order:   order,
+		started: make([]bool, len(reqs)),
+	}
+
+	jobs := make([]JobRecord, 0, len(reqs))
+	jobIDs := make(map[string]string, len(reqs))
+	for i := range reqs {
+		job, err := s.jobs.Create(&reqs[i], source)
+		if err != nil {
+			for _, created := range jobs {
+				s.jobs.Fail(created.ID, err)
+				s.quotas.Settle(created.ID)
+			}
+			charges = charges[i:]
+			release()
+			http.Error(w, fmt.Sprintf("Manifest failed: %v", err), http.StatusInternalServerError)
+			return
+		}
+		s.sources.RecordSubmitted(source)
+		s.quotas.Track(job.ID, charges[i])
+		jobs = append(jobs, job)
+		jobIDs[manifest.Jobs[i].Name] = job.ID
+
+		spec := manifest.Jobs[i]
+		run.record.Jobs = append(run.record.Jobs, ManifestRunJob{
+			Name:     spec.Name,
+			JobID:    job.ID,
+			Needs:    spec.Needs,
+			Priority: spec.Priority,
+		})
+	}
+	s.manifests.add(run)
+
+	log.Printf("[Gateway] Manifest run %s accepted: %d job(s)", id, len(jobs))
+	go s.runManifest(run, jobs, source)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"run_id": id,
+		"name":   manifest.Name,
+		"jobs":   jobIDs,
+	})
+}
+
+// runManifest starts each job of a run once every job it needs has completed,
+// higher priorities first among jobs that become ready together. Jobs needing one
+// that failed are skipped; the rest of the run carries on.
+func (s *Server) runManifest(run *manifestRun, jobs []JobRecord, source JobSource) {
+	type outcome struct {
+		index     int
+		completed bool
+	}
+	done := make(chan outcome)
+	settled := make([]bool, len(jobs))
+	completed := make([]bool, len(jobs))
+	index := make(map[string]int, len(jobs))
+	for i, job := range run.record.Jobs {
+		index[job.Name] = i
+	}
+
+	running := 0
+	for {
+		var ready []int
+		skipped := make(map[int]string)
+		run.mu.Lock()
+		for _, i := range run.order {
+			if run.started[i] {
+				continue
+			}
+			waiting, blocked := false, ""
+			for _, need := range run.record.Jobs[i].Needs {
+				j := index[need]
+				switch {
+				case !settled[j]:
+					waiting = true
+				case !completed[j] && blocked == "":
+					blocked = need
+				}
+			}
+			switch {
+			case blocked != "":
+				// Dependents of this job are visited after it and skipped in turn
+				run.started[i] = true
+				run.failed = true
+				settled[i] = true
+				run.record.Jobs[i].Skipped = fmt.Sprintf("needs %q, which didn't complete", blocked)
+				skipped[i] = run.record.Jobs[i].Skipped
+			case !waiting:
+				run.started[i] = true
+				ready = append(ready, i)
+			}
+		}
+		run.mu.Unlock()
+
+		for i, reason := range skipped {
+			s.jobs.Fail(jobs[i].ID, fmt.Errorf("skipped: %s", reason))
+			s.quotas.Settle(jobs[i].ID)
+		}
+
+		slices.SortStableFunc(ready, func(a, b int) int {
+			return run.record.Jobs[b].Priority - run.record.Jobs[a].Priority
+		})
+		for _, i := range ready {
+			running++
+			go func() {
+				defer s.quotas.Settle(jobs[i].ID)
+				_, _, err := s.executeJob(jobs[i], source)
+				done <- outcome{index: i, completed: err == nil}
+			}()
+		}
+
+		if running == 0 {
+			break
+		}
+		result := <-done
+		running--
+		settled[result.index] = true
+		completed[result.index] = result.completed
+		if !result.completed {
+			run.mu.Lock()
+			run.failed = true
+			run.mu.Unlock()
+		}
+	}
+
+	run.mu.Lock()
+	defer run.mu.Unlock()
+	switch {
+	case run.cancelled:
+		run.record.State = ManifestCancelled
+	case run.failed:
+		run.record.State = ManifestFailed
+	default:
+		run.record.State = ManifestCompleted
+	}
+	run.record.FinishedAt = time.Now()
+	log.Printf("[Gateway] Manifest run %s finished: %s", run.record.ID, run.record.State)
+}
+
+// snapshotManifestRun copies the run record with each job's current status
+func (s *Server) snapshotManifestRun(run *manifestRun) ManifestRunRecord {
+	run.mu.Lock()
+	record := run.record
+	record.Jobs = slices.Clone(run.record.Jobs)
+	run.mu.Unlock()
+
+	for i := range record.Jobs {
+		if job, exists := s.jobs.Get(record.Jobs[i].JobID); exists {
+			record.Jobs[i].Status = job.Status
+		}
+	}
+	return record
+}
+
+// handleGetManifestRun reports a manifest run's state and each job's status
+func (s *Server) handleGetManifestRun(w http.ResponseWriter, r *http.Request) {
+	run, exists := s.manifests.get(r.PathValue("id"))
+	if !exists {
+		http.Error(w, "Manifest run not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.snapshotManifestRun(run))
+}
+
+// handleCancelManifestRun cancels a run's jobs that haven't finished, including
+// those still waiting on dependencies. Only the submitter or an admin may cancel.
+func (s *Server) handleCancelManifestRun(w http.ResponseWriter, r *http.Request) {
+	run, exists := s.manifests.get(r.PathValue("id"))
+	if !exists {
+		http.Error(w, "Manifest run not found", http.StatusNotFound)
+		return
+	}
+	record := s.snapshotManifestRun(run)
+	jobs := make([]JobRecord, 0, len(record.Jobs))
+	for _, runJob := range record.Jobs {
+		if job, exists := s.jobs.Get(runJob.JobID); exists {
+			jobs = append(jobs, job)
+		}
+	}
+	if len(jobs) > 0 && !s.isAdmin(r) && s.sources.Identify(r).ID() != jobs[0].Source.ID() {
+		http.Error(w, "Only the submitter or an admin may cancel this manifest run", http.StatusForbidden)
+		return
+	}
+
+	// Jobs still waiting on dependencies are never started from here on
+	run.mu.Lock()
+	alreadyFinished := !run.record.FinishedAt.IsZero()
+	waiting := make(map[string]bool)
+	if !alreadyFinished {
+		run.cancelled = true
+		for i, job := range run.record.Jobs {
+			if !run.started[i] {
+				run.started[i] = true
+				waiting[job.JobID] = true
+			}
+		}
+	}
+	run.mu.Unlock()
+	if alreadyFinished {
+		http.Error(w, fmt.Sprintf("Manifest run already finished: %s", record.State), http.StatusConflict)
+		return
+	}
+
+	started := make([]JobRecord, 0, len(jobs))
+	for _, job := range jobs {
+		if !waiting[job.ID] {
+			started = append(started, job)
+		}
+	}
+	startedOutcomes := s.cancelJobs(started)
+	outcomes := make([]JobCancellation, 0, len(jobs))
+	for _, job := range jobs {
+		if waiting[job.ID] {
+			s.jobs.Cancel(job.ID)
+			s.quotas.Settle(job.ID)
+			outcomes = append(outcomes, JobCancellation{JobID: job.ID, Status: job.Status, Outcome: CancelOutcomeCancelled})
+		} else {
+			outcomes = append(outcomes, startedOutcomes[0])
+			startedOutcomes = startedOutcomes[1:]
+		}
+	}
+	log.Printf("[Gateway] Manifest run %s cancelled", record.ID)
+	writeCancellations(w, map[string]interface{}{"run_id": record.ID}, outcomes)
+}
+
+func newManifestRunID() (string, error) {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate manifest run ID: %w", err)
+	}
+	return "RUN-" + hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,156 @@
+package gateway
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Metric kinds (Prometheus TYPE values)
+const (
+	metricCounter = "counter"
+	metricGauge   = "gauge"
+)
+
+// metricFamily is a named metric with one value per label set
+type metricFamily struct {
+	kind   string
+	help   string
+	series map[string]float64 // Rendered label set ("" or `{k="v",...}`) -> value
+}
+
+// Metrics is a minimal in-process registry rendered in the Prometheus text format
+type Metrics struct {
+	mu         sync.Mutex
+	families   map[string]*metricFamily
+	collectors []func(*Metrics) // Refresh scrape-time gauges before rendering
+}
+
+func NewMetrics() *Metrics {
+	return &Metrics{
+		families: make(map[string]*metricFamily),
+	}
+}
+
+// Register declares a metric family's type and help text (recording also creates families implicitly)
+func (m *Metrics) Register(name, kind, help string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if family, exists := m.families[name]; exists {
+		family.kind, family.help = kind, help
+		return
+	}
+	m.families[name] = &metricFamily{kind: kind, help: help, series: make(map[string]float64)}
+}
+
+// AddCollector registers a callback run before each render to refresh gauges
+func (m *Metrics) AddCollector(fn func(*Metrics)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.collectors = append(m.collectors, fn)
+}
+
+// Inc adds 1 to a counter. labels are key/value pairs.
+func (m *Metrics) Inc(name string, labels ...string) {
+	m.Add(name, 1, labels...)
+}
+
+// Add adds v to a counter
+func (m *Metrics) Add(name string, v float64, labels ...string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.familyLocked(name, metricCounter).series[renderLabels(labels)] += v
+}
+
+// Set sets a gauge value
+func (m *Metrics) Set(name string, v float64, labels ...string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.familyLocked(name, metricGauge).series[renderLabels(labels)] = v
+}
+
+// Max raises a gauge to v if v exceeds its current value
+func (m *Metrics) Max(name string, v float64, labels ...string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	series := m.familyLocked(name, metricGauge).series
+	key := renderLabels(labels)
+	if current, exists := series[key]; !exists || v > current {
+		series[key] = v
+	}
+}
+
+// Reset drops all series of a family (for gauges whose label sets come and go)
+func (m *Metrics) Reset(name string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if family, exists := m.families[name]; exists {
+		family.series = make(map[string]float64)
+	}
+}
+
+func (m *Metrics) familyLocked(name, kind string) *metricFamily {
+	family, exists := m.families[name]
+	if !exists {
+		family = &metricFamily{kind: kind, series: make(map[string]float64)}
+		m.families[name] = family
+	}
+	return family
+}
+
+// WriteTo renders all metrics in the Prometheus text exposition format
+func (m *Metrics) WriteTo(w io.Writer) (int64, error) {
+	m.mu.Lock()
+	collectors := append([]func(*Metrics){}, m.collectors...)
+	m.mu.Unlock()
+
+	for _, collect := range collectors {
+		collect(m)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	names := make([]string, 0, len(m.families))
+	for name := range m.families {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		family := m.families[name]
+		if family.help != "" {
+			fmt.Fprintf(&b, "# HELP %s %s\n", name, family.help)
+		}
+		fmt.Fprintf(&b, "# TYPE %s %s\n", name, family.kind)
+
+		labelSets := make([]string, 0, len(family.series))
+		for labels := range family.series {
+			labelSets = append(labelSets, labels)
+		}
+		sort.Strings(labelSets)
+		for _, labels := range labelSets {
+			fmt.Fprintf(&b, "%s%s %g\n", name, labels, family.series[labels])
+		}
+	}
+
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+// renderLabels formats key/value pairs as a Prometheus label set
+func renderLabels(kv []string) string {
+	if len(kv) == 0 {
+		return ""
+	}
+
+	pairs := make([]string, 0, len(kv)/2)
+	for i := 0; i+1 < len(kv); i += 2 {
+		pairs = append(pairs, fmt.Sprintf("%s=%q", kv[i], kv[i+1]))
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
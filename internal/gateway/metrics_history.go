@@ -0,0 +1,324 @@
+package gateway
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ahmadhassan44/container-orchestrator/pkg/config"
+	"github.com/ahmadhassan44/container-orchestrator/pkg/protocol"
+)
+
+const (
+	// metricsSampleInterval is how often utilization and queue depth are sampled
+	// into the current minute's rollup
+	metricsSampleInterval = 5 * time.Second
+
+	// metricsHistoryPoints is about how many points GET /metrics/history returns
+	// when no step is given
+	metricsHistoryPoints = 360
+
+	// defaultMetricsRange is the period GET /metrics/history covers by default
+	defaultMetricsRange = 24 * time.Hour
+)
+
+// MetricsRollup is the gateway's utilization, queue depth and throughput over one
+// minute, or over a longer step when rollups are merged for a query
+type MetricsRollup struct {
+	At          time.Time `json:"at"`          // Start of the period
+	Minutes     int       `json:"minutes"`     // Minutes of the period with samples
+	Workers     float64   `json:"workers"`     // Mean active workers
+	Utilization float64   `json:"utilization"` // Mean CPU in use, % of the workers' total capacity
+	QueueDepth  float64   `json:"queue_depth"` // Mean jobs waiting
+	QueueMax    int       `json:"queue_max"`   // Most jobs seen waiting at once
+	Completed   int       `json:"completed"`   // Jobs finished in the period, by final status
+	Failed      int       `json:"failed"`
+	Cancelled   int       `json:"cancelled"`
+}
+
+// metricsMinute accumulates the samples and outcomes of the current minute
+type metricsMinute struct {
+	start       time.Time
+	samples     int
+	workers     float64
+	utilization float64
+	queueDepth  float64
+	queueMax    int
+	completed   int
+	failed      int
+	cancelled   int
+}
+
+// rollup averages the minute's samples
+func (m *metricsMinute) rollup() MetricsRollup {
+	r := MetricsRollup{At: m.start, Minutes: 1, QueueMax: m.queueMax, Completed: m.completed, Failed: m.failed, Cancelled: m.cancelled}
+	if m.samples > 0 {
+		n := float64(m.samples)
+		r.Workers, r.Utilization, r.QueueDepth = round2(m.workers/n), round2(m.utilization/n), round2(m.queueDepth/n)
+	}
+	return r
+}
+
+// MetricsHistory keeps per-minute rollups of the gateway's metrics for charts,
+// without an external Prometheus server. Rollups are held in memory for the
+// retention period and appended to METRICS_HISTORY_FILE, which is compacted to
+// the retention period and read back when the gateway starts.
+type MetricsHistory struct {
+	path      string
+	retention time.Duration
+
+	mu      sync.Mutex
+	file    *os.File // Open for appending (nil = memory only)
+	rollups []MetricsRollup
+	current metricsMinute
+}
+
+func NewMetricsHistory(cfg *config.Config) *MetricsHistory {
+	h := &MetricsHistory{
+		path:      cfg.MetricsHistoryFile,
+		retention: time.Duration(max(cfg.MetricsHistoryDays, 1)) * 24 * time.Hour,
+		current:   metricsMinute{start: time.Now().Truncate(time.Minute)},
+	}
+	if err := h.open(); err != nil {
+		log.Printf("[Metrics] Keeping metrics history in memory only: %v", err)
+		h.path = ""
+	}
+	return h
+}
+
+// open loads the rollups within the retention period, rewrites the file with
+// just those and opens it for appending
+func (h *MetricsHistory) open() error {
+	if h.path == "" {
+		return nil
+	}
+
+	cutoff := time.Now().Add(-h.retention)
+	f, err := os.Open(h.path)
+	switch {
+	case err == nil:
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			var r MetricsRollup
+			if json.Unmarshal(scanner.Bytes(), &r) == nil && r.At.After(cutoff) {
+				h.rollups = append(h.rollups, r)
+			}
+		}
+		err = scanner.Err()
+		f.Close()
+		if err != nil {
+			return err
+		}
+	case !os.IsNotExist(err):
+		return err
+	}
+	sort.Slice(h.rollups, func(i, j int) bool { return h.rollups[i].At.Before(h.rollups[j].At) })
+
+	var buf bytes.Buffer
+	for _, r := range h.rollups {
+		line, _ := json.Marshal(r)
+		buf.Write(append(line, '\n'))
+	}
+	if dir := filepath.Dir(h.path); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return err
+		}
+	}
+	tmp := h.path + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), 0o644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, h.path); err != nil {
+		return err
+	}
+
+	h.file, err = os.OpenFile(h.path, os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	log.Printf("[Metrics] Metrics history at %s (%d minute(s) within %s)", h.path, len(h.rollups), h.retention)
+	return nil
+}
+
+// advanceLocked closes the current minute if now is past it, storing its rollup
+// (caller holds h.mu)
+func (h *MetricsHistory) advanceLocked(now time.Time) {
+	minute := now.Truncate(time.Minute)
+	if !minute.After(h.current.start) {
+		return
+	}
+	if h.current.samples > 0 {
+		h.storeLocked(h.current.rollup())
+	}
+	h.current = metricsMinute{start: minute}
+}
+
+// storeLocked keeps a closed minute's rollup and appends it to the file, dropping
+// rollups past the retention period (caller holds h.mu)
+func (h *MetricsHistory) storeLocked(r MetricsRollup) {
+	cutoff := time.Now().Add(-h.retention)
+	drop := 0
+	for drop < len(h.rollups) && !h.rollups[drop].At.After(cutoff) {
+		drop++
+	}
+	h.rollups = append(h.rollups[drop:], r)
+
+	if h.file == nil {
+		return
+	}
+	line, err := json.Marshal(r)
+	if err != nil {
+		return
+	}
+	if _, err := h.file.Write(append(line, '\n')); err != nil {
+		log.Printf("[Metrics] Failed to append to %s: %v", h.path, err)
+	}
+}
+
+// sample adds one reading of the workers and queue to the current minute
+func (h *MetricsHistory) sample(now time.Time, workers, utilization float64, queueDepth int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.advanceLocked(now)
+	h.current.samples++
+	h.current.workers += workers
+	h.current.utilization += utilization
+	h.current.queueDepth += float64(queueDepth)
+	h.current.queueMax = max(h.current.queueMax, queueDepth)
+}
+
+// RecordJob counts a job towards the current minute's throughput once it reaches
+// a final status. It is a TransitionListener.
+func (h *MetricsHistory) RecordJob(job JobRecord) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.advanceLocked(time.Now())
+	switch job.Status {
+	case protocol.StatusCompleted:
+		h.current.completed++
+	case protocol.StatusFailed:
+		h.current.failed++
+	case protocol.StatusCancelled:
+		h.current.cancelled++
+	}
+}
+
+// Range returns the rollups from from up to to, merged into periods of step
+// (a whole number of minutes), oldest first. Periods without samples, e.g.
+// while the gateway was down, are left out.
+func (h *MetricsHistory) Range(from, to time.Time, step time.Duration) []MetricsRollup {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.advanceLocked(time.Now())
+
+	points := []MetricsRollup{}
+	var bucket *MetricsRollup
+	for _, r := range h.rollups {
+		if r.At.Before(from) || !r.At.Before(to) {
+			continue
+		}
+		start := from.Add(r.At.Sub(from) / step * step)
+		if bucket == nil || !bucket.At.Equal(start) {
+			points = append(points, MetricsRollup{At: start})
+			bucket = &points[len(points)-1]
+		}
+		n := float64(bucket.Minutes)
+		bucket.Workers = (bucket.Workers*n + r.Workers) / (n + 1)
+		bucket.Utilization = (bucket.Utilization*n + r.Utilization) / (n + 1)
+		bucket.QueueDepth = (bucket.QueueDepth*n + r.QueueDepth) / (n + 1)
+		bucket.QueueMax = max(bucket.QueueMax, r.QueueMax)
+		bucket.Completed += r.Completed
+		bucket.Failed += r.Failed
+		bucket.Cancelled += r.Cancelled
+		bucket.Minutes++
+	}
+	for i := range points {
+		p := &points[i]
+		p.Workers, p.Utilization, p.QueueDepth = round2(p.Workers), round2(p.Utilization), round2(p.QueueDepth)
+	}
+	return points
+}
+
+// StartMetricsHistory samples utilization and queue depth every 5 seconds into
+// per-minute rollups
+func (s *Server) StartMetricsHistory() {
+	ctx := s.scheduler.orchestrator.ctx
+	go func() {
+		ticker := time.NewTicker(metricsSampleInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				workers := s.scheduler.orchestrator.GetAllWorkers()
+				inUse, capacity := 0.0, 0.0
+				for _, worker := range workers {
+					inUse += worker.CurrentCPU
+					capacity += 100 * worker.capacity()
+				}
+				utilization := 0.0
+				if capacity > 0 {
+					utilization = 100 * inUse / capacity
+				}
+				s.metricsHistory.sample(now, float64(len(workers)), utilization, s.scheduler.QueueLength())
+			}
+		}
+	}()
+}
+
+// parseMetricsRange parses a period like "90m", "24h" or "7d"
+func parseMetricsRange(raw string) (time.Duration, error) {
+	if days, found := strings.CutSuffix(raw, "d"); found {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, err
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(raw)
+}
+
+// handleMetricsHistory serves per-minute metrics rollups as time series:
+// GET /metrics/history?range=24h[&step=5m]
+func (s *Server) handleMetricsHistory(w http.ResponseWriter, r *http.Request) {
+	period := defaultMetricsRange
+	if raw := r.URL.Query().Get("range"); raw != "" {
+		var err error
+		if period, err = parseMetricsRange(raw); err != nil || period < time.Minute || period > s.metricsHistory.retention {
+			http.Error(w, fmt.Sprintf("range must be a duration like 90m, 24h or 7d, from 1m to %s", s.metricsHistory.retention), http.StatusBadRequest)
+			return
+		}
+	}
+	step := max((period / metricsHistoryPoints).Round(time.Minute), time.Minute)
+	if raw := r.URL.Query().Get("step"); raw != "" {
+		var err error
+		if step, err = parseMetricsRange(raw); err != nil || step < time.Minute || step%time.Minute != 0 {
+			http.Error(w, "step must be a whole number of minutes, like 1m, 5m or 1h", http.StatusBadRequest)
+			return
+		}
+	}
+
+	to := time.Now().Truncate(time.Minute)
+	from := to.Add(-period)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"from":   from,
+		"to":     to,
+		"step":   step.Seconds(),
+		"points": s.metricsHistory.Range(from, to, step),
+	})
+}
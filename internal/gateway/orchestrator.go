@@ -2,16 +2,41 @@ package gateway
 
 import (
 	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"log"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
 	"sync"
 	"time"
 
+	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
 	"github.com/docker/docker/client"
 	"github.com/docker/go-connections/nat"
+
+	"github.com/ahmadhassan44/container-orchestrator/pkg/config"
+	"github.com/ahmadhassan44/container-orchestrator/pkg/protocol"
+	"github.com/ahmadhassan44/container-orchestrator/pkg/version"
 )
 
+// defaultWorkerImage is the image worker containers run unless WORKER_IMAGE says otherwise
+const defaultWorkerImage = "container-orchestrator-worker:latest"
+
+// cpuEpsilon absorbs float rounding when checking reserved CPU against its bounds
+const cpuEpsilon = 0.001
+
+// stopTimeoutMargin is added to the worker drain timeout so the worker can
+// close its HTTP server after draining before Docker escalates to SIGKILL
+const stopTimeoutMargin = 5
+
 // Hardware Topology for i5-1135G7
 // Core 0 is reserved for this Gateway/System.
 var coreMaps = map[int]string{
@@ -24,33 +49,167 @@ var coreMaps = map[int]string{
 type WorkerInfo struct {
 	CoreID        int
 	ContainerID   string
+	UUID          string // Stable identity of the core's worker slot, kept across container replacements
 	HostPort      int
-	CurrentCPU    float64   // Current CPU usage percentage (0-100)
+	BaseURL       string    // Where the gateway reaches the worker API, e.g. "http://[fd00::5]:8080"
+	CurrentCPU    float64   // CPU in use, in percent of a standard worker: measured if fresh, else ReservedCPU
+	ReservedCPU   float64   // Estimated CPU of the jobs placed on it
+	MeasuredCPU   float64   // CPU Docker last measured, less the estimates of jobs released since
+	MeasuredAt    time.Time // When MeasuredCPU was sampled (zero = never)
+	PendingCPU    float64   // Estimates of jobs placed since MeasuredAt, not measured yet
+	Capacity      float64   // CPU relative to a standard worker (1 = the core's default cpuset)
 	LastHeartbeat time.Time // Last successful health check
-	IsHealthy     bool
+	Version       string    // Worker build version reported by its /version endpoint
+	Arch          string    // CPU architecture of the worker's image (e.g. "amd64")
+	ImageID       string    // Image the container runs (content digest)
+	Wire          string    // Dispatch encoding agreed at the version handshake ("" = JSON)
+
+	SigningKey ed25519.PublicKey // Key the container's results must be signed with (nil = unsigned)
+
+	Down   string       // Why it takes no jobs: "failed health probe" or "draining" ("" = up)
+	Health WorkerHealth // Scored as of when the worker was copied out of the orchestrator
 }
 
 type Orchestrator struct {
-	cli            *client.Client
-	ctx            context.Context
-	mu             sync.RWMutex        // Thread-safe lock (RWMutex for better concurrency)
-	workers        map[int]*WorkerInfo // Map[CoreID] -> WorkerInfo
-	workerBasePort int                 // Base port for workers (e.g., 8000)
+	cli             ContainerRuntime
+	runtime         RuntimeStatus // Which backend runs workers, and whether it is degraded
+	ctx             context.Context
+	cancel          context.CancelFunc  // Stops the orchestrator's background loops (on Shutdown)
+	mu              sync.RWMutex        // Thread-safe lock (RWMutex for better concurrency)
+	workers         map[int]*WorkerInfo // Map[CoreID] -> WorkerInfo
+	workerBasePort  int                 // Base port for workers (e.g., 8000)
+	workerPortRange int                 // Ports above the base available for workers
+	workerAddress   workerAddressing    // How the gateway reaches worker containers
+	workerImage     string              // Image reference worker containers run
+	workerImagePull bool                // Pull the worker image when it isn't present locally
+	arch            string              // Container host CPU architecture, normalized (e.g. "arm64")
+	identities      map[int]string      // Core ID -> stable worker UUID
+	workerEnv       []string            // Extra KEY=value env for worker containers (WORKER_ENV)
+	workerMounts    []mount.Mount       // Bind and tmpfs mounts for worker containers
+	workerLimits    container.Resources // Memory and block I/O limits for worker containers
+	workerDNS       workerDNS           // Name resolution overrides for worker containers
+	identityFile    string              // Where identities persist (empty = memory only)
+	drainTimeout    int                 // Seconds workers get to finish in-flight jobs on stop
+
+	expectedWorkerVersion string // Worker version to warn on mismatch against
+	wire                  string // Preferred dispatch encoding (WORKER_WIRE)
+
+	internalURL   string // Gateway internal listener URL handed to workers (empty = disabled)
+	internalToken string // Bearer token workers present to the internal listener
+
+	imageGCRetention int // Seconds unused worker images are kept before garbage collection
+
+	resultSigning bool // Provision every worker container with a result signing key
+
+	exitedWorkers []ExitedWorker // Recently exited workers with their diagnostics, oldest first
+	exitLogLines  int            // Log lines captured from an exited worker
+
+	stats workerStatsCache // Latest raw container stats sample per core
+
+	spawnListener func(coreID int)      // Told about every worker container started
+	coreFilter    func(coreID int) bool // Cores new workers may be spawned on (nil = any)
+
+	resources map[int]WorkerResources // Cores resized away from their default cpuset
+	maxCPU    float64                 // Most CPU a standard worker may have reserved (percent)
+
+	sandbox *Sandbox // Cores whose workers run under a sandboxing OCI runtime
+
+	pinning GatewayPinning // The gateway's own CPUs and priorities, as requested and then applied
+
+	shutdown shutdownState // Whether the gateway is shutting down, and what it drains first
+
+	nodeName string       // Labels worker containers as this gateway's
+	janitor  janitorState // Reconciliation state carried between passes
+	daemon   daemonState  // Whether the Docker daemon answers, and who to tell when that changes
+	health   healthState  // What worker health is scored from
+
+	metrics *Metrics // Gateway-wide metrics registry (served at /metrics)
 }
 
 // NewOrchestrator initializes the Docker client and internal state
-func NewOrchestrator(ctx context.Context, basePort int) (*Orchestrator, error) {
+func NewOrchestrator(ctx context.Context, cfg *config.Config) (*Orchestrator, error) {
 	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
 	if err != nil {
 		return nil, err
 	}
 
-	return &Orchestrator{
-		cli:            cli,
-		ctx:            ctx,
-		workers:        make(map[int]*WorkerInfo),
-		workerBasePort: basePort,
-	}, nil
+	return NewOrchestratorWithRuntime(ctx, cli, cfg), nil
+}
+
+// NewOrchestratorWithRuntime builds an orchestrator on top of an existing runtime
+func NewOrchestratorWithRuntime(ctx context.Context, rt ContainerRuntime, cfg *config.Config) *Orchestrator {
+	expected := cfg.ExpectedWorkerVersion
+	if expected == "" {
+		expected = version.Version
+	}
+	if cfg.WorkerWire != "" && cfg.WorkerWire != protocol.WireJSON && cfg.WorkerWire != protocol.WireProtobuf {
+		log.Printf("[WARNING] Unknown WORKER_WIRE %q; dispatching JSON", cfg.WorkerWire)
+	}
+
+	image := cfg.WorkerImage
+	if image == "" {
+		image = defaultWorkerImage
+	}
+
+	status := RuntimeStatus{Name: cfg.Runtime}
+	if status.Name == "" {
+		status.Name = "docker"
+	}
+	if d, ok := rt.(degradedRuntime); ok {
+		status.Degraded, status.Detail = true, d.Degraded()
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	metrics := NewMetrics()
+	o := &Orchestrator{
+		cli:                   newInstrumentedRuntime(rt, metrics),
+		runtime:               status,
+		sandbox:               NewSandbox(cfg, status.Degraded),
+		pinning:               GatewayPinning{CPUs: cfg.GatewayCPUs, Nice: cfg.GatewayNice, IOPriority: cfg.GatewayIOPriority},
+		resultSigning:         cfg.ResultSigning,
+		ctx:                   ctx,
+		cancel:                cancel,
+		workers:               make(map[int]*WorkerInfo),
+		workerBasePort:        cfg.WorkerBasePort,
+		workerPortRange:       max(cfg.WorkerPortRange, len(coreMaps)),
+		workerAddress:         newWorkerAddressing(cfg),
+		workerImage:           image,
+		workerImagePull:       cfg.WorkerImagePull,
+		drainTimeout:          cfg.WorkerDrainTimeout,
+		imageGCRetention:      cfg.ImageGCRetention,
+		exitLogLines:          cfg.WorkerExitLogLines,
+		expectedWorkerVersion: expected,
+		wire:                  cfg.WorkerWire,
+		identities:            make(map[int]string),
+		resources:             make(map[int]WorkerResources),
+		identityFile:          cfg.WorkerIdentityFile,
+		nodeName:              cfg.NodeName,
+		stats:                 workerStatsCache{samples: make(map[int]statsSample), streams: make(map[string]context.CancelFunc), maxAge: time.Duration(cfg.StatsMaxAge) * time.Second},
+		maxCPU:                max(cfg.MaxCPUThreshold, 100),
+		daemon:                daemonState{status: DaemonStatus{Connected: true, Since: time.Now()}},
+		health:                newHealthState(cfg),
+		metrics:               metrics,
+	}
+	metrics.Register("orchestrator_docker_connected", metricGauge, "Whether the Docker daemon answers pings (1) or not (0)")
+	metrics.Register("orchestrator_docker_connection_events_total", metricCounter, "Docker daemon connections lost and regained, by event")
+	metrics.Set("orchestrator_docker_connected", 1)
+	metrics.Register("orchestrator_janitor_actions_total", metricCounter, "Worker state reconciliation actions, by action")
+	metrics.Register("orchestrator_untracked_containers", metricGauge, "Worker containers on the runtime this gateway doesn't track")
+	metrics.Register("orchestrator_invariant_violations_total", metricCounter, "Worker state invariant violations, by invariant")
+	if err := o.loadIdentities(); err != nil {
+		log.Printf("[WARNING] Starting without persisted worker identities: %v", err)
+	}
+	return o
+}
+
+// Metrics returns the gateway-wide metrics registry
+func (o *Orchestrator) Metrics() *Metrics {
+	return o.metrics
+}
+
+// stopTimeout is the docker stop grace period matching the worker's drain timeout
+func (o *Orchestrator) stopTimeout() int {
+	return o.drainTimeout + stopTimeoutMargin
 }
 
 // CheckConnectivity verifies we can talk to the Docker Daemon
@@ -60,6 +219,113 @@ func (o *Orchestrator) CheckConnectivity() {
 		log.Fatalf("CRITICAL: Cannot connect to Docker Daemon. Is it running? %v", err)
 	}
 	fmt.Printf("✅ Docker Daemon Connected: %s (CPUs: %d)\n", info.Name, info.NCPU)
+	if o.runtime.Degraded {
+		log.Printf("[WARNING] Running degraded: %s", o.runtime.Detail)
+	}
+
+	if runtime := o.sandbox.runtime; runtime != "" && len(o.sandbox.cores) > 0 && info.Runtimes != nil {
+		if _, registered := info.Runtimes[runtime]; !registered {
+			log.Printf("[WARNING] SANDBOX_RUNTIME %q isn't registered with the daemon; sandboxed workers will fail to start", runtime)
+		}
+	}
+
+	o.mu.Lock()
+	o.arch = normalizeArch(info.Architecture)
+	o.mu.Unlock()
+	log.Printf("[Orchestrator] Host architecture: %s", o.arch)
+}
+
+// Sandbox returns the pool of cores whose workers run sandboxed
+func (o *Orchestrator) Sandbox() *Sandbox {
+	return o.sandbox
+}
+
+// RuntimeStatus describes the backend running workers
+type RuntimeStatus struct {
+	Name     string `json:"name"`
+	Degraded bool   `json:"degraded"`
+	Detail   string `json:"detail,omitempty"`
+}
+
+// degradedRuntime is implemented by backends that run workers with less
+// isolation than containers
+type degradedRuntime interface {
+	Degraded() string
+}
+
+// Runtime reports the backend running workers
+func (o *Orchestrator) Runtime() RuntimeStatus {
+	return o.runtime
+}
+
+// PingRuntime checks that the container daemon answers
+func (o *Orchestrator) PingRuntime(ctx context.Context) error {
+	_, err := o.cli.Ping(ctx)
+	return err
+}
+
+// CheckWorkerImage verifies the worker image is present locally
+func (o *Orchestrator) CheckWorkerImage(ctx context.Context) error {
+	if _, _, err := o.cli.ImageInspectWithRaw(ctx, o.workerImage); err != nil {
+		return fmt.Errorf("worker image %s unavailable: %w", o.workerImage, err)
+	}
+	return nil
+}
+
+// ConfigureInternal resolves the internal listener address and token and hands them
+// to workers started from now on. Returns the address to listen on, or "" when the
+// internal listener is disabled. Must be called before any worker is started.
+func (o *Orchestrator) ConfigureInternal(cfg *config.Config) (string, error) {
+	if cfg.InternalPort == 0 {
+		return "", nil
+	}
+
+	host := cfg.InternalBindAddr
+	if host == "" {
+		bridge, err := o.bridgeGateway()
+		if err != nil {
+			return "", err
+		}
+		host = bridge
+	}
+
+	token := cfg.InternalToken
+	if token == "" {
+		buf := make([]byte, 16)
+		if _, err := rand.Read(buf); err != nil {
+			return "", fmt.Errorf("failed to generate internal token: %w", err)
+		}
+		token = hex.EncodeToString(buf)
+	}
+
+	addr := net.JoinHostPort(host, strconv.Itoa(cfg.InternalPort))
+	o.mu.Lock()
+	o.internalURL = "http://" + addr
+	o.internalToken = token
+	o.mu.Unlock()
+	return addr, nil
+}
+
+// bridgeGateway returns the host's address on the default Docker bridge network,
+// which containers can reach but clients outside the host cannot
+func (o *Orchestrator) bridgeGateway() (string, error) {
+	bridge, err := o.cli.NetworkInspect(o.ctx, "bridge", types.NetworkInspectOptions{})
+	if err != nil {
+		return "", fmt.Errorf("failed to inspect bridge network: %w", err)
+	}
+	for _, ipam := range bridge.IPAM.Config {
+		if ipam.Gateway != "" {
+			return ipam.Gateway, nil
+		}
+	}
+	return "", fmt.Errorf("bridge network has no gateway address")
+}
+
+// InternalToken returns the bearer token workers use on the internal listener
+func (o *Orchestrator) InternalToken() string {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	return o.internalToken
 }
 
 // StartWorker spins up a worker container pinned to a specific physical core
@@ -72,108 +338,366 @@ func (o *Orchestrator) StartWorker(coreID int) (string, error) {
 		return "", fmt.Errorf("invalid core ID: %d (valid: 1, 2, 3)", coreID)
 	}
 
+	if o.shutdown.stopping {
+		return "", errShuttingDown
+	}
+	if err := o.daemonDownLocked(); err != nil {
+		return "", err
+	}
+
 	// Check if core is already occupied
 	if worker, exists := o.workers[coreID]; exists {
 		return "", fmt.Errorf("core %d already has worker %s", coreID, worker.ContainerID[:12])
 	}
 
 	// Topology Lookup
-	cpuSet := coreMaps[coreID]
-	hostPort := o.workerBasePort + coreID
-
-	log.Printf("[Orchestrator] Spawning worker on Core %d (CPUs: %s, Port: %d)", coreID, cpuSet, hostPort)
+	resources := o.coreResourcesLocked(coreID)
+	cpuSet := resources.Cpuset
 
 	// Container Config
+	stopTimeout := o.stopTimeout()
+	uuid := o.workerUUIDLocked(coreID)
 	config := &container.Config{
-		Image: "container-orchestrator-worker:latest",
-		Env:   []string{fmt.Sprintf("WORKER_ID=Worker-Core-%d", coreID)},
-	}
-
-	// Host Config - CPU pinning and port mapping
-	hostConfig := &container.HostConfig{
-		Resources: container.Resources{
-			CpusetCpus: cpuSet,
+		Image: o.workerImage,
+		Env: []string{
+			fmt.Sprintf("WORKER_ID=Worker-Core-%d", coreID),
+			"WORKER_UUID=" + uuid,
+			fmt.Sprintf("DRAIN_TIMEOUT=%d", o.drainTimeout),
 		},
-		PortBindings: nat.PortMap{
-			"8080/tcp": []nat.PortBinding{{HostIP: "0.0.0.0", HostPort: fmt.Sprintf("%d", hostPort)}},
+		StopTimeout: &stopTimeout,
+		Labels: map[string]string{
+			imageRoleLabel:     imageRoleWorker,
+			containerCoreLabel: strconv.Itoa(coreID),
+			containerNodeLabel: o.nodeName,
 		},
 	}
+	if o.internalURL != "" {
+		config.Env = append(config.Env,
+			"GATEWAY_INTERNAL_URL="+o.internalURL,
+			"INTERNAL_TOKEN="+o.internalToken)
+	}
+	config.Env = append(config.Env, o.workerEnv...)
+
+	// A fresh key per container: results signed with it came from this container
+	var signingKey ed25519.PublicKey
+	if o.resultSigning {
+		public, private, err := ed25519.GenerateKey(nil)
+		if err != nil {
+			return "", fmt.Errorf("failed to generate result signing key: %w", err)
+		}
+		signingKey = public
+		config.Env = append(config.Env, "RESULT_SIGNING_KEY="+base64.StdEncoding.EncodeToString(private.Seed()))
+	}
 
-	// Create container
-	resp, err := o.cli.ContainerCreate(o.ctx, config, hostConfig, nil, nil, "")
-	if err != nil {
-		return "", fmt.Errorf("container creation failed: %w", err)
+	// Another process may bind the chosen port between probing and Docker binding
+	// it, so on a conflict pick the next free port and try again
+	var containerID string
+	conflicted := make(map[int]bool)
+	for attempt := 1; ; attempt++ {
+		hostPort := 0
+		if o.workerAddress.publish {
+			port, err := o.allocatePortLocked(coreID, conflicted)
+			if err != nil {
+				return "", fmt.Errorf("core %d: %w", coreID, err)
+			}
+			hostPort = port
+		}
+
+		log.Printf("[Orchestrator] Spawning worker on Core %d (CPUs: %s, Port: %d)", coreID, cpuSet, hostPort)
+		if runtime := o.sandbox.Runtime(coreID); runtime != "" && attempt == 1 {
+			log.Printf("[Orchestrator] Core %d is sandboxed: worker runs under %s", coreID, runtime)
+		}
+
+		// Host Config - CPU pinning and port mapping
+		hostConfig := &container.HostConfig{
+			Resources:   o.withWorkerLimits(resources.containerResources()),
+			Mounts:      o.workerMounts,
+			NetworkMode: o.workerAddress.networkMode(),
+			DNS:         o.workerDNS.servers,
+			DNSSearch:   o.workerDNS.search,
+			ExtraHosts:  o.workerDNS.extraHosts,
+			Runtime:     o.sandbox.Runtime(coreID),
+		}
+		if o.workerAddress.publish {
+			hostConfig.PortBindings = nat.PortMap{
+				"8080/tcp": []nat.PortBinding{{HostIP: "0.0.0.0", HostPort: strconv.Itoa(hostPort)}},
+			}
+		}
+
+		// Create container
+		resp, err := o.cli.ContainerCreate(o.ctx, config, hostConfig, nil, o.platform(), "")
+		if err != nil {
+			return "", fmt.Errorf("container creation failed: %w", err)
+		}
+
+		// Start container
+		err = o.cli.ContainerStart(o.ctx, resp.ID, container.StartOptions{})
+		if err == nil {
+			containerID = resp.ID
+			break
+		}
+
+		if rmErr := o.cli.ContainerRemove(o.ctx, resp.ID, container.RemoveOptions{Force: true}); rmErr != nil {
+			log.Printf("[WARNING] Failed to remove unstarted container %s: %v", resp.ID[:12], rmErr)
+		}
+		if !isPortConflict(err) || attempt >= maxPortAttempts {
+			return "", fmt.Errorf("container start failed on port %d: %w", hostPort, err)
+		}
+		log.Printf("[Orchestrator] Port %d already in use, retrying Core %d on another port", hostPort, coreID)
+		conflicted[hostPort] = true
 	}
 
-	// Start container
-	if err := o.cli.ContainerStart(o.ctx, resp.ID, container.StartOptions{}); err != nil {
-		return "", fmt.Errorf("container start failed: %w", err)
+	// Trust the port and addresses Docker reports over the ones we asked for
+	hostPort, baseURL, imageID, err := o.inspectStarted(containerID)
+	if err != nil {
+		o.cli.ContainerRemove(o.ctx, containerID, container.RemoveOptions{Force: true})
+		return "", err
 	}
 
 	// Update internal state
 	o.workers[coreID] = &WorkerInfo{
 		CoreID:        coreID,
-		ContainerID:   resp.ID,
+		ContainerID:   containerID,
+		UUID:          uuid,
 		HostPort:      hostPort,
+		BaseURL:       baseURL,
+		ImageID:       imageID,
+		Arch:          o.imageArch(imageID),
 		CurrentCPU:    0.0,
+		Capacity:      capacityOf(coreID, resources),
 		LastHeartbeat: time.Now(),
-		IsHealthy:     true,
+		SigningKey:    signingKey,
+	}
+	o.forgetHealthLocked(coreID)
+
+	log.Printf("[Orchestrator] Worker started: Core=%d, Container=%s, Port=%d, URL=%s",
+		coreID, containerID[:12], hostPort, baseURL)
+
+	go o.verifyWorkerVersion(coreID, containerID, baseURL)
+	if o.spawnListener != nil {
+		o.spawnListener(coreID)
 	}
 
-	log.Printf("[Orchestrator] Worker started: Core=%d, Container=%s, Port=%d",
-		coreID, resp.ID[:12], hostPort)
+	return containerID, nil
+}
+
+// SetSpawnListener registers a callback for worker container starts. It runs while
+// the orchestrator lock is held, so it must not call back into the orchestrator.
+func (o *Orchestrator) SetSpawnListener(fn func(coreID int)) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.spawnListener = fn
+}
 
-	return resp.ID, nil
+// SetCoreFilter restricts GetNextAvailableCore to cores fn accepts. It is called
+// with the orchestrator lock held, so it must not call back into the orchestrator.
+func (o *Orchestrator) SetCoreFilter(fn func(coreID int) bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.coreFilter = fn
 }
 
-// GetWorkerByCore retrieves worker info for a specific core
+// verifyWorkerVersion records a new worker's build version and warns if it
+// doesn't match what the gateway expects
+func (o *Orchestrator) verifyWorkerVersion(coreID int, containerID string, baseURL string) {
+	httpClient := &http.Client{Timeout: 2 * time.Second}
+	url := baseURL + "/version"
+
+	// The worker needs a moment to boot; retry for up to ~10 seconds
+	var info version.Info
+	for attempt := 0; ; attempt++ {
+		resp, err := httpClient.Get(url)
+		if err == nil {
+			decodeErr := json.NewDecoder(resp.Body).Decode(&info)
+			resp.Body.Close()
+			if resp.StatusCode == http.StatusOK && decodeErr == nil {
+				break
+			}
+		}
+		if attempt >= 20 {
+			log.Printf("[WARNING] Could not determine version of worker on Core %d", coreID)
+			return
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+
+	o.mu.Lock()
+	if worker, exists := o.workers[coreID]; exists && worker.ContainerID == containerID {
+		worker.Version = info.Version
+		if o.wire == protocol.WireProtobuf && slices.Contains(info.Encodings, protocol.WireProtobuf) {
+			worker.Wire = protocol.WireProtobuf
+		}
+	}
+	o.mu.Unlock()
+
+	if info.Version != o.expectedWorkerVersion {
+		log.Printf("[WARNING] Worker on Core %d runs version %s (commit %s), gateway expects %s",
+			coreID, info.Version, info.Commit, o.expectedWorkerVersion)
+	}
+}
+
+// GetWorkerByCore returns a copy of a core's worker. Workers returned by the
+// orchestrator are copies taken under its lock; they go stale but are safe to
+// read from any goroutine, and changes go through the orchestrator's methods.
 func (o *Orchestrator) GetWorkerByCore(coreID int) (*WorkerInfo, bool) {
 	o.mu.RLock()
 	defer o.mu.RUnlock()
 
 	worker, exists := o.workers[coreID]
-	return worker, exists
+	if !exists {
+		return nil, false
+	}
+	now := time.Now()
+	copied := *worker
+	copied.Health = o.scoreLocked(worker, now)
+	o.refreshCPULocked(&copied, now) // A measurement may have gone stale since it was taken
+	return &copied, true
 }
 
-// GetAllWorkers returns a snapshot of all active workers
+// GetAllWorkers returns copies of all active workers
 func (o *Orchestrator) GetAllWorkers() []*WorkerInfo {
 	o.mu.RLock()
 	defer o.mu.RUnlock()
 
+	now := time.Now()
 	workers := make([]*WorkerInfo, 0, len(o.workers))
 	for _, worker := range o.workers {
-		workers = append(workers, worker)
+		copied := *worker
+		copied.Health = o.scoreLocked(worker, now)
+		o.refreshCPULocked(&copied, now)
+		workers = append(workers, &copied)
 	}
 	return workers
 }
 
-// UpdateWorkerCPU updates the CPU usage metric for a worker
+// UpdateWorkerCPU sets a worker's reserved CPU outright
 func (o *Orchestrator) UpdateWorkerCPU(coreID int, cpuPercent float64) {
 	o.mu.Lock()
 	defer o.mu.Unlock()
 
 	if worker, exists := o.workers[coreID]; exists {
-		// Ensure CPU usage never goes below 0%
-		if cpuPercent < 0 {
-			worker.CurrentCPU = 0.0
-		} else {
-			worker.CurrentCPU = cpuPercent
+		o.setCPULocked(worker, cpuPercent)
+	}
+}
+
+// AdjustWorkerCPU adds delta to the reserved CPU of worker's container, reserving
+// (> 0) or releasing (< 0) a job's estimate in one step, and returns the CPU now
+// counted as in use. Nothing changes if the core's worker has been replaced since.
+func (o *Orchestrator) AdjustWorkerCPU(worker *WorkerInfo, delta float64) float64 {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	current, exists := o.workers[worker.CoreID]
+	if !exists || current.ContainerID != worker.ContainerID {
+		return 0
+	}
+
+	// Until the next sample, a placed job counts at its estimate, and a released one
+	// comes off the measurement (if it was placed before the sample, it is in there)
+	if delta > 0 {
+		current.PendingCPU += delta
+	} else {
+		unmeasured := min(current.PendingCPU, -delta)
+		current.PendingCPU -= unmeasured
+		current.MeasuredCPU = max(current.MeasuredCPU-(-delta-unmeasured), 0)
+	}
+	return o.setCPULocked(current, current.ReservedCPU+delta)
+}
+
+// setCPULocked records a worker's reserved CPU, checking it stays within
+// [0, capacity]: at most MAX_CPU_THRESHOLD (or 100%) of the worker's size. A
+// reservation below 0 means a job was released twice and is clamped; one above
+// capacity means jobs were placed past the worker's size and is kept, so the
+// releases still balance. Jobs placed by measured CPU may legitimately reserve
+// more than the worker's size, so that isn't checked while measurements are fresh.
+func (o *Orchestrator) setCPULocked(worker *WorkerInfo, cpuPercent float64) float64 {
+	measured := o.measuredLocked(worker, time.Now())
+	switch limit := o.maxCPU * worker.capacity(); {
+	case cpuPercent < -cpuEpsilon:
+		log.Printf("[WARNING] Invariant violated: Core %d reserved CPU would be %.1f%%, below 0; clamped", worker.CoreID, cpuPercent)
+		o.metrics.Inc("orchestrator_invariant_violations_total", "invariant", "cpu_below_zero")
+	case cpuPercent > limit+cpuEpsilon && !measured:
+		log.Printf("[WARNING] Invariant violated: Core %d reserved CPU %.1f%% exceeds its capacity (%.0f%%)", worker.CoreID, cpuPercent, limit)
+		o.metrics.Inc("orchestrator_invariant_violations_total", "invariant", "cpu_above_capacity")
+	}
+	worker.ReservedCPU = max(cpuPercent, 0)
+	worker.LastHeartbeat = time.Now()
+	o.refreshCPULocked(worker, time.Now())
+	return worker.CurrentCPU
+}
+
+// MarkWorkerHealth records the outcome of a worker health probe
+func (o *Orchestrator) MarkWorkerHealth(coreID int, healthy bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if worker, exists := o.workers[coreID]; exists {
+		worker.Down = "failed health probe"
+		if healthy {
+			worker.Down = ""
+			worker.LastHeartbeat = time.Now()
+		}
+	}
+}
+
+// RecordHeartbeat applies a worker's self-reported state. A draining worker is
+// marked down so nothing new is routed to it. Returns false for unknown workers.
+func (o *Orchestrator) RecordHeartbeat(hb protocol.WorkerHeartbeat) bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	for coreID, worker := range o.workers {
+		if hb.WorkerID != fmt.Sprintf("Worker-Core-%d", coreID) {
+			continue
+		}
+		if hb.WorkerUUID != "" && worker.UUID != "" && hb.WorkerUUID != worker.UUID {
+			return false // A container from a core's previous identity (e.g. before its identity file was reset)
 		}
 		worker.LastHeartbeat = time.Now()
+		worker.Down = ""
+		if hb.Draining {
+			worker.Down = "draining"
+		}
+		if hb.Version != "" {
+			worker.Version = hb.Version
+		}
+		return true
 	}
+	return false
 }
 
 // GetNextAvailableCore finds the first unoccupied core
 func (o *Orchestrator) GetNextAvailableCore() (int, error) {
+	return o.GetNextAvailableCoreWhere(nil)
+}
+
+// GetNextAvailableCoreWhere finds the first unoccupied core that ok accepts (nil
+// accepts any), skipping cores held back by the core filter
+func (o *Orchestrator) GetNextAvailableCoreWhere(ok func(coreID int) bool) (int, error) {
 	o.mu.RLock()
 	defer o.mu.RUnlock()
 
+	if o.shutdown.stopping {
+		return 0, errShuttingDown
+	}
+	if err := o.daemonDownLocked(); err != nil {
+		return 0, err
+	}
+	reserved := 0
 	for coreID := 1; coreID <= 3; coreID++ {
-		if _, exists := o.workers[coreID]; !exists {
-			return coreID, nil
+		if _, exists := o.workers[coreID]; exists {
+			continue
+		}
+		if (o.coreFilter != nil && !o.coreFilter(coreID)) || (ok != nil && !ok(coreID)) {
+			reserved++
+			continue
 		}
+		return coreID, nil
 	}
 
+	if reserved > 0 {
+		return 0, fmt.Errorf("no available cores (%d occupied, %d reserved for maintenance)", 3-reserved, reserved)
+	}
 	return 0, fmt.Errorf("no available cores (all 3 cores occupied)")
 }
 
@@ -184,32 +708,51 @@ func (o *Orchestrator) GetWorkerCount() int {
 	return len(o.workers)
 }
 
-// Shutdown stops and removes all worker containers
-func (o *Orchestrator) Shutdown() error {
+// Shutdown drains in-flight jobs until ctx ends, refusing new jobs and workers
+// meanwhile, then stops and removes all worker containers and the orchestrator's
+// background loops. Containers are removed even if ctx has ended. Only the first
+// call does anything.
+func (o *Orchestrator) Shutdown(ctx context.Context) error {
+	o.mu.Lock()
+	if o.shutdown.stopping {
+		o.mu.Unlock()
+		return nil
+	}
+	o.shutdown.stopping = true
+	drainer := o.shutdown.drainer
+	o.mu.Unlock()
+	defer o.cancel()
+
+	if drainer != nil {
+		log.Println("[Orchestrator] Shutting down: draining in-flight jobs...")
+		if err := drainer(ctx); err != nil {
+			log.Printf("[WARNING] Drain incomplete: %v", err)
+		}
+	}
+
 	o.mu.Lock()
 	defer o.mu.Unlock()
 
 	log.Println("[Orchestrator] Shutting down and cleaning up workers...")
 
-	var errors []error
+	// Stop workers in parallel: each may spend up to the drain timeout finishing its job
+	var (
+		wg     sync.WaitGroup
+		errMu  sync.Mutex
+		errors []error
+	)
 	for coreID, worker := range o.workers {
-		log.Printf("[Orchestrator] Stopping worker on Core %d (Container: %s)", coreID, worker.ContainerID[:12])
-
-		// Stop container with 10 second timeout
-		timeout := 10
-		if err := o.cli.ContainerStop(o.ctx, worker.ContainerID, container.StopOptions{Timeout: &timeout}); err != nil {
-			log.Printf("[WARNING] Failed to stop container %s: %v", worker.ContainerID[:12], err)
-			errors = append(errors, err)
-		}
-
-		// Remove container
-		if err := o.cli.ContainerRemove(o.ctx, worker.ContainerID, container.RemoveOptions{Force: true}); err != nil {
-			log.Printf("[WARNING] Failed to remove container %s: %v", worker.ContainerID[:12], err)
-			errors = append(errors, err)
-		} else {
-			log.Printf("[Orchestrator] Removed worker on Core %d", coreID)
-		}
+		wg.Add(1)
+		go func(coreID int, worker *WorkerInfo) {
+			defer wg.Done()
+			if err := o.stopAndRemove(coreID, worker); err != nil {
+				errMu.Lock()
+				errors = append(errors, err...)
+				errMu.Unlock()
+			}
+		}(coreID, worker)
 	}
+	wg.Wait()
 
 	// Clear the workers map
 	o.workers = make(map[int]*WorkerInfo)
@@ -221,3 +764,44 @@ func (o *Orchestrator) Shutdown() error {
 	log.Println("[Orchestrator] Cleanup completed")
 	return nil
 }
+
+// DetachWorker takes a worker out of the pool so nothing new is placed on it;
+// StopDetached then stops its container
+func (o *Orchestrator) DetachWorker(coreID int) (*WorkerInfo, bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	worker, exists := o.workers[coreID]
+	if exists {
+		delete(o.workers, coreID)
+	}
+	return worker, exists
+}
+
+// StopDetached stops and removes the container of a worker taken out of the pool
+// by DetachWorker. Failures are logged; the core is free either way.
+func (o *Orchestrator) StopDetached(worker *WorkerInfo) {
+	o.stopAndRemove(worker.CoreID, worker)
+}
+
+// stopAndRemove gracefully stops a worker container (letting it drain) and removes it
+func (o *Orchestrator) stopAndRemove(coreID int, worker *WorkerInfo) []error {
+	log.Printf("[Orchestrator] Stopping worker on Core %d (Container: %s)", coreID, worker.ContainerID[:12])
+
+	var errors []error
+	timeout := o.stopTimeout()
+	if err := o.cli.ContainerStop(o.ctx, worker.ContainerID, container.StopOptions{Timeout: &timeout}); err != nil {
+		log.Printf("[WARNING] Failed to stop container %s: %v", worker.ContainerID[:12], err)
+		errors = append(errors, err)
+	}
+
+	// Remove container
+	if err := o.cli.ContainerRemove(o.ctx, worker.ContainerID, container.RemoveOptions{Force: true}); err != nil {
+		log.Printf("[WARNING] Failed to remove container %s: %v", worker.ContainerID[:12], err)
+		errors = append(errors, err)
+	} else {
+		log.Printf("[Orchestrator] Removed worker on Core %d", coreID)
+	}
+
+	return errors
+}
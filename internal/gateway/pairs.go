@@ -0,0 +1,99 @@
+package gateway
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/ahmadhassan44/container-orchestrator/pkg/protocol"
+)
+
+// schedulePairJob runs a job that needs two workers, e.g. network_throughput: it
+// is sent to one, which works against the other. Both are reserved for the job's
+// estimated CPU under the scheduling lock. Pairs don't wait in queues or spawn
+// workers; when no two workers can take the job now, the attempt fails as a
+// queue failure, which a retry policy may retry.
+func (s *Scheduler) schedulePairJob(req *protocol.ComputeRequest, estimatedCPU, loadTime float64) (*protocol.JobResponse, error) {
+	if s.paused.Load() {
+		return nil, failure(protocol.FailureQueue, fmt.Errorf("scheduler is paused"))
+	}
+
+	s.scheduleMux.Lock()
+	sender, receiver := s.findWorkerPair(req, estimatedCPU, loadTime)
+	if sender == nil {
+		s.scheduleMux.Unlock()
+		return nil, failure(protocol.FailureQueue, fmt.Errorf("no two workers can each take %.1f%% CPU for %.0fs", estimatedCPU, loadTime))
+	}
+	s.orchestrator.AdjustWorkerCPU(sender, estimatedCPU)
+	s.orchestrator.AdjustWorkerCPU(receiver, estimatedCPU)
+	s.runningMu.Lock()
+	s.receiving[receiver.CoreID]++
+	s.runningMu.Unlock()
+	s.scheduleMux.Unlock()
+
+	s.annotate(req.JobID, func(a *protocol.JobAnnotations) { a.Placement = protocol.PlacementExisting })
+	s.annotateWorker(req.JobID, sender)
+	s.notifyDecision(telemetryPlaced, req, "", estimatedCPU, loadTime, sender, protocol.PlacementExisting, 0)
+
+	req.Network.Receiver = receiver.BaseURL
+	log.Printf("[Scheduler] Pairing job %s: Worker-Core-%d sends to Worker-Core-%d", req.JobID, sender.CoreID, receiver.CoreID)
+
+	response, err := s.executeJobOnWorker(sender, req)
+
+	s.orchestrator.AdjustWorkerCPU(sender, -estimatedCPU)
+	s.orchestrator.AdjustWorkerCPU(receiver, -estimatedCPU)
+	s.runningMu.Lock()
+	if s.receiving[receiver.CoreID]--; s.receiving[receiver.CoreID] == 0 {
+		delete(s.receiving, receiver.CoreID)
+	}
+	s.runningMu.Unlock()
+	s.wakeQueue()
+	return response, err
+}
+
+// findWorkerPair picks a sender by the job's placement strategy, then a receiver
+// among the remaining workers. Degraded cores are a last resort for either.
+// Returns nils if there aren't two workers with room. Callers hold scheduleMux.
+func (s *Scheduler) findWorkerPair(req *protocol.ComputeRequest, estimatedCPU, duration float64) (*WorkerInfo, *WorkerInfo) {
+	strategy, known := placementStrategies[s.jobStrategy(req.JobID)]
+	if !known {
+		strategy = placementStrategies[placementStrategy]
+	}
+	queue, _ := s.queues.resolve(req.Queue)
+	healthy, degraded := s.degradation.partition(s.placeableWorkers(duration, queue, operationName(req), req.Tolerations))
+	pick := func(exclude *WorkerInfo) *WorkerInfo {
+		for _, pool := range [][]*WorkerInfo{healthy, degraded} {
+			candidates := make([]*WorkerInfo, 0, len(pool))
+			for _, w := range pool {
+				if w != exclude {
+					candidates = append(candidates, w)
+				}
+			}
+			if w := strategy(candidates, estimatedCPU, s.config.MaxCPUThreshold); w != nil {
+				return w
+			}
+		}
+		return nil
+	}
+
+	sender := pick(nil)
+	if sender == nil {
+		return nil, nil
+	}
+	receiver := pick(sender)
+	if receiver == nil {
+		return nil, nil
+	}
+	return sender, receiver
+}
+
+// receivingCores snapshots the cores receiving paired jobs
+func (s *Scheduler) receivingCores() map[int]int {
+	s.runningMu.Lock()
+	defer s.runningMu.Unlock()
+
+	cores := make(map[int]int, len(s.receiving))
+	for coreID, n := range s.receiving {
+		cores[coreID] = n
+	}
+	return cores
+}
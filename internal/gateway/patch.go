@@ -0,0 +1,214 @@
+package gateway
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/ahmadhassan44/container-orchestrator/pkg/protocol"
+)
+
+// maxPatchBytes bounds a PATCH /jobs/{id} body
+const maxPatchBytes = 64 << 10
+
+// Request fields PATCH /jobs/{id} may change: how the job waits and is handled,
+// and the parameters that decide its work. Parameters are fixed once it has run
+// a time slice. Its ID, operation, queue and streaming are fixed.
+var (
+	patchableOptions = []string{"priority", "deadline", "on_queue_timeout", "retry", "labels",
+		"tolerations", "no_smt_sharing", "capture_logs", "partial_results", "annotate", "notify_email"}
+	patchableParams = []string{"cpu_load", "load_time", "iterations", "time_budget", "target_std_error",
+		"seed", "synthetic", "memory", "disk_io", "matrix", "profile"}
+)
+
+// derivedFields are the request fields a parameter block or profile fills in on
+// validation. A patch that changes the block drops them, unless it sets them
+// itself, so they are derived afresh rather than checked against the old values.
+var derivedFields = map[string][]string{
+	"synthetic": {"cpu_load", "load_time"},
+	"profile":   {"cpu_load", "load_time"},
+	"memory":    {"load_time"},
+	"disk_io":   {"load_time"},
+	"matrix":    {"load_time"},
+}
+
+// Why a job modification is refused
+var (
+	errNotQueued      = errors.New("job is not queued")
+	errAlreadySliced  = errors.New("job has already run part of its work")
+	errPatchOverQuota = errors.New("CPU-seconds quota exceeded")
+)
+
+// UpdateQueued applies fn to the request of a job waiting in a queue and
+// re-estimates it, returning the updated request. Returns errNotQueued if the
+// job isn't queued; an error from fn leaves the job unchanged.
+func (s *Scheduler) UpdateQueued(jobID string, fn func(req *protocol.ComputeRequest) error) (protocol.ComputeRequest, error) {
+	// Held so the queue processor can't dispatch the job halfway through the change
+	s.scheduleMux.Lock()
+	defer s.scheduleMux.Unlock()
+
+	var updated protocol.ComputeRequest
+	found, err := s.queues.update(jobID, func(job *QueuedJob) error {
+		req := *job.request
+		if err := fn(&req); err != nil {
+			return err
+		}
+		*job.request = req
+		job.estimatedCPU = s.estimator.EstimateCPUUsage(&req)
+		job.duration = s.estimator.EstimateJobDuration(&req)
+		updated = req
+		return nil
+	})
+	if !found {
+		return updated, errNotQueued
+	}
+	if err != nil {
+		return updated, err
+	}
+
+	s.annotate(jobID, func(a *protocol.JobAnnotations) {
+		a.EstimatedCPU = s.estimator.EstimateCPUUsage(&updated)
+		a.EstimatedDuration = s.estimator.EstimateJobDuration(&updated)
+	})
+	s.wakeQueue()
+	return updated, nil
+}
+
+// mergeRequest applies a JSON merge patch to req: each field in patch replaces
+// the request's, and null resets it. Objects are replaced, not merged, and the
+// fields derived from a replaced parameter block are reset.
+func mergeRequest(req protocol.ComputeRequest, patch map[string]json.RawMessage) (protocol.ComputeRequest, error) {
+	var merged protocol.ComputeRequest
+	data, err := json.Marshal(req)
+	if err != nil {
+		return merged, err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return merged, err
+	}
+	for key := range patch {
+		for _, derived := range derivedFields[key] {
+			if _, set := patch[derived]; !set {
+				delete(fields, derived)
+			}
+		}
+	}
+	for key, value := range patch {
+		if string(value) == "null" {
+			delete(fields, key)
+		} else {
+			fields[key] = value
+		}
+	}
+	if data, err = json.Marshal(fields); err != nil {
+		return merged, err
+	}
+	if err := decodeStrict(data, &merged); err != nil {
+		return merged, err
+	}
+	return merged, nil
+}
+
+// handlePatchJob changes a job that hasn't started yet, queued or held: its
+// priority, deadline, options or parameters. Only its submitter or an admin may.
+func (s *Server) handlePatchJob(w http.ResponseWriter, r *http.Request) {
+	job, exists := s.jobs.Get(r.PathValue("id"))
+	if !exists {
+		http.Error(w, "Job not found", http.StatusNotFound)
+		return
+	}
+	if !s.isAdmin(r) && s.sources.Identify(r).ID() != job.Source.ID() {
+		http.Error(w, "Only the submitter or an admin may change this job", http.StatusForbidden)
+		return
+	}
+
+	var patch map[string]json.RawMessage
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxPatchBytes)).Decode(&patch); err != nil || len(patch) == 0 {
+		http.Error(w, "Body must be a JSON object of the request fields to change", http.StatusBadRequest)
+		return
+	}
+	fields := make([]string, 0, len(patch))
+	changesParams := false
+	for field := range patch {
+		switch {
+		case slices.Contains(patchableParams, field):
+			changesParams = true
+		case !slices.Contains(patchableOptions, field):
+			http.Error(w, fmt.Sprintf("Field %q can't be changed; changeable fields: %s", field,
+				strings.Join(append(slices.Clone(patchableOptions), patchableParams...), ", ")), http.StatusBadRequest)
+			return
+		}
+		fields = append(fields, field)
+	}
+	slices.Sort(fields)
+
+	apply := func(charged bool) func(req *protocol.ComputeRequest) error {
+		return func(req *protocol.ComputeRequest) error {
+			if changesParams && req.Checkpoint != nil {
+				return fmt.Errorf("%w (%.0fs so far); only its options can change", errAlreadySliced, req.Checkpoint.Elapsed)
+			}
+			merged, err := mergeRequest(*req, patch)
+			if err != nil {
+				return fmt.Errorf("Invalid JSON: %v", err)
+			}
+			if err := s.validateRequest(&merged); err != nil {
+				return err
+			}
+			if infeasible := s.checkFeasible(&merged); infeasible != nil {
+				return infeasible
+			}
+			if charged && changesParams {
+				extra := s.scheduler.EstimateCPUSeconds(&merged) - s.scheduler.EstimateCPUSeconds(req)
+				if !s.quotas.Adjust(job.ID, extra) {
+					return fmt.Errorf("%w: the change needs %.1f more CPU-seconds", errPatchOverQuota, extra)
+				}
+			}
+			*req = merged
+			return nil
+		}
+	}
+
+	// Held jobs aren't charged against quotas until they are released
+	var updated protocol.ComputeRequest
+	held, err := s.intake.Update(job.ID, func(req *protocol.ComputeRequest) error {
+		if err := apply(false)(req); err != nil {
+			return err
+		}
+		updated = *req
+		return nil
+	})
+	if !held {
+		updated, err = s.scheduler.UpdateQueued(job.ID, apply(true))
+	}
+
+	var infeasible *InfeasibleJobError
+	switch {
+	case errors.Is(err, errNotQueued):
+		http.Error(w, fmt.Sprintf("Job is %s and no longer waiting to start", job.Status), http.StatusConflict)
+		return
+	case errors.Is(err, errAlreadySliced):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case errors.Is(err, errPatchOverQuota):
+		http.Error(w, err.Error(), http.StatusTooManyRequests)
+		return
+	case errors.As(err, &infeasible):
+		writeInfeasible(w, infeasible)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.jobs.UpdateRequest(job.ID, updated)
+	log.Printf("[Gateway] Job %s changed while waiting: %s", job.ID, strings.Join(fields, ", "))
+
+	job, _ = s.jobs.Get(job.ID)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(job)
+}
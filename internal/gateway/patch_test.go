@@ -0,0 +1,181 @@
+//go:build integration
+
+package gateway
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/ahmadhassan44/container-orchestrator/pkg/config"
+	"github.com/ahmadhassan44/container-orchestrator/pkg/protocol"
+)
+
+// newPausedGateway returns a gateway whose scheduler queues jobs without
+// dispatching them, cancelling whatever is still queued when the test ends
+func newPausedGateway(t *testing.T, cfg *config.Config) *testGateway {
+	t.Helper()
+
+	g := newTestGateway(t, cfg)
+	g.sched.Pause()
+	t.Cleanup(func() { g.sched.CancelJobs(g.sched.inFlight()) })
+	return g
+}
+
+// submitQueued submits req asynchronously as the holder of apiKey and waits
+// until it is queued, returning its job ID
+func (g *testGateway) submitQueued(t *testing.T, req protocol.ComputeRequest, apiKey string) string {
+	t.Helper()
+
+	req.Async = true
+	payload, _ := json.Marshal(req)
+	httpReq, _ := http.NewRequest(http.MethodPost, g.server.URL+"/submit", bytes.NewReader(payload))
+	httpReq.Header.Set("X-API-Key", apiKey)
+	resp, err := http.DefaultClient.Do(httpReq)
+	if err != nil {
+		t.Fatalf("submit: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("submit: status %d, want 202", resp.StatusCode)
+	}
+	var accepted struct {
+		JobID string `json:"job_id"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&accepted); err != nil {
+		t.Fatalf("decode submit response: %v", err)
+	}
+
+	for deadline := time.Now().Add(5 * time.Second); ; {
+		for _, job := range g.sched.queues.waiting() {
+			if job.JobID == accepted.JobID {
+				return accepted.JobID
+			}
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("job %s never queued", accepted.JobID)
+		}
+		time.Sleep(25 * time.Millisecond)
+	}
+}
+
+// patch sends PATCH /jobs/{id} as the holder of apiKey, returning the status and,
+// on success, the updated job
+func (g *testGateway) patch(t *testing.T, jobID, apiKey, body string) (int, JobRecord) {
+	t.Helper()
+
+	httpReq, _ := http.NewRequest(http.MethodPatch, g.server.URL+"/jobs/"+jobID, bytes.NewBufferString(body))
+	httpReq.Header.Set("X-API-Key", apiKey)
+	resp, err := http.DefaultClient.Do(httpReq)
+	if err != nil {
+		t.Fatalf("patch: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var job JobRecord
+	if resp.StatusCode == http.StatusOK {
+		if err := json.NewDecoder(resp.Body).Decode(&job); err != nil {
+			t.Fatalf("decode patched job: %v", err)
+		}
+	}
+	return resp.StatusCode, job
+}
+
+func TestIntegrationPatchRejectsOtherSources(t *testing.T) {
+	cfg := testConfig()
+	cfg.AdminToken = "secret"
+	g := newPausedGateway(t, cfg)
+
+	jobID := g.submitQueued(t, protocol.ComputeRequest{CPULoad: 50, LoadTime: 10}, "alice")
+
+	if status, _ := g.patch(t, jobID, "mallory", `{"priority": 5}`); status != http.StatusForbidden {
+		t.Errorf("patch by another source: status %d, want 403", status)
+	}
+	if status, job := g.patch(t, jobID, "alice", `{"priority": 5}`); status != http.StatusOK || job.Request.Priority != 5 {
+		t.Errorf("patch by the submitter: status %d, priority %d; want 200 and 5", status, job.Request.Priority)
+	}
+}
+
+func TestIntegrationPatchRejectsParamsAfterSlice(t *testing.T) {
+	g := newPausedGateway(t, testConfig())
+
+	jobID := g.submitQueued(t, protocol.ComputeRequest{CPULoad: 50, LoadTime: 10}, "alice")
+	g.sched.queues.update(jobID, func(job *QueuedJob) error {
+		job.request.Checkpoint = &protocol.Checkpoint{Elapsed: 5}
+		return nil
+	})
+
+	if status, _ := g.patch(t, jobID, "alice", `{"load_time": 20}`); status != http.StatusConflict {
+		t.Errorf("parameter patch after a slice: status %d, want 409", status)
+	}
+	if status, _ := g.patch(t, jobID, "alice", `{"priority": 5}`); status != http.StatusOK {
+		t.Errorf("option patch after a slice: status %d, want 200", status)
+	}
+}
+
+func TestIntegrationPatchRejectsOverQuota(t *testing.T) {
+	cfg := testConfig()
+	cfg.QuotaCPUSeconds = 20
+	cfg.QuotaWindow = 3600
+	g := newPausedGateway(t, cfg)
+
+	jobID := g.submitQueued(t, protocol.ComputeRequest{CPULoad: 50, LoadTime: 10}, "alice")
+
+	if status, _ := g.patch(t, jobID, "alice", `{"load_time": 100}`); status != http.StatusTooManyRequests {
+		t.Errorf("patch past the quota: status %d, want 429", status)
+	}
+	if status, job := g.patch(t, jobID, "alice", `{"load_time": 20}`); status != http.StatusOK || job.Request.LoadTime != 20 {
+		t.Errorf("patch within the quota: status %d, load_time %g; want 200 and 20", status, job.Request.LoadTime)
+	}
+}
+
+func TestIntegrationPatchPriorityReordersQueue(t *testing.T) {
+	g := newPausedGateway(t, testConfig())
+
+	first := g.submitQueued(t, protocol.ComputeRequest{CPULoad: 50, LoadTime: 10}, "alice")
+	second := g.submitQueued(t, protocol.ComputeRequest{CPULoad: 50, LoadTime: 10}, "alice")
+
+	if status, _ := g.patch(t, second, "alice", `{"priority": 10}`); status != http.StatusOK {
+		t.Fatalf("priority patch: status %d, want 200", status)
+	}
+	waiting := g.sched.queues.waiting()
+	if len(waiting) != 2 || waiting[0].JobID != second || waiting[1].JobID != first {
+		t.Errorf("queue order after raising the second job's priority = %v, want [%s %s]", waiting, second, first)
+	}
+}
+
+func TestIntegrationPatchSyntheticDuration(t *testing.T) {
+	g := newPausedGateway(t, testConfig())
+
+	jobID := g.submitQueued(t, protocol.ComputeRequest{
+		Operation: "synthetic_load",
+		Synthetic: &protocol.SyntheticLoad{TargetCPU: 40, Duration: 10},
+	}, "alice")
+
+	status, job := g.patch(t, jobID, "alice", `{"synthetic": {"target_cpu": 50, "duration": 20}}`)
+	if status != http.StatusOK {
+		t.Fatalf("synthetic patch: status %d, want 200", status)
+	}
+	if job.Request.LoadTime != 20 || job.Request.CPULoad != 50 {
+		t.Errorf("after the patch load_time = %g, cpu_load = %g; want 20 and 50", job.Request.LoadTime, job.Request.CPULoad)
+	}
+}
+
+func TestIntegrationPatchMatrixSize(t *testing.T) {
+	g := newPausedGateway(t, testConfig())
+
+	jobID := g.submitQueued(t, protocol.ComputeRequest{
+		Operation: "matrix_determinant",
+		Matrix:    &protocol.MatrixParams{Size: 100},
+	}, "alice")
+
+	status, job := g.patch(t, jobID, "alice", `{"matrix": {"size": 2000}}`)
+	if status != http.StatusOK {
+		t.Fatalf("matrix patch: status %d, want 200", status)
+	}
+	if job.Request.Matrix.Size != 2000 || job.Request.LoadTime <= 1 {
+		t.Errorf("after the patch size = %d, load_time = %g; want 2000 and more than 1", job.Request.Matrix.Size, job.Request.LoadTime)
+	}
+}
@@ -0,0 +1,89 @@
+package gateway
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// maxPortAttempts bounds how many ports StartWorker tries when the daemon reports a
+// conflict (another process grabbed the port between probing and binding)
+const maxPortAttempts = 3
+
+// allocatePortLocked picks a host port for a worker on coreID. The conventional
+// basePort+coreID is preferred so ports stay predictable; otherwise the first free
+// port above the conventional ones, up to basePort+range, is used. Ports held by
+// other workers or in skip are never chosen, and every candidate is probed by
+// binding it. Caller holds o.mu.
+func (o *Orchestrator) allocatePortLocked(coreID int, skip map[int]bool) (int, error) {
+	taken := make(map[int]bool, len(o.workers)+len(skip))
+	for _, worker := range o.workers {
+		taken[worker.HostPort] = true
+	}
+	for port := range skip {
+		taken[port] = true
+	}
+
+	preferred := o.workerBasePort + coreID
+	if !taken[preferred] && portAvailable(preferred) {
+		return preferred, nil
+	}
+
+	// Leave the other cores' conventional ports for them
+	first, last := o.workerBasePort+len(coreMaps)+1, o.workerBasePort+o.workerPortRange
+	for port := first; port <= last; port++ {
+		if !taken[port] && portAvailable(port) {
+			return port, nil
+		}
+	}
+	return 0, fmt.Errorf("port %d is in use and no fallback port in %d-%d is free", preferred, first, last)
+}
+
+// portAvailable reports whether port can currently be bound on all interfaces
+func portAvailable(port int) bool {
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	if err != nil {
+		return false
+	}
+	listener.Close()
+	return true
+}
+
+// isPortConflict recognises the daemon's (or the fake runtime's) port clash errors
+func isPortConflict(err error) bool {
+	msg := err.Error()
+	return strings.Contains(msg, "port is already allocated") || strings.Contains(msg, "address already in use")
+}
+
+// inspectStarted reads back what Docker actually started for a worker: the host
+// port published for its 8080/tcp, the base URL the gateway reaches it on and the
+// ID (digest) of the image it runs
+func (o *Orchestrator) inspectStarted(containerID string) (hostPort int, baseURL, imageID string, err error) {
+	inspect, err := o.cli.ContainerInspect(o.ctx, containerID)
+	if err != nil {
+		return 0, "", "", fmt.Errorf("container inspect failed: %w", err)
+	}
+	if inspect.NetworkSettings == nil {
+		return 0, "", "", fmt.Errorf("container %s has no network settings", containerID[:12])
+	}
+	if inspect.ContainerJSONBase != nil {
+		imageID = inspect.Image
+	}
+
+	for _, binding := range inspect.NetworkSettings.Ports["8080/tcp"] {
+		if port, err := strconv.Atoi(binding.HostPort); err == nil && port > 0 {
+			hostPort = port
+			break
+		}
+	}
+	if hostPort == 0 && o.workerAddress.publish {
+		return 0, "", "", fmt.Errorf("container %s has no published port for 8080/tcp", containerID[:12])
+	}
+
+	baseURL, err = o.workerAddress.baseURL(inspect, hostPort)
+	if err != nil {
+		return 0, "", "", fmt.Errorf("container %s: %w", containerID[:12], err)
+	}
+	return hostPort, baseURL, imageID, nil
+}
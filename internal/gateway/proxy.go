@@ -0,0 +1,58 @@
+package gateway
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+)
+
+// proxyBlockedPaths are worker endpoints the proxy refuses: jobs sent straight to
+// a worker would bypass scheduling and CPU accounting (see benchmark mode instead)
+var proxyBlockedPaths = []string{"/submit"}
+
+// handleWorkerProxy forwards GET /workers/{core}/proxy/{path...} (and POST) to the
+// worker's own HTTP API, so its endpoints can be reached without publishing its
+// port. The gateway's admin token is checked and stripped; every call is audited.
+func (s *Server) handleWorkerProxy(w http.ResponseWriter, r *http.Request) {
+	worker, ok := s.runningWorker(w, r)
+	if !ok {
+		return
+	}
+	path := "/" + r.PathValue("path")
+	for _, blocked := range proxyBlockedPaths {
+		if path == blocked || strings.HasPrefix(path, blocked+"/") {
+			http.Error(w, fmt.Sprintf("%s can't be proxied; submit jobs through the gateway", blocked), http.StatusForbidden)
+			return
+		}
+	}
+	target, err := url.Parse(worker.BaseURL)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid worker URL: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	caller := s.sources.Identify(r).ID()
+	proxy := &httputil.ReverseProxy{
+		Rewrite: func(pr *httputil.ProxyRequest) {
+			pr.Out.URL.Scheme = target.Scheme
+			pr.Out.URL.Host = target.Host
+			pr.Out.URL.Path = path
+			pr.Out.URL.RawPath = ""
+			pr.Out.Host = target.Host
+			pr.Out.Header.Del("Authorization")
+		},
+		FlushInterval: -1, // Relay streamed responses as they arrive
+		ModifyResponse: func(resp *http.Response) error {
+			log.Printf("[Audit] Proxied %s %s to Worker-Core-%d for %s: %d", r.Method, path, worker.CoreID, caller, resp.StatusCode)
+			return nil
+		},
+		ErrorHandler: func(w http.ResponseWriter, _ *http.Request, err error) {
+			log.Printf("[Audit] Proxied %s %s to Worker-Core-%d for %s: failed: %v", r.Method, path, worker.CoreID, caller, err)
+			http.Error(w, fmt.Sprintf("Worker communication failed: %v", err), http.StatusBadGateway)
+		},
+	}
+	proxy.ServeHTTP(w, r)
+}
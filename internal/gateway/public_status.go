@@ -0,0 +1,93 @@
+package gateway
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/ahmadhassan44/container-orchestrator/pkg/config"
+)
+
+// publicStatusTTL is how long a rendered public status is served before it is
+// recomputed, so an embedded page with many viewers costs next to nothing
+const publicStatusTTL = 5 * time.Second
+
+// Public states, most severe first
+const (
+	PublicStandby     = "standby"     // A hot standby; submissions go to the primary
+	PublicMaintenance = "maintenance" // Submissions are held
+	PublicPaused      = "paused"      // Scheduling is paused; jobs queue
+	PublicOperational = "operational"
+)
+
+// PublicStatus is the sanitized status safe to share outside the team: no
+// container or worker IDs, no client identities, no job details
+type PublicStatus struct {
+	State       string    `json:"state"`
+	Utilization int       `json:"utilization_percent"` // Share of worker capacity in use, to the nearest 10%
+	QueueDepth  int       `json:"queue_depth"`         // Jobs waiting, across all queues
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// PublicStatusPage renders and caches the public status
+type PublicStatusPage struct {
+	onMain bool // Also served on the main listener
+
+	mu       sync.Mutex
+	rendered []byte
+	at       time.Time
+}
+
+func NewPublicStatusPage(cfg *config.Config) *PublicStatusPage {
+	if !cfg.PublicStatus && cfg.PublicStatusPort <= 0 {
+		return nil
+	}
+	return &PublicStatusPage{onMain: cfg.PublicStatus}
+}
+
+// publicStatus builds the public status from the scheduler's coarse state
+func (s *Server) publicStatus() PublicStatus {
+	status := PublicStatus{State: PublicOperational, UpdatedAt: time.Now().UTC().Truncate(time.Second)}
+	switch {
+	case s.replication.Following():
+		status.State = PublicStandby
+	case s.intake.Holding():
+		status.State = PublicMaintenance
+	case s.scheduler.IsPaused():
+		status.State = PublicPaused
+	}
+	utilization, _ := s.scheduler.queues.load()
+	status.Utilization = int(math.Round(utilization*10)) * 10
+	status.QueueDepth = s.scheduler.QueueLength()
+	return status
+}
+
+// handlePublicStatus serves the public status to anyone, from any origin
+func (s *Server) handlePublicStatus(w http.ResponseWriter, r *http.Request) {
+	page := s.public
+	page.mu.Lock()
+	if page.rendered == nil || time.Since(page.at) >= publicStatusTTL {
+		page.rendered, _ = json.Marshal(s.publicStatus())
+		page.at = time.Now()
+	}
+	body := page.rendered
+	page.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(publicStatusTTL.Seconds())))
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Write(append(body, '\n'))
+}
+
+// StartPublicStatus serves GET /public/status, and nothing else, on port
+func (s *Server) StartPublicStatus(port int) error {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /public/status", s.handlePublicStatus)
+	addr := fmt.Sprintf(":%d", port)
+	log.Printf("[Gateway] Public status listener on %s", addr)
+	return http.ListenAndServe(addr, mux)
+}
@@ -0,0 +1,478 @@
+package gateway
+
+import (
+	"fmt"
+	"log"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/ahmadhassan44/container-orchestrator/pkg/config"
+	"github.com/ahmadhassan44/container-orchestrator/pkg/protocol"
+)
+
+// namedQueue is a FIFO of jobs with its own size, timeout and capacity share
+type namedQueue struct {
+	config        config.QueueConfig
+	items         []*QueuedJob
+	reservedCPU   float64    // Estimated CPU held by this queue's running jobs
+	currentWeight int        // Smooth weighted round-robin state
+	waits         waitWindow // Recent waits of dispatched jobs
+	cores         []int      // Cores the queue's jobs are bound to (nil = any core not bound to a queue)
+}
+
+// queueSet holds all named queues and decides which one dequeues next
+type queueSet struct {
+	mu           sync.Mutex
+	queues       map[string]*namedQueue
+	order        []string // Configuration order, for stable iteration
+	defaultQueue string
+	capacity     float64      // Total worker CPU capacity (standard workers x per-worker threshold)
+	dedicated    map[int]bool // Cores bound to some queue; fixed at startup
+
+	starvationFactor float64 // Waits beyond this multiple of the queue median are starvation (0 = off)
+	starvationBoost  float64 // Weight multiplier for a queue whose head job is starving
+}
+
+func newQueueSet(cfg *config.Config, maxWorkers int) *queueSet {
+	qs := &queueSet{
+		queues:       make(map[string]*namedQueue),
+		defaultQueue: cfg.DefaultQueue,
+		dedicated:    make(map[int]bool),
+		capacity:     float64(maxWorkers) * cfg.MaxCPUThreshold,
+
+		starvationFactor: cfg.StarvationFactor,
+		starvationBoost:  cfg.StarvationBoost,
+	}
+	configs := cfg.Queues
+	if len(configs) == 0 {
+		// No queues configured: a single FIFO with the original limits
+		configs = []config.QueueConfig{{Name: "default", MaxSize: 100, Timeout: 300, Weight: 1, WorkerShare: 1.0}}
+	}
+	for _, qc := range configs {
+		qs.queues[qc.Name] = &namedQueue{config: qc}
+		qs.order = append(qs.order, qc.Name)
+	}
+
+	// Fall back to the first configured queue if the default doesn't exist
+	if _, exists := qs.queues[qs.defaultQueue]; !exists && len(qs.order) > 0 {
+		qs.defaultQueue = qs.order[0]
+	}
+	qs.bindCores(cfg.QueueCores)
+	return qs
+}
+
+// bindCores dedicates cores to the queues QUEUE_CORES names, skipping unknown
+// queues and cores
+func (qs *queueSet) bindCores(queueCores map[string][]int) {
+	for name, cores := range queueCores {
+		q, exists := qs.queues[name]
+		if !exists {
+			log.Printf("[WARNING] Ignoring QUEUE_CORES for unknown queue %q", name)
+			continue
+		}
+		for _, core := range cores {
+			if _, known := coreMaps[core]; !known {
+				log.Printf("[WARNING] Ignoring unknown QUEUE_CORES core %d for queue %q", core, name)
+				continue
+			}
+			if !slices.Contains(q.cores, core) {
+				q.cores = append(q.cores, core)
+			}
+			qs.dedicated[core] = true
+		}
+		slices.Sort(q.cores)
+	}
+
+	for _, name := range qs.order {
+		if cores := qs.queues[name].cores; cores != nil {
+			log.Printf("[Scheduler] Queue %q is bound to cores %v", name, cores)
+		} else if len(qs.dedicated) > 0 && len(qs.dedicated) == len(coreMaps) {
+			log.Printf("[WARNING] Every core is bound to a queue; jobs in queue %q can't run", name)
+		}
+	}
+}
+
+// allows reports whether a job from the named queue may run on coreID. A queue
+// bound to cores runs only on them; other queues run only on cores no queue is
+// bound to. A job outside any queue ("") may run anywhere.
+func (qs *queueSet) allows(name string, coreID int) bool {
+	q, exists := qs.queues[name]
+	switch {
+	case !exists:
+		return true
+	case q.cores != nil:
+		return slices.Contains(q.cores, coreID)
+	default:
+		return !qs.dedicated[coreID]
+	}
+}
+
+// resolve maps a requested queue name to a configured queue name
+func (qs *queueSet) resolve(name string) (string, error) {
+	if name == "" {
+		return qs.defaultQueue, nil
+	}
+	if _, exists := qs.queues[name]; !exists {
+		return "", fmt.Errorf("unknown queue %q", name)
+	}
+	return name, nil
+}
+
+// timeout returns how long a job may wait in the named queue
+func (qs *queueSet) timeout(name string) time.Duration {
+	return time.Duration(qs.queues[name].config.Timeout) * time.Second
+}
+
+// enqueue appends a job to its queue, failing if the queue is full
+func (qs *queueSet) enqueue(job *QueuedJob) error {
+	qs.mu.Lock()
+	defer qs.mu.Unlock()
+
+	q := qs.queues[job.queue]
+	if len(q.items) >= q.config.MaxSize {
+		return failure(protocol.FailureQueue, fmt.Errorf("queue %q full (max size: %d), cannot accept job", job.queue, q.config.MaxSize))
+	}
+	q.insert(job)
+	return nil
+}
+
+// insert adds job behind every job of equal or higher priority (caller holds qs.mu)
+func (q *namedQueue) insert(job *QueuedJob) {
+	i := len(q.items)
+	for i > 0 && q.items[i-1].request.Priority < job.request.Priority {
+		i--
+	}
+	q.items = slices.Insert(q.items, i, job)
+}
+
+// update applies fn to a queued job, moving it if its priority changed. Reports
+// false if the job isn't queued; an error from fn leaves it where it was.
+func (qs *queueSet) update(jobID string, fn func(job *QueuedJob) error) (bool, error) {
+	qs.mu.Lock()
+	defer qs.mu.Unlock()
+
+	for _, q := range qs.queues {
+		for i, item := range q.items {
+			if item.request.JobID != jobID {
+				continue
+			}
+			priority := item.request.Priority
+			if err := fn(item); err != nil {
+				return true, err
+			}
+			if item.request.Priority != priority {
+				q.items = slices.Delete(q.items, i, i+1)
+				q.insert(item)
+			}
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// accepting reports whether the named queue has room for another job
+func (qs *queueSet) accepting(name string) bool {
+	qs.mu.Lock()
+	defer qs.mu.Unlock()
+
+	q := qs.queues[name]
+	return len(q.items) < q.config.MaxSize
+}
+
+// remove takes a job out of its queue, reporting whether it was still waiting
+func (qs *queueSet) remove(job *QueuedJob) bool {
+	qs.mu.Lock()
+	defer qs.mu.Unlock()
+
+	q := qs.queues[job.queue]
+	for i, item := range q.items {
+		if item == job {
+			q.items = append(q.items[:i], q.items[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// removeByID takes a job out of whichever queue holds it (nil if not queued)
+func (qs *queueSet) removeByID(jobID string) *QueuedJob {
+	qs.mu.Lock()
+	defer qs.mu.Unlock()
+
+	for _, q := range qs.queues {
+		for i, item := range q.items {
+			if item.request.JobID == jobID {
+				q.items = append(q.items[:i], q.items[i+1:]...)
+				return item
+			}
+		}
+	}
+	return nil
+}
+
+// expiresAt is when job outwaits its queue: its deadline if it has one and hasn't
+// started yet, else the queue's timeout, plus any grace its timeout policy gave it
+func (job *QueuedJob) expiresAt(timeout time.Duration) time.Time {
+	at := job.enqueuedAt.Add(timeout)
+	if !job.request.Deadline.IsZero() && job.request.Checkpoint == nil {
+		at = job.request.Deadline
+	}
+	return at.Add(job.timeoutGrace)
+}
+
+// queueTimeout is a queued job that outwaited its queue's timeout
+type queueTimeout struct {
+	job    *QueuedJob
+	from   string // Queue it timed out in
+	policy string // Policy applied; QueueTimeoutFail if the job was removed
+	queue  string // Queue it waits in now (policies other than fail)
+}
+
+// expire applies each job's timeout policy once it has waited longer than its
+// queue's timeout (plus any grace a policy gave it). Failed jobs are removed.
+func (qs *queueSet) expire() []queueTimeout {
+	qs.mu.Lock()
+	defer qs.mu.Unlock()
+
+	var timeouts []queueTimeout
+	for _, name := range qs.order {
+		q := qs.queues[name]
+		kept := q.items[:0]
+		for _, job := range q.items {
+			if !time.Now().After(job.expiresAt(time.Duration(q.config.Timeout) * time.Second)) {
+				kept = append(kept, job)
+				continue
+			}
+			timeout := queueTimeout{job: job, from: name, policy: protocol.QueueTimeoutFail}
+			switch job.request.OnQueueTimeout {
+			case protocol.QueueTimeoutExtend:
+				if !job.extended {
+					job.extended = true
+					job.timeoutGrace += time.Duration(q.config.Timeout) * time.Second
+					timeout.policy, timeout.queue = protocol.QueueTimeoutExtend, name
+					kept = append(kept, job)
+				}
+			case protocol.QueueTimeoutDowngrade:
+				// The lower queue's timeout starts now, in place of any deadline
+				if lower := qs.lowerLocked(name); lower != "" {
+					job.queue, job.request.Queue = lower, lower
+					job.request.Deadline = time.Time{}
+					job.timeoutGrace = time.Since(job.enqueuedAt)
+					qs.queues[lower].insert(job)
+					timeout.policy, timeout.queue = protocol.QueueTimeoutDowngrade, lower
+				}
+			}
+			timeouts = append(timeouts, timeout)
+		}
+		q.items = kept
+	}
+	return timeouts
+}
+
+// lowerLocked returns the queue a job timing out in name is downgraded to: the
+// highest-weight queue of lower weight than name's with room ("" if none)
+func (qs *queueSet) lowerLocked(name string) string {
+	weight := qs.queues[name].config.Weight
+	lower := ""
+	for _, candidate := range qs.order {
+		q := qs.queues[candidate]
+		if q.config.Weight >= weight || len(q.items) >= q.config.MaxSize {
+			continue
+		}
+		if lower == "" || q.config.Weight > qs.queues[lower].config.Weight {
+			lower = candidate
+		}
+	}
+	return lower
+}
+
+// withinShareLocked reports whether the queue can take on estimatedCPU more without
+// exceeding its share of cluster capacity (caller holds qs.mu)
+func (qs *queueSet) withinShareLocked(q *namedQueue, estimatedCPU float64) bool {
+	return q.reservedCPU+estimatedCPU <= q.config.WorkerShare*qs.capacity
+}
+
+// setCapacity changes the total worker CPU capacity shares are taken from
+func (qs *queueSet) setCapacity(capacity float64) {
+	qs.mu.Lock()
+	defer qs.mu.Unlock()
+	qs.capacity = capacity
+}
+
+// totalCapacity returns the total worker CPU capacity
+func (qs *queueSet) totalCapacity() float64 {
+	qs.mu.Lock()
+	defer qs.mu.Unlock()
+	return qs.capacity
+}
+
+// canRun reports whether a job from the named queue may start under its worker share
+func (qs *queueSet) canRun(name string, estimatedCPU float64) bool {
+	qs.mu.Lock()
+	defer qs.mu.Unlock()
+	return qs.withinShareLocked(qs.queues[name], estimatedCPU)
+}
+
+// peekNext selects the next queue by smooth weighted round-robin among queues
+// whose head job fits their share, skipping excluded queues. A starving head job
+// boosts its queue's weight. Returns nil if none.
+func (qs *queueSet) peekNext(excluded map[string]bool) *QueuedJob {
+	qs.mu.Lock()
+	defer qs.mu.Unlock()
+
+	var best *namedQueue
+	totalWeight := 0
+	for _, name := range qs.order {
+		q := qs.queues[name]
+		if excluded[name] || len(q.items) == 0 || !qs.withinShareLocked(q, q.items[0].estimatedCPU) {
+			continue
+		}
+		weight := qs.effectiveWeightLocked(q)
+		q.currentWeight += weight
+		totalWeight += weight
+		if best == nil || q.currentWeight > best.currentWeight {
+			best = q
+		}
+	}
+
+	if best == nil {
+		return nil
+	}
+	best.currentWeight -= totalWeight
+	return best.items[0]
+}
+
+// reserve accounts a dispatched job against its queue's share
+func (qs *queueSet) reserve(name string, estimatedCPU float64) {
+	qs.mu.Lock()
+	defer qs.mu.Unlock()
+	qs.queues[name].reservedCPU += estimatedCPU
+}
+
+// release returns a finished job's CPU to its queue's share
+func (qs *queueSet) release(name string, estimatedCPU float64) {
+	qs.mu.Lock()
+	defer qs.mu.Unlock()
+
+	q := qs.queues[name]
+	q.reservedCPU -= estimatedCPU
+	if q.reservedCPU < 0 {
+		q.reservedCPU = 0
+	}
+}
+
+// length returns the total number of waiting jobs across all queues
+func (qs *queueSet) length() int {
+	qs.mu.Lock()
+	defer qs.mu.Unlock()
+
+	total := 0
+	for _, q := range qs.queues {
+		total += len(q.items)
+	}
+	return total
+}
+
+// load returns the share of worker capacity held by running jobs and the share of
+// total queue space that is occupied, both 0-1
+func (qs *queueSet) load() (utilization, fill float64) {
+	qs.mu.Lock()
+	defer qs.mu.Unlock()
+
+	reserved, waiting, size := 0.0, 0, 0
+	for _, q := range qs.queues {
+		reserved += q.reservedCPU
+		waiting += len(q.items)
+		size += q.config.MaxSize
+	}
+	if qs.capacity > 0 {
+		utilization = min(reserved/qs.capacity, 1)
+	}
+	if size > 0 {
+		fill = float64(waiting) / float64(size)
+	}
+	return utilization, fill
+}
+
+// wantsYield reports whether waiting jobs that haven't run yet outnumber the sliced
+// jobs that have already yielded a worker to them
+func (qs *queueSet) wantsYield() bool {
+	qs.mu.Lock()
+	defer qs.mu.Unlock()
+
+	unstarted, yielded := 0, 0
+	for _, q := range qs.queues {
+		for _, job := range q.items {
+			if job.slices == 0 {
+				unstarted++
+			} else {
+				yielded++
+			}
+		}
+	}
+	return unstarted > yielded
+}
+
+// waiting returns a snapshot of all queued jobs, in queue configuration order
+func (qs *queueSet) waiting() []WaitingJob {
+	qs.mu.Lock()
+	defer qs.mu.Unlock()
+
+	jobs := make([]WaitingJob, 0)
+	for _, name := range qs.order {
+		for _, job := range qs.queues[name].items {
+			workLeft := job.duration
+			if job.request.Checkpoint != nil {
+				workLeft -= job.request.Checkpoint.Elapsed
+			}
+			jobs = append(jobs, WaitingJob{
+				JobID:          job.request.JobID,
+				Operation:      operationName(job.request),
+				Queue:          name,
+				WaitingSeconds: time.Since(job.enqueuedAt).Seconds(),
+				EstimatedCPU:   job.estimatedCPU,
+				WorkSeconds:    workLeft,
+				Starving:       job.starving,
+				duration:       job.duration,
+				tolerations:    job.request.Tolerations,
+				noSMTSharing:   job.request.NoSMTSharing,
+			})
+		}
+	}
+	return jobs
+}
+
+// lengths returns waiting jobs per queue
+func (qs *queueSet) lengths() map[string]int {
+	qs.mu.Lock()
+	defer qs.mu.Unlock()
+
+	lengths := make(map[string]int, len(qs.queues))
+	for name, q := range qs.queues {
+		lengths[name] = len(q.items)
+	}
+	return lengths
+}
+
+// status returns per-queue configuration and state
+func (qs *queueSet) status() map[string]interface{} {
+	qs.mu.Lock()
+	defer qs.mu.Unlock()
+
+	status := make(map[string]interface{}, len(qs.queues))
+	for name, q := range qs.queues {
+		status[name] = map[string]interface{}{
+			"queue_size":   len(q.items),
+			"max_size":     q.config.MaxSize,
+			"timeout":      q.config.Timeout,
+			"weight":       q.config.Weight,
+			"worker_share": q.config.WorkerShare,
+			"cores":        q.cores,
+			"reserved_cpu": q.reservedCPU,
+			"wait_p50":     q.waits.quantile(0.5),
+			"wait_p90":     q.waits.quantile(0.9),
+		}
+	}
+	return status
+}
@@ -0,0 +1,186 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// QuotaStatus is a source's CPU-seconds budget in the current window
+type QuotaStatus struct {
+	Source        string    `json:"source"`
+	Budget        float64   `json:"budget_cpu_seconds"`
+	Used          float64   `json:"used_cpu_seconds"`
+	Remaining     float64   `json:"remaining_cpu_seconds"`
+	WindowSeconds int       `json:"window_seconds"`
+	ResetsAt      time.Time `json:"resets_at"`
+}
+
+// quotaWindow is one source's consumption in a fixed window
+type quotaWindow struct {
+	start time.Time
+	used  float64
+}
+
+// quotaCharge is an admitted job's reservation against its source's window.
+// It starts at the estimate and is replaced by actual usage once the job ends.
+type quotaCharge struct {
+	source      string
+	windowStart time.Time
+	estimated   float64
+	actual      float64
+}
+
+// QuotaTracker enforces per-source budgets denominated in estimated CPU-seconds
+// per fixed window, so one huge job counts as much as many small ones
+type QuotaTracker struct {
+	mu      sync.Mutex
+	budget  float64 // CPU-seconds per source per window (0 = unlimited)
+	window  time.Duration
+	windows map[string]*quotaWindow // Source ID -> current window
+	charges map[string]*quotaCharge // Job ID -> reservation awaiting settlement
+}
+
+func NewQuotaTracker(budget float64, windowSeconds int) *QuotaTracker {
+	return &QuotaTracker{
+		budget:  budget,
+		window:  time.Duration(windowSeconds) * time.Second,
+		windows: make(map[string]*quotaWindow),
+		charges: make(map[string]*quotaCharge),
+	}
+}
+
+// Enabled reports whether a budget is configured
+func (q *QuotaTracker) Enabled() bool {
+	return q.budget > 0
+}
+
+// Admit reserves cpuSeconds against the source's budget. It returns nil and false
+// if that would exceed the budget, and a nil charge if quotas are disabled.
+func (q *QuotaTracker) Admit(source string, cpuSeconds float64) (*quotaCharge, bool) {
+	if !q.Enabled() {
+		return nil, true
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	w := q.windowLocked(source)
+	if w.used+cpuSeconds > q.budget {
+		return nil, false
+	}
+	w.used += cpuSeconds
+	return &quotaCharge{source: source, windowStart: w.start, estimated: cpuSeconds}, true
+}
+
+// Track associates a charge with its job so usage reports can reach it
+func (q *QuotaTracker) Track(jobID string, charge *quotaCharge) {
+	if charge == nil {
+		return
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.charges[jobID] = charge
+}
+
+// RecordUsage adds CPU-seconds a job actually consumed (see Scheduler.SetUsageListener)
+func (q *QuotaTracker) RecordUsage(jobID string, cpuSeconds float64) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if charge, exists := q.charges[jobID]; exists {
+		charge.actual += cpuSeconds
+	}
+}
+
+// Settle replaces a finished job's estimate with its actual usage. Charges from a
+// window that has since rolled over are dropped.
+func (q *QuotaTracker) Settle(jobID string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	charge, exists := q.charges[jobID]
+	if !exists {
+		return
+	}
+	delete(q.charges, jobID)
+	q.settleLocked(charge)
+}
+
+// Adjust changes a tracked job's reservation by cpuSeconds, e.g. when a queued job
+// is modified. Returns false, changing nothing, if an increase would exceed the budget.
+func (q *QuotaTracker) Adjust(jobID string, cpuSeconds float64) bool {
+	if !q.Enabled() {
+		return true
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	charge, exists := q.charges[jobID]
+	if !exists {
+		return true
+	}
+	w := q.windowLocked(charge.source)
+	if !w.start.Equal(charge.windowStart) {
+		return true // Charged to a window that has rolled over
+	}
+	if cpuSeconds > 0 && w.used+cpuSeconds > q.budget {
+		return false
+	}
+	w.used = max(w.used+cpuSeconds, 0)
+	charge.estimated += cpuSeconds
+	return true
+}
+
+// Release returns an admitted charge whose job never got created
+func (q *QuotaTracker) Release(charge *quotaCharge) {
+	if charge == nil {
+		return
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.settleLocked(charge)
+}
+
+func (q *QuotaTracker) settleLocked(charge *quotaCharge) {
+	if w, exists := q.windows[charge.source]; exists && w.start.Equal(charge.windowStart) {
+		w.used = max(w.used+charge.actual-charge.estimated, 0)
+	}
+}
+
+// Status reports a source's budget and usage in the current window
+func (q *QuotaTracker) Status(source string) QuotaStatus {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	w := q.windowLocked(source)
+	return QuotaStatus{
+		Source:        source,
+		Budget:        q.budget,
+		Used:          w.used,
+		Remaining:     max(q.budget-w.used, 0),
+		WindowSeconds: int(q.window.Seconds()),
+		ResetsAt:      w.start.Add(q.window),
+	}
+}
+
+// windowLocked returns the source's current window, starting a fresh one if the last expired
+func (q *QuotaTracker) windowLocked(source string) *quotaWindow {
+	w, exists := q.windows[source]
+	if !exists || time.Since(w.start) >= q.window {
+		w = &quotaWindow{start: time.Now()}
+		q.windows[source] = w
+	}
+	return w
+}
+
+// handleQuota reports the caller's CPU-seconds budget and usage
+func (s *Server) handleQuota(w http.ResponseWriter, r *http.Request) {
+	if !s.quotas.Enabled() {
+		http.Error(w, "Quotas are not enabled (set QUOTA_CPU_SECONDS)", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.quotas.Status(s.sources.Identify(r).ID()))
+}
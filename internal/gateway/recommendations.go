@@ -0,0 +1,231 @@
+package gateway
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// recommendMinJobs is how many jobs a core must have run in the period before
+	// its size is judged
+	recommendMinJobs = 20
+
+	// recommendHeadroom is the margin a recommended CPU quota leaves over p95 demand
+	recommendHeadroom = 1.25
+
+	// recommendQuotaStep is what recommended CPU quotas are rounded up to
+	recommendQuotaStep = 0.05
+)
+
+// WorkerRecommendation is the size suggested for one core's worker, and why
+type WorkerRecommendation struct {
+	CoreID      int               `json:"core_id"`
+	Jobs        int               `json:"jobs"`    // Jobs in the period that ran on the core
+	JobCPU      ReportPercentiles `json:"job_cpu"` // Each job's estimated CPU, % of a standard worker
+	Demand      ReportConcurrency `json:"demand"`  // CPU reserved on the core over time
+	Current     WorkerResources   `json:"current"`
+	Recommended WorkerResources   `json:"recommended"`
+	Change      bool              `json:"change"`            // Recommended differs from current
+	Applied     bool              `json:"applied,omitempty"` // Resized to the recommendation just now
+	Reasons     []string          `json:"reasons"`
+}
+
+// WorkerRecommendations sizes every core's worker from a period of job history
+type WorkerRecommendations struct {
+	GeneratedAt time.Time              `json:"generated_at"`
+	From        time.Time              `json:"from"`
+	To          time.Time              `json:"to"`
+	Days        int                    `json:"days"`
+	AutoApply   bool                   `json:"auto_apply"` // Applied every RECOMMENDATION_INTERVAL
+	Workers     []WorkerRecommendation `json:"workers"`
+}
+
+// recommendWorkerSizes suggests a cpuset and CPU quota for every core from the
+// jobs that ran on it between from and to
+func recommendWorkerSizes(entries []HistoryEntry, from, to time.Time, current map[int]WorkerResources, threshold float64) []WorkerRecommendation {
+	byCore := make(map[int][]HistoryEntry)
+	for _, e := range entries {
+		if _, known := coreMaps[e.CoreID]; e.Kind == historyJob && e.RunTime > 0 && known {
+			byCore[e.CoreID] = append(byCore[e.CoreID], e)
+		}
+	}
+
+	recs := make([]WorkerRecommendation, 0, len(coreMaps))
+	for coreID := range coreMaps {
+		recs = append(recs, recommendWorkerSize(coreID, byCore[coreID], from, to, current[coreID], threshold))
+	}
+	sort.Slice(recs, func(i, j int) bool { return recs[i].CoreID < recs[j].CoreID })
+	return recs
+}
+
+// recommendWorkerSize gives a core's worker as many of its default threads as
+// the p95 of the CPU reserved on it needs, with a quota when even that leaves a
+// large margin. Cores whose jobs typically saturate every thread keep them all.
+func recommendWorkerSize(coreID int, jobs []HistoryEntry, from, to time.Time, current WorkerResources, threshold float64) WorkerRecommendation {
+	rec := WorkerRecommendation{CoreID: coreID, Jobs: len(jobs), Current: current, Recommended: current, Reasons: []string{}}
+	cpu := make([]float64, 0, len(jobs))
+	for _, job := range jobs {
+		cpu = append(cpu, job.EstimatedCPU)
+	}
+	rec.JobCPU = percentiles(cpu)
+	rec.Demand = concurrency(jobs, from, to)
+
+	if len(jobs) < recommendMinJobs {
+		rec.Reasons = append(rec.Reasons, fmt.Sprintf("only %d job(s) ran in the period (%d needed); keeping its size", len(jobs), recommendMinJobs))
+		return rec
+	}
+
+	standard, _ := parseCpuset(coreMaps[coreID])
+	threads := len(standard)
+	share := 100 / float64(threads)   // CPU % of a standard worker one thread gives
+	usable := share * threshold / 100 // ... of which the scheduler fills up to MAX_CPU_THRESHOLD
+	need := rec.Demand.P95CPU
+
+	if threads > 1 && rec.JobCPU.P50 >= (float64(threads)-0.5)*share {
+		rec.Recommended = defaultResources(coreID)
+		rec.Reasons = append(rec.Reasons, fmt.Sprintf("most jobs saturate all %d threads (median job %.0f%% CPU); keeping the full cpuset without a quota",
+			threads, rec.JobCPU.P50))
+	} else {
+		want := min(max(int(math.Ceil(need/usable)), 1), threads)
+		cpus := make([]string, want)
+		for i, cpu := range standard[:want] {
+			cpus[i] = strconv.Itoa(cpu)
+		}
+		rec.Recommended = WorkerResources{Cpuset: strings.Join(cpus, ",")}
+		rec.Reasons = append(rec.Reasons, fmt.Sprintf("95%% of busy time needed at most %.0f%% CPU (peak %.0f%%): %d of its %d thread(s)",
+			need, rec.Demand.PeakCPU, want, threads))
+
+		quota := math.Ceil(max(need, 1)*recommendHeadroom/usable/recommendQuotaStep) * recommendQuotaStep
+		if quota <= float64(want)-0.25 {
+			rec.Recommended.CPUs = quota
+			rec.Reasons = append(rec.Reasons, fmt.Sprintf("a %.2f CPU quota leaves %.0f%% headroom over that", quota, 100*(recommendHeadroom-1)))
+		}
+	}
+
+	if threads > 1 && rec.JobCPU.P90 <= share && rec.Demand.PeakJobs > 1 {
+		rec.Reasons = append(rec.Reasons, fmt.Sprintf("90%% of jobs need at most one thread (%.0f%% CPU) and up to %d ran at once: "+
+			"%d 1-thread workers would serve them better, but a core runs a single worker", rec.JobCPU.P90, rec.Demand.PeakJobs, threads))
+	}
+	rec.Change = rec.Recommended != current
+	return rec
+}
+
+// Recommendations sizes every core's worker from the last days of job history
+func (s *Server) Recommendations(days int) (WorkerRecommendations, error) {
+	to := time.Now()
+	from := to.Add(-time.Duration(days) * 24 * time.Hour)
+
+	entries, err := s.history.Since(from)
+	if err != nil {
+		return WorkerRecommendations{}, err
+	}
+	cfg := s.scheduler.config
+	return WorkerRecommendations{
+		GeneratedAt: to,
+		From:        from,
+		To:          to,
+		Days:        days,
+		AutoApply:   cfg.RecommendationAutoApply,
+		Workers:     recommendWorkerSizes(entries, from, to, s.scheduler.orchestrator.CoreResources(), cfg.MaxCPUThreshold),
+	}, nil
+}
+
+// applyRecommendations resizes the workers whose recommendation differs from
+// their size (only coreID's, unless 0), marking each one applied
+func (s *Server) applyRecommendations(recs *WorkerRecommendations, coreID int, by string) {
+	for i := range recs.Workers {
+		rec := &recs.Workers[i]
+		if !rec.Change || (coreID != 0 && rec.CoreID != coreID) {
+			continue
+		}
+		old, err := s.scheduler.ResizeWorker(rec.CoreID, rec.Recommended)
+		if err != nil {
+			log.Printf("[WARNING] Core %d not resized to its recommendation: %v", rec.CoreID, err)
+			rec.Reasons = append(rec.Reasons, "not applied: "+err.Error())
+			continue
+		}
+		rec.Applied = true
+		log.Printf("[Audit] Core %d resized to its recommendation by %s: cpuset %s -> %s, cpus %g -> %g",
+			rec.CoreID, by, old.Cpuset, rec.Recommended.Cpuset, old.CPUs, rec.Recommended.CPUs)
+	}
+}
+
+// StartRecommendations applies the worker size recommendations every
+// RECOMMENDATION_INTERVAL seconds, if RECOMMENDATION_AUTO_APPLY is set
+func (s *Server) StartRecommendations() {
+	cfg := s.scheduler.config
+	if !cfg.RecommendationAutoApply || cfg.RecommendationInterval <= 0 {
+		return
+	}
+	days := min(max(cfg.RecommendationDays, 1), s.history.RetentionDays())
+	log.Printf("[Recommendations] Resizing workers to recommendations from %d day(s) of history every %ds", days, cfg.RecommendationInterval)
+
+	ctx := s.scheduler.orchestrator.ctx
+	go func() {
+		ticker := time.NewTicker(time.Duration(cfg.RecommendationInterval) * time.Second)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				recs, err := s.Recommendations(days)
+				if err != nil {
+					log.Printf("[Recommendations] %v", err)
+					continue
+				}
+				s.applyRecommendations(&recs, 0, "auto-apply")
+			}
+		}
+	}()
+}
+
+// handleRecommendations serves worker size recommendations: GET /recommendations?days=7
+func (s *Server) handleRecommendations(w http.ResponseWriter, r *http.Request) {
+	days, ok := s.reportDays(w, r, s.scheduler.config.RecommendationDays)
+	if !ok {
+		return
+	}
+	recs, err := s.Recommendations(days)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(recs)
+}
+
+// handleApplyRecommendations resizes workers to their recommendations now:
+// POST /recommendations/apply?days=7[&core=2]
+func (s *Server) handleApplyRecommendations(w http.ResponseWriter, r *http.Request) {
+	days, ok := s.reportDays(w, r, s.scheduler.config.RecommendationDays)
+	if !ok {
+		return
+	}
+	coreID := 0
+	if raw := r.URL.Query().Get("core"); raw != "" {
+		var err error
+		if coreID, err = strconv.Atoi(raw); err != nil || coreMaps[coreID] == "" {
+			http.Error(w, "Unknown core", http.StatusNotFound)
+			return
+		}
+	}
+
+	recs, err := s.Recommendations(days)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	s.applyRecommendations(&recs, coreID, "admin")
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(recs)
+}
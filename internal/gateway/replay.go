@@ -0,0 +1,242 @@
+package gateway
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/ahmadhassan44/container-orchestrator/pkg/protocol"
+)
+
+// ReplayJob is one submission of a recorded workload. The scheduler only sees a
+// job's estimated CPU, estimated duration and queue, so a cpu_load job with the
+// recorded estimates presents it with exactly the recorded input.
+type ReplayJob struct {
+	Offset    time.Duration `json:"offset"` // Arrival after the first job
+	JobID     string        `json:"job_id"` // In the recording
+	Operation string        `json:"operation"`
+	Queue     string        `json:"queue,omitempty"`
+	CPULoad   float64       `json:"cpu_load"`
+	LoadTime  float64       `json:"load_time"`
+}
+
+// Request builds the submission for a replayed job, with its durations divided
+// by speed
+func (j ReplayJob) Request(speed float64) protocol.ComputeRequest {
+	return protocol.ComputeRequest{
+		CPULoad:  j.CPULoad,
+		LoadTime: j.LoadTime / speed,
+		Queue:    j.Queue,
+		Annotate: true,
+	}
+}
+
+// LoadWorkload reads a recorded workload, ordered by arrival: either a telemetry
+// CSV (TELEMETRY_FILE) or a job history log (JOB_HISTORY_FILE)
+func LoadWorkload(path string) ([]ReplayJob, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	first, err := r.Peek(1)
+	if err != nil {
+		return nil, fmt.Errorf("empty workload file %s", path)
+	}
+
+	var jobs []ReplayJob
+	var arrivals []time.Time
+	if first[0] == '{' {
+		jobs, arrivals, err = workloadFromHistory(r)
+	} else {
+		jobs, arrivals, err = workloadFromTelemetry(r)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading workload %s: %w", path, err)
+	}
+	if len(jobs) == 0 {
+		return nil, fmt.Errorf("no jobs in workload %s", path)
+	}
+
+	order := make([]int, len(jobs))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return arrivals[order[a]].Before(arrivals[order[b]]) })
+
+	start := arrivals[order[0]]
+	sorted := make([]ReplayJob, len(jobs))
+	for i, idx := range order {
+		sorted[i] = jobs[idx]
+		sorted[i].Offset = arrivals[idx].Sub(start)
+	}
+	return sorted, nil
+}
+
+// workloadFromTelemetry reads jobs from their outcome rows, arriving at their
+// submission time. A job without an outcome (still running when the recording
+// ended) arrives at its first decision.
+func workloadFromTelemetry(r io.Reader) ([]ReplayJob, []time.Time, error) {
+	cr := csv.NewReader(r)
+	header, err := cr.Read()
+	if err != nil {
+		return nil, nil, err
+	}
+	col := make(map[string]int, len(header))
+	for i, name := range header {
+		col[name] = i
+	}
+	for _, name := range []string{"timestamp", "event", "job_id", "queue", "estimated_cpu", "estimated_duration"} {
+		if _, ok := col[name]; !ok {
+			return nil, nil, fmt.Errorf("telemetry CSV has no %q column", name)
+		}
+	}
+	field := func(row []string, name string) string {
+		if i, ok := col[name]; ok && i < len(row) {
+			return row[i]
+		}
+		return ""
+	}
+
+	var (
+		jobs     []ReplayJob
+		arrivals []time.Time
+		index    = make(map[string]int)  // Job ID -> position in jobs
+		outcome  = make(map[string]bool) // Arrival taken from the outcome row
+	)
+	for line := 2; ; line++ {
+		row, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+
+		event, id := field(row, "event"), field(row, "job_id")
+		if outcome[id] || field(row, "estimated_cpu") == "" {
+			continue // Already known, or never reached the scheduler
+		}
+		stamp := field(row, "timestamp")
+		switch event {
+		case telemetryPlaced, telemetryQueued, telemetryDequeue:
+			if _, seen := index[id]; seen {
+				continue // A retried job is placed again; it arrived once
+			}
+		case telemetryOutcome:
+			if field(row, "submitted_at") != "" {
+				stamp = field(row, "submitted_at")
+			}
+			outcome[id] = true
+		default:
+			continue
+		}
+
+		at, err := time.Parse(time.RFC3339Nano, stamp)
+		if err != nil {
+			return nil, nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		cpu, err1 := strconv.ParseFloat(field(row, "estimated_cpu"), 64)
+		duration, err2 := strconv.ParseFloat(field(row, "estimated_duration"), 64)
+		if err := errors.Join(err1, err2); err != nil {
+			return nil, nil, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		job := ReplayJob{JobID: id, Operation: field(row, "operation"), Queue: field(row, "queue"), CPULoad: cpu, LoadTime: duration}
+		if i, seen := index[id]; seen {
+			jobs[i], arrivals[i] = job, at
+			continue
+		}
+		index[id] = len(jobs)
+		jobs = append(jobs, job)
+		arrivals = append(arrivals, at)
+	}
+	return jobs, arrivals, nil
+}
+
+// workloadFromHistory takes jobs that reached a worker, arriving at their submission time
+func workloadFromHistory(r io.Reader) ([]ReplayJob, []time.Time, error) {
+	var (
+		jobs     []ReplayJob
+		arrivals []time.Time
+	)
+	dec := json.NewDecoder(r)
+	for {
+		var e HistoryEntry
+		err := dec.Decode(&e)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		if e.Kind != historyJob || e.SubmittedAt.IsZero() || e.RunTime <= 0 {
+			continue
+		}
+		jobs = append(jobs, ReplayJob{
+			JobID:     e.JobID,
+			Operation: e.Operation,
+			Queue:     e.Queue,
+			CPULoad:   e.EstimatedCPU,
+			LoadTime:  e.EstimatedDuration,
+		})
+		arrivals = append(arrivals, e.SubmittedAt)
+	}
+	return jobs, arrivals, nil
+}
+
+// ReplayResult is how one replayed job fared
+type ReplayResult struct {
+	Job         ReplayJob
+	JobID       string        // Assigned by the gateway in this run
+	Status      int           // HTTP status of the submission
+	Error       string        // Response body on failure
+	Latency     time.Duration // Submission to response
+	Annotations *protocol.JobAnnotations
+}
+
+// ReplaySummary aggregates a replay run, for comparing runs of the same workload
+type ReplaySummary struct {
+	Jobs       int               `json:"jobs"`
+	Failed     int               `json:"failed"`
+	Makespan   float64           `json:"makespan"` // Seconds from the first submission to the last response
+	Latency    ReportPercentiles `json:"latency"`
+	QueueWait  ReportPercentiles `json:"queue_wait"`
+	Placements map[string]int    `json:"placements"`
+	Strategies map[string]int    `json:"strategies"`
+}
+
+// SummarizeReplay aggregates replay results; makespan is measured against start
+func SummarizeReplay(results []ReplayResult, start, end time.Time) ReplaySummary {
+	summary := ReplaySummary{
+		Jobs:       len(results),
+		Makespan:   end.Sub(start).Seconds(),
+		Placements: make(map[string]int),
+		Strategies: make(map[string]int),
+	}
+	var latencies, waits []float64
+	for _, r := range results {
+		latencies = append(latencies, r.Latency.Seconds())
+		if r.Status/100 != 2 {
+			summary.Failed++
+			continue
+		}
+		if a := r.Annotations; a != nil {
+			waits = append(waits, a.QueueWait)
+			summary.Placements[a.Placement]++
+			summary.Strategies[a.Strategy]++
+		}
+	}
+	summary.Latency = percentiles(latencies)
+	summary.QueueWait = percentiles(waits)
+	return summary
+}
@@ -0,0 +1,320 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ahmadhassan44/container-orchestrator/pkg/config"
+	"github.com/ahmadhassan44/container-orchestrator/pkg/signing"
+)
+
+// Replication timing: a primary sends a batch every interval (an empty one doubles
+// as a heartbeat); a standby drops a stream silent for the timeout and reconnects
+// after the retry delay
+const (
+	replicationInterval = time.Second
+	replicationTimeout  = 5 * time.Second
+	replicationRetry    = 2 * time.Second
+)
+
+// errFailover fails the jobs a promoted standby finds unfinished: they ran on the
+// old primary, which took them down with it
+var errFailover = errors.New("lost in gateway failover; resubmit the job")
+
+// ReplicationBatch is one line of the replication stream
+type ReplicationBatch struct {
+	Rev     uint64           `json:"rev"` // Primary's job store revision the batch is current to
+	SentAt  time.Time        `json:"sent_at"`
+	Jobs    []JobRecord      `json:"jobs"`    // Records changed since the previous batch (all of them in the first)
+	Workers []SnapshotWorker `json:"workers"` // The primary's workers
+}
+
+// Replication streams job records and worker state from a primary gateway to hot
+// standbys. A standby applies each batch as it arrives, so on failover it holds
+// every job record, and the primary's worker view, as of a second or two before.
+type Replication struct {
+	primary string // Base URL of the primary this gateway follows ("" = not a standby)
+	secret  string // Signs the replication stream (PEER_SECRET)
+	client  *http.Client
+
+	mu         sync.Mutex
+	following  bool // A standby until promoted
+	connected  bool
+	lastBatch  time.Time // When the last batch arrived
+	primaryRev uint64
+	applied    int              // Records applied
+	workers    []SnapshotWorker // The primary's workers as of the last batch
+	standbys   int              // Standbys streaming from this gateway
+	stop       context.CancelFunc
+}
+
+func NewReplication(cfg *config.Config) *Replication {
+	r := &Replication{
+		primary: strings.TrimRight(cfg.ReplicateFrom, "/"),
+		secret:  cfg.PeerSecret,
+		client:  &http.Client{},
+	}
+	if r.primary != "" && r.secret == "" {
+		log.Printf("[WARNING] REPLICATE_FROM needs PEER_SECRET; not following %s", r.primary)
+		r.primary = ""
+	}
+	r.following = r.primary != ""
+	return r
+}
+
+// Following reports whether this gateway is a standby that hasn't been promoted
+func (r *Replication) Following() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.following
+}
+
+func (r *Replication) setConnected(connected bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.connected = connected
+}
+
+// record notes a batch applied from the primary
+func (r *Replication) record(batch ReplicationBatch) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.lastBatch = time.Now()
+	r.primaryRev = batch.Rev
+	r.applied += len(batch.Jobs)
+	r.workers = batch.Workers
+}
+
+// addStandby counts a standby connecting (+1) or leaving (-1)
+func (r *Replication) addStandby(delta int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.standbys += delta
+}
+
+// Standbys counts the standbys streaming from this gateway
+func (r *Replication) Standbys() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.standbys
+}
+
+// promote stops following the primary, reporting false if this isn't a standby
+func (r *Replication) promote() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.following {
+		return false
+	}
+	r.following, r.connected = false, false
+	if r.stop != nil {
+		r.stop()
+	}
+	return true
+}
+
+// lag is how long ago the last batch arrived (0 before the first)
+func (r *Replication) lag() float64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.lastBatch.IsZero() {
+		return 0
+	}
+	return time.Since(r.lastBatch).Seconds()
+}
+
+// Status reports this gateway's replication role and state
+func (r *Replication) Status() map[string]interface{} {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	status := map[string]interface{}{
+		"role":     "primary",
+		"standbys": r.standbys,
+	}
+	if r.following {
+		status["role"] = "standby"
+		status["primary"] = r.primary
+		status["connected"] = r.connected
+		status["primary_rev"] = r.primaryRev
+		status["records_applied"] = r.applied
+		status["workers"] = r.workers
+		if !r.lastBatch.IsZero() {
+			status["last_batch_at"] = r.lastBatch
+			status["lag_seconds"] = time.Since(r.lastBatch).Seconds()
+		}
+	}
+	return status
+}
+
+// StartReplication follows the primary in REPLICATE_FROM, if set, until promoted
+func (s *Server) StartReplication() {
+	rep := s.replication
+	if !rep.Following() {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	rep.mu.Lock()
+	rep.stop = cancel
+	rep.mu.Unlock()
+
+	log.Printf("[Replication] Standby of %s; submissions are refused until promoted", rep.primary)
+	go func() {
+		for {
+			err := s.followPrimary(ctx)
+			rep.setConnected(false)
+			if ctx.Err() != nil {
+				return
+			}
+			log.Printf("[Replication] Lost %s, reconnecting in %s: %v", rep.primary, replicationRetry, err)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(replicationRetry):
+			}
+		}
+	}()
+}
+
+// followPrimary applies the primary's replication stream until it ends, goes
+// silent or ctx is done
+func (s *Server) followPrimary(ctx context.Context) error {
+	rep := s.replication
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rep.primary+"/replication/stream", nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set(headerForwardedFrom, s.federation.nodeName)
+	if err := signing.Sign(req, rep.secret, nil); err != nil {
+		return err
+	}
+	resp, err := rep.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	rep.setConnected(true)
+	log.Printf("[Replication] Following %s", rep.primary)
+
+	// A primary that hangs without closing the connection is given up on
+	watchdog := time.AfterFunc(replicationTimeout, cancel)
+	defer watchdog.Stop()
+
+	decoder := json.NewDecoder(resp.Body)
+	for {
+		var batch ReplicationBatch
+		if err := decoder.Decode(&batch); err != nil {
+			return fmt.Errorf("stream ended: %w", err)
+		}
+		watchdog.Reset(replicationTimeout)
+		for _, job := range batch.Jobs {
+			s.jobs.Replicate(job)
+		}
+		rep.record(batch)
+	}
+}
+
+// handleReplicationStream streams job record changes and the worker view to a
+// standby, once a second, for as long as it stays connected. The request must be
+// signed with PEER_SECRET.
+func (s *Server) handleReplicationStream(w http.ResponseWriter, r *http.Request) {
+	if s.federation.secret == "" {
+		http.Error(w, "This gateway accepts no standbys (PEER_SECRET unset)", http.StatusForbidden)
+		return
+	}
+	standby := r.Header.Get(headerForwardedFrom)
+	if err := s.forwardVerifier.Verify(s.federation.secret, r.Header, nil); err != nil {
+		log.Printf("[Replication] Rejected standby %q from %s: %v", standby, r.RemoteAddr, err)
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	s.replication.addStandby(1)
+	defer s.replication.addStandby(-1)
+	log.Printf("[Replication] Standby %q connected from %s", standby, r.RemoteAddr)
+	defer log.Printf("[Replication] Standby %q disconnected", standby)
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	flusher, _ := w.(http.Flusher)
+	encoder := json.NewEncoder(w)
+	ticker := time.NewTicker(replicationInterval)
+	defer ticker.Stop()
+
+	var rev uint64
+	for {
+		jobs, current := s.jobs.ChangedSince(rev)
+		batch := ReplicationBatch{Rev: current, SentAt: time.Now(), Jobs: jobs, Workers: snapshotWorkers(s.scheduler.orchestrator)}
+		if err := encoder.Encode(batch); err != nil {
+			return
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		rev = current
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// handleReplicationStatus reports this gateway's replication role and state
+func (s *Server) handleReplicationStatus(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.replication.Status())
+}
+
+// handlePromote makes a standby take over: it stops following its primary,
+// accepts submissions, and fails the replicated jobs that hadn't finished, since
+// they were running or waiting on the old primary
+func (s *Server) handlePromote(w http.ResponseWriter, r *http.Request) {
+	lag := s.replication.lag()
+	if !s.replication.promote() {
+		http.Error(w, "This gateway is not a standby", http.StatusConflict)
+		return
+	}
+
+	failed := 0
+	for _, job := range s.jobs.List("", "", nil, 0) {
+		if job.CompletedAt.IsZero() {
+			s.jobs.Fail(job.ID, errFailover)
+			failed++
+		}
+	}
+	log.Printf("[Audit] Standby promoted (last batch %.1fs ago); %d unfinished job(s) failed", lag, failed)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"promoted":    true,
+		"lag_seconds": lag,
+		"failed_jobs": failed,
+	})
+}
+
+// refuseOnStandby answers a submission to a standby, reporting whether it did
+func (s *Server) refuseOnStandby(w http.ResponseWriter) bool {
+	if !s.replication.Following() {
+		return false
+	}
+	w.Header().Set("Retry-After", "5")
+	http.Error(w, "This gateway is a standby; submit to the primary", http.StatusServiceUnavailable)
+	return true
+}
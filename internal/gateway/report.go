@@ -0,0 +1,338 @@
+package gateway
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"time"
+)
+
+const (
+	// defaultReportDays is the period a capacity report covers unless asked otherwise
+	defaultReportDays = 7
+
+	// reportQueueWaitConcern is the p90 queue wait (seconds) above which the report
+	// recommends a worker beyond what CPU demand alone needs
+	reportQueueWaitConcern = 5.0
+
+	// reportSpawnChurn is the spawns per day above which keeping workers warm is advised
+	reportSpawnChurn = 24.0
+)
+
+// CapacityReport summarizes utilization over a period of job history and
+// recommends how many workers to keep running
+type CapacityReport struct {
+	GeneratedAt    time.Time              `json:"generated_at"`
+	From           time.Time              `json:"from"`
+	To             time.Time              `json:"to"`
+	Days           int                    `json:"days"`
+	Jobs           map[string]int         `json:"jobs"` // By final status, plus "total"
+	Concurrency    ReportConcurrency      `json:"concurrency"`
+	QueueWait      ReportPercentiles      `json:"queue_wait"` // Seconds, jobs that reached a worker
+	Spawns         ReportSpawns           `json:"spawns"`
+	Estimator      ReportEstimator        `json:"estimator"`
+	Recommendation CapacityRecommendation `json:"recommendation"`
+}
+
+// ReportConcurrency describes how many jobs ran at once and the CPU they reserved
+type ReportConcurrency struct {
+	PeakJobs     int       `json:"peak_jobs"`
+	PeakJobsAt   time.Time `json:"peak_jobs_at,omitzero"`
+	PeakCPU      float64   `json:"peak_cpu"`      // Sum of running jobs' estimated CPU %
+	P95CPU       float64   `json:"p95_cpu"`       // Time-weighted, while any job ran
+	MeanCPU      float64   `json:"mean_cpu"`      // Time-weighted over the whole period
+	BusyFraction float64   `json:"busy_fraction"` // Share of the period with a job running
+}
+
+// ReportPercentiles is a distribution summary
+type ReportPercentiles struct {
+	Samples int     `json:"samples"`
+	P50     float64 `json:"p50"`
+	P90     float64 `json:"p90"`
+	P99     float64 `json:"p99"`
+	Max     float64 `json:"max"`
+}
+
+// ReportSpawns counts worker container starts
+type ReportSpawns struct {
+	Total      int     `json:"total"`
+	PerDay     float64 `json:"per_day"`
+	BusiestDay string  `json:"busiest_day,omitempty"` // Local date
+	BusiestMax int     `json:"busiest_day_spawns"`
+}
+
+// ReportEstimator compares estimated and actual run times of completed jobs
+type ReportEstimator struct {
+	Samples      int     `json:"samples"`
+	MeanAbsError float64 `json:"mean_abs_error"` // Seconds
+	Bias         float64 `json:"bias"`           // Mean of actual - estimate (positive = underestimates)
+	RatioP50     float64 `json:"ratio_p50"`      // Actual / estimate
+	RatioP90     float64 `json:"ratio_p90"`
+}
+
+// CapacityRecommendation is the worker count the report suggests, and why
+type CapacityRecommendation struct {
+	Workers        int      `json:"workers"`
+	PeakWorkers    int      `json:"peak_workers"`    // Workers the busiest moment needed
+	InitialWorkers int      `json:"initial_workers"` // Currently configured INITIAL_WORKERS
+	Notes          []string `json:"notes"`
+}
+
+// capacitySizing is what the recommendation is measured against
+type capacitySizing struct {
+	threshold      float64 // CPU % one worker takes (MAX_CPU_THRESHOLD)
+	initialWorkers int
+	maxWorkers     int
+}
+
+// buildCapacityReport analyzes history entries between from and to
+func buildCapacityReport(entries []HistoryEntry, from, to time.Time, sizing capacitySizing) CapacityReport {
+	report := CapacityReport{
+		GeneratedAt: time.Now(),
+		From:        from,
+		To:          to,
+		Days:        int(math.Round(to.Sub(from).Hours() / 24)),
+		Jobs:        map[string]int{"total": 0},
+	}
+
+	var (
+		waits, ratios  []float64
+		errSum, absSum float64
+		spawnsByDay    = make(map[string]int)
+		jobs           []HistoryEntry
+	)
+	for _, e := range entries {
+		switch e.Kind {
+		case historySpawn:
+			report.Spawns.Total++
+			spawnsByDay[e.At.Local().Format(time.DateOnly)]++
+		case historyJob:
+			report.Jobs["total"]++
+			report.Jobs[e.Status]++
+			if e.RunTime <= 0 {
+				continue
+			}
+			jobs = append(jobs, e)
+			waits = append(waits, e.QueueWait)
+			if e.Status == "completed" && e.EstimatedDuration > 0 {
+				diff := e.RunTime - e.EstimatedDuration
+				errSum += diff
+				absSum += math.Abs(diff)
+				ratios = append(ratios, e.RunTime/e.EstimatedDuration)
+			}
+		}
+	}
+
+	report.Concurrency = concurrency(jobs, from, to)
+	report.QueueWait = percentiles(waits)
+
+	if days := to.Sub(from).Hours() / 24; days > 0 {
+		report.Spawns.PerDay = float64(report.Spawns.Total) / days
+	}
+	for day, count := range spawnsByDay {
+		if count > report.Spawns.BusiestMax || (count == report.Spawns.BusiestMax && day < report.Spawns.BusiestDay) {
+			report.Spawns.BusiestDay, report.Spawns.BusiestMax = day, count
+		}
+	}
+
+	if n := len(ratios); n > 0 {
+		report.Estimator = ReportEstimator{
+			Samples:      n,
+			MeanAbsError: absSum / float64(n),
+			Bias:         errSum / float64(n),
+		}
+		dist := percentiles(ratios)
+		report.Estimator.RatioP50, report.Estimator.RatioP90 = dist.P50, dist.P90
+	}
+
+	report.Recommendation = recommendWorkers(report, sizing)
+	return report
+}
+
+// concurrency sweeps job run intervals (ending at each job's finish) to find
+// peak and time-weighted CPU demand
+func concurrency(jobs []HistoryEntry, from, to time.Time) ReportConcurrency {
+	type edge struct {
+		at   time.Time
+		jobs int
+		cpu  float64
+	}
+	edges := make([]edge, 0, 2*len(jobs))
+	for _, job := range jobs {
+		start := job.At.Add(-time.Duration(job.RunTime * float64(time.Second)))
+		if start.Before(from) {
+			start = from
+		}
+		edges = append(edges, edge{start, 1, job.EstimatedCPU}, edge{job.At, -1, -job.EstimatedCPU})
+	}
+	// Ends sort before starts at the same instant so back-to-back jobs don't overlap
+	sort.Slice(edges, func(i, j int) bool {
+		if !edges[i].at.Equal(edges[j].at) {
+			return edges[i].at.Before(edges[j].at)
+		}
+		return edges[i].jobs < edges[j].jobs
+	})
+
+	type level struct {
+		cpu    float64
+		weight float64
+	}
+	var (
+		result         ReportConcurrency
+		levels         []level
+		running        int
+		cpu            float64
+		busy, cpuTotal float64
+	)
+	for i, e := range edges {
+		running += e.jobs
+		cpu = max(cpu+e.cpu, 0)
+		if running > result.PeakJobs {
+			result.PeakJobs, result.PeakJobsAt = running, e.at
+		}
+		result.PeakCPU = max(result.PeakCPU, cpu)
+
+		if i+1 < len(edges) && running > 0 {
+			dt := edges[i+1].at.Sub(e.at).Seconds()
+			if dt > 0 {
+				levels = append(levels, level{cpu, dt})
+				busy += dt
+				cpuTotal += cpu * dt
+			}
+		}
+	}
+
+	if period := to.Sub(from).Seconds(); period > 0 {
+		result.MeanCPU = cpuTotal / period
+		result.BusyFraction = min(busy/period, 1)
+	}
+	if busy > 0 {
+		slices.SortFunc(levels, func(a, b level) int { return cmp.Compare(a.cpu, b.cpu) })
+		target, acc := 0.95*busy, 0.0
+		for _, l := range levels {
+			acc += l.weight
+			if acc >= target {
+				result.P95CPU = l.cpu
+				break
+			}
+		}
+	}
+	return result
+}
+
+// percentiles summarizes samples (all zero when empty)
+func percentiles(samples []float64) ReportPercentiles {
+	if len(samples) == 0 {
+		return ReportPercentiles{}
+	}
+	sorted := slices.Clone(samples)
+	slices.Sort(sorted)
+	at := func(q float64) float64 {
+		i := int(math.Ceil(q*float64(len(sorted)))) - 1
+		return sorted[min(max(i, 0), len(sorted)-1)]
+	}
+	return ReportPercentiles{
+		Samples: len(sorted),
+		P50:     at(0.5),
+		P90:     at(0.9),
+		P99:     at(0.99),
+		Max:     sorted[len(sorted)-1],
+	}
+}
+
+// recommendWorkers sizes the pool for the 95th percentile of busy-time CPU demand,
+// adding a worker when queue waits are long
+func recommendWorkers(report CapacityReport, sizing capacitySizing) CapacityRecommendation {
+	rec := CapacityRecommendation{InitialWorkers: sizing.initialWorkers, Notes: []string{}}
+	workersFor := func(cpu float64) int {
+		return min(max(int(math.Ceil(cpu/sizing.threshold)), 1), sizing.maxWorkers)
+	}
+
+	if report.Concurrency.PeakJobs == 0 {
+		rec.Workers = max(sizing.initialWorkers, 1)
+		rec.PeakWorkers = rec.Workers
+		rec.Notes = append(rec.Notes, "No jobs ran in the period; keeping the configured worker count")
+		return rec
+	}
+
+	rec.Workers = workersFor(report.Concurrency.P95CPU)
+	rec.PeakWorkers = workersFor(report.Concurrency.PeakCPU)
+	rec.Notes = append(rec.Notes, fmt.Sprintf("95%% of busy time needed at most %.0f%% CPU, %d worker(s) at %.0f%% each",
+		report.Concurrency.P95CPU, rec.Workers, sizing.threshold))
+
+	if report.QueueWait.P90 > reportQueueWaitConcern {
+		if rec.Workers < sizing.maxWorkers {
+			rec.Workers++
+			rec.Notes = append(rec.Notes, fmt.Sprintf("p90 queue wait is %.1fs; one more worker added for headroom", report.QueueWait.P90))
+		} else {
+			rec.Notes = append(rec.Notes, fmt.Sprintf("p90 queue wait is %.1fs with every core in use; this node is at capacity", report.QueueWait.P90))
+		}
+	}
+	if rec.PeakWorkers > rec.Workers {
+		rec.Notes = append(rec.Notes, fmt.Sprintf("Peaks needed %d worker(s); the scheduler spawns the extra on demand", rec.PeakWorkers))
+	}
+	if report.Spawns.PerDay > reportSpawnChurn && rec.Workers > sizing.initialWorkers {
+		rec.Notes = append(rec.Notes, fmt.Sprintf("%.1f spawns/day: set INITIAL_WORKERS=%d to keep workers warm", report.Spawns.PerDay, rec.Workers))
+	}
+	if sizing.initialWorkers > rec.PeakWorkers {
+		rec.Notes = append(rec.Notes, fmt.Sprintf("INITIAL_WORKERS=%d keeps more workers than even the peak needed", sizing.initialWorkers))
+	}
+	if report.Estimator.Samples > 0 && report.Estimator.RatioP50 > 1.5 {
+		rec.Notes = append(rec.Notes, fmt.Sprintf("Jobs run %.1fx longer than estimated (median); demand may be understated", report.Estimator.RatioP50))
+	}
+	return rec
+}
+
+// CapacityReport analyzes the last days of job history
+func (s *Server) CapacityReport(days int) (CapacityReport, error) {
+	to := time.Now()
+	from := to.Add(-time.Duration(days) * 24 * time.Hour)
+
+	entries, err := s.history.Since(from)
+	if err != nil {
+		return CapacityReport{}, err
+	}
+	cfg := s.scheduler.config
+	return buildCapacityReport(entries, from, to, capacitySizing{
+		threshold:      cfg.MaxCPUThreshold,
+		initialWorkers: cfg.InitialWorkers,
+		maxWorkers:     len(coreMaps),
+	}), nil
+}
+
+// reportDays reads the ?days= of history a report covers (fallback if absent),
+// answering 400 if it is out of range
+func (s *Server) reportDays(w http.ResponseWriter, r *http.Request, fallback int) (int, bool) {
+	raw := r.URL.Query().Get("days")
+	if raw == "" {
+		return min(max(fallback, 1), s.history.RetentionDays()), true
+	}
+	days, err := strconv.Atoi(raw)
+	if err != nil || days < 1 || days > s.history.RetentionDays() {
+		http.Error(w, fmt.Sprintf("days must be between 1 and %d", s.history.RetentionDays()), http.StatusBadRequest)
+		return 0, false
+	}
+	return days, true
+}
+
+// handleCapacityReport serves a capacity report: GET /admin/report?days=7
+func (s *Server) handleCapacityReport(w http.ResponseWriter, r *http.Request) {
+	days, ok := s.reportDays(w, r, defaultReportDays)
+	if !ok {
+		return
+	}
+
+	report, err := s.CapacityReport(days)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
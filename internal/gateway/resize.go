@@ -0,0 +1,254 @@
+package gateway
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/docker/docker/api/types/container"
+)
+
+// cpuPeriod is the CFS period CPU quotas are expressed against (microseconds)
+const cpuPeriod = 100000
+
+// WorkerResources is the CPU a core's worker container may use
+type WorkerResources struct {
+	Cpuset string  `json:"cpuset"`         // Logical CPUs, e.g. "1,5" or "1-2,5"
+	CPUs   float64 `json:"cpus,omitempty"` // CFS quota in CPUs (0 = none)
+}
+
+// effectiveCPUs is how many CPUs' worth of time the resources allow
+func (r WorkerResources) effectiveCPUs() float64 {
+	cpus, _ := parseCpuset(r.Cpuset)
+	n := float64(len(cpus))
+	if r.CPUs > 0 {
+		return min(n, r.CPUs)
+	}
+	return n
+}
+
+// defaultResources is what a core's worker gets without a resize
+func defaultResources(coreID int) WorkerResources {
+	return WorkerResources{Cpuset: coreMaps[coreID]}
+}
+
+// capacityOf is a core's capacity relative to a standard worker (1 = its default cpuset)
+func capacityOf(coreID int, r WorkerResources) float64 {
+	standard := defaultResources(coreID).effectiveCPUs()
+	if standard == 0 {
+		return 1
+	}
+	return r.effectiveCPUs() / standard
+}
+
+// parseCpuset expands a cpuset list ("1,5", "1-3,7") into sorted CPU numbers
+func parseCpuset(cpuset string) ([]int, error) {
+	seen := make(map[int]bool)
+	for _, part := range strings.Split(cpuset, ",") {
+		part = strings.TrimSpace(part)
+		lo, hi, isRange := strings.Cut(part, "-")
+		first, err := strconv.Atoi(lo)
+		if err != nil || first < 0 {
+			return nil, fmt.Errorf("invalid cpuset %q", cpuset)
+		}
+		last := first
+		if isRange {
+			if last, err = strconv.Atoi(hi); err != nil || last < first {
+				return nil, fmt.Errorf("invalid cpuset %q", cpuset)
+			}
+		}
+		for cpu := first; cpu <= last; cpu++ {
+			seen[cpu] = true
+		}
+	}
+	cpus := make([]int, 0, len(seen))
+	for cpu := range seen {
+		cpus = append(cpus, cpu)
+	}
+	sort.Ints(cpus)
+	return cpus, nil
+}
+
+// capacity is the worker's CPU relative to a standard worker
+func (w *WorkerInfo) capacity() float64 {
+	if w.Capacity <= 0 {
+		return 1
+	}
+	return w.Capacity
+}
+
+// effectiveCapacity is the worker's capacity scaled by its health score
+func (w *WorkerInfo) effectiveCapacity() float64 {
+	return w.capacity() * w.Health.Score
+}
+
+// load is the worker's CPU use relative to its effective capacity
+func (w *WorkerInfo) load() float64 {
+	if w.Health.Score <= 0 {
+		return math.Inf(1)
+	}
+	return w.CurrentCPU / w.effectiveCapacity()
+}
+
+// fits reports whether estimatedCPU more keeps the worker within threshold of its
+// effective capacity
+func (w *WorkerInfo) fits(estimatedCPU, threshold float64) bool {
+	return w.Health.Score > 0 && w.CurrentCPU+estimatedCPU <= threshold*w.effectiveCapacity()
+}
+
+// coreResourcesLocked is what a worker on coreID runs with (caller holds o.mu)
+func (o *Orchestrator) coreResourcesLocked(coreID int) WorkerResources {
+	if r, exists := o.resources[coreID]; exists {
+		return r
+	}
+	return defaultResources(coreID)
+}
+
+// containerResources converts worker resources to Docker's. An update with no
+// quota lifts any previous one.
+func (r WorkerResources) containerResources() container.Resources {
+	res := container.Resources{CpusetCpus: r.Cpuset}
+	if r.CPUs > 0 {
+		res.CPUPeriod = cpuPeriod
+		res.CPUQuota = int64(math.Round(r.CPUs * cpuPeriod))
+	}
+	return res
+}
+
+// ResizeWorker changes the CPUs of a core's running worker in place and keeps
+// them for workers later spawned there. Returns the previous resources.
+func (o *Orchestrator) ResizeWorker(coreID int, r WorkerResources) (WorkerResources, error) {
+	if _, validCore := coreMaps[coreID]; !validCore {
+		return WorkerResources{}, fmt.Errorf("invalid core ID: %d (valid: 1, 2, 3)", coreID)
+	}
+	if r.Cpuset == "" {
+		r.Cpuset = coreMaps[coreID]
+	}
+	cpus, err := parseCpuset(r.Cpuset)
+	if err != nil {
+		return WorkerResources{}, err
+	}
+	if r.CPUs < 0 || math.IsNaN(r.CPUs) || math.IsInf(r.CPUs, 0) {
+		return WorkerResources{}, fmt.Errorf("cpus must be a non-negative number")
+	}
+	if r.CPUs > 0 && r.CPUs < 0.01 {
+		return WorkerResources{}, fmt.Errorf("cpus must be at least 0.01")
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if cpu := o.gatewayCPULocked(cpus); cpu >= 0 {
+		return WorkerResources{}, fmt.Errorf("CPU %d is reserved for the gateway (GATEWAY_CPUSET=%s)", cpu, o.pinning.CPUs)
+	}
+	old := o.coreResourcesLocked(coreID)
+	if worker, exists := o.workers[coreID]; exists {
+		update := container.UpdateConfig{Resources: r.containerResources()}
+		if r.CPUs == 0 && old.CPUs > 0 {
+			update.CPUQuota = -1 // Unlimited
+		}
+		if _, err := o.cli.ContainerUpdate(o.ctx, worker.ContainerID, update); err != nil {
+			return old, fmt.Errorf("failed to update container %s: %w", worker.ContainerID[:12], err)
+		}
+		worker.Capacity = capacityOf(coreID, r)
+	}
+
+	if r == defaultResources(coreID) {
+		delete(o.resources, coreID)
+	} else {
+		o.resources[coreID] = r
+	}
+
+	for other, cpuset := range coreMaps {
+		if other == coreID {
+			continue
+		}
+		theirs, _ := parseCpuset(o.coreResourcesLocked(other).Cpuset)
+		for _, cpu := range cpus {
+			if slices.Contains(theirs, cpu) {
+				log.Printf("[WARNING] Core %d's cpuset %s shares CPU %d with core %d (%s)", coreID, r.Cpuset, cpu, other, cpuset)
+			}
+		}
+	}
+	return old, nil
+}
+
+// CoreResources reports the resources of every core
+func (o *Orchestrator) CoreResources() map[int]WorkerResources {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+
+	resources := make(map[int]WorkerResources, len(coreMaps))
+	for coreID := range coreMaps {
+		resources[coreID] = o.coreResourcesLocked(coreID)
+	}
+	return resources
+}
+
+// TotalCapacity is the cluster's capacity in standard workers, counting every core
+func (o *Orchestrator) TotalCapacity() float64 {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+
+	total := 0.0
+	for coreID := range coreMaps {
+		total += capacityOf(coreID, o.coreResourcesLocked(coreID))
+	}
+	return total
+}
+
+// ResizeWorker changes a core's CPUs and the scheduler's capacity for it together:
+// nothing is placed between the container update and the capacity change
+func (s *Scheduler) ResizeWorker(coreID int, r WorkerResources) (WorkerResources, error) {
+	s.scheduleMux.Lock()
+	defer s.scheduleMux.Unlock()
+
+	old, err := s.orchestrator.ResizeWorker(coreID, r)
+	if err != nil {
+		return old, err
+	}
+	s.queues.setCapacity(s.orchestrator.TotalCapacity() * s.config.MaxCPUThreshold)
+	s.wakeQueue()
+	return old, nil
+}
+
+// handleResizeWorker changes a core's cpuset and CPU quota live:
+// {"cpuset": "1,5,4"} or {"cpus": 1.5}. An empty body restores the defaults.
+func (s *Server) handleResizeWorker(w http.ResponseWriter, r *http.Request) {
+	coreID, err := strconv.Atoi(r.PathValue("core"))
+	if _, exists := coreMaps[coreID]; err != nil || !exists {
+		http.Error(w, "Unknown core", http.StatusNotFound)
+		return
+	}
+	var body WorkerResources
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, fmt.Sprintf("Invalid JSON: %v", err), http.StatusBadRequest)
+			return
+		}
+	}
+
+	old, err := s.scheduler.ResizeWorker(coreID, body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	current := s.scheduler.orchestrator.CoreResources()[coreID]
+	log.Printf("[Audit] Core %d resized: cpuset %s -> %s, cpus %g -> %g (capacity %.2fx)",
+		coreID, old.Cpuset, current.Cpuset, old.CPUs, current.CPUs, capacityOf(coreID, current))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"core_id":   coreID,
+		"previous":  old,
+		"resources": current,
+		"capacity":  capacityOf(coreID, current),
+		"default":   current == defaultResources(coreID),
+	})
+}
@@ -0,0 +1,143 @@
+package gateway
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"time"
+
+	"github.com/ahmadhassan44/container-orchestrator/pkg/protocol"
+)
+
+// defaultRetryBackoff is the first retry delay when a policy doesn't set one
+const defaultRetryBackoff = 1.0
+
+// defaultRetryOn is what a policy retries when it doesn't list failure kinds:
+// failures that say nothing about the job itself
+var defaultRetryOn = []string{protocol.FailureWorkerExit, protocol.FailureConnection}
+
+// jobFailure tags an attempt's error with a protocol failure kind so retry
+// policies can match it
+type jobFailure struct {
+	kind string
+	err  error
+}
+
+func (f *jobFailure) Error() string { return f.err.Error() }
+func (f *jobFailure) Unwrap() error { return f.err }
+
+func failure(kind string, err error) error {
+	return &jobFailure{kind: kind, err: err}
+}
+
+// failureKind returns the kind err was tagged with ("" if untagged, e.g. cancellation)
+func failureKind(err error) string {
+	var f *jobFailure
+	if errors.As(err, &f) {
+		return f.kind
+	}
+	return ""
+}
+
+// validateRetryPolicy rejects malformed policies; limits are enforced later by capping
+func validateRetryPolicy(policy *protocol.RetryPolicy) error {
+	if policy == nil {
+		return nil
+	}
+	if policy.MaxAttempts < 1 {
+		return fmt.Errorf("retry.max_attempts must be at least 1")
+	}
+	if policy.Backoff < 0 {
+		return fmt.Errorf("retry.backoff must be non-negative")
+	}
+	for _, kind := range policy.RetryOn {
+		if !slices.Contains(protocol.FailureKinds, kind) {
+			return fmt.Errorf("unknown retry_on kind %q (valid: %v)", kind, protocol.FailureKinds)
+		}
+	}
+	return nil
+}
+
+// retryPolicy returns the request's policy with defaults filled in and capped at
+// RETRY_MAX_ATTEMPTS / RETRY_MAX_BACKOFF. No policy means a single attempt.
+func (s *Scheduler) retryPolicy(req *protocol.ComputeRequest) protocol.RetryPolicy {
+	if req.Retry == nil {
+		return protocol.RetryPolicy{MaxAttempts: 1}
+	}
+
+	policy := *req.Retry
+	policy.MaxAttempts = max(min(policy.MaxAttempts, s.config.RetryMaxAttempts), 1)
+	if policy.Backoff == 0 {
+		policy.Backoff = defaultRetryBackoff
+	}
+	policy.Backoff = min(policy.Backoff, s.config.RetryMaxBackoff)
+	if len(policy.RetryOn) == 0 {
+		policy.RetryOn = defaultRetryOn
+	}
+	return policy
+}
+
+// retryDelay is the wait before the given retry (1 = first retry), doubling each time
+func (s *Scheduler) retryDelay(policy protocol.RetryPolicy, retry int) time.Duration {
+	seconds := min(policy.Backoff*float64(int(1)<<(retry-1)), s.config.RetryMaxBackoff)
+	return time.Duration(seconds * float64(time.Second))
+}
+
+// waitRetry sleeps before a retry, returning false if the job is cancelled meanwhile
+func (s *Scheduler) waitRetry(jobID string, delay time.Duration) bool {
+	cancelled := make(chan struct{})
+	s.runningMu.Lock()
+	s.retrying[jobID] = cancelled
+	s.runningMu.Unlock()
+
+	defer func() {
+		s.runningMu.Lock()
+		delete(s.retrying, jobID)
+		s.runningMu.Unlock()
+	}()
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-cancelled:
+		return false
+	}
+}
+
+// scheduleWithRetries runs attempt until it succeeds or the request's retry policy
+// gives up on the failure
+func (s *Scheduler) scheduleWithRetries(req *protocol.ComputeRequest, attempt func() (*protocol.JobResponse, error)) (*protocol.JobResponse, error) {
+	policy := s.retryPolicy(req)
+
+	for n := 1; ; n++ {
+		response, err := attempt()
+		s.annotate(req.JobID, func(a *protocol.JobAnnotations) { a.Attempts = n })
+		if err == nil {
+			if n > 1 {
+				response.Attempts = n
+			}
+			return response, nil
+		}
+
+		kind := failureKind(err)
+		if n >= policy.MaxAttempts || !slices.Contains(policy.RetryOn, kind) {
+			if n > 1 {
+				return nil, fmt.Errorf("failed after %d attempts: %w", n, err)
+			}
+			return nil, err
+		}
+
+		delay := s.retryDelay(policy, n)
+		log.Printf("[Scheduler] Job %s attempt %d/%d failed (%s), retrying in %s: %v",
+			req.JobID, n, policy.MaxAttempts, kind, delay, err)
+		s.trace(req, traceRetry, nil, "attempt %d/%d failed (%s), retrying in %s: %v", n, policy.MaxAttempts, kind, delay, err)
+		s.notifyStatus(req.JobID, protocol.StatusQueued)
+		if !s.waitRetry(req.JobID, delay) {
+			return nil, ErrJobCancelled
+		}
+	}
+}
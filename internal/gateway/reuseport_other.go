@@ -0,0 +1,13 @@
+//go:build !linux && !darwin && !dragonfly && !freebsd && !netbsd && !openbsd
+
+package gateway
+
+import (
+	"syscall"
+)
+
+// reusePort is a no-op without SO_REUSEPORT: a reload can only move the listener
+// to another port
+func reusePort(network, address string, c syscall.RawConn) error {
+	return nil
+}
@@ -0,0 +1,307 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/ahmadhassan44/container-orchestrator/pkg/protocol"
+)
+
+// RunningJob describes a job currently executing on a worker
+type RunningJob struct {
+	JobID            string    `json:"job_id"`
+	CoreID           int       `json:"core_id"`
+	Queue            string    `json:"queue,omitempty"`
+	StartedAt        time.Time `json:"started_at"`
+	RemainingSeconds float64   `json:"remaining_seconds"` // Estimated from load_time and checkpoints
+}
+
+// ActiveJob is the operator's view of a job executing on a worker right now
+type ActiveJob struct {
+	JobID            string    `json:"job_id"`
+	Operation        string    `json:"operation"`
+	Queue            string    `json:"queue,omitempty"`
+	CoreID           int       `json:"core_id"`
+	WorkerID         string    `json:"worker_id"`
+	WorkerUUID       string    `json:"worker_uuid,omitempty"`
+	StartedAt        time.Time `json:"started_at"`      // Start of the current run (slice or attempt)
+	ElapsedSeconds   float64   `json:"elapsed_seconds"` // Time spent in the current run
+	RemainingSeconds float64   `json:"remaining_seconds"`
+	RemainingRange   ETARange  `json:"remaining_range"`      // remaining_seconds as a p50/p90 range
+	Progress         float64   `json:"progress"`             // Estimated fraction of the job's work done (0-1)
+	Iterations       int64     `json:"iterations,omitempty"` // Latest count reported by a streamed job
+}
+
+// WaitingJob describes a job sitting in a queue
+type WaitingJob struct {
+	JobID          string  `json:"job_id"`
+	Operation      string  `json:"operation"`
+	Queue          string  `json:"queue"`
+	WaitingSeconds float64 `json:"waiting_seconds"`
+	EstimatedCPU   float64 `json:"estimated_cpu"`
+	WorkSeconds    float64 `json:"work_seconds"`       // Estimated seconds of work left (less any checkpointed progress)
+	Starving       bool    `json:"starving,omitempty"` // Waited past STARVATION_FACTOR x the queue's median
+
+	duration     float64 // Estimated run time, as placement checks maintenance windows against
+	tolerations  []string
+	noSMTSharing bool
+}
+
+// ErrJobCancelled is returned for jobs stopped through Scheduler.Cancel
+var ErrJobCancelled = errors.New("job cancelled")
+
+// runningEntry is the scheduler's bookkeeping for one executing request
+type runningEntry struct {
+	coreID     int
+	workerUUID string
+	operation  string
+	queue      string
+	startedAt  time.Time
+	workTotal  float64            // Estimated seconds of work for the whole job
+	workLeft   float64            // Seconds of work outstanding when this run started
+	iterations int64              // Target iteration count (0 = not a fixed-size job)
+	done       int64              // Iterations reported so far by streamed progress
+	cancel     context.CancelFunc // Aborts the request to the worker
+}
+
+// ProgressSink receives streamed progress events for a job
+type ProgressSink func(event protocol.StreamEvent)
+
+// StatusListener is notified when the scheduler moves a job between queued and running
+type StatusListener func(jobID string, status protocol.Status)
+
+// UsageListener is told the CPU-seconds a job consumed each time a run on a worker
+// ends (once per slice for sliced jobs, including failed and cancelled runs)
+type UsageListener func(jobID string, cpuSeconds float64)
+
+// SetStatusListener registers a callback for job status transitions
+func (s *Scheduler) SetStatusListener(fn StatusListener) {
+	s.runningMu.Lock()
+	defer s.runningMu.Unlock()
+	s.statusListener = fn
+}
+
+func (s *Scheduler) notifyStatus(jobID string, status protocol.Status) {
+	s.runningMu.Lock()
+	fn := s.statusListener
+	s.runningMu.Unlock()
+
+	if fn != nil && jobID != "" {
+		fn(jobID, status)
+	}
+}
+
+// SetUsageListener registers a callback for per-run CPU-seconds consumption
+func (s *Scheduler) SetUsageListener(fn UsageListener) {
+	s.runningMu.Lock()
+	defer s.runningMu.Unlock()
+	s.usageListener = fn
+}
+
+// SetProgressSink routes a job's streamed progress events to fn (nil removes it)
+func (s *Scheduler) SetProgressSink(jobID string, fn ProgressSink) {
+	s.runningMu.Lock()
+	defer s.runningMu.Unlock()
+
+	if fn == nil {
+		delete(s.progressSinks, jobID)
+		return
+	}
+	s.progressSinks[jobID] = fn
+}
+
+func (s *Scheduler) progressSink(jobID string) ProgressSink {
+	s.runningMu.Lock()
+	defer s.runningMu.Unlock()
+	return s.progressSinks[jobID]
+}
+
+// Cancel stops a queued or running job; its submitter receives ErrJobCancelled.
+// Returns false if the job is neither queued nor running.
+func (s *Scheduler) Cancel(jobID string) bool {
+	if ENABLE_JOB_QUEUE {
+		if job := s.queues.removeByID(jobID); job != nil {
+			log.Printf("[Scheduler] Cancelled queued job %s", jobID)
+			job.errorCh <- ErrJobCancelled
+			return true
+		}
+	}
+
+	s.runningMu.Lock()
+	entry, running := s.running[jobID]
+	backoff, retrying := s.retrying[jobID]
+	if retrying {
+		delete(s.retrying, jobID)
+	}
+	s.runningMu.Unlock()
+
+	if retrying {
+		log.Printf("[Scheduler] Cancelled job %s while waiting to retry", jobID)
+		close(backoff)
+		return true
+	}
+	if !running {
+		return false
+	}
+
+	log.Printf("[Scheduler] Cancelling running job %s on Worker-Core-%d", jobID, entry.coreID)
+	entry.cancel()
+	return true
+}
+
+// CancelUnstarted cancels a job that hasn't reached a worker: it is removed from its
+// queue or retry backoff, or, while it is still being placed (e.g. waiting for a
+// worker to spawn), cancelled the moment it is dispatched. Running jobs are left alone.
+func (s *Scheduler) CancelUnstarted(jobID string) {
+	s.runningMu.Lock()
+	_, running := s.running[jobID]
+	if !running {
+		s.cancelOnStart[jobID] = true
+	}
+	s.runningMu.Unlock()
+
+	if !running {
+		s.Cancel(jobID)
+	}
+}
+
+// trackStart records that req has started executing on worker
+func (s *Scheduler) trackStart(worker *WorkerInfo, req *protocol.ComputeRequest, cancel context.CancelFunc) {
+	workTotal := s.estimator.EstimateJobDuration(req)
+	workLeft := workTotal
+	if req.Checkpoint != nil {
+		workLeft -= req.Checkpoint.Elapsed
+	}
+	s.runningMu.Lock()
+	s.running[req.JobID] = &runningEntry{
+		coreID:     worker.CoreID,
+		workerUUID: worker.UUID,
+		operation:  operationName(req),
+		queue:      req.Queue,
+		startedAt:  time.Now(),
+		workTotal:  workTotal,
+		workLeft:   workLeft,
+		iterations: req.Iterations,
+		cancel:     cancel,
+	}
+	s.scaling.touch(worker.CoreID)
+	cancelled := s.cancelOnStart[req.JobID]
+	delete(s.cancelOnStart, req.JobID)
+	s.runningMu.Unlock()
+
+	if s.interference.Isolated(operationName(req), req.NoSMTSharing) {
+		s.interference.Claim(req.JobID, worker.CoreID) // Also covers jobs placed on a freshly spawned worker
+	}
+	s.interference.Start(req.JobID, worker.CoreID, operationName(req), s.orchestrator.CoreResources()[worker.CoreID].Cpuset)
+
+	if cancelled {
+		log.Printf("[Scheduler] Job %s was cancelled before dispatch", req.JobID)
+		cancel()
+	}
+
+	s.notifyStatus(req.JobID, protocol.StatusInProgress)
+}
+
+// trackFinish forgets a request once its worker call returns and reports the
+// CPU-seconds it used: its estimated CPU share over the time it actually ran
+func (s *Scheduler) trackFinish(req *protocol.ComputeRequest) {
+	s.runningMu.Lock()
+	entry, exists := s.running[req.JobID]
+	delete(s.running, req.JobID)
+	fn := s.usageListener
+	s.runningMu.Unlock()
+	s.interference.Finish(req.JobID)
+
+	if !exists {
+		return
+	}
+	s.scaling.touch(entry.coreID)
+	ran := time.Since(entry.startedAt).Seconds()
+	s.annotate(req.JobID, func(a *protocol.JobAnnotations) { a.RunTime += ran })
+	if fn != nil && req.JobID != "" {
+		fn(req.JobID, s.estimator.EstimateCPUUsage(req)/100*ran)
+	}
+}
+
+// RunningJobs returns the jobs currently executing, oldest first
+func (s *Scheduler) RunningJobs() []RunningJob {
+	s.runningMu.Lock()
+	defer s.runningMu.Unlock()
+
+	jobs := make([]RunningJob, 0, len(s.running))
+	for jobID, entry := range s.running {
+		remaining := entry.workLeft - time.Since(entry.startedAt).Seconds()
+		jobs = append(jobs, RunningJob{
+			JobID:            jobID,
+			CoreID:           entry.coreID,
+			Queue:            entry.queue,
+			StartedAt:        entry.startedAt,
+			RemainingSeconds: max(remaining, 0),
+		})
+	}
+
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].StartedAt.Before(jobs[j].StartedAt) })
+	return jobs
+}
+
+// trackProgress records the iteration count from a streamed progress event
+func (s *Scheduler) trackProgress(jobID string, iterations int64) {
+	s.runningMu.Lock()
+	defer s.runningMu.Unlock()
+
+	if entry, exists := s.running[jobID]; exists && iterations > entry.done {
+		entry.done = iterations
+	}
+}
+
+// ActiveJobs returns the jobs currently executing with their elapsed time and
+// estimated progress, oldest first. Progress comes from streamed iteration counts
+// for fixed-size jobs and from the duration estimate otherwise.
+func (s *Scheduler) ActiveJobs() []ActiveJob {
+	s.runningMu.Lock()
+	defer s.runningMu.Unlock()
+
+	jobs := make([]ActiveJob, 0, len(s.running))
+	for jobID, entry := range s.running {
+		elapsed := time.Since(entry.startedAt).Seconds()
+		remaining := max(entry.workLeft-elapsed, 0)
+
+		var progress float64
+		switch {
+		case entry.iterations > 0 && entry.done > 0:
+			progress = float64(entry.done) / float64(entry.iterations)
+		case entry.workTotal > 0:
+			progress = (entry.workTotal - remaining) / entry.workTotal
+		}
+
+		jobs = append(jobs, ActiveJob{
+			JobID:            jobID,
+			Operation:        entry.operation,
+			Queue:            entry.queue,
+			CoreID:           entry.coreID,
+			WorkerID:         fmt.Sprintf("Worker-Core-%d", entry.coreID),
+			WorkerUUID:       entry.workerUUID,
+			StartedAt:        entry.startedAt,
+			ElapsedSeconds:   elapsed,
+			RemainingSeconds: remaining,
+			RemainingRange:   s.eta.runRange(entry.operation, remaining),
+			Progress:         min(max(progress, 0), 1),
+			Iterations:       entry.done,
+		})
+	}
+
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].StartedAt.Before(jobs[j].StartedAt) })
+	return jobs
+}
+
+// WaitingJobs returns the jobs sitting in queues
+func (s *Scheduler) WaitingJobs() []WaitingJob {
+	if !ENABLE_JOB_QUEUE {
+		return []WaitingJob{}
+	}
+	return s.queues.waiting()
+}
@@ -0,0 +1,429 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/api/types/system"
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/docker/go-connections/nat"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+
+	"github.com/ahmadhassan44/container-orchestrator/internal/worker"
+)
+
+// fakeContainer is a "container" backed by an in-process worker HTTP server
+type fakeContainer struct {
+	config     *container.Config
+	hostConfig *container.HostConfig
+	handler    *worker.WorkerHandler
+	server     *http.Server
+	stop       context.CancelFunc // Ends the worker's heartbeat loop
+	hostPort   string             // Port actually bound, reported by ContainerInspect
+
+	// Exit state once the worker server has been stopped or killed
+	exited     bool
+	exitCode   int
+	finishedAt time.Time
+	logs       []string // Lifecycle lines served by ContainerLogs
+}
+
+// FakeRuntime implements ContainerRuntime without Docker by serving the worker
+// handler in-process on the container's published host port. Used for local
+// development (RUNTIME=fake) and the integration test suite.
+type FakeRuntime struct {
+	mu         sync.Mutex
+	containers map[string]*fakeContainer
+	images     map[string]image.Summary
+}
+
+// fakeImageID is the ID of the built-in worker image
+const fakeImageID = "sha256:fake"
+
+// fakeKilledExitCode is what Docker reports for a SIGKILLed process (128 + 9)
+const fakeKilledExitCode = 137
+
+func NewFakeRuntime() *FakeRuntime {
+	return &FakeRuntime{
+		containers: make(map[string]*fakeContainer),
+		images: map[string]image.Summary{
+			fakeImageID: {
+				ID:       fakeImageID,
+				RepoTags: []string{defaultWorkerImage},
+				Labels:   map[string]string{imageRoleLabel: imageRoleWorker},
+				Created:  time.Now().Unix(),
+			},
+		},
+	}
+}
+
+// Info reports a synthetic daemon description
+func (f *FakeRuntime) Info(ctx context.Context) (system.Info, error) {
+	return system.Info{Name: "fake-runtime", NCPU: runtime.NumCPU(), Architecture: runtime.GOARCH}, nil
+}
+
+// Ping always succeeds; the fake daemon is in-process
+func (f *FakeRuntime) Ping(ctx context.Context) (types.Ping, error) {
+	return types.Ping{APIVersion: "fake"}, nil
+}
+
+// ImageInspectWithRaw reports every image as present (workers are built in)
+func (f *FakeRuntime) ImageInspectWithRaw(ctx context.Context, imageID string) (types.ImageInspect, []byte, error) {
+	return types.ImageInspect{ID: "sha256:fake", RepoTags: []string{imageID}, Os: "linux", Architecture: runtime.GOARCH}, nil, nil
+}
+
+// ImagePull succeeds immediately; every image is already "present"
+func (f *FakeRuntime) ImagePull(ctx context.Context, refStr string, options image.PullOptions) (io.ReadCloser, error) {
+	return io.NopCloser(strings.NewReader("")), nil
+}
+
+// ImageList returns the fake image store (filters are ignored)
+func (f *FakeRuntime) ImageList(ctx context.Context, options image.ListOptions) ([]image.Summary, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	images := make([]image.Summary, 0, len(f.images))
+	for _, img := range f.images {
+		images = append(images, img)
+	}
+	return images, nil
+}
+
+// ImageRemove deletes an image from the fake store
+func (f *FakeRuntime) ImageRemove(ctx context.Context, imageID string, options image.RemoveOptions) ([]image.DeleteResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, exists := f.images[imageID]; !exists {
+		return nil, fmt.Errorf("no such image: %s", imageID)
+	}
+	delete(f.images, imageID)
+	return []image.DeleteResponse{{Deleted: imageID}}, nil
+}
+
+// NetworkInspect reports a loopback gateway for every network: fake workers run
+// in-process, so the gateway is reachable from them on localhost
+func (f *FakeRuntime) NetworkInspect(ctx context.Context, networkID string, options types.NetworkInspectOptions) (types.NetworkResource, error) {
+	return types.NetworkResource{
+		Name:   networkID,
+		Driver: "bridge",
+		IPAM:   network.IPAM{Config: []network.IPAMConfig{{Subnet: "127.0.0.0/8", Gateway: "127.0.0.1"}}},
+	}, nil
+}
+
+// ContainerCreate records the container configuration without starting anything
+func (f *FakeRuntime) ContainerCreate(ctx context.Context, config *container.Config, hostConfig *container.HostConfig,
+	networkingConfig *network.NetworkingConfig, platform *ocispec.Platform, containerName string) (container.CreateResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	id, err := newFakeContainerID()
+	if err != nil {
+		return container.CreateResponse{}, err
+	}
+
+	f.containers[id] = &fakeContainer{config: config, hostConfig: hostConfig}
+	return container.CreateResponse{ID: id}, nil
+}
+
+// ContainerInspect reports the container's state and the host port it bound
+func (f *FakeRuntime) ContainerInspect(ctx context.Context, containerID string) (types.ContainerJSON, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	c, exists := f.containers[containerID]
+	if !exists {
+		return types.ContainerJSON{}, fmt.Errorf("no such container: %s", containerID)
+	}
+
+	settings := &types.NetworkSettings{}
+	if c.server != nil {
+		settings.Ports = nat.PortMap{"8080/tcp": []nat.PortBinding{{HostIP: "0.0.0.0", HostPort: c.hostPort}}}
+	}
+	state := &types.ContainerState{Status: "created", Running: c.server != nil}
+	switch {
+	case c.server != nil:
+		state.Status = "running"
+	case c.exited:
+		state.Status = "exited"
+		state.ExitCode = c.exitCode
+		state.FinishedAt = c.finishedAt.Format(time.RFC3339Nano)
+	}
+	return types.ContainerJSON{
+		ContainerJSONBase: &types.ContainerJSONBase{
+			ID:    containerID,
+			Image: fakeImageID,
+			State: state,
+		},
+		Config:          c.config,
+		NetworkSettings: settings,
+	}, nil
+}
+
+// ContainerLogs serves the container's lifecycle lines in Docker's multiplexed stdout format
+func (f *FakeRuntime) ContainerLogs(ctx context.Context, containerID string, options container.LogsOptions) (io.ReadCloser, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	c, exists := f.containers[containerID]
+	if !exists {
+		return nil, fmt.Errorf("no such container: %s", containerID)
+	}
+
+	lines := c.logs
+	if tail, err := strconv.Atoi(options.Tail); err == nil && tail < len(lines) {
+		lines = lines[len(lines)-tail:]
+	}
+
+	var buf bytes.Buffer
+	stdout := stdcopy.NewStdWriter(&buf, stdcopy.Stdout)
+	for _, line := range lines {
+		fmt.Fprintln(stdout, line)
+	}
+	return io.NopCloser(&buf), nil
+}
+
+// ContainerList reports every fake container as running the built-in worker image
+func (f *FakeRuntime) ContainerList(ctx context.Context, options container.ListOptions) ([]types.Container, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	containers := make([]types.Container, 0, len(f.containers))
+	for id, c := range f.containers {
+		state := "created"
+		if c.server != nil {
+			state = "running"
+		} else if c.exited {
+			state = "exited"
+		}
+		containers = append(containers, types.Container{ID: id, Image: c.config.Image, ImageID: fakeImageID, State: state, Labels: c.config.Labels})
+	}
+	return containers, nil
+}
+
+// ContainerStats returns a single synthetic sample in Docker's stats format. The
+// in-process worker shares the gateway's resources, so only identity, timing and
+// the CPU count from the container's cpuset are meaningful.
+func (f *FakeRuntime) ContainerStats(ctx context.Context, containerID string, stream bool) (types.ContainerStats, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	c, exists := f.containers[containerID]
+	if !exists {
+		return types.ContainerStats{}, fmt.Errorf("no such container: %s", containerID)
+	}
+
+	now := time.Now()
+	cpus := uint32(len(strings.Split(c.hostConfig.CpusetCpus, ",")))
+	sample := types.StatsJSON{
+		Stats: types.Stats{
+			Read:      now,
+			PreRead:   now.Add(-time.Second),
+			PidsStats: types.PidsStats{Current: 1},
+			CPUStats:  types.CPUStats{OnlineCPUs: cpus},
+		},
+		Name: "/" + containerID[:12],
+		ID:   containerID,
+	}
+	if c.server == nil {
+		sample.PidsStats.Current = 0
+	}
+
+	body, err := json.Marshal(sample)
+	if err != nil {
+		return types.ContainerStats{}, err
+	}
+	return types.ContainerStats{Body: io.NopCloser(bytes.NewReader(body)), OSType: "linux"}, nil
+}
+
+// ContainerStart binds the published port and serves the worker API on it
+func (f *FakeRuntime) ContainerStart(ctx context.Context, containerID string, options container.StartOptions) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	c, exists := f.containers[containerID]
+	if !exists {
+		return fmt.Errorf("no such container: %s", containerID)
+	}
+	if c.server != nil {
+		return nil
+	}
+
+	bindings := c.hostConfig.PortBindings["8080/tcp"]
+	if len(bindings) == 0 {
+		return fmt.Errorf("container %s has no port binding for 8080/tcp", containerID[:12])
+	}
+
+	listener, err := net.Listen("tcp", fmt.Sprintf("%s:%s", bindings[0].HostIP, bindings[0].HostPort))
+	if err != nil {
+		return fmt.Errorf("port bind failed: %w", err)
+	}
+
+	c.hostPort = strconv.Itoa(listener.Addr().(*net.TCPAddr).Port)
+	c.exited = false
+	c.logs = append(c.logs, fmt.Sprintf("%s listening on port 8080...", fakeEnvValue(c.config.Env, "WORKER_ID")))
+	c.handler = &worker.WorkerHandler{
+		WorkerID:   fakeEnvValue(c.config.Env, "WORKER_ID"),
+		WorkerUUID: fakeEnvValue(c.config.Env, "WORKER_UUID"),
+		Threads:    2,
+	}
+	if seed, err := base64.StdEncoding.DecodeString(fakeEnvValue(c.config.Env, "RESULT_SIGNING_KEY")); err == nil && len(seed) == ed25519.SeedSize {
+		c.handler.SigningKey = ed25519.NewKeyFromSeed(seed)
+	}
+	c.server = &http.Server{Handler: c.handler.Routes()}
+
+	go func(srv *http.Server) {
+		if err := srv.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("[FakeRuntime] Worker server error: %v", err)
+		}
+	}(c.server)
+
+	if gatewayURL := fakeEnvValue(c.config.Env, "GATEWAY_INTERNAL_URL"); gatewayURL != "" {
+		heartbeatCtx, stop := context.WithCancel(context.Background())
+		c.stop = stop
+		go c.handler.RunHeartbeat(heartbeatCtx, gatewayURL, fakeEnvValue(c.config.Env, "INTERNAL_TOKEN"))
+	}
+
+	return nil
+}
+
+// ContainerUpdate records new CPU limits. The in-process worker keeps its thread
+// count, since it shares the gateway's resources anyway.
+func (f *FakeRuntime) ContainerUpdate(ctx context.Context, containerID string, updateConfig container.UpdateConfig) (container.ContainerUpdateOKBody, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	c, exists := f.containers[containerID]
+	if !exists {
+		return container.ContainerUpdateOKBody{}, fmt.Errorf("no such container: %s", containerID)
+	}
+	if updateConfig.CpusetCpus != "" {
+		c.hostConfig.CpusetCpus = updateConfig.CpusetCpus
+	}
+	if updateConfig.CPUQuota != 0 {
+		c.hostConfig.CPUQuota = max(updateConfig.CPUQuota, 0)
+	}
+	if updateConfig.CPUPeriod != 0 {
+		c.hostConfig.CPUPeriod = updateConfig.CPUPeriod
+	}
+	return container.ContainerUpdateOKBody{}, nil
+}
+
+// ContainerStop mimics SIGTERM: the worker drains in-flight jobs, then its server
+// shuts down, all within the stop timeout
+func (f *FakeRuntime) ContainerStop(ctx context.Context, containerID string, options container.StopOptions) error {
+	f.mu.Lock()
+	c, exists := f.containers[containerID]
+	var h *worker.WorkerHandler
+	if exists {
+		h = c.handler
+	}
+	f.mu.Unlock()
+
+	timeout := 10 * time.Second
+	if options.Timeout != nil {
+		timeout = time.Duration(*options.Timeout) * time.Second
+	}
+	stopCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	if h != nil {
+		if err := h.Drain(stopCtx); err != nil {
+			log.Printf("[FakeRuntime] %s", err)
+		}
+	}
+
+	srv, err := f.detachServer(containerID, 0)
+	if err != nil || srv == nil {
+		return err
+	}
+	if err := srv.Shutdown(stopCtx); err != nil {
+		return srv.Close()
+	}
+	return nil
+}
+
+// ContainerKill drops the worker server immediately, severing in-flight requests
+func (f *FakeRuntime) ContainerKill(ctx context.Context, containerID, signal string) error {
+	srv, err := f.detachServer(containerID, fakeKilledExitCode)
+	if err != nil || srv == nil {
+		return err
+	}
+	return srv.Close()
+}
+
+// ContainerRemove forgets the container, killing it first if still running
+func (f *FakeRuntime) ContainerRemove(ctx context.Context, containerID string, options container.RemoveOptions) error {
+	srv, err := f.detachServer(containerID, fakeKilledExitCode)
+	if err != nil {
+		return err
+	}
+	if srv != nil {
+		srv.Close()
+	}
+
+	f.mu.Lock()
+	delete(f.containers, containerID)
+	f.mu.Unlock()
+	return nil
+}
+
+// detachServer marks the container exited with exitCode and returns its server (nil if not running)
+func (f *FakeRuntime) detachServer(containerID string, exitCode int) (*http.Server, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	c, exists := f.containers[containerID]
+	if !exists {
+		return nil, fmt.Errorf("no such container: %s", containerID)
+	}
+
+	if c.stop != nil {
+		c.stop()
+		c.stop = nil
+	}
+	srv := c.server
+	if srv != nil {
+		c.exited, c.exitCode, c.finishedAt = true, exitCode, time.Now()
+		c.logs = append(c.logs, fmt.Sprintf("exited with code %d", exitCode))
+	}
+	c.server = nil
+	return srv, nil
+}
+
+func newFakeContainerID() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate container ID: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+func fakeEnvValue(env []string, key string) string {
+	for _, kv := range env {
+		if v, found := strings.CutPrefix(kv, key+"="); found {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,184 @@
+package gateway
+
+import (
+	"context"
+	"io"
+	"log"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/api/types/system"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/errdefs"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+const (
+	// runtimeRetryAttempts is the total number of tries for a call failing transiently
+	runtimeRetryAttempts = 3
+
+	// runtimeRetryBackoff is the wait before the first retry; it doubles on each further one
+	runtimeRetryBackoff = 200 * time.Millisecond
+)
+
+// instrumentedRuntime wraps a ContainerRuntime, recording per-call latency and
+// errors in the metrics registry and retrying transient daemon failures
+type instrumentedRuntime struct {
+	rt      ContainerRuntime
+	metrics *Metrics
+}
+
+func newInstrumentedRuntime(rt ContainerRuntime, metrics *Metrics) *instrumentedRuntime {
+	metrics.Register("orchestrator_runtime_calls_total", metricCounter, "Container runtime API calls, by operation and result")
+	metrics.Register("orchestrator_runtime_call_seconds_total", metricCounter, "Cumulative container runtime API latency, by operation")
+	metrics.Register("orchestrator_runtime_call_seconds_max", metricGauge, "Slowest container runtime API call seen, by operation")
+	metrics.Register("orchestrator_runtime_retries_total", metricCounter, "Container runtime API calls retried after a transient error")
+
+	return &instrumentedRuntime{rt: rt, metrics: metrics}
+}
+
+// retryable reports whether err is a daemon hiccup worth retrying. Calls that
+// aren't idempotent (create) are only retried when the daemon was never reached.
+func retryable(err error, idempotent bool) bool {
+	if client.IsErrConnectionFailed(err) {
+		return true
+	}
+	return idempotent && (errdefs.IsUnavailable(err) || errdefs.IsSystem(err))
+}
+
+// observe runs call with metrics and retries. Each attempt is recorded separately
+// so latency reflects what the daemon actually served.
+func observe[T any](r *instrumentedRuntime, ctx context.Context, op string, idempotent bool, call func() (T, error)) (T, error) {
+	backoff := runtimeRetryBackoff
+	for attempt := 1; ; attempt++ {
+		start := time.Now()
+		result, err := call()
+		r.record(op, time.Since(start), err)
+
+		if err == nil || attempt >= runtimeRetryAttempts || !retryable(err, idempotent) {
+			return result, err
+		}
+
+		log.Printf("[Runtime] %s failed (attempt %d/%d), retrying in %s: %v", op, attempt, runtimeRetryAttempts, backoff, err)
+		r.metrics.Inc("orchestrator_runtime_retries_total", "op", op)
+		select {
+		case <-ctx.Done():
+			return result, err
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+func (r *instrumentedRuntime) record(op string, elapsed time.Duration, err error) {
+	result := "ok"
+	if err != nil {
+		result = "error"
+	}
+	r.metrics.Inc("orchestrator_runtime_calls_total", "op", op, "result", result)
+	r.metrics.Add("orchestrator_runtime_call_seconds_total", elapsed.Seconds(), "op", op)
+	r.metrics.Max("orchestrator_runtime_call_seconds_max", elapsed.Seconds(), "op", op)
+}
+
+// noValue adapts calls that only return an error to observe
+type noValue struct{}
+
+func (r *instrumentedRuntime) Info(ctx context.Context) (system.Info, error) {
+	return observe(r, ctx, "info", true, func() (system.Info, error) { return r.rt.Info(ctx) })
+}
+
+func (r *instrumentedRuntime) Ping(ctx context.Context) (types.Ping, error) {
+	return observe(r, ctx, "ping", true, func() (types.Ping, error) { return r.rt.Ping(ctx) })
+}
+
+func (r *instrumentedRuntime) ImageInspectWithRaw(ctx context.Context, imageID string) (types.ImageInspect, []byte, error) {
+	var raw []byte
+	inspect, err := observe(r, ctx, "image_inspect", true, func() (types.ImageInspect, error) {
+		inspect, body, err := r.rt.ImageInspectWithRaw(ctx, imageID)
+		raw = body
+		return inspect, err
+	})
+	return inspect, raw, err
+}
+
+func (r *instrumentedRuntime) ImagePull(ctx context.Context, refStr string, options image.PullOptions) (io.ReadCloser, error) {
+	return observe(r, ctx, "image_pull", true, func() (io.ReadCloser, error) { return r.rt.ImagePull(ctx, refStr, options) })
+}
+
+func (r *instrumentedRuntime) ImageList(ctx context.Context, options image.ListOptions) ([]image.Summary, error) {
+	return observe(r, ctx, "image_list", true, func() ([]image.Summary, error) { return r.rt.ImageList(ctx, options) })
+}
+
+func (r *instrumentedRuntime) ImageRemove(ctx context.Context, imageID string, options image.RemoveOptions) ([]image.DeleteResponse, error) {
+	return observe(r, ctx, "image_remove", true, func() ([]image.DeleteResponse, error) {
+		return r.rt.ImageRemove(ctx, imageID, options)
+	})
+}
+
+func (r *instrumentedRuntime) NetworkInspect(ctx context.Context, networkID string, options types.NetworkInspectOptions) (types.NetworkResource, error) {
+	return observe(r, ctx, "network_inspect", true, func() (types.NetworkResource, error) {
+		return r.rt.NetworkInspect(ctx, networkID, options)
+	})
+}
+
+func (r *instrumentedRuntime) ContainerCreate(ctx context.Context, config *container.Config, hostConfig *container.HostConfig,
+	networkingConfig *network.NetworkingConfig, platform *ocispec.Platform, containerName string) (container.CreateResponse, error) {
+	return observe(r, ctx, "container_create", false, func() (container.CreateResponse, error) {
+		return r.rt.ContainerCreate(ctx, config, hostConfig, networkingConfig, platform, containerName)
+	})
+}
+
+func (r *instrumentedRuntime) ContainerInspect(ctx context.Context, containerID string) (types.ContainerJSON, error) {
+	return observe(r, ctx, "container_inspect", true, func() (types.ContainerJSON, error) { return r.rt.ContainerInspect(ctx, containerID) })
+}
+
+func (r *instrumentedRuntime) ContainerLogs(ctx context.Context, containerID string, options container.LogsOptions) (io.ReadCloser, error) {
+	return observe(r, ctx, "container_logs", true, func() (io.ReadCloser, error) { return r.rt.ContainerLogs(ctx, containerID, options) })
+}
+
+func (r *instrumentedRuntime) ContainerList(ctx context.Context, options container.ListOptions) ([]types.Container, error) {
+	return observe(r, ctx, "container_list", true, func() ([]types.Container, error) { return r.rt.ContainerList(ctx, options) })
+}
+
+func (r *instrumentedRuntime) ContainerStats(ctx context.Context, containerID string, stream bool) (types.ContainerStats, error) {
+	return observe(r, ctx, "container_stats", true, func() (types.ContainerStats, error) {
+		return r.rt.ContainerStats(ctx, containerID, stream)
+	})
+}
+
+func (r *instrumentedRuntime) ContainerUpdate(ctx context.Context, containerID string, updateConfig container.UpdateConfig) (container.ContainerUpdateOKBody, error) {
+	return observe(r, ctx, "container_update", true, func() (container.ContainerUpdateOKBody, error) {
+		return r.rt.ContainerUpdate(ctx, containerID, updateConfig)
+	})
+}
+
+func (r *instrumentedRuntime) ContainerStart(ctx context.Context, containerID string, options container.StartOptions) error {
+	_, err := observe(r, ctx, "container_start", true, func() (noValue, error) {
+		return noValue{}, r.rt.ContainerStart(ctx, containerID, options)
+	})
+	return err
+}
+
+func (r *instrumentedRuntime) ContainerStop(ctx context.Context, containerID string, options container.StopOptions) error {
+	_, err := observe(r, ctx, "container_stop", true, func() (noValue, error) {
+		return noValue{}, r.rt.ContainerStop(ctx, containerID, options)
+	})
+	return err
+}
+
+func (r *instrumentedRuntime) ContainerKill(ctx context.Context, containerID, signal string) error {
+	_, err := observe(r, ctx, "container_kill", true, func() (noValue, error) {
+		return noValue{}, r.rt.ContainerKill(ctx, containerID, signal)
+	})
+	return err
+}
+
+func (r *instrumentedRuntime) ContainerRemove(ctx context.Context, containerID string, options container.RemoveOptions) error {
+	_, err := observe(r, ctx, "container_remove", true, func() (noValue, error) {
+		return noValue{}, r.rt.ContainerRemove(ctx, containerID, options)
+	})
+	return err
+}
@@ -0,0 +1,462 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/api/types/system"
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/docker/go-connections/nat"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+
+	"github.com/ahmadhassan44/container-orchestrator/pkg/config"
+)
+
+// localImageID is the ID reported for the worker binary standing in for an image
+const localImageID = "sha256:local-exec"
+
+// localLogLines is how many output lines are kept per worker process
+const localLogLines = 1000
+
+// localProcess is a "container" run as a worker process on the host
+type localProcess struct {
+	config     *container.Config
+	hostConfig *container.HostConfig
+	cmd        *exec.Cmd
+	hostPort   string
+	output     *logTail
+	done       chan struct{} // Closed once the process has exited
+
+	exited     bool
+	exitCode   int
+	finishedAt time.Time
+}
+
+// LocalRuntime implements ContainerRuntime by running the worker binary as local
+// OS processes, pinned to each container's cpuset where the platform allows.
+// Workers get none of a container's isolation (memory, I/O and network limits,
+// a private filesystem), so this is a degraded mode: for development without
+// Docker (RUNTIME=local) and as a fallback when Docker is unreachable
+// (LOCAL_EXEC_FALLBACK).
+type LocalRuntime struct {
+	binary string // Worker executable ("" = not found)
+
+	mu    sync.Mutex
+	procs map[string]*localProcess
+}
+
+func NewLocalRuntime(cfg *config.Config) *LocalRuntime {
+	binary, err := findWorkerBinary(cfg.WorkerBinary)
+	if err != nil {
+		log.Printf("[WARNING] local-exec: %v", err)
+	} else {
+		log.Printf("[LocalRuntime] Running workers as local processes of %s", binary)
+	}
+	return &LocalRuntime{binary: binary, procs: make(map[string]*localProcess)}
+}
+
+// findWorkerBinary resolves the worker executable: the configured path, else
+// "worker" beside the gateway's own executable (as make build lays them out),
+// else "worker" on the PATH
+func findWorkerBinary(configured string) (string, error) {
+	if configured != "" {
+		return exec.LookPath(configured)
+	}
+	if self, err := os.Executable(); err == nil {
+		if path, err := exec.LookPath(filepath.Join(filepath.Dir(self), "worker")); err == nil {
+			return path, nil
+		}
+	}
+	path, err := exec.LookPath("worker")
+	if err != nil {
+		return "", fmt.Errorf("no worker binary beside the gateway or on the PATH; build it with make worker or set WORKER_BINARY")
+	}
+	return path, nil
+}
+
+// Degraded explains what workers run without compared to containers
+func (l *LocalRuntime) Degraded() string {
+	return "local-exec: workers run as host processes without container isolation or resource limits"
+}
+
+// Info describes the host the worker processes run on
+func (l *LocalRuntime) Info(ctx context.Context) (system.Info, error) {
+	return system.Info{Name: "local-exec", NCPU: runtime.NumCPU(), Architecture: runtime.GOARCH}, nil
+}
+
+// Ping succeeds while the worker binary is known
+func (l *LocalRuntime) Ping(ctx context.Context) (types.Ping, error) {
+	if l.binary == "" {
+		return types.Ping{}, fmt.Errorf("local-exec: no worker binary")
+	}
+	return types.Ping{APIVersion: "local-exec"}, nil
+}
+
+// ImageInspectWithRaw reports every image as present while the worker binary is
+func (l *LocalRuntime) ImageInspectWithRaw(ctx context.Context, imageID string) (types.ImageInspect, []byte, error) {
+	if l.binary == "" {
+		return types.ImageInspect{}, nil, fmt.Errorf("local-exec: no worker binary for image %s", imageID)
+	}
+	return types.ImageInspect{ID: localImageID, RepoTags: []string{imageID}, Os: runtime.GOOS, Architecture: runtime.GOARCH}, nil, nil
+}
+
+// ImagePull has nothing to pull: workers run from the local binary
+func (l *LocalRuntime) ImagePull(ctx context.Context, refStr string, options image.PullOptions) (io.ReadCloser, error) {
+	return nil, fmt.Errorf("local-exec runs the worker binary; there is no image to pull")
+}
+
+// ImageList reports the worker binary as the only image
+func (l *LocalRuntime) ImageList(ctx context.Context, options image.ListOptions) ([]image.Summary, error) {
+	if l.binary == "" {
+		return []image.Summary{}, nil
+	}
+	return []image.Summary{{
+		ID:       localImageID,
+		RepoTags: []string{defaultWorkerImage},
+		Labels:   map[string]string{imageRoleLabel: imageRoleWorker},
+	}}, nil
+}
+
+// ImageRemove refuses: the worker binary is not the runtime's to delete
+func (l *LocalRuntime) ImageRemove(ctx context.Context, imageID string, options image.RemoveOptions) ([]image.DeleteResponse, error) {
+	return nil, fmt.Errorf("local-exec: image %s is the worker binary and can't be removed", imageID)
+}
+
+// NetworkInspect reports a loopback gateway for every network: worker processes
+// reach the gateway on localhost
+func (l *LocalRuntime) NetworkInspect(ctx context.Context, networkID string, options types.NetworkInspectOptions) (types.NetworkResource, error) {
+	return types.NetworkResource{
+		Name:   networkID,
+		Driver: "host",
+		IPAM:   network.IPAM{Config: []network.IPAMConfig{{Subnet: "127.0.0.0/8", Gateway: "127.0.0.1"}}},
+	}, nil
+}
+
+// ContainerCreate records the container configuration without starting anything
+func (l *LocalRuntime) ContainerCreate(ctx context.Context, config *container.Config, hostConfig *container.HostConfig,
+	networkingConfig *network.NetworkingConfig, platform *ocispec.Platform, containerName string) (container.CreateResponse, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	id, err := newFakeContainerID()
+	if err != nil {
+		return container.CreateResponse{}, err
+	}
+	l.procs[id] = &localProcess{config: config, hostConfig: hostConfig, output: &logTail{max: localLogLines}}
+	return container.CreateResponse{ID: id}, nil
+}
+
+// ContainerInspect reports the process's state and the port it serves on
+func (l *LocalRuntime) ContainerInspect(ctx context.Context, containerID string) (types.ContainerJSON, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	p, exists := l.procs[containerID]
+	if !exists {
+		return types.ContainerJSON{}, fmt.Errorf("no such container: %s", containerID)
+	}
+
+	settings := &types.NetworkSettings{}
+	state := &types.ContainerState{Status: "created"}
+	switch {
+	case p.exited:
+		state.Status = "exited"
+		state.ExitCode = p.exitCode
+		state.FinishedAt = p.finishedAt.Format(time.RFC3339Nano)
+	case p.cmd != nil:
+		state.Status = "running"
+		state.Running = true
+		state.Pid = p.cmd.Process.Pid
+		settings.Ports = nat.PortMap{"8080/tcp": []nat.PortBinding{{HostIP: "0.0.0.0", HostPort: p.hostPort}}}
+	}
+	return types.ContainerJSON{
+		ContainerJSONBase: &types.ContainerJSONBase{
+			ID:    containerID,
+			Image: localImageID,
+			State: state,
+		},
+		Config:          p.config,
+		NetworkSettings: settings,
+	}, nil
+}
+
+// ContainerLogs serves the process's recent output in Docker's multiplexed stdout format
+func (l *LocalRuntime) ContainerLogs(ctx context.Context, containerID string, options container.LogsOptions) (io.ReadCloser, error) {
+	l.mu.Lock()
+	p, exists := l.procs[containerID]
+	l.mu.Unlock()
+	if !exists {
+		return nil, fmt.Errorf("no such container: %s", containerID)
+	}
+
+	lines := p.output.Lines()
+	if tail, err := strconv.Atoi(options.Tail); err == nil && tail < len(lines) {
+		lines = lines[len(lines)-tail:]
+	}
+	var buf bytes.Buffer
+	stdout := stdcopy.NewStdWriter(&buf, stdcopy.Stdout)
+	for _, line := range lines {
+		fmt.Fprintln(stdout, line)
+	}
+	return io.NopCloser(&buf), nil
+}
+
+// ContainerList reports every worker process
+func (l *LocalRuntime) ContainerList(ctx context.Context, options container.ListOptions) ([]types.Container, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	containers := make([]types.Container, 0, len(l.procs))
+	for id, p := range l.procs {
+		state := "created"
+		if p.exited {
+			state = "exited"
+		} else if p.cmd != nil {
+			state = "running"
+		}
+		containers = append(containers, types.Container{ID: id, Image: p.config.Image, ImageID: localImageID, State: state, Labels: p.config.Labels})
+	}
+	return containers, nil
+}
+
+// ContainerStats returns a single synthetic sample in Docker's stats format; only
+// identity, timing and the CPU count of the process's cpuset are meaningful
+func (l *LocalRuntime) ContainerStats(ctx context.Context, containerID string, stream bool) (types.ContainerStats, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	p, exists := l.procs[containerID]
+	if !exists {
+		return types.ContainerStats{}, fmt.Errorf("no such container: %s", containerID)
+	}
+
+	now := time.Now()
+	cpus, _ := parseCpuset(p.hostConfig.CpusetCpus)
+	sample := types.StatsJSON{
+		Stats: types.Stats{
+			Read:     now,
+			PreRead:  now.Add(-time.Second),
+			CPUStats: types.CPUStats{OnlineCPUs: uint32(len(cpus))},
+		},
+		Name: "/" + containerID[:12],
+		ID:   containerID,
+	}
+	if p.cmd != nil && !p.exited {
+		sample.PidsStats.Current = 1
+	}
+
+	body, err := json.Marshal(sample)
+	if err != nil {
+		return types.ContainerStats{}, err
+	}
+	return types.ContainerStats{Body: io.NopCloser(bytes.NewReader(body)), OSType: runtime.GOOS}, nil
+}
+
+// ContainerStart runs the worker binary serving on the published host port,
+// with only the container's environment, pinned to its cpuset
+func (l *LocalRuntime) ContainerStart(ctx context.Context, containerID string, options container.StartOptions) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	p, exists := l.procs[containerID]
+	if !exists {
+		return fmt.Errorf("no such container: %s", containerID)
+	}
+	if p.cmd != nil && !p.exited {
+		return nil
+	}
+	if l.binary == "" {
+		return fmt.Errorf("local-exec: no worker binary")
+	}
+
+	bindings := p.hostConfig.PortBindings["8080/tcp"]
+	if len(bindings) == 0 {
+		return fmt.Errorf("container %s has no port binding for 8080/tcp", containerID[:12])
+	}
+
+	// The worker binds the port itself once started; a taken port is reported now,
+	// so the orchestrator retries on another as it would with Docker
+	probe, err := net.Listen("tcp", fmt.Sprintf("%s:%s", bindings[0].HostIP, bindings[0].HostPort))
+	if err != nil {
+		return fmt.Errorf("port bind failed: %w", err)
+	}
+	port := strconv.Itoa(probe.Addr().(*net.TCPAddr).Port)
+	probe.Close()
+
+	cmd := exec.Command(l.binary)
+	cmd.Env = append([]string{"PATH=" + os.Getenv("PATH")}, p.config.Env...)
+	cmd.Env = append(cmd.Env, "WORKER_PORT="+port)
+	cmd.Stdout = io.MultiWriter(log.Writer(), p.output)
+	cmd.Stderr = cmd.Stdout
+
+	cpus, err := parseCpuset(p.hostConfig.CpusetCpus)
+	if p.hostConfig.CpusetCpus == "" || err != nil {
+		cpus = nil
+	}
+	if err := startPinned(cmd, cpus); err != nil {
+		return fmt.Errorf("local-exec: starting %s: %w", l.binary, err)
+	}
+
+	p.cmd, p.hostPort, p.exited = cmd, port, false
+	p.done = make(chan struct{})
+	go l.wait(p)
+	return nil
+}
+
+// wait records the process's exit
+func (l *LocalRuntime) wait(p *localProcess) {
+	p.cmd.Wait()
+	code := p.cmd.ProcessState.ExitCode()
+	if status, ok := p.cmd.ProcessState.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+		code = 128 + int(status.Signal())
+	}
+
+	l.mu.Lock()
+	p.exited, p.exitCode, p.finishedAt = true, code, time.Now()
+	l.mu.Unlock()
+	p.output.Write([]byte(fmt.Sprintf("exited with code %d\n", code)))
+	close(p.done)
+}
+
+// ContainerUpdate re-pins a running worker process to a new cpuset. CPU quotas
+// can't be enforced on a plain process and are ignored.
+func (l *LocalRuntime) ContainerUpdate(ctx context.Context, containerID string, updateConfig container.UpdateConfig) (container.ContainerUpdateOKBody, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	p, exists := l.procs[containerID]
+	if !exists {
+		return container.ContainerUpdateOKBody{}, fmt.Errorf("no such container: %s", containerID)
+	}
+	if updateConfig.CpusetCpus == "" {
+		return container.ContainerUpdateOKBody{}, nil
+	}
+	cpus, err := parseCpuset(updateConfig.CpusetCpus)
+	if err != nil {
+		return container.ContainerUpdateOKBody{}, err
+	}
+	p.hostConfig.CpusetCpus = updateConfig.CpusetCpus
+	if p.cmd != nil && !p.exited {
+		if err := repin(p.cmd.Process.Pid, cpus); err != nil {
+			return container.ContainerUpdateOKBody{Warnings: []string{err.Error()}}, nil
+		}
+	}
+	return container.ContainerUpdateOKBody{}, nil
+}
+
+// ContainerStop sends SIGTERM so the worker drains, and kills it if it outlasts the stop timeout
+func (l *LocalRuntime) ContainerStop(ctx context.Context, containerID string, options container.StopOptions) error {
+	p, err := l.running(containerID)
+	if err != nil || p == nil {
+		return err
+	}
+
+	timeout := 10 * time.Second
+	if options.Timeout != nil {
+		timeout = time.Duration(*options.Timeout) * time.Second
+	}
+	if err := p.cmd.Process.Signal(syscall.SIGTERM); err != nil {
+		return p.cmd.Process.Kill()
+	}
+	select {
+	case <-p.done:
+		return nil
+	case <-time.After(timeout):
+	case <-ctx.Done():
+	}
+	log.Printf("[LocalRuntime] %s didn't stop within %s, killing it", containerID[:12], timeout)
+	return p.cmd.Process.Kill()
+}
+
+// ContainerKill kills the worker process immediately
+func (l *LocalRuntime) ContainerKill(ctx context.Context, containerID, signal string) error {
+	p, err := l.running(containerID)
+	if err != nil || p == nil {
+		return err
+	}
+	if err := p.cmd.Process.Kill(); err != nil && !errors.Is(err, os.ErrProcessDone) {
+		return err
+	}
+	<-p.done
+	return nil
+}
+
+// ContainerRemove forgets the container, killing its process first if still running
+func (l *LocalRuntime) ContainerRemove(ctx context.Context, containerID string, options container.RemoveOptions) error {
+	if err := l.ContainerKill(ctx, containerID, "SIGKILL"); err != nil {
+		return err
+	}
+	l.mu.Lock()
+	delete(l.procs, containerID)
+	l.mu.Unlock()
+	return nil
+}
+
+// running returns the container's process if it is running (nil if it isn't)
+func (l *LocalRuntime) running(containerID string) (*localProcess, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	p, exists := l.procs[containerID]
+	if !exists {
+		return nil, fmt.Errorf("no such container: %s", containerID)
+	}
+	if p.cmd == nil || p.exited {
+		return nil, nil
+	}
+	return p, nil
+}
+
+// logTail keeps the last max lines written to it
+type logTail struct {
+	max int
+
+	mu      sync.Mutex
+	lines   []string
+	partial []byte
+}
+
+func (t *logTail) Write(b []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.partial = append(t.partial, b...)
+	for {
+		i := bytes.IndexByte(t.partial, '\n')
+		if i < 0 {
+			break
+		}
+		t.lines = append(t.lines, strings.TrimRight(string(t.partial[:i]), "\r"))
+		t.partial = t.partial[i+1:]
+	}
+	if len(t.lines) > t.max {
+		t.lines = append([]string(nil), t.lines[len(t.lines)-t.max:]...)
+	}
+	return len(b), nil
+}
+
+// Lines returns a copy of the kept lines
+func (t *logTail) Lines() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]string(nil), t.lines...)
+}
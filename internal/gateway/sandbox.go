@@ -0,0 +1,118 @@
+package gateway
+
+import (
+	"fmt"
+	"log"
+	"slices"
+	"sort"
+
+	"github.com/ahmadhassan44/container-orchestrator/pkg/config"
+)
+
+// sandboxTaint is the implicit taint of sandboxed cores: jobs whose operation
+// doesn't need the sandbox only run there if they tolerate it
+const sandboxTaint = "sandbox"
+
+// Sandbox is the pool of cores whose worker containers run under a stronger-
+// isolation OCI runtime (gVisor's runsc, Kata), and the untrusted operations
+// that may only run there. Sandboxed workers are slower, so other jobs stay off
+// them unless they tolerate the "sandbox" taint.
+type Sandbox struct {
+	runtime    string          // OCI runtime for sandboxed workers ("" = no pool)
+	cores      map[int]bool    // Cores in the pool
+	operations map[string]bool // Operations that must run in the pool
+	disabled   string          // Why sandboxed operations can't run at all ("" = they can)
+}
+
+func NewSandbox(cfg *config.Config, degraded bool) *Sandbox {
+	s := &Sandbox{runtime: cfg.SandboxRuntime, cores: make(map[int]bool), operations: make(map[string]bool)}
+	for _, op := range cfg.SandboxOperations {
+		s.operations[op] = true
+	}
+
+	if s.runtime == "" {
+		if len(cfg.SandboxCores) > 0 {
+			log.Printf("[WARNING] SANDBOX_CORES is set without SANDBOX_RUNTIME; ignoring it")
+		}
+	} else {
+		for _, core := range cfg.SandboxCores {
+			if _, exists := coreMaps[core]; !exists {
+				log.Printf("[WARNING] Ignoring unknown SANDBOX_CORES core %d", core)
+				continue
+			}
+			s.cores[core] = true
+		}
+	}
+
+	switch {
+	case len(s.operations) == 0:
+	case degraded:
+		s.disabled = "workers run without container isolation on this gateway"
+	case len(s.cores) == 0:
+		s.disabled = "no sandboxed cores are configured (SANDBOX_RUNTIME, SANDBOX_CORES)"
+	}
+	if len(s.cores) > 0 {
+		log.Printf("[Sandbox] Cores %v run workers under %s", s.Cores(), s.runtime)
+	}
+	if s.disabled != "" {
+		log.Printf("[WARNING] Sandboxed operations %v will be rejected: %s", cfg.SandboxOperations, s.disabled)
+	}
+	return s
+}
+
+// Runtime is the OCI runtime coreID's worker container runs under ("" = the daemon's default)
+func (s *Sandbox) Runtime(coreID int) string {
+	if s.cores[coreID] {
+		return s.runtime
+	}
+	return ""
+}
+
+// Required reports whether operation may only run on sandboxed cores
+func (s *Sandbox) Required(operation string) bool {
+	return s.operations[operation]
+}
+
+// Check returns an error if operation needs the sandbox and this gateway can't provide one
+func (s *Sandbox) Check(operation string) error {
+	if s.Required(operation) && s.disabled != "" {
+		return fmt.Errorf("operation %q must run sandboxed, but %s", operation, s.disabled)
+	}
+	return nil
+}
+
+// Allows reports whether a job running operation, with tolerations, may run on coreID
+func (s *Sandbox) Allows(coreID int, operation string, tolerations []string) bool {
+	if s.cores[coreID] {
+		return s.Required(operation) || tolerates(tolerations, sandboxTaint)
+	}
+	return !s.Required(operation)
+}
+
+// Cores lists the sandboxed cores in order
+func (s *Sandbox) Cores() []int {
+	cores := make([]int, 0, len(s.cores))
+	for core := range s.cores {
+		cores = append(cores, core)
+	}
+	sort.Ints(cores)
+	return cores
+}
+
+// Status reports the pool's runtime, cores and operations
+func (s *Sandbox) Status() map[string]interface{} {
+	operations := make([]string, 0, len(s.operations))
+	for op := range s.operations {
+		operations = append(operations, op)
+	}
+	slices.Sort(operations)
+	status := map[string]interface{}{
+		"runtime":    s.runtime,
+		"cores":      s.Cores(),
+		"operations": operations,
+	}
+	if s.disabled != "" {
+		status["disabled"] = s.disabled
+	}
+	return status
+}
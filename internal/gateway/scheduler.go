@@ -2,34 +2,51 @@ package gateway
 
 import (
 	"bytes"
+	"cmp"
 	"context"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
+	"net/url"
+	"slices"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/ahmadhassan44/container-orchestrator/internal/worker"
 	"github.com/ahmadhassan44/container-orchestrator/pkg/config"
 	"github.com/ahmadhassan44/container-orchestrator/pkg/protocol"
+	"github.com/ahmadhassan44/container-orchestrator/pkg/signing"
 )
 
 // ============================================================================
 // JOB QUEUING FEATURE - Can be enabled/disabled by setting ENABLE_JOB_QUEUE
+// Queue sizes, timeouts and weights are configured per named queue (QUEUES)
 // ============================================================================
 const (
 	ENABLE_JOB_QUEUE = true // Set to false to disable job queuing
-	MAX_QUEUE_SIZE   = 100  // Maximum number of queued jobs
-	QUEUE_TIMEOUT    = 300  // Seconds to wait in queue before giving up
 )
 
 // QueuedJob represents a job waiting to be scheduled
 type QueuedJob struct {
 	request      *protocol.ComputeRequest
+	queue        string // Name of the queue the job waits in
 	responseCh   chan *protocol.JobResponse
 	errorCh      chan error
 	enqueuedAt   time.Time
 	estimatedCPU float64
+	duration     float64 // Estimated run time in seconds
+	slices       int     // Time slices completed so far
+	starving     bool    // Waited past its queue's starvation threshold
+
+	// Grace added to the queue timeout by the job's timeout policy
+	timeoutGrace time.Duration
+	extended     bool // Already given its one extension
 }
 
 // Scheduler handles intelligent job routing and load balancing
@@ -38,46 +55,149 @@ type Scheduler struct {
 	estimator    *CPUEstimator
 	config       *config.Config
 	httpClient   *http.Client
-	scheduleMux  sync.Mutex // Prevents race conditions in concurrent scheduling
+	scheduleMux  sync.Mutex  // Prevents race conditions in concurrent scheduling
+	paused       atomic.Bool // While paused, jobs queue but nothing is dispatched
 
-	// Job Queue (can be disabled by setting ENABLE_JOB_QUEUE = false)
-	jobQueue        chan *QueuedJob
+	// Job Queues (can be disabled by setting ENABLE_JOB_QUEUE = false)
+	queues          *queueSet
 	queueWorkerStop chan struct{}
+	queueStopOnce   sync.Once     // Shutdown and embedders may both stop the processor
+	queueWake       chan struct{} // Signalled when a worker frees capacity
+
+	// Jobs currently executing on workers, by job ID
+	runningMu        sync.Mutex
+	running          map[string]*runningEntry
+	progressSinks    map[string]ProgressSink
+	statusListener   StatusListener
+	usageListener    UsageListener
+	decisionListener DecisionListener
+	traceListener    TraceListener
+	retrying         map[string]chan struct{} // Jobs backing off before a retry; closed to cancel
+	cancelOnStart    map[string]bool          // Jobs to cancel as soon as they are dispatched
+	scheduling       map[string]bool          // Jobs inside ScheduleJob
+	forced           map[string]forcedOutcome // Admin-imposed outcomes, returned in place of the real one
+	annotations      map[string]*protocol.JobAnnotations
+	receiving        map[int]int // Cores receiving paired jobs, with how many
+
+	eta *etaModel // Empirical spread of actual vs. estimated times, for ETA ranges
+
+	experiment *Experiment // A/B routing of jobs through an alternative placement strategy
+
+	maintenance  *Maintenance         // Windows during which cores take no jobs
+	taints       *Taints              // Cores only tolerant jobs run on
+	sandbox      *Sandbox             // Cores untrusted operations are confined to
+	degradation  *DegradationDetector // Cores underperforming their calibration baseline
+	interference *Interference        // What sharing a physical core costs each operation
+	benchmarks   *Benchmarks          // Workers reserved for exclusive benchmarking
+
+	scaling        *scaleGuard // Cooldowns and rate limits on spawning and reaping workers
+	scaleDownFloor func() int  // Workers scale-down must leave running, besides INITIAL_WORKERS
 }
 
 func NewScheduler(orch *Orchestrator, cfg *config.Config) *Scheduler {
 	s := &Scheduler{
-		orchestrator: orch,
-		estimator:    NewCPUEstimator(),
-		config:       cfg,
-		httpClient:   &http.Client{}, // Timeout set per request
+		orchestrator:  orch,
+		estimator:     NewCPUEstimator(),
+		config:        cfg,
+		httpClient:    &http.Client{}, // Timeout set per request
+		running:       make(map[string]*runningEntry),
+		progressSinks: make(map[string]ProgressSink),
+		retrying:      make(map[string]chan struct{}),
+		cancelOnStart: make(map[string]bool),
+		scheduling:    make(map[string]bool),
+		forced:        make(map[string]forcedOutcome),
+		annotations:   make(map[string]*protocol.JobAnnotations),
+		receiving:     make(map[int]int),
+		eta:           newETAModel(),
+		experiment:    NewExperiment(cfg, orch.Metrics()),
+		scaling:       newScaleGuard(cfg, orch.Metrics()),
+		maintenance:   NewMaintenance(cfg),
+		taints:        NewTaints(cfg),
+		sandbox:       orch.Sandbox(),
+		degradation:   NewDegradationDetector(cfg, orch.Metrics()),
+		interference:  NewInterference(cfg, orch.Metrics()),
+		benchmarks:    NewBenchmarks(cfg),
 	}
+	orch.SetCoreFilter(s.maintenance.Spawnable)
+	orch.SetDriftSource(s.degradation.Drift)
+	orch.SetDrainer(s.drain)
+	s.benchmarks.onEnd = func(int) { s.wakeQueue() }
+
+	orch.Metrics().Register("orchestrator_queue_starved_jobs_total", metricCounter, "Queued jobs that waited past their queue's starvation threshold")
+	orch.Metrics().Register("orchestrator_queue_timeouts_total", metricCounter, "Queued jobs that outwaited their queue's timeout, by policy applied")
+	orch.Metrics().Register("orchestrator_result_signature_failures_total", metricCounter, "Worker results rejected for a missing or invalid signature")
 
-	// Initialize job queue if enabled
+	// Initialize job queues if enabled
+	s.queues = newQueueSet(cfg, len(coreMaps))
 	if ENABLE_JOB_QUEUE {
-		s.jobQueue = make(chan *QueuedJob, MAX_QUEUE_SIZE)
 		s.queueWorkerStop = make(chan struct{})
+		s.queueWake = make(chan struct{}, 1)
 		go s.processJobQueue()
-		log.Printf("[Scheduler] Job queuing ENABLED (max queue size: %d, timeout: %ds)",
-			MAX_QUEUE_SIZE, QUEUE_TIMEOUT)
+		for _, name := range s.queues.order {
+			qc := s.queues.queues[name].config
+			log.Printf("[Scheduler] Job queue %q ENABLED (max size: %d, timeout: %ds, weight: %d, worker share: %.0f%%)",
+				qc.Name, qc.MaxSize, qc.Timeout, qc.Weight, qc.WorkerShare*100)
+		}
 	}
 
 	return s
 }
 
-// ScheduleJob finds the best worker for a job or spawns a new one if needed
+// ScheduleJob runs a job, retrying failed attempts as its retry policy allows
 func (s *Scheduler) ScheduleJob(req *protocol.ComputeRequest) (*protocol.JobResponse, error) {
+	if s.orchestrator.ShuttingDown() {
+		return nil, errShuttingDown
+	}
+	strategy := s.experiment.Assign(req.JobID)
+	s.annotate(req.JobID, func(a *protocol.JobAnnotations) {
+		a.Strategy = strategy
+		a.EstimatedCPU = s.estimator.EstimateCPUUsage(req)
+		a.EstimatedDuration = s.estimator.EstimateJobDuration(req)
+	})
+	s.runningMu.Lock()
+	s.scheduling[req.JobID] = true
+	s.runningMu.Unlock()
+	defer func() {
+		s.runningMu.Lock()
+		delete(s.cancelOnStart, req.JobID)
+		s.runningMu.Unlock()
+	}()
+
+	response, err := s.scheduleWithRetries(req, func() (*protocol.JobResponse, error) {
+		return s.scheduleAttempt(req)
+	})
+	if forced, exists := s.finishScheduling(req.JobID); exists {
+		return forced.response, forced.err
+	}
+	if err == nil {
+		s.observeTimes(req)
+	}
+	return response, err
+}
+
+// scheduleAttempt finds the best worker for a job or spawns a new one if needed
+func (s *Scheduler) scheduleAttempt(req *protocol.ComputeRequest) (*protocol.JobResponse, error) {
 	estimatedCPU := s.estimator.EstimateCPUUsage(req)
 	loadTime := s.estimator.EstimateJobDuration(req)
 
 	log.Printf("[Scheduler] Job request: cpu_load=%.1f%%, load_time=%.1fs",
 		estimatedCPU, loadTime)
+	s.trace(req, traceSchedule, nil, "estimated %.1f%% CPU for %.1fs", estimatedCPU, loadTime)
+
+	if worker.IsPaired(req.Operation) {
+		return s.schedulePairJob(req, estimatedCPU, loadTime)
+	}
 
 	// ========================================================================
 	// JOB QUEUING: If enabled, try to queue job when all workers are busy
 	// ========================================================================
 	if ENABLE_JOB_QUEUE {
-		return s.scheduleJobWithQueue(req, estimatedCPU, loadTime)
+		queue, err := s.queues.resolve(req.Queue)
+		if err != nil {
+			return nil, err
+		}
+		req.Queue = queue
+		return s.scheduleJobWithQueue(req, queue, estimatedCPU, loadTime)
 	}
 
 	// Original scheduling logic (without queuing)
@@ -86,25 +206,35 @@ func (s *Scheduler) ScheduleJob(req *protocol.ComputeRequest) (*protocol.JobResp
 
 // scheduleJobDirect handles immediate scheduling without queuing
 func (s *Scheduler) scheduleJobDirect(req *protocol.ComputeRequest, estimatedCPU, loadTime float64) (*protocol.JobResponse, error) {
+	if s.paused.Load() {
+		return nil, fmt.Errorf("scheduler is paused")
+	}
+
 	// Lock to prevent race conditions when multiple jobs arrive simultaneously
 	s.scheduleMux.Lock()
 
 	// Try to find a suitable existing worker
-	worker := s.findSuitableWorker(estimatedCPU)
+	worker := s.findSuitableWorker(req, estimatedCPU, loadTime)
+	placement := protocol.PlacementExisting
 
 	if worker == nil {
 		// No suitable worker found, try to spawn a new one
 		log.Printf("[Scheduler] No suitable worker found, attempting to spawn new worker")
 
-		coreID, err := s.orchestrator.GetNextAvailableCore()
+		coreID, err := s.orchestrator.GetNextAvailableCoreWhere(s.spawnableFor(req.Queue, operationName(req), req.Tolerations, loadTime))
 		if err != nil {
 			s.scheduleMux.Unlock()
-			return nil, fmt.Errorf("cannot spawn worker: %w", err)
+			s.trace(req, traceSchedule, nil, "can't spawn a worker: %v", err)
+			return nil, failure(protocol.FailureQueue, fmt.Errorf("cannot spawn worker: %w", err))
+		}
+		if err := s.scaling.takeSpawn(s.orchestrator.GetWorkerCount()); err != nil {
+			s.scheduleMux.Unlock()
+			return nil, failure(protocol.FailureQueue, fmt.Errorf("cannot spawn worker: %w", err))
 		}
 
 		if _, err := s.orchestrator.StartWorker(coreID); err != nil {
 			s.scheduleMux.Unlock()
-			return nil, fmt.Errorf("failed to start worker on core %d: %w", coreID, err)
+			return nil, failure(protocol.FailureQueue, fmt.Errorf("failed to start worker on core %d: %w", coreID, err))
 		}
 
 		// Wait briefly for worker to initialize
@@ -115,33 +245,31 @@ func (s *Scheduler) scheduleJobDirect(req *protocol.ComputeRequest, estimatedCPU
 			s.scheduleMux.Unlock()
 			return nil, fmt.Errorf("worker spawned but not found in state")
 		}
+		placement = protocol.PlacementSpawned
 	}
+	s.annotate(req.JobID, func(a *protocol.JobAnnotations) { a.Placement = placement })
+	s.annotateWorker(req.JobID, worker)
+	s.notifyDecision(telemetryPlaced, req, "", estimatedCPU, loadTime, worker, placement, 0)
 
 	// Update projected CPU usage BEFORE releasing lock
-	s.orchestrator.UpdateWorkerCPU(worker.CoreID, worker.CurrentCPU+estimatedCPU)
+	reserved := s.orchestrator.AdjustWorkerCPU(worker, estimatedCPU)
 
 	// Release lock - worker is now reserved for this job
 	s.scheduleMux.Unlock()
 
 	log.Printf("[Scheduler] Routing job to Worker-Core-%d (port %d, current_cpu=%.1f%%)",
-		worker.CoreID, worker.HostPort, worker.CurrentCPU)
+		worker.CoreID, worker.HostPort, reserved)
 
 	// Execute job on selected worker
 	response, err := s.executeJobOnWorker(worker, req)
+
+	// Release the job's CPU, whether it finished or failed
+	s.orchestrator.AdjustWorkerCPU(worker, -estimatedCPU)
+	s.wakeQueue()
 	if err != nil {
-		// Restore CPU usage on failure (ensure it doesn't go negative)
-		newCPU := worker.CurrentCPU - estimatedCPU
-		if newCPU < 0 {
-			newCPU = 0
-		}
-		s.orchestrator.UpdateWorkerCPU(worker.CoreID, newCPU)
 		return nil, err
 	}
 
-	// After job completion, decay CPU usage (job is done)
-	// The UpdateWorkerCPU function will ensure it doesn't go below 0
-	s.orchestrator.UpdateWorkerCPU(worker.CoreID, worker.CurrentCPU-estimatedCPU)
-
 	// Check if we need to proactively spawn another worker
 	s.checkProactiveSpawn()
 
@@ -153,62 +281,178 @@ func (s *Scheduler) scheduleJobDirect(req *protocol.ComputeRequest, estimatedCPU
 // ============================================================================
 
 // scheduleJobWithQueue attempts immediate scheduling, or queues if all workers busy
-func (s *Scheduler) scheduleJobWithQueue(req *protocol.ComputeRequest, estimatedCPU, loadTime float64) (*protocol.JobResponse, error) {
-	// Try immediate scheduling first
+func (s *Scheduler) scheduleJobWithQueue(req *protocol.ComputeRequest, queue string, estimatedCPU, loadTime float64) (*protocol.JobResponse, error) {
+	s.applyTimeSlicing(req)
+
+	job := &QueuedJob{
+		request:      req,
+		queue:        queue,
+		responseCh:   make(chan *protocol.JobResponse, 1),
+		errorCh:      make(chan error, 1),
+		enqueuedAt:   time.Now(),
+		estimatedCPU: estimatedCPU,
+		duration:     loadTime,
+	}
+
+	// Try immediate scheduling first, unless paused or the queue has used up its worker share
 	s.scheduleMux.Lock()
-	worker := s.findSuitableWorker(estimatedCPU)
+	var worker *WorkerInfo
+	placement := protocol.PlacementExisting
+	if !s.paused.Load() && s.queues.canRun(queue, estimatedCPU) {
+		worker = s.findSuitableWorker(req, estimatedCPU, loadTime)
 
-	if worker == nil {
-		// Try to spawn a new worker
-		coreID, err := s.orchestrator.GetNextAvailableCore()
-		if err == nil {
-			// Can spawn a worker
-			if _, startErr := s.orchestrator.StartWorker(coreID); startErr == nil {
-				time.Sleep(2 * time.Second)
-				worker, _ = s.orchestrator.GetWorkerByCore(coreID)
+		if worker == nil {
+			// Try to spawn a new worker
+			coreID, err := s.orchestrator.GetNextAvailableCoreWhere(s.spawnableFor(req.Queue, operationName(req), req.Tolerations, loadTime))
+			if err == nil {
+				err = s.scaling.takeSpawn(s.orchestrator.GetWorkerCount())
+				if err != nil {
+					log.Printf("[Scheduler] Not spawning a worker: %v", err)
+				}
+			}
+			if err == nil {
+				// Can spawn a worker
+				s.trace(req, traceSchedule, nil, "spawning a worker on core %d", coreID)
+				if _, startErr := s.orchestrator.StartWorker(coreID); startErr == nil {
+					time.Sleep(2 * time.Second)
+					worker, _ = s.orchestrator.GetWorkerByCore(coreID)
+					placement = protocol.PlacementSpawned
+				} else {
+					s.trace(req, traceSchedule, nil, "worker on core %d failed to start: %v", coreID, startErr)
+				}
+			} else {
+				s.trace(req, traceSchedule, nil, "not spawning a worker: %v", err)
 			}
 		}
 	}
 
 	if worker != nil {
 		// Found a worker - schedule immediately
-		s.orchestrator.UpdateWorkerCPU(worker.CoreID, worker.CurrentCPU+estimatedCPU)
+		s.notifyDecision(telemetryPlaced, req, queue, estimatedCPU, loadTime, worker, placement, 0)
+		reserved := s.orchestrator.AdjustWorkerCPU(worker, estimatedCPU)
+		s.queues.reserve(queue, estimatedCPU)
 		s.scheduleMux.Unlock()
+		s.annotate(req.JobID, func(a *protocol.JobAnnotations) {
+			a.Placement = placement
+			a.Queue = queue
+			a.EstimatedQueueWait = 0
+		})
+		s.annotateWorker(req.JobID, worker)
 
-		log.Printf("[Scheduler] Routing job to Worker-Core-%d (port %d, current_cpu=%.1f%%)",
-			worker.CoreID, worker.HostPort, worker.CurrentCPU)
+		timeout, capped := s.dispatchTimeout(req)
+		log.Printf("[Scheduler] Routing job to Worker-Core-%d (port %d, current_cpu=%.1f%%, timeout=%s, capped=%t)",
+			worker.CoreID, worker.HostPort, reserved, timeout, capped)
+
+		go s.dispatch(worker, job)
+	} else {
+		// No worker available - queue the job
+		s.scheduleMux.Unlock()
+		log.Printf("[Scheduler] All workers busy, queueing job in %q (cpu_load=%.1f%%)", queue, estimatedCPU)
+		estimatedWait := s.estimateQueueWait(queue)
+		s.annotate(req.JobID, func(a *protocol.JobAnnotations) {
+			a.Placement = protocol.PlacementQueued
+			a.Queue = queue
+			a.EstimatedQueueWait = estimatedWait
+		})
+
+		if err := s.queues.enqueue(job); err != nil {
+			return nil, err
+		}
+		s.notifyDecision(telemetryQueued, req, queue, estimatedCPU, loadTime, nil, protocol.PlacementQueued, 0)
+		s.notifyStatus(req.JobID, protocol.StatusQueued)
+	}
+
+	// Wait for the result; the queue processor fails jobs that wait too long
+	select {
+	case response := <-job.responseCh:
+		return response, nil
+	case err := <-job.errorCh:
+		return nil, err
+	}
+}
+
+// applyTimeSlicing marks long checkpointable jobs to run in slices of TimeSlice seconds
+func (s *Scheduler) applyTimeSlicing(req *protocol.ComputeRequest) {
+	if s.config.TimeSlice <= 0 || req.LoadTime <= s.config.TimeSlice || !worker.SupportsCheckpoints(req.Operation) {
+		return
+	}
+	req.SliceTime = s.config.TimeSlice
+	log.Printf("[Scheduler] Job %s will run in %.0fs time slices", req.JobID, req.SliceTime)
+}
+
+// dispatch runs a job on a worker (whose CPU is already reserved) and delivers
+// the result. A sliced job keeps its worker between slices while nothing is
+// waiting; otherwise it yields the worker and rejoins the back of its queue.
+func (s *Scheduler) dispatch(w *WorkerInfo, job *QueuedJob) {
+	for {
+		response, err := s.executeJobOnWorker(w, job.request)
+		if err == nil && response.Checkpoint != nil {
+			job.request.Checkpoint = response.Checkpoint
+			job.slices++
+
+			if s.yieldSlice(w, job) {
+				return
+			}
+			continue
+		}
+
+		s.orchestrator.AdjustWorkerCPU(w, -job.estimatedCPU)
+		s.queues.release(job.queue, job.estimatedCPU)
+		s.wakeQueue()
+
+		if err != nil {
+			job.errorCh <- err
+		} else {
+			if job.slices > 0 {
+				response.Slices = job.slices + 1
+				s.annotate(job.request.JobID, func(a *protocol.JobAnnotations) { a.Slices = response.Slices })
+			}
+			job.responseCh <- response
+		}
 
-		response, err := s.executeJobOnWorker(worker, req)
-		s.orchestrator.UpdateWorkerCPU(worker.CoreID, worker.CurrentCPU-estimatedCPU)
 		s.checkProactiveSpawn()
-		return response, err
+		return
 	}
+}
 
-	// No worker available - queue the job
-	s.scheduleMux.Unlock()
-	log.Printf("[Scheduler] All workers busy, queueing job (cpu_load=%.1f%%)", estimatedCPU)
+// yieldSlice re-queues a checkpointed job if jobs that haven't started yet are
+// waiting, releasing its worker. (Yielding to other sliced jobs would only
+// churn.) Returns false if the job should keep running on the same worker.
+func (s *Scheduler) yieldSlice(w *WorkerInfo, job *QueuedJob) bool {
+	s.scheduleMux.Lock()
+	defer s.scheduleMux.Unlock()
 
-	queuedJob := &QueuedJob{
-		request:      req,
-		responseCh:   make(chan *protocol.JobResponse, 1),
-		errorCh:      make(chan error, 1),
-		enqueuedAt:   time.Now(),
-		estimatedCPU: estimatedCPU,
+	if !s.queues.wantsYield() {
+		return false
+	}
+
+	job.enqueuedAt = time.Now()
+	job.starving = false
+	job.timeoutGrace = 0
+	if err := s.queues.enqueue(job); err != nil {
+		return false // Queue full: carry on rather than lose the job's place on a worker
 	}
 
+	s.orchestrator.AdjustWorkerCPU(w, -job.estimatedCPU)
+	s.queues.release(job.queue, job.estimatedCPU)
+	s.wakeQueue()
+	s.notifyStatus(job.request.JobID, protocol.StatusQueued)
+
+	log.Printf("[Scheduler] Job %s yielded Worker-Core-%d after slice %d (%.0fs of %.0fs done), re-queued in %q",
+		job.request.JobID, w.CoreID, job.slices, job.request.Checkpoint.Elapsed, job.request.LoadTime, job.queue)
+	s.trace(job.request, traceRetry, nil, "yielded Worker-Core-%d to waiting jobs after slice %d (%.0fs done), re-queued in %q",
+		w.CoreID, job.slices, job.request.Checkpoint.Elapsed, job.queue)
+	return true
+}
+
+// wakeQueue has the queue processor run now rather than at its next tick, so a
+// worker that finishes early (e.g. the estimate was high) picks up queued work
+// straight away. Queues are shared by all workers and a job is only bound to a
+// worker at dispatch, so whichever worker frees capacity first takes it.
+func (s *Scheduler) wakeQueue() {
 	select {
-	case s.jobQueue <- queuedJob:
-		// Job queued successfully, wait for response
-		select {
-		case response := <-queuedJob.responseCh:
-			return response, nil
-		case err := <-queuedJob.errorCh:
-			return nil, err
-		case <-time.After(time.Duration(QUEUE_TIMEOUT) * time.Second):
-			return nil, fmt.Errorf("job timed out in queue after %ds", QUEUE_TIMEOUT)
-		}
-	default:
-		return nil, fmt.Errorf("job queue full (max size: %d), cannot accept job", MAX_QUEUE_SIZE)
+	case s.queueWake <- struct{}{}:
+	default: // Already signalled, or queueing disabled
 	}
 }
 
@@ -228,86 +472,168 @@ func (s *Scheduler) processJobQueue() {
 		case <-ticker.C:
 			// Try to process pending jobs
 			s.tryProcessQueue()
+
+		case <-s.queueWake:
+			s.tryProcessQueue()
 		}
 	}
 }
 
-// tryProcessQueue attempts to assign queued jobs to available workers
+// tryProcessQueue assigns queued jobs to available workers, taking turns
+// between named queues by weight
 func (s *Scheduler) tryProcessQueue() {
-	// Process multiple jobs if multiple workers are available
-	for {
-		// Non-blocking check if queue has jobs
-		select {
-		case queuedJob := <-s.jobQueue:
-			// Check if job has timed out
-			if time.Since(queuedJob.enqueuedAt) > time.Duration(QUEUE_TIMEOUT)*time.Second {
-				log.Printf("[Scheduler] Queue job timed out, discarding")
-				queuedJob.errorCh <- fmt.Errorf("job expired in queue")
-				continue // Try next job in queue
-			}
+	for _, timeout := range s.queues.expire() {
+		job := timeout.job
+		s.orchestrator.Metrics().Inc("orchestrator_queue_timeouts_total", "queue", timeout.from, "policy", timeout.policy)
+		if timeout.policy == protocol.QueueTimeoutFail {
+			log.Printf("[Scheduler] Job timed out in queue %q, discarding", job.queue)
+			s.annotate(job.request.JobID, func(a *protocol.JobAnnotations) { a.QueueWait += time.Since(job.enqueuedAt).Seconds() })
+			job.errorCh <- failure(protocol.FailureQueue, fmt.Errorf("job expired in queue %q", job.queue))
+			continue
+		}
 
-			// Try to schedule the queued job
-			s.scheduleMux.Lock()
-			worker := s.findSuitableWorker(queuedJob.estimatedCPU)
+		log.Printf("[Scheduler] Job %s timed out in queue %q, policy %q: now waiting in %q",
+			job.request.JobID, timeout.from, timeout.policy, timeout.queue)
+		s.annotate(job.request.JobID, func(a *protocol.JobAnnotations) { a.Queue = timeout.queue })
+		if sink := s.progressSink(job.request.JobID); sink != nil {
+			sink(protocol.StreamEvent{Type: protocol.StreamEventQueueTimeout, JobID: job.request.JobID, Policy: timeout.policy, Queue: timeout.queue})
+		}
+	}
 
-			if worker != nil {
-				// Worker available - schedule it
-				s.orchestrator.UpdateWorkerCPU(worker.CoreID, worker.CurrentCPU+queuedJob.estimatedCPU)
-				s.scheduleMux.Unlock()
+	for _, job := range s.queues.markStarving() {
+		log.Printf("[Scheduler] Job %s is starving in queue %q (waiting %.1fs)",
+			job.request.JobID, job.queue, time.Since(job.enqueuedAt).Seconds())
+		s.orchestrator.Metrics().Inc("orchestrator_queue_starved_jobs_total", "queue", job.queue)
+	}
 
-				waitTime := time.Since(queuedJob.enqueuedAt)
-				log.Printf("[Scheduler] Dequeued job (waited %.1fs) → Worker-Core-%d",
-					waitTime.Seconds(), worker.CoreID)
+	if s.paused.Load() {
+		return
+	}
 
-				// Execute job asynchronously so we can process more queue items
-				go func(w *WorkerInfo, job *QueuedJob) {
-					response, err := s.executeJobOnWorker(w, job.request)
-					s.orchestrator.UpdateWorkerCPU(w.CoreID, w.CurrentCPU-job.estimatedCPU)
+	s.scheduleMux.Lock()
+	defer s.scheduleMux.Unlock()
 
-					if err != nil {
-						job.errorCh <- err
-					} else {
-						job.responseCh <- response
-					}
+	// Queues whose head job can't be placed this tick
+	blocked := make(map[string]bool)
 
-					s.checkProactiveSpawn()
-				}(worker, queuedJob)
+	// Process multiple jobs if multiple workers are available
+	for {
+		queuedJob := s.queues.peekNext(blocked)
+		if queuedJob == nil {
+			return // Nothing placeable right now
+		}
 
-				// Continue to next queued job immediately
-				continue
-			} else {
-				// Still no worker available - put job back and stop processing this tick
-				s.scheduleMux.Unlock()
-
-				// Try to put job back at front of queue
-				go func(job *QueuedJob) {
-					select {
-					case s.jobQueue <- job:
-						// Requeued successfully
-					case <-time.After(1 * time.Second):
-						// Couldn't requeue - fail the job
-						job.errorCh <- fmt.Errorf("failed to requeue job")
-					}
-				}(queuedJob)
-
-				return // Stop processing this tick
+		worker := s.findSuitableWorker(queuedJob.request, queuedJob.estimatedCPU, queuedJob.duration)
+		if worker == nil {
+			if queuedJob.starving && s.queues.starvationBoost > 1 && queuedJob.estimatedCPU <= s.config.MaxCPUThreshold {
+				// Hold capacity back so freed workers go to the starving job rather
+				// than to smaller jobs that would keep overtaking it
+				return
 			}
+			// Head job doesn't fit anywhere; give other queues a chance
+			blocked[queuedJob.queue] = true
+			continue
+		}
 
-		default:
-			// No more jobs in queue
-			return
+		if !s.queues.remove(queuedJob) {
+			continue // Timed out and withdrawn by its submitter meanwhile
 		}
+
+		// Worker available - schedule it
+		waitTime := time.Since(queuedJob.enqueuedAt)
+		s.notifyDecision(telemetryDequeue, queuedJob.request, queuedJob.queue, queuedJob.estimatedCPU, queuedJob.duration,
+			worker, protocol.PlacementQueued, waitTime)
+		s.orchestrator.AdjustWorkerCPU(worker, queuedJob.estimatedCPU)
+		s.queues.reserve(queuedJob.queue, queuedJob.estimatedCPU)
+
+		s.queues.recordWait(queuedJob.queue, waitTime)
+		s.annotate(queuedJob.request.JobID, func(a *protocol.JobAnnotations) { a.QueueWait += waitTime.Seconds() })
+		s.annotateWorker(queuedJob.request.JobID, worker)
+		log.Printf("[Scheduler] Dequeued job from %q (waited %.1fs) → Worker-Core-%d",
+			queuedJob.queue, waitTime.Seconds(), worker.CoreID)
+
+		// Execute job asynchronously so we can process more queue items
+		go s.dispatch(worker, queuedJob)
 	}
 }
 
-// StopQueueProcessor stops the queue processing goroutine (call on shutdown)
+// StopQueueProcessor stops the queue processing goroutine (call on shutdown).
+// Calls after the first do nothing.
 func (s *Scheduler) StopQueueProcessor() {
 	if ENABLE_JOB_QUEUE {
-		close(s.queueWorkerStop)
-		log.Printf("[Scheduler] Queue processor stopped")
+		s.queueStopOnce.Do(func() {
+			close(s.queueWorkerStop)
+			log.Printf("[Scheduler] Queue processor stopped")
+		})
+	}
+}
+
+// Pause stops dispatching jobs; new and queued jobs wait until Resume
+func (s *Scheduler) Pause() {
+	if !s.paused.Swap(true) {
+		log.Printf("[Scheduler] Paused: jobs will queue but not be dispatched")
 	}
 }
 
+// Resume restarts dispatching after Pause
+func (s *Scheduler) Resume() {
+	if s.paused.Swap(false) {
+		log.Printf("[Scheduler] Resumed")
+	}
+}
+
+// IsPaused reports whether dispatching is paused
+func (s *Scheduler) IsPaused() bool {
+	return s.paused.Load()
+}
+
+// HasCapacity reports whether a job could start now: some worker is below the
+// CPU threshold or a free core is available to spawn one
+func (s *Scheduler) HasCapacity() bool {
+	for _, worker := range s.placeableWorkers(0, "", "", nil) {
+		if worker.CurrentCPU < s.config.MaxCPUThreshold*worker.effectiveCapacity() {
+			return true
+		}
+	}
+	_, err := s.orchestrator.GetNextAvailableCore()
+	return err == nil
+}
+
+// QueueAccepting reports whether the default queue has room for another job
+func (s *Scheduler) QueueAccepting() bool {
+	return ENABLE_JOB_QUEUE && s.queues.accepting(s.queues.defaultQueue)
+}
+
+// QueueLength returns the number of jobs currently queued across all queues
+func (s *Scheduler) QueueLength() int {
+	if !ENABLE_JOB_QUEUE {
+		return 0
+	}
+	return s.queues.length()
+}
+
+// QueueLengths returns the number of jobs waiting in each named queue
+func (s *Scheduler) QueueLengths() map[string]int {
+	if !ENABLE_JOB_QUEUE {
+		return map[string]int{}
+	}
+	return s.queues.lengths()
+}
+
+// QueueFairness returns each queue's recent wait distribution and starving jobs
+func (s *Scheduler) QueueFairness() map[string]QueueFairness {
+	if !ENABLE_JOB_QUEUE {
+		return map[string]QueueFairness{}
+	}
+	return s.queues.fairness()
+}
+
+// HasQueue reports whether a queue name is valid ("" selects the default queue)
+func (s *Scheduler) HasQueue(name string) bool {
+	_, err := s.queues.resolve(name)
+	return err == nil
+}
+
 // GetQueueStatus returns current queue statistics
 func (s *Scheduler) GetQueueStatus() map[string]interface{} {
 	if !ENABLE_JOB_QUEUE {
@@ -317,10 +643,11 @@ func (s *Scheduler) GetQueueStatus() map[string]interface{} {
 	}
 
 	return map[string]interface{}{
-		"enabled":    true,
-		"queue_size": len(s.jobQueue),
-		"max_size":   MAX_QUEUE_SIZE,
-		"timeout":    QUEUE_TIMEOUT,
+		"enabled":       true,
+		"queue_size":    s.queues.length(),
+		"default_queue": s.queues.defaultQueue,
+		"queues":        s.queues.status(),
+		"fairness":      s.queues.fairness(),
 	}
 }
 
@@ -328,72 +655,262 @@ func (s *Scheduler) GetQueueStatus() map[string]interface{} {
 // END OF JOB QUEUING IMPLEMENTATION
 // ============================================================================
 
-// findSuitableWorker locates a worker that can handle the estimated CPU load
-func (s *Scheduler) findSuitableWorker(estimatedCPU float64) *WorkerInfo {
-	workers := s.orchestrator.GetAllWorkers()
+// findSuitableWorker locates a worker that can handle the estimated CPU load for
+// duration seconds without running into a maintenance window, using the
+// placement strategy assigned to the job. Degraded cores are a last resort. A job
+// that must run alone claims its worker's physical cores.
+func (s *Scheduler) findSuitableWorker(req *protocol.ComputeRequest, estimatedCPU, duration float64) *WorkerInfo {
+	workers := s.placeableWorkers(duration, req.Queue, operationName(req), req.Tolerations)
+	isolated := s.interference.Isolated(operationName(req), req.NoSMTSharing)
+	if allowed := s.smtAllowed(isolated); allowed != nil {
+		workers = slices.DeleteFunc(workers, func(w *WorkerInfo) bool { return !allowed(w) })
+	}
 
 	if len(workers) == 0 {
+		s.traceCandidates(req, workers, estimatedCPU, nil)
 		return nil
 	}
 
-	// Strategy: Find worker with lowest current CPU that can accommodate the request
-	var bestWorker *WorkerInfo
-	var lowestCPU float64 = 101.0 // Start above 100%
-
-	for _, worker := range workers {
-		projectedCPU := worker.CurrentCPU + estimatedCPU
-
-		// Check if this worker can handle the load without exceeding threshold
-		if projectedCPU <= s.config.MaxCPUThreshold {
-			if worker.CurrentCPU < lowestCPU {
-				lowestCPU = worker.CurrentCPU
-				bestWorker = worker
-			}
-		}
+	strategy, known := placementStrategies[s.jobStrategy(req.JobID)]
+	if !known {
+		strategy = placementStrategies[placementStrategy]
 	}
-
-	return bestWorker
+	healthy, degraded := s.degradation.partition(workers)
+	worker := strategy(healthy, estimatedCPU, s.config.MaxCPUThreshold)
+	if worker == nil {
+		worker = strategy(degraded, estimatedCPU, s.config.MaxCPUThreshold)
+	}
+	if worker != nil && isolated {
+		s.interference.Claim(req.JobID, worker.CoreID)
+	}
+	s.traceCandidates(req, workers, estimatedCPU, worker)
+	return worker
 }
 
 // executeJobOnWorker sends the job request to a specific worker via HTTP
 func (s *Scheduler) executeJobOnWorker(worker *WorkerInfo, req *protocol.ComputeRequest) (*protocol.JobResponse, error) {
-	url := fmt.Sprintf("http://localhost:%d/submit", worker.HostPort)
+	url := worker.BaseURL + "/submit"
 
-	payload, err := json.Marshal(req)
+	// Workers that agreed to it at the handshake take protobuf, except for streamed jobs
+	contentType := "application/json"
+	marshal := func(req *protocol.ComputeRequest) ([]byte, error) { return json.Marshal(req) }
+	if worker.Wire == protocol.WireProtobuf && !req.Stream && !req.Debug {
+		contentType, marshal = protocol.ContentTypeProtobuf, protocol.MarshalRequestProto
+	}
+	wire := req
+	if req.Debug && !req.Stream {
+		// Debug jobs stream from the worker so their trace gets progress samples
+		streamed := *req
+		streamed.Stream = true
+		wire = &streamed
+	}
+	payload, err := marshal(wire)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	// Set dynamic timeout: job duration + 10 second buffer for overhead
-	jobTimeout := time.Duration(req.LoadTime)*time.Second + 10*time.Second
+	jobTimeout, _ := s.dispatchTimeout(req)
 	ctx, cancel := context.WithTimeout(context.Background(), jobTimeout)
 	defer cancel()
 
+	// Jobs taking partial results are stopped on their worker when cancelled or
+	// out of time, and the connection is kept open for what they have so far
+	callCtx := ctx
+	if req.PartialResults {
+		var hangUp context.CancelFunc
+		callCtx, hangUp = context.WithCancel(context.Background())
+		defer hangUp()
+		defer context.AfterFunc(ctx, func() { s.stopOnWorker(worker, req.JobID, hangUp) })()
+	}
+
+	s.trackStart(worker, req, cancel)
+	defer s.trackFinish(req)
+
+	jobResp, err := s.callWorker(callCtx, url, contentType, payload, req)
+	if err != nil {
+		if errors.Is(ctx.Err(), context.Canceled) {
+			return nil, ErrJobCancelled
+		}
+		// A dead worker explains the failure better than the broken connection does
+		if diag := s.orchestrator.CheckWorkerExit(worker.CoreID, worker.ContainerID); diag != nil {
+			err = failure(protocol.FailureWorkerExit, fmt.Errorf("worker on core %d exited (%s): %w", worker.CoreID, diag, err))
+		} else if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			err = failure(protocol.FailureTimeout, fmt.Errorf("job exceeded its %s dispatch timeout: %w", jobTimeout, err))
+		}
+		s.orchestrator.RecordDispatch(worker.CoreID, err)
+		return nil, err
+	}
+	s.orchestrator.RecordDispatch(worker.CoreID, nil)
+
+	if worker.SigningKey != nil {
+		if err := s.verifyResult(worker, req, jobResp); err != nil {
+			return nil, failure(protocol.FailureWorkerError, err)
+		}
+	}
+	if jobResp.WorkerUUID == "" {
+		jobResp.WorkerUUID = worker.UUID // Workers from older images don't report it
+	}
+	s.degradation.Record(worker.CoreID, req, jobResp)
+	s.interference.Record(req, jobResp)
+	if jobResp.Partial {
+		log.Printf("[Scheduler] Job %s stopped early on Worker-Core-%d; returning its partial result", req.JobID, worker.CoreID)
+	}
+
+	resultType := protocol.ResultTypeFloat
+	if jobResp.Output != nil {
+		resultType = jobResp.Output.Type
+	}
+	log.Printf("[Scheduler] Job completed: job_id=%s, worker=%s, result=%.6f (%s), duration=%s",
+		jobResp.JobID, jobResp.WorkerID, jobResp.Result, resultType, jobResp.TimeTaken)
+
+	return jobResp, nil
+}
+
+// verifyResult checks a result's signature against the key provisioned for the
+// worker's container, and completes it with the key and image for consumers
+func (s *Scheduler) verifyResult(worker *WorkerInfo, req *protocol.ComputeRequest, resp *protocol.JobResponse) error {
+	if _, err := signing.VerifyResult(worker.SigningKey, req, resp); err != nil {
+		s.orchestrator.Metrics().Inc("orchestrator_result_signature_failures_total")
+		log.Printf("[WARNING] Rejecting result of job %s from Worker-Core-%d: %v", req.JobID, worker.CoreID, err)
+		return fmt.Errorf("result from worker on core %d failed signature verification: %w", worker.CoreID, err)
+	}
+	resp.Signature.PublicKey = base64.StdEncoding.EncodeToString(worker.SigningKey)
+	resp.Signature.ImageID = worker.ImageID
+	resp.Signature.Verified = true
+	return nil
+}
+
+// partialResultGrace is how long a stopped job's worker has to return its partial result
+const partialResultGrace = 5 * time.Second
+
+// stopOnWorker asks worker to end jobID early and reply with its partial result,
+// hanging up if it can't or doesn't within partialResultGrace
+func (s *Scheduler) stopOnWorker(worker *WorkerInfo, jobID string, hangUp context.CancelFunc) {
+	time.AfterFunc(partialResultGrace, hangUp)
+
+	ctx, cancel := context.WithTimeout(context.Background(), partialResultGrace)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, worker.BaseURL+"/jobs/"+url.PathEscape(jobID)+"/stop", nil)
+	if err != nil {
+		hangUp()
+		return
+	}
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		log.Printf("[Scheduler] Failed to stop job %s on Worker-Core-%d early: %v", jobID, worker.CoreID, err)
+		hangUp()
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted {
+		log.Printf("[Scheduler] Worker-Core-%d couldn't stop job %s early (status %d)", worker.CoreID, jobID, resp.StatusCode)
+		hangUp()
+	}
+}
+
+// dispatchTimeoutMargin covers HTTP and scheduling overhead on top of the estimated run time
+const dispatchTimeoutMargin = 10 * time.Second
+
+// dispatchTimeout derives the worker request timeout from the estimated duration of
+// this run (one slice for sliced jobs), clamped to the configured bounds. capped
+// reports that the maximum cut the timeout short of the estimate.
+func (s *Scheduler) dispatchTimeout(req *protocol.ComputeRequest) (timeout time.Duration, capped bool) {
+	work := s.estimator.EstimateJobDuration(req)
+	if req.Checkpoint != nil {
+		work -= req.Checkpoint.Elapsed
+	}
+	if req.SliceTime > 0 && req.SliceTime < work {
+		work = req.SliceTime
+	}
+
+	timeout = time.Duration(work*float64(time.Second)) + dispatchTimeoutMargin
+	if minTimeout := time.Duration(s.config.DispatchTimeoutMin * float64(time.Second)); timeout < minTimeout {
+		timeout = minTimeout
+	}
+	if maxTimeout := time.Duration(s.config.DispatchTimeoutMax * float64(time.Second)); maxTimeout > 0 && timeout > maxTimeout {
+		return maxTimeout, true
+	}
+	return timeout, false
+}
+
+// callWorker POSTs the job and decodes the worker's reply, relaying progress
+// events to the job's sink when the worker streams NDJSON
+func (s *Scheduler) callWorker(ctx context.Context, url, contentType string, payload []byte, req *protocol.ComputeRequest) (*protocol.JobResponse, error) {
 	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(payload))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create HTTP request: %w", err)
 	}
-	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Content-Type", contentType)
+	if contentType == protocol.ContentTypeProtobuf {
+		httpReq.Header.Set("Accept", protocol.ContentTypeProtobuf)
+	}
+	s.trace(req, traceDispatch, traceHeaders("", httpReq.Header), "POST %s (%d bytes)", url, len(payload))
 
+	sent := time.Now()
 	resp, err := s.httpClient.Do(httpReq)
 	if err != nil {
-		return nil, fmt.Errorf("worker communication failed: %w", err)
+		s.trace(req, traceDispatch, nil, "request failed after %s: %v", time.Since(sent).Round(time.Millisecond), err)
+		return nil, failure(protocol.FailureConnection, fmt.Errorf("worker communication failed: %w", err))
 	}
 	defer resp.Body.Close()
+	s.trace(req, traceDispatch, traceHeaders(resp.Status, resp.Header), "worker answered %s after %s", resp.Status, time.Since(sent).Round(time.Millisecond))
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("worker returned status %d", resp.StatusCode)
+		// 503 is a draining worker refusing the job, not a verdict on the job
+		kind := protocol.FailureWorkerError
+		if resp.StatusCode == http.StatusServiceUnavailable {
+			kind = protocol.FailureConnection
+		}
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, failure(kind, fmt.Errorf("worker returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(body))))
 	}
 
-	var jobResp protocol.JobResponse
-	if err := json.NewDecoder(resp.Body).Decode(&jobResp); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
+	if resp.Header.Get("Content-Type") == protocol.ContentTypeProtobuf {
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, failure(protocol.FailureConnection, fmt.Errorf("failed to read response: %w", err))
+		}
+		jobResp, err := protocol.UnmarshalResponseProto(body)
+		if err != nil {
+			return nil, failure(protocol.FailureConnection, fmt.Errorf("failed to decode response: %w", err))
+		}
+		return &jobResp, nil
+	}
+	if !strings.HasPrefix(resp.Header.Get("Content-Type"), "application/x-ndjson") {
+		var jobResp protocol.JobResponse
+		if err := json.NewDecoder(resp.Body).Decode(&jobResp); err != nil {
+			return nil, failure(protocol.FailureConnection, fmt.Errorf("failed to decode response: %w", err))
+		}
+		return &jobResp, nil
 	}
 
-	log.Printf("[Scheduler] Job completed: job_id=%s, worker=%s, result=%.6f, duration=%s",
-		jobResp.JobID, jobResp.WorkerID, jobResp.Result, jobResp.TimeTaken)
+	decoder := json.NewDecoder(resp.Body)
+	var sampled time.Time // Last progress event traced
+	for {
+		var event protocol.StreamEvent
+		if err := decoder.Decode(&event); err != nil {
+			return nil, failure(protocol.FailureConnection, fmt.Errorf("worker stream ended without a result: %w", err))
+		}
 
-	return &jobResp, nil
+		switch event.Type {
+		case protocol.StreamEventProgress:
+			s.trackProgress(req.JobID, event.Iterations)
+			if sink := s.progressSink(req.JobID); sink != nil {
+				sink(event)
+			}
+			if req.Debug && time.Since(sampled) >= traceProgressInterval {
+				sampled = time.Now()
+				s.trace(req, traceProgress, nil, "%d iterations after %s", event.Iterations, time.Since(sent).Round(time.Millisecond))
+			}
+		case protocol.StreamEventResult:
+			if event.Response == nil {
+				return nil, fmt.Errorf("worker sent an empty result event")
+			}
+			return event.Response, nil
+		case protocol.StreamEventError:
+			return nil, failure(protocol.FailureWorkerError, fmt.Errorf("worker error: %s", event.Error))
+		}
+	}
 }
 
 // checkProactiveSpawn spawns a new worker if all active workers are near threshold
@@ -407,7 +924,7 @@ func (s *Scheduler) checkProactiveSpawn() {
 	// Check if all workers are above pre-spawn threshold
 	allBusy := true
 	for _, worker := range workers {
-		if worker.CurrentCPU < s.config.PreSpawnThreshold {
+		if worker.CurrentCPU < s.config.PreSpawnThreshold*worker.effectiveCapacity() {
 			allBusy = false
 			break
 		}
@@ -424,6 +941,11 @@ func (s *Scheduler) checkProactiveSpawn() {
 		return
 	}
 
+	if err := s.scaling.takeSpawn(len(workers)); err != nil {
+		log.Printf("[Scheduler] Proactive spawn skipped: %v", err)
+		return
+	}
+
 	log.Printf("[Scheduler] All workers above %.0f%% threshold, proactively spawning worker on Core %d",
 		s.config.PreSpawnThreshold, coreID)
 
@@ -438,13 +960,28 @@ func (s *Scheduler) GetWorkerStatus() []map[string]interface{} {
 
 	status := make([]map[string]interface{}, 0, len(workers))
 	for _, worker := range workers {
-		status = append(status, map[string]interface{}{
+		entry := map[string]interface{}{
 			"core_id":      worker.CoreID,
 			"container_id": worker.ContainerID[:12],
+			"worker_uuid":  worker.UUID,
 			"host_port":    worker.HostPort,
+			"base_url":     worker.BaseURL,
 			"cpu_usage":    fmt.Sprintf("%.1f%%", worker.CurrentCPU),
-			"is_healthy":   worker.IsHealthy,
-		})
+			"cpu_reserved": fmt.Sprintf("%.1f%%", worker.ReservedCPU),
+			"capacity":     worker.capacity(),
+			"health":       worker.Health,
+			"degraded":     s.degradation.Degraded(worker.CoreID),
+			"taints":       s.taints.On(worker.CoreID),
+			"sandbox":      s.sandbox.Runtime(worker.CoreID),
+			"version":      worker.Version,
+			"arch":         worker.Arch,
+			"wire":         cmp.Or(worker.Wire, protocol.WireJSON),
+		}
+		if !worker.MeasuredAt.IsZero() {
+			entry["cpu_measured"] = fmt.Sprintf("%.1f%%", worker.MeasuredCPU)
+			entry["cpu_measured_at"] = worker.MeasuredAt
+		}
+		status = append(status, entry)
 	}
 
 	return status
@@ -0,0 +1,129 @@
+package gateway
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// GatewayPinning is the CPUs and scheduling priorities the gateway process runs with
+type GatewayPinning struct {
+	CPUs       string   `json:"cpus,omitempty"`        // Cpuset its threads are pinned to ("" = unpinned)
+	Nice       int      `json:"nice"`                  // Niceness (0 = unchanged)
+	IOPriority string   `json:"io_priority,omitempty"` // "class[:level]" ("" = unchanged)
+	Errors     []string `json:"errors,omitempty"`      // Settings that couldn't be applied
+}
+
+// ioPriorityClasses are the I/O scheduling classes, numbered as ioprio_set takes them
+var ioPriorityClasses = map[string]int{"realtime": 1, "best-effort": 2, "idle": 3}
+
+// parseIOPriority parses "class[:level]", with levels 0 (highest) to 7 (default 4)
+func parseIOPriority(spec string) (class, level int, err error) {
+	name, levelSpec, hasLevel := strings.Cut(spec, ":")
+	class, known := ioPriorityClasses[name]
+	if !known {
+		return 0, 0, fmt.Errorf("unknown I/O priority class %q (valid: realtime, best-effort, idle)", name)
+	}
+	level = 4
+	if hasLevel {
+		if level, err = strconv.Atoi(levelSpec); err != nil || level < 0 || level > 7 {
+			return 0, 0, fmt.Errorf("invalid I/O priority level %q (valid: 0-7)", levelSpec)
+		}
+	}
+	return class, level, nil
+}
+
+// PinGateway pins the gateway's own threads to GATEWAY_CPUSET and applies
+// GATEWAY_NICE and GATEWAY_IO_PRIORITY, so that heavy worker load can't starve
+// the control plane. A setting that can't be applied, e.g. raising priority
+// without CAP_SYS_NICE, is logged and reported in /status, and the gateway runs
+// on without it.
+func (o *Orchestrator) PinGateway() {
+	o.mu.RLock()
+	p := o.pinning
+	o.mu.RUnlock()
+	if p.CPUs == "" && p.Nice == 0 && p.IOPriority == "" {
+		return
+	}
+
+	fail := func(setting string, err error) {
+		log.Printf("[WARNING] Gateway %s not applied: %v", setting, err)
+		p.Errors = append(p.Errors, fmt.Sprintf("%s: %v", setting, err))
+	}
+
+	if p.CPUs != "" {
+		cpus, err := parseCpuset(p.CPUs)
+		if err == nil {
+			err = repin(os.Getpid(), cpus)
+		}
+		if err != nil {
+			fail("cpuset "+p.CPUs, err)
+			p.CPUs = ""
+		} else {
+			// Threads the runtime starts later inherit the affinity; it just needn't start more than fit
+			if os.Getenv("GOMAXPROCS") == "" {
+				runtime.GOMAXPROCS(len(cpus))
+			}
+			log.Printf("[Gateway] Pinned to CPUs %s", p.CPUs)
+			for coreID, cpuset := range coreMaps {
+				theirs, _ := parseCpuset(cpuset)
+				for _, cpu := range cpus {
+					if slices.Contains(theirs, cpu) {
+						log.Printf("[WARNING] Core %d's workers (cpuset %s) share CPU %d with the gateway", coreID, cpuset, cpu)
+					}
+				}
+			}
+		}
+	}
+
+	if p.Nice != 0 {
+		if err := setNice(p.Nice); err != nil {
+			fail(fmt.Sprintf("niceness %d", p.Nice), err)
+			p.Nice = 0
+		} else {
+			log.Printf("[Gateway] Running at niceness %d", p.Nice)
+		}
+	}
+
+	if p.IOPriority != "" {
+		class, level, err := parseIOPriority(p.IOPriority)
+		if err == nil {
+			err = setIOPriority(class, level)
+		}
+		if err != nil {
+			fail("I/O priority "+p.IOPriority, err)
+			p.IOPriority = ""
+		} else {
+			log.Printf("[Gateway] Running at I/O priority %s", p.IOPriority)
+		}
+	}
+
+	o.mu.Lock()
+	o.pinning = p
+	o.mu.Unlock()
+}
+
+// GatewayPinning reports the gateway's own CPUs and priorities, as applied
+func (o *Orchestrator) GatewayPinning() GatewayPinning {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	return o.pinning
+}
+
+// gatewayCPULocked returns a CPU of cpus the gateway is pinned to, or -1 (caller holds o.mu)
+func (o *Orchestrator) gatewayCPULocked(cpus []int) int {
+	if o.pinning.CPUs == "" {
+		return -1
+	}
+	reserved, _ := parseCpuset(o.pinning.CPUs)
+	for _, cpu := range cpus {
+		if slices.Contains(reserved, cpu) {
+			return cpu
+		}
+	}
+	return -1
+}
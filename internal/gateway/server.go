@@ -1,40 +1,283 @@
 package gateway
 
 import (
+	"context"
+	"crypto/subtle"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"math"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/ahmadhassan44/container-orchestrator/internal/worker"
+	"github.com/ahmadhassan44/container-orchestrator/pkg/config"
 	"github.com/ahmadhassan44/container-orchestrator/pkg/protocol"
+	"github.com/ahmadhassan44/container-orchestrator/pkg/signing"
+	"github.com/ahmadhassan44/container-orchestrator/pkg/version"
+)
+
+const (
+	// defaultWaitTimeout and maxWaitTimeout bound how long GET /jobs/{id}/wait blocks
+	defaultWaitTimeout = 30 * time.Second
+	maxWaitTimeout     = 5 * time.Minute
+
+	// maxSubmitBytes bounds a POST /submit or /estimate body, as a forwarded job's
+	// body is bounded; large matrices are better given by matrix.size
+	maxSubmitBytes = 1 << 20
 )
 
 // Server handles HTTP requests from clients
 type Server struct {
-	scheduler *Scheduler
-	port      int
+	scheduler   *Scheduler
+	jobs        *JobStore
+	batches     *BatchStore
+	manifests   *ManifestStore
+	sources     *SourceTracker
+	metrics     *Metrics
+	federation  *Federation
+	health      *HealthChecker
+	quotas      *QuotaTracker
+	warmup      *WarmUp
+	intake      *Intake
+	replication *Replication
+	public      *PublicStatusPage // nil unless PUBLIC_STATUS or PUBLIC_STATUS_PORT is set
+	limiter     *ConcurrencyLimiter
+	webhooks    *WebhookNotifier // nil unless WEBHOOK_URL is set
+	email       *EmailNotifier   // nil unless SMTP_HOST is set
+	timing      *TimingPolicies
+	history     *JobHistory
+	telemetry   *TelemetryExporter // nil unless TELEMETRY_FILE is set
+	slo         *SLOTracker
+	listener    *ListenerManager
+	port        int
+	adminToken  string
+
+	cancelOnDisconnect bool // Abandoned /submit jobs are cancelled
+
+	metricsHistory *MetricsHistory // Per-minute rollups behind GET /metrics/history
+
+	hooks admissionHooks // Embedders' admission and completion hooks
+
+	internalVerifier *signing.Verifier // Checks signed requests on the internal listener
+	forwardVerifier  *signing.Verifier // Checks jobs forwarded by peer gateways
+	callbackSecret   string            // Signs compensation callbacks (empty = unsigned)
 }
 
-func NewServer(sched *Scheduler, port int) *Server {
-	return &Server{
-		scheduler: sched,
-		port:      port,
+func NewServer(sched *Scheduler, cfg *config.Config) *Server {
+	s := &Server{
+		scheduler:   sched,
+		jobs:        NewJobStore(cfg.JobHistorySize),
+		batches:     NewBatchStore(cfg.JobHistorySize),
+		manifests:   NewManifestStore(cfg.JobHistorySize),
+		sources:     NewSourceTracker(cfg.TrustProxyHeaders),
+		metrics:     sched.orchestrator.Metrics(),
+		federation:  NewFederation(cfg),
+		health:      NewHealthChecker(sched.orchestrator),
+		quotas:      NewQuotaTracker(cfg.QuotaCPUSeconds, cfg.QuotaWindow),
+		warmup:      NewWarmUp(sched, cfg),
+		intake:      NewIntake(cfg),
+		replication: NewReplication(cfg),
+		public:      NewPublicStatusPage(cfg),
+		limiter: NewConcurrencyLimiter(cfg.AdaptiveConcurrency, cfg.ConcurrencyLimitInitial,
+			cfg.ConcurrencyLimitMin, cfg.ConcurrencyLimitMax, sched.orchestrator.Metrics()),
+		webhooks:   NewWebhookNotifier(cfg, sched.orchestrator.Metrics()),
+		email:      NewEmailNotifier(cfg, sched.orchestrator.Metrics()),
+		timing:     NewTimingPolicies(cfg),
+		history:    NewJobHistory(cfg),
+		telemetry:  NewTelemetryExporter(cfg, sched.orchestrator.Metrics()),
+		slo:        NewSLOTracker(cfg, sched.orchestrator.Metrics()),
+		listener:   NewListenerManager(cfg),
+		port:       cfg.GatewayPort,
+		adminToken: cfg.AdminToken,
+
+		cancelOnDisconnect: cfg.CancelOnDisconnect,
+
+		metricsHistory: NewMetricsHistory(cfg),
+
+		internalVerifier: signing.NewVerifier(time.Duration(cfg.SignatureTolerance) * time.Second),
+		forwardVerifier:  signing.NewVerifier(time.Duration(cfg.SignatureTolerance) * time.Second),
+		callbackSecret:   cfg.WebhookSecret,
 	}
+	s.registerMetrics()
+	s.jobs.SetTransitionListener(func(job JobRecord) {
+		s.history.RecordJob(job)
+		s.metricsHistory.RecordJob(job)
+		sched.experiment.RecordOutcome(job)
+		s.slo.RecordJob(job)
+		if s.telemetry != nil {
+			s.telemetry.RecordOutcome(job)
+		}
+		if s.webhooks != nil {
+			s.webhooks.Notify(job)
+		}
+		if s.email != nil {
+			s.email.Notify(job)
+		}
+		s.hooks.completed(job)
+	})
+	if s.webhooks != nil {
+		s.slo.SetAlertListener(s.webhooks.Alert)
+		sched.degradation.SetAlertListener(s.webhooks.DegradationAlert)
+		sched.orchestrator.SetDaemonListener(s.webhooks.DaemonAlert)
+	}
+	if s.telemetry != nil {
+		sched.SetDecisionListener(s.telemetry.RecordDecision)
+	}
+	sched.orchestrator.SetSpawnListener(s.history.RecordSpawn)
+	sched.SetStatusListener(s.jobs.SetStatus)
+	sched.SetTraceListener(s.jobs.AppendTrace)
+	sched.SetUsageListener(s.quotas.RecordUsage)
+	sched.SetScaleDownFloor(s.warmup.Target)
+	s.restoreHeldJobs()
+	return s
+}
+
+// registerMetrics declares gateway-level metrics and the scrape-time collector
+func (s *Server) registerMetrics() {
+	s.metrics.Register("orchestrator_jobs_total", metricCounter, "Jobs handled by the gateway, by final status")
+	s.metrics.Register("orchestrator_workers", metricGauge, "Active worker containers")
+	s.metrics.Register("orchestrator_worker_cpu_percent", metricGauge, "Tracked CPU usage per worker")
+	s.metrics.Register("orchestrator_worker_health", metricGauge, "Composite health score per worker (0-1)")
+	s.metrics.Register("orchestrator_queue_depth", metricGauge, "Jobs waiting in the queue")
+	s.metrics.Register("orchestrator_queue_wait_seconds", metricGauge, "Recent queue waits of dispatched jobs, by queue and quantile")
+	s.metrics.Register("orchestrator_queue_starving_jobs", metricGauge, "Queued jobs past their queue's starvation threshold")
+	s.metrics.Register("orchestrator_internal_rejected_total", metricCounter, "Internal requests rejected for their signature, by reason")
+	s.metrics.Register("orchestrator_replication_lag_seconds", metricGauge, "Seconds since a standby's last batch from its primary")
+	s.metrics.Register("orchestrator_replication_standbys", metricGauge, "Standbys streaming from this gateway")
+
+	s.metrics.AddCollector(func(m *Metrics) {
+		workers := s.scheduler.orchestrator.GetAllWorkers()
+		m.Set("orchestrator_workers", float64(len(workers)))
+		m.Reset("orchestrator_worker_cpu_percent")
+		m.Reset("orchestrator_worker_health")
+		for _, worker := range workers {
+			m.Set("orchestrator_worker_cpu_percent", worker.CurrentCPU, "core", strconv.Itoa(worker.CoreID))
+			m.Set("orchestrator_worker_health", worker.Health.Score, "core", strconv.Itoa(worker.CoreID))
+		}
+		for queue, depth := range s.scheduler.QueueLengths() {
+			m.Set("orchestrator_queue_depth", float64(depth), "queue", queue)
+		}
+		for queue, f := range s.scheduler.QueueFairness() {
+			m.Set("orchestrator_queue_wait_seconds", f.WaitP50, "queue", queue, "quantile", "0.5")
+			m.Set("orchestrator_queue_wait_seconds", f.WaitP90, "queue", queue, "quantile", "0.9")
+			m.Set("orchestrator_queue_wait_seconds", f.WaitP99, "queue", queue, "quantile", "0.99")
+			m.Set("orchestrator_queue_starving_jobs", float64(f.Starving), "queue", queue)
+		}
+		m.Set("orchestrator_replication_standbys", float64(s.replication.Standbys()))
+		if s.replication.Following() {
+			m.Set("orchestrator_replication_lag_seconds", s.replication.lag())
+		}
+	})
 }
 
-// Start begins listening for HTTP requests
+// StartWarmUp begins learning hourly load and pre-spawning ahead of daily peaks
+func (s *Server) StartWarmUp() {
+	s.warmup.Start()
+}
+
+// Start begins listening for HTTP requests. The listener can be swapped later
+// with ReloadListener.
 func (s *Server) Start() error {
+	return s.listener.Serve(s.Handler())
+}
+
+// Handler builds the gateway's HTTP handler with all routes and middleware
+func (s *Server) Handler() http.Handler {
 	mux := http.NewServeMux()
 
 	mux.HandleFunc("/submit", s.handleSubmit)
+	mux.HandleFunc("POST /estimate", s.handleEstimate)
 	mux.HandleFunc("/health", s.handleHealth)
+	mux.HandleFunc("/healthz", s.handleLiveness)
+	mux.HandleFunc("/readyz", s.handleReadiness)
 	mux.HandleFunc("/status", s.handleStatus)
+	mux.HandleFunc("/version", s.handleVersion)
+	mux.HandleFunc("GET /slo", s.handleSLO)
+	if s.public != nil && s.public.onMain {
+		mux.HandleFunc("GET /public/status", s.handlePublicStatus)
+	}
+	mux.HandleFunc("/metrics", s.handleMetrics)
+	mux.HandleFunc("GET /metrics/history", s.handleMetricsHistory)
+	mux.HandleFunc("/cluster/status", s.handleClusterStatus)
+	mux.HandleFunc("/cluster/metrics", s.handleClusterMetrics)
 	mux.HandleFunc("/queue", s.handleQueueStatus) // New endpoint for queue status
+	mux.HandleFunc("GET /queue/insights", s.handleQueueInsights)
+	mux.HandleFunc("GET /jobs", s.handleListJobs)
+	mux.HandleFunc("GET /jobs/active", s.handleActiveJobs)
+	mux.HandleFunc("GET /jobs/{id}", s.handleGetJob)
+	mux.HandleFunc("GET /jobs/{id}/logs", s.handleGetJobLogs)
+	mux.HandleFunc("GET /jobs/{id}/trace", s.handleGetJobTrace)
+	mux.HandleFunc("GET /jobs/{id}/wait", s.handleWaitJob)
+	mux.HandleFunc("POST /jobs/{id}/cancel", s.handleCancelJob)
+	mux.HandleFunc("PATCH /jobs/{id}", s.handlePatchJob)
+	mux.HandleFunc("DELETE /jobs", s.handleCancelJobs)
+	mux.HandleFunc("POST /batches", s.handleSubmitBatch)
+	mux.HandleFunc("GET /batches/{id}", s.handleGetBatch)
+	mux.HandleFunc("DELETE /batches/{id}", s.handleCancelBatch)
+	mux.HandleFunc("POST /submit/manifest", s.handleSubmitManifest)
+	mux.HandleFunc("GET /manifests/{id}", s.handleGetManifestRun)
+	mux.HandleFunc("DELETE /manifests/{id}", s.handleCancelManifestRun)
+	mux.HandleFunc("GET /quota", s.handleQuota)
+	mux.HandleFunc("GET /workers", s.handleWorkers)
+	mux.HandleFunc("GET /workers/{core}/stats", s.handleWorkerStats)
+	mux.HandleFunc("GET /workers/{core}/proxy/{path...}", s.adminOnly(s.handleWorkerProxy))
+	mux.HandleFunc("POST /workers/{core}/proxy/{path...}", s.adminOnly(s.handleWorkerProxy))
 
-	addr := fmt.Sprintf(":%d", s.port)
-	log.Printf("[Gateway] HTTP server listening on %s", addr)
+	// Admin endpoints
+	mux.HandleFunc("GET /admin/denylist", s.adminOnly(s.handleGetDenylist))
+	mux.HandleFunc("POST /admin/denylist", s.adminOnly(s.handleAddDenylist))
+	mux.HandleFunc("DELETE /admin/denylist/{entry}", s.adminOnly(s.handleRemoveDenylist))
+	mux.HandleFunc("POST /admin/scheduler/pause", s.adminOnly(s.handlePauseScheduler))
+	mux.HandleFunc("POST /admin/scheduler/resume", s.adminOnly(s.handleResumeScheduler))
+	mux.HandleFunc("GET /admin/shutdown/plan", s.adminOnly(s.handleShutdownPlan))
+	mux.HandleFunc("POST /admin/images/gc", s.adminOnly(s.handleImageGC))
+	mux.HandleFunc("GET /admin/janitor", s.adminOnly(s.handleJanitor))
+	mux.HandleFunc("POST /admin/janitor/run", s.adminOnly(s.handleRunJanitor))
+	mux.HandleFunc("GET /admin/warmup", s.adminOnly(s.handleWarmUpStatus))
+	mux.HandleFunc("PUT /admin/warmup/override", s.adminOnly(s.handleSetWarmUpOverride))
+	mux.HandleFunc("DELETE /admin/warmup/override", s.adminOnly(s.handleClearWarmUpOverride))
+	mux.HandleFunc("PUT /admin/autoscale/hold", s.adminOnly(s.handleSetWorkerHold))
+	mux.HandleFunc("GET /admin/intake", s.adminOnly(s.handleIntakeStatus))
+	mux.HandleFunc("POST /admin/intake/hold", s.adminOnly(s.handleHoldIntake))
+	mux.HandleFunc("POST /admin/intake/release", s.adminOnly(s.handleReleaseIntake))
+	mux.HandleFunc("GET /replication/stream", s.handleReplicationStream)
+	mux.HandleFunc("GET /admin/replication", s.adminOnly(s.handleReplicationStatus))
+	mux.HandleFunc("POST /admin/replication/promote", s.adminOnly(s.handlePromote))
+	mux.HandleFunc("DELETE /admin/autoscale/hold", s.adminOnly(s.handleClearWorkerHold))
+	mux.HandleFunc("GET /admin/experiment", s.adminOnly(s.handleGetExperiment))
+	mux.HandleFunc("PUT /admin/experiment", s.adminOnly(s.handleSetExperiment))
+	mux.HandleFunc("DELETE /admin/experiment", s.adminOnly(s.handleStopExperiment))
+	mux.HandleFunc("GET /admin/listener", s.adminOnly(s.handleGetListener))
+	mux.HandleFunc("POST /admin/listener/reload", s.adminOnly(s.handleReloadListener))
+	mux.HandleFunc("GET /admin/degradation", s.adminOnly(s.handleGetDegradation))
+	mux.HandleFunc("POST /admin/degradation/{core}/recalibrate", s.adminOnly(s.handleRecalibrate))
+	mux.HandleFunc("GET /admin/interference", s.adminOnly(s.handleGetInterference))
+	mux.HandleFunc("POST /admin/workers/{core}/resize", s.adminOnly(s.handleResizeWorker))
+	mux.HandleFunc("GET /admin/benchmarks", s.adminOnly(s.handleListBenchmarks))
+	mux.HandleFunc("POST /admin/workers/{core}/benchmark", s.adminOnly(s.handleStartBenchmark))
+	mux.HandleFunc("POST /admin/workers/{core}/benchmark/submit", s.adminOnly(s.handleBenchmarkSubmit))
+	mux.HandleFunc("DELETE /admin/workers/{core}/benchmark", s.adminOnly(s.handleEndBenchmark))
+	mux.HandleFunc("GET /admin/taints", s.adminOnly(s.handleGetTaints))
+	mux.HandleFunc("PUT /admin/workers/{core}/taints", s.adminOnly(s.handleSetTaints))
+	mux.HandleFunc("GET /admin/maintenance", s.adminOnly(s.handleGetMaintenance))
+	mux.HandleFunc("POST /admin/maintenance", s.adminOnly(s.handleAddMaintenance))
+	mux.HandleFunc("DELETE /admin/maintenance/{id}", s.adminOnly(s.handleRemoveMaintenance))
+	mux.HandleFunc("GET /admin/report", s.adminOnly(s.handleCapacityReport))
+	mux.HandleFunc("GET /recommendations", s.adminOnly(s.handleRecommendations))
+	mux.HandleFunc("POST /recommendations/apply", s.adminOnly(s.handleApplyRecommendations))
+	mux.HandleFunc("POST /admin/jobs/{id}/force-fail", s.adminOnly(s.handleForceFail))
+	mux.HandleFunc("POST /admin/jobs/{id}/force-complete", s.adminOnly(s.handleForceComplete))
+	mux.HandleFunc("GET /debug/snapshot", s.adminOnly(s.handleSnapshot))
+	mux.HandleFunc("POST /debug/load-snapshot", s.adminOnly(s.handleLoadSnapshot))
+	mux.HandleFunc("GET /admin/timing", s.adminOnly(s.handleGetTiming))
+	mux.HandleFunc("PUT /admin/timing", s.adminOnly(s.handleSetTiming))
+	mux.HandleFunc("DELETE /admin/timing/{entry}", s.adminOnly(s.handleRemoveTiming))
 
-	return http.ListenAndServe(addr, s.loggingMiddleware(mux))
+	return s.loggingMiddleware(s.loadHintsMiddleware(mux))
 }
 
 // handleSubmit accepts job requests from clients
@@ -43,55 +286,494 @@ func (s *Server) handleSubmit(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
+	if s.refuseOnStandby(w) {
+		return
+	}
+	if s.scheduler.orchestrator.ShuttingDown() {
+		http.Error(w, "Gateway is shutting down", http.StatusServiceUnavailable)
+		return
+	}
 
-	var req protocol.ComputeRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, fmt.Sprintf("Invalid JSON: %v", err), http.StatusBadRequest)
+	// Shed load before it reaches the scheduler once the adaptive limit is reached
+	release, admitted := s.limiter.Acquire()
+	if !admitted {
+		s.metrics.Inc("orchestrator_jobs_total", "status", "shed")
+		w.Header().Set("Retry-After", "1")
+		http.Error(w, "Gateway is at its concurrency limit, retry shortly", http.StatusServiceUnavailable)
+		return
+	}
+	var overhead time.Duration
+	detached := false // An async job keeps its slot until it finishes
+	defer func() {
+		if !detached {
+			release(overhead)
+		}
+	}()
+
+	// Jobs forwarded by a peer gateway must carry its signature
+	forwardedFrom, err := s.verifyForwarded(w, r)
+	if err != nil {
+		log.Printf("[Gateway] Rejected forwarded job: %v", err)
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	req, err := decodeComputeRequest(w, r)
+	if err != nil {
+		writeDecodeError(w, err)
 		return
 	}
 
 	// Validate request
-	if req.CPULoad <= 0 || req.CPULoad > 200 {
-		http.Error(w, "cpu_load must be between 0 and 200", http.StatusBadRequest)
+	if err := s.validateRequest(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	// A job ID the client chose must be a UUID no other job has. A peer's forwarded
+	// job keeps the ID the peer gave it.
+	if req.JobID != "" && forwardedFrom == "" {
+		if req.JobID, err = normalizeClientJobID(req.JobID); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if _, exists := s.jobs.Get(req.JobID); exists {
+			http.Error(w, fmt.Sprintf("Job %s already exists", req.JobID), http.StatusConflict)
+			return
+		}
+	}
+	// Refuse work that would inevitably time out, suggesting how to shard it
+	if infeasible := s.checkFeasible(&req); infeasible != nil {
+		s.metrics.Inc("orchestrator_jobs_total", "status", "infeasible")
+		writeInfeasible(w, infeasible)
+		return
+	}
+
+	// Refuse denylisted sources before any scheduling work
+	source := s.sources.Identify(r)
+	if ip := r.Header.Get(headerClientIP); forwardedFrom != "" && ip != "" {
+		source.IP = ip
+	}
+	if s.sources.IsDenied(source) {
+		s.sources.RecordRejected(source)
+		s.metrics.Inc("orchestrator_jobs_total", "status", "rejected")
+		log.Printf("[Gateway] Rejected job from denylisted source %s", source.ID())
+		http.Error(w, "Source is blocked", http.StatusForbidden)
 		return
 	}
-	if req.LoadTime <= 0 {
-		http.Error(w, "load_time must be positive", http.StatusBadRequest)
+
+	// Embedders' policy checks; a peer's forwarded job has been through them there
+	if forwardedFrom == "" {
+		if err := s.admit(&req, source); err != nil {
+			s.writeAdmissionError(w, source, err)
+			return
+		}
+	}
+
+	// Held for maintenance: store the job and answer with its ID. A peer forwarded
+	// the job to start now, so it is refused instead.
+	if s.intake.Holding() {
+		if forwardedFrom != "" {
+			w.Header().Set("Retry-After", "60")
+			http.Error(w, "Submissions are held for maintenance", http.StatusServiceUnavailable)
+			return
+		}
+		s.holdJob(w, req, source)
+		return
+	}
+
+	// At capacity here: hand the job to a peer gateway that can start it now
+	if peer := s.forwardTarget(&req, forwardedFrom); peer != "" && s.forwardJob(w, r, peer, req, source) {
+		return
+	}
+
+	// Reserve the job's estimated CPU-seconds; settled against actual usage when it ends
+	cpuSeconds := s.scheduler.EstimateCPUSeconds(&req)
+	charge, admitted := s.quotas.Admit(source.ID(), cpuSeconds)
+	if !admitted {
+		quota := s.quotas.Status(source.ID())
+		s.metrics.Inc("orchestrator_jobs_total", "status", "quota_exceeded")
+		log.Printf("[Gateway] Rejected job from %s: needs %.1f CPU-seconds, %.1f of %.1f left",
+			source.ID(), cpuSeconds, quota.Remaining, quota.Budget)
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(time.Until(quota.ResetsAt).Seconds()))))
+		http.Error(w, fmt.Sprintf("CPU-seconds quota exceeded: job needs %.1f, %.1f of %.1f remaining until %s",
+			cpuSeconds, quota.Remaining, quota.Budget, quota.ResetsAt.Format(time.RFC3339)), http.StatusTooManyRequests)
 		return
 	}
 
-	// Schedule and execute job
-	response, err := s.scheduler.ScheduleJob(&req)
+	var job JobRecord
+	if forwardedFrom != "" {
+		// Keeps the forwarding gateway's ID, so the job is known by one ID on both
+		job, err = s.jobs.CreateWithID(req.JobID, &req, source)
+		if err == nil {
+			log.Printf("[Gateway] Accepted job %s forwarded by %s", job.ID, forwardedFrom)
+		}
+	} else {
+		job, err = s.createJob(&req, source)
+	}
 	if err != nil {
-		log.Printf("[Gateway] Job scheduling failed: %v", err)
-		http.Error(w, fmt.Sprintf("Job failed: %v", err), http.StatusInternalServerError)
+		s.quotas.Release(charge)
+		writeCreateError(w, err)
+		return
+	}
+	s.sources.RecordSubmitted(source)
+	s.quotas.Track(job.ID, charge)
+
+	if req.Async {
+		detached = true
+		go s.runAsync(job, source, release)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Location", "/jobs/"+job.ID)
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(map[string]interface{}{"job_id": job.ID, "status": job.Status})
 		return
 	}
+	defer s.quotas.Settle(job.ID)
 
+	// Streamed jobs get the job ID first (for cancellation), then progress, then the result
+	var stream *eventWriter
+	if req.Stream {
+		stream = newEventWriter(w)
+		stream.send(protocol.StreamEvent{Type: protocol.StreamEventAccepted, JobID: job.ID})
+		s.scheduler.SetProgressSink(job.ID, stream.send)
+		defer s.scheduler.SetProgressSink(job.ID, nil)
+	}
+
+	// Schedule and execute job; nobody is waiting for it once the client goes away
+	stopWatching := func() bool { return false }
+	if s.cancelOnDisconnect {
+		stopWatching = s.cancelOnClientGone(r.Context(), job.ID)
+	}
+	scheduled := time.Now()
+	response, annotations, err := s.executeJob(job, source)
+	stopWatching()
+
+	// Retried jobs spent time in deliberate backoff, which says nothing about congestion
+	if annotations != nil && annotations.Attempts <= 1 {
+		busy := time.Duration((annotations.RunTime + annotations.QueueWait) * float64(time.Second))
+		overhead = max(time.Since(scheduled)-busy, time.Microsecond)
+	}
+
+	// Blur when and how precisely the outcome is reported, per the source's timing policy
+	timing := s.timing.For(source)
+	timing.Delay(r.Context())
+	response = timing.CoarsenResponse(response)
+
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, ErrJobCancelled) {
+			status = http.StatusConflict
+		}
+		if stream != nil {
+			stream.send(protocol.StreamEvent{Type: protocol.StreamEventError, JobID: job.ID, Error: err.Error()})
+			return
+		}
+		http.Error(w, fmt.Sprintf("Job failed: %v", err), status)
+		return
+	}
+
+	if stream != nil {
+		stream.send(protocol.StreamEvent{Type: protocol.StreamEventResult, JobID: job.ID, Response: response})
+		return
+	}
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 }
 
-// handleHealth provides a simple health check endpoint
+// runAsync executes an async job in the background, holding its concurrency
+// slot and quota charge until it finishes, as a blocking submission would
+func (s *Server) runAsync(job JobRecord, source JobSource, release func(overhead time.Duration)) {
+	var overhead time.Duration
+	defer func() { release(overhead) }()
+	defer s.quotas.Settle(job.ID)
+
+	scheduled := time.Now()
+	_, annotations, _ := s.executeJob(job, source)
+	if annotations != nil && annotations.Attempts <= 1 {
+		busy := time.Duration((annotations.RunTime + annotations.QueueWait) * float64(time.Second))
+		overhead = max(time.Since(scheduled)-busy, time.Microsecond)
+	}
+}
+
+// executeJob schedules a created job and records its outcome on the job record,
+// the source's history and the metrics. Returns the response (carrying the
+// annotations if the request asked for them) and the scheduler's annotations.
+func (s *Server) executeJob(job JobRecord, source JobSource) (*protocol.JobResponse, *protocol.JobAnnotations, error) {
+	response, err := s.scheduler.ScheduleJob(&job.Request)
+	annotations := s.scheduler.TakeAnnotations(job.ID)
+	s.jobs.Annotate(job.ID, annotations)
+
+	if err != nil {
+		if errors.Is(err, ErrJobCancelled) {
+			s.jobs.Cancel(job.ID)
+			s.metrics.Inc("orchestrator_jobs_total", "status", "cancelled")
+			log.Printf("[Gateway] Job %s cancelled", job.ID)
+		} else {
+			s.jobs.Fail(job.ID, err)
+			s.metrics.Inc("orchestrator_jobs_total", "status", "failed")
+			log.Printf("[Gateway] Job scheduling failed: %v", err)
+		}
+		s.sources.RecordResult(source, false)
+		return nil, annotations, err
+	}
+
+	if job.Request.Annotate {
+		response.Annotations = annotations
+	}
+	s.jobs.Complete(job.ID, response)
+	s.sources.RecordResult(source, true)
+	s.metrics.Inc("orchestrator_jobs_total", "status", "completed")
+	return response, annotations, nil
+}
+
+// handleHealth reports Docker, worker image and worker health (503 if any check fails)
 func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	report := s.health.Check()
+
+	w.Header().Set("Content-Type", "application/json")
+	if !report.Healthy {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(report)
+}
+
+// handleCancelJob stops a queued or running job; only its submitter or an admin may cancel it
+func (s *Server) handleCancelJob(w http.ResponseWriter, r *http.Request) {
+	job, exists := s.jobs.Get(r.PathValue("id"))
+	if !exists {
+		http.Error(w, "Job not found", http.StatusNotFound)
+		return
+	}
+	if !s.isAdmin(r) && s.sources.Identify(r).ID() != job.Source.ID() {
+		http.Error(w, "Only the submitter or an admin may cancel this job", http.StatusForbidden)
+		return
+	}
+	if s.intake.Remove(job.ID) {
+		s.jobs.Cancel(job.ID)
+		log.Printf("[Gateway] Job %s cancelled while held", job.ID)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(map[string]string{"job_id": job.ID, "status": CancelOutcomeCancelled})
+		return
+	}
+	if !s.scheduler.Cancel(job.ID) {
+		http.Error(w, fmt.Sprintf("Job is %s, not queued or running", job.Status), http.StatusConflict)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]string{"job_id": job.ID, "status": "cancelling"})
+}
+
+// decodeComputeRequest parses a job request body of at most maxSubmitBytes,
+// rejecting fields the protocol doesn't define so typos and fields from other
+// request shapes aren't silently dropped
+func decodeComputeRequest(w http.ResponseWriter, r *http.Request) (protocol.ComputeRequest, error) {
+	var req protocol.ComputeRequest
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxSubmitBytes))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil {
+		return req, fmt.Errorf("Invalid JSON: %w", err)
+	}
+	return req, nil
+}
+
+// writeDecodeError answers a job request body that couldn't be parsed: 413 if it
+// was too large
+func writeDecodeError(w http.ResponseWriter, err error) {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		http.Error(w, fmt.Sprintf("Request body too large (limit %d bytes)", tooLarge.Limit), http.StatusRequestEntityTooLarge)
+		return
+	}
+	http.Error(w, err.Error(), http.StatusBadRequest)
+}
+
+// createJob records a submitted job under the ID its client chose, or a new one
+func (s *Server) createJob(req *protocol.ComputeRequest, source JobSource) (JobRecord, error) {
+	if req.JobID != "" {
+		return s.jobs.CreateWithID(req.JobID, req, source)
+	}
+	return s.jobs.Create(req, source)
+}
+
+// writeCreateError answers a job that couldn't be recorded: 409 if its ID was
+// taken meanwhile
+func writeCreateError(w http.ResponseWriter, err error) {
+	if errors.Is(err, errJobExists) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	http.Error(w, fmt.Sprintf("Job failed: %v", err), http.StatusInternalServerError)
+}
+
+// validateRequest checks a job request's parameters before it is accepted
+func (s *Server) validateRequest(req *protocol.ComputeRequest) error {
+	if err := worker.ValidateRequest(req); err != nil {
+		return err
+	}
+	if err := validateRetryPolicy(req.Retry); err != nil {
+		return err
+	}
+	if err := validateLabels(req.Labels); err != nil {
+		return err
+	}
+	if err := validateTolerations(req.Tolerations); err != nil {
+		return err
+	}
+	if err := s.checkNotifyEmail(req.NotifyEmail); err != nil {
+		return err
+	}
+	if req.Async && req.Stream {
+		return fmt.Errorf("async and stream can't be combined: an async job's progress is read from GET /jobs/{id}")
+	}
+	if err := s.scheduler.sandbox.Check(operationName(req)); err != nil {
+		return err
+	}
+	if !req.Deadline.IsZero() && !req.Deadline.After(time.Now()) {
+		return fmt.Errorf("deadline %s has already passed", req.Deadline.Format(time.RFC3339))
+	}
+	if req.Network != nil && req.Network.Receiver != "" {
+		return fmt.Errorf("network.receiver is chosen by the gateway; leave it unset")
+	}
+	if limit := s.scheduler.orchestrator.WorkerMemoryLimit(); req.Memory != nil && limit > 0 && int64(req.Memory.WorkingSetMB)<<20 >= limit {
+		return fmt.Errorf("memory.working_set_mb of %d doesn't fit in the workers' %d MiB memory limit", req.Memory.WorkingSetMB, limit>>20)
+	}
+	if !s.scheduler.HasQueue(req.Queue) {
+		return fmt.Errorf("unknown queue: %q", req.Queue)
+	}
+	switch req.OnQueueTimeout {
+	case "", protocol.QueueTimeoutFail, protocol.QueueTimeoutExtend, protocol.QueueTimeoutDowngrade:
+	default:
+		return fmt.Errorf("on_queue_timeout must be %q, %q or %q", protocol.QueueTimeoutFail, protocol.QueueTimeoutExtend, protocol.QueueTimeoutDowngrade)
+	}
+	if arch := s.scheduler.orchestrator.Arch(); arch != "" && !worker.SupportsArch(req.Operation, arch) {
+		arches := worker.OperationArches(req.Operation)
+		err := fmt.Errorf("operation %q needs %v workers; this node runs %s", req.Operation, arches, arch)
+		if peers := s.federation.PeersWithArch(arches); len(peers) > 0 {
+			err = fmt.Errorf("%w (compatible gateways: %s)", err, strings.Join(peers, ", "))
+		}
+		return err
+	}
+	return nil
+}
+
+// handleLiveness reports that the gateway process is up (restart it if this fails)
+func (s *Server) handleLiveness(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte("OK"))
 }
 
+// handleReadiness reports whether the gateway should receive traffic: Docker and
+// the worker image are available, the scheduler isn't paused or shutting down,
+// and a job could either start now or wait in the queue
+func (s *Server) handleReadiness(w http.ResponseWriter, r *http.Request) {
+	health := s.health.Check()
+
+	checks := map[string]ComponentHealth{
+		"docker":       health.Components["docker"],
+		"worker_image": health.Components["worker_image"],
+		"scheduler":    {Healthy: true},
+	}
+	switch {
+	case s.scheduler.orchestrator.ShuttingDown():
+		checks["scheduler"] = ComponentHealth{Healthy: false, Detail: "shutting down"}
+	case s.scheduler.IsPaused():
+		checks["scheduler"] = ComponentHealth{Healthy: false, Detail: "paused"}
+	}
+
+	switch {
+	case s.scheduler.HasCapacity():
+		checks["capacity"] = ComponentHealth{Healthy: true, Detail: "workers available"}
+	case s.scheduler.QueueAccepting():
+		checks["capacity"] = ComponentHealth{Healthy: true, Detail: "workers busy, queue accepting"}
+	default:
+		checks["capacity"] = ComponentHealth{Healthy: false, Detail: "workers busy and queue full"}
+	}
+
+	ready := true
+	for _, check := range checks {
+		ready = ready && check.Healthy
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if !ready {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"ready":  ready,
+		"checks": checks,
+	})
+}
+
+// handleVersion returns the gateway's build info
+func (s *Server) handleVersion(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(version.Get())
+}
+
 // handleStatus returns current system status
 func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.statusSnapshot())
+}
+
+// statusSnapshot builds the /status document
+func (s *Server) statusSnapshot() map[string]interface{} {
 	workers := s.scheduler.GetWorkerStatus()
 	queueStatus := s.scheduler.GetQueueStatus()
 
-	status := map[string]interface{}{
-		"status":       "running",
+	state := "running"
+	if s.scheduler.IsPaused() {
+		state = "paused"
+	}
+
+	runtime := s.scheduler.orchestrator.Runtime()
+	daemon := s.scheduler.orchestrator.Daemon()
+	return map[string]interface{}{
+		"status":       state,
+		"degraded":     runtime.Degraded || !daemon.Connected,
+		"runtime":      runtime,
+		"docker":       daemon,
+		"gateway":      s.scheduler.orchestrator.GatewayPinning(),
+		"arch":         s.scheduler.orchestrator.Arch(),
 		"worker_count": len(workers),
 		"workers":      workers,
 		"queue":        queueStatus, // Include queue status
+		"sources":      s.sources.Snapshot(),
+		"concurrency":  s.limiter.Status(),
+		"eta_model":    s.scheduler.eta.Status(),
+		"autoscaling":  s.scheduler.scaling.Status(),
+		"sandbox":      s.scheduler.sandbox.Status(),
 	}
+}
 
+// handleMetrics serves metrics in the Prometheus text format
+func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	s.metrics.WriteTo(w)
+}
+
+// handleClusterStatus aggregates /status from this gateway and all peers
+func (s *Server) handleClusterStatus(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(status)
+	json.NewEncoder(w).Encode(s.federation.ClusterStatus(s.statusSnapshot()))
+}
+
+// handleClusterMetrics aggregates /metrics from this gateway and all peers, labeled by node
+func (s *Server) handleClusterMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	s.federation.WriteClusterMetrics(w, s.metrics)
+}
+
+// handleWorkers lists active workers and recently exited ones with their exit diagnostics
+func (s *Server) handleWorkers(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"workers": s.scheduler.GetWorkerStatus(),
+		"exited":  s.scheduler.orchestrator.ExitedWorkers(),
+	})
 }
 
 // handleQueueStatus returns detailed queue information
@@ -102,6 +784,189 @@ func (s *Server) handleQueueStatus(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(queueStatus)
 }
 
+// handleListJobs returns recent job records, optionally filtered by ?source= and ?limit=
+func (s *Server) handleListJobs(w http.ResponseWriter, r *http.Request) {
+	limit := 100
+	if val := r.URL.Query().Get("limit"); val != "" {
+		parsed, err := strconv.Atoi(val)
+		if err != nil || parsed < 0 {
+			http.Error(w, "limit must be a non-negative integer", http.StatusBadRequest)
+			return
+		}
+		limit = parsed
+	}
+
+	query := r.URL.Query()
+	labels, err := parseLabelSelector(query["label"])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	jobs := s.jobs.List(query.Get("source"), query.Get("worker"), labels, limit)
+	for i, job := range jobs {
+		jobs[i] = s.timing.For(job.Source).CoarsenRecord(job)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(jobs)
+}
+
+// handleActiveJobs lists the jobs executing on workers right now
+func (s *Server) handleActiveJobs(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	active := s.scheduler.ActiveJobs()
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"count":  len(active),
+		"active": active,
+	})
+}
+
+// handleGetJob returns a single job record
+func (s *Server) handleGetJob(w http.ResponseWriter, r *http.Request) {
+	job, exists := s.jobs.Get(r.PathValue("id"))
+	if !exists {
+		http.Error(w, "Job not found", http.StatusNotFound)
+		return
+	}
+	if job.CompletedAt.IsZero() {
+		job.ETA = s.scheduler.JobETA(job.ID)
+	}
+	switch job.Status {
+	case protocol.StatusQueued:
+		job.WaitReasons = s.scheduler.WaitReasons(job.ID)
+	case protocol.StatusHeld:
+		job.WaitReasons = []WaitReason{intakeWaitReason()}
+	}
+	job = s.timing.For(job.Source).CoarsenRecord(job)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(job)
+}
+
+// handleWaitJob long-polls a job: it returns the record once the job finishes, or
+// its current state when ?timeout= (default 30s) elapses first
+func (s *Server) handleWaitJob(w http.ResponseWriter, r *http.Request) {
+	timeout := defaultWaitTimeout
+	if val := r.URL.Query().Get("timeout"); val != "" {
+		parsed, err := time.ParseDuration(val)
+		if err != nil {
+			// Bare numbers are seconds
+			secs, numErr := strconv.ParseFloat(val, 64)
+			if numErr != nil {
+				http.Error(w, "timeout must be a duration (e.g. 30s) or seconds", http.StatusBadRequest)
+				return
+			}
+			parsed = time.Duration(secs * float64(time.Second))
+		}
+		if parsed < 0 {
+			http.Error(w, "timeout must be non-negative", http.StatusBadRequest)
+			return
+		}
+		timeout = min(parsed, maxWaitTimeout)
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), timeout)
+	defer cancel()
+
+	job, exists := s.jobs.Wait(ctx, r.PathValue("id"))
+	if !exists {
+		http.Error(w, "Job not found", http.StatusNotFound)
+		return
+	}
+	timing := s.timing.For(job.Source)
+	if job.CompletedAt.IsZero() {
+		job.ETA = s.scheduler.JobETA(job.ID)
+	} else {
+		// Returning the moment a job finishes would give its exact completion time away
+		timing.Delay(r.Context())
+	}
+	job = timing.CoarsenRecord(job)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(job)
+}
+
+// handleGetJobLogs returns the worker-side logs captured for a job (text/plain)
+func (s *Server) handleGetJobLogs(w http.ResponseWriter, r *http.Request) {
+	job, exists := s.jobs.Get(r.PathValue("id"))
+	if !exists {
+		http.Error(w, "Job not found", http.StatusNotFound)
+		return
+	}
+	if !job.Request.CaptureLogs {
+		http.Error(w, "Log capture was not requested for this job (set \"capture_logs\": true)", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Write([]byte(job.Logs))
+}
+
+// handleGetDenylist lists blocked sources
+func (s *Server) handleGetDenylist(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.sources.Denylist())
+}
+
+// handleAddDenylist blocks a source (IP, API key, or source ID from /status)
+func (s *Server) handleAddDenylist(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Source string `json:"source"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.Source == "" {
+		http.Error(w, "Body must be JSON with a non-empty \"source\"", http.StatusBadRequest)
+		return
+	}
+
+	entry := s.sources.Deny(body.Source)
+	log.Printf("[Gateway] Source %s added to denylist", entry)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]string{"entry": entry})
+}
+
+// handleRemoveDenylist unblocks a source
+func (s *Server) handleRemoveDenylist(w http.ResponseWriter, r *http.Request) {
+	if !s.sources.Allow(r.PathValue("entry")) {
+		http.Error(w, "Entry not found", http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handlePauseScheduler stops dispatching; submissions queue until resumed
+func (s *Server) handlePauseScheduler(w http.ResponseWriter, r *http.Request) {
+	s.scheduler.Pause()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleResumeScheduler restarts dispatching
+func (s *Server) handleResumeScheduler(w http.ResponseWriter, r *http.Request) {
+	s.scheduler.Resume()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// adminOnly requires the configured admin bearer token (if any)
+func (s *Server) adminOnly(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !s.isAdmin(r) {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// isAdmin reports whether the request carries the admin token (always true if none is configured)
+func (s *Server) isAdmin(r *http.Request) bool {
+	if s.adminToken == "" {
+		return true
+	}
+	expected := "Bearer " + s.adminToken
+	return subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte(expected)) == 1
+}
+
 // loggingMiddleware logs all incoming HTTP requests
 func (s *Server) loggingMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -0,0 +1,83 @@
+package gateway
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	// sheddingThreshold is the utilization above which clients are asked to back off
+	sheddingThreshold = 0.75
+
+	// maxSuggestedDelay caps the backoff suggested to clients
+	maxSuggestedDelay = 60 * time.Second
+)
+
+// LoadHints returns current worker utilization (0-1) and how long a well-behaved
+// client should wait before submitting more work. The delay scales the estimated
+// queue wait by how far load is past sheddingThreshold or how full the queues
+// are, so clients slow down before submissions start being rejected.
+func (s *Scheduler) LoadHints() (utilization float64, delay time.Duration) {
+	if !ENABLE_JOB_QUEUE {
+		return 0, 0
+	}
+
+	utilization, fill := s.queues.load()
+	pressure := max((utilization-sheddingThreshold)/(1-sheddingThreshold), fill)
+	if pressure <= 0 {
+		return utilization, 0
+	}
+
+	wait := s.estimateQueueWait(s.queues.defaultQueue)
+	delay = time.Duration(min(pressure, 1) * wait * float64(time.Second))
+	return utilization, min(delay, maxSuggestedDelay)
+}
+
+// sheddingWriter adds load hint headers to a response just before its headers
+// are sent, so long-running requests report load as of their completion
+type sheddingWriter struct {
+	http.ResponseWriter
+	scheduler   *Scheduler
+	wroteHeader bool
+}
+
+func (w *sheddingWriter) WriteHeader(code int) {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		utilization, delay := w.scheduler.LoadHints()
+		w.Header().Set("X-Orchestrator-Utilization", strconv.FormatFloat(utilization, 'f', 2, 64))
+		w.Header().Set("X-Suggested-Delay-Ms", strconv.FormatInt(int64(math.Ceil(float64(delay)/float64(time.Millisecond))), 10))
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *sheddingWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush keeps streaming responses working through the wrapper
+func (w *sheddingWriter) Flush() {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (w *sheddingWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// loadHintsMiddleware sets X-Orchestrator-Utilization and X-Suggested-Delay-Ms on
+// every response so client SDKs can self-throttle
+func (s *Server) loadHintsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(&sheddingWriter{ResponseWriter: w, scheduler: s.scheduler}, r)
+	})
+}
@@ -0,0 +1,78 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+// shutdownPollInterval is how often a draining gateway checks for jobs still in flight
+const shutdownPollInterval = 250 * time.Millisecond
+
+// errShuttingDown refuses new jobs and workers once shutdown has begun
+var errShuttingDown = errors.New("gateway is shutting down")
+
+// shutdownState tracks a gateway shutdown (guarded by the orchestrator lock)
+type shutdownState struct {
+	stopping bool
+	drainer  func(ctx context.Context) error // Waits for in-flight jobs (nil = none to wait for)
+}
+
+// SetDrainer registers what Shutdown waits on before stopping workers: the
+// scheduler's in-flight jobs
+func (o *Orchestrator) SetDrainer(fn func(ctx context.Context) error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.shutdown.drainer = fn
+}
+
+// ShuttingDown reports whether Shutdown has begun
+func (o *Orchestrator) ShuttingDown() bool {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	return o.shutdown.stopping
+}
+
+// drain waits for every job inside ScheduleJob (queued, running or backing off
+// before a retry) to finish, then stops the queue processor. New jobs are refused
+// meanwhile. Jobs still in flight when ctx ends are cancelled: queued ones are
+// dropped, and running ones are stopped on their workers.
+func (s *Scheduler) drain(ctx context.Context) error {
+	ticker := time.NewTicker(shutdownPollInterval)
+	defer ticker.Stop()
+	defer s.StopQueueProcessor()
+
+	logged := time.Time{}
+	for {
+		inFlight := s.inFlight()
+		if len(inFlight) == 0 {
+			log.Printf("[Scheduler] All jobs drained")
+			return nil
+		}
+		if time.Since(logged) >= 5*time.Second {
+			log.Printf("[Scheduler] Draining %d job(s) (%d running, %d queued)", len(inFlight), len(s.RunningJobs()), s.QueueLength())
+			logged = time.Now()
+		}
+
+		select {
+		case <-ctx.Done():
+			s.CancelJobs(inFlight)
+			return fmt.Errorf("cancelled %d job(s) still in flight: %w", len(inFlight), ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+// inFlight returns the IDs of the jobs inside ScheduleJob
+func (s *Scheduler) inFlight() []string {
+	s.runningMu.Lock()
+	defer s.runningMu.Unlock()
+
+	ids := make([]string, 0, len(s.scheduling))
+	for jobID := range s.scheduling {
+		ids = append(ids, jobID)
+	}
+	return ids
+}
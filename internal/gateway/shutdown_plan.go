@@ -0,0 +1,94 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// plannedJob is a running or queued job annotated with its owner for the shutdown plan
+type plannedJob struct {
+	JobID            string  `json:"job_id"`
+	Owner            string  `json:"owner"`
+	Queue            string  `json:"queue,omitempty"`
+	CoreID           int     `json:"core_id,omitempty"`
+	RemainingSeconds float64 `json:"remaining_seconds"`
+}
+
+// handleShutdownPlan reports what an immediate shutdown would lose without changing anything
+func (s *Server) handleShutdownPlan(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.shutdownPlan())
+}
+
+// shutdownPlan summarises running and queued work, who owns it, and how long a
+// graceful drain would take
+func (s *Server) shutdownPlan() map[string]interface{} {
+	drainTimeout := float64(s.scheduler.orchestrator.drainTimeout)
+	owners := make(map[string]map[string]int)
+	countOwner := func(owner, kind string) {
+		if owners[owner] == nil {
+			owners[owner] = map[string]int{"running": 0, "queued": 0}
+		}
+		owners[owner][kind]++
+	}
+
+	// Running jobs proceed in parallel, so the minimum drain is the longest one
+	running := make([]plannedJob, 0)
+	minDrain := 0.0
+	exceedingDrainTimeout := 0
+	for _, job := range s.scheduler.RunningJobs() {
+		owner := s.jobOwner(job.JobID)
+		countOwner(owner, "running")
+		running = append(running, plannedJob{
+			JobID:            job.JobID,
+			Owner:            owner,
+			Queue:            job.Queue,
+			CoreID:           job.CoreID,
+			RemainingSeconds: job.RemainingSeconds,
+		})
+
+		minDrain = max(minDrain, job.RemainingSeconds)
+		if job.RemainingSeconds > drainTimeout {
+			exceedingDrainTimeout++
+		}
+	}
+
+	// Queued work would additionally have to run through the cluster's total capacity
+	queued := make([]plannedJob, 0)
+	queuedCPUSeconds := 0.0
+	for _, job := range s.scheduler.WaitingJobs() {
+		owner := s.jobOwner(job.JobID)
+		countOwner(owner, "queued")
+		queued = append(queued, plannedJob{
+			JobID:            job.JobID,
+			Owner:            owner,
+			Queue:            job.Queue,
+			RemainingSeconds: job.WorkSeconds,
+		})
+		queuedCPUSeconds += job.EstimatedCPU / 100 * job.WorkSeconds
+	}
+
+	fullDrain := minDrain
+	if capacity := s.scheduler.queues.totalCapacity() / 100; capacity > 0 {
+		fullDrain += queuedCPUSeconds / capacity
+	}
+
+	return map[string]interface{}{
+		"running_jobs":                 running,
+		"queued_jobs":                  queued,
+		"owners":                       owners,
+		"worker_count":                 s.scheduler.orchestrator.GetWorkerCount(),
+		"min_drain_seconds":            minDrain,
+		"full_drain_seconds_estimate":  fullDrain,
+		"worker_drain_timeout":         drainTimeout,
+		"jobs_exceeding_drain_timeout": exceedingDrainTimeout,
+	}
+}
+
+// jobOwner returns the source ID that submitted a job ("unknown" once evicted from history)
+func (s *Server) jobOwner(jobID string) string {
+	if job, exists := s.jobs.Get(jobID); exists {
+		return job.Source.ID()
+	}
+	return "unknown"
+}
@@ -0,0 +1,285 @@
+package gateway
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/ahmadhassan44/container-orchestrator/pkg/config"
+	"github.com/ahmadhassan44/container-orchestrator/pkg/protocol"
+)
+
+const (
+	// sloBucketWidth is the resolution of SLO windows
+	sloBucketWidth = time.Minute
+
+	// sloMinAlertJobs is how many jobs the burn window needs before it can alert,
+	// so a single early failure doesn't page anyone
+	sloMinAlertJobs = 10
+)
+
+// SLO alert events, sent through the webhook endpoint
+const (
+	sloEventBurning   = "slo.burning"
+	sloEventRecovered = "slo.recovered"
+)
+
+// sloBucket counts the jobs of one minute
+type sloBucket struct {
+	minute int64 // Unix minute the counts belong to
+	jobs   int
+	good   int
+}
+
+// sloObjective is one tracked objective, with its counts in a ring of minutes
+type sloObjective struct {
+	name      string
+	objective string  // Human-readable definition
+	target    float64 // Fraction of jobs that must be good
+	buckets   []sloBucket
+	burning   bool // Burn rate above the alert threshold at the last check
+}
+
+func (o *sloObjective) record(at time.Time, good bool) {
+	minute := at.Unix() / int64(sloBucketWidth/time.Second)
+	b := &o.buckets[minute%int64(len(o.buckets))]
+	if b.minute != minute {
+		*b = sloBucket{minute: minute}
+	}
+	b.jobs++
+	if good {
+		b.good++
+	}
+}
+
+// counts sums the buckets of the last span before now
+func (o *sloObjective) counts(now time.Time, span time.Duration) (jobs, good int) {
+	current := now.Unix() / int64(sloBucketWidth/time.Second)
+	oldest := current - int64(span/sloBucketWidth) + 1
+	for _, b := range o.buckets {
+		if b.minute >= oldest && b.minute <= current {
+			jobs += b.jobs
+			good += b.good
+		}
+	}
+	return jobs, good
+}
+
+// SLOStatus is an objective's compliance over the SLO window
+type SLOStatus struct {
+	Name       string  `json:"name"`
+	Objective  string  `json:"objective"`
+	Target     float64 `json:"target"`     // Percent
+	Jobs       int     `json:"jobs"`       // Counted over the window
+	Good       int     `json:"good"`       // Of which met the objective
+	Compliance float64 `json:"compliance"` // Percent (100 with no jobs)
+	// Share of the window's error budget left: 1 untouched, 0 spent, negative overspent
+	ErrorBudgetRemaining float64 `json:"error_budget_remaining"`
+	// How fast the budget is being spent over the burn window (1 = exactly on budget)
+	BurnRate       float64 `json:"burn_rate"`
+	BurnWindowJobs int     `json:"burn_window_jobs"`
+	Burning        bool    `json:"burning"` // Burn rate at or above SLO_ALERT_BURN_RATE
+}
+
+// SLOAlertListener is told when an objective starts or stops burning its error
+// budget too fast. It runs on the transitioning goroutine, so it must not block.
+type SLOAlertListener func(event string, status SLOStatus)
+
+// SLOTracker measures finished jobs against the configured service level
+// objectives over a rolling window, and reports error budget burn
+type SLOTracker struct {
+	startSeconds float64
+	window       time.Duration
+	burnWindow   time.Duration
+	alertRate    float64
+	metrics      *Metrics
+
+	mu         sync.Mutex
+	objectives []*sloObjective
+	start      *sloObjective // nil when not tracked
+	success    *sloObjective // nil when not tracked
+	alert      SLOAlertListener
+}
+
+func NewSLOTracker(cfg *config.Config, metrics *Metrics) *SLOTracker {
+	t := &SLOTracker{
+		startSeconds: cfg.SLOStartSeconds,
+		window:       time.Duration(max(cfg.SLOWindow, 60)) * time.Second,
+		alertRate:    cfg.SLOAlertBurnRate,
+		metrics:      metrics,
+	}
+	t.burnWindow = min(time.Duration(max(cfg.SLOBurnWindow, 60))*time.Second, t.window)
+
+	t.start = t.addObjective("start_latency", cfg.SLOStartTarget,
+		fmt.Sprintf("%g%% of jobs start within %gs", cfg.SLOStartTarget, cfg.SLOStartSeconds))
+	t.success = t.addObjective("success", cfg.SLOSuccessTarget,
+		fmt.Sprintf("%g%% of jobs complete successfully", cfg.SLOSuccessTarget))
+
+	metrics.Register("orchestrator_slo_compliance_percent", metricGauge, "Share of jobs meeting each SLO over the SLO window")
+	metrics.Register("orchestrator_slo_error_budget_remaining", metricGauge, "Share of each SLO's error budget left in the SLO window")
+	metrics.Register("orchestrator_slo_burn_rate", metricGauge, "Error budget burn rate of each SLO over the burn window")
+	metrics.Register("orchestrator_slo_alerts_total", metricCounter, "SLO burn alerts, by SLO and event")
+	metrics.AddCollector(func(m *Metrics) {
+		for _, status := range t.Status() {
+			m.Set("orchestrator_slo_compliance_percent", status.Compliance, "slo", status.Name)
+			m.Set("orchestrator_slo_error_budget_remaining", status.ErrorBudgetRemaining, "slo", status.Name)
+			m.Set("orchestrator_slo_burn_rate", status.BurnRate, "slo", status.Name)
+		}
+	})
+
+	for _, o := range t.objectives {
+		log.Printf("[SLO] Tracking %s: %s", o.name, o.objective)
+	}
+	return t
+}
+
+// addObjective tracks an objective with a target in percent; 0 leaves it untracked
+func (t *SLOTracker) addObjective(name string, target float64, objective string) *sloObjective {
+	if target <= 0 {
+		return nil
+	}
+	if target >= 100 {
+		log.Printf("[WARNING] SLO %s: a %g%% target leaves no error budget, not tracked", name, target)
+		return nil
+	}
+	o := &sloObjective{
+		name:      name,
+		objective: objective,
+		target:    target / 100,
+		buckets:   make([]sloBucket, int(t.window/sloBucketWidth)+1),
+	}
+	t.objectives = append(t.objectives, o)
+	return o
+}
+
+// SetAlertListener registers a callback for SLO burn alerts
+func (t *SLOTracker) SetAlertListener(fn SLOAlertListener) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.alert = fn
+}
+
+// RecordJob counts a finished job towards the objectives. Cancelled jobs count
+// only towards start latency, and only if they had started. It is a
+// TransitionListener.
+func (t *SLOTracker) RecordJob(job JobRecord) {
+	switch job.Status {
+	case protocol.StatusCompleted, protocol.StatusFailed, protocol.StatusCancelled:
+	default:
+		return
+	}
+	at := job.CompletedAt
+	if at.IsZero() {
+		at = time.Now()
+	}
+	var runTime float64
+	if job.Annotations != nil {
+		runTime = job.Annotations.RunTime
+	}
+
+	t.mu.Lock()
+	if t.start != nil && (runTime > 0 || job.Status == protocol.StatusFailed) {
+		// Time to start is everything but the time spent on workers; a failed job
+		// that never reached one didn't start in time
+		toStart := at.Sub(job.SubmittedAt).Seconds() - runTime
+		t.start.record(at, runTime > 0 && toStart <= t.startSeconds)
+	}
+	if t.success != nil && job.Status != protocol.StatusCancelled {
+		t.success.record(at, job.Status == protocol.StatusCompleted)
+	}
+	alerts := t.checkBurnLocked(time.Now())
+	fn := t.alert
+	t.mu.Unlock()
+
+	for _, a := range alerts {
+		t.metrics.Inc("orchestrator_slo_alerts_total", "slo", a.status.Name, "event", a.event)
+		if fn != nil {
+			fn(a.event, a.status)
+		}
+	}
+}
+
+type sloAlert struct {
+	event  string
+	status SLOStatus
+}
+
+// checkBurnLocked flags objectives whose burn rate crossed the alert threshold
+// since the last check and returns the alerts to send (caller holds t.mu)
+func (t *SLOTracker) checkBurnLocked(now time.Time) []sloAlert {
+	if t.alertRate <= 0 {
+		return nil
+	}
+	var alerts []sloAlert
+	for _, o := range t.objectives {
+		status := t.statusLocked(o, now)
+		burning := status.BurnWindowJobs >= sloMinAlertJobs && status.BurnRate >= t.alertRate
+		if burning == o.burning {
+			continue
+		}
+		o.burning, status.Burning = burning, burning
+		if burning {
+			log.Printf("[SLO] %s is burning its error budget %.1fx too fast (%.2f%% compliance, %.0f%% of budget left)",
+				o.name, status.BurnRate, status.Compliance, 100*status.ErrorBudgetRemaining)
+			alerts = append(alerts, sloAlert{event: sloEventBurning, status: status})
+		} else {
+			log.Printf("[SLO] %s burn rate back to %.1fx", o.name, status.BurnRate)
+			alerts = append(alerts, sloAlert{event: sloEventRecovered, status: status})
+		}
+	}
+	return alerts
+}
+
+// statusLocked computes an objective's compliance and burn as of now (caller holds t.mu)
+func (t *SLOTracker) statusLocked(o *sloObjective, now time.Time) SLOStatus {
+	status := SLOStatus{
+		Name:                 o.name,
+		Objective:            o.objective,
+		Target:               100 * o.target,
+		Compliance:           100,
+		ErrorBudgetRemaining: 1,
+		Burning:              o.burning,
+	}
+	budget := 1 - o.target
+
+	status.Jobs, status.Good = o.counts(now, t.window)
+	if status.Jobs > 0 {
+		bad := float64(status.Jobs - status.Good)
+		status.Compliance = 100 * float64(status.Good) / float64(status.Jobs)
+		status.ErrorBudgetRemaining = 1 - bad/(budget*float64(status.Jobs))
+	}
+
+	jobs, good := o.counts(now, t.burnWindow)
+	status.BurnWindowJobs = jobs
+	if jobs > 0 {
+		status.BurnRate = float64(jobs-good) / float64(jobs) / budget
+	}
+	return status
+}
+
+// Status reports every tracked objective as of now
+func (t *SLOTracker) Status() []SLOStatus {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	statuses := make([]SLOStatus, 0, len(t.objectives))
+	for _, o := range t.objectives {
+		statuses = append(statuses, t.statusLocked(o, now))
+	}
+	return statuses
+}
+
+// handleSLO reports SLO compliance and error budget burn
+func (s *Server) handleSLO(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"window":          s.slo.window.Seconds(),
+		"burn_window":     s.slo.burnWindow.Seconds(),
+		"alert_burn_rate": s.slo.alertRate,
+		"objectives":      s.slo.Status(),
+	})
+}
@@ -0,0 +1,360 @@
+package gateway
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ahmadhassan44/container-orchestrator/pkg/config"
+	"github.com/ahmadhassan44/container-orchestrator/pkg/protocol"
+	"github.com/ahmadhassan44/container-orchestrator/pkg/version"
+)
+
+// redacted replaces secrets and label values in a snapshot
+const redacted = "[redacted]"
+
+// maxSnapshotBody bounds a snapshot uploaded for restoring
+const maxSnapshotBody = 16 << 20
+
+// Snapshot is the gateway's in-memory scheduling state at one instant, for
+// inspecting a misbehaving gateway after the fact or replaying its state on a
+// fake-runtime gateway. The scheduler has no circuit breakers; degradation and
+// autoscaling state are their closest equivalents.
+type Snapshot struct {
+	TakenAt time.Time              `json:"taken_at"`
+	Node    string                 `json:"node"`
+	Version string                 `json:"version"`
+	Runtime string                 `json:"runtime"`
+	Config  map[string]interface{} `json:"config"` // Secrets redacted
+	Paused  bool                   `json:"paused"`
+	Workers []SnapshotWorker       `json:"workers"`
+	Running []SnapshotRunningJob   `json:"running"`
+	Queued  []SnapshotQueuedJob    `json:"queued"` // Dispatch order within each queue
+	Queues  map[string]interface{} `json:"queues"`
+	Taints  map[int][]string       `json:"core_taints,omitempty"`
+	Windows []MaintenanceWindow    `json:"maintenance"`
+
+	// Informational only; not restored
+	Degradation []CoreDegradation        `json:"degradation"`
+	Autoscaling map[string]interface{}   `json:"autoscaling"`
+	Benchmarks  []map[string]interface{} `json:"benchmarks"`
+}
+
+// SnapshotWorker is a worker and the CPU reserved on it
+type SnapshotWorker struct {
+	CoreID      int     `json:"core_id"`
+	WorkerUUID  string  `json:"worker_uuid"`
+	ContainerID string  `json:"container_id"`
+	BaseURL     string  `json:"base_url"`
+	ReservedCPU float64 `json:"cpu_reserved"` // Estimated CPU of the jobs placed on it
+	Capacity    float64 `json:"capacity"`
+	Health      float64 `json:"health"` // Composite health score (0-1)
+	Version     string  `json:"version,omitempty"`
+	Arch        string  `json:"arch,omitempty"`
+}
+
+// SnapshotRunningJob is a running job's reservation
+type SnapshotRunningJob struct {
+	JobID            string    `json:"job_id"`
+	CoreID           int       `json:"core_id"`
+	Operation        string    `json:"operation"`
+	Queue            string    `json:"queue,omitempty"`
+	StartedAt        time.Time `json:"started_at"`
+	RemainingSeconds float64   `json:"remaining_seconds"`
+	EstimatedCPU     float64   `json:"estimated_cpu"`
+}
+
+// SnapshotQueuedJob is a waiting job with its request, payload redacted
+type SnapshotQueuedJob struct {
+	JobID        string                  `json:"job_id"`
+	Queue        string                  `json:"queue"`
+	EnqueuedAt   time.Time               `json:"enqueued_at"`
+	EstimatedCPU float64                 `json:"estimated_cpu"`
+	Duration     float64                 `json:"duration"`
+	Source       JobSource               `json:"source"`
+	Request      protocol.ComputeRequest `json:"request"`
+}
+
+// contents copies the queued jobs, in queue configuration order and FIFO within each queue
+func (qs *queueSet) contents() []QueuedJob {
+	qs.mu.Lock()
+	defer qs.mu.Unlock()
+
+	var jobs []QueuedJob
+	for _, name := range qs.order {
+		for _, job := range qs.queues[name].items {
+			copied := *job
+			request := *job.request
+			copied.request = &request
+			jobs = append(jobs, copied)
+		}
+	}
+	return jobs
+}
+
+// redactRequest drops what a snapshot shouldn't carry of a request: label values
+// and checkpointed operation state
+func redactRequest(req protocol.ComputeRequest) protocol.ComputeRequest {
+	if len(req.Labels) > 0 {
+		labels := make(map[string]string, len(req.Labels))
+		for key := range req.Labels {
+			labels[key] = redacted
+		}
+		req.Labels = labels
+	}
+	req.Checkpoint = nil
+	return req
+}
+
+// redactedConfig lists cfg's fields, blanking tokens and secrets that are set
+func redactedConfig(cfg *config.Config) map[string]interface{} {
+	fields := make(map[string]interface{})
+	value := reflect.ValueOf(*cfg)
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		secret := strings.HasSuffix(field.Name, "Token") || strings.HasSuffix(field.Name, "Secret")
+		if secret && !value.Field(i).IsZero() {
+			fields[field.Name] = redacted
+			continue
+		}
+		fields[field.Name] = value.Field(i).Interface()
+	}
+	return fields
+}
+
+// snapshotWorkers describes the orchestrator's workers, by core
+func snapshotWorkers(orch *Orchestrator) []SnapshotWorker {
+	workers := []SnapshotWorker{}
+	for _, worker := range orch.GetAllWorkers() {
+		workers = append(workers, SnapshotWorker{
+			CoreID:      worker.CoreID,
+			WorkerUUID:  worker.UUID,
+			ContainerID: worker.ContainerID,
+			BaseURL:     worker.BaseURL,
+			ReservedCPU: worker.ReservedCPU,
+			Capacity:    worker.capacity(),
+			Health:      worker.Health.Score,
+			Version:     worker.Version,
+			Arch:        worker.Arch,
+		})
+	}
+	sort.Slice(workers, func(i, j int) bool { return workers[i].CoreID < workers[j].CoreID })
+	return workers
+}
+
+// Snapshot captures the scheduler's state. Each part is read under its own lock,
+// so a job moving between queue and worker meanwhile may show in both or neither.
+func (s *Server) Snapshot() Snapshot {
+	sched := s.scheduler
+	snap := Snapshot{
+		TakenAt:     time.Now(),
+		Node:        s.federation.nodeName,
+		Version:     version.Get().Version,
+		Runtime:     sched.config.Runtime,
+		Config:      redactedConfig(sched.config),
+		Paused:      sched.IsPaused(),
+		Workers:     snapshotWorkers(sched.orchestrator),
+		Running:     []SnapshotRunningJob{},
+		Queued:      []SnapshotQueuedJob{},
+		Queues:      sched.GetQueueStatus(),
+		Taints:      sched.taints.Cores(),
+		Windows:     sched.maintenance.Windows(),
+		Degradation: sched.degradation.Status(),
+		Autoscaling: sched.scaling.Status(),
+		Benchmarks:  sched.benchmarks.Sessions(sched),
+	}
+
+	for _, job := range sched.ActiveJobs() {
+		running := SnapshotRunningJob{
+			JobID:            job.JobID,
+			CoreID:           job.CoreID,
+			Operation:        job.Operation,
+			Queue:            job.Queue,
+			StartedAt:        job.StartedAt,
+			RemainingSeconds: job.RemainingSeconds,
+		}
+		sched.runningMu.Lock()
+		if a, exists := sched.annotations[job.JobID]; exists {
+			running.EstimatedCPU = a.EstimatedCPU
+		}
+		sched.runningMu.Unlock()
+		snap.Running = append(snap.Running, running)
+	}
+
+	if ENABLE_JOB_QUEUE {
+		for _, job := range sched.queues.contents() {
+			queued := SnapshotQueuedJob{
+				JobID:        job.request.JobID,
+				Queue:        job.queue,
+				EnqueuedAt:   job.enqueuedAt,
+				EstimatedCPU: job.estimatedCPU,
+				Duration:     job.duration,
+				Request:      redactRequest(*job.request),
+			}
+			if record, exists := s.jobs.Get(queued.JobID); exists {
+				queued.Source = record.Source
+			}
+			snap.Queued = append(snap.Queued, queued)
+		}
+	}
+	return snap
+}
+
+// RestoreReport summarizes a snapshot restore
+type RestoreReport struct {
+	Workers     int      `json:"workers"`
+	Running     int      `json:"running"` // Replayed as CPU holds for their remaining time
+	Queued      int      `json:"queued"`
+	Windows     int      `json:"maintenance"`
+	Errors      []string `json:"errors"`
+	NotRestored []string `json:"not_restored"`
+}
+
+// restoreSnapshot rebuilds snap's workers, reservations, taints, maintenance and
+// queue on an idle gateway. Running jobs can't be resumed, so each becomes a hold
+// on its worker's and queue's CPU that lasts its remaining time; queued jobs are
+// resubmitted under their IDs with their (redacted) requests.
+func (s *Server) restoreSnapshot(snap Snapshot) RestoreReport {
+	sched := s.scheduler
+	report := RestoreReport{
+		Errors:      []string{},
+		NotRestored: []string{"config", "degradation baselines", "autoscaling guards", "benchmark sessions", "worker capacity", "queue wait history"},
+	}
+
+	// Queued jobs must queue, not start, while the rest is rebuilt
+	sched.Pause()
+
+	for _, worker := range sched.orchestrator.GetAllWorkers() {
+		if detached, exists := sched.orchestrator.DetachWorker(worker.CoreID); exists {
+			sched.orchestrator.StopDetached(detached)
+		}
+	}
+	for _, worker := range snap.Workers {
+		if _, err := sched.orchestrator.StartWorker(worker.CoreID); err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("worker on core %d: %v", worker.CoreID, err))
+			continue
+		}
+		sched.orchestrator.UpdateWorkerCPU(worker.CoreID, worker.ReservedCPU)
+		report.Workers++
+	}
+
+	for _, job := range snap.Running {
+		if _, exists := sched.orchestrator.GetWorkerByCore(job.CoreID); !exists {
+			report.Errors = append(report.Errors, fmt.Sprintf("running job %s: no worker on core %d", job.JobID, job.CoreID))
+			continue
+		}
+		if job.Queue != "" && ENABLE_JOB_QUEUE {
+			sched.queues.reserve(job.Queue, job.EstimatedCPU)
+		}
+		time.AfterFunc(time.Duration(job.RemainingSeconds*float64(time.Second)), func() {
+			if worker, exists := sched.orchestrator.GetWorkerByCore(job.CoreID); exists {
+				sched.orchestrator.AdjustWorkerCPU(worker, -job.EstimatedCPU)
+			}
+			if job.Queue != "" && ENABLE_JOB_QUEUE {
+				sched.queues.release(job.Queue, job.EstimatedCPU)
+			}
+			sched.wakeQueue()
+		})
+		report.Running++
+	}
+
+	for core, taints := range snap.Taints {
+		if err := sched.taints.Set(core, taints); err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("taints of core %d: %v", core, err))
+		}
+	}
+
+	existing := make(map[string]bool)
+	for _, window := range sched.maintenance.Windows() {
+		existing[window.ID] = true
+	}
+	for _, window := range snap.Windows {
+		if window.Source != "api" || existing[window.ID] || !window.End.After(time.Now()) {
+			continue // Config windows come from this gateway's own config
+		}
+		if _, err := sched.maintenance.Add(window.Start, window.End, window.Cores, window.Reason, "api"); err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("maintenance window %s: %v", window.ID, err))
+			continue
+		}
+		report.Windows++
+	}
+
+	for _, queued := range snap.Queued {
+		req := queued.Request
+		req.Queue = queued.Queue
+		job, err := s.jobs.CreateWithID(queued.JobID, &req, queued.Source)
+		if err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("queued job %s: %v", queued.JobID, err))
+			continue
+		}
+		go s.executeJob(job, queued.Source)
+		if !s.awaitQueued(job.ID, time.Second) {
+			report.Errors = append(report.Errors, fmt.Sprintf("queued job %s: not queued within 1s, order may differ", job.ID))
+		}
+		report.Queued++
+	}
+
+	if !snap.Paused {
+		sched.Resume()
+	}
+	sched.wakeQueue()
+	return report
+}
+
+// awaitQueued waits for a resubmitted job to reach its queue, so the next one
+// queues behind it
+func (s *Server) awaitQueued(jobID string, timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		for _, job := range s.scheduler.WaitingJobs() {
+			if job.JobID == jobID {
+				return true
+			}
+		}
+		if record, exists := s.jobs.Get(jobID); exists && record.Status != protocol.StatusAccepted {
+			return true // Already ran or failed
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return false
+}
+
+// handleSnapshot dumps the gateway's in-memory state as one JSON document
+func (s *Server) handleSnapshot(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=snapshot-%s.json", time.Now().UTC().Format("20060102T150405Z")))
+	json.NewEncoder(w).Encode(s.Snapshot())
+}
+
+// handleLoadSnapshot restores a snapshot on an idle fake-runtime gateway
+func (s *Server) handleLoadSnapshot(w http.ResponseWriter, r *http.Request) {
+	if s.scheduler.config.Runtime != "fake" {
+		http.Error(w, "Snapshots can only be loaded with RUNTIME=fake", http.StatusForbidden)
+		return
+	}
+	var snap Snapshot
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxSnapshotBody)).Decode(&snap); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid snapshot: %v", err), http.StatusBadRequest)
+		return
+	}
+	if running, waiting := len(s.scheduler.RunningJobs()), len(s.scheduler.WaitingJobs()); running > 0 || waiting > 0 {
+		http.Error(w, fmt.Sprintf("Gateway is busy (%d running, %d queued); load snapshots on an idle gateway", running, waiting),
+			http.StatusConflict)
+		return
+	}
+
+	report := s.restoreSnapshot(snap)
+	log.Printf("[Audit] Snapshot from %s taken %s loaded: %d workers, %d running holds, %d queued jobs, %d errors",
+		snap.Node, snap.TakenAt.Format(time.RFC3339), report.Workers, report.Running, report.Queued, len(report.Errors))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
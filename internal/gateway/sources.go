@@ -0,0 +1,211 @@
+package gateway
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// JobSource identifies the client that submitted a job
+type JobSource struct {
+	IP        string `json:"ip"`
+	UserAgent string `json:"user_agent,omitempty"`
+	APIKey    string `json:"api_key,omitempty"` // Fingerprint only; raw keys are never stored
+}
+
+// ID returns the key used for per-source statistics: the API key fingerprint if present, else the IP
+func (src JobSource) ID() string {
+	if src.APIKey != "" {
+		return "key:" + src.APIKey
+	}
+	return "ip:" + src.IP
+}
+
+// SourceStats aggregates submissions from a single source
+type SourceStats struct {
+	Submitted  int       `json:"submitted"`
+	Completed  int       `json:"completed"`
+	Failed     int       `json:"failed"`
+	Rejected   int       `json:"rejected"`
+	LastSeen   time.Time `json:"last_seen"`
+	LastIP     string    `json:"last_ip"`
+	UserAgents []string  `json:"user_agents"`
+}
+
+const maxUserAgentsPerSource = 5
+
+// SourceTracker records per-source statistics and enforces the admin denylist
+type SourceTracker struct {
+	mu                sync.RWMutex
+	stats             map[string]*SourceStats
+	denylist          map[string]time.Time // Normalized source ID ("ip:..." / "key:...") -> time added
+	trustProxyHeaders bool
+}
+
+func NewSourceTracker(trustProxyHeaders bool) *SourceTracker {
+	return &SourceTracker{
+		stats:             make(map[string]*SourceStats),
+		denylist:          make(map[string]time.Time),
+		trustProxyHeaders: trustProxyHeaders,
+	}
+}
+
+// Identify extracts the client IP, user agent and API key from a request
+func (t *SourceTracker) Identify(r *http.Request) JobSource {
+	ip := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		ip = host
+	}
+
+	// X-Forwarded-For is client-controlled, so only honor it behind a trusted proxy
+	if t.trustProxyHeaders {
+		if fwd := r.Header.Get("X-Forwarded-For"); fwd != "" {
+			ip = strings.TrimSpace(strings.Split(fwd, ",")[0])
+		}
+	}
+
+	return JobSource{
+		IP:        ip,
+		UserAgent: r.UserAgent(),
+		APIKey:    fingerprintAPIKey(r.Header.Get("X-API-Key")),
+	}
+}
+
+// IsDenied reports whether the source's IP or API key is on the denylist
+func (t *SourceTracker) IsDenied(src JobSource) bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	if _, denied := t.denylist["ip:"+src.IP]; denied {
+		return true
+	}
+	_, denied := t.denylist[src.ID()]
+	return denied
+}
+
+// RecordSubmitted counts an accepted submission
+func (t *SourceTracker) RecordSubmitted(src JobSource) {
+	t.update(src, func(st *SourceStats) { st.Submitted++ })
+}
+
+// RecordRejected counts a submission refused by the denylist
+func (t *SourceTracker) RecordRejected(src JobSource) {
+	t.update(src, func(st *SourceStats) { st.Rejected++ })
+}
+
+// RecordResult counts a finished job
+func (t *SourceTracker) RecordResult(src JobSource, success bool) {
+	t.update(src, func(st *SourceStats) {
+		if success {
+			st.Completed++
+		} else {
+			st.Failed++
+		}
+	})
+}
+
+func (t *SourceTracker) update(src JobSource, fn func(*SourceStats)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	st, exists := t.stats[src.ID()]
+	if !exists {
+		st = &SourceStats{}
+		t.stats[src.ID()] = st
+	}
+
+	fn(st)
+	st.LastSeen = time.Now()
+	st.LastIP = src.IP
+
+	if src.UserAgent != "" && len(st.UserAgents) < maxUserAgentsPerSource {
+		for _, ua := range st.UserAgents {
+			if ua == src.UserAgent {
+				return
+			}
+		}
+		st.UserAgents = append(st.UserAgents, src.UserAgent)
+	}
+}
+
+// Snapshot returns a copy of all per-source statistics keyed by source ID
+func (t *SourceTracker) Snapshot() map[string]SourceStats {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	snapshot := make(map[string]SourceStats, len(t.stats))
+	for id, st := range t.stats {
+		cp := *st
+		cp.UserAgents = append([]string(nil), st.UserAgents...)
+		snapshot[id] = cp
+	}
+	return snapshot
+}
+
+// Deny adds a source to the denylist and returns the normalized entry.
+// Accepts a source ID as shown in stats, a bare IP, or a raw API key.
+func (t *SourceTracker) Deny(entry string) string {
+	entry = normalizeSourceEntry(entry)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.denylist[entry] = time.Now()
+	return entry
+}
+
+// Allow removes an entry from the denylist, reporting whether it was present
+func (t *SourceTracker) Allow(entry string) bool {
+	entry = normalizeSourceEntry(entry)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	_, existed := t.denylist[entry]
+	delete(t.denylist, entry)
+	return existed
+}
+
+// Denylist returns the current denylist entries, oldest first
+func (t *SourceTracker) Denylist() []map[string]interface{} {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	entries := make([]map[string]interface{}, 0, len(t.denylist))
+	for entry, added := range t.denylist {
+		entries = append(entries, map[string]interface{}{
+			"entry":    entry,
+			"added_at": added,
+		})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i]["added_at"].(time.Time).Before(entries[j]["added_at"].(time.Time))
+	})
+	return entries
+}
+
+// normalizeSourceEntry converts a bare IP or raw API key into a source ID
+func normalizeSourceEntry(entry string) string {
+	entry = strings.TrimSpace(entry)
+	switch {
+	case strings.HasPrefix(entry, "ip:"), strings.HasPrefix(entry, "key:"):
+		return entry
+	case net.ParseIP(entry) != nil:
+		return "ip:" + entry
+	default:
+		return "key:" + fingerprintAPIKey(entry)
+	}
+}
+
+// fingerprintAPIKey returns a short stable hash so keys can be told apart without being stored
+func fingerprintAPIKey(key string) string {
+	if key == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:6])
+}
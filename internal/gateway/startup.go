@@ -0,0 +1,170 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/ahmadhassan44/container-orchestrator/pkg/config"
+)
+
+// Startup placement policies (INITIAL_PLACEMENT)
+const (
+	PlacementPack   = "pack"   // Lowest core IDs first
+	PlacementSpread = "spread" // Cores furthest apart first
+	PlacementCores  = "cores"  // Exactly the cores listed in INITIAL_WORKER_CORES
+)
+
+// readyPollInterval is how often a starting worker's health endpoint is probed
+const readyPollInterval = 100 * time.Millisecond
+
+// InitialCores chooses the cores for the initial workers under a placement policy
+func InitialCores(policy string, count int, listed []int) ([]int, error) {
+	all := make([]int, 0, len(coreMaps))
+	for coreID := range coreMaps {
+		all = append(all, coreID)
+	}
+	slices.Sort(all)
+
+	switch policy {
+	case PlacementPack:
+		return all[:min(max(count, 0), len(all))], nil
+	case PlacementSpread:
+		return spreadOrder(all)[:min(max(count, 0), len(all))], nil
+	case PlacementCores:
+		if len(listed) == 0 {
+			return nil, fmt.Errorf("INITIAL_PLACEMENT=cores needs INITIAL_WORKER_CORES")
+		}
+		cores := make([]int, 0, len(listed))
+		for _, coreID := range listed {
+			if _, valid := coreMaps[coreID]; !valid {
+				return nil, fmt.Errorf("invalid core ID in INITIAL_WORKER_CORES: %d (valid: %v)", coreID, all)
+			}
+			if !slices.Contains(cores, coreID) {
+				cores = append(cores, coreID)
+			}
+		}
+		return cores, nil
+	default:
+		return nil, fmt.Errorf("unknown INITIAL_PLACEMENT %q (want %s, %s or %s)", policy, PlacementPack, PlacementSpread, PlacementCores)
+	}
+}
+
+// spreadOrder orders cores so each next one is as far as possible from those
+// already chosen (ties go to the lower ID), e.g. 1, 3, 2
+func spreadOrder(sorted []int) []int {
+	order := []int{sorted[0]}
+	for len(order) < len(sorted) {
+		best, bestDist := 0, -1
+		for _, coreID := range sorted {
+			if slices.Contains(order, coreID) {
+				continue
+			}
+			dist := -1
+			for _, chosen := range order {
+				if d := abs(coreID - chosen); dist < 0 || d < dist {
+					dist = d
+				}
+			}
+			if dist > bestDist {
+				best, bestDist = coreID, dist
+			}
+		}
+		order = append(order, best)
+	}
+	return order
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// StartInitialWorkers starts the startup workers on the cores the placement policy
+// picks, up to STARTUP_CONCURRENCY at a time, and waits for each to pass its health
+// probe. Container creation itself is serialized by the orchestrator; what runs
+// concurrently is the workers booting. Returns how many workers became ready.
+func (o *Orchestrator) StartInitialWorkers(cfg *config.Config) (int, error) {
+	cores, err := InitialCores(cfg.InitialPlacement, cfg.InitialWorkers, cfg.InitialWorkerCores)
+	if err != nil {
+		return 0, err
+	}
+	if cfg.InitialPlacement != PlacementCores && cfg.InitialWorkers > len(coreMaps) {
+		log.Printf("[Startup] Cannot spawn more than %d workers (hardware limit)", len(coreMaps))
+	}
+	log.Printf("[Startup] Spawning %d initial worker(s) on cores %v (placement: %s)", len(cores), cores, cfg.InitialPlacement)
+
+	timeout := time.Duration(max(cfg.StartupReadyTimeout, 1)) * time.Second
+	sem := make(chan struct{}, max(cfg.StartupConcurrency, 1))
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	ready := 0
+
+	for _, coreID := range cores {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			start := time.Now()
+			if _, err := o.StartWorker(coreID); err != nil {
+				log.Printf("[WARNING] Failed to start initial worker on core %d: %v", coreID, err)
+				return
+			}
+			if err := o.WaitWorkerReady(coreID, timeout); err != nil {
+				log.Printf("[WARNING] Initial worker on core %d not ready: %v", coreID, err)
+				return
+			}
+			log.Printf("[Startup] Worker on core %d ready in %s", coreID, time.Since(start).Round(time.Millisecond))
+
+			mu.Lock()
+			ready++
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	return ready, nil
+}
+
+// WaitWorkerReady polls a worker's health endpoint until it answers 200 OK or
+// timeout elapses. A worker that misses the deadline is left running; the health
+// checker takes it from there.
+func (o *Orchestrator) WaitWorkerReady(coreID int, timeout time.Duration) error {
+	worker, exists := o.GetWorkerByCore(coreID)
+	if !exists {
+		return fmt.Errorf("no worker on core %d", coreID)
+	}
+
+	ctx, cancel := context.WithTimeout(o.ctx, timeout)
+	defer cancel()
+
+	url := worker.BaseURL + "/health"
+	ticker := time.NewTicker(readyPollInterval)
+	defer ticker.Stop()
+
+	for {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return err
+		}
+		if resp, err := http.DefaultClient.Do(req); err == nil {
+			resp.Body.Close()
+			if resp.StatusCode == http.StatusOK {
+				return nil
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("no healthy response from %s within %s", worker.BaseURL, timeout)
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,255 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/api/types"
+)
+
+const (
+	// statsCacheTTL is how long a container stats sample is served before Docker is
+	// asked again, so dashboards polling several times a second cost one call
+	statsCacheTTL = 2 * time.Second
+
+	// statsTimeout bounds one stats call; a non-streaming sample takes Docker about
+	// a second because it measures CPU over an interval
+	statsTimeout = 5 * time.Second
+
+	// maxStatsSize bounds the raw sample read from the daemon
+	maxStatsSize = 1 << 20
+
+	// statsReconcileInterval is how often the collector starts streams for new
+	// workers, stops those of departed ones, and restarts broken ones
+	statsReconcileInterval = 2 * time.Second
+)
+
+// statsSample is the most recent raw stats document for one container
+type statsSample struct {
+	containerID string
+	raw         json.RawMessage
+	sampledAt   time.Time
+}
+
+// workerStatsCache holds the latest sample per core
+type workerStatsCache struct {
+	mu      sync.Mutex
+	samples map[int]statsSample
+	streams map[string]context.CancelFunc // Stats streams open, by container ID
+	maxAge  time.Duration                 // How long a measured CPU sample is trusted (0 = never used)
+}
+
+// WorkerStats returns the most recent raw Docker stats sample for the worker on a
+// core, exactly as the daemon reported it (CPU and pre-CPU counters, memory,
+// network, block I/O, PIDs and throttling), and when it was taken. A sample
+// younger than statsCacheTTL is reused.
+func (o *Orchestrator) WorkerStats(ctx context.Context, coreID int) (json.RawMessage, time.Time, error) {
+	worker, exists := o.GetWorkerByCore(coreID)
+	if !exists {
+		return nil, time.Time{}, fmt.Errorf("no worker on core %d", coreID)
+	}
+
+	o.stats.mu.Lock()
+	cached, found := o.stats.samples[coreID]
+	o.stats.mu.Unlock()
+	if found && cached.containerID == worker.ContainerID && time.Since(cached.sampledAt) < statsCacheTTL {
+		return cached.raw, cached.sampledAt, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, statsTimeout)
+	defer cancel()
+
+	stats, err := o.cli.ContainerStats(ctx, worker.ContainerID, false)
+	if err != nil {
+		o.mu.Lock()
+		o.recordStatsLocked(coreID, false)
+		o.mu.Unlock()
+		return nil, time.Time{}, fmt.Errorf("container stats: %w", err)
+	}
+	defer stats.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(stats.Body, maxStatsSize))
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("reading container stats: %w", err)
+	}
+	if !json.Valid(body) {
+		return nil, time.Time{}, fmt.Errorf("daemon returned malformed stats")
+	}
+
+	sample := statsSample{containerID: worker.ContainerID, raw: body, sampledAt: time.Now()}
+	o.stats.mu.Lock()
+	o.stats.samples[coreID] = sample
+	o.stats.mu.Unlock()
+	o.mu.Lock()
+	o.recordStatsLocked(coreID, true)
+	o.mu.Unlock()
+
+	return sample.raw, sample.sampledAt, nil
+}
+
+// handleWorkerStats serves the raw Docker stats sample for the worker on a core
+func (s *Server) handleWorkerStats(w http.ResponseWriter, r *http.Request) {
+	coreID, err := strconv.Atoi(r.PathValue("core"))
+	if err != nil {
+		http.Error(w, "Core must be an integer", http.StatusBadRequest)
+		return
+	}
+	if _, exists := s.scheduler.orchestrator.GetWorkerByCore(coreID); !exists {
+		http.Error(w, fmt.Sprintf("No worker on core %d", coreID), http.StatusNotFound)
+		return
+	}
+
+	raw, sampledAt, err := s.scheduler.orchestrator.WorkerStats(r.Context(), coreID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Last-Modified", sampledAt.UTC().Format(http.TimeFormat))
+	w.Header().Set("Cache-Control", fmt.Sprintf("max-age=%d", int(statsCacheTTL.Seconds())))
+	w.Write(raw)
+}
+
+// StartStatsCollector streams Docker stats for every worker and feeds the CPU it
+// measures into the scheduler's view of the worker (STATS_TELEMETRY). A stream
+// that breaks is reopened on the next pass; meanwhile the worker's CPU falls back
+// to its jobs' estimates once the last sample is STATS_MAX_AGE seconds old.
+func (o *Orchestrator) StartStatsCollector() {
+	if o.stats.maxAge <= 0 {
+		return
+	}
+	log.Printf("[Stats] Measuring worker CPU from Docker stats (samples trusted for %s)", o.stats.maxAge)
+
+	go func() {
+		ticker := time.NewTicker(statsReconcileInterval)
+		defer ticker.Stop()
+
+		for {
+			o.reconcileStatsStreams()
+			select {
+			case <-o.ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// reconcileStatsStreams opens a stats stream for each worker without one and
+// closes those of containers no longer tracked
+func (o *Orchestrator) reconcileStatsStreams() {
+	tracked := o.trackedContainers()
+	live := make(map[string]bool, len(tracked))
+	for _, containerID := range tracked {
+		live[containerID] = true
+	}
+
+	o.stats.mu.Lock()
+	defer o.stats.mu.Unlock()
+	for containerID, stop := range o.stats.streams {
+		if !live[containerID] {
+			stop()
+			delete(o.stats.streams, containerID)
+		}
+	}
+	if !o.DaemonConnected() {
+		return
+	}
+	for coreID, containerID := range tracked {
+		if _, open := o.stats.streams[containerID]; open {
+			continue
+		}
+		ctx, cancel := context.WithCancel(o.ctx)
+		o.stats.streams[containerID] = cancel
+		go o.streamStats(ctx, coreID, containerID)
+	}
+}
+
+// streamStats reads a worker's stats stream until it ends or ctx is cancelled,
+// recording each sample
+func (o *Orchestrator) streamStats(ctx context.Context, coreID int, containerID string) {
+	defer func() {
+		o.stats.mu.Lock()
+		if ctx.Err() == nil {
+			delete(o.stats.streams, containerID) // Reopened on the next pass
+		}
+		o.stats.mu.Unlock()
+	}()
+
+	stats, err := o.cli.ContainerStats(ctx, containerID, true)
+	if err != nil {
+		if ctx.Err() == nil {
+			o.mu.Lock()
+			o.recordStatsLocked(coreID, false)
+			o.mu.Unlock()
+			log.Printf("[Stats] Worker on Core %d: %v", coreID, err)
+		}
+		return
+	}
+	defer stats.Body.Close()
+
+	decoder := json.NewDecoder(stats.Body)
+	for {
+		var raw json.RawMessage
+		if err := decoder.Decode(&raw); err != nil {
+			return
+		}
+		var sample types.StatsJSON
+		if err := json.Unmarshal(raw, &sample); err != nil {
+			continue
+		}
+
+		now := time.Now()
+		o.stats.mu.Lock()
+		o.stats.samples[coreID] = statsSample{containerID: containerID, raw: raw, sampledAt: now}
+		o.stats.mu.Unlock()
+
+		cpu, measured := standardWorkerCPU(coreID, &sample)
+		o.mu.Lock()
+		o.recordStatsLocked(coreID, true)
+		if worker, exists := o.workers[coreID]; exists && worker.ContainerID == containerID && measured {
+			worker.MeasuredCPU, worker.MeasuredAt, worker.PendingCPU = cpu, now, 0
+			o.refreshCPULocked(worker, now)
+		}
+		o.mu.Unlock()
+	}
+}
+
+// standardWorkerCPU is the CPU a stats sample measured, in percent of a standard
+// worker (the core's default cpuset), as docker stats computes it. False when
+// the sample carries no CPU counters to compare, e.g. the first of a stream.
+func standardWorkerCPU(coreID int, sample *types.StatsJSON) (float64, bool) {
+	cpuDelta := float64(sample.CPUStats.CPUUsage.TotalUsage) - float64(sample.PreCPUStats.CPUUsage.TotalUsage)
+	systemDelta := float64(sample.CPUStats.SystemUsage) - float64(sample.PreCPUStats.SystemUsage)
+	cpus := float64(sample.CPUStats.OnlineCPUs)
+	if sample.PreCPUStats.SystemUsage == 0 || systemDelta <= 0 || cpuDelta < 0 || cpus == 0 {
+		return 0, false
+	}
+	standard, _ := parseCpuset(coreMaps[coreID])
+	return cpuDelta / systemDelta * cpus * 100 / float64(max(len(standard), 1)), true
+}
+
+// measuredLocked reports whether a worker's measured CPU is fresh enough to use
+// (caller holds o.mu)
+func (o *Orchestrator) measuredLocked(worker *WorkerInfo, now time.Time) bool {
+	return o.stats.maxAge > 0 && !worker.MeasuredAt.IsZero() && now.Sub(worker.MeasuredAt) <= o.stats.maxAge
+}
+
+// refreshCPULocked sets the CPU a worker counts as using: its last measurement
+// plus the estimates of jobs placed since, or its jobs' estimates alone when the
+// measurement is missing or stale (caller holds o.mu)
+func (o *Orchestrator) refreshCPULocked(worker *WorkerInfo, now time.Time) {
+	if o.measuredLocked(worker, now) {
+		worker.CurrentCPU = worker.MeasuredCPU + worker.PendingCPU
+		return
+	}
+	worker.CurrentCPU = worker.ReservedCPU
+}
@@ -0,0 +1,35 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+
+	"github.com/ahmadhassan44/container-orchestrator/pkg/protocol"
+)
+
+// eventWriter streams NDJSON StreamEvents to a client, flushing after each line
+type eventWriter struct {
+	mu      sync.Mutex
+	w       http.ResponseWriter
+	encoder *json.Encoder
+}
+
+func newEventWriter(w http.ResponseWriter) *eventWriter {
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("Cache-Control", "no-cache")
+	return &eventWriter{w: w, encoder: json.NewEncoder(w)}
+}
+
+// send writes one event; safe for concurrent use
+func (e *eventWriter) send(event protocol.StreamEvent) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if err := e.encoder.Encode(event); err != nil {
+		return
+	}
+	if flusher, ok := e.w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
@@ -0,0 +1,226 @@
+package gateway
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/ahmadhassan44/container-orchestrator/pkg/config"
+)
+
+// Limits on taints and tolerations
+const (
+	maxTaints      = 16
+	maxTaintLength = 63
+)
+
+// tolerateAll is the toleration that tolerates every taint
+const tolerateAll = "*"
+
+// Taints mark capacity normal jobs should stay off, e.g. "thermally-limited" or
+// "experimental-image=v2". A job is only placed on a core, or spawns a worker on
+// it, if it tolerates every taint of the node and of the core.
+type Taints struct {
+	node []string // Apply to every core
+
+	mu    sync.RWMutex
+	cores map[int][]string
+}
+
+func NewTaints(cfg *config.Config) *Taints {
+	t := &Taints{cores: make(map[int][]string)}
+	if err := validateTaints(cfg.NodeTaints); err != nil {
+		log.Printf("[WARNING] Ignoring NODE_TAINTS: %v", err)
+	} else {
+		t.node = cfg.NodeTaints
+	}
+
+	for _, spec := range cfg.WorkerTaints {
+		core, taint, err := parseCoreTaint(spec)
+		if err != nil {
+			log.Printf("[WARNING] Ignoring WORKER_TAINTS entry %q: %v", spec, err)
+			continue
+		}
+		if !slices.Contains(t.cores[core], taint) {
+			t.cores[core] = append(t.cores[core], taint)
+		}
+	}
+	return t
+}
+
+// parseCoreTaint parses "core:taint"
+func parseCoreTaint(spec string) (int, string, error) {
+	coreText, taint, found := strings.Cut(spec, ":")
+	if !found {
+		return 0, "", fmt.Errorf("expected core:taint")
+	}
+	core, err := strconv.Atoi(strings.TrimSpace(coreText))
+	if _, exists := coreMaps[core]; err != nil || !exists {
+		return 0, "", fmt.Errorf("unknown core %q", coreText)
+	}
+	taint = strings.TrimSpace(taint)
+	if err := validateTaint(taint); err != nil {
+		return 0, "", err
+	}
+	return core, taint, nil
+}
+
+// validateTaint checks a taint or toleration is key or key=value, with no
+// separators a config list or selector would split on
+func validateTaint(taint string) error {
+	key, _, _ := strings.Cut(taint, "=")
+	if key == "" || len(taint) > maxTaintLength || strings.ContainsAny(taint, ",:; \t") {
+		return fmt.Errorf("invalid taint %q: must be key or key=value, at most %d characters, without ',', ':', ';' or spaces",
+			taint, maxTaintLength)
+	}
+	return nil
+}
+
+func validateTaints(taints []string) error {
+	if len(taints) > maxTaints {
+		return fmt.Errorf("too many taints: %d (max %d)", len(taints), maxTaints)
+	}
+	for _, taint := range taints {
+		if err := validateTaint(taint); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateTolerations checks a job's tolerations: taints, keys or "*"
+func validateTolerations(tolerations []string) error {
+	if len(tolerations) > maxTaints {
+		return fmt.Errorf("too many tolerations: %d (max %d)", len(tolerations), maxTaints)
+	}
+	for _, toleration := range tolerations {
+		if toleration == tolerateAll {
+			continue
+		}
+		if err := validateTaint(toleration); err != nil {
+			return fmt.Errorf("invalid toleration %q: must be \"*\", key or key=value", toleration)
+		}
+	}
+	return nil
+}
+
+// tolerates reports whether tolerations cover taint: "*", its key, or the taint itself
+func tolerates(tolerations []string, taint string) bool {
+	key, _, _ := strings.Cut(taint, "=")
+	for _, toleration := range tolerations {
+		if toleration == tolerateAll || toleration == key || toleration == taint {
+			return true
+		}
+	}
+	return false
+}
+
+// On returns coreID's taints, the node's included
+func (t *Taints) On(coreID int) []string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return append(slices.Clone(t.node), t.cores[coreID]...)
+}
+
+// Tolerated reports whether a job with tolerations may run on coreID
+func (t *Taints) Tolerated(coreID int, tolerations []string) bool {
+	for _, taint := range t.On(coreID) {
+		if !tolerates(tolerations, taint) {
+			return false
+		}
+	}
+	return true
+}
+
+// Set replaces coreID's own taints (empty = none)
+func (t *Taints) Set(coreID int, taints []string) error {
+	if err := validateTaints(taints); err != nil {
+		return err
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if len(taints) == 0 {
+		delete(t.cores, coreID)
+		return nil
+	}
+	t.cores[coreID] = slices.Compact(slices.Sorted(slices.Values(taints)))
+	return nil
+}
+
+// Cores returns each tainted core's own taints
+func (t *Taints) Cores() map[int][]string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	cores := make(map[int][]string, len(t.cores))
+	for core, taints := range t.cores {
+		cores[core] = slices.Clone(taints)
+	}
+	return cores
+}
+
+// Status lists the node's taints and each tainted core's own
+func (t *Taints) Status() map[string]interface{} {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	cores := make([]map[string]interface{}, 0, len(t.cores))
+	for core, taints := range t.cores {
+		cores = append(cores, map[string]interface{}{"core_id": core, "taints": taints})
+	}
+	sort.Slice(cores, func(i, j int) bool { return cores[i]["core_id"].(int) < cores[j]["core_id"].(int) })
+	return map[string]interface{}{
+		"node":  append([]string{}, t.node...),
+		"cores": cores,
+	}
+}
+
+// spawnableFor returns the filter for cores a job may spawn a worker on: clear of
+// maintenance for duration seconds, free of taints it doesn't tolerate, in or
+// out of the sandbox pool as its operation requires, and open to its queue
+func (s *Scheduler) spawnableFor(queue, operation string, tolerations []string, duration float64) func(coreID int) bool {
+	return func(coreID int) bool {
+		return s.maintenance.Clear(coreID, duration) && s.taints.Tolerated(coreID, tolerations) &&
+			s.sandbox.Allows(coreID, operation, tolerations) && s.queues.allows(queue, coreID)
+	}
+}
+
+// handleGetTaints lists the node's and cores' taints
+func (s *Server) handleGetTaints(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.scheduler.taints.Status())
+}
+
+// handleSetTaints replaces a core's taints: {"taints": ["thermally-limited"]}. Running
+// jobs are left alone; only later placements are affected.
+func (s *Server) handleSetTaints(w http.ResponseWriter, r *http.Request) {
+	coreID, err := strconv.Atoi(r.PathValue("core"))
+	if _, exists := coreMaps[coreID]; err != nil || !exists {
+		http.Error(w, "Unknown core", http.StatusNotFound)
+		return
+	}
+	var body struct {
+		Taints []string `json:"taints"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "Body must be JSON with a \"taints\" list", http.StatusBadRequest)
+		return
+	}
+	if err := s.scheduler.taints.Set(coreID, body.Taints); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	log.Printf("[Audit] Core %d taints set to %v", coreID, body.Taints)
+
+	// Queued jobs may fit on the core now
+	s.scheduler.wakeQueue()
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"core_id": coreID, "taints": s.scheduler.taints.On(coreID)})
+}
@@ -0,0 +1,269 @@
+package gateway
+
+import (
+	"bufio"
+	"encoding/csv"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/ahmadhassan44/container-orchestrator/pkg/config"
+	"github.com/ahmadhassan44/container-orchestrator/pkg/protocol"
+)
+
+// telemetryFlushInterval bounds how long written rows sit in the file buffer
+const telemetryFlushInterval = time.Second
+
+// Telemetry row events
+const (
+	telemetryPlaced  = "placed"  // Dispatched to a worker on submission
+	telemetryQueued  = "queued"  // Put in a queue because nothing could run it
+	telemetryDequeue = "dequeue" // Taken from a queue and dispatched
+	telemetryOutcome = "outcome" // Finished (completed, failed or cancelled)
+)
+
+// telemetryColumns is the CSV header; every row has all columns, empty where
+// they don't apply to its event
+var telemetryColumns = []string{
+	"timestamp", "event", "job_id", "operation", "queue", "strategy", "placement",
+	"core_id", "estimated_cpu", "estimated_duration", "worker_cpu", "workers", "queue_depth",
+	"queue_wait", "run_time", "attempts", "status", "error", "submitted_at",
+}
+
+// SchedulingDecision is one placement decision the scheduler made for a job
+type SchedulingDecision struct {
+	At                time.Time
+	JobID             string
+	Event             string // telemetryPlaced, telemetryQueued or telemetryDequeue
+	Operation         string
+	Strategy          string
+	Queue             string
+	Placement         string
+	CoreID            int     // 0 when queued
+	EstimatedCPU      float64 // Percent
+	EstimatedDuration float64 // Seconds
+	WorkerCPU         float64 // Chosen worker's reserved CPU before this job
+	Workers           int     // Workers running at the time
+	QueueDepth        int     // Jobs waiting across all queues
+	QueueWait         float64 // Seconds, for dequeues
+}
+
+// DecisionListener is told about every scheduling decision. It runs on the
+// scheduling path (sometimes under the scheduling lock), so it must not block.
+type DecisionListener func(d SchedulingDecision)
+
+// SetDecisionListener registers a callback for scheduling decisions
+func (s *Scheduler) SetDecisionListener(fn DecisionListener) {
+	s.runningMu.Lock()
+	defer s.runningMu.Unlock()
+	s.decisionListener = fn
+}
+
+// notifyDecision counts a decision towards the strategy experiment and reports it
+// to the listener, if any; worker is nil when queued
+func (s *Scheduler) notifyDecision(event string, req *protocol.ComputeRequest, queue string, estimatedCPU, duration float64,
+	worker *WorkerInfo, placement string, queueWait time.Duration) {
+	if req.JobID == "" {
+		return
+	}
+	d := SchedulingDecision{
+		At:                time.Now(),
+		JobID:             req.JobID,
+		Event:             event,
+		Operation:         operationName(req),
+		Strategy:          s.jobStrategy(req.JobID),
+		Queue:             queue,
+		Placement:         placement,
+		EstimatedCPU:      estimatedCPU,
+		EstimatedDuration: duration,
+		QueueWait:         queueWait.Seconds(),
+	}
+	if worker != nil {
+		d.CoreID = worker.CoreID
+		d.WorkerCPU = worker.CurrentCPU
+	}
+	s.experiment.recordDecision(d, worker, s.config.MaxCPUThreshold)
+	switch {
+	case worker == nil:
+		s.trace(req, traceSchedule, nil, "queued in %q; %d job(s) now waiting across all queues", queue, s.QueueLength())
+	case event == telemetryDequeue:
+		s.trace(req, traceSchedule, nil, "dequeued from %q after %s onto Worker-Core-%d (%.1f%% CPU in use)",
+			queue, queueWait.Round(time.Millisecond), worker.CoreID, worker.CurrentCPU)
+	default:
+		s.trace(req, traceSchedule, nil, "placed on Worker-Core-%d (%s, %.1f%% CPU in use)", worker.CoreID, placement, worker.CurrentCPU)
+	}
+
+	s.runningMu.Lock()
+	fn := s.decisionListener
+	s.runningMu.Unlock()
+	if fn == nil {
+		return
+	}
+	d.Workers = s.orchestrator.GetWorkerCount()
+	d.QueueDepth = s.QueueLength()
+	fn(d)
+}
+
+// TelemetryExporter writes every scheduling decision and job outcome as a CSV
+// row to TELEMETRY_FILE, for offline analysis (e.g. pandas.read_csv). Rows are
+// handed to a background writer through a bounded buffer; when it is full,
+// rows are dropped rather than slowing scheduling down.
+type TelemetryExporter struct {
+	path    string
+	rows    chan []string
+	metrics *Metrics
+}
+
+// NewTelemetryExporter returns nil when no TELEMETRY_FILE is configured or it
+// can't be opened
+func NewTelemetryExporter(cfg *config.Config, metrics *Metrics) *TelemetryExporter {
+	if cfg.TelemetryFile == "" {
+		return nil
+	}
+	metrics.Register("orchestrator_telemetry_rows_total", metricCounter, "Telemetry rows, by event and result (written or dropped)")
+
+	w, err := openTelemetryFile(cfg.TelemetryFile)
+	if err != nil {
+		log.Printf("[WARNING] Telemetry export disabled: %v", err)
+		return nil
+	}
+
+	t := &TelemetryExporter{
+		path:    cfg.TelemetryFile,
+		rows:    make(chan []string, max(cfg.TelemetryBuffer, 1)),
+		metrics: metrics,
+	}
+	go t.run(w)
+
+	log.Printf("[Telemetry] Exporting scheduling decisions and job outcomes to %s", t.path)
+	return t
+}
+
+// openTelemetryFile opens the file for appending, writing the header if it's new
+func openTelemetryFile(path string) (*os.File, error) {
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, err
+		}
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	if info.Size() == 0 {
+		w := csv.NewWriter(f)
+		w.Write(telemetryColumns)
+		w.Flush()
+		if err := w.Error(); err != nil {
+			f.Close()
+			return nil, err
+		}
+	}
+	return f, nil
+}
+
+// run writes rows as they arrive, flushing when the buffer drains or every second
+func (t *TelemetryExporter) run(f *os.File) {
+	buf := bufio.NewWriter(f)
+	w := csv.NewWriter(buf)
+	ticker := time.NewTicker(telemetryFlushInterval)
+	defer ticker.Stop()
+
+	flush := func() {
+		w.Flush()
+		if err := buf.Flush(); err != nil {
+			log.Printf("[Telemetry] Failed to write %s: %v", t.path, err)
+		}
+	}
+	for {
+		select {
+		case row := <-t.rows:
+			w.Write(row)
+			t.metrics.Inc("orchestrator_telemetry_rows_total", "event", row[1], "result", "written")
+			if len(t.rows) == 0 {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
+
+// enqueue hands a row to the writer without blocking
+func (t *TelemetryExporter) enqueue(row []string) {
+	select {
+	case t.rows <- row:
+	default:
+		t.metrics.Inc("orchestrator_telemetry_rows_total", "event", row[1], "result", "dropped")
+	}
+}
+
+// RecordDecision exports a scheduling decision. It is a DecisionListener.
+func (t *TelemetryExporter) RecordDecision(d SchedulingDecision) {
+	row := make([]string, len(telemetryColumns))
+	row[0] = d.At.UTC().Format(time.RFC3339Nano)
+	row[1] = d.Event
+	row[2] = d.JobID
+	row[3] = d.Operation
+	row[4] = d.Queue
+	row[5] = d.Strategy
+	row[6] = d.Placement
+	if d.CoreID != 0 {
+		row[7] = strconv.Itoa(d.CoreID)
+		row[10] = formatFloat(d.WorkerCPU)
+	}
+	row[8] = formatFloat(d.EstimatedCPU)
+	row[9] = formatFloat(d.EstimatedDuration)
+	row[11] = strconv.Itoa(d.Workers)
+	row[12] = strconv.Itoa(d.QueueDepth)
+	if d.Event == telemetryDequeue {
+		row[13] = formatFloat(d.QueueWait)
+	}
+	t.enqueue(row)
+}
+
+// RecordOutcome exports a job's final status. It is a TransitionListener.
+func (t *TelemetryExporter) RecordOutcome(job JobRecord) {
+	switch job.Status {
+	case protocol.StatusCompleted, protocol.StatusFailed, protocol.StatusCancelled:
+	default:
+		return
+	}
+
+	row := make([]string, len(telemetryColumns))
+	row[0] = job.CompletedAt.UTC().Format(time.RFC3339Nano)
+	row[1] = telemetryOutcome
+	row[2] = job.ID
+	row[3] = operationName(&job.Request)
+	row[4] = job.Request.Queue
+	if a := job.Annotations; a != nil {
+		if a.Queue != "" {
+			row[4] = a.Queue
+		}
+		row[5] = a.Strategy
+		row[6] = a.Placement
+		if a.CoreID != 0 {
+			row[7] = strconv.Itoa(a.CoreID)
+		}
+		row[8] = formatFloat(a.EstimatedCPU)
+		row[9] = formatFloat(a.EstimatedDuration)
+		row[13] = formatFloat(a.QueueWait)
+		row[14] = formatFloat(a.RunTime)
+		row[15] = strconv.Itoa(a.Attempts)
+	}
+	row[16] = job.Status.String()
+	row[17] = job.Error
+	row[18] = job.SubmittedAt.UTC().Format(time.RFC3339Nano)
+	t.enqueue(row)
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
@@ -0,0 +1,231 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"math/rand/v2"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ahmadhassan44/container-orchestrator/pkg/config"
+	"github.com/ahmadhassan44/container-orchestrator/pkg/protocol"
+)
+
+// maxTimingJitter bounds the random delay a policy may add to a response
+const maxTimingJitter = 60 * time.Second
+
+// TimingPolicy blurs the timing a tenant can observe of its own jobs, so precise
+// run times and queue waits can't be used to infer what co-located tenants run
+type TimingPolicy struct {
+	JitterMs    int     `json:"jitter_ms"`   // Upper bound of a random delay added before results are returned
+	Granularity float64 `json:"granularity"` // Seconds reported durations and timestamps are rounded to (0 = exact)
+}
+
+func (p TimingPolicy) validate() error {
+	if p.JitterMs < 0 || time.Duration(p.JitterMs)*time.Millisecond > maxTimingJitter {
+		return fmt.Errorf("jitter_ms must be between 0 and %d", maxTimingJitter.Milliseconds())
+	}
+	if p.Granularity < 0 || math.IsNaN(p.Granularity) || math.IsInf(p.Granularity, 0) {
+		return fmt.Errorf("granularity must be a non-negative number of seconds")
+	}
+	return nil
+}
+
+// Delay sleeps for a random time up to the policy's jitter, or until ctx ends
+func (p TimingPolicy) Delay(ctx context.Context) {
+	if p.JitterMs <= 0 {
+		return
+	}
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Duration(rand.Int64N(int64(p.JitterMs)*int64(time.Millisecond) + 1))):
+	}
+}
+
+// round rounds seconds to the policy's granularity
+func (p TimingPolicy) round(seconds float64) float64 {
+	if p.Granularity <= 0 {
+		return seconds
+	}
+	return math.Round(seconds/p.Granularity) * p.Granularity
+}
+
+// truncate rounds a timestamp down to the policy's granularity
+func (p TimingPolicy) truncate(t time.Time) time.Time {
+	if p.Granularity <= 0 || t.IsZero() {
+		return t
+	}
+	return t.Truncate(time.Duration(p.Granularity * float64(time.Second)))
+}
+
+// CoarsenResponse returns a copy of resp with its timings rounded to the granularity
+func (p TimingPolicy) CoarsenResponse(resp *protocol.JobResponse) *protocol.JobResponse {
+	if resp == nil || p.Granularity <= 0 {
+		return resp
+	}
+	cp := *resp
+	if taken, err := time.ParseDuration(resp.TimeTaken); err == nil {
+		cp.TimeTaken = time.Duration(p.round(taken.Seconds()) * float64(time.Second)).String()
+	}
+	cp.Annotations = p.coarsenAnnotations(resp.Annotations)
+	return &cp
+}
+
+func (p TimingPolicy) coarsenAnnotations(a *protocol.JobAnnotations) *protocol.JobAnnotations {
+	if a == nil {
+		return nil
+	}
+	cp := *a
+	cp.EstimatedDuration = p.round(a.EstimatedDuration)
+	cp.EstimatedQueueWait = p.round(a.EstimatedQueueWait)
+	cp.QueueWait = p.round(a.QueueWait)
+	cp.RunTime = p.round(a.RunTime)
+	return &cp
+}
+
+// CoarsenRecord rounds a job record's timestamps, timings and ETA to the granularity
+func (p TimingPolicy) CoarsenRecord(job JobRecord) JobRecord {
+	if p.Granularity <= 0 {
+		return job
+	}
+	job.SubmittedAt = p.truncate(job.SubmittedAt)
+	job.CompletedAt = p.truncate(job.CompletedAt)
+	job.Response = p.CoarsenResponse(job.Response)
+	job.Annotations = p.coarsenAnnotations(job.Annotations)
+	if job.ETA != nil {
+		eta := *job.ETA
+		for _, r := range []*ETARange{&eta.QueueWait, &eta.Completion} {
+			r.P50, r.P90 = p.round(r.P50), p.round(r.P90)
+		}
+		job.ETA = &eta
+	}
+	return job
+}
+
+// TimingPolicies holds the default timing policy and per-source overrides set
+// by the administrator
+type TimingPolicies struct {
+	mu        sync.RWMutex
+	defaults  TimingPolicy
+	overrides map[string]TimingPolicy // Normalized source ID -> policy
+}
+
+func NewTimingPolicies(cfg *config.Config) *TimingPolicies {
+	t := &TimingPolicies{
+		defaults:  TimingPolicy{JitterMs: cfg.TimingJitterMs, Granularity: cfg.TimingGranularity},
+		overrides: make(map[string]TimingPolicy),
+	}
+	if err := t.defaults.validate(); err != nil {
+		log.Printf("[WARNING] Ignoring TIMING_JITTER_MS/TIMING_GRANULARITY: %v", err)
+		t.defaults = TimingPolicy{}
+	}
+	return t
+}
+
+// For returns the policy for a source: an override for its source ID, then for
+// its IP, then the default
+func (t *TimingPolicies) For(src JobSource) TimingPolicy {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	if p, exists := t.overrides[src.ID()]; exists {
+		return p
+	}
+	if p, exists := t.overrides["ip:"+src.IP]; exists {
+		return p
+	}
+	return t.defaults
+}
+
+// Set installs a policy override and returns the normalized source entry
+func (t *TimingPolicies) Set(entry string, p TimingPolicy) (string, error) {
+	if err := p.validate(); err != nil {
+		return "", err
+	}
+	entry = normalizeSourceEntry(entry)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.overrides[entry] = p
+	return entry, nil
+}
+
+// Remove deletes an override, reporting whether it was present
+func (t *TimingPolicies) Remove(entry string) bool {
+	entry = normalizeSourceEntry(entry)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	_, existed := t.overrides[entry]
+	delete(t.overrides, entry)
+	return existed
+}
+
+// Status returns the default policy and the overrides, sorted by source
+func (t *TimingPolicies) Status() map[string]interface{} {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	overrides := make([]map[string]interface{}, 0, len(t.overrides))
+	for entry, p := range t.overrides {
+		overrides = append(overrides, map[string]interface{}{
+			"source":      entry,
+			"jitter_ms":   p.JitterMs,
+			"granularity": p.Granularity,
+		})
+	}
+	sort.Slice(overrides, func(i, j int) bool {
+		return overrides[i]["source"].(string) < overrides[j]["source"].(string)
+	})
+	return map[string]interface{}{
+		"default":   t.defaults,
+		"overrides": overrides,
+	}
+}
+
+// handleGetTiming lists the default timing policy and per-source overrides
+func (s *Server) handleGetTiming(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.timing.Status())
+}
+
+// handleSetTiming sets a source's timing policy (IP, API key, or source ID from /status)
+func (s *Server) handleSetTiming(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Source string `json:"source"`
+		TimingPolicy
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.Source == "" {
+		http.Error(w, "Body must be JSON with a non-empty \"source\"", http.StatusBadRequest)
+		return
+	}
+
+	entry, err := s.timing.Set(body.Source, body.TimingPolicy)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	log.Printf("[Gateway] Timing policy for %s: jitter %dms, granularity %gs", entry, body.JitterMs, body.Granularity)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"source":      entry,
+		"jitter_ms":   body.JitterMs,
+		"granularity": body.Granularity,
+	})
+}
+
+// handleRemoveTiming returns a source to the default timing policy
+func (s *Server) handleRemoveTiming(w http.ResponseWriter, r *http.Request) {
+	if !s.timing.Remove(r.PathValue("entry")) {
+		http.Error(w, "Entry not found", http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
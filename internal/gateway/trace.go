@@ -0,0 +1,135 @@
+package gateway
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ahmadhassan44/container-orchestrator/pkg/protocol"
+)
+
+const (
+	// maxTraceEvents bounds a debug job's trace; later events are counted, not kept
+	maxTraceEvents = 500
+
+	// traceProgressInterval is the least time between progress samples in a trace
+	traceProgressInterval = time.Second
+)
+
+// Trace event kinds
+const (
+	traceSchedule = "schedule" // Estimates, placement candidates and decisions
+	traceDispatch = "dispatch" // Requests to workers and their replies
+	traceProgress = "progress" // Sampled streamed progress
+	traceRetry    = "retry"    // Failed attempts and slices handed back to the queue
+)
+
+// TraceEvent is one entry in a debug job's timeline
+type TraceEvent struct {
+	Time    time.Time         `json:"time"`
+	Kind    string            `json:"kind"`
+	Message string            `json:"message"`
+	Fields  map[string]string `json:"fields,omitempty"` // e.g. dispatch headers
+}
+
+// TraceListener is told about every event traced for a debug job
+type TraceListener func(jobID string, event TraceEvent)
+
+// SetTraceListener registers a callback for debug jobs' trace events
+func (s *Scheduler) SetTraceListener(fn TraceListener) {
+	s.runningMu.Lock()
+	defer s.runningMu.Unlock()
+	s.traceListener = fn
+}
+
+// trace adds an event to req's timeline if it is a debug job. The caller must
+// not hold runningMu.
+func (s *Scheduler) trace(req *protocol.ComputeRequest, kind string, fields map[string]string, format string, args ...interface{}) {
+	if !req.Debug || req.JobID == "" {
+		return
+	}
+	s.runningMu.Lock()
+	fn := s.traceListener
+	s.runningMu.Unlock()
+
+	if fn != nil {
+		fn(req.JobID, TraceEvent{Time: time.Now(), Kind: kind, Message: fmt.Sprintf(format, args...), Fields: fields})
+	}
+}
+
+// traceCandidates records which workers a debug job could be placed on, with
+// the CPU they have in use, and which one was picked (nil = none had room)
+func (s *Scheduler) traceCandidates(req *protocol.ComputeRequest, candidates []*WorkerInfo, estimatedCPU float64, chosen *WorkerInfo) {
+	if !req.Debug {
+		return
+	}
+	fields := make(map[string]string, len(candidates))
+	for _, w := range candidates {
+		fields[fmt.Sprintf("core_%d", w.CoreID)] = fmt.Sprintf("%.1f%% of %.0f%% in use (health %.2f)", w.CurrentCPU, s.config.MaxCPUThreshold*w.effectiveCapacity(), w.Health.Score)
+	}
+	if chosen == nil {
+		s.trace(req, traceSchedule, fields, "none of %d placeable worker(s) has %.1f%% CPU free", len(candidates), estimatedCPU)
+		return
+	}
+	s.trace(req, traceSchedule, fields, "picked Worker-Core-%d of %d placeable worker(s) by %s", chosen.CoreID, len(candidates), s.jobStrategy(req.JobID))
+}
+
+// traceHeaders flattens HTTP headers into trace fields
+func traceHeaders(status string, header http.Header) map[string]string {
+	fields := make(map[string]string, len(header)+1)
+	if status != "" {
+		fields["status"] = status
+	}
+	for name, values := range header {
+		fields[name] = strings.Join(values, ", ")
+	}
+	return fields
+}
+
+// AppendTrace adds an event to a job's trace, counting it as dropped once the
+// trace is full
+func (js *JobStore) AppendTrace(id string, event TraceEvent) {
+	js.mu.Lock()
+	defer js.mu.Unlock()
+
+	job, exists := js.jobs[id]
+	if !exists {
+		return
+	}
+	if len(job.Trace) >= maxTraceEvents {
+		job.TraceDropped++
+		return
+	}
+	job.Trace = append(job.Trace, event)
+}
+
+// handleGetJobTrace serves a debug job's timeline: GET /jobs/{id}/trace
+func (s *Server) handleGetJobTrace(w http.ResponseWriter, r *http.Request) {
+	job, exists := s.jobs.Get(r.PathValue("id"))
+	if !exists {
+		http.Error(w, "Job not found", http.StatusNotFound)
+		return
+	}
+	if !job.Request.Debug {
+		http.Error(w, "Tracing was not requested for this job (set \"debug\": true)", http.StatusNotFound)
+		return
+	}
+	if !s.isAdmin(r) && s.sources.Identify(r).ID() != job.Source.ID() {
+		http.Error(w, "Only the submitter or an admin may read this job's trace", http.StatusForbidden)
+		return
+	}
+
+	events := job.Trace
+	if events == nil {
+		events = []TraceEvent{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"job_id":  job.ID,
+		"status":  job.Status,
+		"events":  events,
+		"dropped": job.TraceDropped,
+	})
+}
@@ -0,0 +1,344 @@
+package gateway
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/ahmadhassan44/container-orchestrator/pkg/config"
+)
+
+const (
+	// warmUpInterval is how often demand is sampled and the warm-up plan re-evaluated
+	warmUpInterval = 15 * time.Second
+
+	// warmUpSmoothing weights the newest day's peak in an hour's moving average
+	warmUpSmoothing = 0.3
+)
+
+// HourlyLoad is the demand model for one hour of the day
+type HourlyLoad struct {
+	Days        int     `json:"days"`         // Days this hour has been observed
+	PeakWorkers float64 `json:"peak_workers"` // Smoothed daily peak of workers in demand
+}
+
+// LoadHistory is a time-of-day seasonal model of worker demand, indexed by local hour.
+// It is persisted so the gateway remembers daily peaks across restarts.
+type LoadHistory struct {
+	Hours [24]HourlyLoad `json:"hours"`
+}
+
+// WarmUpOverride replaces the model's target until it expires
+type WarmUpOverride struct {
+	Workers   int       `json:"workers"` // 0 = no pre-spawning while active
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// WarmUpDecision records one evaluation of the warm-up plan
+type WarmUpDecision struct {
+	At        time.Time `json:"at"`
+	ForHour   int       `json:"for_hour"`          // Local hour the plan was made for
+	Predicted float64   `json:"predicted_workers"` // Model demand for that hour
+	Target    int       `json:"target_workers"`
+	Current   int       `json:"current_workers"`
+	Spawned   int       `json:"spawned"`
+	Reason    string    `json:"reason"`
+}
+
+// WarmUp learns hourly worker demand and pre-spawns workers ahead of predictable
+// daily peaks, so rush-hour jobs don't pay for cold starts
+type WarmUp struct {
+	scheduler *Scheduler
+	enabled   bool
+	path      string        // Where the history is persisted (empty = memory only)
+	lead      time.Duration // How far ahead of an hour its peak is prepared for
+	minDays   int           // Days an hour must be observed before it is trusted
+
+	mu       sync.Mutex
+	history  LoadHistory
+	hour     time.Time // Start of the hour currently being observed
+	peak     int       // Peak demand seen so far this hour
+	override *WarmUpOverride
+	last     *WarmUpDecision
+}
+
+func NewWarmUp(sched *Scheduler, cfg *config.Config) *WarmUp {
+	w := &WarmUp{
+		scheduler: sched,
+		enabled:   cfg.WarmUpEnabled,
+		path:      cfg.LoadHistoryFile,
+		lead:      time.Duration(cfg.WarmUpLead) * time.Second,
+		minDays:   max(cfg.WarmUpMinDays, 1),
+	}
+	if err := w.load(); err != nil {
+		log.Printf("[Warmup] Starting with empty load history: %v", err)
+	}
+	return w
+}
+
+// Start plans immediately (covering a restart during a peak) and then keeps
+// sampling demand and re-planning in the background
+func (w *WarmUp) Start() {
+	w.tick(time.Now())
+
+	go func() {
+		ticker := time.NewTicker(warmUpInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-w.scheduler.orchestrator.ctx.Done():
+				return
+			case now := <-ticker.C:
+				w.tick(now)
+			}
+		}
+	}()
+}
+
+func (w *WarmUp) tick(now time.Time) {
+	w.observe(now, w.demand())
+	if w.enabled {
+		w.plan(now)
+	}
+}
+
+// demand is how many workers current load needs: those running jobs, plus one
+// per waiting job, up to the number of cores
+func (w *WarmUp) demand() int {
+	busy := make(map[int]bool)
+	for _, job := range w.scheduler.RunningJobs() {
+		busy[job.CoreID] = true
+	}
+	return min(len(busy)+len(w.scheduler.WaitingJobs()), len(coreMaps))
+}
+
+// observe records demand for the current hour, folding the previous hour's
+// peak into the model when the hour rolls over
+func (w *WarmUp) observe(now time.Time, demand int) {
+	hour := hourStart(now)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if !w.hour.IsZero() && !w.hour.Equal(hour) {
+		slot := &w.history.Hours[w.hour.Hour()]
+		if slot.Days == 0 {
+			slot.PeakWorkers = float64(w.peak)
+		} else {
+			slot.PeakWorkers = warmUpSmoothing*float64(w.peak) + (1-warmUpSmoothing)*slot.PeakWorkers
+		}
+		slot.Days++
+		w.peak = 0
+
+		if err := w.saveLocked(); err != nil {
+			log.Printf("[Warmup] Failed to persist load history: %v", err)
+		}
+	}
+	w.hour = hour
+	w.peak = max(w.peak, demand)
+}
+
+// plan spawns workers up to the demand predicted for the coming peak. Workers
+// are only ever added here; the model never stops running workers.
+func (w *WarmUp) plan(now time.Time) {
+	w.mu.Lock()
+	decision := w.targetLocked(now)
+	w.mu.Unlock()
+
+	orch := w.scheduler.orchestrator
+	decision.Current = orch.GetWorkerCount()
+	_, maximum := w.scheduler.scaling.limits()
+	for orch.GetWorkerCount() < min(decision.Target, maximum) {
+		coreID, err := orch.GetNextAvailableCore()
+		if err != nil {
+			break
+		}
+		if _, err := orch.StartWorker(coreID); err != nil {
+			log.Printf("[Warmup] Pre-spawn on Core %d failed: %v", coreID, err)
+			break
+		}
+		w.scheduler.scaling.recordSpawn()
+		decision.Spawned++
+	}
+
+	w.mu.Lock()
+	changed := w.last == nil || w.last.Target != decision.Target || w.last.Reason != decision.Reason
+	w.last = &decision
+	w.mu.Unlock()
+
+	if changed || decision.Spawned > 0 {
+		log.Printf("[Warmup] Hour %02d: %s -> target %d worker(s), %d running, spawned %d",
+			decision.ForHour, decision.Reason, decision.Target, decision.Current, decision.Spawned)
+	}
+}
+
+// Target is the worker count the last plan aimed for (0 while warm-up is disabled
+// or hasn't planned yet); scale-down doesn't go below it
+func (w *WarmUp) Target() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if !w.enabled || w.last == nil {
+		return 0
+	}
+	return w.last.Target
+}
+
+// targetLocked decides how many workers should be running now: enough for the
+// busier of the current hour and the hour the lead time reaches into
+func (w *WarmUp) targetLocked(now time.Time) WarmUpDecision {
+	ahead := now.Add(w.lead)
+	decision := WarmUpDecision{At: now, ForHour: ahead.Hour()}
+
+	if w.override != nil {
+		if now.Before(w.override.ExpiresAt) {
+			decision.Target = w.override.Workers
+			decision.Reason = fmt.Sprintf("override until %s", w.override.ExpiresAt.Format(time.TimeOnly))
+			return decision
+		}
+		log.Printf("[Warmup] Override expired")
+		w.override = nil
+	}
+
+	trusted := 0
+	for _, hour := range []int{now.Hour(), ahead.Hour()} {
+		slot := w.history.Hours[hour]
+		if slot.Days >= w.minDays {
+			trusted++
+			decision.Predicted = max(decision.Predicted, slot.PeakWorkers)
+		}
+	}
+	if trusted == 0 {
+		decision.Reason = fmt.Sprintf("insufficient history (need %d day(s))", w.minDays)
+		return decision
+	}
+
+	decision.Target = min(int(math.Ceil(decision.Predicted)), len(coreMaps))
+	decision.Reason = fmt.Sprintf("model predicts %.1f worker(s)", decision.Predicted)
+	return decision
+}
+
+// SetOverride pins the target worker count for a while, or clears the override (nil)
+func (w *WarmUp) SetOverride(override *WarmUpOverride) {
+	w.mu.Lock()
+	w.override = override
+	w.mu.Unlock()
+
+	if override != nil {
+		log.Printf("[Warmup] Override set: %d worker(s) until %s", override.Workers, override.ExpiresAt.Format(time.RFC3339))
+	} else {
+		log.Printf("[Warmup] Override cleared")
+	}
+	if w.enabled {
+		w.plan(time.Now())
+	}
+}
+
+// Status reports the model, the current hour's observation and the latest decision
+func (w *WarmUp) Status() map[string]interface{} {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return map[string]interface{}{
+		"enabled":         w.enabled,
+		"lead_seconds":    int(w.lead.Seconds()),
+		"min_days":        w.minDays,
+		"history":         w.history.Hours,
+		"current_hour":    w.hour.Hour(),
+		"current_peak":    w.peak,
+		"override":        w.override,
+		"latest_decision": w.last,
+	}
+}
+
+func (w *WarmUp) load() error {
+	if w.path == "" {
+		return nil
+	}
+	data, err := os.ReadFile(w.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, &w.history); err != nil {
+		return fmt.Errorf("invalid load history %s: %w", w.path, err)
+	}
+	log.Printf("[Warmup] Loaded load history from %s", w.path)
+	return nil
+}
+
+// saveLocked writes the history atomically so a crash can't leave it truncated
+func (w *WarmUp) saveLocked() error {
+	if w.path == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(w.history, "", "  ")
+	if err != nil {
+		return err
+	}
+	if dir := filepath.Dir(w.path); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return err
+		}
+	}
+	tmp := w.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, w.path)
+}
+
+// hourStart truncates t to the start of its local hour
+func hourStart(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, t.Location())
+}
+
+// handleWarmUpStatus shows the hourly load model and the latest warm-up decision
+func (s *Server) handleWarmUpStatus(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.warmup.Status())
+}
+
+// handleSetWarmUpOverride pins the warm-up target ({"workers": n, "duration_seconds": s})
+func (s *Server) handleSetWarmUpOverride(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Workers         *int `json:"workers"`
+		DurationSeconds int  `json:"duration_seconds"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.Workers == nil {
+		http.Error(w, "Body must be JSON with \"workers\" and \"duration_seconds\"", http.StatusBadRequest)
+		return
+	}
+	if *body.Workers < 0 || *body.Workers > len(coreMaps) {
+		http.Error(w, fmt.Sprintf("workers must be between 0 and %d", len(coreMaps)), http.StatusBadRequest)
+		return
+	}
+	if body.DurationSeconds <= 0 {
+		http.Error(w, "duration_seconds must be positive", http.StatusBadRequest)
+		return
+	}
+
+	override := &WarmUpOverride{
+		Workers:   *body.Workers,
+		ExpiresAt: time.Now().Add(time.Duration(body.DurationSeconds) * time.Second),
+	}
+	s.warmup.SetOverride(override)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(override)
+}
+
+// handleClearWarmUpOverride returns warm-up to the model's target
+func (s *Server) handleClearWarmUpOverride(w http.ResponseWriter, r *http.Request) {
+	s.warmup.SetOverride(nil)
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,233 @@
+package gateway
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/ahmadhassan44/container-orchestrator/pkg/config"
+	"github.com/ahmadhassan44/container-orchestrator/pkg/protocol"
+	"github.com/ahmadhassan44/container-orchestrator/pkg/signing"
+	"github.com/ahmadhassan44/container-orchestrator/pkg/version"
+)
+
+const (
+	// webhookBacklog is how many undelivered events are held before new ones are dropped
+	webhookBacklog = 1000
+
+	// webhookRetryBackoff is the wait before the first redelivery; it doubles each time
+	webhookRetryBackoff = time.Second
+)
+
+// WebhookEvent is the JSON body POSTed to WEBHOOK_URL on a job status change or
+// an SLO alert
+type WebhookEvent struct {
+	Event     string           `json:"event"` // "job.<status>", e.g. "job.failed", "slo.burning" or "core.degraded"
+	Timestamp time.Time        `json:"timestamp"`
+	Job       *JobRecord       `json:"job,omitempty"`    // The record as of the transition
+	SLO       *SLOStatus       `json:"slo,omitempty"`    // The objective as of the alert
+	Core      *CoreDegradation `json:"core,omitempty"`   // The core and its evidence as of the alert
+	Docker    *DaemonStatus    `json:"docker,omitempty"` // The Docker connection as of the alert
+}
+
+// subject names what an event is about, for logs
+func (e WebhookEvent) subject() string {
+	if e.SLO != nil {
+		return "SLO " + e.SLO.Name
+	}
+	if e.Core != nil {
+		return fmt.Sprintf("core %d", e.Core.CoreID)
+	}
+	if e.Docker != nil {
+		return "the Docker daemon"
+	}
+	return e.Job.ID
+}
+
+// WebhookNotifier sends job lifecycle events to an external endpoint. Events are
+// queued and delivered in order by a single goroutine, so a slow endpoint never
+// holds up scheduling; when the backlog is full, new events are dropped.
+type WebhookNotifier struct {
+	url          string
+	events       []string
+	queues       []string
+	operations   []string
+	sources      []string
+	defaultQueue string
+	retries      int
+	secret       string // Signs each delivery (empty = unsigned)
+	client       *http.Client
+	pending      chan WebhookEvent
+	metrics      *Metrics
+}
+
+// NewWebhookNotifier returns nil when no WEBHOOK_URL is configured
+func NewWebhookNotifier(cfg *config.Config, metrics *Metrics) *WebhookNotifier {
+	if cfg.WebhookURL == "" {
+		return nil
+	}
+	metrics.Register("orchestrator_webhooks_total", metricCounter, "Job lifecycle webhooks, by event and result")
+
+	for _, event := range cfg.WebhookEvents {
+		if !slices.Contains(statusNames(), event) {
+			log.Printf("[WARNING] WEBHOOK_EVENTS: unknown job status %q (valid: %v)", event, statusNames())
+		}
+	}
+
+	n := &WebhookNotifier{
+		url:          cfg.WebhookURL,
+		events:       cfg.WebhookEvents,
+		queues:       cfg.WebhookQueues,
+		operations:   cfg.WebhookOperations,
+		sources:      cfg.WebhookSources,
+		defaultQueue: cfg.DefaultQueue,
+		retries:      max(cfg.WebhookRetries, 0),
+		secret:       cfg.WebhookSecret,
+		client:       &http.Client{Timeout: time.Duration(max(cfg.WebhookTimeout, 1)) * time.Second},
+		pending:      make(chan WebhookEvent, webhookBacklog),
+		metrics:      metrics,
+	}
+	go n.run()
+
+	log.Printf("[Webhooks] Sending %v events to %s", n.events, n.url)
+	return n
+}
+
+// statusNames lists every job status name
+func statusNames() []string {
+	names := make([]string, 0, int(protocol.StatusHeld)+1)
+	for status := protocol.StatusAccepted; status <= protocol.StatusHeld; status++ {
+		names = append(names, status.String())
+	}
+	return names
+}
+
+// Notify queues an event for a job transition if it passes the filters.
+// It is a TransitionListener.
+func (n *WebhookNotifier) Notify(job JobRecord) {
+	if !n.matches(job) {
+		return
+	}
+
+	n.enqueue(WebhookEvent{Event: "job." + job.Status.String(), Timestamp: time.Now(), Job: &job})
+}
+
+// Alert queues an SLO alert. Alerts are not subject to the job filters. It is an
+// SLOAlertListener.
+func (n *WebhookNotifier) Alert(event string, status SLOStatus) {
+	n.enqueue(WebhookEvent{Event: event, Timestamp: time.Now(), SLO: &status})
+}
+
+// DegradationAlert queues a core degradation alert. Like SLO alerts, it is not
+// subject to the job filters. It is a DegradationListener.
+func (n *WebhookNotifier) DegradationAlert(event string, status CoreDegradation) {
+	n.enqueue(WebhookEvent{Event: event, Timestamp: time.Now(), Core: &status})
+}
+
+// DaemonAlert queues an alert for the Docker daemon being lost or reconnecting.
+// It is a DaemonListener.
+func (n *WebhookNotifier) DaemonAlert(event string, status DaemonStatus) {
+	n.enqueue(WebhookEvent{Event: event, Timestamp: time.Now(), Docker: &status})
+}
+
+// enqueue hands an event to the delivery goroutine without blocking
+func (n *WebhookNotifier) enqueue(event WebhookEvent) {
+	select {
+	case n.pending <- event:
+	default:
+		n.metrics.Inc("orchestrator_webhooks_total", "event", event.Event, "result", "dropped")
+		log.Printf("[Webhooks] Backlog full, dropped %s for %s", event.Event, event.subject())
+	}
+}
+
+// matches applies the event, queue, operation and source filters
+func (n *WebhookNotifier) matches(job JobRecord) bool {
+	if !slices.Contains(n.events, job.Status.String()) {
+		return false
+	}
+
+	queue := job.Request.Queue
+	if job.Annotations != nil && job.Annotations.Queue != "" {
+		queue = job.Annotations.Queue
+	}
+	if queue == "" {
+		queue = n.defaultQueue
+	}
+	operation := operationName(&job.Request)
+
+	return matchesFilter(n.queues, queue) &&
+		matchesFilter(n.operations, operation) &&
+		matchesFilter(n.sources, job.Source.ID())
+}
+
+// matchesFilter reports whether value is allowed by filter (an empty filter allows all)
+func matchesFilter(filter []string, value string) bool {
+	return len(filter) == 0 || slices.Contains(filter, value)
+}
+
+func (n *WebhookNotifier) run() {
+	for event := range n.pending {
+		n.deliver(event)
+	}
+}
+
+// deliver POSTs an event, retrying with backoff on errors and non-2xx responses
+func (n *WebhookNotifier) deliver(event WebhookEvent) {
+	body, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("[Webhooks] Failed to encode %s for %s: %v", event.Event, event.subject(), err)
+		return
+	}
+
+	backoff := webhookRetryBackoff
+	for attempt := 0; ; attempt++ {
+		err := n.post(body)
+		if err == nil {
+			n.metrics.Inc("orchestrator_webhooks_total", "event", event.Event, "result", "sent")
+			return
+		}
+		if attempt >= n.retries {
+			n.metrics.Inc("orchestrator_webhooks_total", "event", event.Event, "result", "failed")
+			log.Printf("[Webhooks] Giving up on %s for %s after %d attempt(s): %v", event.Event, event.subject(), attempt+1, err)
+			return
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+func (n *WebhookNotifier) post(body []byte) error {
+	resp, err := postSigned(n.client, n.url, body, n.secret)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("endpoint returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// postSigned POSTs a JSON body to a client-facing endpoint, signed with a fresh
+// timestamp and nonce when secret is set
+func postSigned(client *http.Client, target string, body []byte, secret string) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "container-orchestrator/"+version.Get().Version)
+	if secret != "" {
+		if err := signing.Sign(req, secret, body); err != nil {
+			return nil, err
+		}
+	}
+	return client.Do(req)
+}
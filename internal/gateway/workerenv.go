@@ -0,0 +1,311 @@
+package gateway
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/blkiodev"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/go-units"
+
+	"github.com/ahmadhassan44/container-orchestrator/pkg/config"
+)
+
+// reservedWorkerEnv are set by StartWorker itself and can't be overridden from config
+var reservedWorkerEnv = []string{"WORKER_ID", "WORKER_UUID", "DRAIN_TIMEOUT", "GATEWAY_INTERNAL_URL", "INTERNAL_TOKEN"}
+
+var envKeyPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// ConfigureWorkerEnvironment validates the extra env vars, bind mounts, tmpfs
+// mounts and resource limits from config and applies them to workers started
+// from now on. Must be called before any worker is started.
+func (o *Orchestrator) ConfigureWorkerEnvironment(cfg *config.Config) error {
+	env, err := parseWorkerEnv(cfg.WorkerEnv)
+	if err != nil {
+		return err
+	}
+
+	var limits container.Resources
+	if cfg.WorkerMemory != "" {
+		limits.Memory, err = units.RAMInBytes(cfg.WorkerMemory)
+		if err != nil || limits.Memory < minWorkerMemory {
+			return fmt.Errorf("invalid WORKER_MEMORY %q (want a size of at least 6m, e.g. 512m)", cfg.WorkerMemory)
+		}
+	}
+	for _, spec := range cfg.WorkerIOLimits {
+		if err := parseIOLimit(spec, &limits); err != nil {
+			return err
+		}
+	}
+
+	dns, err := parseWorkerDNS(cfg)
+	if err != nil {
+		return err
+	}
+	switch cfg.WorkerEgress {
+	case "", egressAllow:
+	case egressNone:
+		if err := o.checkEgressIsolated(); err != nil {
+			return fmt.Errorf("WORKER_EGRESS=none: %w", err)
+		}
+	default:
+		return fmt.Errorf("invalid WORKER_EGRESS %q (valid: allow, none)", cfg.WorkerEgress)
+	}
+
+	var mounts []mount.Mount
+	for _, spec := range cfg.WorkerMounts {
+		m, err := parseBindMount(spec)
+		if err != nil {
+			return err
+		}
+		mounts = append(mounts, m)
+	}
+	for _, spec := range cfg.WorkerTmpfs {
+		m, err := parseTmpfsMount(spec)
+		if err != nil {
+			return err
+		}
+		mounts = append(mounts, m)
+	}
+
+	targets := make(map[string]bool, len(mounts))
+	for _, m := range mounts {
+		if targets[m.Target] {
+			return fmt.Errorf("worker mount target %s used more than once", m.Target)
+		}
+		targets[m.Target] = true
+	}
+
+	o.mu.Lock()
+	o.workerEnv = env
+	o.workerMounts = mounts
+	o.workerLimits = limits
+	o.workerDNS = dns
+	o.mu.Unlock()
+
+	for _, m := range mounts {
+		if m.Type == mount.TypeBind {
+			log.Printf("[Orchestrator] Workers mount %s at %s (read-only: %t)", m.Source, m.Target, m.ReadOnly)
+		} else {
+			log.Printf("[Orchestrator] Workers get tmpfs at %s", m.Target)
+		}
+	}
+	if len(env) > 0 {
+		log.Printf("[Orchestrator] Workers get %d extra environment variable(s)", len(env))
+	}
+	if limits.Memory > 0 {
+		log.Printf("[Orchestrator] Workers are limited to %s of memory", units.BytesSize(float64(limits.Memory)))
+	}
+	for _, spec := range cfg.WorkerIOLimits {
+		log.Printf("[Orchestrator] Workers get block I/O limits %s", spec)
+	}
+	if len(dns.servers) > 0 || len(dns.search) > 0 {
+		log.Printf("[Orchestrator] Workers resolve names via %v (search: %v)", dns.servers, dns.search)
+	}
+	if len(dns.extraHosts) > 0 {
+		log.Printf("[Orchestrator] Workers get extra hosts entries %v", dns.extraHosts)
+	}
+	if cfg.WorkerEgress == egressNone {
+		log.Printf("[Orchestrator] Worker egress disabled: network %s is internal", o.workerAddress.networkName())
+	}
+	return nil
+}
+
+// Worker egress policies (WORKER_EGRESS)
+const (
+	egressAllow = "allow" // Workers reach whatever their network routes to
+	egressNone  = "none"  // Workers only reach containers on their internal network
+)
+
+// workerDNS holds the name resolution overrides for worker containers
+type workerDNS struct {
+	servers    []string
+	search     []string
+	extraHosts []string // "name:ip", as Docker takes them
+}
+
+// parseWorkerDNS checks WORKER_DNS holds IP addresses and WORKER_EXTRA_HOSTS
+// holds name:ip (or name:host-gateway) entries
+func parseWorkerDNS(cfg *config.Config) (workerDNS, error) {
+	for _, server := range cfg.WorkerDNS {
+		if net.ParseIP(server) == nil {
+			return workerDNS{}, fmt.Errorf("invalid WORKER_DNS entry %q (want an IP address)", server)
+		}
+	}
+	for _, entry := range cfg.WorkerExtraHosts {
+		name, ip, found := strings.Cut(entry, ":")
+		if !found || name == "" || (net.ParseIP(ip) == nil && ip != "host-gateway") {
+			return workerDNS{}, fmt.Errorf("invalid WORKER_EXTRA_HOSTS entry %q (want name:ip or name:host-gateway)", entry)
+		}
+	}
+	return workerDNS{servers: cfg.WorkerDNS, search: cfg.WorkerDNSSearch, extraHosts: cfg.WorkerExtraHosts}, nil
+}
+
+// checkEgressIsolated verifies workers can't reach beyond their network: it must
+// be a user-defined internal network, which Docker gives no route out, and the
+// gateway must reach workers on it rather than through published ports
+func (o *Orchestrator) checkEgressIsolated() error {
+	if o.workerAddress.mode == addressHost {
+		return fmt.Errorf("needs WORKER_ADDRESS_MODE=container_ip or container_name; published ports don't work on an internal network")
+	}
+	name := o.workerAddress.networkName()
+	if name == "bridge" {
+		return fmt.Errorf("needs WORKER_NETWORK set to an internal network (docker network create --internal <name>)")
+	}
+	nw, err := o.cli.NetworkInspect(o.ctx, name, types.NetworkInspectOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to inspect network %s: %w", name, err)
+	}
+	if !nw.Internal {
+		return fmt.Errorf("network %s is not internal; recreate it with docker network create --internal", name)
+	}
+	return nil
+}
+
+// minWorkerMemory is Docker's smallest accepted memory limit
+const minWorkerMemory = 6 * 1024 * 1024
+
+// parseIOLimit parses "/dev/device[:riops=N][:wiops=N][:rbps=SIZE][:wbps=SIZE]"
+// into limits
+func parseIOLimit(spec string, limits *container.Resources) error {
+	parts := strings.Split(spec, ":")
+	device := parts[0]
+	if !path.IsAbs(device) || len(parts) < 2 {
+		return fmt.Errorf("invalid WORKER_IO_LIMITS entry %q (want /dev/device:riops=N[:wiops=N][:rbps=SIZE][:wbps=SIZE])", spec)
+	}
+	for _, opt := range parts[1:] {
+		key, val, _ := strings.Cut(opt, "=")
+		var rate int64
+		var err error
+		switch key {
+		case "riops", "wiops":
+			rate, err = strconv.ParseInt(val, 10, 64)
+		case "rbps", "wbps":
+			rate, err = units.RAMInBytes(val)
+		default:
+			return fmt.Errorf("invalid WORKER_IO_LIMITS entry %q: unknown option %q", spec, key)
+		}
+		if err != nil || rate <= 0 {
+			return fmt.Errorf("invalid WORKER_IO_LIMITS entry %q: bad %s %q", spec, key, val)
+		}
+
+		throttle := &blkiodev.ThrottleDevice{Path: device, Rate: uint64(rate)}
+		switch key {
+		case "riops":
+			limits.BlkioDeviceReadIOps = append(limits.BlkioDeviceReadIOps, throttle)
+		case "wiops":
+			limits.BlkioDeviceWriteIOps = append(limits.BlkioDeviceWriteIOps, throttle)
+		case "rbps":
+			limits.BlkioDeviceReadBps = append(limits.BlkioDeviceReadBps, throttle)
+		case "wbps":
+			limits.BlkioDeviceWriteBps = append(limits.BlkioDeviceWriteBps, throttle)
+		}
+	}
+	return nil
+}
+
+// withWorkerLimits adds the configured memory and block I/O limits to res
+func (o *Orchestrator) withWorkerLimits(res container.Resources) container.Resources {
+	res.Memory = o.workerLimits.Memory
+	res.BlkioDeviceReadIOps = o.workerLimits.BlkioDeviceReadIOps
+	res.BlkioDeviceWriteIOps = o.workerLimits.BlkioDeviceWriteIOps
+	res.BlkioDeviceReadBps = o.workerLimits.BlkioDeviceReadBps
+	res.BlkioDeviceWriteBps = o.workerLimits.BlkioDeviceWriteBps
+	return res
+}
+
+// WorkerMemoryLimit returns each worker container's memory limit in bytes (0 = none)
+func (o *Orchestrator) WorkerMemoryLimit() int64 {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	return o.workerLimits.Memory
+}
+
+// parseWorkerEnv checks WORKER_ENV entries are KEY=value with a valid, unreserved key
+func parseWorkerEnv(entries []string) ([]string, error) {
+	env := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		key, _, found := strings.Cut(entry, "=")
+		if !found || !envKeyPattern.MatchString(key) {
+			return nil, fmt.Errorf("invalid WORKER_ENV entry %q (want KEY=value)", entry)
+		}
+		if slices.Contains(reservedWorkerEnv, key) {
+			return nil, fmt.Errorf("WORKER_ENV may not set %s (set by the gateway)", key)
+		}
+		env = append(env, entry)
+	}
+	return env, nil
+}
+
+// parseBindMount parses "/host/path:/container/path[:ro|rw]" (read-only unless rw).
+// The host path must exist, since Docker refuses to bind a missing one.
+func parseBindMount(spec string) (mount.Mount, error) {
+	parts := strings.Split(spec, ":")
+	if len(parts) < 2 || len(parts) > 3 {
+		return mount.Mount{}, fmt.Errorf("invalid WORKER_MOUNTS entry %q (want /host:/container[:ro|rw])", spec)
+	}
+	source, target := parts[0], parts[1]
+	if !path.IsAbs(source) || !path.IsAbs(target) {
+		return mount.Mount{}, fmt.Errorf("invalid WORKER_MOUNTS entry %q: paths must be absolute", spec)
+	}
+	if target == "/" {
+		return mount.Mount{}, fmt.Errorf("invalid WORKER_MOUNTS entry %q: can't mount over /", spec)
+	}
+
+	readOnly := true
+	if len(parts) == 3 {
+		switch parts[2] {
+		case "ro":
+		case "rw":
+			readOnly = false
+		default:
+			return mount.Mount{}, fmt.Errorf("invalid WORKER_MOUNTS entry %q: mode must be ro or rw", spec)
+		}
+	}
+	if _, err := os.Stat(source); err != nil {
+		return mount.Mount{}, fmt.Errorf("WORKER_MOUNTS source %s: %w", source, err)
+	}
+
+	return mount.Mount{Type: mount.TypeBind, Source: source, Target: path.Clean(target), ReadOnly: readOnly}, nil
+}
+
+// parseTmpfsMount parses "/container/path[:size=64m][:mode=1777]"
+func parseTmpfsMount(spec string) (mount.Mount, error) {
+	parts := strings.Split(spec, ":")
+	target := parts[0]
+	if !path.IsAbs(target) || target == "/" {
+		return mount.Mount{}, fmt.Errorf("invalid WORKER_TMPFS entry %q: target must be an absolute path other than /", spec)
+	}
+
+	opts := &mount.TmpfsOptions{}
+	for _, opt := range parts[1:] {
+		key, val, _ := strings.Cut(opt, "=")
+		switch key {
+		case "size":
+			size, err := units.RAMInBytes(val)
+			if err != nil || size <= 0 {
+				return mount.Mount{}, fmt.Errorf("invalid WORKER_TMPFS entry %q: bad size %q", spec, val)
+			}
+			opts.SizeBytes = size
+		case "mode":
+			mode, err := strconv.ParseUint(val, 8, 32)
+			if err != nil {
+				return mount.Mount{}, fmt.Errorf("invalid WORKER_TMPFS entry %q: bad mode %q", spec, val)
+			}
+			opts.Mode = os.FileMode(mode)
+		default:
+			return mount.Mount{}, fmt.Errorf("invalid WORKER_TMPFS entry %q: unknown option %q", spec, key)
+		}
+	}
+
+	return mount.Mount{Type: mount.TypeTmpfs, Target: path.Clean(target), TmpfsOptions: opts}, nil
+}
@@ -0,0 +1,32 @@
+//go:build linux
+
+package worker
+
+import (
+	"log"
+	"runtime"
+	"sync/atomic"
+
+	"golang.org/x/sys/unix"
+)
+
+// affinityCPUs is the CPU count of the process's affinity mask when last checked
+var affinityCPUs atomic.Int32
+
+// followAffinity sets GOMAXPROCS to the CPU count when the process's cpuset
+// changes, so a worker the gateway resized uses its new CPUs from the next job.
+// The count seen first is taken as the starting point and left alone.
+func followAffinity(workerID string) {
+	var set unix.CPUSet
+	if err := unix.SchedGetaffinity(0, &set); err != nil {
+		return
+	}
+	cpus := int32(set.Count())
+	previous := affinityCPUs.Swap(cpus)
+	if previous == 0 || previous == cpus || cpus == 0 {
+		return
+	}
+	log.Printf("[%s] cpuset changed from %d to %d CPU(s), GOMAXPROCS %d -> %d",
+		workerID, previous, cpus, runtime.GOMAXPROCS(0), cpus)
+	runtime.GOMAXPROCS(int(cpus))
+}
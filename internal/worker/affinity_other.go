@@ -0,0 +1,7 @@
+//go:build !linux
+
+package worker
+
+// followAffinity is a no-op where the CPU affinity can't be read: GOMAXPROCS
+// stays as set at startup
+func followAffinity(workerID string) {}
@@ -1,9 +1,13 @@
 package worker
 
 import (
+	"context"
 	"math"
 	"sync"
+	"sync/atomic"
 	"time"
+
+	"github.com/ahmadhassan44/container-orchestrator/pkg/protocol"
 )
 
 // GenerateCPULoad creates CPU load at specified percentage for specified duration
@@ -11,6 +15,16 @@ import (
 // durationSeconds: how long to sustain the load
 // threads: number of goroutines to use (from GOMAXPROCS)
 func GenerateCPULoad(cpuPercent float64, durationSeconds float64, threads int) float64 {
+	return GenerateCPULoadContext(context.Background(), cpuPercent, durationSeconds, threads)
+}
+
+// GenerateCPULoadContext is GenerateCPULoad that stops early when ctx is cancelled
+func GenerateCPULoadContext(ctx context.Context, cpuPercent float64, durationSeconds float64, threads int) float64 {
+	// A flag is cheaper than ctx.Err() inside the tight loops
+	var cancelled atomic.Bool
+	stop := context.AfterFunc(ctx, func() { cancelled.Store(true) })
+	defer stop()
+
 	var wg sync.WaitGroup
 	wg.Add(threads)
 
@@ -35,7 +49,7 @@ func GenerateCPULoad(cpuPercent float64, durationSeconds float64, threads int) f
 			// For 100% CPU (workRatio == 1.0), skip work/sleep cycles entirely
 			if workRatio >= 0.99 {
 				// Continuous computation for maximum CPU utilization
-				for time.Now().Before(endTime) {
+				for time.Now().Before(endTime) && !cancelled.Load() {
 					// Tight loop of CPU-intensive operations
 					_ = math.Sqrt(math.Pow(float64(localOps), 2) + math.Pow(3.14159, 2))
 					_ = math.Sin(float64(localOps)) * math.Cos(float64(localOps))
@@ -48,7 +62,7 @@ func GenerateCPULoad(cpuPercent float64, durationSeconds float64, threads int) f
 				workTime := time.Duration(float64(quantumMs) * workRatio)
 				sleepTime := quantumMs - workTime
 
-				for time.Now().Before(endTime) {
+				for time.Now().Before(endTime) && !cancelled.Load() {
 					// Work phase: perform CPU-intensive math operations
 					workStart := time.Now()
 					for time.Since(workStart) < workTime {
@@ -75,3 +89,29 @@ func GenerateCPULoad(cpuPercent float64, durationSeconds float64, threads int) f
 	// Return total operations performed as a metric
 	return float64(totalOps)
 }
+
+// GenerateCPULoadProfile is GenerateCPULoadContext following a profile of load
+// segments, starting offset seconds into it, under the same feedback control as
+// synthetic_load. Returns the operations performed and the CPU seconds used.
+func GenerateCPULoadProfile(ctx context.Context, profile []protocol.LoadSegment, offset, durationSeconds float64, threads int) (float64, float64) {
+	return GenerateSyntheticLoad(ctx, profileWaveform(profile), offset, durationSeconds, threads)
+}
+
+// profileWaveform returns the CPU percentage at t seconds into a profile; past its
+// end the last segment's load holds
+func profileWaveform(profile []protocol.LoadSegment) func(t float64) float64 {
+	return func(t float64) float64 {
+		from := 0.0
+		for _, segment := range profile {
+			if t < segment.Duration {
+				if segment.Ramp {
+					return from + (segment.CPULoad-from)*t/segment.Duration
+				}
+				return segment.CPULoad
+			}
+			t -= segment.Duration
+			from = segment.CPULoad
+		}
+		return from
+	}
+}
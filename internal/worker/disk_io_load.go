@@ -0,0 +1,153 @@
+package worker
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ahmadhassan44/container-orchestrator/pkg/protocol"
+)
+
+// defaultDiskBlockKB is disk_io_load's block size when the request gives none
+const defaultDiskBlockKB = 4
+
+// diskIOLoadResult reports the I/O a disk_io_load job performed
+type diskIOLoadResult struct {
+	Operations   int64   `json:"operations"`
+	Reads        int64   `json:"reads"`
+	Writes       int64   `json:"writes"`
+	Bytes        int64   `json:"bytes"`
+	AchievedIOPS float64 `json:"achieved_iops"`
+	AchievedMBps float64 `json:"achieved_mbps"`
+	SetupSeconds float64 `json:"setup_seconds"` // Creating and filling the scratch file
+}
+
+// diskIOLoadOperation reads and writes blocks of a scratch file in the worker's
+// temporary directory (TMPDIR) at a target IOPS, split across the worker's threads
+func diskIOLoadOperation(jc *JobContext) (*protocol.ResultEnvelope, error) {
+	p := jc.Request.DiskIO
+	blockKB := p.BlockKB
+	if blockKB == 0 {
+		blockKB = defaultDiskBlockKB
+	}
+	access, mode := p.Access, p.Mode
+	if access == "" {
+		access = protocol.DiskAccessSequential
+	}
+	if mode == "" {
+		mode = protocol.DiskModeWrite
+	}
+
+	setupStart := time.Now()
+	f, err := os.CreateTemp("", "disk-io-load-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create scratch file: %w", err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	size := int64(p.FileMB) << 20
+	block := make([]byte, blockKB<<10)
+	for i := range block {
+		block[i] = byte(i)
+	}
+	if err := fillScratchFile(f, size, mode != protocol.DiskModeWrite); err != nil {
+		return nil, err
+	}
+	setup := time.Since(setupStart)
+	blocks := size / int64(len(block))
+
+	threads := max(jc.Threads, 1)
+	rate := p.IOPS / float64(threads)
+	jc.Logf("%s %s I/O on a %d MiB file in %d KiB blocks at %s across %d threads for %.1fs",
+		access, mode, p.FileMB, blockKB, iopsLabel(p.IOPS), threads, p.Duration)
+
+	start := time.Now()
+	end := start.Add(time.Duration(p.Duration * float64(time.Second)))
+	var next atomic.Int64 // Sequential access: the next block, shared so threads walk the file together
+	var reads, writes atomic.Int64
+	var wg sync.WaitGroup
+	var ioErr error
+	var errOnce sync.Once
+
+	wg.Add(threads)
+	for i := 0; i < threads; i++ {
+		go func() {
+			defer wg.Done()
+			buf := make([]byte, len(block))
+			copy(buf, block)
+			for n := int64(0); time.Now().Before(end) && !jc.Cancelled(); n++ {
+				index := rand.Int64N(blocks)
+				if access == protocol.DiskAccessSequential {
+					index = (next.Add(1) - 1) % blocks
+				}
+				offset := index * int64(len(buf))
+
+				var err error
+				if mode == protocol.DiskModeRead || (mode == protocol.DiskModeReadWrite && n%2 == 0) {
+					_, err = f.ReadAt(buf, offset)
+					reads.Add(1)
+				} else {
+					if _, err = f.WriteAt(buf, offset); err == nil && p.Sync {
+						err = f.Sync()
+					}
+					writes.Add(1)
+				}
+				if err != nil {
+					errOnce.Do(func() { ioErr = err })
+					return
+				}
+				paceTo(start, float64(n+1), rate, end)
+			}
+		}()
+	}
+	wg.Wait()
+	if ioErr != nil {
+		return nil, fmt.Errorf("scratch file I/O failed: %w", ioErr)
+	}
+	elapsed := max(time.Since(start).Seconds(), 1e-9)
+
+	ops := reads.Load() + writes.Load()
+	result := diskIOLoadResult{
+		Operations:   ops,
+		Reads:        reads.Load(),
+		Writes:       writes.Load(),
+		Bytes:        ops * int64(len(block)),
+		AchievedIOPS: float64(ops) / elapsed,
+		AchievedMBps: float64(ops*int64(len(block))) / (1 << 20) / elapsed,
+		SetupSeconds: setup.Seconds(),
+	}
+	jc.Logf("performed %d operations at %.0f IOPS (%.1f MiB/s)", ops, result.AchievedIOPS, result.AchievedMBps)
+	return protocol.JSONResult(result)
+}
+
+// fillScratchFile sizes the scratch file. Files that will be read are written out
+// in full, so reads return data rather than holes.
+func fillScratchFile(f *os.File, size int64, fill bool) error {
+	if !fill {
+		if err := f.Truncate(size); err != nil {
+			return fmt.Errorf("failed to size scratch file: %w", err)
+		}
+		return nil
+	}
+	chunk := make([]byte, 1<<20)
+	for written := int64(0); written < size; written += int64(len(chunk)) {
+		if _, err := f.Write(chunk); err != nil {
+			return fmt.Errorf("failed to fill scratch file: %w", err)
+		}
+	}
+	if err := f.Sync(); err != nil {
+		return fmt.Errorf("failed to fill scratch file: %w", err)
+	}
+	return nil
+}
+
+func iopsLabel(iops float64) string {
+	if iops <= 0 {
+		return "full speed"
+	}
+	return fmt.Sprintf("%.0f IOPS", iops)
+}
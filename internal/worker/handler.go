@@ -1,52 +1,330 @@
 package worker
 
 import (
+	"context"
+	"crypto/ed25519"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
+	"maps"
 	"net/http"
 	"runtime"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/ahmadhassan44/container-orchestrator/pkg/protocol"
+	"github.com/ahmadhassan44/container-orchestrator/pkg/signing"
+	"github.com/ahmadhassan44/container-orchestrator/pkg/version"
 )
 
 type WorkerHandler struct {
 	WorkerID string
+
+	// WorkerUUID is the stable identity the orchestrator assigns to the worker's core;
+	// replacement containers on the same core keep it
+	WorkerUUID string
+
+	// Threads overrides the number of load goroutines (0 = GOMAXPROCS)
+	Threads int
+
+	// LogLimit caps captured job logs in bytes (0 = DefaultJobLogLimit)
+	LogLimit int
+
+	// SigningKey signs every result (nil = results go unsigned); the gateway
+	// provisions it when it starts the container
+	SigningKey ed25519.PrivateKey
+
+	drainMu  sync.Mutex
+	draining bool
+	inflight sync.WaitGroup
+
+	jobsMu sync.Mutex
+	jobs   map[string]context.CancelCauseFunc // Running jobs by ID, for /jobs/{id}/stop
+}
+
+// errStopped cancels a job stopped through /jobs/{id}/stop: its operation ends
+// early, but the connection stays open for a partial result
+var errStopped = errors.New("stopped by gateway")
+
+// Routes returns a mux with all worker endpoints registered
+func (h *WorkerHandler) Routes() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/submit", h.StartJob)
+	mux.HandleFunc("POST /jobs/{id}/stop", h.StopJob)
+
+	// Receiving end of network_throughput jobs run by other workers
+	mux.HandleFunc("/net/sink", h.NetSink)
+	mux.HandleFunc("/net/ping", h.NetPing)
+
+	// Health check for the Gateway to ping
+	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		if h.isDraining() {
+			http.Error(w, "DRAINING", http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("OK"))
+	})
+
+	// Liveness: the process is up and serving
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("OK"))
+	})
+
+	// Readiness: accepting new jobs (not draining)
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		if h.isDraining() {
+			http.Error(w, "DRAINING", http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("READY"))
+	})
+
+	// Build info so the gateway can detect image version skew
+	mux.HandleFunc("/version", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		info := version.Get()
+		info.Encodings = protocol.Encodings
+		json.NewEncoder(w).Encode(info)
+	})
+
+	return mux
+}
+
+// Drain stops accepting new jobs and waits for in-flight jobs to finish or ctx to expire
+func (h *WorkerHandler) Drain(ctx context.Context) error {
+	h.drainMu.Lock()
+	h.draining = true
+	h.drainMu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		h.inflight.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("in-flight jobs still running after drain timeout: %w", ctx.Err())
+	}
+}
+
+func (h *WorkerHandler) isDraining() bool {
+	h.drainMu.Lock()
+	defer h.drainMu.Unlock()
+	return h.draining
+}
+
+// beginJob registers an in-flight job unless the worker is draining
+func (h *WorkerHandler) beginJob() bool {
+	h.drainMu.Lock()
+	defer h.drainMu.Unlock()
+
+	if h.draining {
+		return false
+	}
+	h.inflight.Add(1)
+	return true
 }
 
 func (h *WorkerHandler) StartJob(w http.ResponseWriter, r *http.Request) {
-	// 1. Parse the CPU load request
+	if !h.beginJob() {
+		http.Error(w, "Worker is draining", http.StatusServiceUnavailable)
+		return
+	}
+	defer h.inflight.Done()
+
+	// 1. Parse the CPU load request, in JSON or the gateway's protobuf dispatch encoding
 	var req protocol.ComputeRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+	if r.Header.Get("Content-Type") == protocol.ContentTypeProtobuf {
+		body, err := io.ReadAll(r.Body)
+		if err == nil {
+			req, err = protocol.UnmarshalRequestProto(body)
+		}
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Invalid protobuf request: %v", err), http.StatusBadRequest)
+			return
+		}
+	} else if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, "Invalid JSON", http.StatusBadRequest)
 		return
 	}
 
-	log.Printf("[%s] Starting CPU Load: %.1f%% for %.1fs",
-		h.WorkerID, req.CPULoad, req.LoadTime)
+	// Hashed as received: operations may fill in derived parameters as they run
+	paramsHash := signing.ParamsHash(&req)
+
+	if err := ValidateRequest(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	op, _ := LookupOperation(req.Operation)
+
+	log.Printf("[%s] Starting %s: CPU Load %.1f%% for %.1fs",
+		h.WorkerID, operationName(req.Operation), req.CPULoad, expectedDuration(&req))
 
-	// 3. Execute CPU load simulation
+	// 3. Execute the operation
 	startTime := time.Now()
 
-	// Dynamically use all assigned threads (e.g., 2)
+	// Dynamically use all assigned threads (e.g., 2), following live cpuset resizes
+	if h.Threads == 0 {
+		followAffinity(h.WorkerID)
+	}
 	numThreads := runtime.GOMAXPROCS(0)
+	if h.Threads > 0 {
+		numThreads = h.Threads
+	}
 
-	// Generate CPU load that matches the requested percentage and duration
-	result := GenerateCPULoad(req.CPULoad, req.LoadTime, numThreads)
+	ctx, stop := context.WithCancelCause(r.Context())
+	defer stop(nil)
+	defer h.trackJob(req.JobID, stop)()
+
+	jc := &JobContext{Request: &req, Threads: numThreads, WorkerID: h.WorkerID, Ctx: ctx}
+	var stream *eventStream
+	if req.Stream {
+		stream = newEventStream(w)
+		jc.progress = stream.send
+	}
+	if req.CaptureLogs {
+		limit := h.LogLimit
+		if limit <= 0 {
+			limit = DefaultJobLogLimit
+		}
+		jc.logs = &jobLogBuffer{limit: limit}
+	}
+
+	output, err := op(jc)
+	partial := false
+	if err == nil && jc.Cancelled() {
+		if req.PartialResults && output != nil && context.Cause(ctx) == errStopped {
+			partial = true
+			jc.checkpoint = nil // The job ends here; there is nothing to resume
+		} else {
+			err = fmt.Errorf("cancelled by gateway")
+		}
+	}
+	if err != nil {
+		log.Printf("[%s] Operation failed: %v", h.WorkerID, err)
+		if stream != nil {
+			stream.send(protocol.StreamEvent{Type: protocol.StreamEventError, JobID: req.JobID, Error: fmt.Sprintf("Operation failed: %v", err)})
+			return
+		}
+		http.Error(w, fmt.Sprintf("Operation failed: %v", err), http.StatusInternalServerError)
+		return
+	}
 
 	duration := time.Since(startTime)
 
 	// 4. Return the Scientific Result
+	jobID := req.JobID
+	if jobID == "" {
+		jobID = fmt.Sprintf("JOB-%d", time.Now().Unix())
+	}
+
 	resp := protocol.JobResponse{
-		JobID:     fmt.Sprintf("JOB-%d", time.Now().Unix()),
-		WorkerID:  h.WorkerID,
-		Result:    result,
-		TimeTaken: duration.String(),
+		JobID:      jobID,
+		WorkerID:   h.WorkerID,
+		WorkerUUID: h.WorkerUUID,
+		Output:     output,
+		TimeTaken:  duration.String(),
+		Logs:       jc.CapturedLogs(),
+		Checkpoint: jc.checkpoint,
+		Iterations: jc.iterations,
+		Precision:  jc.precision,
+		Partial:    partial,
+	}
+	if result, isFloat := output.Float(); isFloat {
+		resp.Result = result
+	}
+	if quantity, units := ResultUnits(req.Operation); quantity != "" {
+		resp.Units = units
+		resp.Metadata = map[string]interface{}{"quantity": quantity}
+		maps.Copy(resp.Metadata, jc.metadata)
+	}
+	if h.SigningKey != nil {
+		signature, err := signing.SignResult(h.SigningKey, paramsHash, req.Operation, &resp, startTime, startTime.Add(duration))
+		if err != nil {
+			log.Printf("[%s] Failed to sign result: %v", h.WorkerID, err)
+		}
+		resp.Signature = signature
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(resp)
+	switch {
+	case stream != nil:
+		stream.send(protocol.StreamEvent{Type: protocol.StreamEventResult, JobID: jobID, Response: &resp})
+	case strings.Contains(r.Header.Get("Accept"), protocol.ContentTypeProtobuf):
+		body, err := protocol.MarshalResponseProto(&resp)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", protocol.ContentTypeProtobuf)
+		w.Write(body)
+	default:
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	}
 
-	log.Printf("[%s] Job Finished in %s. Result: %f", h.WorkerID, duration, result)
+	if partial {
+		log.Printf("[%s] Job stopped after %s. Partial result type: %s", h.WorkerID, duration, output.Type)
+		return
+	}
+	log.Printf("[%s] Job Finished in %s. Result type: %s", h.WorkerID, duration, output.Type)
+}
+
+// trackJob makes a running job stoppable by ID until the returned func is called
+func (h *WorkerHandler) trackJob(jobID string, stop context.CancelCauseFunc) func() {
+	if jobID == "" {
+		return func() {}
+	}
+	h.jobsMu.Lock()
+	if h.jobs == nil {
+		h.jobs = make(map[string]context.CancelCauseFunc)
+	}
+	h.jobs[jobID] = stop
+	h.jobsMu.Unlock()
+
+	return func() {
+		h.jobsMu.Lock()
+		delete(h.jobs, jobID)
+		h.jobsMu.Unlock()
+	}
+}
+
+// StopJob ends a running job early. Its /submit call then returns the result so
+// far, flagged partial, if the job asked for partial results.
+func (h *WorkerHandler) StopJob(w http.ResponseWriter, r *http.Request) {
+	jobID := r.PathValue("id")
+	h.jobsMu.Lock()
+	stop, exists := h.jobs[jobID]
+	h.jobsMu.Unlock()
+	if !exists {
+		http.Error(w, "No such running job", http.StatusNotFound)
+		return
+	}
+
+	log.Printf("[%s] Stopping job %s early", h.WorkerID, jobID)
+	stop(errStopped)
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// expectedDuration is the run time implied by the request (0 if it depends on iterations)
+func expectedDuration(req *protocol.ComputeRequest) float64 {
+	if req.TimeBudget > 0 {
+		return req.TimeBudget
+	}
+	return req.LoadTime
+}
+
+func operationName(name string) string {
+	if name == "" {
+		return protocol.DefaultOperation
+	}
+	return name
 }
@@ -0,0 +1,73 @@
+package worker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/ahmadhassan44/container-orchestrator/pkg/protocol"
+	"github.com/ahmadhassan44/container-orchestrator/pkg/signing"
+	"github.com/ahmadhassan44/container-orchestrator/pkg/version"
+)
+
+// HeartbeatInterval is how often a worker reports to the gateway's internal listener
+const HeartbeatInterval = 5 * time.Second
+
+// RunHeartbeat posts the worker's state to gatewayURL every HeartbeatInterval until
+// ctx is done. Failures are logged and retried on the next tick.
+func (h *WorkerHandler) RunHeartbeat(ctx context.Context, gatewayURL, token string) {
+	httpClient := &http.Client{Timeout: 2 * time.Second}
+	ticker := time.NewTicker(HeartbeatInterval)
+	defer ticker.Stop()
+
+	failing := false
+	for {
+		err := h.sendHeartbeat(ctx, httpClient, gatewayURL, token)
+		if err != nil && !failing && ctx.Err() == nil {
+			log.Printf("%s heartbeat failed: %v", h.WorkerID, err)
+		}
+		failing = err != nil
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (h *WorkerHandler) sendHeartbeat(ctx context.Context, httpClient *http.Client, gatewayURL, token string) error {
+	body, err := json.Marshal(protocol.WorkerHeartbeat{
+		WorkerID:   h.WorkerID,
+		WorkerUUID: h.WorkerUUID,
+		Version:    version.Version,
+		Draining:   h.isDraining(),
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, gatewayURL+"/internal/heartbeat", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	// Signed rather than sending the token, so it can't be sniffed and reused
+	if err := signing.Sign(req, token, body); err != nil {
+		return err
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		return fmt.Errorf("gateway returned %s", resp.Status)
+	}
+	return nil
+}
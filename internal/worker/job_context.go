@@ -0,0 +1,151 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ahmadhassan44/container-orchestrator/pkg/protocol"
+)
+
+// DefaultJobLogLimit bounds captured logs per job when WorkerHandler.LogLimit is unset
+const DefaultJobLogLimit = 64 * 1024
+
+// JobContext carries per-job facilities into an Operation
+type JobContext struct {
+	Request  *protocol.ComputeRequest
+	Threads  int
+	WorkerID string
+
+	// Ctx is cancelled when the gateway abandons the job; long operations should stop early
+	Ctx context.Context
+
+	logs       *jobLogBuffer        // nil unless the request asked for log capture
+	checkpoint *protocol.Checkpoint // Set when the operation stops early at a slice boundary
+	iterations int64                // Reported by iterative operations
+	precision  *protocol.Precision  // Reported by statistical operations
+	metadata   map[string]interface{}
+
+	progress func(protocol.StreamEvent) // nil unless the request is streamed
+}
+
+// Cancelled reports whether the job has been abandoned
+func (jc *JobContext) Cancelled() bool {
+	return jc.Ctx != nil && jc.Ctx.Err() != nil
+}
+
+// ProgressEvery returns the iteration interval between progress reports (0 = not streaming)
+func (jc *JobContext) ProgressEvery() int64 {
+	if jc.progress == nil {
+		return 0
+	}
+	if jc.Request.ProgressEvery > 0 {
+		return jc.Request.ProgressEvery
+	}
+	return protocol.DefaultProgressEvery
+}
+
+// Progress streams an intermediate result (no-op unless the request is streamed)
+func (jc *JobContext) Progress(iterations int64, output *protocol.ResultEnvelope, precision *protocol.Precision) {
+	if jc.progress == nil {
+		return
+	}
+	jc.progress(protocol.StreamEvent{
+		Type:       protocol.StreamEventProgress,
+		JobID:      jc.Request.JobID,
+		Iterations: iterations,
+		Output:     output,
+		Precision:  precision,
+	})
+}
+
+// SetPrecision records the achieved precision of a statistical result
+func (jc *JobContext) SetPrecision(p *protocol.Precision) {
+	jc.precision = p
+}
+
+// SetIterations records how many iterations an iterative operation completed
+func (jc *JobContext) SetIterations(n int64) {
+	jc.iterations = n
+}
+
+// SetMetadata records a detail of how the result was obtained, returned in the
+// response's metadata
+func (jc *JobContext) SetMetadata(key string, value interface{}) {
+	if jc.metadata == nil {
+		jc.metadata = make(map[string]interface{})
+	}
+	jc.metadata[key] = value
+}
+
+// SliceTime returns how many of the remaining seconds this run may use
+func (jc *JobContext) SliceTime(remaining float64) float64 {
+	if jc.Request.SliceTime > 0 && jc.Request.SliceTime < remaining {
+		return jc.Request.SliceTime
+	}
+	return remaining
+}
+
+// SaveCheckpoint records progress so the job can resume in a later slice
+func (jc *JobContext) SaveCheckpoint(elapsed float64, state interface{}) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("failed to marshal checkpoint: %w", err)
+	}
+	jc.checkpoint = &protocol.Checkpoint{Elapsed: elapsed, State: data}
+	return nil
+}
+
+// Logf writes an operation debug line to the worker log and, if enabled, the job's captured logs
+func (jc *JobContext) Logf(format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	log.Printf("[%s] %s: %s", jc.WorkerID, jc.Request.JobID, msg)
+
+	if jc.logs != nil {
+		jc.logs.append(fmt.Sprintf("%s %s\n", time.Now().Format(time.RFC3339Nano), msg))
+	}
+}
+
+// CapturedLogs returns the job's captured log text ("" if capture is disabled)
+func (jc *JobContext) CapturedLogs() string {
+	if jc.logs == nil {
+		return ""
+	}
+	return jc.logs.String()
+}
+
+// jobLogBuffer is a size-bounded, concurrency-safe log accumulator
+type jobLogBuffer struct {
+	mu        sync.Mutex
+	buf       strings.Builder
+	limit     int
+	truncated int // Bytes dropped after reaching the limit
+}
+
+func (b *jobLogBuffer) append(line string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if remaining := b.limit - b.buf.Len(); remaining < len(line) {
+		if remaining > 0 {
+			b.buf.WriteString(line[:remaining])
+		}
+		b.truncated += len(line) - max(remaining, 0)
+		return
+	}
+	b.buf.WriteString(line)
+}
+
+func (b *jobLogBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.truncated > 0 {
+		return fmt.Sprintf("%s\n... [truncated %d bytes]\n", b.buf.String(), b.truncated)
+	}
+	return b.buf.String()
+}
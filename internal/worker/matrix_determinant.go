@@ -0,0 +1,141 @@
+package worker
+
+import (
+	"fmt"
+	"math"
+	"sync"
+
+	"github.com/ahmadhassan44/container-orchestrator/pkg/protocol"
+)
+
+const (
+	// matrixParallelRows is the fewest rows left to eliminate before a step's row
+	// updates are split across threads
+	matrixParallelRows = 64
+
+	// matrixFlopsPerSecond is a conservative rate of elimination on one thread,
+	// from which a matrix's load_time is estimated
+	matrixFlopsPerSecond = 250e6
+)
+
+// matrixDeterminantOperation takes the determinant of the given matrix, or of a
+// random one of the given size, by LU decomposition with partial pivoting. The
+// row updates of each elimination step are split across the worker's threads.
+func matrixDeterminantOperation(jc *JobContext) (*protocol.ResultEnvelope, error) {
+	req := jc.Request
+	var a [][]float64
+	if p := req.Matrix; len(p.Values) > 0 {
+		a = make([][]float64, len(p.Values))
+		for i, row := range p.Values {
+			a[i] = append([]float64(nil), row...)
+		}
+		jc.Logf("taking the determinant of the given %dx%d matrix across %d threads", len(a), len(a), jc.Threads)
+	} else {
+		a = randomMatrix(p.Size, req.Seed)
+		jc.Logf("taking the determinant of a random %dx%d matrix across %d threads", p.Size, p.Size, jc.Threads)
+	}
+	n := len(a)
+
+	// The determinant is the product of the pivots, kept as a mantissa and a
+	// binary exponent so it can't overflow or underflow along the way
+	mantissa, exponent := 1.0, 0
+	for k := 0; k < n; k++ {
+		if jc.Cancelled() {
+			return nil, fmt.Errorf("stopped after %d of %d elimination steps", k, n)
+		}
+
+		pivot := k
+		for i := k + 1; i < n; i++ {
+			if math.Abs(a[i][k]) > math.Abs(a[pivot][k]) {
+				pivot = i
+			}
+		}
+		if a[pivot][k] == 0 {
+			mantissa = 0
+			jc.Logf("matrix is singular (no pivot in column %d)", k)
+			break
+		}
+		if pivot != k {
+			a[k], a[pivot] = a[pivot], a[k]
+			mantissa = -mantissa
+		}
+
+		m, e := math.Frexp(a[k][k])
+		mantissa, exponent = mantissa*m, exponent+e
+		m, e = math.Frexp(mantissa)
+		mantissa, exponent = m, exponent+e
+
+		eliminateBelow(a, k, jc.Threads)
+	}
+
+	jc.SetMetadata("size", n)
+	if req.Seed != 0 {
+		jc.SetMetadata("seed", req.Seed)
+	}
+	if mantissa == 0 {
+		jc.SetMetadata("singular", true)
+		return protocol.FloatResult(0), nil
+	}
+
+	logAbs := math.Log(math.Abs(mantissa)) + float64(exponent)*math.Ln2
+	jc.SetMetadata("sign", math.Copysign(1, mantissa))
+	jc.SetMetadata("log_abs_determinant", logAbs)
+	det := math.Ldexp(mantissa, exponent)
+	if math.IsInf(det, 0) {
+		return nil, fmt.Errorf("determinant overflows a float64 (ln|det| = %.6g)", logAbs)
+	}
+	jc.Logf("determinant %.10g (ln|det| = %.6g)", det, logAbs)
+	return protocol.FloatResult(det), nil
+}
+
+// eliminateBelow subtracts multiples of pivot row k from the rows below it,
+// zeroing column k under the pivot
+func eliminateBelow(a [][]float64, k, threads int) {
+	rows := len(a) - k - 1
+	if rows < matrixParallelRows || threads < 2 {
+		eliminateRows(a, k, k+1, len(a))
+		return
+	}
+
+	var wg sync.WaitGroup
+	chunk := (rows + threads - 1) / threads
+	for from := k + 1; from < len(a); from += chunk {
+		wg.Add(1)
+		go func(from, to int) {
+			defer wg.Done()
+			eliminateRows(a, k, from, to)
+		}(from, min(from+chunk, len(a)))
+	}
+	wg.Wait()
+}
+
+// eliminateRows applies pivot row k to rows from up to to
+func eliminateRows(a [][]float64, k, from, to int) {
+	pivotRow := a[k][k:]
+	for i := from; i < to; i++ {
+		row := a[i][k:]
+		factor := row[0] / pivotRow[0]
+		if factor == 0 {
+			continue
+		}
+		for j := range row {
+			row[j] -= factor * pivotRow[j]
+		}
+	}
+}
+
+// randomMatrix returns an n x n matrix of entries uniform in [-s, s]. Scaling by
+// s = sqrt(3e/n) keeps the determinant's magnitude from growing with n, so large
+// matrices don't overflow.
+func randomMatrix(n int, seed int64) [][]float64 {
+	rng := newBatchRand(seed, 1)
+	scale := math.Sqrt(3 * math.E / float64(n))
+	a := make([][]float64, n)
+	for i := range a {
+		a[i] = make([]float64, n)
+		for j := range a[i] {
+			a[i][j] = scale * (2*rng.Float64() - 1)
+		}
+	}
+	return a
+}
@@ -0,0 +1,108 @@
+package worker
+
+import (
+	"fmt"
+	"os"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/ahmadhassan44/container-orchestrator/pkg/protocol"
+)
+
+// memoryChunk is how much a thread writes between pacing checks
+const memoryChunk = 1 << 20
+
+// memoryLoadResult reports the traffic a memory_load job generated
+type memoryLoadResult struct {
+	WorkingSetMB int     `json:"working_set_mb"`
+	BytesWritten int64   `json:"bytes_written"`
+	Passes       float64 `json:"passes"`        // Times the working set was rewritten
+	AchievedMBps float64 `json:"achieved_mbps"` // MiB/s over the timed part
+	SetupSeconds float64 `json:"setup_seconds"` // Allocating and touching the working set
+}
+
+// paceTo sleeps until done units of work are due at rate per second since start,
+// but not past end. A rate of 0 doesn't pace.
+func paceTo(start time.Time, done, rate float64, end time.Time) {
+	if rate <= 0 {
+		return
+	}
+	due := start.Add(time.Duration(done / rate * float64(time.Second)))
+	if wait := time.Until(due); wait > 0 {
+		time.Sleep(min(wait, time.Until(end)))
+	}
+}
+
+// memoryLoadOperation holds a working set in memory and rewrites it at a target
+// bandwidth, split across the worker's threads
+func memoryLoadOperation(jc *JobContext) (*protocol.ResultEnvelope, error) {
+	p := jc.Request.Memory
+
+	setupStart := time.Now()
+	jc.Logf("allocating a %d MiB working set", p.WorkingSetMB)
+	buf := make([]byte, p.WorkingSetMB<<20)
+	// Every page is touched up front, so the working set is resident before the timed part
+	pageSize := os.Getpagesize()
+	for i := 0; i < len(buf); i += pageSize {
+		buf[i] = 1
+	}
+	setup := time.Since(setupStart)
+
+	src := make([]byte, memoryChunk)
+	for i := range src {
+		src[i] = byte(i)
+	}
+
+	threads := max(min(jc.Threads, len(buf)/memoryChunk), 1)
+	rate := p.BandwidthMBps * (1 << 20) / float64(threads) // Bytes per second per thread
+	jc.Logf("rewriting it at %s across %d threads for %.1fs", bandwidthLabel(p.BandwidthMBps), threads, p.Duration)
+
+	start := time.Now()
+	end := start.Add(time.Duration(p.Duration * float64(time.Second)))
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var written int64
+
+	wg.Add(threads)
+	for i := 0; i < threads; i++ {
+		region := buf[i*len(buf)/threads : (i+1)*len(buf)/threads]
+		go func() {
+			defer wg.Done()
+			var local int64
+			offset := 0
+			for time.Now().Before(end) && !jc.Cancelled() {
+				n := copy(region[offset:], src)
+				local += int64(n)
+				offset = (offset + n) % len(region)
+				paceTo(start, float64(local), rate, end)
+			}
+			mu.Lock()
+			written += local
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	elapsed := time.Since(start).Seconds()
+
+	result := memoryLoadResult{
+		WorkingSetMB: p.WorkingSetMB,
+		BytesWritten: written,
+		Passes:       float64(written) / float64(len(buf)),
+		AchievedMBps: float64(written) / (1 << 20) / max(elapsed, 1e-9),
+		SetupSeconds: setup.Seconds(),
+	}
+	jc.Logf("wrote %.1f passes at %.1f MiB/s", result.Passes, result.AchievedMBps)
+
+	// Hand the working set back to the OS now rather than when the scavenger gets to it
+	buf = nil
+	debug.FreeOSMemory()
+	return protocol.JSONResult(result)
+}
+
+func bandwidthLabel(mbps float64) string {
+	if mbps <= 0 {
+		return "full speed"
+	}
+	return fmt.Sprintf("%.1f MiB/s", mbps)
+}
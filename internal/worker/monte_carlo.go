@@ -0,0 +1,157 @@
+package worker
+
+import (
+	"math"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/ahmadhassan44/container-orchestrator/pkg/protocol"
+)
+
+// monteCarloBatch is how many samples a thread draws between stop-condition checks
+const monteCarloBatch = 1 << 16
+
+// piSampler accumulates samples from all threads and decides when to stop
+type piSampler struct {
+	mu       sync.Mutex
+	inside   int64
+	total    int64
+	claimed  int64
+	batches  uint64    // Batches handed out, used as the per-batch RNG stream
+	maxIters int64     // 0 = unbounded (time budget only)
+	deadline time.Time // Zero = no time budget
+	target   float64   // Standard error bound (0 = none)
+	met      bool
+
+	jc           *JobContext
+	progressNext int64 // Sample count at which to stream the next progress event (0 = never)
+}
+
+// claim reserves the next batch, returning its index and size (0 once any stop condition holds)
+func (p *piSampler) claim() (uint64, int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.met || p.jc.Cancelled() || (!p.deadline.IsZero() && !time.Now().Before(p.deadline)) {
+		return 0, 0
+	}
+	n := int64(monteCarloBatch)
+	if p.maxIters > 0 {
+		n = min(n, p.maxIters-p.claimed)
+	}
+	p.claimed += n
+	p.batches++
+	return p.batches, n
+}
+
+// record adds a finished batch, checks the precision target and streams progress when due
+func (p *piSampler) record(hits, n int64) {
+	p.mu.Lock()
+	p.inside += hits
+	p.total += n
+	if p.target > 0 && piStdError(p.inside, p.total) <= p.target {
+		p.met = true
+	}
+
+	inside, total := p.inside, p.total
+	report := p.progressNext > 0 && total >= p.progressNext
+	if report {
+		every := p.jc.ProgressEvery()
+		p.progressNext = (total/every + 1) * every
+	}
+	p.mu.Unlock()
+
+	if report {
+		estimate, precision := piResult(inside, total)
+		p.jc.Progress(total, protocol.FloatResult(estimate), precision)
+	}
+}
+
+// monteCarloPiOperation estimates pi by sampling random points in the unit square.
+// Runs a fixed number of samples (iterations) or as many as fit in time_budget;
+// with target_std_error it stops as soon as the estimate is precise enough.
+func monteCarloPiOperation(jc *JobContext) (*protocol.ResultEnvelope, error) {
+	req := jc.Request
+	sampler := &piSampler{maxIters: req.Iterations, target: req.TargetStdError, jc: jc, progressNext: jc.ProgressEvery()}
+
+	if req.TimeBudget > 0 {
+		sampler.deadline = time.Now().Add(time.Duration(req.TimeBudget * float64(time.Second)))
+		jc.Logf("sampling for up to %.1fs across %d threads", req.TimeBudget, jc.Threads)
+	} else {
+		jc.Logf("drawing up to %d samples across %d threads", req.Iterations, jc.Threads)
+	}
+	if req.TargetStdError > 0 {
+		jc.Logf("stopping early at std error <= %g", req.TargetStdError)
+	}
+
+	// Seeding per batch (not per thread) keeps seeded results independent of
+	// which thread happens to draw which batch
+	var wg sync.WaitGroup
+	for t := 0; t < jc.Threads; t++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for batch, n := sampler.claim(); n > 0; batch, n = sampler.claim() {
+				sampler.record(samplePi(newBatchRand(req.Seed, batch), n), n)
+			}
+		}()
+	}
+	wg.Wait()
+
+	estimate, precision := piResult(sampler.inside, sampler.total)
+	if req.TargetStdError > 0 {
+		precision.Target = req.TargetStdError
+		precision.TargetMet = &sampler.met
+	}
+
+	jc.SetIterations(sampler.total)
+	jc.SetPrecision(precision)
+	jc.SetMetadata("samples", sampler.total)
+	jc.SetMetadata("inside", sampler.inside)
+	jc.SetMetadata("ci95", precision.CI95)
+	if req.Seed != 0 {
+		jc.SetMetadata("seed", req.Seed)
+	}
+	jc.Logf("%d samples, pi ~= %.8f (std error %.2g)", sampler.total, estimate, precision.StdError)
+	return protocol.FloatResult(estimate), nil
+}
+
+// piResult returns the pi estimate and its precision for the samples so far
+func piResult(inside, total int64) (float64, *protocol.Precision) {
+	estimate := 4 * float64(inside) / float64(total)
+	stdError := piStdError(inside, total)
+	return estimate, &protocol.Precision{
+		StdError: stdError,
+		CI95:     [2]float64{estimate - 1.96*stdError, estimate + 1.96*stdError},
+	}
+}
+
+// piStdError is the standard error of 4*inside/total (binomial proportion, scaled)
+func piStdError(inside, total int64) float64 {
+	if total == 0 {
+		return math.Inf(1)
+	}
+	p := float64(inside) / float64(total)
+	return 4 * math.Sqrt(p*(1-p)/float64(total))
+}
+
+// samplePi draws n points and returns how many fell inside the quarter circle
+func samplePi(rng *rand.Rand, n int64) int64 {
+	var hits int64
+	for i := int64(0); i < n; i++ {
+		x, y := rng.Float64(), rng.Float64()
+		if x*x+y*y <= 1 {
+			hits++
+		}
+	}
+	return hits
+}
+
+// newBatchRand returns a generator for one batch, deterministic when the job has a seed
+func newBatchRand(seed int64, batch uint64) *rand.Rand {
+	if seed == 0 {
+		return rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64()))
+	}
+	return rand.New(rand.NewPCG(uint64(seed), batch))
+}
@@ -0,0 +1,188 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/ahmadhassan44/container-orchestrator/pkg/protocol"
+)
+
+// Defaults for network_throughput
+const (
+	defaultNetworkChunkKB = 64
+	networkPingInterval   = 100 * time.Millisecond
+)
+
+// sinkReport is the receiver's account of a network_throughput stream
+type sinkReport struct {
+	Bytes   int64   `json:"bytes"`
+	Seconds float64 `json:"seconds"`
+}
+
+// NetSink reads and discards a network_throughput stream, reporting what arrived
+func (h *WorkerHandler) NetSink(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !h.beginJob() {
+		http.Error(w, "Worker is draining", http.StatusServiceUnavailable)
+		return
+	}
+	defer h.inflight.Done()
+
+	start := time.Now()
+	n, err := io.Copy(io.Discard, r.Body)
+	if err != nil && n == 0 {
+		http.Error(w, fmt.Sprintf("Stream failed: %v", err), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(sinkReport{Bytes: n, Seconds: time.Since(start).Seconds()})
+}
+
+// NetPing answers network_throughput's round-trip probes
+func (h *WorkerHandler) NetPing(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// LatencyStats summarises round-trip times in milliseconds
+type LatencyStats struct {
+	Samples int     `json:"samples"`
+	P50     float64 `json:"p50_ms"`
+	P90     float64 `json:"p90_ms"`
+	P99     float64 `json:"p99_ms"`
+	Max     float64 `json:"max_ms"`
+}
+
+// networkThroughputResult reports what a network_throughput job measured
+type networkThroughputResult struct {
+	Receiver       string       `json:"receiver"`
+	BytesSent      int64        `json:"bytes_sent"`
+	BytesReceived  int64        `json:"bytes_received"` // As counted by the receiver
+	ThroughputMbps float64      `json:"throughput_mbps"`
+	Latency        LatencyStats `json:"latency"` // Measured while the stream ran
+	FailedPings    int          `json:"failed_pings,omitempty"`
+}
+
+// networkThroughputOperation streams data to the receiving worker for the job's
+// duration at the target rate, probing round trips to it on a separate connection
+func networkThroughputOperation(jc *JobContext) (*protocol.ResultEnvelope, error) {
+	p := jc.Request.Network
+	if p.Receiver == "" {
+		return nil, fmt.Errorf("network_throughput needs a receiving worker; submit it through the gateway")
+	}
+	chunkKB := p.ChunkKB
+	if chunkKB == 0 {
+		chunkKB = defaultNetworkChunkKB
+	}
+	ctx := jc.Ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	start := time.Now()
+	end := start.Add(time.Duration(p.Duration * float64(time.Second)))
+	rate := p.RateMbps * 1e6 / 8 // Bytes per second
+	jc.Logf("streaming to %s at %s in %d KiB chunks for %.1fs", p.Receiver, rateLabel(p.RateMbps), chunkKB, p.Duration)
+
+	// Sender: the request body is fed from a pipe until the duration is up
+	pr, pw := io.Pipe()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.Receiver+"/net/sink", pr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid receiver %q: %w", p.Receiver, err)
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	var sent int64
+	sendDone := make(chan struct{})
+	go func() {
+		defer close(sendDone)
+		chunk := make([]byte, chunkKB<<10)
+		for time.Now().Before(end) && ctx.Err() == nil {
+			if _, err := pw.Write(chunk); err != nil {
+				return
+			}
+			sent += int64(len(chunk))
+			paceTo(start, float64(sent), rate, end)
+		}
+		pw.Close()
+	}()
+
+	// Prober: round trips to the receiver while the stream runs
+	var rtts []float64
+	failedPings := 0
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		client := &http.Client{Timeout: time.Second}
+		ticker := time.NewTicker(networkPingInterval)
+		defer ticker.Stop()
+		for time.Now().Before(end) && ctx.Err() == nil {
+			probeStart := time.Now()
+			probe, _ := http.NewRequestWithContext(ctx, http.MethodGet, p.Receiver+"/net/ping", nil)
+			if resp, err := client.Do(probe); err != nil {
+				failedPings++
+			} else {
+				io.Copy(io.Discard, resp.Body)
+				resp.Body.Close()
+				rtts = append(rtts, float64(time.Since(probeStart).Microseconds())/1000)
+			}
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+			}
+		}
+	}()
+
+	resp, err := http.DefaultClient.Do(req)
+	pr.Close()
+	<-sendDone
+	wg.Wait()
+	if err != nil {
+		return nil, fmt.Errorf("receiver %s unreachable: %w", p.Receiver, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("receiver returned status %d: %s", resp.StatusCode, body)
+	}
+	var report sinkReport
+	if err := json.NewDecoder(resp.Body).Decode(&report); err != nil {
+		return nil, fmt.Errorf("failed to decode receiver report: %w", err)
+	}
+
+	result := networkThroughputResult{
+		Receiver:       p.Receiver,
+		BytesSent:      sent,
+		BytesReceived:  report.Bytes,
+		ThroughputMbps: float64(report.Bytes) * 8 / 1e6 / max(report.Seconds, 1e-9),
+		Latency:        latencyStats(rtts),
+		FailedPings:    failedPings,
+	}
+	jc.Logf("delivered %d bytes at %.1f Mbit/s, p50 round trip %.2fms", report.Bytes, result.ThroughputMbps, result.Latency.P50)
+	return protocol.JSONResult(result)
+}
+
+// latencyStats summarises round-trip samples (in milliseconds)
+func latencyStats(rtts []float64) LatencyStats {
+	if len(rtts) == 0 {
+		return LatencyStats{}
+	}
+	slices.Sort(rtts)
+	at := func(q float64) float64 { return rtts[min(int(q*float64(len(rtts))), len(rtts)-1)] }
+	return LatencyStats{Samples: len(rtts), P50: at(0.5), P90: at(0.9), P99: at(0.99), Max: rtts[len(rtts)-1]}
+}
+
+func rateLabel(mbps float64) string {
+	if mbps <= 0 {
+		return "full speed"
+	}
+	return fmt.Sprintf("%.1f Mbit/s", mbps)
+}
@@ -0,0 +1,171 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/ahmadhassan44/container-orchestrator/pkg/protocol"
+)
+
+// Operation executes a job and returns its typed result
+type Operation func(jc *JobContext) (*protocol.ResultEnvelope, error)
+
+// operationSpec describes a registered operation and the job modes it supports
+type operationSpec struct {
+	run         Operation
+	checkpoints bool // Can stop after a time slice and resume from a checkpoint
+	iterative   bool // Counts iterations, so accepts either "iterations" or a "time_budget"
+	precision   bool // Reports a standard error and can stop early at "target_std_error"
+	randomized  bool // Draws random numbers, so a "seed" makes it reproducible
+	profiles    bool // Can follow a "profile" of load segments instead of a constant cpu_load
+	cpuHint     bool // Generates no set CPU load, so cpu_load is an optional scheduling hint
+	paired      bool // Runs against a second worker the scheduler reserves alongside the first
+
+	// params names the request field holding the operation's typed parameters, and
+	// derive checks them and fills the request's load_time (and cpu_load) from them
+	params string
+	derive func(req *protocol.ComputeRequest) error
+
+	// CPU architectures the operation's native dependencies are built for (nil = any).
+	// The gateway only accepts such operations on nodes of a listed architecture.
+	arches []string
+
+	// quantity names what the result is, and units gives the unit of a float result
+	// ("result") or of each numeric field of a JSON result
+	quantity string
+	units    map[string]string
+}
+
+// operations is the registry of computations a worker can dispatch to by name
+var operations = map[string]operationSpec{
+	"cpu_load": {run: cpuLoadOperation, checkpoints: true, profiles: true,
+		quantity: "cpu_operations", units: map[string]string{"result": "operations"}},
+	"monte_carlo_pi": {run: monteCarloPiOperation, iterative: true, precision: true, randomized: true,
+		quantity: "pi_estimate", units: map[string]string{"result": "dimensionless"}},
+	"prime_search": {run: primeSearchOperation, iterative: true,
+		quantity: "prime_count", units: map[string]string{"result": "primes"}},
+	"matrix_determinant": {run: matrixDeterminantOperation, cpuHint: true, randomized: true, params: "matrix", derive: applyMatrix,
+		quantity: "determinant", units: map[string]string{"result": "dimensionless"}},
+	"synthetic_load": {run: syntheticLoadOperation, checkpoints: true, params: "synthetic", derive: applySynthetic,
+		quantity: "synthetic_load_summary", units: map[string]string{
+			"operations": "operations", "cpu_seconds": "s", "target_avg_cpu": "%", "achieved_avg_cpu": "%",
+		}},
+	"memory_load": {run: memoryLoadOperation, cpuHint: true, params: "memory", derive: applyMemoryLoad,
+		quantity: "memory_bandwidth", units: map[string]string{
+			"working_set_mb": "MiB", "bytes_written": "bytes", "passes": "passes", "achieved_mbps": "MiB/s", "setup_seconds": "s",
+		}},
+	"disk_io_load": {run: diskIOLoadOperation, cpuHint: true, params: "disk_io", derive: applyDiskIOLoad,
+		quantity: "disk_io_throughput", units: map[string]string{
+			"operations": "operations", "reads": "operations", "writes": "operations", "bytes": "bytes",
+			"achieved_iops": "operations/s", "achieved_mbps": "MiB/s", "setup_seconds": "s",
+		}},
+
+	"network_throughput": {run: networkThroughputOperation, cpuHint: true, paired: true, params: "network", derive: applyNetworkLoad,
+		quantity: "network_throughput", units: map[string]string{
+			"bytes_sent": "bytes", "bytes_received": "bytes", "throughput_mbps": "Mbit/s", "latency.samples": "round trips",
+			"latency.p50_ms": "ms", "latency.p90_ms": "ms", "latency.p99_ms": "ms", "latency.max_ms": "ms", "failed_pings": "round trips",
+		}},
+}
+
+func lookupSpec(name string) (operationSpec, bool) {
+	if name == "" {
+		name = protocol.DefaultOperation
+	}
+	spec, exists := operations[name]
+	return spec, exists
+}
+
+// LookupOperation resolves an operation name (empty = protocol.DefaultOperation)
+func LookupOperation(name string) (Operation, bool) {
+	spec, exists := lookupSpec(name)
+	return spec.run, exists
+}
+
+// SupportsCheckpoints reports whether an operation can be split into time slices
+func SupportsCheckpoints(name string) bool {
+	spec, _ := lookupSpec(name)
+	return spec.checkpoints
+}
+
+// SupportsArch reports whether an operation can run on workers of the given architecture
+func SupportsArch(name, arch string) bool {
+	spec, _ := lookupSpec(name)
+	return spec.arches == nil || slices.Contains(spec.arches, arch)
+}
+
+// OperationArches lists the architectures an operation is restricted to (nil = any)
+func OperationArches(name string) []string {
+	spec, _ := lookupSpec(name)
+	return spec.arches
+}
+
+// IsPaired reports whether an operation needs a second worker to run against
+func IsPaired(name string) bool {
+	spec, _ := lookupSpec(name)
+	return spec.paired
+}
+
+// ResultUnits returns what an operation's result is and the units of its fields
+func ResultUnits(name string) (string, map[string]string) {
+	spec, _ := lookupSpec(name)
+	return spec.quantity, maps.Clone(spec.units)
+}
+
+// IsIterative reports whether an operation runs a number of iterations (fixed or time-budgeted)
+func IsIterative(name string) bool {
+	spec, _ := lookupSpec(name)
+	return spec.iterative
+}
+
+// cpuLoadState is cpu_load's checkpointed progress
+type cpuLoadState struct {
+	Ops float64 `json:"ops"`
+}
+
+// cpuLoadOperation generates synthetic CPU load; the result is the number of operations performed
+func cpuLoadOperation(jc *JobContext) (*protocol.ResultEnvelope, error) {
+	req := jc.Request
+
+	var state cpuLoadState
+	elapsed := 0.0
+	if cp := req.Checkpoint; cp != nil {
+		if len(cp.State) > 0 {
+			if err := json.Unmarshal(cp.State, &state); err != nil {
+				return nil, fmt.Errorf("invalid checkpoint state: %w", err)
+			}
+		}
+		elapsed = cp.Elapsed
+		jc.Logf("resuming at %.1fs of %.1fs (%.0f operations so far)", elapsed, req.LoadTime, state.Ops)
+	}
+
+	runTime := jc.SliceTime(req.LoadTime - elapsed)
+	ctx := jc.Ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if len(req.Profile) > 0 {
+		jc.Logf("following a %d-segment profile peaking at %.1f%% for %.1fs across %d threads",
+			len(req.Profile), req.CPULoad, runTime, jc.Threads)
+		ops, _ := GenerateCPULoadProfile(ctx, req.Profile, elapsed, runTime, jc.Threads)
+		state.Ops += ops
+	} else {
+		jc.Logf("generating %.1f%% load for %.1fs across %d threads (%.1f%% per thread)",
+			req.CPULoad, runTime, jc.Threads, req.CPULoad/float64(jc.Threads))
+		state.Ops += GenerateCPULoadContext(ctx, req.CPULoad, runTime, jc.Threads)
+	}
+
+	if elapsed+runTime < req.LoadTime {
+		if err := jc.SaveCheckpoint(elapsed+runTime, state); err != nil {
+			return nil, err
+		}
+		jc.Logf("slice finished, checkpointed at %.1fs", elapsed+runTime)
+	}
+
+	jc.SetMetadata("threads", jc.Threads)
+	jc.SetMetadata("target_cpu_load", req.CPULoad)
+	jc.Logf("performed %.0f operations", state.Ops)
+	return protocol.FloatResult(state.Ops), nil
+}
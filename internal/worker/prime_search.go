@@ -0,0 +1,191 @@
+package worker
+
+import (
+	"math/bits"
+	"sync"
+	"time"
+
+	"github.com/ahmadhassan44/container-orchestrator/pkg/protocol"
+)
+
+// primeSearchBatch is how many integers a thread tests between stop-condition checks
+const primeSearchBatch = 1 << 14
+
+// Miller-Rabin witnesses that decide primality exactly: the first four for every
+// n below 3,215,031,751, all twelve for every n below 2^64
+var (
+	smallWitnesses = []uint64{2, 3, 5, 7}
+	fullWitnesses  = []uint64{2, 3, 5, 7, 11, 13, 17, 19, 23, 29, 31, 37}
+)
+
+// primeSearcher hands out ranges of integers to test and accumulates what the
+// threads found
+type primeSearcher struct {
+	mu       sync.Mutex
+	claimed  int64     // Integers handed out, from 2 upwards
+	tested   int64     // Integers tested so far
+	primes   int64     // Primes found so far
+	largest  uint64    // Largest prime found so far
+	maxIters int64     // 0 = unbounded (time budget only)
+	deadline time.Time // Zero = no time budget
+
+	jc           *JobContext
+	progressNext int64 // Tested count at which to stream the next progress event (0 = never)
+}
+
+// claim reserves the next range, returning its first integer and length (0 once
+// any stop condition holds)
+func (p *primeSearcher) claim() (uint64, int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.jc.Cancelled() || (!p.deadline.IsZero() && !time.Now().Before(p.deadline)) {
+		return 0, 0
+	}
+	n := int64(primeSearchBatch)
+	if p.maxIters > 0 {
+		n = min(n, p.maxIters-p.claimed)
+	}
+	start := uint64(p.claimed) + 2
+	p.claimed += n
+	return start, n
+}
+
+// record adds a finished range and streams progress when due
+func (p *primeSearcher) record(primes int64, largest uint64, n int64) {
+	p.mu.Lock()
+	p.primes += primes
+	p.tested += n
+	p.largest = max(p.largest, largest)
+
+	found, tested := p.primes, p.tested
+	report := p.progressNext > 0 && tested >= p.progressNext
+	if report {
+		every := p.jc.ProgressEvery()
+		p.progressNext = (tested/every + 1) * every
+	}
+	p.mu.Unlock()
+
+	if report {
+		p.jc.Progress(tested, protocol.FloatResult(float64(found)), nil)
+	}
+}
+
+// primeSearchOperation counts the primes among the integers from 2 upwards:
+// a fixed number of them (iterations) or as many as fit in time_budget. Each is
+// tested with a deterministic Miller-Rabin test, so the count is exact.
+func primeSearchOperation(jc *JobContext) (*protocol.ResultEnvelope, error) {
+	req := jc.Request
+	searcher := &primeSearcher{maxIters: req.Iterations, jc: jc, progressNext: jc.ProgressEvery()}
+
+	if req.TimeBudget > 0 {
+		searcher.deadline = time.Now().Add(time.Duration(req.TimeBudget * float64(time.Second)))
+		jc.Logf("searching for primes for up to %.1fs across %d threads", req.TimeBudget, jc.Threads)
+	} else {
+		jc.Logf("searching for primes below %d across %d threads", req.Iterations+2, jc.Threads)
+	}
+
+	// Ranges are claimed in order but may finish out of order; once every thread
+	// is done, the tested integers are exactly 2 up to 2+claimed
+	var wg sync.WaitGroup
+	for t := 0; t < jc.Threads; t++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for start, n := searcher.claim(); n > 0; start, n = searcher.claim() {
+				primes, largest := countPrimes(start, uint64(n))
+				searcher.record(primes, largest, n)
+			}
+		}()
+	}
+	wg.Wait()
+
+	jc.SetIterations(searcher.tested)
+	jc.SetMetadata("searched_to", searcher.tested+1)
+	if searcher.largest > 0 {
+		jc.SetMetadata("largest_prime", searcher.largest)
+	}
+	jc.Logf("%d primes up to %d (largest %d)", searcher.primes, searcher.tested+1, searcher.largest)
+	return protocol.FloatResult(float64(searcher.primes)), nil
+}
+
+// countPrimes tests the n integers from start, returning how many are prime and
+// the largest of them (0 if none)
+func countPrimes(start, n uint64) (int64, uint64) {
+	var count int64
+	var largest uint64
+	for k := start; k < start+n; k++ {
+		if isPrime(k) {
+			count++
+			largest = k
+		}
+	}
+	return count, largest
+}
+
+// isPrime reports whether n is prime: trial division by the small primes, then
+// Miller-Rabin with a witness set that is exact for n's size
+func isPrime(n uint64) bool {
+	if n < 2 {
+		return false
+	}
+	for _, p := range fullWitnesses {
+		if n%p == 0 {
+			return n == p
+		}
+	}
+	if n < 41*41 {
+		return true
+	}
+
+	d, s := n-1, 0
+	for d%2 == 0 {
+		d /= 2
+		s++
+	}
+	witnesses := fullWitnesses
+	if n < 3215031751 {
+		witnesses = smallWitnesses
+	}
+	for _, a := range witnesses {
+		if !millerRabinRound(n, d, s, a) {
+			return false
+		}
+	}
+	return true
+}
+
+// millerRabinRound reports whether n = d*2^s + 1 passes the strong probable-prime
+// test to base a
+func millerRabinRound(n, d uint64, s int, a uint64) bool {
+	x := powMod(a, d, n)
+	if x == 1 || x == n-1 {
+		return true
+	}
+	for i := 1; i < s; i++ {
+		x = mulMod(x, x, n)
+		if x == n-1 {
+			return true
+		}
+	}
+	return false
+}
+
+// mulMod returns a*b mod m without overflowing
+func mulMod(a, b, m uint64) uint64 {
+	hi, lo := bits.Mul64(a, b)
+	return bits.Rem64(hi, lo, m)
+}
+
+// powMod returns b^e mod m
+func powMod(b, e, m uint64) uint64 {
+	result := uint64(1)
+	b %= m
+	for ; e > 0; e >>= 1 {
+		if e&1 == 1 {
+			result = mulMod(result, b, m)
+		}
+		b = mulMod(b, b, m)
+	}
+	return result
+}
@@ -0,0 +1,34 @@
+package worker
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+
+	"github.com/ahmadhassan44/container-orchestrator/pkg/protocol"
+)
+
+// eventStream writes NDJSON StreamEvents to a response, flushing after each line
+type eventStream struct {
+	mu      sync.Mutex
+	w       http.ResponseWriter
+	encoder *json.Encoder
+}
+
+func newEventStream(w http.ResponseWriter) *eventStream {
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	return &eventStream{w: w, encoder: json.NewEncoder(w)}
+}
+
+// send writes one event; operation threads may call it concurrently
+func (s *eventStream) send(event protocol.StreamEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.encoder.Encode(event); err != nil {
+		return // Gateway went away; the job context is cancelled too
+	}
+	if flusher, ok := s.w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
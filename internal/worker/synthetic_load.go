@@ -0,0 +1,232 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ahmadhassan44/container-orchestrator/pkg/protocol"
+)
+
+// loadQuantum is the control period: each thread works part of every quantum and
+// sleeps the rest
+const loadQuantum = 10 * time.Millisecond
+
+// Defaults for the periodic waveforms
+const (
+	defaultSyntheticPeriod = 10.0
+	defaultSyntheticDuty   = 0.5
+)
+
+// loadWaveform returns the target CPU percentage at t seconds into a synthetic load
+func loadWaveform(p *protocol.SyntheticLoad) func(t float64) float64 {
+	period := p.Period
+	if period == 0 {
+		period = defaultSyntheticPeriod
+	}
+	duty := p.Duty
+	if duty == 0 {
+		duty = defaultSyntheticDuty
+	}
+
+	switch p.Pattern {
+	case protocol.SyntheticPatternRamp:
+		return func(t float64) float64 { return p.TargetCPU * min(t/p.Duration, 1) }
+	case protocol.SyntheticPatternSine:
+		return func(t float64) float64 { return p.TargetCPU * (1 - math.Cos(2*math.Pi*t/period)) / 2 }
+	case protocol.SyntheticPatternBurst:
+		return func(t float64) float64 {
+			if math.Mod(t, period) < duty*period {
+				return p.TargetCPU
+			}
+			return 0
+		}
+	default:
+		return func(float64) float64 { return p.TargetCPU }
+	}
+}
+
+// loadController sets one thread's work time per quantum. It measures the CPU time
+// the thread actually got and carries the shortfall (or excess) into the next
+// quantum, correcting for preemption, sleep overshoot and loop overhead that an
+// open-loop work/sleep split leaves in the achieved load.
+type loadController struct {
+	measured bool          // Readings come from the thread's CPU clock
+	lastCPU  time.Duration // Thread CPU time at the last reading
+	debt     time.Duration // CPU time owed to the target; negative when ahead
+	used     time.Duration // Total CPU time consumed
+}
+
+func newLoadController() *loadController {
+	c := &loadController{}
+	c.lastCPU, c.measured = threadCPUTime()
+	return c
+}
+
+// workTime is how long to work in the next quantum to follow ratio (0-1)
+func (c *loadController) workTime(ratio float64) time.Duration {
+	work := time.Duration(ratio*float64(loadQuantum)) + c.debt
+	return min(max(work, 0), loadQuantum)
+}
+
+// settle records a finished quantum that lasted wall and worked for work
+func (c *loadController) settle(ratio float64, wall, work time.Duration) {
+	used := work
+	if c.measured {
+		if now, ok := threadCPUTime(); ok {
+			used = now - c.lastCPU
+			c.lastCPU = now
+		}
+	}
+	c.used += used
+	// Bounded so a stretch of contention isn't repaid as a long spike afterwards
+	c.debt += time.Duration(ratio*float64(wall)) - used
+	c.debt = min(max(c.debt, -loadQuantum), loadQuantum)
+}
+
+// GenerateSyntheticLoad makes the process's CPU usage follow waveform (percent of
+// one CPU, spread over threads) for durationSeconds, starting offset seconds into
+// the waveform. Returns the operations performed and the CPU seconds used.
+func GenerateSyntheticLoad(ctx context.Context, waveform func(t float64) float64, offset, durationSeconds float64, threads int) (float64, float64) {
+	var cancelled atomic.Bool
+	stop := context.AfterFunc(ctx, func() { cancelled.Store(true) })
+	defer stop()
+
+	start := time.Now()
+	endTime := start.Add(time.Duration(durationSeconds * float64(time.Second)))
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var totalOps uint64
+	var totalCPU time.Duration
+
+	wg.Add(threads)
+	for i := 0; i < threads; i++ {
+		go func() {
+			defer wg.Done()
+			// Keeps the thread CPU clock to this goroutine's work
+			runtime.LockOSThread()
+			defer runtime.UnlockOSThread()
+
+			ctl := newLoadController()
+			var localOps uint64
+			for !cancelled.Load() {
+				quantumStart := time.Now()
+				if !quantumStart.Before(endTime) {
+					break
+				}
+				t := offset + quantumStart.Sub(start).Seconds()
+				ratio := min(max(waveform(t)/float64(threads)/100, 0), 1)
+
+				work := ctl.workTime(ratio)
+				for time.Since(quantumStart) < work {
+					_ = math.Sqrt(math.Pow(float64(localOps), 2) + math.Pow(3.14159, 2))
+					_ = math.Sin(float64(localOps)) * math.Cos(float64(localOps))
+					localOps++
+				}
+				if rest := loadQuantum - time.Since(quantumStart); rest > 0 {
+					time.Sleep(min(rest, time.Until(endTime)))
+				}
+				ctl.settle(ratio, time.Since(quantumStart), work)
+			}
+
+			mu.Lock()
+			totalOps += localOps
+			totalCPU += ctl.used
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	return float64(totalOps), totalCPU.Seconds()
+}
+
+// syntheticLoadState is synthetic_load's checkpointed progress
+type syntheticLoadState struct {
+	Ops        float64 `json:"ops"`
+	CPUSeconds float64 `json:"cpu_seconds"`
+}
+
+// syntheticLoadResult reports how closely the load followed its waveform
+type syntheticLoadResult struct {
+	Pattern        string  `json:"pattern"`
+	Operations     float64 `json:"operations"`
+	CPUSeconds     float64 `json:"cpu_seconds"`
+	TargetAvgCPU   float64 `json:"target_avg_cpu"`   // Mean of the waveform, in percent
+	AchievedAvgCPU float64 `json:"achieved_avg_cpu"` // CPU seconds over elapsed time, in percent
+}
+
+// syntheticLoadOperation generates CPU load that follows a waveform, under
+// feedback control; the result compares the achieved load with the target
+func syntheticLoadOperation(jc *JobContext) (*protocol.ResultEnvelope, error) {
+	params := jc.Request.Synthetic
+	pattern := params.Pattern
+	if pattern == "" {
+		pattern = protocol.SyntheticPatternConstant
+	}
+
+	var state syntheticLoadState
+	elapsed := 0.0
+	if cp := jc.Request.Checkpoint; cp != nil {
+		if len(cp.State) > 0 {
+			if err := json.Unmarshal(cp.State, &state); err != nil {
+				return nil, fmt.Errorf("invalid checkpoint state: %w", err)
+			}
+		}
+		elapsed = cp.Elapsed
+		jc.Logf("resuming %s load at %.1fs of %.1fs", pattern, elapsed, params.Duration)
+	}
+
+	runTime := jc.SliceTime(params.Duration - elapsed)
+	jc.Logf("generating %s load peaking at %.1f%% for %.1fs across %d threads",
+		pattern, params.TargetCPU, runTime, jc.Threads)
+
+	ctx := jc.Ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	waveform := loadWaveform(params)
+	startedAt := time.Now()
+	ops, cpuSeconds := GenerateSyntheticLoad(ctx, waveform, elapsed, runTime, jc.Threads)
+	ran := time.Since(startedAt).Seconds()
+	state.Ops += ops
+	state.CPUSeconds += cpuSeconds
+
+	if elapsed+runTime < params.Duration {
+		if err := jc.SaveCheckpoint(elapsed+runTime, state); err != nil {
+			return nil, err
+		}
+		jc.Logf("slice finished, checkpointed at %.1fs", elapsed+runTime)
+	}
+
+	result := syntheticLoadResult{
+		Pattern:        pattern,
+		Operations:     state.Ops,
+		CPUSeconds:     state.CPUSeconds,
+		TargetAvgCPU:   averageLoad(waveform, 0, elapsed+ran),
+		AchievedAvgCPU: 100 * state.CPUSeconds / max(elapsed+ran, 1e-9),
+	}
+	jc.SetMetadata("threads", jc.Threads)
+	jc.SetMetadata("elapsed_seconds", elapsed+ran)
+	jc.Logf("achieved %.1f%% average CPU against a target of %.1f%%", result.AchievedAvgCPU, result.TargetAvgCPU)
+	return protocol.JSONResult(result)
+}
+
+// averageLoad is the mean of waveform over [from, to], sampled every quantum
+func averageLoad(waveform func(t float64) float64, from, to float64) float64 {
+	step := loadQuantum.Seconds()
+	if to-from < step {
+		return waveform(from)
+	}
+	sum, n := 0.0, 0
+	for t := from + step/2; t < to; t += step {
+		sum += waveform(t)
+		n++
+	}
+	return sum / float64(n)
+}
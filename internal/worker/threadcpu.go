@@ -0,0 +1,19 @@
+//go:build linux
+
+package worker
+
+import (
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// threadCPUTime returns the CPU time consumed by the calling OS thread. Callers
+// lock their goroutine to its thread so the reading is theirs alone.
+func threadCPUTime() (time.Duration, bool) {
+	var ts unix.Timespec
+	if err := unix.ClockGettime(unix.CLOCK_THREAD_CPUTIME_ID, &ts); err != nil {
+		return 0, false
+	}
+	return time.Duration(ts.Nano()), true
+}
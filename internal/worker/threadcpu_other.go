@@ -0,0 +1,11 @@
+//go:build !linux
+
+package worker
+
+import "time"
+
+// threadCPUTime is unavailable here: the load controller falls back to trusting
+// its own work phases, i.e. runs open loop
+func threadCPUTime() (time.Duration, bool) {
+	return 0, false
+}
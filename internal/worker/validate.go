@@ -0,0 +1,278 @@
+package worker
+
+import (
+	"fmt"
+	"math"
+	"slices"
+
+	"github.com/ahmadhassan44/container-orchestrator/pkg/protocol"
+)
+
+// ValidateRequest checks a request's parameters against its operation's supported modes.
+// Requests come in two shapes: synthetic load (cpu_load with load_time) and iterative
+// operations (iterations or time_budget, with cpu_load as an optional scheduling
+// hint). Fields belonging to the other shape are rejected rather than ignored.
+// Operations with typed parameters (e.g. "synthetic" for synthetic_load) are
+// load-shaped: their load_time, and cpu_load unless it's only a hint, are filled
+// in from the parameters for the scheduler to reserve and estimate.
+func ValidateRequest(req *protocol.ComputeRequest) error {
+	spec, exists := lookupSpec(req.Operation)
+	if !exists {
+		return fmt.Errorf("unknown operation: %q", req.Operation)
+	}
+	name := operationName(req.Operation)
+
+	for _, p := range typedParams(req) {
+		if p.set && p.field != spec.params {
+			return fmt.Errorf("%s does not take %q parameters", name, p.field)
+		}
+	}
+	if spec.derive != nil {
+		if err := spec.derive(req); err != nil {
+			return err
+		}
+	}
+	if len(req.Profile) > 0 {
+		if !spec.profiles {
+			return fmt.Errorf("%s does not take a load profile", name)
+		}
+		if err := applyProfile(req); err != nil {
+			return err
+		}
+	}
+
+	if req.CPULoad < 0 || req.CPULoad > 200 || (!spec.iterative && !spec.cpuHint && req.CPULoad == 0) {
+		return fmt.Errorf("cpu_load must be between 0 and 200")
+	}
+
+	if spec.iterative {
+		if req.LoadTime != 0 {
+			return fmt.Errorf("%s does not take load_time; set iterations or time_budget instead", name)
+		}
+		if req.Iterations < 0 || req.TimeBudget < 0 {
+			return fmt.Errorf("iterations and time_budget must not be negative")
+		}
+		if (req.Iterations > 0) == (req.TimeBudget > 0) {
+			return fmt.Errorf("%s requires exactly one of iterations or time_budget", name)
+		}
+	} else {
+		if req.Iterations != 0 || req.TimeBudget != 0 {
+			return fmt.Errorf("%s does not take iterations or time_budget; set load_time instead", name)
+		}
+		if req.LoadTime <= 0 {
+			return fmt.Errorf("load_time must be positive")
+		}
+	}
+
+	if req.TargetStdError < 0 || (req.TargetStdError > 0 && !spec.precision) {
+		return fmt.Errorf("target_std_error is not supported by %s", name)
+	}
+
+	if req.Seed != 0 && !spec.randomized {
+		return fmt.Errorf("seed is not supported by %s", name)
+	}
+
+	if (req.SliceTime > 0 || req.Checkpoint != nil) && !spec.checkpoints {
+		return fmt.Errorf("operation %q does not support checkpoints", name)
+	}
+	return nil
+}
+
+// applySynthetic checks a synthetic_load request's parameters and derives its
+// cpu_load (the waveform's peak) and load_time. Either may already be set, as on
+// a request the gateway validated, but only to the derived value.
+func applySynthetic(req *protocol.ComputeRequest) error {
+	p := req.Synthetic
+	if p == nil {
+		return fmt.Errorf("synthetic_load requires \"synthetic\" parameters")
+	}
+	if p.TargetCPU <= 0 || p.TargetCPU > 200 {
+		return fmt.Errorf("synthetic.target_cpu must be between 0 and 200")
+	}
+	if p.Duration <= 0 {
+		return fmt.Errorf("synthetic.duration must be positive")
+	}
+	if p.Pattern != "" && !slices.Contains(protocol.SyntheticPatterns, p.Pattern) {
+		return fmt.Errorf("unknown synthetic.pattern %q (valid: %v)", p.Pattern, protocol.SyntheticPatterns)
+	}
+	periodic := p.Pattern == protocol.SyntheticPatternSine || p.Pattern == protocol.SyntheticPatternBurst
+	if p.Period != 0 && (!periodic || p.Period < 2*loadQuantum.Seconds()) {
+		return fmt.Errorf("synthetic.period must be at least %gs, and is only taken by sine and burst", 2*loadQuantum.Seconds())
+	}
+	if p.Duty != 0 && (p.Pattern != protocol.SyntheticPatternBurst || p.Duty < 0 || p.Duty >= 1) {
+		return fmt.Errorf("synthetic.duty must be between 0 and 1, and is only taken by burst")
+	}
+
+	if (req.CPULoad != 0 && req.CPULoad != p.TargetCPU) || (req.LoadTime != 0 && req.LoadTime != p.Duration) {
+		return fmt.Errorf("synthetic_load sets cpu_load and load_time from synthetic.target_cpu and synthetic.duration; leave them unset")
+	}
+	req.CPULoad = p.TargetCPU
+	req.LoadTime = p.Duration
+	return nil
+}
+
+// typedParam is whether a request carries one operation's parameter block
+type typedParam struct {
+	field string
+	set   bool
+}
+
+func typedParams(req *protocol.ComputeRequest) []typedParam {
+	return []typedParam{
+		{"synthetic", req.Synthetic != nil},
+		{"memory", req.Memory != nil},
+		{"disk_io", req.DiskIO != nil},
+		{"network", req.Network != nil},
+		{"matrix", req.Matrix != nil},
+	}
+}
+
+// Bounds on the resource load operations' sizes
+const (
+	maxWorkingSetMB = 64 * 1024
+	maxDiskFileMB   = 64 * 1024
+	maxDiskBlockKB  = 4096
+	maxMatrixSize   = 4096
+)
+
+// deriveLoadTime sets load_time to an operation's duration parameter, under the
+// same rule as applySynthetic
+func deriveLoadTime(req *protocol.ComputeRequest, field string, duration float64) error {
+	if duration <= 0 {
+		return fmt.Errorf("%s.duration must be positive", field)
+	}
+	if req.LoadTime != 0 && req.LoadTime != duration {
+		return fmt.Errorf("%s sets load_time from %s.duration; leave it unset", operationName(req.Operation), field)
+	}
+	req.LoadTime = duration
+	return nil
+}
+
+// applyMemoryLoad checks a memory_load request's parameters and derives its load_time
+func applyMemoryLoad(req *protocol.ComputeRequest) error {
+	p := req.Memory
+	if p == nil {
+		return fmt.Errorf("memory_load requires \"memory\" parameters")
+	}
+	if p.WorkingSetMB <= 0 || p.WorkingSetMB > maxWorkingSetMB {
+		return fmt.Errorf("memory.working_set_mb must be between 1 and %d", maxWorkingSetMB)
+	}
+	if p.BandwidthMBps < 0 {
+		return fmt.Errorf("memory.bandwidth_mbps must not be negative")
+	}
+	return deriveLoadTime(req, "memory", p.Duration)
+}
+
+// applyDiskIOLoad checks a disk_io_load request's parameters and derives its load_time
+func applyDiskIOLoad(req *protocol.ComputeRequest) error {
+	p := req.DiskIO
+	if p == nil {
+		return fmt.Errorf("disk_io_load requires \"disk_io\" parameters")
+	}
+	if p.FileMB <= 0 || p.FileMB > maxDiskFileMB {
+		return fmt.Errorf("disk_io.file_mb must be between 1 and %d", maxDiskFileMB)
+	}
+	if p.BlockKB < 0 || p.BlockKB > maxDiskBlockKB || p.BlockKB > p.FileMB*1024 {
+		return fmt.Errorf("disk_io.block_kb must be between 1 and %d, and fit in the file", maxDiskBlockKB)
+	}
+	if p.IOPS < 0 {
+		return fmt.Errorf("disk_io.iops must not be negative")
+	}
+	switch p.Access {
+	case "", protocol.DiskAccessSequential, protocol.DiskAccessRandom:
+	default:
+		return fmt.Errorf("unknown disk_io.access %q (valid: sequential, random)", p.Access)
+	}
+	switch p.Mode {
+	case "", protocol.DiskModeRead, protocol.DiskModeWrite, protocol.DiskModeReadWrite:
+	default:
+		return fmt.Errorf("unknown disk_io.mode %q (valid: read, write, readwrite)", p.Mode)
+	}
+	return deriveLoadTime(req, "disk_io", p.Duration)
+}
+
+// maxProfileSegments bounds a load profile's length
+const maxProfileSegments = 256
+
+// applyProfile checks a load profile and derives the request's cpu_load (its peak)
+// and load_time (its total), under the same rule as applySynthetic
+func applyProfile(req *protocol.ComputeRequest) error {
+	if len(req.Profile) > maxProfileSegments {
+		return fmt.Errorf("profile has %d segments (max %d)", len(req.Profile), maxProfileSegments)
+	}
+	peak, total := 0.0, 0.0
+	for i, segment := range req.Profile {
+		if segment.Duration <= 0 {
+			return fmt.Errorf("profile segment %d: duration must be positive", i)
+		}
+		if segment.CPULoad < 0 || segment.CPULoad > 200 {
+			return fmt.Errorf("profile segment %d: cpu_load must be between 0 and 200", i)
+		}
+		peak = max(peak, segment.CPULoad)
+		total += segment.Duration
+	}
+	if peak == 0 {
+		return fmt.Errorf("profile never loads the CPU")
+	}
+
+	if (req.CPULoad != 0 && req.CPULoad != peak) || (req.LoadTime != 0 && req.LoadTime != total) {
+		return fmt.Errorf("a profile sets cpu_load to its peak and load_time to its total duration; leave them unset")
+	}
+	req.CPULoad = peak
+	req.LoadTime = total
+	return nil
+}
+
+// maxNetworkChunkKB bounds network_throughput's write size
+const maxNetworkChunkKB = 4096
+
+// applyNetworkLoad checks a network_throughput request's parameters and derives its load_time
+func applyNetworkLoad(req *protocol.ComputeRequest) error {
+	p := req.Network
+	if p == nil {
+		return fmt.Errorf("network_throughput requires \"network\" parameters")
+	}
+	if p.RateMbps < 0 {
+		return fmt.Errorf("network.rate_mbps must not be negative")
+	}
+	if p.ChunkKB < 0 || p.ChunkKB > maxNetworkChunkKB {
+		return fmt.Errorf("network.chunk_kb must be between 1 and %d", maxNetworkChunkKB)
+	}
+	return deriveLoadTime(req, "network", p.Duration)
+}
+
+// applyMatrix checks a matrix_determinant request's parameters and estimates its
+// load_time from the matrix's size (about 2n^3/3 floating-point operations),
+// under the same rule as applySynthetic
+func applyMatrix(req *protocol.ComputeRequest) error {
+	p := req.Matrix
+	if p == nil {
+		return fmt.Errorf("matrix_determinant requires \"matrix\" parameters")
+	}
+	if (p.Size > 0) == (len(p.Values) > 0) {
+		return fmt.Errorf("matrix_determinant requires exactly one of matrix.size or matrix.values")
+	}
+	n := p.Size
+	if len(p.Values) > 0 {
+		n = len(p.Values)
+		for i, row := range p.Values {
+			if len(row) != n {
+				return fmt.Errorf("matrix.values must be square: row %d has %d entries, not %d", i, len(row), n)
+			}
+		}
+		if req.Seed != 0 {
+			return fmt.Errorf("seed is only taken with matrix.size")
+		}
+	}
+	if n > maxMatrixSize {
+		return fmt.Errorf("matrix must have at most %d rows", maxMatrixSize)
+	}
+
+	estimate := math.Ceil(2 * math.Pow(float64(n), 3) / 3 / matrixFlopsPerSecond)
+	estimate = max(estimate, 1)
+	if req.LoadTime != 0 && req.LoadTime != estimate {
+		return fmt.Errorf("matrix_determinant sets load_time from the matrix's size; leave it unset")
+	}
+	req.LoadTime = estimate
+	return nil
+}
@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"strconv"
+	"strings"
 )
 
 type Config struct {
@@ -15,22 +16,551 @@ type Config struct {
 	// Gateway HTTP port
 	GatewayPort int
 
+	// TLS certificate and key for the public listener (both empty = plain HTTP)
+	TLSCertFile string
+	TLSKeyFile  string
+
+	// Seconds a replaced listener gets to finish in-flight requests on reload
+	ListenerDrainTimeout int
+
 	// Worker base port (8001, 8002, 8003 for cores 1, 2, 3)
 	WorkerBasePort int
 
+	// Number of ports above WorkerBasePort that workers may be given when base+core is taken
+	WorkerPortRange int
+
+	// How the gateway addresses workers: "host" (the published port on WorkerHost),
+	// "container_ip" (the container's address on WorkerNetwork) or "container_name"
+	// (its DNS name on WorkerNetwork, which must be a user-defined network)
+	WorkerAddressMode string
+	WorkerHost        string // Hostname or IP (v4 or v6) in "host" mode
+	WorkerNetwork     string // Docker network workers join (empty = the default bridge)
+
+	// Whether worker ports are published on the host. Always on in "host" mode;
+	// without it workers are reachable only over WorkerNetwork (and via the
+	// gateway's /workers/{core}/proxy/).
+	WorkerPublishPorts bool
+
+	// DNS servers, DNS search domains and extra /etc/hosts entries ("name:ip") for
+	// every worker container (empty = Docker's defaults)
+	WorkerDNS        []string
+	WorkerDNSSearch  []string
+	WorkerExtraHosts []string
+
+	// Worker egress: "allow" or "none" (WorkerNetwork must be an internal network)
+	WorkerEgress string
+
+	// Worker image reference, and whether to pull it (for the host's platform) when missing
+	WorkerImage     string
+	WorkerImagePull bool
+
+	// Where the stable worker identity assigned to each core persists (empty = memory only)
+	WorkerIdentityFile string
+
+	// Extra environment ("KEY=value"), bind mounts ("/host:/container[:ro|rw]") and
+	// tmpfs mounts ("/container[:size=64m][:mode=1777]") for every worker container
+	WorkerEnv    []string
+	WorkerMounts []string
+	WorkerTmpfs  []string
+
+	// Memory limit ("512m") and block I/O limits ("/dev/sda:riops=500:wbps=50m")
+	// for every worker container (empty = unlimited)
+	WorkerMemory   string
+	WorkerIOLimits []string
+
 	// Initial workers to spawn on startup
 	InitialWorkers int
+
+	// Which cores initial workers go on: "pack" (lowest core IDs first), "spread"
+	// (cores furthest apart first) or "cores" (exactly InitialWorkerCores)
+	InitialPlacement   string
+	InitialWorkerCores []int
+
+	// Initial workers started at once, and seconds each may take to pass its health probe
+	StartupConcurrency  int
+	StartupReadyTimeout int
+
+	// Container runtime backend: "docker", "fake" (in-process workers, no Docker)
+	// or "local" (worker binaries run as host processes, degraded)
+	Runtime string
+
+	// Worker executable run by the local runtime (empty = "worker" beside the gateway, else on PATH)
+	WorkerBinary string
+
+	// Fall back to the local runtime when Docker is unreachable at startup instead of exiting
+	LocalExecFallback bool
+
+	// The gateway's own CPUs (cpuset, empty = unpinned), niceness (0 = unchanged)
+	// and I/O priority ("class[:level]", empty = unchanged), so worker load can't
+	// starve the control plane
+	GatewayCPUs       string
+	GatewayNice       int
+	GatewayIOPriority string
+
+	// Bearer token required for /admin endpoints (empty = admin endpoints unprotected)
+	AdminToken string
+
+	// Trust X-Forwarded-For when identifying clients (only enable behind a reverse proxy)
+	TrustProxyHeaders bool
+
+	// Number of finished job records retained in memory
+	JobHistorySize int
+
+	// Seconds a stopping worker may spend finishing its in-flight job before exiting
+	WorkerDrainTimeout int
+
+	// Seconds a gateway shutting down waits for its in-flight jobs before
+	// cancelling them and removing the workers
+	ShutdownTimeout int
+
+	// Log lines captured from a worker container that exits unexpectedly
+	WorkerExitLogLines int
+
+	// Worker image version the gateway expects (empty = the gateway's own version)
+	ExpectedWorkerVersion string
+
+	// Encoding of job dispatch to workers: "json" or "protobuf". Protobuf is only used
+	// with workers that advertise it, and never for streamed jobs.
+	WorkerWire string
+
+	// Name of this gateway in cluster views (default: hostname)
+	NodeName string
+
+	// Base URLs of peer gateways to federate /status and /metrics from
+	PeerGateways []string
+
+	// Forward jobs this gateway can't start right away to a peer that can. PeerSecret
+	// signs forwarded jobs; both ends need it to send or accept them.
+	ForwardToPeers bool
+	PeerSecret     string
+
+	// Base URL of a primary gateway to follow as a hot standby ("" = not a standby).
+	// The replication stream is signed with PeerSecret.
+	ReplicateFrom string
+
+	// Named job queues and the queue used when a request doesn't pick one
+	Queues       []QueueConfig
+	DefaultQueue string
+
+	// Cores each named queue is bound to: its jobs run only there, and other
+	// queues' jobs run only on cores no queue is bound to (unset = any core)
+	QueueCores map[string][]int
+
+	// Seconds per time slice for long checkpointable jobs (0 = never slice). Jobs
+	// longer than this run slice by slice, yielding their worker to queued jobs in between.
+	TimeSlice float64
+
+	// Admission limits: jobs estimated to run longer than MaxJobDuration seconds or
+	// asking for more than MaxIterations are rejected with a sharding suggestion (0 = no limit)
+	MaxJobDuration float64
+	MaxIterations  int64
+
+	// Bounds in seconds on the worker request timeout derived from a job's estimated duration
+	DispatchTimeoutMin float64
+	DispatchTimeoutMax float64
+
+	// Port for the internal listener serving worker-facing APIs (0 = disabled)
+	InternalPort int
+
+	// Serve a sanitized GET /public/status on the main listener; PublicStatusPort
+	// serves it alone on a listener of its own (0 = none)
+	PublicStatus     bool
+	PublicStatusPort int
+
+	// Address the internal listener binds to (empty = the Docker bridge gateway)
+	InternalBindAddr string
+
+	// Secret workers sign internal requests with (empty = random per start)
+	InternalToken string
+
+	// Seconds a signed message's timestamp may be off before it is rejected as stale
+	SignatureTolerance int
+
+	// Provision each worker container with an ed25519 key and require it to sign results
+	ResultSigning bool
+
+	// Seconds between worker image garbage collection runs (0 = only on demand)
+	ImageGCInterval int
+
+	// Seconds an unused worker image is kept before garbage collection may remove it
+	ImageGCRetention int
+
+	// Seconds between reconciliations of tracked workers against the runtime (0 = never)
+	JanitorInterval int
+
+	// Seconds between pings of the Docker daemon (0 = never), and the cap on the
+	// doubling wait between reconnection attempts once it is lost
+	DockerPingInterval int
+	DockerMaxBackoff   int
+
+	// Seconds a worker's CPU measured from streamed Docker stats is trusted before
+	// the scheduler falls back to its jobs' estimates (0 = estimates only)
+	StatsMaxAge int
+
+	// Caps on per-request retry policies
+	RetryMaxAttempts int
+	RetryMaxBackoff  float64 // Seconds
+
+	// Estimated CPU-seconds each source may submit per quota window (0 = unlimited)
+	QuotaCPUSeconds float64
+	QuotaWindow     int // Seconds
+
+	// Adaptive limit on in-flight /submit requests (bounds and starting point)
+	AdaptiveConcurrency     bool
+	ConcurrencyLimitInitial int
+	ConcurrencyLimitMin     int
+	ConcurrencyLimitMax     int
+
+	// Pre-spawn workers ahead of daily peaks learned from hourly load history
+	WarmUpEnabled   bool
+	WarmUpLead      int    // Seconds ahead of an hour its predicted peak is prepared for
+	WarmUpMinDays   int    // Days an hour must be observed before its prediction is used
+	LoadHistoryFile string // Where hourly load statistics persist (empty = memory only)
+
+	// Log of finished jobs and worker spawns behind capacity reports
+	JobHistoryFile          string // Append-only JSON lines (empty = memory only)
+	JobHistoryRetentionDays int    // Entries older than this are dropped on startup
+
+	// Per-minute rollups of utilization, queue depth and throughput behind
+	// GET /metrics/history
+	MetricsHistoryFile string // Append-only JSON lines (empty = memory only)
+	MetricsHistoryDays int    // Rollups older than this are dropped
+
+	// Per-tenant hours jobs are admitted in, enforced by the sample admission
+	// hook: "tenant=days HH:MM-HH:MM [zone][;...]" (empty = no hook)
+	BusinessHours string
+
+	// Vertical worker sizing recommended from the last RecommendationDays of job
+	// history, and applied every RecommendationInterval seconds if RecommendationAutoApply
+	RecommendationDays      int
+	RecommendationInterval  int
+	RecommendationAutoApply bool
+
+	// A queued job is starving once it has waited StarvationFactor times its queue's
+	// median wait (0 = no detection); its queue's weight is then multiplied by
+	// StarvationBoost (1 = flag only)
+	StarvationFactor float64
+	StarvationBoost  float64
+
+	// Default timing policy for multi-tenant isolation: a random delay of up to
+	// TimingJitterMs before results are returned, and durations and timestamps
+	// rounded to TimingGranularity seconds (0 = exact). Overridable per source.
+	TimingJitterMs    int
+	TimingGranularity float64
+
+	// Cancel a /submit job when its client disconnects before the result is ready
+	CancelOnDisconnect bool
+
+	// Where submissions held for maintenance persist (empty = memory only), and how
+	// many are released per second by default
+	IntakeFile        string
+	IntakeReleaseRate float64
+
+	// Autoscaling guards. ScaleDownIdle is how long (seconds) a worker must sit idle
+	// before it is stopped (0 = never scale down). The cooldowns are seconds a scale
+	// event in one direction blocks the opposite one; the rates cap spawns and reaps
+	// per minute (0 = unlimited).
+	ScaleDownIdle      int
+	ScaleUpCooldown    int
+	ScaleDownCooldown  int
+	MaxSpawnsPerMinute int
+	MaxReapsPerMinute  int
+
+	// Bounds on the worker count, kept by spawning and scale-down alike
+	// (MaxWorkers 0 = one per core)
+	MinWorkers int
+	MaxWorkers int
+
+	// A/B experiment: route ExperimentPercent of jobs through the placement
+	// strategy ExperimentStrategy (empty = no experiment)
+	ExperimentStrategy string
+	ExperimentPercent  float64
+
+	// Research telemetry: every scheduling decision and job outcome as CSV rows,
+	// written in the background through a buffer of TelemetryBuffer rows
+	TelemetryFile   string // Empty = disabled
+	TelemetryBuffer int
+
+	// Service level objectives: SLOStartTarget percent of jobs start within
+	// SLOStartSeconds, SLOSuccessTarget percent complete successfully (0 = not
+	// tracked). Compliance covers the last SLOWindow seconds; burn rate the last
+	// SLOBurnWindow. An alert fires when it exceeds SLOAlertBurnRate (0 = off).
+	SLOStartSeconds  float64
+	SLOStartTarget   float64
+	SLOSuccessTarget float64
+	SLOWindow        int // Seconds
+	SLOBurnWindow    int // Seconds
+	SLOAlertBurnRate float64
+
+	// Per-core degradation detection: a core is degraded when its recent median
+	// throughput (over DegradationWindow jobs) is DegradationThreshold percent
+	// below the baseline from its first DegradationBaseline jobs (0 = off)
+	DegradationThreshold float64
+	DegradationBaseline  int
+	DegradationWindow    int
+
+	// Worker health scoring: seconds without a heartbeat at which a worker scores 0,
+	// seconds of dispatch errors counted, and seconds without Docker stats at which
+	// the stats factor bottoms out
+	HealthHeartbeatTimeout int
+	HealthErrorWindow      int
+	HealthStatsStale       int
+
+	// Hyperthread interference: throughput of jobs that shared a physical core
+	// against jobs that had it to themselves, over the last InterferenceWindow jobs
+	// of each, logged every InterferenceInterval seconds (0 = never). Operations
+	// losing InterferenceIsolate percent or more run alone (0 = only on request).
+	InterferenceWindow   int
+	InterferenceInterval int
+	InterferenceIsolate  float64
+
+	// Maintenance windows, "start/end[@core,core][;...]" with RFC3339 times (no
+	// cores = all). Affected cores are drained MaintenanceDrainLead seconds ahead.
+	MaintenanceWindows   string
+	MaintenanceDrainLead int
+
+	// Taints keep jobs that don't tolerate them off this node's cores (NodeTaints)
+	// or off single cores (WorkerTaints, "core:taint" entries)
+	NodeTaints   []string
+	WorkerTaints []string
+
+	// OCI runtime (e.g. "runsc", "kata-runtime") for the worker containers of the
+	// sandbox pool's cores, and operations that may only run in that pool
+	SandboxRuntime    string
+	SandboxCores      []int
+	SandboxOperations []string
+
+	// Longest a worker may be reserved for an admin benchmark session (seconds)
+	BenchmarkMaxDuration int
+
+	// Job lifecycle webhooks: where they go and which transitions are sent.
+	// Empty filter lists match everything.
+	WebhookURL        string
+	WebhookEvents     []string // Job statuses, e.g. "queued", "in_progress", "failed"
+	WebhookQueues     []string
+	WebhookOperations []string
+	WebhookSources    []string // Source IDs as shown in /status
+	WebhookTimeout    int      // Seconds per delivery attempt
+	WebhookRetries    int      // Extra attempts after a failed delivery
+	WebhookSecret     string   // Signs webhooks and compensation callbacks (empty = unsigned)
+
+	// Summary emails to submitters who set notify_email: the SMTP relay (SMTPHost
+	// empty = disabled), the sender, and how many seconds a job or batch must take
+	// from submission to finish before it earns one
+	SMTPHost         string
+	SMTPPort         int
+	SMTPUsername     string
+	SMTPPassword     string
+	SMTPFrom         string
+	EmailMinDuration float64
+
+	// Base URL links to job records point at (empty = http://NODE_NAME:GATEWAY_PORT)
+	GatewayURL string
+
+	// Log outputs and rotation
+	Log LogConfig
+}
+
+// LogConfig selects where log lines go. Outputs are "stderr", "stdout", "syslog"
+// or "file:<path>"; Routes overrides them for lines tagged with a component
+// (e.g. "Audit" for "[Audit] ..." lines).
+type LogConfig struct {
+	Outputs     []string
+	Routes      map[string][]string
+	MaxSizeMB   int // Rotate log files past this size (0 = no limit)
+	MaxAgeHours int // Rotate log files after this long (0 = no limit)
+	MaxBackups  int // Rotated files kept per log file (0 = keep all)
+}
+
+// QueueConfig defines one named job queue with isolated backpressure
+type QueueConfig struct {
+	Name        string
+	MaxSize     int     // Maximum jobs waiting in this queue
+	Timeout     int     // Seconds a job may wait before giving up
+	Weight      int     // Relative share of dequeue turns when several queues have work
+	WorkerShare float64 // Fraction (0-1] of total worker CPU capacity this queue's running jobs may hold
+}
+
+// defaultQueues is used when QUEUES is unset: latency-sensitive work gets most
+// turns, bulk work can never occupy more than a third of the cluster
+var defaultQueues = []QueueConfig{
+	{Name: "interactive", MaxSize: 20, Timeout: 60, Weight: 6, WorkerShare: 1.0},
+	{Name: "batch", MaxSize: 100, Timeout: 300, Weight: 3, WorkerShare: 0.67},
+	{Name: "bulk", MaxSize: 500, Timeout: 3600, Weight: 1, WorkerShare: 0.34},
 }
 
 // LoadConfig reads configuration from environment variables with sensible defaults
 func LoadConfig() *Config {
 	return &Config{
-		MaxCPUThreshold:   getEnvAsFloat("MAX_CPU_THRESHOLD", 100.0),
-		PreSpawnThreshold: getEnvAsFloat("PRESPAWN_THRESHOLD", 99.0),
-		GatewayPort:       getEnvAsInt("GATEWAY_PORT", 3000),
-		WorkerBasePort:    getEnvAsInt("WORKER_BASE_PORT", 8000),
-		InitialWorkers:    getEnvAsInt("INITIAL_WORKERS", 1),
+		MaxCPUThreshold:         getEnvAsFloat("MAX_CPU_THRESHOLD", 100.0),
+		PreSpawnThreshold:       getEnvAsFloat("PRESPAWN_THRESHOLD", 99.0),
+		GatewayPort:             getEnvAsInt("GATEWAY_PORT", 3000),
+		TLSCertFile:             getEnv("TLS_CERT_FILE", ""),
+		TLSKeyFile:              getEnv("TLS_KEY_FILE", ""),
+		ListenerDrainTimeout:    getEnvAsInt("LISTENER_DRAIN_TIMEOUT", 300),
+		WorkerBasePort:          getEnvAsInt("WORKER_BASE_PORT", 8000),
+		WorkerPortRange:         getEnvAsInt("WORKER_PORT_RANGE", 100),
+		WorkerAddressMode:       getEnv("WORKER_ADDRESS_MODE", "host"),
+		WorkerHost:              getEnv("WORKER_HOST", "localhost"),
+		WorkerNetwork:           getEnv("WORKER_NETWORK", ""),
+		WorkerPublishPorts:      getEnvAsBool("WORKER_PUBLISH_PORTS", true),
+		WorkerDNS:               getEnvAsList("WORKER_DNS"),
+		WorkerDNSSearch:         getEnvAsList("WORKER_DNS_SEARCH"),
+		WorkerExtraHosts:        getEnvAsList("WORKER_EXTRA_HOSTS"),
+		WorkerEgress:            getEnv("WORKER_EGRESS", "allow"),
+		WorkerImage:             getEnv("WORKER_IMAGE", "container-orchestrator-worker:latest"),
+		WorkerImagePull:         getEnvAsBool("WORKER_IMAGE_PULL", false),
+		WorkerIdentityFile:      getEnv("WORKER_IDENTITY_FILE", "worker_identities.json"),
+		WorkerEnv:               getEnvAsList("WORKER_ENV"),
+		WorkerMounts:            getEnvAsList("WORKER_MOUNTS"),
+		WorkerTmpfs:             getEnvAsList("WORKER_TMPFS"),
+		WorkerMemory:            getEnv("WORKER_MEMORY", ""),
+		WorkerIOLimits:          getEnvAsList("WORKER_IO_LIMITS"),
+		InitialWorkers:          getEnvAsInt("INITIAL_WORKERS", 1),
+		InitialPlacement:        getEnv("INITIAL_PLACEMENT", "pack"),
+		InitialWorkerCores:      getEnvAsIntList("INITIAL_WORKER_CORES"),
+		StartupConcurrency:      getEnvAsInt("STARTUP_CONCURRENCY", 3),
+		StartupReadyTimeout:     getEnvAsInt("STARTUP_READY_TIMEOUT", 30),
+		Runtime:                 getEnv("RUNTIME", "docker"),
+		WorkerBinary:            getEnv("WORKER_BINARY", ""),
+		LocalExecFallback:       getEnvAsBool("LOCAL_EXEC_FALLBACK", false),
+		GatewayCPUs:             getEnv("GATEWAY_CPUSET", ""),
+		GatewayNice:             getEnvAsInt("GATEWAY_NICE", 0),
+		GatewayIOPriority:       getEnv("GATEWAY_IO_PRIORITY", ""),
+		AdminToken:              getEnv("ADMIN_TOKEN", ""),
+		TrustProxyHeaders:       getEnvAsBool("TRUST_PROXY_HEADERS", false),
+		JobHistorySize:          getEnvAsInt("JOB_HISTORY_SIZE", 1000),
+		WorkerDrainTimeout:      getEnvAsInt("WORKER_DRAIN_TIMEOUT", 30),
+		ShutdownTimeout:         getEnvAsInt("SHUTDOWN_TIMEOUT", 300),
+		WorkerExitLogLines:      getEnvAsInt("WORKER_EXIT_LOG_LINES", 50),
+		ExpectedWorkerVersion:   getEnv("EXPECTED_WORKER_VERSION", ""),
+		WorkerWire:              getEnv("WORKER_WIRE", "json"),
+		NodeName:                getEnv("NODE_NAME", hostname()),
+		PeerGateways:            getEnvAsList("PEER_GATEWAYS"),
+		ForwardToPeers:          getEnvAsBool("FORWARD_TO_PEERS", false),
+		PeerSecret:              getEnv("PEER_SECRET", ""),
+		ReplicateFrom:           getEnv("REPLICATE_FROM", ""),
+		Queues:                  getEnvAsQueues("QUEUES", defaultQueues),
+		DefaultQueue:            getEnv("DEFAULT_QUEUE", "batch"),
+		QueueCores:              getEnvAsQueueCores("QUEUE_CORES"),
+		TimeSlice:               getEnvAsFloat("TIME_SLICE", 60),
+		MaxJobDuration:          getEnvAsFloat("MAX_JOB_DURATION", 3600),
+		MaxIterations:           int64(getEnvAsInt("MAX_ITERATIONS", 0)),
+		DispatchTimeoutMin:      getEnvAsFloat("DISPATCH_TIMEOUT_MIN", 10),
+		DispatchTimeoutMax:      getEnvAsFloat("DISPATCH_TIMEOUT_MAX", 3600),
+		InternalPort:            getEnvAsInt("INTERNAL_PORT", 3001),
+		PublicStatus:            getEnvAsBool("PUBLIC_STATUS", false),
+		PublicStatusPort:        getEnvAsInt("PUBLIC_STATUS_PORT", 0),
+		InternalBindAddr:        getEnv("INTERNAL_BIND_ADDR", ""),
+		InternalToken:           getEnv("INTERNAL_TOKEN", ""),
+		SignatureTolerance:      getEnvAsInt("SIGNATURE_TOLERANCE", 300),
+		ResultSigning:           getEnvAsBool("RESULT_SIGNING", false),
+		ImageGCInterval:         getEnvAsInt("IMAGE_GC_INTERVAL", 3600),
+		JanitorInterval:         getEnvAsInt("JANITOR_INTERVAL", 30),
+		DockerPingInterval:      getEnvAsInt("DOCKER_PING_INTERVAL", 5),
+		DockerMaxBackoff:        getEnvAsInt("DOCKER_MAX_BACKOFF", 60),
+		StatsMaxAge:             getEnvAsInt("STATS_MAX_AGE", 5),
+		ImageGCRetention:        getEnvAsInt("IMAGE_GC_RETENTION", 7*24*3600),
+		RetryMaxAttempts:        getEnvAsInt("RETRY_MAX_ATTEMPTS", 5),
+		RetryMaxBackoff:         getEnvAsFloat("RETRY_MAX_BACKOFF", 60),
+		QuotaCPUSeconds:         getEnvAsFloat("QUOTA_CPU_SECONDS", 0),
+		QuotaWindow:             getEnvAsInt("QUOTA_WINDOW", 3600),
+		AdaptiveConcurrency:     getEnvAsBool("ADAPTIVE_CONCURRENCY", true),
+		ConcurrencyLimitInitial: getEnvAsInt("CONCURRENCY_LIMIT_INITIAL", 100),
+		ConcurrencyLimitMin:     getEnvAsInt("CONCURRENCY_LIMIT_MIN", 10),
+		ConcurrencyLimitMax:     getEnvAsInt("CONCURRENCY_LIMIT_MAX", 1000),
+		WarmUpEnabled:           getEnvAsBool("WARMUP_ENABLED", true),
+		WarmUpLead:              getEnvAsInt("WARMUP_LEAD", 900),
+		WarmUpMinDays:           getEnvAsInt("WARMUP_MIN_DAYS", 3),
+		LoadHistoryFile:         getEnv("LOAD_HISTORY_FILE", "load_history.json"),
+		JobHistoryFile:          getEnv("JOB_HISTORY_FILE", "job_history.jsonl"),
+		JobHistoryRetentionDays: getEnvAsInt("JOB_HISTORY_RETENTION_DAYS", 30),
+		MetricsHistoryFile:      getEnv("METRICS_HISTORY_FILE", "metrics_history.jsonl"),
+		MetricsHistoryDays:      getEnvAsInt("METRICS_HISTORY_DAYS", 7),
+		BusinessHours:           getEnv("BUSINESS_HOURS", ""),
+		RecommendationDays:      getEnvAsInt("RECOMMENDATION_DAYS", 7),
+		RecommendationInterval:  getEnvAsInt("RECOMMENDATION_INTERVAL", 3600),
+		RecommendationAutoApply: getEnvAsBool("RECOMMENDATION_AUTO_APPLY", false),
+		StarvationFactor:        getEnvAsFloat("STARVATION_FACTOR", 4),
+		StarvationBoost:         getEnvAsFloat("STARVATION_BOOST", 2),
+		TimingJitterMs:          getEnvAsInt("TIMING_JITTER_MS", 0),
+		TimingGranularity:       getEnvAsFloat("TIMING_GRANULARITY", 0),
+		CancelOnDisconnect:      getEnvAsBool("CANCEL_ON_DISCONNECT", true),
+		IntakeFile:              getEnv("INTAKE_FILE", "held_jobs.json"),
+		IntakeReleaseRate:       getEnvAsFloat("INTAKE_RELEASE_RATE", 1),
+		ScaleDownIdle:           getEnvAsInt("SCALE_DOWN_IDLE", 0),
+		ScaleUpCooldown:         getEnvAsInt("SCALE_UP_COOLDOWN", 30),
+		ScaleDownCooldown:       getEnvAsInt("SCALE_DOWN_COOLDOWN", 120),
+		MaxSpawnsPerMinute:      getEnvAsInt("MAX_SPAWNS_PER_MINUTE", 0),
+		MaxReapsPerMinute:       getEnvAsInt("MAX_REAPS_PER_MINUTE", 1),
+		MinWorkers:              getEnvAsInt("MIN_WORKERS", 0),
+		MaxWorkers:              getEnvAsInt("MAX_WORKERS", 0),
+		ExperimentStrategy:      getEnv("EXPERIMENT_STRATEGY", ""),
+		ExperimentPercent:       getEnvAsFloat("EXPERIMENT_PERCENT", 10),
+		TelemetryFile:           getEnv("TELEMETRY_FILE", ""),
+		TelemetryBuffer:         getEnvAsInt("TELEMETRY_BUFFER", 10000),
+		SLOStartSeconds:         getEnvAsFloat("SLO_START_SECONDS", 10),
+		SLOStartTarget:          getEnvAsFloat("SLO_START_TARGET", 95),
+		SLOSuccessTarget:        getEnvAsFloat("SLO_SUCCESS_TARGET", 99),
+		SLOWindow:               getEnvAsInt("SLO_WINDOW", 86400),
+		SLOBurnWindow:           getEnvAsInt("SLO_BURN_WINDOW", 3600),
+		SLOAlertBurnRate:        getEnvAsFloat("SLO_ALERT_BURN_RATE", 0),
+		DegradationThreshold:    getEnvAsFloat("DEGRADATION_THRESHOLD", 20),
+		DegradationBaseline:     getEnvAsInt("DEGRADATION_BASELINE_SAMPLES", 5),
+		DegradationWindow:       getEnvAsInt("DEGRADATION_WINDOW", 10),
+		HealthHeartbeatTimeout:  getEnvAsInt("HEALTH_HEARTBEAT_TIMEOUT", 30),
+		HealthErrorWindow:       getEnvAsInt("HEALTH_ERROR_WINDOW", 300),
+		HealthStatsStale:        getEnvAsInt("HEALTH_STATS_STALE", 60),
+		InterferenceWindow:      getEnvAsInt("INTERFERENCE_WINDOW", 50),
+		InterferenceInterval:    getEnvAsInt("INTERFERENCE_REPORT_INTERVAL", 3600),
+		InterferenceIsolate:     getEnvAsFloat("INTERFERENCE_ISOLATE", 0),
+		MaintenanceWindows:      getEnv("MAINTENANCE_WINDOWS", ""),
+		MaintenanceDrainLead:    getEnvAsInt("MAINTENANCE_DRAIN_LEAD", 600),
+		NodeTaints:              getEnvAsList("NODE_TAINTS"),
+		WorkerTaints:            getEnvAsList("WORKER_TAINTS"),
+		SandboxRuntime:          getEnv("SANDBOX_RUNTIME", ""),
+		SandboxCores:            getEnvAsIntList("SANDBOX_CORES"),
+		SandboxOperations:       getEnvAsList("SANDBOX_OPERATIONS"),
+		BenchmarkMaxDuration:    getEnvAsInt("BENCHMARK_MAX_DURATION", 3600),
+		WebhookURL:              getEnv("WEBHOOK_URL", ""),
+		WebhookEvents:           getEnvAsListDefault("WEBHOOK_EVENTS", []string{"queued", "in_progress", "completed", "failed", "cancelled"}),
+		WebhookQueues:           getEnvAsList("WEBHOOK_QUEUES"),
+		WebhookOperations:       getEnvAsList("WEBHOOK_OPERATIONS"),
+		WebhookSources:          getEnvAsList("WEBHOOK_SOURCES"),
+		WebhookTimeout:          getEnvAsInt("WEBHOOK_TIMEOUT", 5),
+		WebhookRetries:          getEnvAsInt("WEBHOOK_RETRIES", 3),
+		WebhookSecret:           getEnv("WEBHOOK_SECRET", ""),
+		SMTPHost:                getEnv("SMTP_HOST", ""),
+		SMTPPort:                getEnvAsInt("SMTP_PORT", 587),
+		SMTPUsername:            getEnv("SMTP_USERNAME", ""),
+		SMTPPassword:            getEnv("SMTP_PASSWORD", ""),
+		SMTPFrom:                getEnv("SMTP_FROM", ""),
+		EmailMinDuration:        getEnvAsFloat("EMAIL_MIN_DURATION", 60),
+		GatewayURL:              getEnv("GATEWAY_URL", ""),
+		Log:                     LoadLogConfig(),
+	}
+}
+
+// LoadLogConfig reads log sink settings; shared by the gateway and worker binaries
+func LoadLogConfig() LogConfig {
+	outputs := getEnvAsList("LOG_OUTPUT")
+	if len(outputs) == 0 {
+		outputs = []string{"stderr"}
+	}
+	return LogConfig{
+		Outputs:     outputs,
+		Routes:      getEnvAsRoutes("LOG_ROUTES"),
+		MaxSizeMB:   getEnvAsInt("LOG_MAX_SIZE_MB", 100),
+		MaxAgeHours: getEnvAsInt("LOG_MAX_AGE_HOURS", 24),
+		MaxBackups:  getEnvAsInt("LOG_MAX_BACKUPS", 7),
+	}
+}
+
+func getEnv(key string, defaultVal string) string {
+	if val := os.Getenv(key); val != "" {
+		return val
 	}
+	return defaultVal
 }
 
 func getEnvAsFloat(key string, defaultVal float64) float64 {
@@ -50,3 +580,119 @@ func getEnvAsInt(key string, defaultVal int) int {
 	}
 	return defaultVal
 }
+
+func getEnvAsBool(key string, defaultVal bool) bool {
+	if val := os.Getenv(key); val != "" {
+		if parsed, err := strconv.ParseBool(val); err == nil {
+			return parsed
+		}
+	}
+	return defaultVal
+}
+
+// getEnvAsList parses a comma-separated list, dropping empty entries
+func getEnvAsList(key string) []string {
+	var list []string
+	for _, item := range strings.Split(os.Getenv(key), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// getEnvAsListDefault is getEnvAsList with a fallback for an unset or empty variable
+func getEnvAsListDefault(key string, defaultVal []string) []string {
+	if list := getEnvAsList(key); len(list) > 0 {
+		return list
+	}
+	return defaultVal
+}
+
+// getEnvAsIntList parses a comma-separated list of integers, skipping malformed entries
+func getEnvAsIntList(key string) []int {
+	var list []int
+	for _, item := range getEnvAsList(key) {
+		if parsed, err := strconv.Atoi(item); err == nil {
+			list = append(list, parsed)
+		}
+	}
+	return list
+}
+
+// getEnvAsRoutes parses "Component=output+output,..." (e.g. "Audit=file:/var/log/audit.log+syslog").
+// Malformed entries are skipped.
+func getEnvAsRoutes(key string) map[string][]string {
+	routes := make(map[string][]string)
+	for _, spec := range getEnvAsList(key) {
+		component, outputs, found := strings.Cut(spec, "=")
+		if !found || component == "" || outputs == "" {
+			continue
+		}
+		for _, output := range strings.Split(outputs, "+") {
+			if output = strings.TrimSpace(output); output != "" {
+				routes[strings.TrimSpace(component)] = append(routes[strings.TrimSpace(component)], output)
+			}
+		}
+	}
+	return routes
+}
+
+// getEnvAsQueueCores parses "queue=core+core,..." (e.g. "interactive=1,batch=2+3").
+// Malformed entries and core IDs are skipped.
+func getEnvAsQueueCores(key string) map[string][]int {
+	queueCores := make(map[string][]int)
+	for _, spec := range getEnvAsList(key) {
+		queue, cores, found := strings.Cut(spec, "=")
+		if queue = strings.TrimSpace(queue); !found || queue == "" {
+			continue
+		}
+		for _, core := range strings.Split(cores, "+") {
+			if parsed, err := strconv.Atoi(strings.TrimSpace(core)); err == nil {
+				queueCores[queue] = append(queueCores[queue], parsed)
+			}
+		}
+	}
+	return queueCores
+}
+
+// getEnvAsQueues parses "name:size:timeout:weight:share,..." (e.g. "interactive:20:60:6:1.0").
+// Malformed entries are skipped; if nothing valid remains the defaults are used.
+func getEnvAsQueues(key string, defaultVal []QueueConfig) []QueueConfig {
+	var queues []QueueConfig
+	for _, spec := range getEnvAsList(key) {
+		parts := strings.Split(spec, ":")
+		if len(parts) != 5 || parts[0] == "" {
+			continue
+		}
+
+		size, errSize := strconv.Atoi(parts[1])
+		timeout, errTimeout := strconv.Atoi(parts[2])
+		weight, errWeight := strconv.Atoi(parts[3])
+		share, errShare := strconv.ParseFloat(parts[4], 64)
+		if errSize != nil || errTimeout != nil || errWeight != nil || errShare != nil ||
+			size <= 0 || timeout <= 0 || weight <= 0 || share <= 0 || share > 1 {
+			continue
+		}
+
+		queues = append(queues, QueueConfig{
+			Name:        parts[0],
+			MaxSize:     size,
+			Timeout:     timeout,
+			Weight:      weight,
+			WorkerShare: share,
+		})
+	}
+
+	if len(queues) == 0 {
+		return defaultVal
+	}
+	return queues
+}
+
+func hostname() string {
+	if name, err := os.Hostname(); err == nil {
+		return name
+	}
+	return "gateway"
+}
@@ -0,0 +1,109 @@
+package logging
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/ahmadhassan44/container-orchestrator/pkg/config"
+)
+
+// Setup points the standard logger at the configured sinks. Lines are routed by
+// their [Component] prefix; components without a route go to the default outputs.
+func Setup(cfg config.LogConfig) error {
+	opened := make(map[string]io.Writer)
+	open := func(specs []string) ([]io.Writer, error) {
+		var sinks []io.Writer
+		for _, spec := range specs {
+			if sink, exists := opened[spec]; exists {
+				sinks = append(sinks, sink)
+				continue
+			}
+			sink, err := openSink(spec, cfg)
+			if err != nil {
+				return nil, fmt.Errorf("log sink %q: %w", spec, err)
+			}
+			opened[spec] = sink
+			sinks = append(sinks, sink)
+		}
+		return sinks, nil
+	}
+
+	router := &router{routes: make(map[string][]io.Writer)}
+	var err error
+	if router.defaults, err = open(cfg.Outputs); err != nil {
+		return err
+	}
+	for component, specs := range cfg.Routes {
+		if router.routes[component], err = open(specs); err != nil {
+			return err
+		}
+	}
+
+	log.SetOutput(router)
+	return nil
+}
+
+// openSink creates the writer for one output: "stderr", "stdout", "file:<path>" or "syslog"
+func openSink(spec string, cfg config.LogConfig) (io.Writer, error) {
+	switch {
+	case spec == "stderr":
+		return os.Stderr, nil
+	case spec == "stdout":
+		return os.Stdout, nil
+	case spec == "syslog":
+		return newSyslogSink()
+	case strings.HasPrefix(spec, "file:"):
+		return NewRotatingFile(strings.TrimPrefix(spec, "file:"), cfg.MaxSizeMB, cfg.MaxAgeHours, cfg.MaxBackups)
+	}
+	return nil, fmt.Errorf("unknown output (want stderr, stdout, syslog or file:<path>)")
+}
+
+// router fans each log line out to the sinks for its component
+type router struct {
+	defaults []io.Writer
+	routes   map[string][]io.Writer // Component name (without brackets) -> sinks
+}
+
+// Write receives exactly one formatted line per call from the log package
+func (r *router) Write(line []byte) (int, error) {
+	sinks, exists := r.routes[component(line)]
+	if !exists {
+		sinks = r.defaults
+	}
+	for _, sink := range sinks {
+		// Nowhere left to report a failing sink; keep the others going
+		sink.Write(line)
+	}
+	return len(line), nil
+}
+
+// component extracts "Scheduler" from "2026/01/02 15:04:05 [Scheduler] ...". Lines
+// without a bracketed tag straight after the timestamp have no component.
+func component(line []byte) string {
+	text := string(line)
+	open := strings.IndexByte(text, '[')
+	if open < 0 || strings.Trim(text[:open], "0123456789/:. \n") != "" {
+		return ""
+	}
+	end := strings.IndexByte(text[open:], ']')
+	if end < 0 {
+		return ""
+	}
+	return text[open+1 : open+end]
+}
+
+// message strips the timestamp the log package prepends, for sinks that add their own
+func message(line []byte) string {
+	text := strings.TrimRight(string(line), "\n")
+	if open := strings.IndexByte(text, '['); open >= 0 && strings.Trim(text[:open], "0123456789/:. \n") == "" {
+		return text[open:]
+	}
+	// No component tag: drop the standard "2006/01/02 15:04:05 " prefix if present
+	if len(text) > 20 && text[4] == '/' && text[19] == ' ' {
+		return text[20:]
+	}
+	return text
+}
@@ -0,0 +1,120 @@
+package logging
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// RotatingFile is a log file that is rotated once it grows past a size or age
+// limit, keeping a bounded number of rotated copies beside it
+type RotatingFile struct {
+	mu         sync.Mutex
+	path       string
+	maxSize    int64         // Bytes (0 = no size limit)
+	maxAge     time.Duration // Rotate after the file has been written this long (0 = never)
+	maxBackups int           // Rotated copies kept (0 = keep all)
+
+	file     *os.File
+	size     int64
+	openedAt time.Time
+}
+
+func NewRotatingFile(path string, maxSizeMB, maxAgeHours, maxBackups int) (*RotatingFile, error) {
+	if path == "" {
+		return nil, fmt.Errorf("empty file path")
+	}
+	f := &RotatingFile{
+		path:       path,
+		maxSize:    int64(maxSizeMB) * 1024 * 1024,
+		maxAge:     time.Duration(maxAgeHours) * time.Hour,
+		maxBackups: maxBackups,
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *RotatingFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.size > 0 && f.due(int64(len(p))) {
+		if err := f.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+// due reports whether writing n more bytes should go to a fresh file
+func (f *RotatingFile) due(n int64) bool {
+	return (f.maxSize > 0 && f.size+n > f.maxSize) || (f.maxAge > 0 && time.Since(f.openedAt) >= f.maxAge)
+}
+
+// open appends to an existing file, treating its last modification as when it
+// was started so restarts don't reset the age limit
+func (f *RotatingFile) open() error {
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	f.file = file
+	f.size = info.Size()
+	f.openedAt = time.Now()
+	if f.size > 0 {
+		f.openedAt = info.ModTime()
+	}
+	return nil
+}
+
+// rotate renames the current file to <path>.<timestamp> and starts a new one
+func (f *RotatingFile) rotate() error {
+	f.file.Close()
+	backup := f.path + "." + time.Now().Format("20060102-150405.000")
+	if err := os.Rename(f.path, backup); err != nil {
+		return err
+	}
+	if err := f.open(); err != nil {
+		return err
+	}
+	f.prune()
+	return nil
+}
+
+// prune deletes the oldest rotated copies beyond maxBackups
+func (f *RotatingFile) prune() {
+	if f.maxBackups <= 0 {
+		return
+	}
+	backups, err := filepath.Glob(f.path + ".*")
+	if err != nil || len(backups) <= f.maxBackups {
+		return
+	}
+	// Timestamp suffixes sort chronologically
+	sort.Strings(backups)
+	for _, old := range backups[:len(backups)-f.maxBackups] {
+		os.Remove(old)
+	}
+}
+
+func (f *RotatingFile) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.file.Close()
+}
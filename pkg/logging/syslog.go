@@ -0,0 +1,38 @@
+//go:build !windows && !plan9
+
+package logging
+
+import (
+	"io"
+	"log/syslog"
+	"os"
+	"path/filepath"
+)
+
+// syslogSink forwards lines to the local syslog daemon (journald listens on the
+// same socket), mapping [ERROR] and [WARNING] lines to their severities
+type syslogSink struct {
+	w *syslog.Writer
+}
+
+func newSyslogSink() (io.Writer, error) {
+	w, err := syslog.New(syslog.LOG_INFO|syslog.LOG_DAEMON, filepath.Base(os.Args[0]))
+	if err != nil {
+		return nil, err
+	}
+	return &syslogSink{w: w}, nil
+}
+
+func (s *syslogSink) Write(line []byte) (int, error) {
+	msg := message(line)
+	var err error
+	switch component(line) {
+	case "ERROR", "FATAL":
+		err = s.w.Err(msg)
+	case "WARNING":
+		err = s.w.Warning(msg)
+	default:
+		err = s.w.Info(msg)
+	}
+	return len(line), err
+}
@@ -1,13 +1,282 @@
 package protocol
 
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// DefaultOperation is used when a request doesn't name one
+const DefaultOperation = "cpu_load"
+
 type ComputeRequest struct {
+	// JobID is assigned by the gateway before dispatch so worker logs and
+	// responses carry the same identifier as the gateway's job record. A client
+	// may choose it on /submit (a UUID) to correlate the job with its own systems.
+	JobID string `json:"job_id,omitempty"`
+
+	// Operation selects the worker-side computation (default: "cpu_load")
+	Operation string `json:"operation,omitempty"`
+
 	// CPULoad is the target CPU usage percentage (0-100)
 	// Example: 50 means 50% CPU utilization
+	// Required for cpu_load; for iterative operations it is an optional scheduling hint.
+	// Derived from Profile, or from Synthetic for synthetic_load.
 	CPULoad float64 `json:"cpu_load"`
 
-	// LoadTime is how long the CPU should be loaded (in seconds)
+	// LoadTime is how long the CPU should be loaded (in seconds), cpu_load only
 	// Example: 5.0 means sustain the load for 5 seconds
 	LoadTime float64 `json:"load_time"`
+
+	// Iterations is the fixed amount of work for iterative operations (e.g. samples
+	// for monte_carlo_pi, or integers tested for prime_search)
+	Iterations int64 `json:"iterations,omitempty"`
+
+	// TimeBudget replaces Iterations with a wall-clock budget in seconds: the worker
+	// runs the operation until the budget expires and reports how many iterations it completed
+	TimeBudget float64 `json:"time_budget,omitempty"`
+
+	// TargetStdError stops statistical operations early once the result's standard
+	// error reaches this bound; Iterations or TimeBudget then act as the maximum budget
+	TargetStdError float64 `json:"target_std_error,omitempty"`
+
+	// Seed makes randomized operations reproducible (0 = random); rejected by deterministic ones
+	Seed int64 `json:"seed,omitempty"`
+
+	// Queue names the queue to wait in when all workers are busy (default: gateway's DEFAULT_QUEUE)
+	Queue string `json:"queue,omitempty"`
+
+	// OnQueueTimeout is what happens when the job outwaits its queue's timeout (see
+	// QueueTimeout constants; "" = QueueTimeoutFail)
+	OnQueueTimeout string `json:"on_queue_timeout,omitempty"`
+
+	// Priority orders jobs within their queue: higher goes first, equal priorities
+	// in arrival order (default: 0)
+	Priority int `json:"priority,omitempty"`
+
+	// Deadline replaces the queue's timeout: the latest time the job may start
+	// before its OnQueueTimeout policy applies (zero = the queue's timeout)
+	Deadline time.Time `json:"deadline,omitzero"`
+
+	// CaptureLogs asks the worker to return the operation's debug output with the result
+	CaptureLogs bool `json:"capture_logs,omitempty"`
+
+	// Stream asks for NDJSON StreamEvents (progress, then the result) instead of a single JSON response
+	Stream bool `json:"stream,omitempty"`
+
+	// Async answers 202 with the job ID as soon as the job is accepted; the
+	// result is then read from GET /jobs/{id}
+	Async bool `json:"async,omitempty"`
+
+	// ProgressEvery emits a progress event every N iterations while streaming (0 = DefaultProgressEvery)
+	ProgressEvery int64 `json:"progress_every,omitempty"`
+
+	// SliceTime caps how many seconds this run may work before checkpointing
+	// (0 = run to completion). Only honoured by operations that support checkpoints.
+	SliceTime float64 `json:"slice_time,omitempty"`
+
+	// Checkpoint resumes a previously sliced run
+	Checkpoint *Checkpoint `json:"checkpoint,omitempty"`
+
+	// Retry opts into automatic retries of failed attempts (default: fail on the first error)
+	Retry *RetryPolicy `json:"retry,omitempty"`
+
+	// Annotate includes the scheduler's annotations in the response (they are always
+	// kept on the gateway's job record)
+	Annotate bool `json:"annotate,omitempty"`
+
+	// PartialResults returns whatever result the operation has when the job is
+	// cancelled or hits its deadline, flagged Partial, instead of an error
+	PartialResults bool `json:"partial_results,omitempty"`
+
+	// Labels tag the job for selecting it later, e.g. {"experiment": "42"}
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// Debug records a timeline of how the gateway handled the job: scheduling
+	// decisions, worker dispatches and sampled progress (GET /jobs/{id}/trace)
+	Debug bool `json:"debug,omitempty"`
+
+	// NotifyEmail is sent a summary when the job finishes, if it ran longer than
+	// the gateway's EMAIL_MIN_DURATION
+	NotifyEmail string `json:"notify_email,omitempty"`
+
+	// Tolerations let the job run on cores with matching taints: a taint ("key=value"),
+	// a taint key, or "*" for any
+	Tolerations []string `json:"tolerations,omitempty"`
+
+	// NoSMTSharing runs the job alone on its physical cores: on an idle worker
+	// whose hyperthread siblings are idle too, and kept that way while it runs
+	NoSMTSharing bool `json:"no_smt_sharing,omitempty"`
+
+	// Synthetic holds the synthetic_load operation's parameters
+	Synthetic *SyntheticLoad `json:"synthetic,omitempty"`
+
+	// Memory and DiskIO hold the memory_load and disk_io_load operations' parameters
+	Memory *MemoryLoad `json:"memory,omitempty"`
+	DiskIO *DiskIOLoad `json:"disk_io,omitempty"`
+
+	// Network holds the network_throughput operation's parameters
+	Network *NetworkLoad `json:"network,omitempty"`
+
+	// Matrix holds the matrix_determinant operation's parameters
+	Matrix *MatrixParams `json:"matrix,omitempty"`
+
+	// Profile makes cpu_load follow these segments in turn instead of a constant
+	// load. CPULoad becomes the profile's peak and LoadTime its total duration.
+	Profile []LoadSegment `json:"profile,omitempty"`
+}
+
+// MemoryLoad parameterises memory_load, which allocates a working set, touches
+// every page, then rewrites it in passes at a target bandwidth
+type MemoryLoad struct {
+	WorkingSetMB  int     `json:"working_set_mb"`           // MiB
+	BandwidthMBps float64 `json:"bandwidth_mbps,omitempty"` // Target MiB/s written (0 = as fast as possible)
+	Duration      float64 `json:"duration"`                 // Seconds
+}
+
+// DiskIOLoad parameterises disk_io_load, which reads and writes blocks of a
+// scratch file inside the worker container at a target IOPS
+type DiskIOLoad struct {
+	FileMB   int     `json:"file_mb"`            // Scratch file size in MiB
+	BlockKB  int     `json:"block_kb,omitempty"` // KiB per operation (default: 4)
+	IOPS     float64 `json:"iops,omitempty"`     // Target operations per second (0 = as fast as possible)
+	Access   string  `json:"access,omitempty"`   // One of the DiskAccess* patterns (default: sequential)
+	Mode     string  `json:"mode,omitempty"`     // One of the DiskMode* mixes (default: write)
+	Sync     bool    `json:"sync,omitempty"`     // fsync after every write, so writes reach the device
+	Duration float64 `json:"duration"`           // Seconds
+}
+
+// NetworkLoad parameterises network_throughput, which streams data from the
+// worker running the job to a second worker while measuring round trips to it
+type NetworkLoad struct {
+	Duration float64 `json:"duration"`            // Seconds
+	RateMbps float64 `json:"rate_mbps,omitempty"` // Target megabits per second sent (0 = as fast as possible)
+	ChunkKB  int     `json:"chunk_kb,omitempty"`  // KiB per write (default: 64)
+
+	// Receiver is the receiving worker's URL as seen from the sender. The gateway
+	// sets it when it reserves the pair; clients leave it unset.
+	Receiver string `json:"receiver,omitempty"`
+}
+
+// MatrixParams parameterises matrix_determinant, which takes the determinant of
+// either the given matrix or a random one of the given size
+type MatrixParams struct {
+	Size   int         `json:"size,omitempty"`   // Rows of a random matrix, seeded by the request's seed
+	Values [][]float64 `json:"values,omitempty"` // A square matrix, row by row, instead of a random one
+}
+
+// disk_io_load access patterns and operation mixes
+const (
+	DiskAccessSequential = "sequential"
+	DiskAccessRandom     = "random"
+
+	DiskModeRead      = "read"
+	DiskModeWrite     = "write"
+	DiskModeReadWrite = "readwrite" // Alternates reads and writes
+)
+
+// LoadSegment is one step of a load profile
+type LoadSegment struct {
+	Duration float64 `json:"duration"`       // Seconds
+	CPULoad  float64 `json:"cpu_load"`       // CPU percentage (0-200; 0 idles)
+	Ramp     bool    `json:"ramp,omitempty"` // Move linearly from the previous segment's load (0 for the first) instead of stepping
+}
+
+// SyntheticLoad shapes the CPU usage of a synthetic_load job over time. The
+// gateway schedules it as cpu_load = TargetCPU (its peak) for load_time = Duration.
+type SyntheticLoad struct {
+	TargetCPU float64 `json:"target_cpu"`        // Peak CPU percentage (0-200)
+	Duration  float64 `json:"duration"`          // Seconds
+	Pattern   string  `json:"pattern,omitempty"` // One of the SyntheticPattern* waveforms (default: constant)
+	Period    float64 `json:"period,omitempty"`  // Seconds per cycle, sine and burst only (default: 10)
+	Duty      float64 `json:"duty,omitempty"`    // Fraction of each cycle at TargetCPU, burst only (default: 0.5)
+}
+
+// Queue timeout policies
+const (
+	QueueTimeoutFail      = "fail"      // Fail the job
+	QueueTimeoutExtend    = "extend"    // Wait one more queue timeout, then fail
+	QueueTimeoutDowngrade = "downgrade" // Move to the next lower-weight queue and keep waiting; fail in the lowest
+)
+
+// Synthetic load waveforms
+const (
+	SyntheticPatternConstant = "constant" // TargetCPU throughout
+	SyntheticPatternRamp     = "ramp"     // Linear rise from 0 to TargetCPU over Duration
+	SyntheticPatternSine     = "sine"     // Oscillates between 0 and TargetCPU, starting at 0
+	SyntheticPatternBurst    = "burst"    // TargetCPU for Duty of each Period, idle for the rest
+)
+
+// SyntheticPatterns lists every valid SyntheticLoad.Pattern
+var SyntheticPatterns = []string{SyntheticPatternConstant, SyntheticPatternRamp, SyntheticPatternSine, SyntheticPatternBurst}
+
+// RetryPolicy controls how the gateway retries a failed job. The gateway caps
+// attempts and backoff at its configured limits.
+type RetryPolicy struct {
+	MaxAttempts int      `json:"max_attempts"`       // Total attempts, including the first
+	Backoff     float64  `json:"backoff,omitempty"`  // Seconds before the first retry, doubling after each (default: 1)
+	RetryOn     []string `json:"retry_on,omitempty"` // Failure kinds to retry (default: worker_exit, connection)
+}
+
+// Failure kinds a RetryPolicy can retry on
+const (
+	FailureWorkerExit  = "worker_exit"  // The worker container died mid-job
+	FailureConnection  = "connection"   // The worker couldn't be reached or broke off the response
+	FailureTimeout     = "timeout"      // The job overran its dispatch timeout
+	FailureWorkerError = "worker_error" // The worker reported the job failed
+	FailureQueue       = "queue"        // The queue was full, the job expired in it, or no worker could start
+)
+
+// FailureKinds lists every valid RetryPolicy.RetryOn entry
+var FailureKinds = []string{FailureWorkerExit, FailureConnection, FailureTimeout, FailureWorkerError, FailureQueue}
+
+// BatchRequest submits several jobs together (POST /batches). OnFailure decides
+// what happens to the rest of the batch when one job fails permanently.
+type BatchRequest struct {
+	Jobs      []ComputeRequest `json:"jobs"`
+	OnFailure string           `json:"on_failure,omitempty"` // One of the BatchOnFailure* policies (default: continue)
+
+	// CompensationURL receives a POST per completed job when a rollback runs
+	// (required for BatchOnFailureRollback)
+	CompensationURL string `json:"compensation_url,omitempty"`
+
+	// NotifyEmail is sent a summary of the whole batch when it finishes, if it ran
+	// longer than the gateway's EMAIL_MIN_DURATION
+	NotifyEmail string `json:"notify_email,omitempty"`
+}
+
+// Batch failure policies
+const (
+	BatchOnFailureContinue = "continue"        // Let the other jobs run to their own outcome
+	BatchOnFailureAbort    = "abort_remaining" // Cancel siblings that haven't started yet
+	BatchOnFailureRollback = "rollback"        // Abort, then compensate every job that completed
+)
+
+// Manifest describes a run of related jobs in one YAML or JSON document (POST
+// /submit/manifest), like a small CI pipeline file
+type Manifest struct {
+	Name string `json:"name,omitempty"`
+
+	// Templates are named partial requests that jobs extend
+	Templates map[string]map[string]interface{} `json:"templates,omitempty"`
+
+	Jobs []ManifestJob `json:"jobs"`
+}
+
+// ManifestJob is one job of a manifest: its template's fields overlaid with
+// Request, started once every job it Needs has completed
+type ManifestJob struct {
+	Name     string                 `json:"name"`
+	Template string                 `json:"template,omitempty"`
+	Request  map[string]interface{} `json:"request,omitempty"`  // ComputeRequest fields, overriding the template's
+	Needs    []string               `json:"needs,omitempty"`    // Names of jobs that must complete first
+	Priority int                    `json:"priority,omitempty"` // Higher starts first among jobs ready together
+}
+
+// Checkpoint is an operation's saved progress between time slices
+type Checkpoint struct {
+	Elapsed float64         `json:"elapsed"`         // Seconds of work completed so far
+	State   json.RawMessage `json:"state,omitempty"` // Operation-specific progress
 }
 
 type JobParameters struct {
@@ -17,10 +286,163 @@ type JobParameters struct {
 }
 
 type JobResponse struct {
-	JobID     string  `json:"job_id"`
-	WorkerID  string  `json:"worker_id"`
-	Result    float64 `json:"result"`     // The actual math answer
-	TimeTaken string  `json:"time_taken"` // "1.24s"
+	JobID      string          `json:"job_id"`
+	WorkerID   string          `json:"worker_id"`
+	WorkerUUID string          `json:"worker_uuid,omitempty"` // Stable identity of the worker slot, across container restarts
+	Result     float64         `json:"result"`                // Compatibility field: set when Output is a float
+	Output     *ResultEnvelope `json:"output,omitempty"`      // Typed operation result
+	TimeTaken  string          `json:"time_taken"`            // "1.24s"
+	Logs       string          `json:"logs,omitempty"`        // Captured operation logs (if requested)
+
+	Iterations int64      `json:"iterations,omitempty"` // Iterations completed by iterative operations
+	Precision  *Precision `json:"precision,omitempty"`  // Achieved precision of statistical results
+
+	// Units names the unit of a float result (key "result") or of each field of a JSON
+	// result (nested fields as "latency.p50_ms"). Metadata says what the result is
+	// ("quantity") and how it was obtained, e.g. the samples behind an estimate.
+	Units    map[string]string      `json:"units,omitempty"`
+	Metadata map[string]interface{} `json:"metadata,omitempty"`
+
+	// Partial is set when the job was stopped early: the result covers only the work done
+	Partial bool `json:"partial,omitempty"`
+
+	// Checkpoint is set when a slice ended before the job finished; resubmit it to continue
+	Checkpoint *Checkpoint `json:"checkpoint,omitempty"`
+	Slices     int         `json:"slices,omitempty"`   // Time slices the job ran in (set by the gateway)
+	Attempts   int         `json:"attempts,omitempty"` // Attempts it took when the job was retried (set by the gateway)
+
+	// Annotations describe how the gateway scheduled the job (set when the request asked to annotate)
+	Annotations *JobAnnotations `json:"annotations,omitempty"`
+
+	// Signature is the worker's signature of the result (set when the gateway runs with RESULT_SIGNING)
+	Signature *ResultSignature `json:"signature,omitempty"`
+}
+
+// ResultSignature is a worker's ed25519 signature over a JSON document describing
+// a job's result (signing.ResultPayload), made with the key the gateway provisioned
+// for the worker's container when it started it
+type ResultSignature struct {
+	Algorithm string `json:"algorithm"` // "ed25519"
+	KeyID     string `json:"key_id"`    // First 16 hex digits of the public key's SHA-256
+	Payload   string `json:"payload"`   // Base64 of the signed document
+	Signature string `json:"signature"` // Base64
+
+	// Set by the gateway once it has verified the signature
+	PublicKey string `json:"public_key,omitempty"` // Base64 of the worker's ed25519 public key
+	ImageID   string `json:"image_id,omitempty"`   // Image (digest) the signing worker's container ran
+	Verified  bool   `json:"verified,omitempty"`
+}
+
+// Placements recorded in JobAnnotations
+const (
+	PlacementExisting  = "existing_worker" // Dispatched straight to a running worker
+	PlacementSpawned   = "spawned_worker"  // Dispatched to a worker started for it
+	PlacementQueued    = "queued"          // Waited in a queue before dispatch
+	PlacementForwarded = "forwarded"       // Handed to the peer gateway in Peer
+)
+
+// JobAnnotations is scheduler metadata about one job: where and how it was placed,
+// and what the estimator predicted against what actually happened. Durations are
+// in seconds; when a job was retried, placement reflects the last attempt.
+type JobAnnotations struct {
+	Strategy    string `json:"strategy"`  // Worker selection strategy
+	Placement   string `json:"placement"` // See Placement constants
+	Queue       string `json:"queue,omitempty"`
+	CoreID      int    `json:"core_id,omitempty"`
+	WorkerUUID  string `json:"worker_uuid,omitempty"`  // Stable worker identity (see GET /jobs?worker=)
+	WorkerImage string `json:"worker_image,omitempty"` // Image ID (digest) the worker container ran
+	Peer        string `json:"peer,omitempty"`         // Peer gateway a forwarded job ran on
+
+	EstimatedCPU       float64 `json:"estimated_cpu"`
+	EstimatedDuration  float64 `json:"estimated_duration"`
+	EstimatedQueueWait float64 `json:"estimated_queue_wait"` // Predicted when the job was queued
+	QueueWait          float64 `json:"queue_wait"`           // Total time spent in queues
+	RunTime            float64 `json:"run_time"`             // Total time spent on workers
+
+	Attempts int `json:"attempts"`
+	Slices   int `json:"slices,omitempty"`
+}
+
+// Precision describes the statistical uncertainty of an estimate
+type Precision struct {
+	StdError  float64    `json:"std_error"`
+	CI95      [2]float64 `json:"ci95"`                 // 95% confidence interval (normal approximation)
+	Target    float64    `json:"target,omitempty"`     // Requested std error bound, if any
+	TargetMet *bool      `json:"target_met,omitempty"` // Set when a target was requested
+}
+
+// DefaultProgressEvery is the progress interval for streamed iterative jobs
+const DefaultProgressEvery = 100_000_000
+
+// Stream event types
+const (
+	StreamEventAccepted = "accepted" // Gateway accepted the job (carries the job ID for cancellation)
+	StreamEventProgress = "progress" // Intermediate result
+	StreamEventResult   = "result"   // Final response
+	StreamEventError    = "error"    // Job failed or was cancelled
+
+	StreamEventQueueTimeout = "queue_timeout" // The job outwaited its queue and its timeout policy kept it waiting
+)
+
+// StreamEvent is one NDJSON line of a streamed job
+type StreamEvent struct {
+	Type       string          `json:"type"`
+	JobID      string          `json:"job_id,omitempty"`
+	Iterations int64           `json:"iterations,omitempty"`
+	Output     *ResultEnvelope `json:"output,omitempty"`
+	Precision  *Precision      `json:"precision,omitempty"`
+	Response   *JobResponse    `json:"response,omitempty"` // Set on the final "result" event
+	Error      string          `json:"error,omitempty"`
+
+	// Set on "queue_timeout" events: the policy applied and the queue the job now waits in
+	Policy string `json:"policy,omitempty"`
+	Queue  string `json:"queue,omitempty"`
+}
+
+// Result envelope types
+const (
+	ResultTypeFloat  = "float"  // Data holds a JSON number
+	ResultTypeJSON   = "json"   // Data holds an arbitrary JSON document (lists, matrices, ...)
+	ResultTypeBinary = "binary" // Binary holds raw bytes (base64 in JSON)
+)
+
+// ResultEnvelope carries an operation-specific result of any shape
+type ResultEnvelope struct {
+	Type   string          `json:"type"`
+	Data   json.RawMessage `json:"data,omitempty"`
+	Binary []byte          `json:"binary,omitempty"`
+}
+
+// FloatResult wraps a scalar result
+func FloatResult(v float64) *ResultEnvelope {
+	data, _ := json.Marshal(v)
+	return &ResultEnvelope{Type: ResultTypeFloat, Data: data}
+}
+
+// JSONResult wraps any JSON-marshalable result
+func JSONResult(v interface{}) (*ResultEnvelope, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal result: %w", err)
+	}
+	return &ResultEnvelope{Type: ResultTypeJSON, Data: data}, nil
+}
+
+// BinaryResult wraps an opaque byte payload
+func BinaryResult(b []byte) *ResultEnvelope {
+	return &ResultEnvelope{Type: ResultTypeBinary, Binary: b}
+}
+
+// Float returns the scalar value of a float envelope
+func (e *ResultEnvelope) Float() (float64, bool) {
+	if e == nil || e.Type != ResultTypeFloat {
+		return 0, false
+	}
+	var v float64
+	if err := json.Unmarshal(e.Data, &v); err != nil {
+		return 0, false
+	}
+	return v, true
 }
 
 type Status int
@@ -31,10 +453,45 @@ const (
 	StatusInProgress
 	StatusCompleted
 	StatusFailed
+	StatusCancelled
+	StatusHeld // Accepted while submissions are held; runs once they are released
 )
 
+var statusNames = [...]string{"accepted", "queued", "in_progress", "completed", "failed", "cancelled", "held"}
+
+func (s Status) String() string {
+	if s < 0 || int(s) >= len(statusNames) {
+		return "unknown"
+	}
+	return statusNames[s]
+}
+
+// MarshalText encodes the status as its name in JSON
+func (s Status) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText decodes a status from its name
+func (s *Status) UnmarshalText(text []byte) error {
+	for i, name := range statusNames {
+		if name == string(text) {
+			*s = Status(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown job status %q", text)
+}
+
 type JobStatus struct {
 	JobID      string `json:"job_id"`
 	Percentage int    `json:"percentage_complete"`
 	Result     string `json:"result,omitempty"`
 }
+
+// WorkerHeartbeat is sent periodically by a worker to the gateway's internal listener
+type WorkerHeartbeat struct {
+	WorkerID   string `json:"worker_id"`
+	WorkerUUID string `json:"worker_uuid,omitempty"`
+	Version    string `json:"version"`
+	Draining   bool   `json:"draining"`
+}
@@ -0,0 +1,429 @@
+package protocol
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Dispatch encodings between the gateway and its workers. The public API is JSON only.
+const (
+	WireJSON     = "json"
+	WireProtobuf = "protobuf"
+)
+
+// ContentTypeProtobuf marks a dispatch request or response in the protobuf encoding
+const ContentTypeProtobuf = "application/x-protobuf"
+
+// Encodings lists the dispatch encodings this build speaks, advertised by workers at the handshake
+var Encodings = []string{WireJSON, WireProtobuf}
+
+// The protobuf encoding follows dispatch.proto. The scalar fields of small jobs
+// are encoded natively; the typed parameters of load operations, annotations and
+// result metadata travel as embedded JSON, being rare or free-form.
+
+// requestExtras are the ComputeRequest fields carried as embedded JSON
+type requestExtras struct {
+	Retry     *RetryPolicy   `json:"retry,omitempty"`
+	Synthetic *SyntheticLoad `json:"synthetic,omitempty"`
+	Memory    *MemoryLoad    `json:"memory,omitempty"`
+	DiskIO    *DiskIOLoad    `json:"disk_io,omitempty"`
+	Network   *NetworkLoad   `json:"network,omitempty"`
+	Profile   []LoadSegment  `json:"profile,omitempty"`
+	Matrix    *MatrixParams  `json:"matrix,omitempty"`
+}
+
+func (e requestExtras) empty() bool {
+	return e.Retry == nil && e.Synthetic == nil && e.Memory == nil && e.DiskIO == nil && e.Network == nil && len(e.Profile) == 0 && e.Matrix == nil
+}
+
+// MarshalRequestProto encodes a request for dispatch in the protobuf encoding
+func MarshalRequestProto(req *ComputeRequest) ([]byte, error) {
+	b := make([]byte, 0, 64)
+	b = appendString(b, 1, req.JobID)
+	b = appendString(b, 2, req.Operation)
+	b = appendDouble(b, 3, req.CPULoad)
+	b = appendDouble(b, 4, req.LoadTime)
+	b = appendInt(b, 5, req.Iterations)
+	b = appendDouble(b, 6, req.TimeBudget)
+	b = appendDouble(b, 7, req.TargetStdError)
+	b = appendInt(b, 8, req.Seed)
+	b = appendString(b, 9, req.Queue)
+	b = appendString(b, 10, req.OnQueueTimeout)
+	b = appendBool(b, 11, req.CaptureLogs)
+	b = appendBool(b, 12, req.Stream)
+	b = appendInt(b, 13, req.ProgressEvery)
+	b = appendDouble(b, 14, req.SliceTime)
+	b = appendBool(b, 15, req.Annotate)
+	b = appendBool(b, 16, req.PartialResults)
+	b = appendMap(b, 17, req.Labels)
+	for _, toleration := range req.Tolerations {
+		b = protowire.AppendTag(b, 18, protowire.BytesType)
+		b = protowire.AppendString(b, toleration)
+	}
+	if cp := req.Checkpoint; cp != nil {
+		b = protowire.AppendTag(b, 19, protowire.BytesType)
+		b = protowire.AppendBytes(b, appendBytes(appendDouble(nil, 1, cp.Elapsed), 2, cp.State))
+	}
+
+	extras := requestExtras{req.Retry, req.Synthetic, req.Memory, req.DiskIO, req.Network, req.Profile, req.Matrix}
+	if !extras.empty() {
+		data, err := json.Marshal(extras)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal request parameters: %w", err)
+		}
+		b = appendBytes(b, 20, data)
+	}
+	return b, nil
+}
+
+// UnmarshalRequestProto decodes a request sent in the protobuf encoding
+func UnmarshalRequestProto(b []byte) (ComputeRequest, error) {
+	var req ComputeRequest
+	err := consumeFields(b, func(num protowire.Number, v fieldValue) error {
+		switch num {
+		case 1:
+			req.JobID = v.string()
+		case 2:
+			req.Operation = v.string()
+		case 3:
+			req.CPULoad = v.double()
+		case 4:
+			req.LoadTime = v.double()
+		case 5:
+			req.Iterations = int64(v.varint)
+		case 6:
+			req.TimeBudget = v.double()
+		case 7:
+			req.TargetStdError = v.double()
+		case 8:
+			req.Seed = int64(v.varint)
+		case 9:
+			req.Queue = v.string()
+		case 10:
+			req.OnQueueTimeout = v.string()
+		case 11:
+			req.CaptureLogs = v.varint != 0
+		case 12:
+			req.Stream = v.varint != 0
+		case 13:
+			req.ProgressEvery = int64(v.varint)
+		case 14:
+			req.SliceTime = v.double()
+		case 15:
+			req.Annotate = v.varint != 0
+		case 16:
+			req.PartialResults = v.varint != 0
+		case 17:
+			if req.Labels == nil {
+				req.Labels = make(map[string]string)
+			}
+			return consumeMapEntry(v.bytes, req.Labels)
+		case 18:
+			req.Tolerations = append(req.Tolerations, v.string())
+		case 19:
+			req.Checkpoint = &Checkpoint{}
+			return consumeFields(v.bytes, func(num protowire.Number, v fieldValue) error {
+				switch num {
+				case 1:
+					req.Checkpoint.Elapsed = v.double()
+				case 2:
+					req.Checkpoint.State = json.RawMessage(v.clone())
+				}
+				return nil
+			})
+		case 20:
+			var extras requestExtras
+			if err := json.Unmarshal(v.bytes, &extras); err != nil {
+				return fmt.Errorf("invalid request parameters: %w", err)
+			}
+			req.Retry, req.Synthetic, req.Memory, req.DiskIO, req.Network, req.Profile, req.Matrix =
+				extras.Retry, extras.Synthetic, extras.Memory, extras.DiskIO, extras.Network, extras.Profile, extras.Matrix
+		}
+		return nil
+	})
+	return req, err
+}
+
+// MarshalResponseProto encodes a worker's response in the protobuf encoding
+func MarshalResponseProto(resp *JobResponse) ([]byte, error) {
+	b := make([]byte, 0, 128)
+	b = appendString(b, 1, resp.JobID)
+	b = appendString(b, 2, resp.WorkerID)
+	b = appendString(b, 3, resp.WorkerUUID)
+	b = appendDouble(b, 4, resp.Result)
+	if out := resp.Output; out != nil {
+		var msg []byte
+		msg = appendString(msg, 1, out.Type)
+		msg = appendBytes(msg, 2, out.Data)
+		msg = appendBytes(msg, 3, out.Binary)
+		b = protowire.AppendTag(b, 5, protowire.BytesType)
+		b = protowire.AppendBytes(b, msg)
+	}
+	b = appendString(b, 6, resp.TimeTaken)
+	b = appendString(b, 7, resp.Logs)
+	b = appendInt(b, 8, resp.Iterations)
+	if p := resp.Precision; p != nil {
+		msg := appendDouble(nil, 1, p.StdError)
+		msg = appendDouble(msg, 2, p.CI95[0])
+		msg = appendDouble(msg, 3, p.CI95[1])
+		msg = appendDouble(msg, 4, p.Target)
+		if p.TargetMet != nil {
+			// Present even when false: absence means no target was requested
+			msg = protowire.AppendTag(msg, 5, protowire.VarintType)
+			msg = protowire.AppendVarint(msg, protowire.EncodeBool(*p.TargetMet))
+		}
+		b = protowire.AppendTag(b, 9, protowire.BytesType)
+		b = protowire.AppendBytes(b, msg)
+	}
+	b = appendBool(b, 10, resp.Partial)
+	if cp := resp.Checkpoint; cp != nil {
+		b = protowire.AppendTag(b, 11, protowire.BytesType)
+		b = protowire.AppendBytes(b, appendBytes(appendDouble(nil, 1, cp.Elapsed), 2, cp.State))
+	}
+	b = appendInt(b, 12, int64(resp.Slices))
+	b = appendInt(b, 13, int64(resp.Attempts))
+	b = appendMap(b, 14, resp.Units)
+	if len(resp.Metadata) > 0 {
+		data, err := json.Marshal(resp.Metadata)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal metadata: %w", err)
+		}
+		b = appendBytes(b, 15, data)
+	}
+	if resp.Annotations != nil {
+		data, err := json.Marshal(resp.Annotations)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal annotations: %w", err)
+		}
+		b = appendBytes(b, 16, data)
+	}
+	if resp.Signature != nil {
+		data, err := json.Marshal(resp.Signature)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal signature: %w", err)
+		}
+		b = appendBytes(b, 17, data)
+	}
+	return b, nil
+}
+
+// UnmarshalResponseProto decodes a worker's response sent in the protobuf encoding
+func UnmarshalResponseProto(b []byte) (JobResponse, error) {
+	var resp JobResponse
+	err := consumeFields(b, func(num protowire.Number, v fieldValue) error {
+		switch num {
+		case 1:
+			resp.JobID = v.string()
+		case 2:
+			resp.WorkerID = v.string()
+		case 3:
+			resp.WorkerUUID = v.string()
+		case 4:
+			resp.Result = v.double()
+		case 5:
+			resp.Output = &ResultEnvelope{}
+			return consumeFields(v.bytes, func(num protowire.Number, v fieldValue) error {
+				switch num {
+				case 1:
+					resp.Output.Type = v.string()
+				case 2:
+					resp.Output.Data = json.RawMessage(v.clone())
+				case 3:
+					resp.Output.Binary = v.clone()
+				}
+				return nil
+			})
+		case 6:
+			resp.TimeTaken = v.string()
+		case 7:
+			resp.Logs = v.string()
+		case 8:
+			resp.Iterations = int64(v.varint)
+		case 9:
+			resp.Precision = &Precision{}
+			return consumeFields(v.bytes, func(num protowire.Number, v fieldValue) error {
+				switch num {
+				case 1:
+					resp.Precision.StdError = v.double()
+				case 2:
+					resp.Precision.CI95[0] = v.double()
+				case 3:
+					resp.Precision.CI95[1] = v.double()
+				case 4:
+					resp.Precision.Target = v.double()
+				case 5:
+					met := v.varint != 0
+					resp.Precision.TargetMet = &met
+				}
+				return nil
+			})
+		case 10:
+			resp.Partial = v.varint != 0
+		case 11:
+			resp.Checkpoint = &Checkpoint{}
+			return consumeFields(v.bytes, func(num protowire.Number, v fieldValue) error {
+				switch num {
+				case 1:
+					resp.Checkpoint.Elapsed = v.double()
+				case 2:
+					resp.Checkpoint.State = json.RawMessage(v.clone())
+				}
+				return nil
+			})
+		case 12:
+			resp.Slices = int(v.varint)
+		case 13:
+			resp.Attempts = int(v.varint)
+		case 14:
+			if resp.Units == nil {
+				resp.Units = make(map[string]string)
+			}
+			return consumeMapEntry(v.bytes, resp.Units)
+		case 15:
+			if err := json.Unmarshal(v.bytes, &resp.Metadata); err != nil {
+				return fmt.Errorf("invalid metadata: %w", err)
+			}
+		case 16:
+			if err := json.Unmarshal(v.bytes, &resp.Annotations); err != nil {
+				return fmt.Errorf("invalid annotations: %w", err)
+			}
+		case 17:
+			if err := json.Unmarshal(v.bytes, &resp.Signature); err != nil {
+				return fmt.Errorf("invalid signature: %w", err)
+			}
+		}
+		return nil
+	})
+	return resp, err
+}
+
+// Field encoders. Zero values are omitted, as in proto3.
+
+func appendString(b []byte, num protowire.Number, v string) []byte {
+	if v == "" {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendString(b, v)
+}
+
+func appendBytes(b []byte, num protowire.Number, v []byte) []byte {
+	if len(v) == 0 {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, v)
+}
+
+func appendDouble(b []byte, num protowire.Number, v float64) []byte {
+	if v == 0 {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.Fixed64Type)
+	return protowire.AppendFixed64(b, math.Float64bits(v))
+}
+
+func appendInt(b []byte, num protowire.Number, v int64) []byte {
+	if v == 0 {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.VarintType)
+	return protowire.AppendVarint(b, uint64(v))
+}
+
+func appendBool(b []byte, num protowire.Number, v bool) []byte {
+	if !v {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.VarintType)
+	return protowire.AppendVarint(b, 1)
+}
+
+// appendMap encodes a map<string, string> as its entries, in key order
+func appendMap(b []byte, num protowire.Number, m map[string]string) []byte {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		var entry []byte
+		entry = protowire.AppendTag(entry, 1, protowire.BytesType)
+		entry = protowire.AppendString(entry, k)
+		entry = protowire.AppendTag(entry, 2, protowire.BytesType)
+		entry = protowire.AppendString(entry, m[k])
+		b = protowire.AppendTag(b, num, protowire.BytesType)
+		b = protowire.AppendBytes(b, entry)
+	}
+	return b
+}
+
+// fieldValue is a decoded field: varint and fixed64 values in varint, length-
+// delimited ones in bytes (aliasing the input)
+type fieldValue struct {
+	varint uint64
+	bytes  []byte
+}
+
+func (v fieldValue) string() string  { return string(v.bytes) }
+func (v fieldValue) double() float64 { return math.Float64frombits(v.varint) }
+func (v fieldValue) clone() []byte   { return append([]byte(nil), v.bytes...) }
+
+var errMalformed = errors.New("malformed protobuf message")
+
+// consumeFields calls fn for each field of a message, skipping unknown wire types
+func consumeFields(b []byte, fn func(num protowire.Number, v fieldValue) error) error {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return errMalformed
+		}
+		b = b[n:]
+
+		var v fieldValue
+		switch typ {
+		case protowire.VarintType:
+			v.varint, n = protowire.ConsumeVarint(b)
+		case protowire.Fixed64Type:
+			v.varint, n = protowire.ConsumeFixed64(b)
+		case protowire.BytesType:
+			v.bytes, n = protowire.ConsumeBytes(b)
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, b)
+			if n < 0 {
+				return errMalformed
+			}
+			b = b[n:]
+			continue
+		}
+		if n < 0 {
+			return errMalformed
+		}
+		b = b[n:]
+		if err := fn(num, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// consumeMapEntry decodes one map<string, string> entry into m
+func consumeMapEntry(b []byte, m map[string]string) error {
+	var key, value string
+	err := consumeFields(b, func(num protowire.Number, v fieldValue) error {
+		switch num {
+		case 1:
+			key = v.string()
+		case 2:
+			value = v.string()
+		}
+		return nil
+	})
+	m[key] = value
+	return err
+}
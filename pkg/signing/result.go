@@ -0,0 +1,167 @@
+package signing
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ahmadhassan44/container-orchestrator/pkg/protocol"
+)
+
+// AlgorithmEd25519 is the only result signature algorithm
+const AlgorithmEd25519 = "ed25519"
+
+// Reasons a result signature is rejected
+var (
+	ErrResultUnsigned = errors.New("result is not signed")
+	ErrResultMismatch = errors.New("signed payload doesn't match the result")
+)
+
+// ResultPayload is the document a worker signs for a job's result. Consumers
+// holding the signing worker's public key can check a result against it.
+type ResultPayload struct {
+	JobID      string                   `json:"job_id"`
+	Operation  string                   `json:"operation"`
+	ParamsHash string                   `json:"params_hash"` // See ParamsHash
+	WorkerUUID string                   `json:"worker_uuid"`
+	Result     float64                  `json:"result"`
+	Output     *protocol.ResultEnvelope `json:"output,omitempty"`
+	Iterations int64                    `json:"iterations,omitempty"`
+	Partial    bool                     `json:"partial,omitempty"`
+	StartedAt  time.Time                `json:"started_at"`
+	FinishedAt time.Time                `json:"finished_at"`
+	TimeTaken  string                   `json:"time_taken"`
+}
+
+// resultParams are the request fields that decide what a job computes; queueing,
+// retry, streaming and slicing options don't change the result and are left out
+type resultParams struct {
+	Operation      string                  `json:"operation"`
+	CPULoad        float64                 `json:"cpu_load"`
+	LoadTime       float64                 `json:"load_time"`
+	Iterations     int64                   `json:"iterations,omitempty"`
+	TimeBudget     float64                 `json:"time_budget,omitempty"`
+	TargetStdError float64                 `json:"target_std_error,omitempty"`
+	Seed           int64                   `json:"seed,omitempty"`
+	Synthetic      *protocol.SyntheticLoad `json:"synthetic,omitempty"`
+	Memory         *protocol.MemoryLoad    `json:"memory,omitempty"`
+	DiskIO         *protocol.DiskIOLoad    `json:"disk_io,omitempty"`
+	Network        *protocol.NetworkLoad   `json:"network,omitempty"`
+	Profile        []protocol.LoadSegment  `json:"profile,omitempty"`
+	Matrix         *protocol.MatrixParams  `json:"matrix,omitempty"`
+}
+
+// ParamsHash is the hex SHA-256 of the JSON encoding of req's computation
+// parameters: operation (defaulted), cpu_load, load_time, iterations,
+// time_budget, target_std_error, seed, synthetic, memory, disk_io, network,
+// profile and matrix, in that order, with empty optional fields omitted
+func ParamsHash(req *protocol.ComputeRequest) string {
+	params := resultParams{
+		Operation:      req.Operation,
+		CPULoad:        req.CPULoad,
+		LoadTime:       req.LoadTime,
+		Iterations:     req.Iterations,
+		TimeBudget:     req.TimeBudget,
+		TargetStdError: req.TargetStdError,
+		Seed:           req.Seed,
+		Synthetic:      req.Synthetic,
+		Memory:         req.Memory,
+		DiskIO:         req.DiskIO,
+		Network:        req.Network,
+		Profile:        req.Profile,
+		Matrix:         req.Matrix,
+	}
+	if params.Operation == "" {
+		params.Operation = protocol.DefaultOperation
+	}
+	data, _ := json.Marshal(params)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// KeyID identifies a public key: the first 16 hex digits of its SHA-256
+func KeyID(key ed25519.PublicKey) string {
+	sum := sha256.Sum256(key)
+	return hex.EncodeToString(sum[:8])
+}
+
+// SignResult signs resp, the result of a job with the given parameters hash and
+// operation that ran from started to finished
+func SignResult(key ed25519.PrivateKey, paramsHash, operation string, resp *protocol.JobResponse, started, finished time.Time) (*protocol.ResultSignature, error) {
+	if operation == "" {
+		operation = protocol.DefaultOperation
+	}
+	payload, err := json.Marshal(ResultPayload{
+		JobID:      resp.JobID,
+		Operation:  operation,
+		ParamsHash: paramsHash,
+		WorkerUUID: resp.WorkerUUID,
+		Result:     resp.Result,
+		Output:     resp.Output,
+		Iterations: resp.Iterations,
+		Partial:    resp.Partial,
+		StartedAt:  started.UTC(),
+		FinishedAt: finished.UTC(),
+		TimeTaken:  resp.TimeTaken,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal result payload: %w", err)
+	}
+	return &protocol.ResultSignature{
+		Algorithm: AlgorithmEd25519,
+		KeyID:     KeyID(key.Public().(ed25519.PublicKey)),
+		Payload:   base64.StdEncoding.EncodeToString(payload),
+		Signature: base64.StdEncoding.EncodeToString(ed25519.Sign(key, payload)),
+	}, nil
+}
+
+// VerifyResult checks resp's signature under key, and that the signed payload
+// describes resp as the result of req
+func VerifyResult(key ed25519.PublicKey, req *protocol.ComputeRequest, resp *protocol.JobResponse) (ResultPayload, error) {
+	var payload ResultPayload
+	sig := resp.Signature
+	if sig == nil {
+		return payload, ErrResultUnsigned
+	}
+	if sig.Algorithm != AlgorithmEd25519 || sig.KeyID != KeyID(key) {
+		return payload, fmt.Errorf("%w: signed with %s key %s", ErrBadSignature, sig.Algorithm, sig.KeyID)
+	}
+	data, err := base64.StdEncoding.DecodeString(sig.Payload)
+	if err != nil {
+		return payload, fmt.Errorf("%w: payload isn't base64", ErrBadSignature)
+	}
+	signature, err := base64.StdEncoding.DecodeString(sig.Signature)
+	if err != nil || !ed25519.Verify(key, data, signature) {
+		return payload, ErrBadSignature
+	}
+
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return payload, fmt.Errorf("%w: %v", ErrResultMismatch, err)
+	}
+	operation := req.Operation
+	if operation == "" {
+		operation = protocol.DefaultOperation
+	}
+	signedOutput, _ := json.Marshal(payload.Output)
+	output, _ := json.Marshal(resp.Output)
+	switch {
+	case payload.JobID != resp.JobID || payload.JobID != req.JobID:
+		return payload, fmt.Errorf("%w: job ID", ErrResultMismatch)
+	case payload.Operation != operation || payload.ParamsHash != ParamsHash(req):
+		return payload, fmt.Errorf("%w: parameters", ErrResultMismatch)
+	case payload.WorkerUUID != resp.WorkerUUID:
+		return payload, fmt.Errorf("%w: worker", ErrResultMismatch)
+	case payload.Result != resp.Result || !bytes.Equal(signedOutput, output) ||
+		payload.Iterations != resp.Iterations || payload.Partial != resp.Partial:
+		return payload, fmt.Errorf("%w: result", ErrResultMismatch)
+	case payload.TimeTaken != resp.TimeTaken:
+		return payload, fmt.Errorf("%w: timing", ErrResultMismatch)
+	}
+	return payload, nil
+}
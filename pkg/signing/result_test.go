@@ -0,0 +1,16 @@
+package signing
+
+import (
+	"testing"
+
+	"github.com/ahmadhassan44/container-orchestrator/pkg/protocol"
+)
+
+func TestParamsHashBindsMatrix(t *testing.T) {
+	a := &protocol.ComputeRequest{Operation: "matrix_determinant", Matrix: &protocol.MatrixParams{Values: [][]float64{{2, 1}, {1, 3}}}}
+	b := &protocol.ComputeRequest{Operation: "matrix_determinant", Matrix: &protocol.MatrixParams{Values: [][]float64{{2, 1}, {1, 4}}}}
+
+	if ParamsHash(a) == ParamsHash(b) {
+		t.Errorf("requests for different matrices hash alike: %s", ParamsHash(a))
+	}
+}
@@ -0,0 +1,33 @@
+package version
+
+import "runtime"
+
+// Build metadata, injected at build time via:
+//
+//	-ldflags "-X github.com/ahmadhassan44/container-orchestrator/pkg/version.Version=1.2.3 ..."
+var (
+	Version   = "dev"
+	Commit    = "unknown"
+	BuildDate = "unknown"
+)
+
+// Info describes the running binary
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildDate string `json:"build_date"`
+	GoVersion string `json:"go_version"`
+
+	// Dispatch encodings the binary accepts (reported by workers only)
+	Encodings []string `json:"encodings,omitempty"`
+}
+
+// Get returns the build info of the running binary
+func Get() Info {
+	return Info{
+		Version:   Version,
+		Commit:    Commit,
+		BuildDate: BuildDate,
+		GoVersion: runtime.Version(),
+	}
+}
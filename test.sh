@@ -34,8 +34,8 @@ extract_json() {
 
 # Test 1: Health Check
 print_test "1" "Health Check"
-HEALTH=$(curl -s $GATEWAY_URL/health)
-if [ "$HEALTH" == "OK" ]; then
+HEALTH_CODE=$(curl -s -o /dev/null -w "%{http_code}" $GATEWAY_URL/health)
+if [ "$HEALTH_CODE" == "200" ]; then
     print_result "Gateway is healthy"
 else
     echo -e "${RED}✗ Gateway health check failed${NC}"
@@ -43,8 +43,8 @@ show_status() {
 
 # TEST 1: Health Check
 print_test "1" "System Health Check"
-HEALTH=$(curl -s $GATEWAY_URL/health)
-if [ "$HEALTH" == "OK" ]; then
+HEALTH_CODE=$(curl -s -o /dev/null -w "%{http_code}" $GATEWAY_URL/health)
+if [ "$HEALTH_CODE" == "200" ]; then
     print_result "Gateway is healthy and accepting requests"
 else
     echo -e "${RED}✗ Gateway health check failed${NC}"